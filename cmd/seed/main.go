@@ -0,0 +1,62 @@
+// cmd/seed/main.go
+// Command seed populates a development database with randomized users.
+//
+// Usage:
+//
+//	go run ./cmd/seed -users 200 -admins 2
+//
+// Every seeded user can log in with the password models.TestUserPassword.
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"time"
+
+	"go-template/internal/container"
+	"go-template/internal/models"
+	"go-template/internal/repositories"
+	"go-template/internal/repositories/testutil"
+)
+
+func main() {
+	userCount := flag.Int("users", 100, "number of regular users to create")
+	adminCount := flag.Int("admins", 1, "number of admin users to create")
+	timeout := flag.Duration("timeout", 5*time.Minute, "maximum time to spend seeding")
+	flag.Parse()
+
+	deps := container.NewDependencies()
+	if deps.GetConfig().IsProduction() {
+		log.Fatal("❌ Refusing to seed a production database")
+	}
+
+	if err := deps.Initialize(); err != nil {
+		log.Fatalf("❌ Failed to initialize dependencies: %v", err)
+	}
+	defer deps.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	repo := repositories.NewUserRepository(deps.GetDB(), deps.GetLogger("seed"))
+
+	users, err := testutil.SeedUsers(ctx, repo, *userCount)
+	if err != nil {
+		log.Fatalf("❌ Failed to seed users: %v", err)
+	}
+	log.Printf("✅ Created %d users", len(users))
+
+	admins, err := testutil.SeedUsers(ctx, repo, *adminCount,
+		models.WithRoles(models.RoleUser, models.RoleAdmin),
+		models.WithVerified(true),
+	)
+	if err != nil {
+		log.Fatalf("❌ Failed to seed admins: %v", err)
+	}
+	for _, admin := range admins {
+		log.Printf("✅ Created admin %s", admin.Email)
+	}
+
+	log.Printf("🔑 All seeded users share the password %q", models.TestUserPassword)
+}
@@ -3,21 +3,33 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	httpSwagger "github.com/swaggo/http-swagger"
 
 	_ "go-template/docs" // Import generated docs
 
 	"go-template/internal/container"
 	"go-template/internal/database"
+	"go-template/internal/database/migrations"
+	"go-template/internal/modules/auth"
+	"go-template/internal/modules/system"
 	"go-template/internal/modules/users"
+	"go-template/internal/shared/health"
+	"go-template/internal/shared/metrics"
+	"go-template/internal/shared/middleware"
 	"go-template/internal/shared/response"
+	"go-template/internal/shared/tracing"
 )
 
 // @title Go API Template
@@ -45,6 +57,9 @@ import (
 // @tag.name Users
 // @tag.description User management operations including CRUD, search, and account management
 
+// @tag.name Auth
+// @tag.description Authentication operations including login and account lockout
+
 // @tag.name System
 // @tag.description System health and configuration endpoints
 
@@ -54,21 +69,64 @@ func main() {
 	// Create dependency container
 	deps := container.NewDependencies()
 
+	// Initialize tracing (no-op unless TRACING_ENABLED)
+	shutdownTracing, err := tracing.Setup(deps.Context, deps.GetConfig())
+	if err != nil {
+		log.Fatalf("❌ Failed to initialize tracing: %v", err)
+	}
+
 	// Initialize all dependencies
 	if err := deps.Initialize(); err != nil {
 		log.Fatalf("❌ Failed to initialize dependencies: %v", err)
 	}
 
+	// Compact JSON in production, indented everywhere else for readability
+	response.Configure(!deps.GetConfig().IsProduction())
+
+	// Cache metrics are registered before the routes so services count their lookups
+	deps.CacheMetrics = metrics.NewCacheMetrics(prometheus.DefaultRegisterer)
+
 	// Setup routes (Phase 1 + Phase 2 + Swagger)
 	setupAllRoutes(deps)
 
+	// Global middleware: tracing, access logging and metrics wrap the mux so the matched route pattern is available
+	// Panics are recovered outermost so every other middleware is covered. The request timeout sits
+	// closest to the mux so timed-out requests are still logged and measured; streaming exports
+	// manage their own write deadlines and are exempt. Maintenance mode never blocks the probes
+	// or the endpoint that switches it off. Write requests must send JSON, except multipart avatar uploads
+	httpMetrics := middleware.NewMetrics(prometheus.DefaultRegisterer)
+	accessLog := middleware.AccessLog(
+		deps.GetLogger("http"),
+		middleware.QuietRoutes("GET /health", "GET /livez", "GET /readyz", "GET /metrics"),
+		deps.GetConfig().GetTrustedProxies(),
+	)
+	timeout := middleware.Timeout(deps.GetConfig().GetRequestTimeout(), users.ExportPath)
+	maintenance := middleware.Maintenance(
+		deps.Maintenance, deps.GetConfig().MaintenanceAllowReads,
+		system.MaintenancePath, "/health", "/livez", "/readyz", "/metrics",
+	)
+	handler := middleware.Chain(
+		deps.InFlight.Middleware,
+		middleware.Recovery(deps.GetLogger("http")),
+		middleware.RequestID,
+		middleware.Tracing,
+		accessLog,
+		middleware.CORS(deps.GetConfig().GetCORSAllowedOrigins()),
+		httpMetrics.Middleware,
+		maintenance,
+		middleware.RequireJSON(deps.Mux, users.AvatarUploadPattern),
+		timeout,
+		middleware.EnvelopeVersion,
+	)(deps.Mux)
+
 	// Create HTTP server with optimized settings
 	server := &http.Server{
-		Addr:         deps.GetConfig().GetServerAddress(),
-		Handler:      deps.Mux,
-		ReadTimeout:  15 * time.Second,
-		WriteTimeout: 15 * time.Second,
-		IdleTimeout:  60 * time.Second,
+		Addr:              deps.GetConfig().GetServerAddress(),
+		Handler:           handler,
+		ReadTimeout:       deps.GetConfig().ServerReadTimeout,
+		WriteTimeout:      deps.GetConfig().ServerWriteTimeout,
+		IdleTimeout:       deps.GetConfig().ServerIdleTimeout,
+		ReadHeaderTimeout: deps.GetConfig().ServerReadHeaderTimeout,
 	}
 
 	// Start server in a goroutine
@@ -96,9 +154,14 @@ func main() {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	// Shutdown HTTP server
-	if err := server.Shutdown(ctx); err != nil {
-		log.Printf("⚠️  Server forced to shutdown: %v", err)
+	// Shutdown HTTP server, reporting in-flight requests while they drain
+	if err := drainServer(ctx, server, deps.InFlightRequests, time.Second); err != nil {
+		log.Printf("⚠️  Server forced to shutdown with %d request(s) in flight: %v", deps.InFlightRequests(), err)
+	}
+
+	// Flush pending spans
+	if err := shutdownTracing(ctx); err != nil {
+		log.Printf("⚠️  Error shutting down tracing: %v", err)
 	}
 
 	// Close all dependencies
@@ -109,6 +172,28 @@ func main() {
 	log.Println("✅ Server shutdown complete")
 }
 
+// drainServer shuts the server down, logging the in-flight count every interval until
+// every request has finished or ctx expires
+func drainServer(ctx context.Context, server *http.Server, inFlight func() int64, interval time.Duration) error {
+	shutdownDone := make(chan error, 1)
+	go func() {
+		shutdownDone <- server.Shutdown(ctx)
+	}()
+	
+	drainTicker := time.NewTicker(interval)
+	defer drainTicker.Stop()
+	
+	log.Printf("⏳ Draining %d in-flight request(s)...", inFlight())
+	for {
+		select {
+		case err := <-shutdownDone:
+			return err
+		case <-drainTicker.C:
+			log.Printf("⏳ In-flight requests: %d", inFlight())
+		}
+	}
+}
+
 // setupAllRoutes configures all application routes including Swagger
 func setupAllRoutes(deps *container.Dependencies) {
 	logger := deps.GetLogger("routes")
@@ -152,18 +237,42 @@ func setupSwaggerRoutes(deps *container.Dependencies) {
 				"GET /api/v1/users",
 				"POST /api/v1/users", 
 				"GET /api/v1/users/{id}",
+				"GET /api/v1/users/me",
+				"PATCH /api/v1/users/me",
 				"PATCH /api/v1/users/{id}",
+				"PUT /api/v1/users/{id}",
 				"DELETE /api/v1/users/{id}",
 				"GET /api/v1/users/search",
 				"GET /api/v1/users/stats",
+				"GET /api/v1/users/count",
 				"GET /api/v1/users/{id}/profile",
+				"POST /api/v1/users/{id}/profile-view",
 				"PATCH /api/v1/users/{id}/password",
 				"PATCH /api/v1/users/{id}/verify",
+				"PUT /api/v1/users/{id}/roles",
+				"POST /api/v1/users/{id}/avatar",
+				"POST /api/v1/users/{id}/verification/send",
+				"POST /api/v1/users/batch-get",
+				"GET /api/v1/users/export",
+				"POST /api/v1/users/{id}/impersonate",
+				"POST /api/v1/auth/login",
+				"POST /api/v1/auth/verify-email",
+				"POST /api/v1/auth/forgot-password",
+				"POST /api/v1/auth/reset-password",
+				"GET /api/v1/system/maintenance",
+				"POST /api/v1/system/maintenance",
 			},
 			"models_documented": []string{
 				"CreateUserRequest",
 				"UpdateUserRequest", 
 				"ChangePasswordRequest",
+				"LoginRequest",
+				"LoginResponse",
+				"SetRolesRequest",
+				"BatchGetUsersRequest",
+				"VerifyEmailRequest",
+				"ForgotPasswordRequest",
+				"ResetPasswordRequest",
 				"UserResponse",
 				"UserProfileResponse",
 				"UserListResponse",
@@ -186,10 +295,15 @@ func setupBusinessRoutes(deps *container.Dependencies) {
 	// Users module - completely self-contained
 	users.RegisterRoutes(deps)
 
+	// Auth module - login and account security
+	auth.RegisterRoutes(deps)
+
+	// System module - admin endpoints for operating the service
+	system.RegisterRoutes(deps)
+
 	// Future modules will be added here:
 	// products.RegisterRoutes(deps)
 	// orders.RegisterRoutes(deps)
-	// auth.RegisterRoutes(deps)
 
 	logger.Info("✅ Business modules registered successfully")
 }
@@ -201,61 +315,17 @@ func setupTestRoutes(deps *container.Dependencies) {
 
 	mux := deps.Mux
 
-	// Health check endpoint - Enhanced for Phase 2 + Swagger
-	// @Summary System health check
-	// @Description Get system health status including database and cache connectivity
-	// @Tags System
-	// @Accept json
-	// @Produce json
-	// @Success 200 {object} response.Response{data=object} "System is healthy"
-	// @Failure 503 {object} response.Response{error=response.ErrorInfo} "System is unhealthy"
-	// @Router /health [get]
-	mux.HandleFunc("GET /health", func(w http.ResponseWriter, r *http.Request) {
-		logger.Info("Health check requested")
-		
-		health := map[string]interface{}{
-			"status":      "healthy",
-			"version":     "1.0.0",
-			"phase":       "2", // Updated to Phase 2
-			"environment": deps.GetConfig().Environment,
-			"timestamp":   time.Now().UTC().Format(time.RFC3339),
-			"features": map[string]bool{
-				"users_module":     true,
-				"swagger_docs":     true,
-				"mongodb":          true,
-				"redis_cache":      true,
-				"structured_logs":  true,
-				"api_responses":    true,
-			},
-			"documentation": map[string]string{
-				"swagger_ui":       "/swagger/",
-				"api_info":         "/api/v1",
-				"openapi_spec":     "/api/v1/openapi.json",
-			},
-		}
-
-		// Check database connection
-		if err := database.PingMongoDB(deps.GetDB()); err != nil {
-			health["database"] = "unhealthy"
-			health["database_error"] = err.Error()
-			logger.Error("Database health check failed", err)
-			response.ErrorWithDetails(w, "HEALTH_CHECK_FAILED", "Database is unhealthy", health, http.StatusServiceUnavailable)
-			return
-		}
-		health["database"] = "healthy"
-
-		// Check Redis connection
-		if err := deps.GetCache().Ping(r.Context()); err != nil {
-			health["cache"] = "unhealthy"
-			health["cache_error"] = err.Error()
-			logger.Error("Cache health check failed", err)
-			response.ErrorWithDetails(w, "HEALTH_CHECK_FAILED", "Cache is unhealthy", health, http.StatusServiceUnavailable)
-			return
-		}
-		health["cache"] = "healthy"
+	// Health checks for external dependencies
+	registerHealthRoutes(deps, newHealthChecker(deps))
 
-		response.JSON(w, health, http.StatusOK)
-	})
+	// Prometheus metrics endpoint
+	// @Summary Prometheus metrics
+	// @Description Expose HTTP request metrics in the Prometheus text format
+	// @Tags System
+	// @Produce plain
+	// @Success 200 {string} string "Prometheus metrics"
+	// @Router /metrics [get]
+	mux.Handle("GET /metrics", promhttp.Handler())
 
 	// API Info endpoint - Updated for Swagger
 	// @Summary API information
@@ -280,18 +350,26 @@ func setupTestRoutes(deps *container.Dependencies) {
 			},
 			"endpoints": map[string]interface{}{
 				"health": "/health",
+				"liveness": "/livez",
+				"readiness": "/readyz",
+				"metrics": "/metrics",
 				"api_info": "/api/v1",
 				"users": map[string]interface{}{
 					"list":         "GET /api/v1/users",
 					"get":          "GET /api/v1/users/{id}",
 					"create":       "POST /api/v1/users",
 					"update":       "PATCH /api/v1/users/{id}",
+					"replace":      "PUT /api/v1/users/{id}",
 					"delete":       "DELETE /api/v1/users/{id}",
 					"search":       "GET /api/v1/users/search",
 					"stats":        "GET /api/v1/users/stats",
+					"count":        "GET /api/v1/users/count",
 					"profile":      "GET /api/v1/users/{id}/profile",
+					"profile_view": "POST /api/v1/users/{id}/profile-view",
 					"change_password": "PUT /api/v1/users/{id}/password",
 					"verify":       "PUT /api/v1/users/{id}/verify",
+					"send_verification": "POST /api/v1/users/{id}/verification/send",
+					"avatar":       "POST /api/v1/users/{id}/avatar",
 				},
 				"testing": map[string]string{
 					"database": "/test/database",
@@ -472,7 +550,9 @@ func setupTestRoutes(deps *container.Dependencies) {
 					"delete_user":   "DELETE /api/v1/users/{id}",
 					"search_users":  "GET /api/v1/users/search",
 					"user_stats":    "GET /api/v1/users/stats",
+					"count_users":   "GET /api/v1/users/count",
 					"user_profile":  "GET /api/v1/users/{id}/profile",
+					"upload_avatar": "POST /api/v1/users/{id}/avatar",
 				},
 				"testing": map[string]string{
 					"db_test":       "/test/database",
@@ -488,4 +568,131 @@ func setupTestRoutes(deps *container.Dependencies) {
 	})
 
 	logger.Info("✅ System routes configured successfully")
-}
\ No newline at end of file
+}
+
+// cacheCircuitState reports the Redis circuit breaker state, or "n/a" if the cache has none
+func cacheCircuitState(deps *container.Dependencies) string {
+	if breaker, ok := deps.GetCache().(interface{ CircuitState() database.CircuitState }); ok {
+		return string(breaker.CircuitState())
+	}
+	return "n/a"
+}
+
+// newHealthChecker registers a check for each external dependency
+func newHealthChecker(deps *container.Dependencies) *health.Checker {
+	checker := health.NewChecker(health.DefaultCheckTimeout)
+	checker.Register("database", func(ctx context.Context) error {
+		return database.PingMongoDB(deps.GetDB())
+	})
+	// While the Redis circuit breaker is open the ping fails fast; after the cool-down it acts as the probe
+	checker.Register("cache", func(ctx context.Context) error {
+		return deps.GetCache().Ping(ctx)
+	})
+	// Missing indexes leave queries working but slow, so they degrade rather than fail the system
+	checker.Register("user_indexes", func(ctx context.Context) error {
+		missing, err := migrations.MissingUserIndexes(ctx, deps.GetDB())
+		if err != nil {
+			return err
+		}
+		if len(missing) > 0 {
+			return health.Degraded(fmt.Errorf("missing indexes: %s", strings.Join(missing, ", ")))
+		}
+		return nil
+	})
+	
+	return checker
+}
+
+// registerHealthRoutes sets up the health report and the liveness and readiness probes
+func registerHealthRoutes(deps *container.Dependencies, checker *health.Checker) {
+	logger := deps.GetLogger("system")
+	mux := deps.Mux
+
+	// Health check endpoint - Enhanced for Phase 2 + Swagger
+	// @Summary System health check
+	// @Description Get system health status including per-dependency status and latency for database, cache and user indexes. Missing indexes report a degraded status without failing the check.
+	// @Tags System
+	// @Accept json
+	// @Produce json
+	// @Param verbose query bool false "Include per-check latencies" default(true)
+	// @Success 200 {object} response.Response{data=object} "System is healthy"
+	// @Failure 503 {object} response.Response{error=response.ErrorInfo} "System is unhealthy"
+	// @Router /health [get]
+	mux.HandleFunc("GET /health", func(w http.ResponseWriter, r *http.Request) {
+		logger.Info("Health check requested")
+		
+		report := checker.RunAll(r.Context())
+		if verbose, err := strconv.ParseBool(r.URL.Query().Get("verbose")); err == nil && !verbose {
+			report = report.WithoutLatencies()
+		}
+		
+		healthInfo := map[string]interface{}{
+			"status":      report.Status,
+			"checks":      report.Checks,
+			"version":     "1.0.0",
+			"phase":       "2", // Updated to Phase 2
+			"environment": deps.GetConfig().Environment,
+			"timestamp":   time.Now().UTC().Format(time.RFC3339),
+			"cache_circuit": cacheCircuitState(deps),
+			"features": map[string]bool{
+				"users_module":     true,
+				"swagger_docs":     true,
+				"mongodb":          true,
+				"redis_cache":      true,
+				"structured_logs":  true,
+				"api_responses":    true,
+			},
+			"documentation": map[string]string{
+				"swagger_ui":       "/swagger/",
+				"api_info":         "/api/v1",
+				"openapi_spec":     "/api/v1/openapi.json",
+			},
+		}
+
+		for name, result := range report.Checks {
+			switch result.Status {
+			case health.StatusUnhealthy:
+				logger.Warn("Health check failed", "check", name, "error", result.Error)
+			case health.StatusDegraded:
+				logger.Warn("Health check degraded", "check", name, "error", result.Error)
+			}
+		}
+
+		if !report.IsHealthy() {
+			response.ErrorWithDetails(w, "HEALTH_CHECK_FAILED", "One or more dependencies are unhealthy", healthInfo, http.StatusServiceUnavailable)
+			return
+		}
+
+		response.JSON(w, healthInfo, http.StatusOK)
+	})
+
+	// Liveness probe - process is up, no dependency checks
+	// @Summary Liveness probe
+	// @Description Returns 200 as long as the process is running. Does not check dependencies, so transient outages do not restart the pod.
+	// @Tags System
+	// @Produce json
+	// @Success 200 {object} response.Response{data=object} "Process is alive"
+	// @Router /livez [get]
+	mux.HandleFunc("GET /livez", func(w http.ResponseWriter, r *http.Request) {
+		response.JSON(w, map[string]string{"status": "alive"}, http.StatusOK)
+	})
+
+	// Readiness probe - dependencies are reachable
+	// @Summary Readiness probe
+	// @Description Runs the dependency checks and returns 503 when any dependency is unreachable so traffic is routed elsewhere
+	// @Tags System
+	// @Produce json
+	// @Success 200 {object} response.Response{data=health.HealthReport} "Ready to serve traffic"
+	// @Failure 503 {object} response.Response{error=response.ErrorInfo} "Not ready"
+	// @Router /readyz [get]
+	mux.HandleFunc("GET /readyz", func(w http.ResponseWriter, r *http.Request) {
+		report := checker.RunAll(r.Context()).WithoutLatencies()
+		if !report.IsHealthy() {
+			logger.Warn("Readiness check failed", "checks", report.Checks)
+			response.ErrorWithDetails(w, "NOT_READY", "One or more dependencies are unavailable", report, http.StatusServiceUnavailable)
+			return
+		}
+
+		response.JSON(w, report, http.StatusOK)
+	})
+}
@@ -0,0 +1,329 @@
+// cmd/server/main_test.go
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go-template/internal/config"
+	"go-template/internal/container"
+	"go-template/internal/database"
+	"go-template/internal/interfaces"
+	"go-template/internal/shared/health"
+	"go-template/internal/shared/logtest"
+	"go-template/internal/shared/middleware"
+)
+
+// newTestDependencies returns a container with a fresh mux and a recording logger, and no connections
+func newTestDependencies(t *testing.T) *container.Dependencies {
+	t.Helper()
+	deps := container.NewDependenciesWithConfig(&config.Config{Environment: "test"})
+	deps.Logger = logtest.New()
+	t.Cleanup(deps.Cancel)
+	return deps
+}
+
+// newFakeChecker returns a checker whose checks report the given errors
+func newFakeChecker(results map[string]error) *health.Checker {
+	checker := health.NewChecker(health.DefaultCheckTimeout)
+	for name, err := range results {
+		checker.Register(name, func(ctx context.Context) error { return err })
+	}
+	return checker
+}
+
+// get serves a GET request for target through deps' mux
+func get(deps *container.Dependencies, target string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	deps.Mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+	return rec
+}
+
+func TestHealthRoute(t *testing.T) {
+	tests := []struct {
+		name        string
+		checks      map[string]error
+		query       string
+		wantStatus  int
+		wantReport  health.Status
+		wantLatency bool
+	}{
+		{
+			name:        "all healthy",
+			checks:      map[string]error{"database": nil, "cache": nil},
+			wantStatus:  http.StatusOK,
+			wantReport:  health.StatusHealthy,
+			wantLatency: true,
+		},
+		{
+			name:        "degraded dependency still serves",
+			checks:      map[string]error{"database": nil, "user_indexes": health.Degraded(errors.New("missing indexes"))},
+			wantStatus:  http.StatusOK,
+			wantReport:  health.StatusDegraded,
+			wantLatency: true,
+		},
+		{
+			name:        "failing dependency",
+			checks:      map[string]error{"database": nil, "cache": errors.New("connection refused")},
+			wantStatus:  http.StatusServiceUnavailable,
+			wantReport:  health.StatusUnhealthy,
+			wantLatency: true,
+		},
+		{
+			name:       "verbose=false omits latencies",
+			checks:     map[string]error{"database": nil},
+			query:      "?verbose=false",
+			wantStatus: http.StatusOK,
+			wantReport: health.StatusHealthy,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deps := newTestDependencies(t)
+			registerHealthRoutes(deps, newFakeChecker(tt.checks))
+
+			rec := get(deps, "/health"+tt.query)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+
+			var body struct {
+				Data  map[string]json.RawMessage `json:"data"`
+				Error *struct {
+					Details map[string]json.RawMessage `json:"details"`
+				} `json:"error"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatalf("invalid body: %v", err)
+			}
+			info := body.Data
+			if body.Error != nil {
+				info = body.Error.Details
+			}
+
+			var status health.Status
+			var checks map[string]map[string]interface{}
+			if err := json.Unmarshal(info["status"], &status); err != nil {
+				t.Fatalf("invalid status: %v", err)
+			}
+			if err := json.Unmarshal(info["checks"], &checks); err != nil {
+				t.Fatalf("invalid checks: %v", err)
+			}
+			if status != tt.wantReport {
+				t.Errorf("report status = %q, want %q", status, tt.wantReport)
+			}
+			if len(checks) != len(tt.checks) {
+				t.Errorf("got %d checks, want %d", len(checks), len(tt.checks))
+			}
+			if !tt.wantLatency {
+				for name, check := range checks {
+					if _, ok := check["latency_ms"]; ok {
+						t.Errorf("check %s has latency_ms with verbose=false", name)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestProbeRoutes(t *testing.T) {
+	tests := []struct {
+		name      string
+		checks    map[string]error
+		wantLivez int
+		wantReady int
+	}{
+		{
+			name:      "dependencies up",
+			checks:    map[string]error{"database": nil, "cache": nil},
+			wantLivez: http.StatusOK,
+			wantReady: http.StatusOK,
+		},
+		{
+			name:      "cache down",
+			checks:    map[string]error{"database": nil, "cache": errors.New("connection refused")},
+			wantLivez: http.StatusOK,
+			wantReady: http.StatusServiceUnavailable,
+		},
+		{
+			name:      "everything down",
+			checks:    map[string]error{"database": errors.New("no reachable servers"), "cache": errors.New("connection refused")},
+			wantLivez: http.StatusOK,
+			wantReady: http.StatusServiceUnavailable,
+		},
+		{
+			name:      "degraded dependency is still ready",
+			checks:    map[string]error{"database": nil, "user_indexes": health.Degraded(errors.New("missing indexes"))},
+			wantLivez: http.StatusOK,
+			wantReady: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deps := newTestDependencies(t)
+			calls := 0
+			checker := newFakeChecker(tt.checks)
+			checker.Register("counter", func(ctx context.Context) error {
+				calls++
+				return nil
+			})
+			registerHealthRoutes(deps, checker)
+
+			// Liveness never runs the dependency checks
+			if rec := get(deps, "/livez"); rec.Code != tt.wantLivez {
+				t.Errorf("/livez status = %d, want %d", rec.Code, tt.wantLivez)
+			}
+			if calls != 0 {
+				t.Errorf("/livez ran the dependency checks %d times", calls)
+			}
+
+			if rec := get(deps, "/readyz"); rec.Code != tt.wantReady {
+				t.Errorf("/readyz status = %d, want %d: %s", rec.Code, tt.wantReady, rec.Body.String())
+			}
+			if calls != 1 {
+				t.Errorf("/readyz ran the dependency checks %d times, want 1", calls)
+			}
+		})
+	}
+}
+
+func TestDrainServer(t *testing.T) {
+	tests := []struct {
+		name    string
+		timeout time.Duration
+		release bool // whether the slow request finishes during the drain
+		wantErr error
+	}{
+		{name: "waits for the in-flight request", timeout: 5 * time.Second, release: true},
+		{name: "gives up when the timeout elapses", timeout: 50 * time.Millisecond, wantErr: context.DeadlineExceeded},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			counter := middleware.NewInFlightCounter()
+			started := make(chan struct{})
+			release := make(chan struct{})
+			defer close(release)
+			finished := make(chan struct{})
+			slow := counter.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				close(started)
+				<-release
+				w.WriteHeader(http.StatusNoContent)
+				close(finished)
+			}))
+
+			listener, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatalf("Listen() error = %v", err)
+			}
+			server := &http.Server{Handler: slow}
+			go server.Serve(listener)
+
+			go func() {
+				resp, err := http.Get("http://" + listener.Addr().String())
+				if err == nil {
+					resp.Body.Close()
+				}
+			}()
+			<-started
+
+			ctx, cancel := context.WithTimeout(context.Background(), tt.timeout)
+			defer cancel()
+			drained := make(chan error, 1)
+			go func() {
+				drained <- drainServer(ctx, server, counter.Count, 10*time.Millisecond)
+			}()
+
+			// The drain must not finish while the request is still being handled
+			select {
+			case err := <-drained:
+				if tt.release {
+					t.Fatalf("drainServer() returned %v with a request in flight", err)
+				}
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("drainServer() error = %v, want %v", err, tt.wantErr)
+				}
+				if counter.Count() != 1 {
+					t.Errorf("in-flight count = %d after the timeout, want 1", counter.Count())
+				}
+				return
+			case <-time.After(100 * time.Millisecond):
+			}
+			if counter.Count() != 1 {
+				t.Errorf("in-flight count = %d while draining, want 1", counter.Count())
+			}
+
+			release <- struct{}{}
+			if err := <-drained; !errors.Is(err, tt.wantErr) {
+				t.Errorf("drainServer() error = %v, want %v", err, tt.wantErr)
+			}
+			select {
+			case <-finished:
+			default:
+				t.Error("drainServer() returned before the request finished")
+			}
+			if counter.Count() != 0 {
+				t.Errorf("in-flight count = %d after draining, want 0", counter.Count())
+			}
+		})
+	}
+}
+
+// breakerCache is a cache that reports a fixed circuit breaker state
+type breakerCache struct {
+	*database.MemoryCache
+	state database.CircuitState
+}
+
+func (c breakerCache) CircuitState() database.CircuitState {
+	return c.state
+}
+
+func TestCacheCircuitState(t *testing.T) {
+	tests := []struct {
+		name  string
+		cache interfaces.CacheInterface
+		want  string
+	}{
+		{name: "no cache", want: "n/a"},
+		{name: "cache without a breaker", cache: database.NewMemoryCache(), want: "n/a"},
+		{name: "closed breaker", cache: breakerCache{MemoryCache: database.NewMemoryCache(), state: database.CircuitClosed}, want: "closed"},
+		{name: "open breaker", cache: breakerCache{MemoryCache: database.NewMemoryCache(), state: database.CircuitOpen}, want: "open"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deps := newTestDependencies(t)
+			deps.Cache = tt.cache
+			if tt.cache != nil {
+				t.Cleanup(func() { tt.cache.Close() })
+			}
+
+			if got := cacheCircuitState(deps); got != tt.want {
+				t.Errorf("cacheCircuitState() = %q, want %q", got, tt.want)
+			}
+
+			// The state is reported in the health payload
+			registerHealthRoutes(deps, newFakeChecker(map[string]error{"cache": nil}))
+			var body struct {
+				Data struct {
+					CacheCircuit string `json:"cache_circuit"`
+				} `json:"data"`
+			}
+			if err := json.Unmarshal(get(deps, "/health").Body.Bytes(), &body); err != nil {
+				t.Fatalf("invalid body: %v", err)
+			}
+			if body.Data.CacheCircuit != tt.want {
+				t.Errorf("cache_circuit = %q, want %q", body.Data.CacheCircuit, tt.want)
+			}
+		})
+	}
+}
@@ -2,11 +2,17 @@
 package config
 
 import (
+	"errors"
 	"fmt"
 	"log"
+	"net/netip"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
-	"github.com/kelseyhightower/envconfig"
 )
 
 // Config holds all configuration for the application
@@ -14,31 +20,128 @@ type Config struct {
 	// Server Configuration
 	Port        string `envconfig:"PORT" default:"8080"`
 	Environment string `envconfig:"ENV" default:"development"`
+	// Handlers running longer than this are answered with 503; 0 disables the limit
+	RequestTimeoutSeconds int `envconfig:"REQUEST_TIMEOUT_SECONDS" default:"10"`
+	// HTTP server timeouts as Go durations, e.g. "15s"; 0 disables the read, write and idle timeouts
+	// Long avatar uploads need a longer read timeout and streamed exports a longer write timeout.
+	ServerReadTimeout       time.Duration `envconfig:"SERVER_READ_TIMEOUT" default:"15s"`
+	ServerWriteTimeout      time.Duration `envconfig:"SERVER_WRITE_TIMEOUT" default:"15s"`
+	ServerIdleTimeout       time.Duration `envconfig:"SERVER_IDLE_TIMEOUT" default:"60s"`
+	// Bounds how long clients may take to send request headers, guarding against Slowloris
+	ServerReadHeaderTimeout time.Duration `envconfig:"SERVER_READ_HEADER_TIMEOUT" default:"5s"`
+	// Start in maintenance mode, answering writes (or every request) with 503; admins can toggle it at runtime
+	MaintenanceMode       bool `envconfig:"MAINTENANCE_MODE" default:"false"`
+	MaintenanceAllowReads bool `envconfig:"MAINTENANCE_ALLOW_READS" default:"true"`
+	// Connection attempts to MongoDB and Redis at startup; the wait starts at the backoff and doubles
+	StartupRetryAttempts int           `envconfig:"STARTUP_RETRY_ATTEMPTS" default:"5"`
+	StartupRetryBackoff  time.Duration `envconfig:"STARTUP_RETRY_BACKOFF" default:"1s"`
+	// Start anyway when MongoDB or Redis stays unreachable; /readyz fails until they are back
+	AllowDegradedStart bool `envconfig:"ALLOW_DEGRADED_START" default:"false"`
 	
 	// Database Configuration
 	MongoURL      string `envconfig:"MONGO_URL" required:"true"`
 	DatabaseName  string `envconfig:"DATABASE_NAME" default:"go_api_template"`
+	// Optional connection for analytics queries, read from secondaries when possible
+	MongoReadURL string `envconfig:"MONGO_READ_URL" default:""`
+	// Connection pool bounds, applied to both MongoDB connections
+	MongoMaxPoolSize int `envconfig:"MONGO_MAX_POOL_SIZE" default:"100"`
+	MongoMinPoolSize int `envconfig:"MONGO_MIN_POOL_SIZE" default:"10"`
+	// Whether startup aborts when an index conflicts with existing indexes or data
+	FailOnIndexError bool `envconfig:"FAIL_ON_INDEX_ERROR" default:"true"`
+	
+	// Cache Configuration
+	// "memory" keeps the cache in process for tests and local development without Redis
+	CacheDriver string `envconfig:"CACHE_DRIVER" default:"redis"`
+	// Cache TTLs vary randomly by up to this percentage so entries do not expire together
+	CacheTTLJitterPercent int `envconfig:"CACHE_TTL_JITTER_PERCENT" default:"10"`
+	// Users who logged in most recently to load into the cache at startup; 0 disables warming
+	CacheWarmCount int `envconfig:"CACHE_WARM_COUNT" default:"0"`
 	
 	// Redis Configuration
-	RedisURL      string `envconfig:"REDIS_URL" required:"true"`
+	RedisURL      string `envconfig:"REDIS_URL"`
 	RedisPassword string `envconfig:"REDIS_PASSWORD" default:""`
 	RedisDB       int    `envconfig:"REDIS_DB" default:"0"`
+	RedisPoolSize     int `envconfig:"REDIS_POOL_SIZE" default:"100"`
+	RedisMinIdleConns int `envconfig:"REDIS_MIN_IDLE_CONNS" default:"10"`
 	
 	// JWT Configuration
-	JWTSecret           string `envconfig:"JWT_SECRET" required:"true"`
+	// HS256 signs with JWT_SECRET; RS256 signs with the private key and publishes the public key
+	JWTAlgorithm        string `envconfig:"JWT_ALGORITHM" default:"HS256"`
+	JWTSecret           string `envconfig:"JWT_SECRET"`
+	JWTPrivateKeyFile   string `envconfig:"JWT_PRIVATE_KEY_FILE"`
+	JWTPublicKeyFile    string `envconfig:"JWT_PUBLIC_KEY_FILE"`
 	JWTExpirationHours  int    `envconfig:"JWT_EXPIRATION_HOURS" default:"24"`
+	JWTRefreshExpirationHours int `envconfig:"JWT_REFRESH_EXPIRATION_HOURS" default:"168"`
+	JWTRememberExpirationHours int `envconfig:"JWT_REMEMBER_EXPIRATION_HOURS" default:"720"`
+	
+	// Username Configuration: allow letters and digits from any script instead of only ASCII
+	AllowUnicodeUsernames bool `envconfig:"ALLOW_UNICODE_USERNAMES" default:"false"`
+	
+	// Password Hashing Configuration
+	PasswordAlgo string `envconfig:"PASSWORD_ALGO" default:"bcrypt"`
+	// Work factor of new bcrypt hashes (4-31); each step doubles the hashing time
+	BcryptCost int `envconfig:"BCRYPT_COST" default:"12"`
+	// Changing the pepper invalidates every existing password hash
+	PasswordPepper string `envconfig:"PASSWORD_PEPPER" default:""`
+	
+	// Password Policy Configuration
+	PasswordMinLength      int  `envconfig:"PASSWORD_MIN_LENGTH" default:"8"`
+	PasswordRequireSpecial bool `envconfig:"PASSWORD_REQUIRE_SPECIAL" default:"false"`
+	// Out of uppercase, lowercase, digits and special characters
+	PasswordMinCharClasses int `envconfig:"PASSWORD_MIN_CHAR_CLASSES" default:"3"`
+	
+	// Account Lockout Configuration
+	MaxFailedLogins        int `envconfig:"MAX_FAILED_LOGINS" default:"5"`
+	LockoutDurationMinutes int `envconfig:"LOCKOUT_DURATION_MINUTES" default:"30"`
+	// Failed logins allowed from one IP address per window, across all accounts; 0 disables
+	MaxFailedLoginsPerIP int `envconfig:"MAX_FAILED_LOGINS_PER_IP" default:"20"`
+	LoginIPWindowMinutes int `envconfig:"LOGIN_IP_WINDOW_MINUTES" default:"15"`
 	
 	// API Configuration
 	RateLimitPerMinute int `envconfig:"RATE_LIMIT_PER_MINUTE" default:"100"`
+	// Largest page size clients may request when listing
+	MaxPageLimit int `envconfig:"MAX_PAGE_LIMIT" default:"100"`
+	// Comma-separated origins browsers may call the API from, or "*"; empty disables CORS
+	CORSAllowedOrigins string `envconfig:"CORS_ALLOWED_ORIGINS" default:""`
+	// Comma-separated CIDRs or addresses of proxies whose X-Forwarded-For and X-Real-IP
+	// headers are trusted; empty uses the connection address as the client IP
+	TrustedProxies string `envconfig:"TRUSTED_PROXIES" default:""`
+	
+	// Presence Configuration
+	// Users active within this many minutes are reported as online
+	OnlineWindowMinutes int `envconfig:"ONLINE_WINDOW_MINUTES" default:"5"`
+	// How long browsers and CDNs may serve a public profile without revalidating
+	ProfileCacheMaxAgeSeconds int `envconfig:"PROFILE_CACHE_MAX_AGE_SECONDS" default:"60"`
+	
+	// Soft-Delete Cleanup Configuration
+	// Soft-deleted users are permanently removed once they have been deleted this many days
+	SoftDeleteRetentionDays int `envconfig:"SOFT_DELETE_RETENTION_DAYS" default:"30"`
+	CleanupIntervalHours    int `envconfig:"CLEANUP_INTERVAL_HOURS" default:"24"`
 	
 	// Logging Configuration
 	LogLevel string `envconfig:"LOG_LEVEL" default:"info"`
+	
+	// Tracing Configuration
+	TracingEnabled bool   `envconfig:"TRACING_ENABLED" default:"false"`
+	OTLPEndpoint   string `envconfig:"OTEL_EXPORTER_OTLP_ENDPOINT" default:"http://localhost:4318"`
+	ServiceName    string `envconfig:"OTEL_SERVICE_NAME" default:"go-template"`
+	
+	// Webhook Configuration
+	// User lifecycle events are POSTed to WebhookURL when it is set, signed with WebhookSecret
+	WebhookURL    string `envconfig:"WEBHOOK_URL" default:""`
+	WebhookSecret string `envconfig:"WEBHOOK_SECRET" default:""`
+	
+	// Upload Configuration
+	AvatarStorageDir string `envconfig:"AVATAR_STORAGE_DIR" default:"./uploads/avatars"`
+	AvatarBaseURL    string `envconfig:"AVATAR_BASE_URL" default:"/uploads/avatars"`
 }
 
+// instance is the process-wide config used by the main binary; use New for isolated configs
 var instance *Config
 
-// Load loads configuration from environment variables
-// It tries to load from .env file first, then from environment
+// Load loads configuration from environment variables and caches it for Get
+// It tries to load from .env file first, then from environment.
+// When CONFIG_FILE is set the file is loaded with environment variables overriding it.
 func Load() *Config {
 	if instance != nil {
 		return instance
@@ -49,17 +152,23 @@ func Load() *Config {
 		log.Printf("Warning: .env file not found or could not be loaded: %v", err)
 	}
 
-	instance = &Config{}
-	
-	// Process environment variables into config struct
-	if err := envconfig.Process("", instance); err != nil {
-		log.Fatalf("Failed to process environment variables: %v", err)
+	// Delegate to the config file when one is configured
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		cfg, err := LoadFromFile(path)
+		if err != nil {
+			log.Fatalf("Failed to load config file: %v", err)
+		}
+		instance = cfg
+		log.Printf("Configuration loaded successfully from %s for environment: %s", path, instance.Environment)
+		return instance
 	}
 
-	// Validate required configurations
-	if err := instance.validate(); err != nil {
-		log.Fatalf("Configuration validation failed: %v", err)
+	// Process environment variables into config struct
+	cfg, err := New(WithEnv())
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
 	}
+	instance = cfg
 
 	log.Printf("Configuration loaded successfully for environment: %s", instance.Environment)
 	return instance
@@ -74,26 +183,173 @@ func Get() *Config {
 }
 
 // validate performs basic validation on the configuration
+// Every invalid field is reported so misconfigurations can be fixed in one pass
 func (c *Config) validate() error {
-	// Add custom validation logic here
+	var errs []error
+	
 	if c.MongoURL == "" {
-		return fmt.Errorf("MONGO_URL is required")
+		errs = append(errs, fmt.Errorf("MONGO_URL is required"))
+	}
+	
+	if c.MongoReadURL != "" && !strings.HasPrefix(c.MongoReadURL, "mongodb://") && !strings.HasPrefix(c.MongoReadURL, "mongodb+srv://") {
+		errs = append(errs, fmt.Errorf("MONGO_READ_URL must be a mongodb:// or mongodb+srv:// URL"))
+	}
+	
+	// Validate cache driver; only the redis driver needs a server
+	switch c.CacheDriver {
+	case "redis":
+		if c.RedisURL == "" {
+			errs = append(errs, fmt.Errorf("REDIS_URL is required when CACHE_DRIVER is redis"))
+		}
+	case "memory":
+	default:
+		errs = append(errs, fmt.Errorf("CACHE_DRIVER must be either redis or memory, got %q", c.CacheDriver))
+	}
+	
+	if c.CacheTTLJitterPercent < 0 || c.CacheTTLJitterPercent > 50 {
+		errs = append(errs, fmt.Errorf("CACHE_TTL_JITTER_PERCENT must be between 0 and 50, got %d", c.CacheTTLJitterPercent))
+	}
+	if c.CacheWarmCount < 0 {
+		errs = append(errs, fmt.Errorf("CACHE_WARM_COUNT must not be negative, got %d", c.CacheWarmCount))
+	}
+	
+	// Redis ships with 16 logical databases by default
+	if c.RedisDB < 0 || c.RedisDB > 15 {
+		errs = append(errs, fmt.Errorf("REDIS_DB must be between 0 and 15, got %d", c.RedisDB))
+	}
+	
+	// Pool bounds
+	if c.MongoMaxPoolSize < 1 {
+		errs = append(errs, fmt.Errorf("MONGO_MAX_POOL_SIZE must be at least 1, got %d", c.MongoMaxPoolSize))
+	}
+	if c.MongoMinPoolSize < 0 || c.MongoMinPoolSize > c.MongoMaxPoolSize {
+		errs = append(errs, fmt.Errorf("MONGO_MIN_POOL_SIZE must be between 0 and MONGO_MAX_POOL_SIZE (%d), got %d", c.MongoMaxPoolSize, c.MongoMinPoolSize))
+	}
+	if c.RedisPoolSize < 1 {
+		errs = append(errs, fmt.Errorf("REDIS_POOL_SIZE must be at least 1, got %d", c.RedisPoolSize))
+	}
+	if c.RedisMinIdleConns < 0 || c.RedisMinIdleConns > c.RedisPoolSize {
+		errs = append(errs, fmt.Errorf("REDIS_MIN_IDLE_CONNS must be between 0 and REDIS_POOL_SIZE (%d), got %d", c.RedisPoolSize, c.RedisMinIdleConns))
 	}
 	
-	if c.RedisURL == "" {
-		return fmt.Errorf("REDIS_URL is required")
+	if port, err := strconv.Atoi(c.Port); err != nil || port < 1 || port > 65535 {
+		errs = append(errs, fmt.Errorf("PORT must be a valid TCP port (1-65535), got %q", c.Port))
 	}
 	
-	if c.JWTSecret == "" {
-		return fmt.Errorf("JWT_SECRET is required")
+	if c.RequestTimeoutSeconds < 0 {
+		errs = append(errs, fmt.Errorf("REQUEST_TIMEOUT_SECONDS must not be negative, got %d", c.RequestTimeoutSeconds))
+	}
+	if c.ServerReadTimeout < 0 {
+		errs = append(errs, fmt.Errorf("SERVER_READ_TIMEOUT must not be negative, got %s", c.ServerReadTimeout))
+	}
+	if c.ServerWriteTimeout < 0 {
+		errs = append(errs, fmt.Errorf("SERVER_WRITE_TIMEOUT must not be negative, got %s", c.ServerWriteTimeout))
+	}
+	if c.ServerIdleTimeout < 0 {
+		errs = append(errs, fmt.Errorf("SERVER_IDLE_TIMEOUT must not be negative, got %s", c.ServerIdleTimeout))
+	}
+	// Unlike the others this one has no "disabled" value, it is the Slowloris guard
+	if c.ServerReadHeaderTimeout <= 0 {
+		errs = append(errs, fmt.Errorf("SERVER_READ_HEADER_TIMEOUT must be positive, got %s", c.ServerReadHeaderTimeout))
 	}
 	
-	// Validate JWT secret length (minimum 32 characters for security)
-	if len(c.JWTSecret) < 32 {
-		return fmt.Errorf("JWT_SECRET must be at least 32 characters long")
+	if c.StartupRetryAttempts < 1 {
+		errs = append(errs, fmt.Errorf("STARTUP_RETRY_ATTEMPTS must be at least 1, got %d", c.StartupRetryAttempts))
+	}
+	if c.StartupRetryBackoff < 0 {
+		errs = append(errs, fmt.Errorf("STARTUP_RETRY_BACKOFF must not be negative, got %s", c.StartupRetryBackoff))
 	}
 	
-	return nil
+	// Validate the JWT signing key for the configured algorithm
+	switch c.JWTAlgorithm {
+	case "HS256":
+		// Minimum 32 characters for security
+		if c.JWTSecret == "" {
+			errs = append(errs, fmt.Errorf("JWT_SECRET is required"))
+		} else if len(c.JWTSecret) < 32 {
+			errs = append(errs, fmt.Errorf("JWT_SECRET must be at least 32 characters long"))
+		}
+	case "RS256":
+		if c.JWTPrivateKeyFile == "" {
+			errs = append(errs, fmt.Errorf("JWT_PRIVATE_KEY_FILE is required when JWT_ALGORITHM is RS256"))
+		}
+		if c.JWTPublicKeyFile == "" {
+			errs = append(errs, fmt.Errorf("JWT_PUBLIC_KEY_FILE is required when JWT_ALGORITHM is RS256"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("JWT_ALGORITHM must be HS256 or RS256, got %q", c.JWTAlgorithm))
+	}
+	
+	if c.JWTExpirationHours <= 0 {
+		errs = append(errs, fmt.Errorf("JWT_EXPIRATION_HOURS must be greater than 0, got %d", c.JWTExpirationHours))
+	}
+	
+	if c.MaxFailedLoginsPerIP < 0 {
+		errs = append(errs, fmt.Errorf("MAX_FAILED_LOGINS_PER_IP must not be negative, got %d", c.MaxFailedLoginsPerIP))
+	}
+	if c.MaxFailedLoginsPerIP > 0 && c.LoginIPWindowMinutes <= 0 {
+		errs = append(errs, fmt.Errorf("LOGIN_IP_WINDOW_MINUTES must be greater than 0, got %d", c.LoginIPWindowMinutes))
+	}
+	
+	if c.RateLimitPerMinute <= 0 {
+		errs = append(errs, fmt.Errorf("RATE_LIMIT_PER_MINUTE must be greater than 0, got %d", c.RateLimitPerMinute))
+	}
+	
+	if c.MaxPageLimit < 20 {
+		errs = append(errs, fmt.Errorf("MAX_PAGE_LIMIT must be at least the default page size of 20, got %d", c.MaxPageLimit))
+	}
+	
+	if c.OnlineWindowMinutes <= 0 {
+		errs = append(errs, fmt.Errorf("ONLINE_WINDOW_MINUTES must be greater than 0, got %d", c.OnlineWindowMinutes))
+	}
+	if c.ProfileCacheMaxAgeSeconds < 0 {
+		errs = append(errs, fmt.Errorf("PROFILE_CACHE_MAX_AGE_SECONDS must not be negative, got %d", c.ProfileCacheMaxAgeSeconds))
+	}
+	
+	if c.SoftDeleteRetentionDays <= 0 {
+		errs = append(errs, fmt.Errorf("SOFT_DELETE_RETENTION_DAYS must be greater than 0, got %d", c.SoftDeleteRetentionDays))
+	}
+	
+	if c.CleanupIntervalHours <= 0 {
+		errs = append(errs, fmt.Errorf("CLEANUP_INTERVAL_HOURS must be greater than 0, got %d", c.CleanupIntervalHours))
+	}
+	
+	for _, proxy := range c.GetTrustedProxies() {
+		if _, err := netip.ParsePrefix(proxy); err != nil {
+			if _, err := netip.ParseAddr(proxy); err != nil {
+				errs = append(errs, fmt.Errorf("TRUSTED_PROXIES entries must be CIDRs or IP addresses, got %q", proxy))
+			}
+		}
+	}
+	
+	// Validate webhook target; deliveries must be signed
+	if c.WebhookURL != "" {
+		if u, err := url.Parse(c.WebhookURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, fmt.Errorf("WEBHOOK_URL must be an absolute http or https URL, got %q", c.WebhookURL))
+		}
+		if len(c.WebhookSecret) < 32 {
+			errs = append(errs, fmt.Errorf("WEBHOOK_SECRET must be at least 32 characters long when WEBHOOK_URL is set"))
+		}
+	}
+	
+	// Validate password hashing algorithm
+	if c.PasswordAlgo != "bcrypt" && c.PasswordAlgo != "argon2id" {
+		errs = append(errs, fmt.Errorf("PASSWORD_ALGO must be either bcrypt or argon2id"))
+	}
+	if c.BcryptCost < 4 || c.BcryptCost > 31 {
+		errs = append(errs, fmt.Errorf("BCRYPT_COST must be between 4 and 31, got %d", c.BcryptCost))
+	}
+	
+	// Validate password policy; it may only be made stricter than the defaults
+	if c.PasswordMinLength < 8 || c.PasswordMinLength > 128 {
+		errs = append(errs, fmt.Errorf("PASSWORD_MIN_LENGTH must be between 8 and 128"))
+	}
+	
+	if c.PasswordMinCharClasses < 3 || c.PasswordMinCharClasses > 4 {
+		errs = append(errs, fmt.Errorf("PASSWORD_MIN_CHAR_CLASSES must be 3 or 4"))
+	}
+	
+	return errors.Join(errs...)
 }
 
 // IsDevelopment returns true if running in development mode
@@ -111,6 +367,83 @@ func (c *Config) IsTest() bool {
 	return c.Environment == "test"
 }
 
+// GetRequestTimeout returns the per-request handler deadline; zero means no limit
+func (c *Config) GetRequestTimeout() time.Duration {
+	return time.Duration(c.RequestTimeoutSeconds) * time.Second
+}
+
+// GetLockoutDuration returns the account lockout window as a time.Duration
+func (c *Config) GetLockoutDuration() time.Duration {
+	return time.Duration(c.LockoutDurationMinutes) * time.Minute
+}
+
+// GetLoginIPWindow returns the window in which failed logins per IP address are counted
+func (c *Config) GetLoginIPWindow() time.Duration {
+	return time.Duration(c.LoginIPWindowMinutes) * time.Minute
+}
+
+// GetJWTExpiration returns the access token lifetime as a time.Duration
+func (c *Config) GetJWTExpiration() time.Duration {
+	return time.Duration(c.JWTExpirationHours) * time.Hour
+}
+
+// GetJWTRefreshExpiration returns the refresh token lifetime as a time.Duration
+func (c *Config) GetJWTRefreshExpiration() time.Duration {
+	return time.Duration(c.JWTRefreshExpirationHours) * time.Hour
+}
+
+// GetJWTRememberExpiration returns the refresh token lifetime for "remember me" logins
+func (c *Config) GetJWTRememberExpiration() time.Duration {
+	return time.Duration(c.JWTRememberExpirationHours) * time.Hour
+}
+
+// GetOnlineWindow returns how recently a user must have been active to count as online
+func (c *Config) GetOnlineWindow() time.Duration {
+	return time.Duration(c.OnlineWindowMinutes) * time.Minute
+}
+
+// GetCacheTTLJitter returns the fraction by which cache TTLs may vary, e.g. 0.1 for ±10%
+func (c *Config) GetCacheTTLJitter() float64 {
+	return float64(c.CacheTTLJitterPercent) / 100
+}
+
+// GetCORSAllowedOrigins returns the origins allowed to call the API from a browser
+func (c *Config) GetCORSAllowedOrigins() []string {
+	var origins []string
+	for _, origin := range strings.Split(c.CORSAllowedOrigins, ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			origins = append(origins, origin)
+		}
+	}
+	return origins
+}
+
+// GetTrustedProxies returns the CIDRs and addresses of proxies trusted to report the client IP
+func (c *Config) GetTrustedProxies() []string {
+	var proxies []string
+	for _, proxy := range strings.Split(c.TrustedProxies, ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			proxies = append(proxies, proxy)
+		}
+	}
+	return proxies
+}
+
+// GetProfileCacheMaxAge returns how long public profiles may be cached by clients and CDNs
+func (c *Config) GetProfileCacheMaxAge() time.Duration {
+	return time.Duration(c.ProfileCacheMaxAgeSeconds) * time.Second
+}
+
+// GetSoftDeleteRetention returns how long soft-deleted users are kept before cleanup removes them
+func (c *Config) GetSoftDeleteRetention() time.Duration {
+	return time.Duration(c.SoftDeleteRetentionDays) * 24 * time.Hour
+}
+
+// GetCleanupInterval returns how often soft-deleted users are cleaned up
+func (c *Config) GetCleanupInterval() time.Duration {
+	return time.Duration(c.CleanupIntervalHours) * time.Hour
+}
+
 // GetServerAddress returns the complete server address
 func (c *Config) GetServerAddress() string {
 	return ":" + c.Port
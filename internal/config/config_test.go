@@ -0,0 +1,404 @@
+// internal/config/config_test.go
+package config
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// validValues is the smallest set of values that passes validation
+func validValues() map[string]string {
+	return map[string]string{
+		"MONGO_URL":  "mongodb://localhost:27017",
+		"REDIS_URL":  "redis://localhost:6379",
+		"JWT_SECRET": "test-secret-test-secret-test-secret",
+	}
+}
+
+// withOverrides returns validValues with overrides applied
+func withOverrides(overrides map[string]string) map[string]string {
+	values := validValues()
+	for key, value := range overrides {
+		values[key] = value
+	}
+	return values
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name      string
+		overrides map[string]string
+		wantErrs  []string
+	}{
+		{name: "valid config"},
+		{name: "redis db lower bound", overrides: map[string]string{"REDIS_DB": "0"}},
+		{name: "redis db upper bound", overrides: map[string]string{"REDIS_DB": "15"}},
+		{name: "negative redis db", overrides: map[string]string{"REDIS_DB": "-1"}, wantErrs: []string{"REDIS_DB must be between 0 and 15, got -1"}},
+		{name: "redis db too high", overrides: map[string]string{"REDIS_DB": "16"}, wantErrs: []string{"REDIS_DB must be between 0 and 15, got 16"}},
+		{name: "zero rate limit", overrides: map[string]string{"RATE_LIMIT_PER_MINUTE": "0"}, wantErrs: []string{"RATE_LIMIT_PER_MINUTE must be greater than 0"}},
+		{name: "negative rate limit", overrides: map[string]string{"RATE_LIMIT_PER_MINUTE": "-5"}, wantErrs: []string{"RATE_LIMIT_PER_MINUTE must be greater than 0"}},
+		{name: "zero jwt expiration", overrides: map[string]string{"JWT_EXPIRATION_HOURS": "0"}, wantErrs: []string{"JWT_EXPIRATION_HOURS must be greater than 0"}},
+		{name: "non-numeric port", overrides: map[string]string{"PORT": "http"}, wantErrs: []string{`PORT must be a valid TCP port (1-65535), got "http"`}},
+		{name: "port zero", overrides: map[string]string{"PORT": "0"}, wantErrs: []string{"PORT must be a valid TCP port"}},
+		{name: "port too high", overrides: map[string]string{"PORT": "65536"}, wantErrs: []string{"PORT must be a valid TCP port"}},
+		{name: "highest port", overrides: map[string]string{"PORT": "65535"}},
+		{name: "memory cache needs no redis", overrides: map[string]string{"CACHE_DRIVER": "memory", "REDIS_URL": ""}},
+		{name: "redis cache needs a url", overrides: map[string]string{"CACHE_DRIVER": "redis", "REDIS_URL": ""}, wantErrs: []string{"REDIS_URL is required when CACHE_DRIVER is redis"}},
+		{name: "unknown cache driver", overrides: map[string]string{"CACHE_DRIVER": "memcached"}, wantErrs: []string{`CACHE_DRIVER must be either redis or memory, got "memcached"`}},
+		{name: "webhook", overrides: map[string]string{"WEBHOOK_URL": "https://hooks.example.com/users", "WEBHOOK_SECRET": strings.Repeat("s", 32)}},
+		{name: "relative webhook url", overrides: map[string]string{"WEBHOOK_URL": "/hooks", "WEBHOOK_SECRET": strings.Repeat("s", 32)}, wantErrs: []string{"WEBHOOK_URL must be an absolute http or https URL"}},
+		{name: "webhook url with another scheme", overrides: map[string]string{"WEBHOOK_URL": "ftp://hooks.example.com", "WEBHOOK_SECRET": strings.Repeat("s", 32)}, wantErrs: []string{"WEBHOOK_URL must be an absolute http or https URL"}},
+		{name: "webhook without a secret", overrides: map[string]string{"WEBHOOK_URL": "https://hooks.example.com/users"}, wantErrs: []string{"WEBHOOK_SECRET must be at least 32 characters long"}},
+		{name: "secret without a webhook", overrides: map[string]string{"WEBHOOK_SECRET": "short"}},
+		{name: "request timeout disabled", overrides: map[string]string{"REQUEST_TIMEOUT_SECONDS": "0"}},
+		{name: "negative request timeout", overrides: map[string]string{"REQUEST_TIMEOUT_SECONDS": "-1"}, wantErrs: []string{"REQUEST_TIMEOUT_SECONDS must not be negative, got -1"}},
+		{name: "stricter password policy", overrides: map[string]string{"PASSWORD_MIN_LENGTH": "16", "PASSWORD_REQUIRE_SPECIAL": "true", "PASSWORD_MIN_CHAR_CLASSES": "4"}},
+		{name: "password min length below default", overrides: map[string]string{"PASSWORD_MIN_LENGTH": "7"}, wantErrs: []string{"PASSWORD_MIN_LENGTH must be between 8 and 128"}},
+		{name: "password min length too high", overrides: map[string]string{"PASSWORD_MIN_LENGTH": "129"}, wantErrs: []string{"PASSWORD_MIN_LENGTH must be between 8 and 128"}},
+		{name: "too few password char classes", overrides: map[string]string{"PASSWORD_MIN_CHAR_CLASSES": "2"}, wantErrs: []string{"PASSWORD_MIN_CHAR_CLASSES must be 3 or 4"}},
+		{name: "too many password char classes", overrides: map[string]string{"PASSWORD_MIN_CHAR_CLASSES": "5"}, wantErrs: []string{"PASSWORD_MIN_CHAR_CLASSES must be 3 or 4"}},
+		{name: "soft delete retention", overrides: map[string]string{"SOFT_DELETE_RETENTION_DAYS": "90", "CLEANUP_INTERVAL_HOURS": "6"}},
+		{name: "zero soft delete retention", overrides: map[string]string{"SOFT_DELETE_RETENTION_DAYS": "0"}, wantErrs: []string{"SOFT_DELETE_RETENTION_DAYS must be greater than 0, got 0"}},
+		{name: "negative cleanup interval", overrides: map[string]string{"CLEANUP_INTERVAL_HOURS": "-1"}, wantErrs: []string{"CLEANUP_INTERVAL_HOURS must be greater than 0, got -1"}},
+		{name: "larger max page limit", overrides: map[string]string{"MAX_PAGE_LIMIT": "500"}},
+		{name: "max page limit at the default page size", overrides: map[string]string{"MAX_PAGE_LIMIT": "20"}},
+		{name: "max page limit below the default page size", overrides: map[string]string{"MAX_PAGE_LIMIT": "19"}, wantErrs: []string{"MAX_PAGE_LIMIT must be at least the default page size of 20, got 19"}},
+		{name: "read url", overrides: map[string]string{"MONGO_READ_URL": "mongodb://replica:27017/?readPreference=secondaryPreferred"}},
+		{name: "srv read url", overrides: map[string]string{"MONGO_READ_URL": "mongodb+srv://cluster.example.com"}},
+		{name: "read url with another scheme", overrides: map[string]string{"MONGO_READ_URL": "postgres://replica"}, wantErrs: []string{"MONGO_READ_URL must be a mongodb:// or mongodb+srv:// URL"}},
+		{name: "custom pool sizes", overrides: map[string]string{"MONGO_MAX_POOL_SIZE": "50", "MONGO_MIN_POOL_SIZE": "50", "REDIS_POOL_SIZE": "20", "REDIS_MIN_IDLE_CONNS": "0"}},
+		{name: "zero mongo max pool size", overrides: map[string]string{"MONGO_MAX_POOL_SIZE": "0", "MONGO_MIN_POOL_SIZE": "0"}, wantErrs: []string{"MONGO_MAX_POOL_SIZE must be at least 1, got 0"}},
+		{name: "mongo min pool size above max", overrides: map[string]string{"MONGO_MAX_POOL_SIZE": "5", "MONGO_MIN_POOL_SIZE": "6"}, wantErrs: []string{"MONGO_MIN_POOL_SIZE must be between 0 and MONGO_MAX_POOL_SIZE (5), got 6"}},
+		{name: "negative mongo min pool size", overrides: map[string]string{"MONGO_MIN_POOL_SIZE": "-1"}, wantErrs: []string{"MONGO_MIN_POOL_SIZE must be between 0 and MONGO_MAX_POOL_SIZE (100), got -1"}},
+		{name: "zero redis pool size", overrides: map[string]string{"REDIS_POOL_SIZE": "0", "REDIS_MIN_IDLE_CONNS": "0"}, wantErrs: []string{"REDIS_POOL_SIZE must be at least 1, got 0"}},
+		{name: "redis min idle conns above pool size", overrides: map[string]string{"REDIS_POOL_SIZE": "5", "REDIS_MIN_IDLE_CONNS": "6"}, wantErrs: []string{"REDIS_MIN_IDLE_CONNS must be between 0 and REDIS_POOL_SIZE (5), got 6"}},
+		{name: "negative redis min idle conns", overrides: map[string]string{"REDIS_MIN_IDLE_CONNS": "-1"}, wantErrs: []string{"REDIS_MIN_IDLE_CONNS must be between 0 and REDIS_POOL_SIZE (100), got -1"}},
+		{name: "no ttl jitter", overrides: map[string]string{"CACHE_TTL_JITTER_PERCENT": "0"}},
+		{name: "maximum ttl jitter", overrides: map[string]string{"CACHE_TTL_JITTER_PERCENT": "50"}},
+		{name: "ttl jitter above 50", overrides: map[string]string{"CACHE_TTL_JITTER_PERCENT": "51"}, wantErrs: []string{"CACHE_TTL_JITTER_PERCENT must be between 0 and 50, got 51"}},
+		{name: "negative ttl jitter", overrides: map[string]string{"CACHE_TTL_JITTER_PERCENT": "-1"}, wantErrs: []string{"CACHE_TTL_JITTER_PERCENT must be between 0 and 50, got -1"}},
+		{name: "profile caching disabled", overrides: map[string]string{"PROFILE_CACHE_MAX_AGE_SECONDS": "0"}},
+		{name: "negative profile max age", overrides: map[string]string{"PROFILE_CACHE_MAX_AGE_SECONDS": "-1"}, wantErrs: []string{"PROFILE_CACHE_MAX_AGE_SECONDS must not be negative, got -1"}},
+		{name: "ip login throttling disabled", overrides: map[string]string{"MAX_FAILED_LOGINS_PER_IP": "0", "LOGIN_IP_WINDOW_MINUTES": "0"}},
+		{name: "negative failed logins per ip", overrides: map[string]string{"MAX_FAILED_LOGINS_PER_IP": "-1"}, wantErrs: []string{"MAX_FAILED_LOGINS_PER_IP must not be negative, got -1"}},
+		{name: "ip throttling without a window", overrides: map[string]string{"MAX_FAILED_LOGINS_PER_IP": "20", "LOGIN_IP_WINDOW_MINUTES": "0"}, wantErrs: []string{"LOGIN_IP_WINDOW_MINUTES must be greater than 0, got 0"}},
+		{name: "server timeouts disabled", overrides: map[string]string{"SERVER_READ_TIMEOUT": "0", "SERVER_WRITE_TIMEOUT": "0", "SERVER_IDLE_TIMEOUT": "0"}},
+		{name: "negative server read timeout", overrides: map[string]string{"SERVER_READ_TIMEOUT": "-1s"}, wantErrs: []string{"SERVER_READ_TIMEOUT must not be negative, got -1s"}},
+		{name: "negative server write timeout", overrides: map[string]string{"SERVER_WRITE_TIMEOUT": "-1s"}, wantErrs: []string{"SERVER_WRITE_TIMEOUT must not be negative, got -1s"}},
+		{name: "negative server idle timeout", overrides: map[string]string{"SERVER_IDLE_TIMEOUT": "-1m"}, wantErrs: []string{"SERVER_IDLE_TIMEOUT must not be negative, got -1m0s"}},
+		{name: "read header timeout cannot be disabled", overrides: map[string]string{"SERVER_READ_HEADER_TIMEOUT": "0"}, wantErrs: []string{"SERVER_READ_HEADER_TIMEOUT must be positive, got 0s"}},
+		{name: "hs256 without a secret", overrides: map[string]string{"JWT_SECRET": ""}, wantErrs: []string{"JWT_SECRET is required"}},
+		{name: "short hs256 secret", overrides: map[string]string{"JWT_SECRET": "short"}, wantErrs: []string{"JWT_SECRET must be at least 32 characters long"}},
+		{name: "rs256 needs no secret", overrides: map[string]string{"JWT_ALGORITHM": "RS256", "JWT_SECRET": "", "JWT_PRIVATE_KEY_FILE": "/keys/jwt.pem", "JWT_PUBLIC_KEY_FILE": "/keys/jwt.pub"}},
+		{
+			name:      "rs256 without key files",
+			overrides: map[string]string{"JWT_ALGORITHM": "RS256"},
+			wantErrs:  []string{"JWT_PRIVATE_KEY_FILE is required when JWT_ALGORITHM is RS256", "JWT_PUBLIC_KEY_FILE is required when JWT_ALGORITHM is RS256"},
+		},
+		{name: "none algorithm", overrides: map[string]string{"JWT_ALGORITHM": "none"}, wantErrs: []string{`JWT_ALGORITHM must be HS256 or RS256, got "none"`}},
+		{name: "unsupported algorithm", overrides: map[string]string{"JWT_ALGORITHM": "ES256"}, wantErrs: []string{`JWT_ALGORITHM must be HS256 or RS256, got "ES256"`}},
+		{name: "lowercase algorithm", overrides: map[string]string{"JWT_ALGORITHM": "rs256"}, wantErrs: []string{`JWT_ALGORITHM must be HS256 or RS256, got "rs256"`}},
+		{name: "lowest bcrypt cost", overrides: map[string]string{"BCRYPT_COST": "4"}},
+		{name: "highest bcrypt cost", overrides: map[string]string{"BCRYPT_COST": "31"}},
+		{name: "bcrypt cost too low", overrides: map[string]string{"BCRYPT_COST": "3"}, wantErrs: []string{"BCRYPT_COST must be between 4 and 31, got 3"}},
+		{name: "bcrypt cost too high", overrides: map[string]string{"BCRYPT_COST": "32"}, wantErrs: []string{"BCRYPT_COST must be between 4 and 31, got 32"}},
+		{name: "cache warming", overrides: map[string]string{"CACHE_WARM_COUNT": "500"}},
+		{name: "negative cache warm count", overrides: map[string]string{"CACHE_WARM_COUNT": "-1"}, wantErrs: []string{"CACHE_WARM_COUNT must not be negative, got -1"}},
+		{name: "single startup attempt", overrides: map[string]string{"STARTUP_RETRY_ATTEMPTS": "1", "STARTUP_RETRY_BACKOFF": "0s"}},
+		{name: "no startup attempts", overrides: map[string]string{"STARTUP_RETRY_ATTEMPTS": "0"}, wantErrs: []string{"STARTUP_RETRY_ATTEMPTS must be at least 1, got 0"}},
+		{name: "negative startup backoff", overrides: map[string]string{"STARTUP_RETRY_BACKOFF": "-1s"}, wantErrs: []string{"STARTUP_RETRY_BACKOFF must not be negative, got -1s"}},
+		{name: "trusted proxies", overrides: map[string]string{"TRUSTED_PROXIES": "10.0.0.0/8, 192.0.2.10, 2001:db8::/32"}},
+		{name: "trusted proxy hostname", overrides: map[string]string{"TRUSTED_PROXIES": "10.0.0.0/8,proxy.internal"}, wantErrs: []string{`TRUSTED_PROXIES entries must be CIDRs or IP addresses, got "proxy.internal"`}},
+		{name: "trusted proxy cidr out of range", overrides: map[string]string{"TRUSTED_PROXIES": "10.0.0.0/33"}, wantErrs: []string{`TRUSTED_PROXIES entries must be CIDRs or IP addresses, got "10.0.0.0/33"`}},
+		{
+			name: "every invalid field is reported",
+			overrides: map[string]string{
+				"REDIS_DB":              "99",
+				"RATE_LIMIT_PER_MINUTE": "0",
+				"JWT_EXPIRATION_HOURS":  "-1",
+				"PORT":                  "abc",
+			},
+			wantErrs: []string{"REDIS_DB", "RATE_LIMIT_PER_MINUTE", "JWT_EXPIRATION_HOURS", "PORT"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := New(WithValues(withOverrides(tt.overrides)))
+			if len(tt.wantErrs) == 0 {
+				if err != nil {
+					t.Fatalf("New() error = %v", err)
+				}
+				if cfg == nil {
+					t.Fatal("New() = nil config")
+				}
+				return
+			}
+
+			if err == nil {
+				t.Fatal("New() error = nil, want validation errors")
+			}
+			for _, want := range tt.wantErrs {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("New() error = %q, want it to mention %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestNewIsIsolated(t *testing.T) {
+	instance = nil
+	t.Cleanup(func() { instance = nil })
+
+	first, err := New(WithValues(withOverrides(map[string]string{"PORT": "8081", "REDIS_DB": "1"})))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	second, err := New(WithValues(withOverrides(map[string]string{"PORT": "8082"})))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	if first == second {
+		t.Fatal("New() returned the same *Config twice")
+	}
+	tests := []struct {
+		name          string
+		cfg           *Config
+		wantPort      string
+		wantRedisDB   int
+		wantServeAddr string
+	}{
+		{name: "first", cfg: first, wantPort: "8081", wantRedisDB: 1, wantServeAddr: ":8081"},
+		{name: "second", cfg: second, wantPort: "8082", wantRedisDB: 0, wantServeAddr: ":8082"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.cfg.Port != tt.wantPort || tt.cfg.RedisDB != tt.wantRedisDB {
+				t.Errorf("Port = %q, RedisDB = %d, want %q, %d", tt.cfg.Port, tt.cfg.RedisDB, tt.wantPort, tt.wantRedisDB)
+			}
+			if got := tt.cfg.GetServerAddress(); got != tt.wantServeAddr {
+				t.Errorf("GetServerAddress() = %q, want %q", got, tt.wantServeAddr)
+			}
+		})
+	}
+
+	if instance != nil {
+		t.Error("New() set the package-level config instance")
+	}
+}
+
+func TestGetRequestTimeout(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  time.Duration
+	}{
+		{name: "default", want: 10 * time.Second},
+		{name: "configured", value: "30", want: 30 * time.Second},
+		{name: "disabled", value: "0", want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			overrides := map[string]string{}
+			if tt.value != "" {
+				overrides["REQUEST_TIMEOUT_SECONDS"] = tt.value
+			}
+			cfg, err := New(WithValues(withOverrides(overrides)))
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			if got := cfg.GetRequestTimeout(); got != tt.want {
+				t.Errorf("GetRequestTimeout() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetSoftDeleteCleanup(t *testing.T) {
+	tests := []struct {
+		name          string
+		overrides     map[string]string
+		wantRetention time.Duration
+		wantInterval  time.Duration
+	}{
+		{name: "defaults", wantRetention: 30 * 24 * time.Hour, wantInterval: 24 * time.Hour},
+		{name: "configured", overrides: map[string]string{"SOFT_DELETE_RETENTION_DAYS": "7", "CLEANUP_INTERVAL_HOURS": "1"}, wantRetention: 7 * 24 * time.Hour, wantInterval: time.Hour},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := New(WithValues(withOverrides(tt.overrides)))
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			if got := cfg.GetSoftDeleteRetention(); got != tt.wantRetention {
+				t.Errorf("GetSoftDeleteRetention() = %v, want %v", got, tt.wantRetention)
+			}
+			if got := cfg.GetCleanupInterval(); got != tt.wantInterval {
+				t.Errorf("GetCleanupInterval() = %v, want %v", got, tt.wantInterval)
+			}
+		})
+	}
+}
+
+func TestGetCacheTTLJitter(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  float64
+	}{
+		{name: "default", want: 0.1},
+		{name: "configured", value: "25", want: 0.25},
+		{name: "disabled", value: "0", want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			overrides := map[string]string{}
+			if tt.value != "" {
+				overrides["CACHE_TTL_JITTER_PERCENT"] = tt.value
+			}
+			cfg, err := New(WithValues(withOverrides(overrides)))
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			if got := cfg.GetCacheTTLJitter(); got != tt.want {
+				t.Errorf("GetCacheTTLJitter() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetCORSAllowedOrigins(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  []string
+	}{
+		{name: "default disables CORS", want: nil},
+		{name: "single origin", value: "https://app.example.com", want: []string{"https://app.example.com"}},
+		{name: "several origins", value: "https://app.example.com, https://admin.example.com", want: []string{"https://app.example.com", "https://admin.example.com"}},
+		{name: "empty entries are dropped", value: " ,https://app.example.com,, ", want: []string{"https://app.example.com"}},
+		{name: "any origin", value: "*", want: []string{"*"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			overrides := map[string]string{}
+			if tt.value != "" {
+				overrides["CORS_ALLOWED_ORIGINS"] = tt.value
+			}
+			cfg, err := New(WithValues(withOverrides(overrides)))
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			if got := cfg.GetCORSAllowedOrigins(); !slices.Equal(got, tt.want) {
+				t.Errorf("GetCORSAllowedOrigins() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetTrustedProxies(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  []string
+	}{
+		{name: "default trusts none", want: nil},
+		{name: "single cidr", value: "10.0.0.0/8", want: []string{"10.0.0.0/8"}},
+		{name: "cidrs and addresses", value: "10.0.0.0/8, 192.0.2.10", want: []string{"10.0.0.0/8", "192.0.2.10"}},
+		{name: "empty entries are dropped", value: " ,10.0.0.0/8,, ", want: []string{"10.0.0.0/8"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			overrides := map[string]string{}
+			if tt.value != "" {
+				overrides["TRUSTED_PROXIES"] = tt.value
+			}
+			cfg, err := New(WithValues(withOverrides(overrides)))
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			if got := cfg.GetTrustedProxies(); !slices.Equal(got, tt.want) {
+				t.Errorf("GetTrustedProxies() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFailOnIndexError(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  bool
+	}{
+		{name: "default fails fast", want: true},
+		{name: "tolerated", value: "false", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			overrides := map[string]string{}
+			if tt.value != "" {
+				overrides["FAIL_ON_INDEX_ERROR"] = tt.value
+			}
+			cfg, err := New(WithValues(withOverrides(overrides)))
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			if cfg.FailOnIndexError != tt.want {
+				t.Errorf("FailOnIndexError = %v, want %v", cfg.FailOnIndexError, tt.want)
+			}
+		})
+	}
+}
+
+func TestServerTimeouts(t *testing.T) {
+	tests := []struct {
+		name        string
+		overrides   map[string]string
+		want        [4]time.Duration // read, write, idle, read header
+		wantLoadErr string
+	}{
+		{name: "defaults", want: [4]time.Duration{15 * time.Second, 15 * time.Second, 60 * time.Second, 5 * time.Second}},
+		{
+			name:      "configured",
+			overrides: map[string]string{"SERVER_READ_TIMEOUT": "2m30s", "SERVER_WRITE_TIMEOUT": "10m", "SERVER_IDLE_TIMEOUT": "90s", "SERVER_READ_HEADER_TIMEOUT": "500ms"},
+			want:      [4]time.Duration{150 * time.Second, 10 * time.Minute, 90 * time.Second, 500 * time.Millisecond},
+		},
+		{
+			name:      "zero means no timeout",
+			overrides: map[string]string{"SERVER_READ_TIMEOUT": "0", "SERVER_WRITE_TIMEOUT": "0s", "SERVER_IDLE_TIMEOUT": "0"},
+			want:      [4]time.Duration{0, 0, 0, 5 * time.Second},
+		},
+		{name: "surrounding spaces", overrides: map[string]string{"SERVER_READ_TIMEOUT": " 30s "}, want: [4]time.Duration{30 * time.Second, 15 * time.Second, 60 * time.Second, 5 * time.Second}},
+		{name: "missing unit", overrides: map[string]string{"SERVER_READ_TIMEOUT": "15"}, wantLoadErr: `invalid duration "15"`},
+		{name: "not a duration", overrides: map[string]string{"SERVER_WRITE_TIMEOUT": "forever"}, wantLoadErr: `invalid duration "forever"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := New(WithValues(withOverrides(tt.overrides)))
+			if tt.wantLoadErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantLoadErr) {
+					t.Fatalf("New() error = %v, want it to mention %q", err, tt.wantLoadErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			got := [4]time.Duration{cfg.ServerReadTimeout, cfg.ServerWriteTimeout, cfg.ServerIdleTimeout, cfg.ServerReadHeaderTimeout}
+			if got != tt.want {
+				t.Errorf("timeouts = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
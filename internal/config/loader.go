@@ -0,0 +1,253 @@
+// internal/config/loader.go
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// lookupFunc resolves the value of a configuration key such as "MONGO_URL"
+type lookupFunc func(key string) (string, bool)
+
+// Option customizes how New builds a Config
+type Option func(*options)
+
+// options holds the sources New reads from
+type options struct {
+	values map[string]string
+	file   string
+	useEnv bool
+}
+
+// WithValues sets explicit values keyed by environment variable name (e.g. "PORT")
+// They take precedence over every other source.
+func WithValues(values map[string]string) Option {
+	return func(o *options) {
+		if o.values == nil {
+			o.values = map[string]string{}
+		}
+		for key, value := range values {
+			o.values[key] = value
+		}
+	}
+}
+
+// WithFile reads values from a YAML or JSON config file
+func WithFile(path string) Option {
+	return func(o *options) {
+		o.file = path
+	}
+}
+
+// WithEnv reads values from the process environment, overriding the config file
+func WithEnv() Option {
+	return func(o *options) {
+		o.useEnv = true
+	}
+}
+
+// New builds and validates a fresh Config without touching the package-level instance
+// Sources are applied in order of precedence: WithValues, then WithEnv, then WithFile,
+// then struct defaults. With no options only defaults apply, so required keys must be
+// provided through one of the sources.
+func New(opts ...Option) (*Config, error) {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	var fileValues map[string]string
+	if o.file != "" {
+		values, err := readConfigFile(o.file)
+		if err != nil {
+			return nil, err
+		}
+		fileValues = values
+	}
+
+	lookup := func(key string) (string, bool) {
+		if value, ok := o.values[key]; ok {
+			return value, true
+		}
+		if o.useEnv {
+			if value, ok := os.LookupEnv(key); ok {
+				return value, true
+			}
+		}
+		value, ok := fileValues[key]
+		return value, ok
+	}
+
+	cfg := &Config{}
+	if err := process(cfg, lookup); err != nil {
+		return nil, err
+	}
+
+	if err := cfg.validate(); err != nil {
+		return nil, fmt.Errorf("configuration validation failed: %w", err)
+	}
+
+	return cfg, nil
+}
+
+// LoadFromFile loads configuration from a YAML or JSON file, detected by extension
+// Keys are the same names used for environment variables (e.g. MONGO_URL); they may be
+// written in lower case and nested, so `redis: {url: ...}` sets REDIS_URL.
+// Environment variables take precedence over values from the file.
+func LoadFromFile(path string) (*Config, error) {
+	return New(WithFile(path), WithEnv())
+}
+
+// readConfigFile parses a config file into a flat map of upper-cased keys
+func readConfigFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	raw := map[string]interface{}{}
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &raw)
+	case ".json":
+		err = json.Unmarshal(data, &raw)
+	default:
+		return nil, fmt.Errorf("unsupported config file extension %q (use .yaml, .yml or .json)", ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	values := map[string]string{}
+	if err := flattenConfig("", raw, values); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+
+	// Reject unknown keys so typos do not silently fall back to defaults
+	known := configKeys()
+	for key := range values {
+		if !known[key] {
+			return nil, fmt.Errorf("invalid config file %s: unknown key %q", path, key)
+		}
+	}
+
+	return values, nil
+}
+
+// flattenConfig converts nested maps into KEY_SUBKEY entries with scalar string values
+func flattenConfig(prefix string, raw map[string]interface{}, out map[string]string) error {
+	for key, value := range raw {
+		name := strings.ToUpper(key)
+		if prefix != "" {
+			name = prefix + "_" + name
+		}
+
+		switch v := value.(type) {
+		case map[string]interface{}:
+			if err := flattenConfig(name, v, out); err != nil {
+				return err
+			}
+		case []interface{}:
+			return fmt.Errorf("key %q: lists are not supported", name)
+		case nil:
+			// Explicit nulls leave the key unset
+		case float64:
+			// JSON numbers decode as float64; keep integers free of exponents
+			out[name] = strconv.FormatFloat(v, 'f', -1, 64)
+		default:
+			out[name] = fmt.Sprint(v)
+		}
+	}
+	return nil
+}
+
+// configKeys returns the set of keys declared through envconfig tags on Config
+func configKeys() map[string]bool {
+	keys := map[string]bool{}
+	t := reflect.TypeOf(Config{})
+	for i := 0; i < t.NumField(); i++ {
+		if key := t.Field(i).Tag.Get("envconfig"); key != "" {
+			keys[key] = true
+		}
+	}
+	return keys
+}
+
+// process populates cfg from lookup using the envconfig, default and required struct tags
+// Every missing or malformed value is reported rather than only the first.
+func process(cfg *Config, lookup lookupFunc) error {
+	var errs []error
+
+	v := reflect.ValueOf(cfg).Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		key := field.Tag.Get("envconfig")
+		if key == "" {
+			continue
+		}
+
+		value, ok := lookup(key)
+		if !ok {
+			if field.Tag.Get("required") == "true" {
+				errs = append(errs, fmt.Errorf("required key %s missing value", key))
+				continue
+			}
+			value, ok = field.Tag.Lookup("default")
+			if !ok {
+				continue
+			}
+		}
+
+		if err := setField(v.Field(i), value); err != nil {
+			errs = append(errs, fmt.Errorf("assigning %s: %w", key, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// durationType is checked before kinds, as time.Duration is an int64
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// setField parses value into a struct field of a supported kind
+// time.Duration fields take Go duration strings such as "15s" or "1m30s".
+func setField(field reflect.Value, value string) error {
+	if field.Type() == durationType {
+		d, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", value)
+		}
+		field.SetInt(int64(d))
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Int:
+		n, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", value)
+		}
+		field.SetInt(int64(n))
+	case reflect.Bool:
+		b, err := strconv.ParseBool(strings.TrimSpace(value))
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", value)
+		}
+		field.SetBool(b)
+	default:
+		return fmt.Errorf("unsupported field type %s", field.Kind())
+	}
+	return nil
+}
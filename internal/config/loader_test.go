@@ -0,0 +1,230 @@
+// internal/config/loader_test.go
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeConfigFile writes content to a file called name in a temporary directory
+func writeConfigFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	return path
+}
+
+const sampleYAML = `
+mongo_url: mongodb://file-host:27017
+port: 9090
+jwt_secret: file-secret-file-secret-file-secret
+redis:
+  url: redis://file-host:6379
+  db: 3
+`
+
+const sampleJSON = `{
+  "MONGO_URL": "mongodb://file-host:27017",
+  "PORT": 9090,
+  "JWT_SECRET": "file-secret-file-secret-file-secret",
+  "REDIS": {"URL": "redis://file-host:6379", "DB": 3}
+}`
+
+func TestLoadFromFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		env     map[string]string
+		// check inspects the loaded config
+		check   func(t *testing.T, cfg *Config)
+		wantErr string
+	}{
+		{
+			name:    "yaml file",
+			file:    "config.yaml",
+			content: sampleYAML,
+			check: func(t *testing.T, cfg *Config) {
+				assertConfig(t, cfg, "9090", "mongodb://file-host:27017", "redis://file-host:6379", 3)
+			},
+		},
+		{
+			name:    "json file",
+			file:    "config.json",
+			content: sampleJSON,
+			check: func(t *testing.T, cfg *Config) {
+				assertConfig(t, cfg, "9090", "mongodb://file-host:27017", "redis://file-host:6379", 3)
+			},
+		},
+		{
+			name:    "env overrides file",
+			file:    "config.yml",
+			content: sampleYAML,
+			env:     map[string]string{"PORT": "7070", "REDIS_DB": "5"},
+			check: func(t *testing.T, cfg *Config) {
+				assertConfig(t, cfg, "7070", "mongodb://file-host:27017", "redis://file-host:6379", 5)
+			},
+		},
+		{
+			name:    "defaults fill unset keys",
+			file:    "config.yaml",
+			content: sampleYAML,
+			check: func(t *testing.T, cfg *Config) {
+				if cfg.RateLimitPerMinute != 100 {
+					t.Errorf("RateLimitPerMinute = %d, want the default 100", cfg.RateLimitPerMinute)
+				}
+				if cfg.JWTAlgorithm != "HS256" {
+					t.Errorf("JWTAlgorithm = %q, want the default HS256", cfg.JWTAlgorithm)
+				}
+				if cfg.BcryptCost != 12 {
+					t.Errorf("BcryptCost = %d, want the default 12", cfg.BcryptCost)
+				}
+				if cfg.MaintenanceMode || !cfg.MaintenanceAllowReads {
+					t.Errorf("maintenance = %v with reads %v, want off with reads allowed", cfg.MaintenanceMode, cfg.MaintenanceAllowReads)
+				}
+				if cfg.CacheWarmCount != 0 {
+					t.Errorf("CacheWarmCount = %d, want warming disabled by default", cfg.CacheWarmCount)
+				}
+				if cfg.AllowUnicodeUsernames {
+					t.Error("AllowUnicodeUsernames = true, want ASCII-only usernames by default")
+				}
+				if cfg.TrustedProxies != "" {
+					t.Errorf("TrustedProxies = %q, want no proxies trusted by default", cfg.TrustedProxies)
+				}
+				if cfg.StartupRetryAttempts != 5 || cfg.StartupRetryBackoff != time.Second || cfg.AllowDegradedStart {
+					t.Errorf("startup = %d attempts, %v backoff, degraded %v, want 5 attempts, 1s backoff and no degraded start",
+						cfg.StartupRetryAttempts, cfg.StartupRetryBackoff, cfg.AllowDegradedStart)
+				}
+			},
+		},
+		{
+			name:    "durations from file",
+			file:    "config.yaml",
+			content: sampleYAML + "server:\n  read_timeout: 2m\n  write_timeout: 0\n",
+			check: func(t *testing.T, cfg *Config) {
+				if cfg.ServerReadTimeout != 2*time.Minute || cfg.ServerWriteTimeout != 0 {
+					t.Errorf("server timeouts = %v/%v, want 2m0s/0s", cfg.ServerReadTimeout, cfg.ServerWriteTimeout)
+				}
+			},
+		},
+		{
+			name:    "rs256 keys from file",
+			file:    "config.yaml",
+			content: sampleYAML + "jwt:\n  algorithm: RS256\n  private_key_file: /keys/jwt.pem\n  public_key_file: /keys/jwt.pub\n",
+			check: func(t *testing.T, cfg *Config) {
+				if cfg.JWTAlgorithm != "RS256" || cfg.JWTPrivateKeyFile != "/keys/jwt.pem" || cfg.JWTPublicKeyFile != "/keys/jwt.pub" {
+					t.Errorf("jwt = %q %q %q, want RS256 with both key files", cfg.JWTAlgorithm, cfg.JWTPrivateKeyFile, cfg.JWTPublicKeyFile)
+				}
+			},
+		},
+		{
+			name:    "maintenance from file",
+			file:    "config.yaml",
+			content: sampleYAML + "maintenance:\n  mode: true\n  allow_reads: false\n",
+			check: func(t *testing.T, cfg *Config) {
+				if !cfg.MaintenanceMode || cfg.MaintenanceAllowReads {
+					t.Errorf("maintenance = %v with reads %v, want on with reads refused", cfg.MaintenanceMode, cfg.MaintenanceAllowReads)
+				}
+			},
+		},
+		{
+			name:    "startup retries from file",
+			file:    "config.yaml",
+			content: sampleYAML + "startup:\n  retry_attempts: 10\n  retry_backoff: 250ms\nallow_degraded_start: true\n",
+			check: func(t *testing.T, cfg *Config) {
+				if cfg.StartupRetryAttempts != 10 || cfg.StartupRetryBackoff != 250*time.Millisecond || !cfg.AllowDegradedStart {
+					t.Errorf("startup = %d attempts, %v backoff, degraded %v, want 10 attempts, 250ms backoff and a degraded start",
+						cfg.StartupRetryAttempts, cfg.StartupRetryBackoff, cfg.AllowDegradedStart)
+				}
+			},
+		},
+		{
+			name:    "unicode usernames from file",
+			file:    "config.yaml",
+			content: sampleYAML + "allow_unicode_usernames: true\n",
+			check: func(t *testing.T, cfg *Config) {
+				if !cfg.AllowUnicodeUsernames {
+					t.Error("AllowUnicodeUsernames = false, want true")
+				}
+			},
+		},
+		{
+			name:    "trusted proxies from file",
+			file:    "config.yaml",
+			content: sampleYAML + "trusted_proxies: 10.0.0.0/8,192.0.2.10\n",
+			check: func(t *testing.T, cfg *Config) {
+				if got := cfg.GetTrustedProxies(); !slices.Equal(got, []string{"10.0.0.0/8", "192.0.2.10"}) {
+					t.Errorf("GetTrustedProxies() = %q, want both entries", got)
+				}
+			},
+		},
+		{name: "malformed yaml", file: "config.yaml", content: "mongo_url: [unclosed", wantErr: "failed to parse config file"},
+		{name: "malformed json", file: "config.json", content: `{"MONGO_URL": `, wantErr: "failed to parse config file"},
+		{name: "unknown key", file: "config.yaml", content: sampleYAML + "prot: 80\n", wantErr: `unknown key "PROT"`},
+		{name: "lists are rejected", file: "config.yaml", content: sampleYAML + "cors_allowed_origins: [a, b]\n", wantErr: "lists are not supported"},
+		{name: "unsupported extension", file: "config.toml", content: sampleYAML, wantErr: "unsupported config file extension"},
+		{name: "required key missing", file: "config.yaml", content: "jwt_secret: file-secret-file-secret-file-secret\n", wantErr: "required key MONGO_URL missing value"},
+		{name: "invalid value", file: "config.yaml", content: sampleYAML + "rate_limit_per_minute: many\n", wantErr: "assigning RATE_LIMIT_PER_MINUTE"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+
+			cfg, err := LoadFromFile(writeConfigFile(t, tt.file, tt.content))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("LoadFromFile() error = %v, want it to mention %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadFromFile() error = %v", err)
+			}
+			tt.check(t, cfg)
+		})
+	}
+}
+
+func TestLoadFromFileMissing(t *testing.T) {
+	_, err := LoadFromFile(filepath.Join(t.TempDir(), "missing.yaml"))
+	if err == nil || !strings.Contains(err.Error(), "failed to read config file") {
+		t.Fatalf("LoadFromFile() error = %v, want a read error", err)
+	}
+}
+
+// assertConfig checks the fields set by the sample config files
+func assertConfig(t *testing.T, cfg *Config, port, mongoURL, redisURL string, redisDB int) {
+	t.Helper()
+	if cfg.Port != port {
+		t.Errorf("Port = %q, want %q", cfg.Port, port)
+	}
+	if cfg.MongoURL != mongoURL {
+		t.Errorf("MongoURL = %q, want %q", cfg.MongoURL, mongoURL)
+	}
+	if cfg.RedisURL != redisURL {
+		t.Errorf("RedisURL = %q, want %q", cfg.RedisURL, redisURL)
+	}
+	if cfg.RedisDB != redisDB {
+		t.Errorf("RedisDB = %d, want %d", cfg.RedisDB, redisDB)
+	}
+}
+
+func TestLoadDelegatesToConfigFile(t *testing.T) {
+	t.Setenv("CONFIG_FILE", writeConfigFile(t, "config.yaml", sampleYAML))
+	instance = nil
+	t.Cleanup(func() { instance = nil })
+
+	assertConfig(t, Load(), "9090", "mongodb://file-host:27017", "redis://file-host:6379", 3)
+	if Get() != Load() {
+		t.Error("Get() returned a different config than Load()")
+	}
+}
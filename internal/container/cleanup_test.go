@@ -0,0 +1,89 @@
+// internal/container/cleanup_test.go
+package container
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"go-template/internal/repositories"
+	"go-template/internal/shared/logtest"
+)
+
+// cleanupRepository records Cleanup calls on top of the memory repository
+type cleanupRepository struct {
+	*repositories.MemoryUserRepository
+	calls chan time.Duration
+	err   error
+}
+
+func (r *cleanupRepository) Cleanup(ctx context.Context, retention time.Duration) (int64, error) {
+	r.calls <- retention
+	return 0, r.err
+}
+
+func TestRunCleanup(t *testing.T) {
+	tests := []struct {
+		name         string
+		err          error
+		wantErrorLog bool
+	}{
+		{name: "purges every interval"},
+		{name: "failures are logged and retried", err: errors.New("mongo down"), wantErrorLog: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &cleanupRepository{MemoryUserRepository: repositories.NewMemoryUserRepository(), calls: make(chan time.Duration, 10), err: tt.err}
+			logger := logtest.New()
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan struct{})
+			go func() {
+				runCleanup(ctx, repo, 5*time.Millisecond, 72*time.Hour, logger)
+				close(done)
+			}()
+
+			for range 2 {
+				select {
+				case retention := <-repo.calls:
+					if retention != 72*time.Hour {
+						t.Errorf("Cleanup() retention = %v, want 72h", retention)
+					}
+				case <-time.After(time.Second):
+					t.Fatal("Cleanup() was not called")
+				}
+			}
+
+			cancel()
+			select {
+			case <-done:
+			case <-time.After(time.Second):
+				t.Fatal("runCleanup did not stop after the context was cancelled")
+			}
+			if !logger.Has(slog.LevelInfo, "Soft-delete cleanup stopped") {
+				t.Error("stop was not logged")
+			}
+			if got := logger.Has(slog.LevelError, "Failed to clean up soft-deleted users"); got != tt.wantErrorLog {
+				t.Errorf("error logged = %v, want %v", got, tt.wantErrorLog)
+			}
+			// The repository logs the purged count, so the scheduler does not repeat it
+			if logger.Has(slog.LevelInfo, "Cleaned up soft-deleted users") {
+				t.Error("scheduler logged the purge the repository already reports")
+			}
+		})
+	}
+}
+
+func TestRunCleanupStopsBeforeFirstRun(t *testing.T) {
+	repo := &cleanupRepository{MemoryUserRepository: repositories.NewMemoryUserRepository(), calls: make(chan time.Duration, 1)}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	runCleanup(ctx, repo, time.Hour, time.Hour, logtest.New())
+
+	if len(repo.calls) != 0 {
+		t.Error("Cleanup() ran although the context was already cancelled")
+	}
+}
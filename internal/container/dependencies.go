@@ -2,14 +2,38 @@ package container
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"go-template/internal/database"
+	"go-template/internal/database/migrations"
 	"go-template/internal/interfaces"
+	"go-template/internal/models"
+	"go-template/internal/repositories"
+	"go-template/internal/shared/events"
+	"go-template/internal/shared/mail"
+	"go-template/internal/shared/middleware"
+	"go-template/internal/shared/storage"
+	"go-template/internal/shared/utils"
 	"log"
 	"log/slog"
 	"os"
+	"strings"
+	"time"
 )
 
+// weakBcryptCost is the bcrypt cost below which production startup logs a warning
+const weakBcryptCost = 10
+
+// maxStartupRetryBackoff caps the doubling wait between startup connection attempts
+const maxStartupRetryBackoff = 30 * time.Second
+
+// redactedValue replaces the value of sensitive log attributes
+const redactedValue = "[REDACTED]"
+
+// sensitiveLogKeys are log attribute keys whose values are never written
+// Keys ending in one of them after an underscore, such as new_password, are redacted too.
+var sensitiveLogKeys = []string{"password", "salt", "token", "authorization"}
+
 // Initialize sets up all dependencies and returns a fully configured Dependencies container
 func (d *Dependencies) Initialize() error {
 	log.Println("Initializing application dependencies...")
@@ -22,19 +46,100 @@ func (d *Dependencies) Initialize() error {
 	logger := d.GetLogger("container")
 	logger.Info("Logger initialized successfully")
 
-	// Initialize database connection
-	if err := d.initDatabase(); err != nil {
-		logger.Error("Failed to initialize database", err)
-		return fmt.Errorf("failed to initialize database: %w", err)
+	// Initialize database connection, retrying while MongoDB comes up
+	databaseDegraded := false
+	if err := d.retryStartup(logger, "database", d.initDatabase); err != nil {
+		if !d.Config.AllowDegradedStart {
+			logger.Error("Failed to initialize database", err)
+			return fmt.Errorf("failed to initialize database: %w", err)
+		}
+		logger.Error("Database unavailable, starting degraded because ALLOW_DEGRADED_START is set", err)
+		if err := d.openDatabase(); err != nil {
+			return fmt.Errorf("failed to open database: %w", err)
+		}
+		databaseDegraded = true
+	} else {
+		logger.Info("Database initialized successfully")
+	}
+
+	// Without MongoDB the read connection and migrations wait for the next start
+	if databaseDegraded {
+		logger.Warn("Skipping the analytics read connection and migrations while the database is unavailable")
+	} else {
+		// Connect to the analytics read connection when configured
+		if err := d.initReadDatabase(); err != nil {
+			logger.Error("Failed to initialize read database", err)
+			return fmt.Errorf("failed to initialize read database: %w", err)
+		}
+		logger.Info("Analytics reads configured", "dedicated_connection", d.ReadDB != nil)
+
+		// Apply pending database migrations
+		if err := d.runMigrations(); err != nil {
+			logger.Error("Failed to run database migrations", err)
+			return fmt.Errorf("failed to run database migrations: %w", err)
+		}
+		logger.Info("Database migrations applied successfully")
+	}
+
+	// Initialize cache connection, retrying while Redis comes up
+	if err := d.retryStartup(logger, "cache", d.initCache); err != nil {
+		if !d.Config.AllowDegradedStart {
+			logger.Error("Failed to initialize cache", err)
+			return fmt.Errorf("failed to initialize cache: %w", err)
+		}
+		logger.Error("Cache unavailable, starting degraded because ALLOW_DEGRADED_START is set", err)
+		d.openCache()
+	} else {
+		logger.Info("Cache initialized successfully")
+	}
+
+	// Start listening for cache invalidations from other instances
+	d.initInvalidator()
+	logger.Info("Cache invalidation subscriber started")
+
+	// Configure password hashing
+	if err := d.initPasswordHashing(); err != nil {
+		logger.Error("Failed to configure password hashing", err)
+		return fmt.Errorf("failed to configure password hashing: %w", err)
+	}
+	logger.Info("Password hashing configured successfully", "algorithm", d.Config.PasswordAlgo, "peppered", d.Config.PasswordPepper != "",
+		"bcrypt_cost", d.Config.BcryptCost,
+		"min_length", d.Config.PasswordMinLength, "min_char_classes", d.Config.PasswordMinCharClasses, "require_special", d.Config.PasswordRequireSpecial)
+	if d.bcryptCostIsWeak() {
+		logger.Warn("BCRYPT_COST is below the recommended minimum for production", "bcrypt_cost", d.Config.BcryptCost, "recommended_min", weakBcryptCost)
+	}
+
+	// Apply the configured page size limit to list queries
+	models.SetMaxPageLimit(d.Config.MaxPageLimit)
+
+	// Apply the configured username character set
+	models.SetAllowUnicodeUsernames(d.Config.AllowUnicodeUsernames)
+
+	// Initialize token service
+	if err := d.initTokenService(); err != nil {
+		logger.Error("Failed to initialize token service", err)
+		return fmt.Errorf("failed to initialize token service: %w", err)
 	}
-	logger.Info("Database initialized successfully")
+	logger.Info("Token service initialized successfully", "algorithm", d.Tokens.Algorithm())
 
-	// Initialize cache connection
-	if err := d.initCache(); err != nil {
-		logger.Error("Failed to initialize cache", err)
-		return fmt.Errorf("failed to initialize cache: %w", err)
+	// Initialize file storage
+	if err := d.initStorage(); err != nil {
+		logger.Error("Failed to initialize file storage", err)
+		return fmt.Errorf("failed to initialize file storage: %w", err)
 	}
-	logger.Info("Cache initialized successfully")
+	logger.Info("File storage initialized successfully", "dir", d.Config.AvatarStorageDir)
+
+	// Initialize mailer
+	d.initMailer()
+	logger.Info("Mailer initialized successfully")
+
+	// Start delivering domain events
+	d.initEvents()
+	logger.Info("Event dispatcher started", "webhook_enabled", d.Config.WebhookURL != "")
+
+	// Start purging expired soft-deleted users
+	d.initCleanup()
+	logger.Info("Soft-delete cleanup scheduled", "retention", d.Config.GetSoftDeleteRetention(), "interval", d.Config.GetCleanupInterval())
 
 	logger.Info("All dependencies initialized successfully")
 	return nil
@@ -59,8 +164,9 @@ func (d *Dependencies) initLogger() error {
 
 	// Configure handler options
 	opts := &slog.HandlerOptions{
-		Level:     logLevel,
-		AddSource: d.Config.IsDevelopment(),
+		Level:       logLevel,
+		AddSource:   d.Config.IsDevelopment(),
+		ReplaceAttr: redactSensitiveAttr,
 	}
 
 	// Use JSON handler for production, text handler for development
@@ -80,7 +186,7 @@ func (d *Dependencies) initLogger() error {
 
 // initDatabase initializes the MongoDB connection
 func (d *Dependencies) initDatabase() error {
-	db, err := database.ConnectMongoDB(d.Config.MongoURL, d.Config.DatabaseName)
+	db, err := database.ConnectMongoDB(d.Config.MongoURL, d.Config.DatabaseName, uint64(d.Config.MongoMaxPoolSize), uint64(d.Config.MongoMinPoolSize))
 	if err != nil {
 		return err
 	}
@@ -89,12 +195,105 @@ func (d *Dependencies) initDatabase() error {
 	return nil
 }
 
-// initCache initializes the Redis cache connection
+// openDatabase creates the MongoDB client without requiring MongoDB to be reachable
+// Used for a degraded start; requests needing the database fail until it is back.
+func (d *Dependencies) openDatabase() error {
+	db, err := database.OpenMongoDB(d.Config.MongoURL, d.Config.DatabaseName, uint64(d.Config.MongoMaxPoolSize), uint64(d.Config.MongoMinPoolSize))
+	if err != nil {
+		return err
+	}
+
+	d.DB = db
+	return nil
+}
+
+// retryStartup runs connect until it succeeds or STARTUP_RETRY_ATTEMPTS attempts have failed
+// The wait between attempts starts at STARTUP_RETRY_BACKOFF and doubles, up to
+// maxStartupRetryBackoff. Each failed attempt is logged.
+func (d *Dependencies) retryStartup(logger interfaces.LoggerInterface, dependency string, connect func() error) error {
+	attempts := max(d.Config.StartupRetryAttempts, 1)
+	backoff := d.Config.StartupRetryBackoff
+
+	for attempt := 1; ; attempt++ {
+		err := connect()
+		if err == nil {
+			return nil
+		}
+		if attempt == attempts {
+			return fmt.Errorf("%s unavailable after %d attempts: %w", dependency, attempts, err)
+		}
+
+		logger.Warn("Dependency unavailable at startup, retrying",
+			"dependency", dependency,
+			"attempt", attempt,
+			"max_attempts", attempts,
+			"retry_in", backoff,
+			"error", err.Error(),
+		)
+		select {
+		case <-time.After(backoff):
+		case <-d.Context.Done():
+			return fmt.Errorf("%s unavailable, startup cancelled: %w", dependency, err)
+		}
+		backoff = min(2*backoff, maxStartupRetryBackoff)
+	}
+}
+
+// initReadDatabase connects to MONGO_READ_URL for analytics queries
+// Without it analytics queries use the primary connection.
+func (d *Dependencies) initReadDatabase() error {
+	if d.Config.MongoReadURL == "" {
+		return nil
+	}
+
+	db, err := database.ConnectMongoDBReadOnly(d.Config.MongoReadURL, d.Config.DatabaseName, uint64(d.Config.MongoMaxPoolSize), uint64(d.Config.MongoMinPoolSize))
+	if err != nil {
+		return err
+	}
+
+	d.ReadDB = db
+	return nil
+}
+
+// runMigrations applies pending schema migrations
+// An index that conflicts with existing indexes or data is always logged; unless FAIL_ON_INDEX_ERROR
+// is set, startup then continues and the failed migration and those after it are retried on the
+// next start.
+func (d *Dependencies) runMigrations() error {
+	ctx, cancel := context.WithTimeout(d.Context, 2*migrations.DefaultLockTTL)
+	defer cancel()
+
+	err := migrations.Migrate(ctx, d.DB)
+
+	var conflict *migrations.IndexConflictError
+	if errors.As(err, &conflict) {
+		logger := d.GetLogger("migrations")
+		logger.Error("Index conflicts with existing indexes or data", err,
+			"collection", conflict.Collection,
+			"index", conflict.Index,
+			"reason", conflict.Reason,
+		)
+		if !d.Config.FailOnIndexError {
+			logger.Warn("Continuing without the index because FAIL_ON_INDEX_ERROR is false", "index", conflict.Index)
+			return nil
+		}
+	}
+	return err
+}
+
+// initCache initializes the cache for the configured driver
 func (d *Dependencies) initCache() error {
+	if d.Config.CacheDriver == "memory" {
+		d.Cache = database.NewMemoryCache()
+		return nil
+	}
+
 	cache, err := database.ConnectRedis(
 		d.Config.RedisURL,
 		d.Config.RedisPassword,
 		d.Config.RedisDB,
+		d.Config.RedisPoolSize,
+		d.Config.RedisMinIdleConns,
 	)
 	if err != nil {
 		return err
@@ -104,6 +303,135 @@ func (d *Dependencies) initCache() error {
 	return nil
 }
 
+// openCache creates the Redis cache without requiring Redis to be reachable
+// Used for a degraded start; the circuit breaker fails cache calls fast until Redis is back.
+func (d *Dependencies) openCache() {
+	d.Cache = database.OpenRedis(
+		d.Config.RedisURL,
+		d.Config.RedisPassword,
+		d.Config.RedisDB,
+		d.Config.RedisPoolSize,
+		d.Config.RedisMinIdleConns,
+	)
+}
+
+// bcryptCostIsWeak reports whether production hashes new passwords with a bcrypt cost below the recommended minimum
+func (d *Dependencies) bcryptCostIsWeak() bool {
+	return d.Config.IsProduction() && d.Config.PasswordAlgo == utils.AlgorithmBcrypt && d.Config.BcryptCost < weakBcryptCost
+}
+
+// initPasswordHashing selects the algorithm used for new password hashes, the optional pepper
+// and the password strength policy
+func (d *Dependencies) initPasswordHashing() error {
+	ps, err := utils.NewPasswordServiceWithAlgorithm(d.Config.PasswordAlgo, d.Config.PasswordPepper)
+	if err != nil {
+		return err
+	}
+	if err := ps.SetBcryptCost(d.Config.BcryptCost); err != nil {
+		return err
+	}
+	ps.SetPolicy(utils.PasswordPolicy{
+		MinLength:      d.Config.PasswordMinLength,
+		RequireSpecial: d.Config.PasswordRequireSpecial,
+		MinCharClasses: d.Config.PasswordMinCharClasses,
+	})
+
+	utils.SetDefaultPasswordService(ps)
+	return nil
+}
+
+// initInvalidator creates the cache invalidator and starts its subscriber
+// The subscriber stops when the container context is cancelled on Close
+func (d *Dependencies) initInvalidator() {
+	d.Invalidator = database.NewInvalidator(d.Cache, d.GetLogger("cache-invalidation"))
+	go d.Invalidator.Run(d.Context)
+}
+
+// initTokenService initializes the JWT token service for the configured algorithm
+func (d *Dependencies) initTokenService() error {
+	if d.Config.JWTAlgorithm == utils.AlgorithmRS256 {
+		key, err := utils.LoadRSAKeyPair(d.Config.JWTPrivateKeyFile, d.Config.JWTPublicKeyFile)
+		if err != nil {
+			return err
+		}
+		d.Tokens = utils.NewRSATokenService(
+			key,
+			d.Config.GetJWTExpiration(),
+			d.Config.GetJWTRefreshExpiration(),
+			d.Config.GetJWTRememberExpiration(),
+		)
+		return nil
+	}
+
+	d.Tokens = utils.NewTokenService(
+		d.Config.JWTSecret,
+		d.Config.GetJWTExpiration(),
+		d.Config.GetJWTRefreshExpiration(),
+		d.Config.GetJWTRememberExpiration(),
+	)
+	return nil
+}
+
+// initStorage initializes local file storage for avatar uploads
+func (d *Dependencies) initStorage() error {
+	store, err := storage.NewLocalStorage(d.Config.AvatarStorageDir, d.Config.AvatarBaseURL)
+	if err != nil {
+		return err
+	}
+
+	d.Storage = store
+	return nil
+}
+
+// initMailer initializes the mailer used for transactional emails
+// No delivery provider is wired up yet, so emails are written to the log
+func (d *Dependencies) initMailer() {
+	d.Mailer = mail.NewLogMailer(d.GetLogger("mailer"))
+}
+
+// initEvents wires the event outbox to its subscribers and starts the dispatcher
+// The dispatcher stops when the container context is cancelled on Close
+func (d *Dependencies) initEvents() {
+	d.Events = events.NewEventBus()
+	if d.Config.WebhookURL != "" {
+		d.Events.Subscribe(events.NewWebhookSubscriber(d.Config.WebhookURL, d.Config.WebhookSecret, d.GetLogger("webhook")))
+	}
+
+	outbox := repositories.NewOutboxRepository(d.DB)
+	d.Dispatcher = events.NewDispatcher(outbox, d.Events, d.GetLogger("events"))
+	go d.Dispatcher.Run(d.Context)
+}
+
+// initCleanup starts the scheduler that permanently removes expired soft-deleted users
+// The scheduler stops when the container context is cancelled on Close
+func (d *Dependencies) initCleanup() {
+	logger := d.GetLogger("cleanup")
+	repo := repositories.NewUserRepository(d.DB, logger)
+	go runCleanup(d.Context, repo, d.Config.GetCleanupInterval(), d.Config.GetSoftDeleteRetention(), logger)
+}
+
+// runCleanup purges soft-deleted users every interval until ctx is cancelled
+// The first run happens one interval after startup so restarts do not trigger a purge.
+func runCleanup(ctx context.Context, repo repositories.UserRepositoryInterface, interval, retention time.Duration, logger interfaces.LoggerInterface) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Info("Soft-delete cleanup stopped")
+			return
+		case <-ticker.C:
+			// The repository logs how many users were purged
+			if _, err := repo.Cleanup(ctx, retention); err != nil {
+				if ctx.Err() == nil {
+					logger.Error("Failed to clean up soft-deleted users", err)
+				}
+			}
+		}
+	}
+}
+
 // StructuredLogger implements interfaces.LoggerInterface using slog
 type StructuredLogger struct {
 	logger *slog.Logger
@@ -151,10 +479,29 @@ func (l *StructuredLogger) Log(ctx context.Context, level slog.Level, msg string
 	l.logger.Log(ctx, level, msg, args...)
 }
 
+// redactSensitiveAttr hides the values of sensitive attributes, in any group
+func redactSensitiveAttr(groups []string, attr slog.Attr) slog.Attr {
+	if isSensitiveLogKey(attr.Key) {
+		return slog.String(attr.Key, redactedValue)
+	}
+	return attr
+}
+
+// isSensitiveLogKey reports whether key names a value that must not be logged
+func isSensitiveLogKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, sensitive := range sensitiveLogKeys {
+		if key == sensitive || strings.HasSuffix(key, "_"+sensitive) {
+			return true
+		}
+	}
+	return false
+}
+
 // getRequestIDFromContext extracts request ID from context
 func getRequestIDFromContext(ctx context.Context) string {
 	if ctx == nil {
 		return ""
 	}
-	return ""
+	return middleware.RequestIDFromContext(ctx)
 }
\ No newline at end of file
@@ -0,0 +1,466 @@
+// internal/container/dependencies_test.go
+package container
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"golang.org/x/crypto/bcrypt"
+
+	"go-template/internal/config"
+	"go-template/internal/database"
+	"go-template/internal/shared/logtest"
+	"go-template/internal/shared/utils"
+)
+
+// newTestConfig builds a valid config with values layered over the required settings
+// The memory cache driver is the default so no Redis URL is needed.
+func newTestConfig(t *testing.T, values map[string]string) *config.Config {
+	t.Helper()
+	merged := map[string]string{
+		"MONGO_URL":    "mongodb://localhost:27017",
+		"JWT_SECRET":   "test-secret-test-secret-test-secret",
+		"CACHE_DRIVER": "memory",
+	}
+	for key, value := range values {
+		merged[key] = value
+	}
+	cfg, err := config.New(config.WithValues(merged))
+	if err != nil {
+		t.Fatalf("config.New() error = %v", err)
+	}
+	return cfg
+}
+
+func TestInitCache(t *testing.T) {
+	server := miniredis.RunT(t)
+
+	tests := []struct {
+		name       string
+		values     map[string]string
+		wantMemory bool
+		wantErr    bool
+	}{
+		{name: "memory driver needs no redis", values: map[string]string{"CACHE_DRIVER": "memory"}, wantMemory: true},
+		{name: "redis driver", values: map[string]string{"CACHE_DRIVER": "redis", "REDIS_URL": server.Addr()}},
+		{name: "unreachable redis", values: map[string]string{"CACHE_DRIVER": "redis", "REDIS_URL": "127.0.0.1:1"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deps := NewDependenciesWithConfig(newTestConfig(t, tt.values))
+			defer deps.Cancel()
+
+			err := deps.initCache()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("initCache() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			defer deps.Cache.Close()
+
+			if _, isMemory := deps.Cache.(*database.MemoryCache); isMemory != tt.wantMemory {
+				t.Errorf("cache is %T, want memory = %v", deps.Cache, tt.wantMemory)
+			}
+			ctx := context.Background()
+			if err := deps.Cache.Set(ctx, "key", "value", 0); err != nil {
+				t.Fatalf("Set() error = %v", err)
+			}
+			if got, err := deps.Cache.Get(ctx, "key"); err != nil || got != "value" {
+				t.Errorf("Get() = %q, %v, want value", got, err)
+			}
+		})
+	}
+}
+
+func TestInitPasswordHashingPolicy(t *testing.T) {
+	t.Cleanup(func() { utils.SetDefaultPasswordService(utils.NewPasswordService()) })
+
+	tests := []struct {
+		name   string
+		values map[string]string
+		want   utils.PasswordPolicy
+	}{
+		{name: "defaults", want: utils.DefaultPasswordPolicy()},
+		{
+			name:   "stricter policy",
+			values: map[string]string{"PASSWORD_MIN_LENGTH": "14", "PASSWORD_REQUIRE_SPECIAL": "true", "PASSWORD_MIN_CHAR_CLASSES": "4"},
+			want:   utils.PasswordPolicy{MinLength: 14, RequireSpecial: true, MinCharClasses: 4},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deps := NewDependenciesWithConfig(newTestConfig(t, tt.values))
+			defer deps.Cancel()
+
+			if err := deps.initPasswordHashing(); err != nil {
+				t.Fatalf("initPasswordHashing() error = %v", err)
+			}
+
+			// The global helpers go through the configured service
+			short := "Sh0rt-pass"
+			err := utils.ValidatePassword(short)
+			if wantFail := len(short) < tt.want.MinLength; (err != nil) != wantFail {
+				t.Errorf("ValidatePassword(%q) error = %v, want failure %v", short, err, wantFail)
+			}
+		})
+	}
+}
+
+func TestGetReadDB(t *testing.T) {
+	// Connecting is lazy, so these clients never need a server
+	database := func(name string) *mongo.Database {
+		client, err := mongo.Connect(context.Background(), options.Client().ApplyURI("mongodb://127.0.0.1:1"))
+		if err != nil {
+			t.Fatalf("mongo.Connect() error = %v", err)
+		}
+		t.Cleanup(func() { client.Disconnect(context.Background()) })
+		return client.Database(name)
+	}
+	primary, read := database("primary"), database("read")
+
+	tests := []struct {
+		name   string
+		readDB *mongo.Database
+		want   *mongo.Database
+	}{
+		{name: "falls back to the primary", want: primary},
+		{name: "dedicated read connection", readDB: read, want: read},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deps := NewDependenciesWithConfig(newTestConfig(t, nil))
+			defer deps.Cancel()
+			deps.DB = primary
+			deps.ReadDB = tt.readDB
+
+			if got := deps.GetReadDB(); got != tt.want {
+				t.Errorf("GetReadDB() = %s, want %s", got.Name(), tt.want.Name())
+			}
+		})
+	}
+}
+
+func TestInitReadDatabaseIsOptional(t *testing.T) {
+	deps := NewDependenciesWithConfig(newTestConfig(t, nil))
+	defer deps.Cancel()
+
+	if err := deps.initReadDatabase(); err != nil {
+		t.Fatalf("initReadDatabase() error = %v", err)
+	}
+	if deps.ReadDB != nil {
+		t.Error("ReadDB is set although MONGO_READ_URL is empty")
+	}
+}
+
+func TestInitTokenService(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	writeKey := func(name, blockType string, der []byte) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600); err != nil {
+			t.Fatalf("WriteFile() error = %v", err)
+		}
+		return path
+	}
+	privateKey := writeKey("jwt.pem", "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(key))
+	publicKey := writeKey("jwt.pub", "RSA PUBLIC KEY", x509.MarshalPKCS1PublicKey(&key.PublicKey))
+
+	tests := []struct {
+		name     string
+		values   map[string]string
+		wantAlg  string
+		wantJWKS bool
+		wantErr  string
+	}{
+		{name: "HS256 by default", wantAlg: utils.AlgorithmHS256},
+		{
+			name:     "RS256 with a key pair",
+			values:   map[string]string{"JWT_ALGORITHM": "RS256", "JWT_PRIVATE_KEY_FILE": privateKey, "JWT_PUBLIC_KEY_FILE": publicKey},
+			wantAlg:  utils.AlgorithmRS256,
+			wantJWKS: true,
+		},
+		{
+			name:    "RS256 with a missing key file",
+			values:  map[string]string{"JWT_ALGORITHM": "RS256", "JWT_PRIVATE_KEY_FILE": filepath.Join(dir, "missing.pem"), "JWT_PUBLIC_KEY_FILE": publicKey},
+			wantErr: "failed to read key file",
+		},
+		{
+			name:    "RS256 with the keys swapped",
+			values:  map[string]string{"JWT_ALGORITHM": "RS256", "JWT_PRIVATE_KEY_FILE": publicKey, "JWT_PUBLIC_KEY_FILE": privateKey},
+			wantErr: "invalid private key",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deps := NewDependenciesWithConfig(newTestConfig(t, tt.values))
+			defer deps.Cancel()
+
+			err := deps.initTokenService()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("initTokenService() error = %v, want it to mention %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("initTokenService() error = %v", err)
+			}
+
+			if got := deps.Tokens.Algorithm(); got != tt.wantAlg {
+				t.Errorf("Algorithm() = %q, want %q", got, tt.wantAlg)
+			}
+			if _, ok := deps.Tokens.JWKS(); ok != tt.wantJWKS {
+				t.Errorf("JWKS() ok = %v, want %v", ok, tt.wantJWKS)
+			}
+			token, err := deps.Tokens.GenerateAccessToken("user-1", []string{"user"}, 0)
+			if err != nil {
+				t.Fatalf("GenerateAccessToken() error = %v", err)
+			}
+			if _, err := deps.Tokens.ValidateToken(token); err != nil {
+				t.Errorf("ValidateToken() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestInitPasswordHashingBcryptCost(t *testing.T) {
+	t.Cleanup(func() { utils.SetDefaultPasswordService(utils.NewPasswordService()) })
+
+	tests := []struct {
+		name     string
+		values   map[string]string
+		wantCost int
+		wantWeak bool
+	}{
+		{name: "configured cost", values: map[string]string{"BCRYPT_COST": "5"}, wantCost: 5},
+		{name: "low cost outside production", values: map[string]string{"BCRYPT_COST": "4", "ENV": "development"}, wantCost: 4},
+		{name: "low cost in production", values: map[string]string{"BCRYPT_COST": "4", "ENV": "production"}, wantCost: 4, wantWeak: true},
+		{name: "recommended minimum in production", values: map[string]string{"BCRYPT_COST": "10", "ENV": "production"}, wantCost: 10},
+		{name: "argon2id ignores the bcrypt cost", values: map[string]string{"BCRYPT_COST": "4", "ENV": "production", "PASSWORD_ALGO": "argon2id"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deps := NewDependenciesWithConfig(newTestConfig(t, tt.values))
+			defer deps.Cancel()
+
+			if err := deps.initPasswordHashing(); err != nil {
+				t.Fatalf("initPasswordHashing() error = %v", err)
+			}
+			if got := deps.bcryptCostIsWeak(); got != tt.wantWeak {
+				t.Errorf("bcryptCostIsWeak() = %v, want %v", got, tt.wantWeak)
+			}
+			if tt.wantCost == 0 {
+				return
+			}
+
+			// The global helpers hash with the configured cost
+			hash, err := utils.HashPassword("Str0ng-Passw0rd")
+			if err != nil {
+				t.Fatalf("HashPassword() error = %v", err)
+			}
+			if cost, err := bcrypt.Cost([]byte(hash)); err != nil || cost != tt.wantCost {
+				t.Errorf("bcrypt.Cost() = %d, %v, want %d", cost, err, tt.wantCost)
+			}
+		})
+	}
+}
+
+func TestRedactSensitiveAttr(t *testing.T) {
+	tests := []struct {
+		name         string
+		args         []interface{}
+		with         []interface{}
+		path         []string
+		wantRedacted bool
+	}{
+		{name: "password", args: []interface{}{"password", "hunter2"}, path: []string{"password"}, wantRedacted: true},
+		{name: "salt", args: []interface{}{"salt", "abc"}, path: []string{"salt"}, wantRedacted: true},
+		{name: "token", args: []interface{}{"token", "eyJ"}, path: []string{"token"}, wantRedacted: true},
+		{name: "authorization", args: []interface{}{"authorization", "Bearer eyJ"}, path: []string{"authorization"}, wantRedacted: true},
+		{name: "key case is ignored", args: []interface{}{"Authorization", "Bearer eyJ"}, path: []string{"Authorization"}, wantRedacted: true},
+		{name: "suffix after an underscore", args: []interface{}{"refresh_token", "eyJ"}, path: []string{"refresh_token"}, wantRedacted: true},
+		{name: "non-string value", args: []interface{}{"new_password", 1234}, path: []string{"new_password"}, wantRedacted: true},
+		{name: "inside a group", args: []interface{}{slog.Group("request", "password", "hunter2")}, path: []string{"request", "password"}, wantRedacted: true},
+		{name: "attribute added with With", with: []interface{}{"token", "eyJ"}, path: []string{"token"}, wantRedacted: true},
+		{name: "username is kept", args: []interface{}{"username", "alice"}, path: []string{"username"}},
+		{name: "suffix without an underscore is kept", args: []interface{}{"tokens", "3"}, path: []string{"tokens"}},
+		{name: "prefix is kept", args: []interface{}{"token_count", "3"}, path: []string{"token_count"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := &StructuredLogger{logger: slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{ReplaceAttr: redactSensitiveAttr}))}
+			logger.With(tt.with...).Info("test", tt.args...)
+
+			var entry map[string]interface{}
+			if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+				t.Fatalf("invalid log line %q: %v", buf.String(), err)
+			}
+			value := interface{}(entry)
+			for _, key := range tt.path {
+				group, ok := value.(map[string]interface{})
+				if !ok {
+					t.Fatalf("log line %s has no %v", buf.String(), tt.path)
+				}
+				value = group[key]
+			}
+
+			if redacted := value == redactedValue; redacted != tt.wantRedacted {
+				t.Errorf("%s = %v, want redacted %v", strings.Join(tt.path, "."), value, tt.wantRedacted)
+			}
+			if tt.wantRedacted && strings.Contains(buf.String(), "hunter2") {
+				t.Errorf("log line %s contains the password", buf.String())
+			}
+		})
+	}
+}
+
+func TestInitLoggerRedactsSensitiveAttrs(t *testing.T) {
+	// The logger writes to stdout, so the test swaps it for a pipe
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	t.Cleanup(func() { os.Stdout = stdout })
+
+	// Production logs JSON, which is simpler to inspect than text
+	cfg := newTestConfig(t, nil)
+	cfg.Environment = "production"
+	deps := &Dependencies{Config: cfg}
+	if err := deps.initLogger(); err != nil {
+		t.Fatalf("initLogger() error = %v", err)
+	}
+	deps.Logger.Info("User created", "username", "alice", "password", "hunter2")
+	w.Close()
+	os.Stdout = stdout
+
+	var buf bytes.Buffer
+	if _, err := buf.ReadFrom(r); err != nil {
+		t.Fatal(err)
+	}
+	var entry map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("invalid log line %q: %v", buf.String(), err)
+	}
+	if entry["password"] != redactedValue || entry["username"] != "alice" {
+		t.Errorf("log line = %s, want the password redacted and the username kept", buf.String())
+	}
+}
+
+func TestRetryStartup(t *testing.T) {
+	errDown := errors.New("connection refused")
+
+	tests := []struct {
+		name        string
+		attempts    int
+		failures    int // attempts failing before connect succeeds
+		wantCalls   int
+		wantErr     bool
+		wantRetryIn []time.Duration
+	}{
+		{name: "first attempt", attempts: 3, wantCalls: 1},
+		{name: "second attempt", attempts: 3, failures: 1, wantCalls: 2, wantRetryIn: []time.Duration{time.Millisecond}},
+		{name: "last attempt", attempts: 3, failures: 2, wantCalls: 3, wantRetryIn: []time.Duration{time.Millisecond, 2 * time.Millisecond}},
+		{name: "gives up", attempts: 3, failures: 5, wantCalls: 3, wantErr: true, wantRetryIn: []time.Duration{time.Millisecond, 2 * time.Millisecond}},
+		{name: "single attempt", attempts: 1, failures: 1, wantCalls: 1, wantErr: true},
+		{name: "attempts below one try once", attempts: 0, failures: 1, wantCalls: 1, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := newTestConfig(t, nil)
+			cfg.StartupRetryAttempts = tt.attempts
+			cfg.StartupRetryBackoff = time.Millisecond
+			d := &Dependencies{Config: cfg, Context: context.Background()}
+			logger := logtest.New()
+
+			// The fake connector fails the first tt.failures calls
+			calls := 0
+			connect := func() error {
+				calls++
+				if calls <= tt.failures {
+					return errDown
+				}
+				return nil
+			}
+
+			err := d.retryStartup(logger, "database", connect)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("retryStartup() error = %v, want error %v", err, tt.wantErr)
+			}
+			if tt.wantErr && (!errors.Is(err, errDown) || !strings.Contains(err.Error(), "database unavailable after")) {
+				t.Errorf("retryStartup() error = %v, want the last connection error wrapped", err)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("connect called %d times, want %d", calls, tt.wantCalls)
+			}
+
+			// Each retry is logged with the doubling wait
+			var retryIn []time.Duration
+			for _, entry := range logger.Entries() {
+				if entry.Level != slog.LevelWarn || entry.Msg != "Dependency unavailable at startup, retrying" {
+					continue
+				}
+				for i := 0; i+1 < len(entry.Args); i += 2 {
+					if entry.Args[i] == "retry_in" {
+						retryIn = append(retryIn, entry.Args[i+1].(time.Duration))
+					}
+				}
+			}
+			if !slices.Equal(retryIn, tt.wantRetryIn) {
+				t.Errorf("logged retries = %v, want %v", retryIn, tt.wantRetryIn)
+			}
+		})
+	}
+}
+
+func TestRetryStartupStopsOnShutdown(t *testing.T) {
+	cfg := newTestConfig(t, nil)
+	cfg.StartupRetryAttempts = 5
+	cfg.StartupRetryBackoff = time.Hour
+	ctx, cancel := context.WithCancel(context.Background())
+	d := &Dependencies{Config: cfg, Context: ctx}
+
+	calls := 0
+	connect := func() error {
+		calls++
+		cancel()
+		return errors.New("connection refused")
+	}
+
+	err := d.retryStartup(logtest.New(), "cache", connect)
+	if err == nil || !strings.Contains(err.Error(), "startup cancelled") {
+		t.Fatalf("retryStartup() error = %v, want the startup cancelled", err)
+	}
+	if calls != 1 {
+		t.Errorf("connect called %d times, want 1", calls)
+	}
+}
@@ -4,9 +4,16 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"sync/atomic"
 
 	"go-template/internal/config"
+	"go-template/internal/database"
 	"go-template/internal/interfaces"
+	"go-template/internal/shared/events"
+	"go-template/internal/shared/metrics"
+	"go-template/internal/shared/middleware"
+	"go-template/internal/shared/storage"
+	"go-template/internal/shared/utils"
 
 	"go.mongodb.org/mongo-driver/mongo"
 )
@@ -14,35 +21,70 @@ import (
 // Dependencies container holds all application dependencies
 type Dependencies struct {
 	// HTTP Server components
-	Mux *http.ServeMux
+	Mux      *http.ServeMux
+	InFlight *middleware.InFlightCounter
+	// Maintenance is set while the API refuses writes; it starts from MAINTENANCE_MODE
+	Maintenance *atomic.Bool
 	
 	// Configuration
 	Config *config.Config
 	
 	// Database connections
 	DB *mongo.Database
+	// ReadDB serves analytics queries from secondaries; nil unless MONGO_READ_URL is set
+	ReadDB *mongo.Database
 	
 	// Cache connection
 	Cache interfaces.CacheInterface
 	
+	// Cross-instance cache invalidation
+	Invalidator *database.Invalidator
+	
+	// Cache hit/miss counters; nil discards them, set it before routes are registered to export them
+	CacheMetrics interfaces.CacheMetrics
+	
 	// Logging
 	Logger interfaces.LoggerInterface
 	
+	// Authentication
+	Tokens *utils.TokenService
+	
+	// File storage for uploaded avatars
+	Storage *storage.LocalStorage
+	
+	// Outgoing email delivery
+	Mailer interfaces.Mailer
+	
+	// Domain events, stored in the outbox and delivered to webhooks
+	Events     *events.EventBus
+	Dispatcher *events.Dispatcher
+	
 	// Context for graceful shutdown
 	Context context.Context
 	Cancel  context.CancelFunc
 }
 
-// NewDependencies creates a new Dependencies container with all components initialized
+// NewDependencies creates a new Dependencies container using the process-wide config
 func NewDependencies() *Dependencies {
+	return NewDependenciesWithConfig(config.Load())
+}
+
+// NewDependenciesWithConfig creates a new Dependencies container using the given config
+// Use it with config.New to run isolated containers, e.g. in tests
+func NewDependenciesWithConfig(cfg *config.Config) *Dependencies {
 	// Create context for graceful shutdown
 	ctx, cancel := context.WithCancel(context.Background())
 	
+	maintenance := new(atomic.Bool)
+	maintenance.Store(cfg.MaintenanceMode)
+	
 	return &Dependencies{
-		Mux:     http.NewServeMux(),
-		Config:  config.Load(),
-		Context: ctx,
-		Cancel:  cancel,
+		Mux:         http.NewServeMux(),
+		InFlight:    middleware.NewInFlightCounter(),
+		Maintenance: maintenance,
+		Config:      cfg,
+		Context:     ctx,
+		Cancel:      cancel,
 	}
 }
 
@@ -51,11 +93,32 @@ func (d *Dependencies) GetDB() *mongo.Database {
 	return d.DB
 }
 
+// GetReadDB returns the database for analytics reads, falling back to the primary connection
+func (d *Dependencies) GetReadDB() *mongo.Database {
+	if d.ReadDB != nil {
+		return d.ReadDB
+	}
+	return d.DB
+}
+
 // GetCache returns the cache interface
 func (d *Dependencies) GetCache() interfaces.CacheInterface {
 	return d.Cache
 }
 
+// GetCacheInvalidator returns the invalidator that drops cache keys on every instance
+func (d *Dependencies) GetCacheInvalidator() interfaces.CacheInvalidator {
+	return d.Invalidator
+}
+
+// GetCacheMetrics returns the counters for cache lookups
+func (d *Dependencies) GetCacheMetrics() interfaces.CacheMetrics {
+	if d.CacheMetrics == nil {
+		return metrics.NopCacheMetrics{}
+	}
+	return d.CacheMetrics
+}
+
 // GetLogger returns a logger with optional component context
 func (d *Dependencies) GetLogger(component string) interfaces.LoggerInterface {
 	if component != "" {
@@ -64,6 +127,31 @@ func (d *Dependencies) GetLogger(component string) interfaces.LoggerInterface {
 	return d.Logger
 }
 
+// GetTokenService returns the JWT token service
+func (d *Dependencies) GetTokenService() *utils.TokenService {
+	return d.Tokens
+}
+
+// GetStorage returns the file storage used for uploads
+func (d *Dependencies) GetStorage() *storage.LocalStorage {
+	return d.Storage
+}
+
+// GetMailer returns the mailer used for transactional emails
+func (d *Dependencies) GetMailer() interfaces.Mailer {
+	return d.Mailer
+}
+
+// GetEventPublisher returns the publisher for domain events
+func (d *Dependencies) GetEventPublisher() interfaces.EventPublisher {
+	return d.Dispatcher
+}
+
+// InFlightRequests returns the number of HTTP requests currently being served
+func (d *Dependencies) InFlightRequests() int64 {
+	return d.InFlight.Count()
+}
+
 // GetConfig returns the application configuration
 func (d *Dependencies) GetConfig() *config.Config {
 	return d.Config
@@ -89,6 +177,12 @@ func (d *Dependencies) Close() error {
 		}
 	}
 	
+	if d.ReadDB != nil {
+		if err := d.ReadDB.Client().Disconnect(context.Background()); err != nil {
+			errors = append(errors, fmt.Errorf("failed to close read database: %w", err))
+		}
+	}
+	
 	// If there were any errors, return the first one
 	if len(errors) > 0 {
 		return errors[0]
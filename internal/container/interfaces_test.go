@@ -0,0 +1,62 @@
+// internal/container/interfaces_test.go
+package container
+
+import (
+	"strconv"
+	"testing"
+
+	"go-template/internal/config"
+)
+
+func TestNewDependenciesWithConfig(t *testing.T) {
+	newConfig := func(port string, maintenance bool) *config.Config {
+		t.Helper()
+		cfg, err := config.New(config.WithValues(map[string]string{
+			"MONGO_URL":        "mongodb://localhost:27017",
+			"REDIS_URL":        "redis://localhost:6379",
+			"JWT_SECRET":       "test-secret-test-secret-test-secret",
+			"PORT":             port,
+			"MAINTENANCE_MODE": strconv.FormatBool(maintenance),
+		}))
+		if err != nil {
+			t.Fatalf("config.New() error = %v", err)
+		}
+		return cfg
+	}
+
+	first := NewDependenciesWithConfig(newConfig("8081", true))
+	defer first.Cancel()
+	second := NewDependenciesWithConfig(newConfig("8082", false))
+	defer second.Cancel()
+
+	tests := []struct {
+		name            string
+		deps            *Dependencies
+		wantPort        string
+		wantMaintenance bool
+	}{
+		{name: "first", deps: first, wantPort: "8081", wantMaintenance: true},
+		{name: "second", deps: second, wantPort: "8082", wantMaintenance: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.deps.Config.Port != tt.wantPort {
+				t.Errorf("Config.Port = %q, want %q", tt.deps.Config.Port, tt.wantPort)
+			}
+			if got := tt.deps.Maintenance.Load(); got != tt.wantMaintenance {
+				t.Errorf("Maintenance = %v, want %v", got, tt.wantMaintenance)
+			}
+		})
+	}
+
+	if first.Mux == second.Mux || first.InFlight == second.InFlight || first.Maintenance == second.Maintenance {
+		t.Error("containers share state, want each to own its mux, in-flight counter and maintenance flag")
+	}
+
+	// Toggling one container must not leak into the other
+	first.Maintenance.Store(false)
+	second.Maintenance.Store(true)
+	if first.Maintenance.Load() || !second.Maintenance.Load() {
+		t.Error("maintenance flags are shared between containers")
+	}
+}
@@ -0,0 +1,77 @@
+// internal/container/migrations_test.go
+package container
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+
+	"go-template/internal/config"
+	"go-template/internal/database/migrations"
+	"go-template/internal/shared/logtest"
+)
+
+func TestRunMigrationsIndexConflict(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	conflict := mtest.CreateCommandErrorResponse(mtest.CommandError{Code: 85, Name: "IndexOptionsConflict", Message: "Index already exists with different options"})
+	unauthorized := mtest.CreateCommandErrorResponse(mtest.CommandError{Code: 13, Name: "Unauthorized", Message: "not authorized"})
+
+	tests := []struct {
+		name             string
+		failOnIndexError bool
+		createReply      bson.D
+		wantErr          bool
+		wantConflictLog  bool
+		wantContinueLog  bool
+	}{
+		{name: "conflict fails startup", failOnIndexError: true, createReply: conflict, wantErr: true, wantConflictLog: true},
+		{name: "conflict tolerated", failOnIndexError: false, createReply: conflict, wantConflictLog: true, wantContinueLog: true},
+		{name: "other failures always fail startup", failOnIndexError: false, createReply: unauthorized, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		mt.Run(tt.name, func(mt *mtest.T) {
+			// Only the last migration is pending; its first index cannot be created
+			applied := make([]bson.D, 0, 3)
+			for _, migration := range migrations.All()[:3] {
+				applied = append(applied, bson.D{{Key: "_id", Value: migration.Version}, {Key: "applied_at", Value: time.Now()}})
+			}
+			mt.AddMockResponses(
+				mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}),
+				mtest.CreateCursorResponse(0, "test._migrations", mtest.FirstBatch, applied...),
+				mtest.CreateCursorResponse(0, "test.users", mtest.FirstBatch),
+				tt.createReply,
+				mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}),
+			)
+
+			logger := logtest.New()
+			deps := &Dependencies{
+				Context: context.Background(),
+				Config:  &config.Config{FailOnIndexError: tt.failOnIndexError},
+				DB:      mt.DB,
+				Logger:  logger,
+			}
+
+			err := deps.runMigrations()
+			if (err != nil) != tt.wantErr {
+				mt.Fatalf("runMigrations() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			var indexErr *migrations.IndexConflictError
+			if tt.wantErr && tt.wantConflictLog && !errors.As(err, &indexErr) {
+				mt.Errorf("runMigrations() error = %v, want the index conflict", err)
+			}
+			if logged := logger.Has(slog.LevelError, "Index conflicts with existing indexes or data"); logged != tt.wantConflictLog {
+				mt.Errorf("conflict logged = %v, want %v", logged, tt.wantConflictLog)
+			}
+			if logged := logger.Has(slog.LevelWarn, "Continuing without the index because FAIL_ON_INDEX_ERROR is false"); logged != tt.wantContinueLog {
+				mt.Errorf("continue logged = %v, want %v", logged, tt.wantContinueLog)
+			}
+		})
+	}
+}
@@ -0,0 +1,155 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// CircuitState describes whether the Redis circuit breaker lets commands through
+type CircuitState string
+
+// Circuit breaker states
+const (
+	CircuitClosed   CircuitState = "closed"
+	CircuitOpen     CircuitState = "open"
+	CircuitHalfOpen CircuitState = "half-open"
+)
+
+// Circuit breaker defaults
+const (
+	DefaultBreakerThreshold = 5
+	DefaultBreakerCooldown  = 30 * time.Second
+)
+
+// ErrCircuitOpen is returned for Redis commands short-circuited while the breaker is open
+var ErrCircuitOpen = errors.New("redis circuit breaker is open")
+
+// circuitBreaker is a go-redis hook that stops sending commands to Redis after
+// repeated connection failures. Once the cool-down has elapsed a single probe
+// command is let through; its outcome closes or re-opens the circuit.
+type circuitBreaker struct {
+	mu        sync.Mutex
+	state     CircuitState
+	failures  int
+	openedAt  time.Time
+	probing   bool
+	threshold int
+	cooldown  time.Duration
+}
+
+// newCircuitBreaker creates a closed circuit breaker
+func newCircuitBreaker(threshold int, cooldown time.Duration) *circuitBreaker {
+	return &circuitBreaker{
+		state:     CircuitClosed,
+		threshold: threshold,
+		cooldown:  cooldown,
+	}
+}
+
+// State returns the current breaker state
+func (b *circuitBreaker) State() CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+// allow reports whether a command may be sent to Redis
+func (b *circuitBreaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case CircuitOpen:
+		if time.Since(b.openedAt) < b.cooldown {
+			return ErrCircuitOpen
+		}
+		b.state = CircuitHalfOpen
+		b.probing = true
+		return nil
+	case CircuitHalfOpen:
+		// Only one probe at a time while half-open
+		if b.probing {
+			return ErrCircuitOpen
+		}
+		b.probing = true
+		return nil
+	default:
+		return nil
+	}
+}
+
+// record updates the breaker with the outcome of a command
+func (b *circuitBreaker) record(ctx context.Context, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !isConnectionFailure(ctx, err) {
+		if b.state != CircuitClosed {
+			log.Println("Redis circuit breaker closed, cache is available again")
+		}
+		b.state = CircuitClosed
+		b.failures = 0
+		b.probing = false
+		return
+	}
+
+	b.failures++
+	b.probing = false
+	if b.state == CircuitHalfOpen || (b.state == CircuitClosed && b.failures >= b.threshold) {
+		b.state = CircuitOpen
+		b.openedAt = time.Now()
+		log.Printf("Redis circuit breaker opened after %d consecutive failures, cooling down for %s: %v",
+			b.failures, b.cooldown, err)
+	}
+}
+
+// isConnectionFailure reports whether err means Redis could not be reached
+// Replies from the server (including redis.Nil) and caller cancellations do not count.
+func isConnectionFailure(ctx context.Context, err error) bool {
+	if err == nil || ctx.Err() != nil {
+		return false
+	}
+
+	var redisErr redis.Error
+	return !errors.As(err, &redisErr)
+}
+
+// DialHook implements redis.Hook
+func (b *circuitBreaker) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+// ProcessHook implements redis.Hook
+func (b *circuitBreaker) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		if err := b.allow(); err != nil {
+			cmd.SetErr(err)
+			return err
+		}
+
+		err := next(ctx, cmd)
+		b.record(ctx, err)
+		return err
+	}
+}
+
+// ProcessPipelineHook implements redis.Hook
+func (b *circuitBreaker) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		if err := b.allow(); err != nil {
+			for _, cmd := range cmds {
+				cmd.SetErr(err)
+			}
+			return err
+		}
+
+		err := next(ctx, cmds)
+		b.record(ctx, err)
+		return err
+	}
+}
@@ -0,0 +1,124 @@
+// internal/database/breaker_test.go
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	errDown := errors.New("connection refused")
+
+	tests := []struct {
+		name string
+		// outcomes are recorded in order; nil is a successful command
+		outcomes  []error
+		wait      time.Duration
+		wantState CircuitState
+		wantAllow error
+	}{
+		{name: "starts closed", wantState: CircuitClosed},
+		{name: "failures below threshold", outcomes: []error{errDown, errDown}, wantState: CircuitClosed},
+		{name: "opens at threshold", outcomes: []error{errDown, errDown, errDown}, wantState: CircuitOpen, wantAllow: ErrCircuitOpen},
+		{name: "success resets the count", outcomes: []error{errDown, errDown, nil, errDown, errDown}, wantState: CircuitClosed},
+		{name: "server replies are not failures", outcomes: []error{redis.Nil, redis.Nil, redis.Nil}, wantState: CircuitClosed},
+		{name: "probe allowed after cool-down", outcomes: []error{errDown, errDown, errDown}, wait: 30 * time.Millisecond, wantState: CircuitOpen},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newCircuitBreaker(3, 20*time.Millisecond)
+			for _, err := range tt.outcomes {
+				b.record(context.Background(), err)
+			}
+			time.Sleep(tt.wait)
+
+			if got := b.State(); got != tt.wantState {
+				t.Errorf("State() = %q, want %q", got, tt.wantState)
+			}
+			if err := b.allow(); !errors.Is(err, tt.wantAllow) {
+				t.Errorf("allow() = %v, want %v", err, tt.wantAllow)
+			}
+		})
+	}
+}
+
+func TestCircuitBreakerHalfOpen(t *testing.T) {
+	errDown := errors.New("connection refused")
+
+	tests := []struct {
+		name      string
+		probe     error
+		wantState CircuitState
+	}{
+		{name: "successful probe closes", wantState: CircuitClosed},
+		{name: "failed probe re-opens", probe: errDown, wantState: CircuitOpen},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newCircuitBreaker(1, 10*time.Millisecond)
+			b.record(context.Background(), errDown)
+			time.Sleep(20 * time.Millisecond)
+
+			if err := b.allow(); err != nil {
+				t.Fatalf("allow() after cool-down = %v, want the probe let through", err)
+			}
+			if got := b.State(); got != CircuitHalfOpen {
+				t.Fatalf("State() = %q, want %q", got, CircuitHalfOpen)
+			}
+			// Only one probe runs at a time
+			if err := b.allow(); !errors.Is(err, ErrCircuitOpen) {
+				t.Fatalf("second allow() while probing = %v, want %v", err, ErrCircuitOpen)
+			}
+
+			b.record(context.Background(), tt.probe)
+			if got := b.State(); got != tt.wantState {
+				t.Errorf("State() after probe = %q, want %q", got, tt.wantState)
+			}
+		})
+	}
+}
+
+func TestRedisCacheCircuitBreaker(t *testing.T) {
+	ctx := context.Background()
+	server := miniredis.RunT(t)
+	cache := newRedisCache(redis.NewClient(&redis.Options{Addr: server.Addr(), MaxRetries: -1}))
+	t.Cleanup(func() { cache.client.Close() })
+	cache.breaker.cooldown = 50 * time.Millisecond
+
+	if err := cache.Set(ctx, "key", "value", time.Minute); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+
+	server.Close()
+	for i := 0; i < DefaultBreakerThreshold; i++ {
+		if _, err := cache.Get(ctx, "key"); err == nil {
+			t.Fatal("Get() with Redis down succeeded")
+		}
+	}
+	if got := cache.CircuitState(); got != CircuitOpen {
+		t.Fatalf("CircuitState() = %q after %d failures, want %q", got, DefaultBreakerThreshold, CircuitOpen)
+	}
+	if _, err := cache.Get(ctx, "key"); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Get() while open error = %v, want %v", err, ErrCircuitOpen)
+	}
+
+	if err := server.Restart(); err != nil {
+		t.Fatalf("Restart() error = %v", err)
+	}
+	time.Sleep(60 * time.Millisecond)
+
+	// The first call after the cool-down probes Redis and closes the circuit
+	if err := cache.Ping(ctx); err != nil {
+		t.Fatalf("Ping() after restart error = %v", err)
+	}
+	if got := cache.CircuitState(); got != CircuitClosed {
+		t.Errorf("CircuitState() after a successful probe = %q, want %q", got, CircuitClosed)
+	}
+}
@@ -0,0 +1,141 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"go-template/internal/interfaces"
+)
+
+// Distributed lock settings
+const (
+	lockKeyPrefix     = "lock:"
+	DefaultLockTTL    = 10 * time.Second
+	lockRetryInterval = 50 * time.Millisecond
+	lockMaxWait       = 2 * time.Second
+)
+
+// serializeValue converts a value to the form stored in the cache
+// Strings and byte slices are stored as is; anything else is serialized to JSON
+func serializeValue(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case []byte:
+		return v, nil
+	default:
+		return json.Marshal(value)
+	}
+}
+
+// getJSON retrieves and unmarshals a JSON value from cache
+func getJSON(ctx context.Context, cache interfaces.CacheInterface, key string, dest interface{}) error {
+	data, err := cache.Get(ctx, key)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal([]byte(data), dest)
+}
+
+// remember implements the cache-aside pattern
+// It tries to get from cache first; on a miss it calls the fetcher and stores the JSON-serialized
+// result for expiration before returning. The value is unmarshaled into dest on both paths,
+// so callers see the same typed result whether or not the cache was hit.
+// Everything runs on the caller's goroutine and stops once ctx is done.
+func remember(ctx context.Context, cache interfaces.CacheInterface, key string, expiration time.Duration, dest interface{}, fetcher func() (interface{}, error)) error {
+	// A caller that already gave up gets neither a cached nor a fetched value
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	// Try to get from cache first
+	if err := getJSON(ctx, cache, key, dest); err == nil {
+		return nil
+	}
+
+	// A lookup that failed because the caller gave up is not a miss
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	// Not in cache, call fetcher and store the result
+	return fetchAndStore(ctx, cache, key, expiration, dest, fetcher)
+}
+
+// rememberWithLock implements the cache-aside pattern with stampede protection
+// On a miss only the caller holding the lock runs the fetcher; other callers briefly
+// wait for the value to appear in cache and fall back to fetching themselves if it does not.
+// The result is unmarshaled into dest on every path.
+func rememberWithLock(ctx context.Context, cache interfaces.CacheInterface, key string, expiration time.Duration, dest interface{}, fetcher func() (interface{}, error)) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	// Try to get from cache first
+	if err := getJSON(ctx, cache, key, dest); err == nil {
+		return nil
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	lockKey := lockKeyPrefix + key
+	acquired, err := cache.Lock(ctx, lockKey, DefaultLockTTL)
+	if err != nil {
+		log.Printf("Cache lock unavailable for %s, fetching without lock: %v", key, err)
+		return fetchAndStore(ctx, cache, key, expiration, dest, fetcher)
+	}
+
+	if acquired {
+		defer cache.Unlock(context.Background(), lockKey)
+
+		// Another caller may have filled the cache while we acquired the lock
+		if err := getJSON(ctx, cache, key, dest); err == nil {
+			return nil
+		}
+		return fetchAndStore(ctx, cache, key, expiration, dest, fetcher)
+	}
+
+	// Someone else is fetching; wait for the value to land in cache
+	deadline := time.Now().Add(lockMaxWait)
+	for time.Now().Before(deadline) {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(lockRetryInterval):
+		}
+
+		if err := getJSON(ctx, cache, key, dest); err == nil {
+			return nil
+		}
+	}
+
+	log.Printf("Timed out waiting for cache key %s, fetching directly", key)
+	return fetchAndStore(ctx, cache, key, expiration, dest, fetcher)
+}
+
+// fetchAndStore calls the fetcher, stores the result in cache and unmarshals it into dest
+func fetchAndStore(ctx context.Context, cache interfaces.CacheInterface, key string, expiration time.Duration, dest interface{}, fetcher func() (interface{}, error)) error {
+	value, err := fetcher()
+	if err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to serialize value for key %s: %w", key, err)
+	}
+
+	if err := cache.Set(ctx, key, data, expiration); err != nil {
+		log.Printf("Failed to cache value for key %s: %v", key, err)
+	}
+
+	return json.Unmarshal(data, dest)
+}
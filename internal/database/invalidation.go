@@ -0,0 +1,142 @@
+package database
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"go-template/internal/interfaces"
+)
+
+// InvalidationChannel is the pub/sub channel carrying cache invalidation events
+const InvalidationChannel = "cache:invalidate"
+
+// Subscription retry backoff
+const (
+	invalidationMinBackoff = time.Second
+	invalidationMaxBackoff = 30 * time.Second
+)
+
+// InvalidationEvent lists cache keys that every instance must drop
+type InvalidationEvent struct {
+	Origin string   `json:"origin"`
+	Keys   []string `json:"keys"`
+}
+
+// Invalidator deletes cache keys locally and broadcasts the deletion to other instances
+// Each instance runs a subscriber (see Run) that deletes the keys it receives, so copies
+// held by instances that do not share the same cache are dropped as well.
+type Invalidator struct {
+	cache      interfaces.CacheInterface
+	logger     interfaces.LoggerInterface
+	instanceID string
+}
+
+// NewInvalidator creates an Invalidator with a random instance ID
+func NewInvalidator(cache interfaces.CacheInterface, logger interfaces.LoggerInterface) *Invalidator {
+	idBytes := make([]byte, 8)
+	if _, err := rand.Read(idBytes); err != nil {
+		// Fall back to a time-based ID; it only needs to be unique among running instances
+		idBytes = []byte(fmt.Sprintf("%08x", time.Now().UnixNano()))
+	}
+
+	return &Invalidator{
+		cache:      cache,
+		logger:     logger,
+		instanceID: hex.EncodeToString(idBytes),
+	}
+}
+
+// Invalidate deletes keys from the local cache and publishes them to other instances
+func (i *Invalidator) Invalidate(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	var errs []error
+	if err := i.cache.Delete(ctx, keys...); err != nil {
+		errs = append(errs, fmt.Errorf("failed to delete cache keys: %w", err))
+	}
+
+	event := InvalidationEvent{Origin: i.instanceID, Keys: keys}
+	if err := i.cache.Publish(ctx, InvalidationChannel, event); err != nil {
+		errs = append(errs, fmt.Errorf("failed to publish invalidation event: %w", err))
+	}
+
+	return errors.Join(errs...)
+}
+
+// Run subscribes to invalidation events and deletes the received keys until ctx is cancelled
+// The subscription is re-established with exponential backoff whenever it fails.
+func (i *Invalidator) Run(ctx context.Context) {
+	backoff := invalidationMinBackoff
+	for {
+		err := i.subscribe(ctx)
+		if ctx.Err() != nil {
+			i.logger.Info("Cache invalidation subscriber stopped")
+			return
+		}
+
+		i.logger.Warn("Cache invalidation subscription lost, retrying",
+			"error", fmt.Sprint(err), "retry_in", backoff.String())
+
+		select {
+		case <-ctx.Done():
+			i.logger.Info("Cache invalidation subscriber stopped")
+			return
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+		if backoff > invalidationMaxBackoff {
+			backoff = invalidationMaxBackoff
+		}
+	}
+}
+
+// subscribe listens on the invalidation channel until the subscription ends
+func (i *Invalidator) subscribe(ctx context.Context) error {
+	sub, err := i.cache.Subscribe(ctx, InvalidationChannel)
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to %s: %w", InvalidationChannel, err)
+	}
+	defer sub.Close()
+	i.logger.Info("Subscribed to cache invalidation events", "channel", InvalidationChannel, "instance_id", i.instanceID)
+
+	messages := sub.Messages()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case msg, ok := <-messages:
+			if !ok {
+				return errors.New("subscription channel closed")
+			}
+			i.handle(ctx, msg.Payload)
+		}
+	}
+}
+
+// handle deletes the keys carried by an invalidation event published by another instance
+func (i *Invalidator) handle(ctx context.Context, payload string) {
+	var event InvalidationEvent
+	if err := json.Unmarshal([]byte(payload), &event); err != nil {
+		i.logger.Warn("Ignoring malformed cache invalidation event", "error", err.Error())
+		return
+	}
+
+	// Our own deletions were applied before publishing
+	if event.Origin == i.instanceID || len(event.Keys) == 0 {
+		return
+	}
+
+	if err := i.cache.Delete(ctx, event.Keys...); err != nil {
+		i.logger.Warn("Failed to apply cache invalidation event", "keys", event.Keys, "error", err.Error())
+		return
+	}
+	i.logger.Debug("Applied cache invalidation event", "origin", event.Origin, "keys", event.Keys)
+}
@@ -0,0 +1,186 @@
+// internal/database/invalidation_test.go
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+
+	"go-template/internal/shared/logtest"
+)
+
+// invalidationInstance is one API instance: its own cache database and invalidator
+type invalidationInstance struct {
+	cache       *RedisCache
+	invalidator *Invalidator
+}
+
+// newInvalidationInstance connects an instance to logical database db of server
+// Redis pub/sub spans every database, so instances share events but not keys.
+func newInvalidationInstance(t *testing.T, server *miniredis.Miniredis, db int) *invalidationInstance {
+	t.Helper()
+	cache := newRedisCache(redis.NewClient(&redis.Options{Addr: server.Addr(), DB: db}))
+	t.Cleanup(func() { cache.client.Close() })
+	return &invalidationInstance{cache: cache, invalidator: NewInvalidator(cache, logtest.New())}
+}
+
+// run starts the instance's subscriber and waits until it is listening
+func (i *invalidationInstance) run(t *testing.T, server *miniredis.Miniredis) {
+	t.Helper()
+	before := server.PubSubNumSub(InvalidationChannel)[InvalidationChannel]
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		i.invalidator.Run(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	waitFor(t, "subscription", func() bool {
+		return server.PubSubNumSub(InvalidationChannel)[InvalidationChannel] > before
+	})
+}
+
+// waitFor polls cond until it holds, failing the test after a second
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// exists reports whether key is in the instance's cache
+func (i *invalidationInstance) exists(t *testing.T, key string) bool {
+	t.Helper()
+	exists, err := i.cache.Exists(context.Background(), key)
+	if err != nil {
+		t.Fatalf("Exists() error = %v", err)
+	}
+	return exists
+}
+
+func TestInvalidatorAcrossInstances(t *testing.T) {
+	ctx := context.Background()
+	server := miniredis.RunT(t)
+	first := newInvalidationInstance(t, server, 0)
+	second := newInvalidationInstance(t, server, 1)
+	first.run(t, server)
+	second.run(t, server)
+
+	for _, instance := range []*invalidationInstance{first, second} {
+		if err := instance.cache.Set(ctx, "user:id:1", "cached", time.Hour); err != nil {
+			t.Fatalf("Set() error = %v", err)
+		}
+		if err := instance.cache.Set(ctx, "user:id:2", "cached", time.Hour); err != nil {
+			t.Fatalf("Set() error = %v", err)
+		}
+	}
+
+	if err := first.invalidator.Invalidate(ctx, "user:id:1"); err != nil {
+		t.Fatalf("Invalidate() error = %v", err)
+	}
+
+	if first.exists(t, "user:id:1") {
+		t.Error("key still cached on the invalidating instance")
+	}
+	waitFor(t, "the other instance to drop the key", func() bool { return !second.exists(t, "user:id:1") })
+	if !first.exists(t, "user:id:2") || !second.exists(t, "user:id:2") {
+		t.Error("an unrelated key was dropped")
+	}
+}
+
+func TestInvalidatorIgnoredEvents(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		// payload builds the raw message published for the subscribing instance
+		payload    func(i *invalidationInstance) string
+		wantExists bool
+	}{
+		{
+			name: "event from another instance",
+			payload: func(i *invalidationInstance) string {
+				return mustMarshal(t, InvalidationEvent{Origin: "other", Keys: []string{"user:id:1"}})
+			},
+		},
+		{
+			name: "own event",
+			payload: func(i *invalidationInstance) string {
+				return mustMarshal(t, InvalidationEvent{Origin: i.invalidator.instanceID, Keys: []string{"user:id:1"}})
+			},
+			wantExists: true,
+		},
+		{
+			name:       "malformed event",
+			payload:    func(i *invalidationInstance) string { return "not json" },
+			wantExists: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := miniredis.RunT(t)
+			instance := newInvalidationInstance(t, server, 0)
+			instance.run(t, server)
+			if err := instance.cache.Set(ctx, "user:id:1", "cached", time.Hour); err != nil {
+				t.Fatalf("Set() error = %v", err)
+			}
+			if err := instance.cache.Set(ctx, "sentinel", "cached", time.Hour); err != nil {
+				t.Fatalf("Set() error = %v", err)
+			}
+
+			server.Publish(InvalidationChannel, tt.payload(instance))
+			// Events are handled in order, so once the sentinel is gone the event above was applied
+			server.Publish(InvalidationChannel, mustMarshal(t, InvalidationEvent{Origin: "other", Keys: []string{"sentinel"}}))
+			waitFor(t, "the sentinel event", func() bool { return !instance.exists(t, "sentinel") })
+
+			if got := instance.exists(t, "user:id:1"); got != tt.wantExists {
+				t.Errorf("key exists = %v, want %v", got, tt.wantExists)
+			}
+		})
+	}
+}
+
+// mustMarshal encodes v as JSON, failing the test on error
+func mustMarshal(t *testing.T, v interface{}) string {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	return string(data)
+}
+
+func TestInvalidatorResubscribesAfterRestart(t *testing.T) {
+	ctx := context.Background()
+	server := miniredis.RunT(t)
+	instance := newInvalidationInstance(t, server, 0)
+	instance.run(t, server)
+
+	server.Close()
+	if err := server.Restart(); err != nil {
+		t.Fatalf("Restart() error = %v", err)
+	}
+	waitFor(t, "the subscription to be restored", func() bool {
+		return server.PubSubNumSub(InvalidationChannel)[InvalidationChannel] > 0
+	})
+
+	if err := instance.cache.Set(ctx, "user:id:1", "cached", time.Hour); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	server.Publish(InvalidationChannel, mustMarshal(t, InvalidationEvent{Origin: "other", Keys: []string{"user:id:1"}}))
+	waitFor(t, "the key to be dropped", func() bool { return !instance.exists(t, "user:id:1") })
+}
@@ -0,0 +1,498 @@
+package database
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"go-template/internal/interfaces"
+)
+
+// Special TTL results, matching what Redis reports
+const (
+	ttlNoExpiry   = time.Duration(-1) // the key exists but never expires
+	ttlMissingKey = time.Duration(-2) // the key does not exist
+)
+
+// memorySweepInterval is how often expired entries are purged from a MemoryCache
+const memorySweepInterval = time.Minute
+
+// memorySubscriberBuffer is how many undelivered messages a subscriber may queue
+// before further messages to it are dropped
+const memorySubscriberBuffer = 64
+
+// ErrCacheClosed is returned by MemoryCache operations after Close
+var ErrCacheClosed = errors.New("cache is closed")
+
+// memoryEntry is a cached value with an optional expiry
+type memoryEntry struct {
+	value     string
+	expiresAt time.Time // zero means no expiry
+}
+
+// expired reports whether the entry has expired at now
+func (e memoryEntry) expired(now time.Time) bool {
+	return !e.expiresAt.IsZero() && !now.Before(e.expiresAt)
+}
+
+// MemoryCache implements the CacheInterface in process memory.
+// It is meant for tests and local development without Redis: data is lost on restart,
+// and pub/sub and locks only reach subscribers and callers in the same process.
+type MemoryCache struct {
+	mu          sync.Mutex
+	entries     map[string]memoryEntry
+	lockTokens  map[string]string
+	subscribers map[string]map[*memorySubscription]struct{}
+	closed      bool
+	stop        chan struct{}
+}
+
+// NewMemoryCache creates an empty MemoryCache and starts purging expired entries
+func NewMemoryCache() *MemoryCache {
+	cache := &MemoryCache{
+		entries:     make(map[string]memoryEntry),
+		lockTokens:  make(map[string]string),
+		subscribers: make(map[string]map[*memorySubscription]struct{}),
+		stop:        make(chan struct{}),
+	}
+	go cache.sweep()
+	return cache
+}
+
+// sweep periodically removes expired entries so unread keys do not accumulate
+func (m *MemoryCache) sweep() {
+	ticker := time.NewTicker(memorySweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.stop:
+			return
+		case now := <-ticker.C:
+			m.mu.Lock()
+			for key, entry := range m.entries {
+				if entry.expired(now) {
+					delete(m.entries, key)
+				}
+			}
+			m.mu.Unlock()
+		}
+	}
+}
+
+// lookup returns the live entry for key, dropping it if it has expired
+// The caller must hold m.mu
+func (m *MemoryCache) lookup(key string) (memoryEntry, bool) {
+	entry, ok := m.entries[key]
+	if !ok {
+		return memoryEntry{}, false
+	}
+	if entry.expired(time.Now()) {
+		delete(m.entries, key)
+		return memoryEntry{}, false
+	}
+	return entry, true
+}
+
+// store sets key to value, expiring after expiration unless it is zero
+// The caller must hold m.mu
+func (m *MemoryCache) store(key, value string, expiration time.Duration) {
+	entry := memoryEntry{value: value}
+	if expiration > 0 {
+		entry.expiresAt = time.Now().Add(expiration)
+	}
+	m.entries[key] = entry
+}
+
+// Get retrieves a value from cache
+func (m *MemoryCache) Get(ctx context.Context, key string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return "", ErrCacheClosed
+	}
+	entry, ok := m.lookup(key)
+	if !ok {
+		return "", fmt.Errorf("%w: %s", interfaces.ErrCacheMiss, key)
+	}
+	return entry.value, nil
+}
+
+// GetDel atomically retrieves a value and removes it from cache
+func (m *MemoryCache) GetDel(ctx context.Context, key string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return "", ErrCacheClosed
+	}
+	entry, ok := m.lookup(key)
+	if !ok {
+		return "", fmt.Errorf("%w: %s", interfaces.ErrCacheMiss, key)
+	}
+	delete(m.entries, key)
+	return entry.value, nil
+}
+
+// Set stores a value in cache with expiration; zero expiration keeps it forever
+func (m *MemoryCache) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error {
+	serialized, err := serializeString(value)
+	if err != nil {
+		return fmt.Errorf("failed to serialize value for key %s: %w", key, err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return ErrCacheClosed
+	}
+	m.store(key, serialized, expiration)
+	return nil
+}
+
+// Delete removes one or more keys from cache
+func (m *MemoryCache) Delete(ctx context.Context, keys ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return ErrCacheClosed
+	}
+	for _, key := range keys {
+		delete(m.entries, key)
+	}
+	return nil
+}
+
+// Exists checks if a key exists in cache
+func (m *MemoryCache) Exists(ctx context.Context, key string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return false, ErrCacheClosed
+	}
+	_, ok := m.lookup(key)
+	return ok, nil
+}
+
+// MGet retrieves multiple values at once; missing keys yield nil like Redis
+func (m *MemoryCache) MGet(ctx context.Context, keys ...string) ([]interface{}, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return nil, ErrCacheClosed
+	}
+	values := make([]interface{}, len(keys))
+	for i, key := range keys {
+		if entry, ok := m.lookup(key); ok {
+			values[i] = entry.value
+		}
+	}
+	return values, nil
+}
+
+// MSet sets multiple key-value pairs at once, given as key1, value1, key2, value2, ...
+func (m *MemoryCache) MSet(ctx context.Context, pairs ...interface{}) error {
+	if len(pairs)%2 != 0 {
+		return fmt.Errorf("MSet requires key-value pairs, got %d arguments", len(pairs))
+	}
+
+	values := make(map[string]string, len(pairs)/2)
+	for i := 0; i < len(pairs); i += 2 {
+		key, ok := pairs[i].(string)
+		if !ok {
+			return fmt.Errorf("MSet key at position %d must be a string, got %T", i, pairs[i])
+		}
+		value, err := serializeString(pairs[i+1])
+		if err != nil {
+			return fmt.Errorf("failed to serialize value for key %s: %w", key, err)
+		}
+		values[key] = value
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return ErrCacheClosed
+	}
+	for key, value := range values {
+		m.store(key, value, 0)
+	}
+	return nil
+}
+
+// Increment increments a numeric value, starting from 0 for missing keys
+// The key keeps its expiry, as with Redis INCR
+func (m *MemoryCache) Increment(ctx context.Context, key string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return 0, ErrCacheClosed
+	}
+
+	entry, ok := m.lookup(key)
+	var current int64
+	if ok {
+		parsed, err := strconv.ParseInt(entry.value, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("value of key %s is not an integer", key)
+		}
+		current = parsed
+	}
+
+	entry.value = strconv.FormatInt(current+1, 10)
+	m.entries[key] = entry
+	return current + 1, nil
+}
+
+// Expire sets expiration time for a key; missing keys are ignored
+// A non-positive expiration deletes the key, as with Redis EXPIRE
+func (m *MemoryCache) Expire(ctx context.Context, key string, expiration time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return ErrCacheClosed
+	}
+
+	entry, ok := m.lookup(key)
+	if !ok {
+		return nil
+	}
+	if expiration <= 0 {
+		delete(m.entries, key)
+		return nil
+	}
+	entry.expiresAt = time.Now().Add(expiration)
+	m.entries[key] = entry
+	return nil
+}
+
+// TTL returns the time to live for a key
+// Like Redis it returns -1 for keys without expiry and -2 for missing keys
+func (m *MemoryCache) TTL(ctx context.Context, key string) (time.Duration, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return 0, ErrCacheClosed
+	}
+
+	entry, ok := m.lookup(key)
+	if !ok {
+		return ttlMissingKey, nil
+	}
+	if entry.expiresAt.IsZero() {
+		return ttlNoExpiry, nil
+	}
+	return time.Until(entry.expiresAt), nil
+}
+
+// FlushAll removes all keys
+func (m *MemoryCache) FlushAll(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return ErrCacheClosed
+	}
+	m.entries = make(map[string]memoryEntry)
+	m.lockTokens = make(map[string]string)
+	return nil
+}
+
+// Ping reports whether the cache is still open
+func (m *MemoryCache) Ping(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return ErrCacheClosed
+	}
+	return nil
+}
+
+// Close discards all data and ends every subscription
+func (m *MemoryCache) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return nil
+	}
+	m.closed = true
+	close(m.stop)
+
+	for _, subs := range m.subscribers {
+		for sub := range subs {
+			sub.closeLocked()
+		}
+	}
+	m.subscribers = nil
+	m.entries = nil
+	m.lockTokens = nil
+
+	log.Println("In-memory cache closed")
+	return nil
+}
+
+// Publish delivers a message to every subscriber of channel in this process
+// Subscribers that fall too far behind miss messages instead of blocking the publisher
+func (m *MemoryCache) Publish(ctx context.Context, channel string, message interface{}) error {
+	payload, err := serializeString(message)
+	if err != nil {
+		return fmt.Errorf("failed to serialize message for channel %s: %w", channel, err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return ErrCacheClosed
+	}
+	for sub := range m.subscribers[channel] {
+		select {
+		case sub.messages <- &interfaces.Message{Channel: channel, Payload: payload}:
+		default:
+			log.Printf("Dropping message on channel %s for a slow subscriber", channel)
+		}
+	}
+	return nil
+}
+
+// Subscribe subscribes to one or more channels
+func (m *MemoryCache) Subscribe(ctx context.Context, channels ...string) (interfaces.Subscription, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return nil, ErrCacheClosed
+	}
+
+	sub := &memorySubscription{
+		cache:    m,
+		channels: channels,
+		messages: make(chan *interfaces.Message, memorySubscriberBuffer),
+	}
+	for _, channel := range channels {
+		if m.subscribers[channel] == nil {
+			m.subscribers[channel] = make(map[*memorySubscription]struct{})
+		}
+		m.subscribers[channel][sub] = struct{}{}
+	}
+	return sub, nil
+}
+
+// Lock acquires a lock if key is not already set; it expires after ttl if never released
+func (m *MemoryCache) Lock(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	tokenBytes := make([]byte, 16)
+	if _, err := rand.Read(tokenBytes); err != nil {
+		return false, fmt.Errorf("failed to generate lock token: %w", err)
+	}
+	token := hex.EncodeToString(tokenBytes)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return false, ErrCacheClosed
+	}
+	if _, held := m.lookup(key); held {
+		return false, nil
+	}
+	m.store(key, token, ttl)
+	m.lockTokens[key] = token
+	return true, nil
+}
+
+// Unlock releases a lock previously acquired with Lock
+// Locks that have expired or were re-acquired since are left untouched
+func (m *MemoryCache) Unlock(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return nil
+	}
+	token, ok := m.lockTokens[key]
+	if !ok {
+		return nil
+	}
+	delete(m.lockTokens, key)
+
+	if entry, held := m.lookup(key); held && entry.value == token {
+		delete(m.entries, key)
+	}
+	return nil
+}
+
+// Remember implements the cache-aside pattern, see remember
+func (m *MemoryCache) Remember(ctx context.Context, key string, expiration time.Duration, dest interface{}, fetcher func() (interface{}, error)) error {
+	return remember(ctx, m, key, expiration, dest, fetcher)
+}
+
+// RememberWithLock implements the cache-aside pattern with stampede protection, see rememberWithLock
+func (m *MemoryCache) RememberWithLock(ctx context.Context, key string, expiration time.Duration, dest interface{}, fetcher func() (interface{}, error)) error {
+	return rememberWithLock(ctx, m, key, expiration, dest, fetcher)
+}
+
+// serializeString converts a value to the string stored in the cache, see serializeValue
+func serializeString(value interface{}) (string, error) {
+	serialized, err := serializeValue(value)
+	if err != nil {
+		return "", err
+	}
+	switch v := serialized.(type) {
+	case string:
+		return v, nil
+	case []byte:
+		return string(v), nil
+	default:
+		return fmt.Sprint(v), nil
+	}
+}
+
+// memorySubscription is an interfaces.Subscription backed by a MemoryCache
+type memorySubscription struct {
+	cache    *MemoryCache
+	channels []string
+	messages chan *interfaces.Message
+	closed   bool
+}
+
+// Messages returns the channel messages are delivered on
+func (s *memorySubscription) Messages() <-chan *interfaces.Message {
+	return s.messages
+}
+
+// Close unsubscribes from all channels
+func (s *memorySubscription) Close() error {
+	s.cache.mu.Lock()
+	defer s.cache.mu.Unlock()
+
+	for _, channel := range s.channels {
+		delete(s.cache.subscribers[channel], s)
+	}
+	s.closeLocked()
+	return nil
+}
+
+// closeLocked closes the message channel once; the caller must hold the cache mutex
+func (s *memorySubscription) closeLocked() {
+	if !s.closed {
+		s.closed = true
+		close(s.messages)
+	}
+}
@@ -0,0 +1,285 @@
+// internal/database/memory_cache_test.go
+package database
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"go-template/internal/interfaces"
+)
+
+// cacheBackend is a CacheInterface under test with a way to let time pass for its keys
+type cacheBackend struct {
+	name string
+	ttl  time.Duration // shortest expiry the backend honors
+	open func(t *testing.T) (interfaces.CacheInterface, func(time.Duration))
+}
+
+// cacheBackends runs the semantic tests against MemoryCache and, for parity, Redis
+var cacheBackends = []cacheBackend{
+	{
+		name: "memory",
+		ttl:  30 * time.Millisecond,
+		open: func(t *testing.T) (interfaces.CacheInterface, func(time.Duration)) {
+			cache := NewMemoryCache()
+			t.Cleanup(func() { cache.Close() })
+			return cache, time.Sleep
+		},
+	},
+	{
+		name: "redis",
+		ttl:  2 * time.Second, // EXPIRE and TTL work in whole seconds
+		open: func(t *testing.T) (interfaces.CacheInterface, func(time.Duration)) {
+			cache, server := newTestRedisCache(t)
+			return cache, server.FastForward
+		},
+	},
+}
+
+func TestCacheSemantics(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		run  func(t *testing.T, cache interfaces.CacheInterface, advance func(time.Duration), ttl time.Duration)
+	}{
+		{
+			name: "get missing key",
+			run: func(t *testing.T, cache interfaces.CacheInterface, advance func(time.Duration), ttl time.Duration) {
+				if _, err := cache.Get(ctx, "missing"); !errors.Is(err, interfaces.ErrCacheMiss) {
+					t.Errorf("Get() error = %v, want ErrCacheMiss", err)
+				}
+			},
+		},
+		{
+			name: "set and get",
+			run: func(t *testing.T, cache interfaces.CacheInterface, advance func(time.Duration), ttl time.Duration) {
+				mustSet(t, cache, "key", "value", 0)
+				if got, err := cache.Get(ctx, "key"); err != nil || got != "value" {
+					t.Errorf("Get() = %q, %v, want value", got, err)
+				}
+			},
+		},
+		{
+			name: "key expires",
+			run: func(t *testing.T, cache interfaces.CacheInterface, advance func(time.Duration), ttl time.Duration) {
+				mustSet(t, cache, "key", "value", ttl)
+				if got, _ := cache.TTL(ctx, "key"); got <= 0 || got > ttl {
+					t.Errorf("TTL() = %v, want within (0, %v]", got, ttl)
+				}
+				advance(ttl + ttl/2)
+				if _, err := cache.Get(ctx, "key"); !errors.Is(err, interfaces.ErrCacheMiss) {
+					t.Errorf("Get() error = %v after expiry, want ErrCacheMiss", err)
+				}
+				if exists, _ := cache.Exists(ctx, "key"); exists {
+					t.Error("Exists() = true after expiry")
+				}
+				if got, _ := cache.TTL(ctx, "key"); got != ttlMissingKey {
+					t.Errorf("TTL() = %v after expiry, want %v", got, ttlMissingKey)
+				}
+			},
+		},
+		{
+			name: "zero expiration keeps the key",
+			run: func(t *testing.T, cache interfaces.CacheInterface, advance func(time.Duration), ttl time.Duration) {
+				mustSet(t, cache, "key", "value", 0)
+				advance(ttl + ttl/2)
+				if got, _ := cache.TTL(ctx, "key"); got != ttlNoExpiry {
+					t.Errorf("TTL() = %v, want %v", got, ttlNoExpiry)
+				}
+				if exists, _ := cache.Exists(ctx, "key"); !exists {
+					t.Error("Exists() = false, want true")
+				}
+			},
+		},
+		{
+			name: "increment counts from zero",
+			run: func(t *testing.T, cache interfaces.CacheInterface, advance func(time.Duration), ttl time.Duration) {
+				for want := int64(1); want <= 3; want++ {
+					if got, err := cache.Increment(ctx, "counter"); err != nil || got != want {
+						t.Fatalf("Increment() = %d, %v, want %d", got, err, want)
+					}
+				}
+				if got, _ := cache.Get(ctx, "counter"); got != "3" {
+					t.Errorf("Get() = %q, want 3", got)
+				}
+			},
+		},
+		{
+			name: "increment keeps the expiry",
+			run: func(t *testing.T, cache interfaces.CacheInterface, advance func(time.Duration), ttl time.Duration) {
+				cache.Increment(ctx, "counter")
+				if err := cache.Expire(ctx, "counter", ttl); err != nil {
+					t.Fatalf("Expire() error = %v", err)
+				}
+				cache.Increment(ctx, "counter")
+				if got, _ := cache.TTL(ctx, "counter"); got <= 0 {
+					t.Errorf("TTL() = %v after Increment, want the expiry kept", got)
+				}
+				advance(ttl + ttl/2)
+				if got, _ := cache.Increment(ctx, "counter"); got != 1 {
+					t.Errorf("Increment() = %d after expiry, want 1", got)
+				}
+			},
+		},
+		{
+			name: "increment rejects non-integers",
+			run: func(t *testing.T, cache interfaces.CacheInterface, advance func(time.Duration), ttl time.Duration) {
+				mustSet(t, cache, "key", "abc", 0)
+				if _, err := cache.Increment(ctx, "key"); err == nil {
+					t.Error("Increment() error = nil, want an error")
+				}
+			},
+		},
+		{
+			name: "expire on a missing key is a no-op",
+			run: func(t *testing.T, cache interfaces.CacheInterface, advance func(time.Duration), ttl time.Duration) {
+				if err := cache.Expire(ctx, "missing", ttl); err != nil {
+					t.Errorf("Expire() error = %v", err)
+				}
+				if exists, _ := cache.Exists(ctx, "missing"); exists {
+					t.Error("Expire() created the key")
+				}
+			},
+		},
+		{
+			name: "delete removes every key and ignores missing ones",
+			run: func(t *testing.T, cache interfaces.CacheInterface, advance func(time.Duration), ttl time.Duration) {
+				mustSet(t, cache, "a", "1", 0)
+				mustSet(t, cache, "b", "2", 0)
+				mustSet(t, cache, "c", "3", 0)
+				if err := cache.Delete(ctx, "a", "b", "missing"); err != nil {
+					t.Fatalf("Delete() error = %v", err)
+				}
+				for key, want := range map[string]bool{"a": false, "b": false, "c": true} {
+					if exists, _ := cache.Exists(ctx, key); exists != want {
+						t.Errorf("Exists(%s) = %v, want %v", key, exists, want)
+					}
+				}
+			},
+		},
+		{
+			name: "getdel returns and removes",
+			run: func(t *testing.T, cache interfaces.CacheInterface, advance func(time.Duration), ttl time.Duration) {
+				mustSet(t, cache, "key", "value", 0)
+				if got, err := cache.GetDel(ctx, "key"); err != nil || got != "value" {
+					t.Fatalf("GetDel() = %q, %v, want value", got, err)
+				}
+				if _, err := cache.GetDel(ctx, "key"); !errors.Is(err, interfaces.ErrCacheMiss) {
+					t.Errorf("second GetDel() error = %v, want ErrCacheMiss", err)
+				}
+			},
+		},
+		{
+			name: "mset and mget",
+			run: func(t *testing.T, cache interfaces.CacheInterface, advance func(time.Duration), ttl time.Duration) {
+				if err := cache.MSet(ctx, "a", "1", "b", 2); err != nil {
+					t.Fatalf("MSet() error = %v", err)
+				}
+				got, err := cache.MGet(ctx, "a", "missing", "b")
+				if err != nil {
+					t.Fatalf("MGet() error = %v", err)
+				}
+				if want := []interface{}{"1", nil, "2"}; !reflect.DeepEqual(got, want) {
+					t.Errorf("MGet() = %v, want %v", got, want)
+				}
+			},
+		},
+		{
+			name: "lock is exclusive until released",
+			run: func(t *testing.T, cache interfaces.CacheInterface, advance func(time.Duration), ttl time.Duration) {
+				if ok, err := cache.Lock(ctx, "lock", time.Minute); err != nil || !ok {
+					t.Fatalf("Lock() = %v, %v, want acquired", ok, err)
+				}
+				if ok, _ := cache.Lock(ctx, "lock", time.Minute); ok {
+					t.Error("second Lock() acquired a held lock")
+				}
+				if err := cache.Unlock(ctx, "lock"); err != nil {
+					t.Fatalf("Unlock() error = %v", err)
+				}
+				if ok, _ := cache.Lock(ctx, "lock", time.Minute); !ok {
+					t.Error("Lock() failed after Unlock")
+				}
+			},
+		},
+	}
+
+	for _, backend := range cacheBackends {
+		for _, tt := range tests {
+			t.Run(backend.name+"/"+tt.name, func(t *testing.T) {
+				cache, advance := backend.open(t)
+				tt.run(t, cache, advance, backend.ttl)
+			})
+		}
+	}
+}
+
+func TestMemoryCachePubSub(t *testing.T) {
+	ctx := context.Background()
+	cache := NewMemoryCache()
+	defer cache.Close()
+
+	sub, err := cache.Subscribe(ctx, "events")
+	if err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+	if err := cache.Publish(ctx, "other", "ignored"); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+	if err := cache.Publish(ctx, "events", "hello"); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+
+	select {
+	case msg := <-sub.Messages():
+		if msg.Channel != "events" || msg.Payload != "hello" {
+			t.Errorf("message = %+v, want hello on events", msg)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no message delivered")
+	}
+
+	if err := sub.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if _, open := <-sub.Messages(); open {
+		t.Error("message channel still open after Close")
+	}
+	if err := cache.Publish(ctx, "events", "after close"); err != nil {
+		t.Errorf("Publish() after unsubscribe error = %v", err)
+	}
+}
+
+func TestMemoryCacheClosed(t *testing.T) {
+	ctx := context.Background()
+	cache := NewMemoryCache()
+	sub, _ := cache.Subscribe(ctx, "events")
+	cache.Close()
+
+	if _, open := <-sub.Messages(); open {
+		t.Error("subscription still open after the cache closed")
+	}
+	if _, err := cache.Get(ctx, "key"); !errors.Is(err, ErrCacheClosed) {
+		t.Errorf("Get() error = %v, want ErrCacheClosed", err)
+	}
+	if err := cache.Set(ctx, "key", "value", 0); !errors.Is(err, ErrCacheClosed) {
+		t.Errorf("Set() error = %v, want ErrCacheClosed", err)
+	}
+	if err := cache.Ping(ctx); !errors.Is(err, ErrCacheClosed) {
+		t.Errorf("Ping() error = %v, want ErrCacheClosed", err)
+	}
+	if err := cache.Close(); err != nil {
+		t.Errorf("second Close() error = %v", err)
+	}
+}
+
+// mustSet stores value under key, failing the test on error
+func mustSet(t *testing.T, cache interfaces.CacheInterface, key string, value interface{}, expiration time.Duration) {
+	t.Helper()
+	if err := cache.Set(context.Background(), key, value, expiration); err != nil {
+		t.Fatalf("Set(%s) error = %v", key, err)
+	}
+}
@@ -0,0 +1,50 @@
+// internal/database/migrations/001_user_indexes.go
+package migrations
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// userIndexes creates the baseline indexes for the users collection
+var userIndexes = Migration{
+	Version:     "001_user_indexes",
+	Description: "Create baseline indexes on users",
+	Up: func(ctx context.Context, db *mongo.Database) error {
+		indexes := []mongo.IndexModel{
+			{
+				Keys:    bson.D{{Key: "username", Value: 1}},
+				Options: options.Index().SetUnique(true).SetName("idx_users_username"),
+			},
+			{
+				Keys:    bson.D{{Key: "email", Value: 1}},
+				Options: options.Index().SetUnique(true).SetName("idx_users_email"),
+			},
+			{
+				Keys:    bson.D{{Key: "created_at", Value: -1}},
+				Options: options.Index().SetName("idx_users_created_at"),
+			},
+			{
+				Keys:    bson.D{{Key: "is_active", Value: 1}},
+				Options: options.Index().SetName("idx_users_is_active"),
+			},
+			{
+				Keys:    bson.D{{Key: "roles", Value: 1}},
+				Options: options.Index().SetName("idx_users_roles"),
+			},
+			{
+				Keys:    bson.D{{Key: "deleted_at", Value: 1}},
+				Options: options.Index().SetName("idx_users_deleted_at"),
+			},
+		}
+
+		if err := createIndexes(ctx, db.Collection("users"), indexes); err != nil {
+			return fmt.Errorf("failed to create user indexes: %w", err)
+		}
+		return nil
+	},
+}
@@ -0,0 +1,33 @@
+// internal/database/migrations/002_user_text_index.go
+package migrations
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// userTextIndex creates the full-text index used by user search
+var userTextIndex = Migration{
+	Version:     "002_user_text_index",
+	Description: "Create text index on user name and contact fields",
+	Up: func(ctx context.Context, db *mongo.Database) error {
+		index := mongo.IndexModel{
+			Keys: bson.D{
+				{Key: "username", Value: "text"},
+				{Key: "email", Value: "text"},
+				{Key: "first_name", Value: "text"},
+				{Key: "last_name", Value: "text"},
+			},
+			Options: options.Index().SetName("idx_users_text"),
+		}
+
+		if err := createIndexes(ctx, db.Collection("users"), []mongo.IndexModel{index}); err != nil {
+			return fmt.Errorf("failed to create user text index: %w", err)
+		}
+		return nil
+	},
+}
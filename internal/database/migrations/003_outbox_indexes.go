@@ -0,0 +1,38 @@
+// internal/database/migrations/003_outbox_indexes.go
+package migrations
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// outboxSentRetentionSeconds is how long delivered outbox events are kept before MongoDB removes them
+const outboxSentRetentionSeconds = 7 * 24 * 60 * 60
+
+// outboxIndexes creates the indexes the event dispatcher polls with
+var outboxIndexes = Migration{
+	Version:     "003_outbox_indexes",
+	Description: "Create outbox polling index and expire delivered events",
+	Up: func(ctx context.Context, db *mongo.Database) error {
+		indexes := []mongo.IndexModel{
+			{
+				Keys:    bson.D{{Key: "status", Value: 1}, {Key: "next_attempt_at", Value: 1}},
+				Options: options.Index().SetName("idx_outbox_status_next_attempt"),
+			},
+			{
+				// Pending and dead events have no sent_at, so only delivered ones expire
+				Keys:    bson.D{{Key: "sent_at", Value: 1}},
+				Options: options.Index().SetName("idx_outbox_sent_at_ttl").SetExpireAfterSeconds(outboxSentRetentionSeconds),
+			},
+		}
+
+		if err := createIndexes(ctx, db.Collection("outbox"), indexes); err != nil {
+			return fmt.Errorf("failed to create outbox indexes: %w", err)
+		}
+		return nil
+	},
+}
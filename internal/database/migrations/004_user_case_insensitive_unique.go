@@ -0,0 +1,67 @@
+// internal/database/migrations/004_user_case_insensitive_unique.go
+package migrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// indexNotFoundCode is the MongoDB error code for dropping an index that does not exist
+const indexNotFoundCode = 27
+
+// userCaseInsensitiveUnique makes username and email uniqueness ignore case
+// The new indexes are built before anything is changed, so if existing users differ only by
+// case the migration fails and those accounts must be merged or renamed by hand first.
+var userCaseInsensitiveUnique = Migration{
+	Version:     "004_user_case_insensitive_unique",
+	Description: "Enforce case-insensitive unique usernames and emails",
+	Up: func(ctx context.Context, db *mongo.Database) error {
+		collection := db.Collection("users")
+		collation := &options.Collation{Locale: "en", Strength: 2}
+
+		indexes := []mongo.IndexModel{
+			{
+				Keys:    bson.D{{Key: "username", Value: 1}},
+				Options: options.Index().SetUnique(true).SetCollation(collation).SetName("idx_users_username_ci"),
+			},
+			{
+				Keys:    bson.D{{Key: "email", Value: 1}},
+				Options: options.Index().SetUnique(true).SetCollation(collation).SetName("idx_users_email_ci"),
+			},
+		}
+
+		if err := createIndexes(ctx, collection, indexes); err != nil {
+			if mongo.IsDuplicateKeyError(err) {
+				return fmt.Errorf("users differ only by the case of their username or email, resolve them before migrating: %w", err)
+			}
+			return fmt.Errorf("failed to create case-insensitive user indexes: %w", err)
+		}
+
+		// Store existing values lowercased, as new users are
+		lowercase := mongo.Pipeline{
+			{{Key: "$set", Value: bson.M{
+				"username": bson.M{"$toLower": "$username"},
+				"email":    bson.M{"$toLower": "$email"},
+			}}},
+		}
+		if _, err := collection.UpdateMany(ctx, bson.M{}, lowercase); err != nil {
+			return fmt.Errorf("failed to lowercase usernames and emails: %w", err)
+		}
+
+		for _, name := range []string{"idx_users_username", "idx_users_email"} {
+			if _, err := collection.Indexes().DropOne(ctx, name); err != nil {
+				var cmdErr mongo.CommandError
+				if errors.As(err, &cmdErr) && cmdErr.Code == indexNotFoundCode {
+					continue
+				}
+				return fmt.Errorf("failed to drop index %s: %w", name, err)
+			}
+		}
+		return nil
+	},
+}
@@ -0,0 +1,199 @@
+// internal/database/migrations/indexes.go
+package migrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// MongoDB error codes relevant to index creation
+const (
+	namespaceNotFoundCode     = 26
+	indexOptionsConflictCode  = 85 // The keys are already indexed under another name or with other options
+	indexKeySpecsConflictCode = 86 // The name is already used by an index with other keys or options
+)
+
+// IndexConflictError reports an index that cannot be created because of existing indexes or data
+type IndexConflictError struct {
+	Collection string
+	Index      string
+	Reason     string
+	Err        error
+}
+
+// Error describes the conflicting index
+func (e *IndexConflictError) Error() string {
+	return fmt.Sprintf("index %s on %s conflicts: %s", e.Index, e.Collection, e.Reason)
+}
+
+// Unwrap returns the underlying MongoDB error
+func (e *IndexConflictError) Unwrap() error {
+	return e.Err
+}
+
+// indexSpec is the part of an existing index definition used to recognize equivalent indexes
+type indexSpec struct {
+	Name               string   `bson:"name"`
+	Key                bson.D   `bson:"key"`
+	Unique             bool     `bson:"unique"`
+	ExpireAfterSeconds *float64 `bson:"expireAfterSeconds"`
+	Collation          *struct {
+		Locale   string `bson:"locale"`
+		Strength int    `bson:"strength"`
+	} `bson:"collation"`
+}
+
+// createIndexes creates the given indexes on collection one at a time
+// Indexes that already exist with the same keys and options, under any name, are skipped.
+// Indexes that clash with existing indexes or with the stored documents are reported as
+// *IndexConflictError.
+func createIndexes(ctx context.Context, collection *mongo.Collection, indexes []mongo.IndexModel) error {
+	existing, err := listIndexes(ctx, collection)
+	if err != nil {
+		return err
+	}
+
+	for _, index := range indexes {
+		name := indexName(index)
+
+		if equivalent, ok := findEquivalentIndex(existing, index); ok {
+			if equivalent != name {
+				log.Printf("Index %s on %s already exists as %s, skipping", name, collection.Name(), equivalent)
+			}
+			continue
+		}
+
+		if _, err := collection.Indexes().CreateOne(ctx, index); err != nil {
+			if reason, ok := indexConflictReason(err); ok {
+				return &IndexConflictError{Collection: collection.Name(), Index: name, Reason: reason, Err: err}
+			}
+			return fmt.Errorf("failed to create index %s on %s: %w", name, collection.Name(), err)
+		}
+	}
+	return nil
+}
+
+// listIndexes returns the indexes defined on collection, none if it does not exist yet
+func listIndexes(ctx context.Context, collection *mongo.Collection) ([]indexSpec, error) {
+	cursor, err := collection.Indexes().List(ctx)
+	if err != nil {
+		var cmdErr mongo.CommandError
+		if errors.As(err, &cmdErr) && cmdErr.Code == namespaceNotFoundCode {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to list indexes on %s: %w", collection.Name(), err)
+	}
+
+	var specs []indexSpec
+	if err := cursor.All(ctx, &specs); err != nil {
+		return nil, fmt.Errorf("failed to decode indexes on %s: %w", collection.Name(), err)
+	}
+	return specs, nil
+}
+
+// findEquivalentIndex returns the name of an existing index with the same keys and options as index
+func findEquivalentIndex(existing []indexSpec, index mongo.IndexModel) (string, bool) {
+	keys, ok := index.Keys.(bson.D)
+	if !ok {
+		return "", false
+	}
+
+	for _, spec := range existing {
+		if sameKeys(spec.Key, keys) && sameOptions(spec, index) {
+			return spec.Name, true
+		}
+	}
+	return "", false
+}
+
+// sameKeys reports whether two key documents index the same fields in the same order and direction
+func sameKeys(a, b bson.D) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Key != b[i].Key || !sameKeyValue(a[i].Value, b[i].Value) {
+			return false
+		}
+	}
+	return true
+}
+
+// sameKeyValue compares index directions or types, ignoring the numeric type used to store them
+func sameKeyValue(a, b interface{}) bool {
+	af, aNumeric := toFloat(a)
+	bf, bNumeric := toFloat(b)
+	if aNumeric || bNumeric {
+		return aNumeric && bNumeric && af == bf
+	}
+	return a == b
+}
+
+// sameOptions reports whether an existing index has the options index asks for
+func sameOptions(spec indexSpec, index mongo.IndexModel) bool {
+	opts := index.Options
+	if opts == nil {
+		return !spec.Unique && spec.ExpireAfterSeconds == nil && spec.Collation == nil
+	}
+
+	unique := opts.Unique != nil && *opts.Unique
+	if spec.Unique != unique {
+		return false
+	}
+
+	if (spec.ExpireAfterSeconds == nil) != (opts.ExpireAfterSeconds == nil) {
+		return false
+	}
+	if opts.ExpireAfterSeconds != nil && *spec.ExpireAfterSeconds != float64(*opts.ExpireAfterSeconds) {
+		return false
+	}
+
+	if (spec.Collation == nil) != (opts.Collation == nil) {
+		return false
+	}
+	if opts.Collation != nil && (spec.Collation.Locale != opts.Collation.Locale || spec.Collation.Strength != opts.Collation.Strength) {
+		return false
+	}
+	return true
+}
+
+// indexName returns the name set on an index model
+func indexName(index mongo.IndexModel) string {
+	if index.Options != nil && index.Options.Name != nil {
+		return *index.Options.Name
+	}
+	return fmt.Sprint(index.Keys)
+}
+
+// indexConflictReason describes why MongoDB refused to create an index, if it was a conflict
+func indexConflictReason(err error) (string, bool) {
+	if mongo.IsDuplicateKeyError(err) {
+		return "existing documents have duplicate values for its keys", true
+	}
+
+	var cmdErr mongo.CommandError
+	if errors.As(err, &cmdErr) && (cmdErr.Code == indexOptionsConflictCode || cmdErr.Code == indexKeySpecsConflictCode) {
+		return cmdErr.Message, true
+	}
+	return "", false
+}
+
+// toFloat converts numeric BSON values to float64
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
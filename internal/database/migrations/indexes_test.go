@@ -0,0 +1,193 @@
+// internal/database/migrations/indexes_test.go
+package migrations
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// existingIndexes replies to listIndexes on test.users with the given index definitions
+func existingIndexes(specs ...bson.D) bson.D {
+	return mtest.CreateCursorResponse(0, "test.users", mtest.FirstBatch, specs...)
+}
+
+func TestCreateIndexes(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	collation := &options.Collation{Locale: "en", Strength: 2}
+	usernameIndex := mongo.IndexModel{
+		Keys:    bson.D{{Key: "username", Value: 1}},
+		Options: options.Index().SetUnique(true).SetCollation(collation).SetName("idx_users_username_ci"),
+	}
+	created := mtest.CreateSuccessResponse()
+
+	tests := []struct {
+		name         string
+		responses    []bson.D
+		wantCommands []string
+		wantConflict string // expected IndexConflictError reason, if any
+		wantErr      bool
+	}{
+		{
+			name:         "new index is created",
+			responses:    []bson.D{existingIndexes(bson.D{{Key: "name", Value: "_id_"}, {Key: "key", Value: bson.D{{Key: "_id", Value: 1}}}}), created},
+			wantCommands: []string{"listIndexes users", "createIndexes users"},
+		},
+		{
+			name: "identical index is skipped",
+			responses: []bson.D{existingIndexes(bson.D{
+				{Key: "name", Value: "idx_users_username_ci"},
+				{Key: "key", Value: bson.D{{Key: "username", Value: int32(1)}}},
+				{Key: "unique", Value: true},
+				{Key: "collation", Value: bson.D{{Key: "locale", Value: "en"}, {Key: "strength", Value: int32(2)}}},
+			})},
+			wantCommands: []string{"listIndexes users"},
+		},
+		{
+			name: "equivalent index under another name is skipped",
+			responses: []bson.D{existingIndexes(bson.D{
+				{Key: "name", Value: "username_1"},
+				{Key: "key", Value: bson.D{{Key: "username", Value: 1.0}}},
+				{Key: "unique", Value: true},
+				{Key: "collation", Value: bson.D{{Key: "locale", Value: "en"}, {Key: "strength", Value: int32(2)}}},
+			})},
+			wantCommands: []string{"listIndexes users"},
+		},
+		{
+			name: "missing collection",
+			responses: []bson.D{
+				mtest.CreateCommandErrorResponse(mtest.CommandError{Code: namespaceNotFoundCode, Name: "NamespaceNotFound", Message: "ns does not exist"}),
+				created,
+			},
+			wantCommands: []string{"listIndexes users", "createIndexes users"},
+		},
+		{
+			name: "existing index with other options",
+			responses: []bson.D{
+				existingIndexes(bson.D{
+					{Key: "name", Value: "idx_users_username_ci"},
+					{Key: "key", Value: bson.D{{Key: "username", Value: int32(1)}}},
+				}),
+				mtest.CreateCommandErrorResponse(mtest.CommandError{Code: indexKeySpecsConflictCode, Name: "IndexKeySpecsConflict", Message: "An existing index has the same name"}),
+			},
+			wantCommands: []string{"listIndexes users", "createIndexes users"},
+			wantConflict: "An existing index has the same name",
+		},
+		{
+			name: "documents violate uniqueness",
+			responses: []bson.D{
+				existingIndexes(),
+				mtest.CreateCommandErrorResponse(mtest.CommandError{Code: 11000, Name: "DuplicateKey", Message: "E11000 duplicate key error"}),
+			},
+			wantCommands: []string{"listIndexes users", "createIndexes users"},
+			wantConflict: "existing documents have duplicate values for its keys",
+		},
+		{
+			name: "other failure",
+			responses: []bson.D{
+				existingIndexes(),
+				mtest.CreateCommandErrorResponse(mtest.CommandError{Code: 13, Name: "Unauthorized", Message: "not authorized"}),
+			},
+			wantCommands: []string{"listIndexes users", "createIndexes users"},
+			wantErr:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		mt.Run(tt.name, func(mt *mtest.T) {
+			mt.AddMockResponses(tt.responses...)
+
+			err := createIndexes(context.Background(), mt.DB.Collection("users"), []mongo.IndexModel{usernameIndex})
+
+			var conflict *IndexConflictError
+			switch {
+			case tt.wantConflict != "":
+				if !errors.As(err, &conflict) {
+					mt.Fatalf("createIndexes() error = %v, want an IndexConflictError", err)
+				}
+				if conflict.Collection != "users" || conflict.Index != "idx_users_username_ci" || conflict.Reason != tt.wantConflict {
+					mt.Errorf("conflict = %+v, want users.idx_users_username_ci: %s", conflict, tt.wantConflict)
+				}
+			case tt.wantErr:
+				if err == nil || errors.As(err, &conflict) {
+					mt.Errorf("createIndexes() error = %v, want a plain failure", err)
+				}
+			case err != nil:
+				mt.Fatalf("createIndexes() error = %v", err)
+			}
+
+			if got := sentCommands(mt); !slices.Equal(got, tt.wantCommands) {
+				mt.Errorf("commands = %v, want %v", got, tt.wantCommands)
+			}
+		})
+	}
+}
+
+func TestSameOptions(t *testing.T) {
+	var ttl float64 = 3600
+	collation := &struct {
+		Locale   string `bson:"locale"`
+		Strength int    `bson:"strength"`
+	}{Locale: "en", Strength: 2}
+
+	tests := []struct {
+		name  string
+		spec  indexSpec
+		index mongo.IndexModel
+		want  bool
+	}{
+		{name: "plain index without options", spec: indexSpec{}, index: mongo.IndexModel{}, want: true},
+		{name: "existing unique, wanted plain", spec: indexSpec{Unique: true}, index: mongo.IndexModel{}},
+		{name: "both unique", spec: indexSpec{Unique: true}, index: mongo.IndexModel{Options: options.Index().SetUnique(true)}, want: true},
+		{name: "unique wanted, existing plain", spec: indexSpec{}, index: mongo.IndexModel{Options: options.Index().SetUnique(true)}},
+		{name: "same expiry", spec: indexSpec{ExpireAfterSeconds: &ttl}, index: mongo.IndexModel{Options: options.Index().SetExpireAfterSeconds(3600)}, want: true},
+		{name: "other expiry", spec: indexSpec{ExpireAfterSeconds: &ttl}, index: mongo.IndexModel{Options: options.Index().SetExpireAfterSeconds(60)}},
+		{name: "expiry missing", spec: indexSpec{}, index: mongo.IndexModel{Options: options.Index().SetExpireAfterSeconds(3600)}},
+		{
+			name:  "same collation",
+			spec:  indexSpec{Collation: collation},
+			index: mongo.IndexModel{Options: options.Index().SetCollation(&options.Collation{Locale: "en", Strength: 2})},
+			want:  true,
+		},
+		{name: "other strength", spec: indexSpec{Collation: collation}, index: mongo.IndexModel{Options: options.Index().SetCollation(&options.Collation{Locale: "en", Strength: 1})}},
+		{name: "collation missing", spec: indexSpec{}, index: mongo.IndexModel{Options: options.Index().SetCollation(&options.Collation{Locale: "en", Strength: 2})}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sameOptions(tt.spec, tt.index); got != tt.want {
+				t.Errorf("sameOptions() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSameKeys(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b bson.D
+		want bool
+	}{
+		{name: "numeric types differ", a: bson.D{{Key: "a", Value: int32(1)}}, b: bson.D{{Key: "a", Value: 1.0}}, want: true},
+		{name: "directions differ", a: bson.D{{Key: "a", Value: 1}}, b: bson.D{{Key: "a", Value: -1}}},
+		{name: "order differs", a: bson.D{{Key: "a", Value: 1}, {Key: "b", Value: 1}}, b: bson.D{{Key: "b", Value: 1}, {Key: "a", Value: 1}}},
+		{name: "extra key", a: bson.D{{Key: "a", Value: 1}}, b: bson.D{{Key: "a", Value: 1}, {Key: "b", Value: 1}}},
+		{name: "text indexes", a: bson.D{{Key: "_fts", Value: "text"}}, b: bson.D{{Key: "_fts", Value: "text"}}, want: true},
+		{name: "text against numeric", a: bson.D{{Key: "a", Value: "text"}}, b: bson.D{{Key: "a", Value: 1}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sameKeys(tt.a, tt.b); got != tt.want {
+				t.Errorf("sameKeys() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
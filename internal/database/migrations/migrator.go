@@ -0,0 +1,206 @@
+// internal/database/migrations/migrator.go
+package migrations
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// migrationsCollection records which migrations have been applied
+	migrationsCollection = "_migrations"
+
+	// lockCollection holds the lock document that serializes migration runs across replicas
+	lockCollection = "_migrations_lock"
+	lockID         = "migrations"
+
+	// DefaultLockTTL is how long a lock is held before another replica may take it over
+	DefaultLockTTL = 5 * time.Minute
+
+	lockRetryInterval = 500 * time.Millisecond
+)
+
+// Migration represents a single versioned schema change
+// Versions are applied in lexical order, so they should be zero-padded (e.g. "001_user_indexes")
+type Migration struct {
+	Version     string
+	Description string
+	Up          func(ctx context.Context, db *mongo.Database) error
+}
+
+// appliedMigration is the record stored for each applied migration
+type appliedMigration struct {
+	Version     string    `bson:"_id"`
+	Description string    `bson:"description"`
+	AppliedAt   time.Time `bson:"applied_at"`
+}
+
+// Migrator applies pending migrations to a database
+type Migrator struct {
+	db         *mongo.Database
+	migrations []Migration
+	lockTTL    time.Duration
+	owner      string
+}
+
+// NewMigrator creates a new Migrator for the given migrations
+func NewMigrator(db *mongo.Database, migrations []Migration) *Migrator {
+	sorted := make([]Migration, len(migrations))
+	copy(sorted, migrations)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Version < sorted[j].Version
+	})
+
+	return &Migrator{
+		db:         db,
+		migrations: sorted,
+		lockTTL:    DefaultLockTTL,
+		owner:      lockOwner(),
+	}
+}
+
+// Migrate applies all registered migrations that have not been applied yet
+func Migrate(ctx context.Context, db *mongo.Database) error {
+	return NewMigrator(db, All()).Run(ctx)
+}
+
+// Run acquires the migration lock and applies pending migrations in order
+// Running it again once everything is applied is a no-op
+func (m *Migrator) Run(ctx context.Context) error {
+	if err := m.acquireLock(ctx); err != nil {
+		return err
+	}
+	defer m.releaseLock()
+
+	applied, err := m.appliedVersions(ctx)
+	if err != nil {
+		return err
+	}
+
+	pending := 0
+	for _, migration := range m.migrations {
+		if applied[migration.Version] {
+			continue
+		}
+		pending++
+
+		log.Printf("Applying migration %s: %s", migration.Version, migration.Description)
+		if err := migration.Up(ctx, m.db); err != nil {
+			return fmt.Errorf("migration %s failed: %w", migration.Version, err)
+		}
+
+		record := appliedMigration{
+			Version:     migration.Version,
+			Description: migration.Description,
+			AppliedAt:   time.Now().UTC(),
+		}
+		if _, err := m.db.Collection(migrationsCollection).InsertOne(ctx, record); err != nil {
+			return fmt.Errorf("failed to record migration %s: %w", migration.Version, err)
+		}
+	}
+
+	if pending == 0 {
+		log.Println("Database schema is up to date")
+	} else {
+		log.Printf("Applied %d migration(s)", pending)
+	}
+	return nil
+}
+
+// appliedVersions returns the set of migration versions already applied
+func (m *Migrator) appliedVersions(ctx context.Context) (map[string]bool, error) {
+	cursor, err := m.db.Collection(migrationsCollection).Find(ctx, bson.M{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load applied migrations: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	applied := make(map[string]bool)
+	for cursor.Next(ctx) {
+		var record appliedMigration
+		if err := cursor.Decode(&record); err != nil {
+			return nil, fmt.Errorf("failed to decode applied migration: %w", err)
+		}
+		applied[record.Version] = true
+	}
+
+	return applied, cursor.Err()
+}
+
+// acquireLock waits until this migrator holds the lock document or ctx is done
+// An expired lock left behind by a crashed replica is taken over
+func (m *Migrator) acquireLock(ctx context.Context) error {
+	for {
+		acquired, err := m.tryLock(ctx)
+		if err != nil {
+			return err
+		}
+		if acquired {
+			return nil
+		}
+
+		log.Println("Waiting for another instance to finish migrations...")
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("timed out waiting for migration lock: %w", ctx.Err())
+		case <-time.After(lockRetryInterval):
+		}
+	}
+}
+
+// tryLock attempts to take the lock once
+func (m *Migrator) tryLock(ctx context.Context) (bool, error) {
+	now := time.Now().UTC()
+	filter := bson.M{
+		"_id": lockID,
+		"$or": []bson.M{
+			{"expires_at": bson.M{"$lt": now}},
+			{"owner": m.owner},
+		},
+	}
+	update := bson.M{"$set": bson.M{
+		"owner":      m.owner,
+		"expires_at": now.Add(m.lockTTL),
+	}}
+
+	_, err := m.db.Collection(lockCollection).UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	if err != nil {
+		// The upsert collides with a live lock held by someone else
+		if mongo.IsDuplicateKeyError(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to acquire migration lock: %w", err)
+	}
+
+	return true, nil
+}
+
+// releaseLock removes the lock document if it is still held by this migrator
+func (m *Migrator) releaseLock() {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := m.db.Collection(lockCollection).DeleteOne(ctx, bson.M{"_id": lockID, "owner": m.owner})
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		log.Printf("Warning: Failed to release migration lock: %v", err)
+	}
+}
+
+// lockOwner builds an identifier unique to this process
+func lockOwner() string {
+	hostname, _ := os.Hostname()
+	suffix := make([]byte, 4)
+	rand.Read(suffix)
+	return fmt.Sprintf("%s-%d-%s", hostname, os.Getpid(), hex.EncodeToString(suffix))
+}
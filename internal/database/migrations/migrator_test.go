@@ -0,0 +1,214 @@
+// internal/database/migrations/migrator_test.go
+package migrations
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// countingMigrations returns migrations that record the order they run in
+func countingMigrations(ran *[]string, versions ...string) []Migration {
+	migrations := make([]Migration, len(versions))
+	for i, version := range versions {
+		migrations[i] = Migration{
+			Version:     version,
+			Description: "test migration " + version,
+			Up: func(ctx context.Context, db *mongo.Database) error {
+				*ran = append(*ran, version)
+				return nil
+			},
+		}
+	}
+	return migrations
+}
+
+// appliedCursor replies to the applied-migrations query with the given versions
+func appliedCursor(versions ...string) bson.D {
+	docs := make([]bson.D, len(versions))
+	for i, version := range versions {
+		docs[i] = bson.D{{Key: "_id", Value: version}, {Key: "applied_at", Value: time.Now()}}
+	}
+	return mtest.CreateCursorResponse(0, "test._migrations", mtest.FirstBatch, docs...)
+}
+
+// sentCommands lists the names of the commands sent since the last call, with their collection
+func sentCommands(mt *mtest.T) []string {
+	var commands []string
+	for _, event := range mt.GetAllStartedEvents() {
+		collection, _ := event.Command.Lookup(event.CommandName).StringValueOK()
+		commands = append(commands, event.CommandName+" "+collection)
+	}
+	mt.ClearEvents()
+	return commands
+}
+
+func TestMigratorRun(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	lockAcquired := mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1})
+	lockReleased := mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1})
+	recorded := mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1})
+
+	tests := []struct {
+		name         string
+		applied      []string
+		wantRan      []string
+		wantCommands []string
+	}{
+		{
+			name:    "fresh database applies everything in order",
+			wantRan: []string{"001_first", "002_second", "003_third"},
+			wantCommands: []string{
+				"update _migrations_lock", "find _migrations",
+				"insert _migrations", "insert _migrations", "insert _migrations",
+				"delete _migrations_lock",
+			},
+		},
+		{
+			name:    "second run is a no-op",
+			applied: []string{"001_first", "002_second", "003_third"},
+			wantCommands: []string{
+				"update _migrations_lock", "find _migrations", "delete _migrations_lock",
+			},
+		},
+		{
+			name:    "only pending migrations run",
+			applied: []string{"001_first"},
+			wantRan: []string{"002_second", "003_third"},
+			wantCommands: []string{
+				"update _migrations_lock", "find _migrations",
+				"insert _migrations", "insert _migrations",
+				"delete _migrations_lock",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		mt.Run(tt.name, func(mt *mtest.T) {
+			var ran []string
+			// Registered out of order; versions decide the order they run in
+			migrator := NewMigrator(mt.DB, countingMigrations(&ran, "003_third", "001_first", "002_second"))
+
+			responses := []bson.D{lockAcquired, appliedCursor(tt.applied...)}
+			for range tt.wantRan {
+				responses = append(responses, recorded)
+			}
+			responses = append(responses, lockReleased)
+			mt.AddMockResponses(responses...)
+
+			if err := migrator.Run(context.Background()); err != nil {
+				mt.Fatalf("Run() error = %v", err)
+			}
+			if !slices.Equal(ran, tt.wantRan) {
+				mt.Errorf("ran %v, want %v", ran, tt.wantRan)
+			}
+			if got := sentCommands(mt); !slices.Equal(got, tt.wantCommands) {
+				mt.Errorf("commands = %v, want %v", got, tt.wantCommands)
+			}
+		})
+	}
+}
+
+func TestMigratorRunTwice(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("run twice", func(mt *mtest.T) {
+		var ran []string
+		migrations := countingMigrations(&ran, "001_first", "002_second")
+		lock := mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1})
+		ok := mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1})
+
+		// First run: nothing applied yet, both migrations are recorded
+		mt.AddMockResponses(lock, appliedCursor(), ok, ok, ok)
+		if err := NewMigrator(mt.DB, migrations).Run(context.Background()); err != nil {
+			mt.Fatalf("first Run() error = %v", err)
+		}
+
+		// Second run: the records written by the first run are found
+		mt.ClearEvents()
+		mt.AddMockResponses(lock, appliedCursor(ran...), ok)
+		if err := NewMigrator(mt.DB, migrations).Run(context.Background()); err != nil {
+			mt.Fatalf("second Run() error = %v", err)
+		}
+
+		if !slices.Equal(ran, []string{"001_first", "002_second"}) {
+			mt.Errorf("ran %v, want each migration exactly once", ran)
+		}
+		for _, command := range sentCommands(mt) {
+			if command == "insert _migrations" {
+				mt.Error("second run recorded a migration")
+			}
+		}
+	})
+}
+
+func TestMigratorFailures(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("failed migration stops the run", func(mt *mtest.T) {
+		var ran []string
+		migrations := countingMigrations(&ran, "001_first", "003_third")
+		migrations = append(migrations, Migration{
+			Version: "002_broken",
+			Up: func(ctx context.Context, db *mongo.Database) error {
+				return errors.New("index build failed")
+			},
+		})
+		ok := mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1})
+		mt.AddMockResponses(ok, appliedCursor(), ok, ok)
+
+		err := NewMigrator(mt.DB, migrations).Run(context.Background())
+		if err == nil {
+			mt.Fatal("Run() error = nil, want the migration failure")
+		}
+		if !slices.Equal(ran, []string{"001_first"}) {
+			mt.Errorf("ran %v, want only the migrations before the failure", ran)
+		}
+		// The lock is released even though the run failed
+		commands := sentCommands(mt)
+		if len(commands) == 0 || commands[len(commands)-1] != "delete _migrations_lock" {
+			mt.Errorf("commands = %v, want the lock released last", commands)
+		}
+	})
+
+	mt.Run("lock held by another replica", func(mt *mtest.T) {
+		var ran []string
+		held := mtest.CreateWriteErrorsResponse(mtest.WriteError{Index: 0, Code: 11000, Message: "E11000 duplicate key error"})
+		for i := 0; i < 10; i++ {
+			mt.AddMockResponses(held)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		err := NewMigrator(mt.DB, countingMigrations(&ran, "001_first")).Run(ctx)
+		if !errors.Is(err, context.DeadlineExceeded) {
+			mt.Errorf("Run() error = %v, want it to wait for the lock until ctx expires", err)
+		}
+		if len(ran) != 0 {
+			mt.Errorf("ran %v without holding the lock", ran)
+		}
+	})
+}
+
+func TestAllMigrations(t *testing.T) {
+	seen := map[string]bool{}
+	for i, migration := range All() {
+		if migration.Version == "" || migration.Up == nil {
+			t.Errorf("migration %d has no version or Up function", i)
+		}
+		if seen[migration.Version] {
+			t.Errorf("duplicate migration version %s", migration.Version)
+		}
+		seen[migration.Version] = true
+		if i > 0 && All()[i-1].Version >= migration.Version {
+			t.Errorf("migration %s is registered after %s", migration.Version, All()[i-1].Version)
+		}
+	}
+}
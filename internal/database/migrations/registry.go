@@ -0,0 +1,13 @@
+// internal/database/migrations/registry.go
+package migrations
+
+// All returns every migration known to the application
+// Append new migrations here; never edit or reorder ones that have shipped
+func All() []Migration {
+	return []Migration{
+		userIndexes,
+		userTextIndex,
+		outboxIndexes,
+		userCaseInsensitiveUnique,
+	}
+}
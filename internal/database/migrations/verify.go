@@ -0,0 +1,85 @@
+// internal/database/migrations/verify.go
+package migrations
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// expectedUserIndexes is the set of indexes the users collection has once every migration ran
+func expectedUserIndexes() []mongo.IndexModel {
+	collation := &options.Collation{Locale: "en", Strength: 2}
+
+	return []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "username", Value: 1}},
+			Options: options.Index().SetUnique(true).SetCollation(collation).SetName("idx_users_username_ci"),
+		},
+		{
+			Keys:    bson.D{{Key: "email", Value: 1}},
+			Options: options.Index().SetUnique(true).SetCollation(collation).SetName("idx_users_email_ci"),
+		},
+		{
+			Keys:    bson.D{{Key: "created_at", Value: -1}},
+			Options: options.Index().SetName("idx_users_created_at"),
+		},
+		{
+			Keys:    bson.D{{Key: "is_active", Value: 1}},
+			Options: options.Index().SetName("idx_users_is_active"),
+		},
+		{
+			Keys:    bson.D{{Key: "roles", Value: 1}},
+			Options: options.Index().SetName("idx_users_roles"),
+		},
+		{
+			Keys:    bson.D{{Key: "deleted_at", Value: 1}},
+			Options: options.Index().SetName("idx_users_deleted_at"),
+		},
+		{
+			Keys: bson.D{
+				{Key: "username", Value: "text"},
+				{Key: "email", Value: "text"},
+				{Key: "first_name", Value: "text"},
+				{Key: "last_name", Value: "text"},
+			},
+			Options: options.Index().SetName("idx_users_text"),
+		},
+	}
+}
+
+// MissingUserIndexes returns the names of the expected users indexes that do not exist
+// An index counts as present when one with the same name, or with the same keys and
+// options under another name, is defined on the collection.
+func MissingUserIndexes(ctx context.Context, db *mongo.Database) ([]string, error) {
+	return missingIndexes(ctx, db.Collection("users"), expectedUserIndexes())
+}
+
+// missingIndexes returns the names of the given indexes that collection does not have
+func missingIndexes(ctx context.Context, collection *mongo.Collection, indexes []mongo.IndexModel) ([]string, error) {
+	existing, err := listIndexes(ctx, collection)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make(map[string]bool, len(existing))
+	for _, spec := range existing {
+		names[spec.Name] = true
+	}
+
+	var missing []string
+	for _, index := range indexes {
+		// Text indexes are stored with internal keys, so only their name identifies them
+		name := indexName(index)
+		if names[name] {
+			continue
+		}
+		if _, ok := findEquivalentIndex(existing, index); ok {
+			continue
+		}
+		missing = append(missing, name)
+	}
+	return missing, nil
+}
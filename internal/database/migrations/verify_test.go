@@ -0,0 +1,118 @@
+// internal/database/migrations/verify_test.go
+package migrations
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// userIndexSpec is the listIndexes entry for an index migrations create on users
+func userIndexSpec(name string, keys bson.D, ciUnique bool) bson.D {
+	spec := bson.D{{Key: "v", Value: int32(2)}, {Key: "name", Value: name}, {Key: "key", Value: keys}}
+	if ciUnique {
+		spec = append(spec,
+			bson.E{Key: "unique", Value: true},
+			bson.E{Key: "collation", Value: bson.D{{Key: "locale", Value: "en"}, {Key: "strength", Value: int32(2)}}},
+		)
+	}
+	return spec
+}
+
+func TestMissingUserIndexes(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	// The indexes of a fully migrated users collection, as the server lists them
+	specs := map[string]bson.D{
+		"_id_":                  userIndexSpec("_id_", bson.D{{Key: "_id", Value: int32(1)}}, false),
+		"idx_users_username_ci": userIndexSpec("idx_users_username_ci", bson.D{{Key: "username", Value: int32(1)}}, true),
+		"idx_users_email_ci":    userIndexSpec("idx_users_email_ci", bson.D{{Key: "email", Value: int32(1)}}, true),
+		"idx_users_created_at":  userIndexSpec("idx_users_created_at", bson.D{{Key: "created_at", Value: int32(-1)}}, false),
+		"idx_users_is_active":   userIndexSpec("idx_users_is_active", bson.D{{Key: "is_active", Value: int32(1)}}, false),
+		"idx_users_roles":       userIndexSpec("idx_users_roles", bson.D{{Key: "roles", Value: int32(1)}}, false),
+		"idx_users_deleted_at":  userIndexSpec("idx_users_deleted_at", bson.D{{Key: "deleted_at", Value: int32(1)}}, false),
+		// Text indexes are listed with internal keys
+		"idx_users_text": userIndexSpec("idx_users_text", bson.D{{Key: "_fts", Value: "text"}, {Key: "_ftsx", Value: int32(1)}}, false),
+	}
+	expected := []string{"idx_users_username_ci", "idx_users_email_ci", "idx_users_created_at",
+		"idx_users_is_active", "idx_users_roles", "idx_users_deleted_at", "idx_users_text"}
+	// migratedExcept returns the index list of the migrated collection minus the named indexes
+	migratedExcept := func(names ...string) []bson.D {
+		var docs []bson.D
+		for _, name := range append([]string{"_id_"}, expected...) {
+			if !slices.Contains(names, name) {
+				docs = append(docs, specs[name])
+			}
+		}
+		return docs
+	}
+
+	tests := []struct {
+		name        string
+		reply       bson.D
+		wantMissing []string
+		wantErr     string
+	}{
+		{name: "every index present", reply: existingIndexes(migratedExcept()...)},
+		{name: "one index missing", reply: existingIndexes(migratedExcept("idx_users_roles")...), wantMissing: []string{"idx_users_roles"}},
+		{
+			name:        "several indexes missing",
+			reply:       existingIndexes(migratedExcept("idx_users_email_ci", "idx_users_text")...),
+			wantMissing: []string{"idx_users_email_ci", "idx_users_text"},
+		},
+		{
+			name: "equivalent index under another name",
+			reply: existingIndexes(append(migratedExcept("idx_users_is_active"),
+				userIndexSpec("is_active_1", bson.D{{Key: "is_active", Value: 1.0}}, false))...),
+		},
+		{
+			// The old case-sensitive unique index does not stand in for the case-insensitive one
+			name: "index with other options",
+			reply: existingIndexes(append(migratedExcept("idx_users_username_ci"),
+				userIndexSpec("idx_users_username", bson.D{{Key: "username", Value: int32(1)}}, false))...),
+			wantMissing: []string{"idx_users_username_ci"},
+		},
+		{
+			name:        "collection not created yet",
+			reply:       mtest.CreateCommandErrorResponse(mtest.CommandError{Code: namespaceNotFoundCode, Name: "NamespaceNotFound", Message: "ns does not exist"}),
+			wantMissing: expected,
+		},
+		{
+			name:    "listing fails",
+			reply:   mtest.CreateCommandErrorResponse(mtest.CommandError{Code: 13, Name: "Unauthorized", Message: "not authorized"}),
+			wantErr: "failed to list indexes on users",
+		},
+	}
+
+	for _, tt := range tests {
+		mt.Run(tt.name, func(mt *mtest.T) {
+			mt.AddMockResponses(tt.reply)
+
+			missing, err := MissingUserIndexes(context.Background(), mt.DB)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					mt.Fatalf("MissingUserIndexes() error = %v, want it to mention %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				mt.Fatalf("MissingUserIndexes() error = %v", err)
+			}
+			if !slices.Equal(missing, tt.wantMissing) {
+				mt.Errorf("MissingUserIndexes() = %v, want %v", missing, tt.wantMissing)
+			}
+
+			// Verifying only reads the index list; it never creates anything
+			if started := mt.GetStartedEvent(); started.CommandName != "listIndexes" || started.Command.Lookup("listIndexes").StringValue() != "users" {
+				mt.Errorf("command = %s %v, want listIndexes on users", started.CommandName, started.Command)
+			}
+			if next := mt.GetStartedEvent(); next != nil {
+				mt.Errorf("unexpected command %s after listing the indexes", next.CommandName)
+			}
+		})
+	}
+}
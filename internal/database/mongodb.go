@@ -12,13 +12,47 @@ import (
 )
 
 // ConnectMongoDB establishes a connection to MongoDB with optimized settings
-func ConnectMongoDB(mongoURL, databaseName string) (*mongo.Database, error) {
+func ConnectMongoDB(mongoURL, databaseName string, maxPoolSize, minPoolSize uint64) (*mongo.Database, error) {
+	database, err := connectMongoDB(mongoClientOptions(mongoURL, readpref.Primary(), maxPoolSize, minPoolSize), mongoURL, databaseName)
+	if err != nil {
+		return nil, err
+	}
+	
+	// Log database stats for monitoring
+	go logDatabaseStats(database)
+
+	return database, nil
+}
+
+// OpenMongoDB creates a MongoDB client without waiting for a server to be reachable
+// The driver keeps connecting in the background, so operations fail until MongoDB is up.
+// It lets the server start while MongoDB is down; prefer ConnectMongoDB otherwise.
+func OpenMongoDB(mongoURL, databaseName string, maxPoolSize, minPoolSize uint64) (*mongo.Database, error) {
+	client, err := mongo.Connect(context.Background(), mongoClientOptions(mongoURL, readpref.Primary(), maxPoolSize, minPoolSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create MongoDB client: %w", err)
+	}
+
+	log.Printf("Opened MongoDB database %s without a connection check", databaseName)
+	return client.Database(databaseName), nil
+}
+
+// ConnectMongoDBReadOnly connects to MongoDB for analytics reads
+// Reads prefer secondaries so heavy aggregations stay off the primary, falling back to the
+// primary when no secondary is available. The connection must not be used for writes.
+func ConnectMongoDBReadOnly(mongoURL, databaseName string, maxPoolSize, minPoolSize uint64) (*mongo.Database, error) {
+	return connectMongoDB(mongoClientOptions(mongoURL, readpref.SecondaryPreferred(), maxPoolSize, minPoolSize), mongoURL, databaseName)
+}
+
+// mongoClientOptions builds the client options for a connection reading with the given preference
+func mongoClientOptions(mongoURL string, readPreference *readpref.ReadPref, maxPoolSize, minPoolSize uint64) *options.ClientOptions {
 	// Configure client options for optimal performance
-	clientOptions := options.Client().
+	return options.Client().
 		ApplyURI(mongoURL).
+		SetReadPreference(readPreference).
 		// Connection pool settings
-		SetMaxPoolSize(100).                // Maximum number of connections in the pool
-		SetMinPoolSize(10).                 // Minimum number of connections to maintain
+		SetMaxPoolSize(maxPoolSize).        // Maximum number of connections in the pool
+		SetMinPoolSize(minPoolSize).        // Minimum number of connections to maintain
 		SetMaxConnIdleTime(30 * time.Second). // Close connections after 30s of inactivity
 		// Timeout settings
 		SetConnectTimeout(30 * time.Second).     // Timeout for initial connection
@@ -30,6 +64,11 @@ func ConnectMongoDB(mongoURL, databaseName string) (*mongo.Database, error) {
 		// Monitoring
 		SetHeartbeatInterval(10 * time.Second). // Health check interval
 		SetLocalThreshold(15 * time.Millisecond) // Local threshold for server selection
+}
+
+// connectMongoDB creates a client from clientOptions and verifies it can reach a server
+func connectMongoDB(clientOptions *options.ClientOptions, mongoURL, databaseName string) (*mongo.Database, error) {
+	readPreference := clientOptions.ReadPreference
 
 	// Create context with timeout for connection
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
@@ -44,19 +83,16 @@ func ConnectMongoDB(mongoURL, databaseName string) (*mongo.Database, error) {
 	}
 
 	// Ping MongoDB to verify connection
-	if err := client.Ping(ctx, readpref.Primary()); err != nil {
+	if err := client.Ping(ctx, readPreference); err != nil {
+		// Release the client's background monitors, the caller may retry
+		_ = client.Disconnect(context.Background())
 		return nil, fmt.Errorf("failed to ping MongoDB: %w", err)
 	}
 
-	log.Printf("Successfully connected to MongoDB database: %s", databaseName)
+	log.Printf("Successfully connected to MongoDB database: %s (read preference: %s)", databaseName, readPreference.Mode())
 
 	// Return the database instance
-	database := client.Database(databaseName)
-	
-	// Log database stats for monitoring
-	go logDatabaseStats(database)
-
-	return database, nil
+	return client.Database(databaseName), nil
 }
 
 // logDatabaseStats logs database connection statistics periodically
@@ -109,50 +145,6 @@ func CloseMongoDB(db *mongo.Database) error {
 	return nil
 }
 
-// CreateIndexes creates commonly used indexes for better performance
-// This will be expanded in Phase 2 when we add specific collections
-func CreateIndexes(db *mongo.Database) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
-
-	log.Println("Creating database indexes...")
-
-	// Example: Create index for users collection (will be used in Phase 2)
-	usersCollection := db.Collection("users")
-	
-	// Index for email field (unique)
-	emailIndex := mongo.IndexModel{
-		Keys:    map[string]interface{}{"email": 1},
-		Options: options.Index().SetUnique(true).SetName("idx_users_email"),
-	}
-	
-	// Index for username field (unique)
-	usernameIndex := mongo.IndexModel{
-		Keys:    map[string]interface{}{"username": 1},
-		Options: options.Index().SetUnique(true).SetName("idx_users_username"),
-	}
-	
-	// Index for created_at field (for sorting)
-	createdAtIndex := mongo.IndexModel{
-		Keys:    map[string]interface{}{"created_at": -1},
-		Options: options.Index().SetName("idx_users_created_at"),
-	}
-
-	// Create indexes
-	_, err := usersCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
-		emailIndex,
-		usernameIndex,
-		createdAtIndex,
-	})
-
-	if err != nil {
-		return fmt.Errorf("failed to create indexes: %w", err)
-	}
-
-	log.Println("Database indexes created successfully")
-	return nil
-}
-
 // GetCollectionNames returns all collection names in the database
 func GetCollectionNames(db *mongo.Database) ([]string, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
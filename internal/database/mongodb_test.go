@@ -0,0 +1,94 @@
+// internal/database/mongodb_test.go
+package database
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"go.uber.org/goleak"
+)
+
+func TestMongoClientOptions(t *testing.T) {
+	tests := []struct {
+		name           string
+		readPreference *readpref.ReadPref
+		maxPoolSize    uint64
+		minPoolSize    uint64
+		wantMode       readpref.Mode
+	}{
+		{name: "primary with default pool", readPreference: readpref.Primary(), maxPoolSize: 100, minPoolSize: 10, wantMode: readpref.PrimaryMode},
+		{name: "secondary preferred with small pool", readPreference: readpref.SecondaryPreferred(), maxPoolSize: 5, minPoolSize: 0, wantMode: readpref.SecondaryPreferredMode},
+		{name: "min equal to max", readPreference: readpref.Primary(), maxPoolSize: 20, minPoolSize: 20, wantMode: readpref.PrimaryMode},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := mongoClientOptions("mongodb://localhost:27017", tt.readPreference, tt.maxPoolSize, tt.minPoolSize)
+
+			if opts.MaxPoolSize == nil || *opts.MaxPoolSize != tt.maxPoolSize {
+				t.Errorf("MaxPoolSize = %v, want %d", opts.MaxPoolSize, tt.maxPoolSize)
+			}
+			if opts.MinPoolSize == nil || *opts.MinPoolSize != tt.minPoolSize {
+				t.Errorf("MinPoolSize = %v, want %d", opts.MinPoolSize, tt.minPoolSize)
+			}
+			if opts.ReadPreference == nil || opts.ReadPreference.Mode() != tt.wantMode {
+				t.Errorf("ReadPreference = %v, want mode %v", opts.ReadPreference, tt.wantMode)
+			}
+		})
+	}
+}
+
+func TestOpenMongoDBWithoutServer(t *testing.T) {
+	// Nothing listens on the address once the listener is closed
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+
+	tests := []struct {
+		name    string
+		connect func() error
+		wantErr bool
+	}{
+		{
+			name: "open returns without a server",
+			connect: func() error {
+				db, err := OpenMongoDB("mongodb://"+addr, "app", 10, 0)
+				if err == nil {
+					db.Client().Disconnect(context.Background())
+				}
+				return err
+			},
+		},
+		{
+			// The checked connection fails, which is what startup retries
+			name: "connect fails without a server",
+			connect: func() error {
+				_, err := connectMongoDB(mongoClientOptions("mongodb://"+addr, readpref.Primary(), 10, 0).SetServerSelectionTimeout(50*time.Millisecond), "mongodb://"+addr, "app")
+				return err
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ignore := goleak.IgnoreCurrent()
+			start := time.Now()
+			err := tt.connect()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want error %v", err, tt.wantErr)
+			}
+			if elapsed := time.Since(start); elapsed > 5*time.Second {
+				t.Errorf("took %v, want no wait for the server", elapsed)
+			}
+			// Clients of failed attempts are closed, so retries do not pile up monitors
+			goleak.VerifyNone(t, ignore)
+		})
+	}
+}
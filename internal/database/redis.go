@@ -2,33 +2,97 @@ package database
 
 import (
 	"context"
-	"encoding/json"
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
 	"go-template/internal/interfaces"
 	"log"
+	"sync"
 	"time"
 
 	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"go-template/internal/shared/tracing"
 )
 
+// unlockScript deletes a lock only if it still holds the token set by this client,
+// so an expired lock re-acquired by someone else is never released by mistake
+var unlockScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
 // RedisCache implements the CacheInterface using Redis
 type RedisCache struct {
-	client redis.UniversalClient
+	client  redis.UniversalClient
+	tracer  trace.Tracer
+	breaker *circuitBreaker
+	
+	// lockTokens maps held lock keys to the token stored in Redis
+	lockTokens sync.Map
 }
 
 // ConnectRedis establishes a connection to Redis and returns a CacheInterface implementation
-func ConnectRedis(redisURL, password string, db int) (interfaces.CacheInterface, error) {
+func ConnectRedis(redisURL, password string, db, poolSize, minIdleConns int) (interfaces.CacheInterface, error) {
 	log.Printf("Connecting to Redis at %s...", redisURL)
 
+	// Create Redis client
+	client := redis.NewClient(redisOptions(redisURL, password, db, poolSize, minIdleConns))
+
+	// Test connection
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := client.Ping(ctx).Err(); err != nil {
+		_ = client.Close()
+		return nil, fmt.Errorf("failed to connect to Redis: %w", err)
+	}
+
+	log.Println("Successfully connected to Redis")
+
+	return newRedisCache(client), nil
+}
+
+// OpenRedis creates a Redis cache without checking that Redis is reachable
+// Calls fail, and trip the circuit breaker, until Redis is up. It lets the server start
+// while Redis is down; prefer ConnectRedis otherwise.
+func OpenRedis(redisURL, password string, db, poolSize, minIdleConns int) interfaces.CacheInterface {
+	log.Printf("Opened Redis at %s without a connection check", redisURL)
+	return newRedisCache(redis.NewClient(redisOptions(redisURL, password, db, poolSize, minIdleConns)))
+}
+
+// newRedisCache wraps client in our CacheInterface implementation
+func newRedisCache(client *redis.Client) *RedisCache {
+	cache := &RedisCache{
+		client:  client,
+		tracer:  tracing.Tracer("go-template/cache"),
+		breaker: newCircuitBreaker(DefaultBreakerThreshold, DefaultBreakerCooldown),
+	}
+	
+	// Stop hammering Redis while it is down; calls fail fast with ErrCircuitOpen
+	client.AddHook(cache.breaker)
+	
+	// Start periodic stats logging
+	go cache.logStats()
+
+	return cache
+}
+
+// redisOptions builds the client options for the Redis connection
+func redisOptions(redisURL, password string, db, poolSize, minIdleConns int) *redis.Options {
 	// Configure Redis client options for optimal performance
-	options := &redis.Options{
+	return &redis.Options{
 		Addr:     redisURL,
 		Password: password,
 		DB:       db,
 		
 		// Connection pool settings
-		PoolSize:     100,                // Maximum number of socket connections
-		MinIdleConns: 10,                 // Minimum number of idle connections
+		PoolSize:     poolSize,           // Maximum number of socket connections
+		MinIdleConns: minIdleConns,       // Minimum number of idle connections
 		PoolTimeout:  30 * time.Second,   // Amount of time client waits for connection
 		
 		// Timeouts
@@ -41,34 +105,55 @@ func ConnectRedis(redisURL, password string, db int) (interfaces.CacheInterface,
 		MinRetryBackoff: 8 * time.Millisecond,  // Minimum backoff between each retry
 		MaxRetryBackoff: 512 * time.Millisecond, // Maximum backoff between each retry
 	}
+}
 
-	// Create Redis client
-	client := redis.NewClient(options)
-
-	// Test connection
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
-	if err := client.Ping(ctx).Err(); err != nil {
-		return nil, fmt.Errorf("failed to connect to Redis: %w", err)
-	}
-
-	log.Println("Successfully connected to Redis")
-
-	// Wrap in our CacheInterface implementation
-	cache := &RedisCache{client: client}
-	
-	// Start periodic stats logging
-	go cache.logStats()
+// CircuitState returns the state of the circuit breaker guarding Redis calls
+func (r *RedisCache) CircuitState() CircuitState {
+	return r.breaker.State()
+}
 
-	return cache, nil
+// startSpan starts a client span for a cache operation
+func (r *RedisCache) startSpan(ctx context.Context, operation, key string) (context.Context, trace.Span) {
+	return r.tracer.Start(ctx, "cache."+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", "redis"),
+			attribute.String("db.operation", operation),
+			attribute.String("cache.key", key),
+		),
+	)
 }
 
 // Get retrieves a value from cache
 func (r *RedisCache) Get(ctx context.Context, key string) (string, error) {
+	ctx, span := r.startSpan(ctx, "Get", key)
+	defer span.End()
+	
 	result, err := r.client.Get(ctx, key).Result()
 	if err == redis.Nil {
-		return "", fmt.Errorf("key not found: %s", key)
+		span.SetAttributes(attribute.Bool("cache.hit", false))
+		return "", fmt.Errorf("%w: %s", interfaces.ErrCacheMiss, key)
+	}
+	span.SetAttributes(attribute.Bool("cache.hit", err == nil))
+	if err != nil {
+		span.RecordError(err)
+	}
+	return result, err
+}
+
+// GetDel atomically retrieves a value and removes it from cache
+func (r *RedisCache) GetDel(ctx context.Context, key string) (string, error) {
+	ctx, span := r.startSpan(ctx, "GetDel", key)
+	defer span.End()
+	
+	result, err := r.client.GetDel(ctx, key).Result()
+	if err == redis.Nil {
+		span.SetAttributes(attribute.Bool("cache.hit", false))
+		return "", fmt.Errorf("%w: %s", interfaces.ErrCacheMiss, key)
+	}
+	span.SetAttributes(attribute.Bool("cache.hit", err == nil))
+	if err != nil {
+		span.RecordError(err)
 	}
 	return result, err
 }
@@ -76,21 +161,15 @@ func (r *RedisCache) Get(ctx context.Context, key string) (string, error) {
 // Set stores a value in cache with expiration
 func (r *RedisCache) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error {
 	// Serialize value to JSON if it's not a string
-	var serialized interface{}
-	switch v := value.(type) {
-	case string:
-		serialized = v
-	case []byte:
-		serialized = v
-	default:
-		jsonBytes, err := json.Marshal(value)
-		if err != nil {
-			return fmt.Errorf("failed to serialize value for key %s: %w", key, err)
-		}
-		serialized = jsonBytes
+	serialized, err := serializeValue(value)
+	if err != nil {
+		return fmt.Errorf("failed to serialize value for key %s: %w", key, err)
 	}
 
-	return r.client.Set(ctx, key, serialized, expiration).Err()
+	ctx, span := r.startSpan(ctx, "Set", key)
+	err = r.client.Set(ctx, key, serialized, expiration).Err()
+	tracing.EndSpan(span, err)
+	return err
 }
 
 // Delete removes one or more keys from cache
@@ -161,26 +240,65 @@ func (r *RedisCache) Close() error {
 // Publish publishes a message to a channel
 func (r *RedisCache) Publish(ctx context.Context, channel string, message interface{}) error {
 	// Serialize message to JSON
-	var payload interface{}
-	switch v := message.(type) {
-	case string:
-		payload = v
-	case []byte:
-		payload = v
-	default:
-		jsonBytes, err := json.Marshal(message)
-		if err != nil {
-			return fmt.Errorf("failed to serialize message for channel %s: %w", channel, err)
-		}
-		payload = jsonBytes
+	payload, err := serializeValue(message)
+	if err != nil {
+		return fmt.Errorf("failed to serialize message for channel %s: %w", channel, err)
 	}
 
 	return r.client.Publish(ctx, channel, payload).Err()
 }
 
 // Subscribe subscribes to one or more channels
-func (r *RedisCache) Subscribe(ctx context.Context, channels ...string) *redis.PubSub {
-	return r.client.Subscribe(ctx, channels...)
+// It waits for Redis to confirm the subscription so connection errors surface here
+func (r *RedisCache) Subscribe(ctx context.Context, channels ...string) (interfaces.Subscription, error) {
+	pubsub := r.client.Subscribe(ctx, channels...)
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return nil, err
+	}
+	return newRedisSubscription(pubsub), nil
+}
+
+// redisSubscription adapts a go-redis PubSub to interfaces.Subscription
+type redisSubscription struct {
+	pubsub   *redis.PubSub
+	messages chan *interfaces.Message
+	done     chan struct{}
+	once     sync.Once
+}
+
+// newRedisSubscription starts forwarding messages from pubsub
+func newRedisSubscription(pubsub *redis.PubSub) *redisSubscription {
+	sub := &redisSubscription{
+		pubsub:   pubsub,
+		messages: make(chan *interfaces.Message),
+		done:     make(chan struct{}),
+	}
+	go sub.forward()
+	return sub
+}
+
+// forward copies messages until the PubSub channel closes or the subscription is closed
+func (s *redisSubscription) forward() {
+	defer close(s.messages)
+	for msg := range s.pubsub.Channel() {
+		select {
+		case s.messages <- &interfaces.Message{Channel: msg.Channel, Payload: msg.Payload}:
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// Messages returns the channel messages are delivered on
+func (s *redisSubscription) Messages() <-chan *interfaces.Message {
+	return s.messages
+}
+
+// Close ends the subscription
+func (s *redisSubscription) Close() error {
+	s.once.Do(func() { close(s.done) })
+	return s.pubsub.Close()
 }
 
 // logStats logs Redis connection statistics periodically
@@ -210,12 +328,7 @@ func (r *RedisCache) logStats() {
 
 // GetJSON retrieves and unmarshals a JSON value from cache
 func (r *RedisCache) GetJSON(ctx context.Context, key string, dest interface{}) error {
-	data, err := r.Get(ctx, key)
-	if err != nil {
-		return err
-	}
-	
-	return json.Unmarshal([]byte(data), dest)
+	return getJSON(ctx, r, key, dest)
 }
 
 // SetJSON marshals and stores a value as JSON in cache
@@ -223,26 +336,46 @@ func (r *RedisCache) SetJSON(ctx context.Context, key string, value interface{},
 	return r.Set(ctx, key, value, expiration)
 }
 
-// Remember implements the cache-aside pattern
-// It tries to get from cache first, if not found, calls the fetcher function and caches the result
-func (r *RedisCache) Remember(ctx context.Context, key string, expiration time.Duration, fetcher func() (interface{}, error)) (interface{}, error) {
-	// Try to get from cache first
-	if data, err := r.Get(ctx, key); err == nil {
-		return data, nil
+// Remember implements the cache-aside pattern, see remember
+func (r *RedisCache) Remember(ctx context.Context, key string, expiration time.Duration, dest interface{}, fetcher func() (interface{}, error)) error {
+	return remember(ctx, r, key, expiration, dest, fetcher)
+}
+
+// Lock attempts to acquire a distributed lock using SET NX PX
+// Returns true if the lock was acquired; the lock expires after ttl if never released
+func (r *RedisCache) Lock(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	tokenBytes := make([]byte, 16)
+	if _, err := rand.Read(tokenBytes); err != nil {
+		return false, fmt.Errorf("failed to generate lock token: %w", err)
 	}
+	token := hex.EncodeToString(tokenBytes)
 	
-	// Not in cache, call fetcher
-	value, err := fetcher()
+	acquired, err := r.client.SetNX(ctx, key, token, ttl).Result()
 	if err != nil {
-		return nil, err
+		return false, fmt.Errorf("failed to acquire lock %s: %w", key, err)
 	}
 	
-	// Store in cache (fire and forget)
-	go func() {
-		bgCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
-		r.Set(bgCtx, key, value, expiration)
-	}()
+	if acquired {
+		r.lockTokens.Store(key, token)
+	}
+	return acquired, nil
+}
+
+// Unlock releases a distributed lock previously acquired with Lock
+// Locks that have expired or are held by another client are left untouched
+func (r *RedisCache) Unlock(ctx context.Context, key string) error {
+	token, ok := r.lockTokens.LoadAndDelete(key)
+	if !ok {
+		return nil
+	}
 	
-	return value, nil
-}
\ No newline at end of file
+	if err := unlockScript.Run(ctx, r.client, []string{key}, token).Err(); err != nil {
+		return fmt.Errorf("failed to release lock %s: %w", key, err)
+	}
+	return nil
+}
+
+// RememberWithLock implements the cache-aside pattern with stampede protection, see rememberWithLock
+func (r *RedisCache) RememberWithLock(ctx context.Context, key string, expiration time.Duration, dest interface{}, fetcher func() (interface{}, error)) error {
+	return rememberWithLock(ctx, r, key, expiration, dest, fetcher)
+}
@@ -0,0 +1,442 @@
+// internal/database/redis_test.go
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.uber.org/goleak"
+
+	"go-template/internal/interfaces"
+)
+
+// newTestRedisCache returns a RedisCache backed by an in-process miniredis server
+func newTestRedisCache(t *testing.T) (*RedisCache, *miniredis.Miniredis) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	cache := newRedisCache(redis.NewClient(&redis.Options{Addr: server.Addr()}))
+	t.Cleanup(func() { cache.client.Close() })
+	return cache, server
+}
+
+func TestRedisLock(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		run  func(t *testing.T, holder, other *RedisCache, server *miniredis.Miniredis)
+		want bool // whether a fresh client can acquire the lock afterwards
+	}{
+		{
+			name: "held lock blocks other clients",
+			run:  func(t *testing.T, holder, other *RedisCache, server *miniredis.Miniredis) {},
+		},
+		{
+			name: "released lock can be acquired",
+			run: func(t *testing.T, holder, other *RedisCache, server *miniredis.Miniredis) {
+				if err := holder.Unlock(ctx, "lock:key"); err != nil {
+					t.Fatalf("Unlock() error = %v", err)
+				}
+			},
+			want: true,
+		},
+		{
+			name: "lock expires after its ttl",
+			run: func(t *testing.T, holder, other *RedisCache, server *miniredis.Miniredis) {
+				server.FastForward(2 * time.Second)
+			},
+			want: true,
+		},
+		{
+			name: "unlock by a client that does not hold the lock is a no-op",
+			run: func(t *testing.T, holder, other *RedisCache, server *miniredis.Miniredis) {
+				if err := other.Unlock(ctx, "lock:key"); err != nil {
+					t.Fatalf("Unlock() error = %v", err)
+				}
+			},
+		},
+		{
+			name: "expired lock taken over is not released by the old holder",
+			run: func(t *testing.T, holder, other *RedisCache, server *miniredis.Miniredis) {
+				server.FastForward(2 * time.Second)
+				if acquired, err := other.Lock(ctx, "lock:key", time.Second); err != nil || !acquired {
+					t.Fatalf("Lock() after expiry = %v, %v, want true", acquired, err)
+				}
+				if err := holder.Unlock(ctx, "lock:key"); err != nil {
+					t.Fatalf("Unlock() error = %v", err)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			holder, server := newTestRedisCache(t)
+			other := newRedisCache(redis.NewClient(&redis.Options{Addr: server.Addr()}))
+			t.Cleanup(func() { other.client.Close() })
+
+			acquired, err := holder.Lock(ctx, "lock:key", time.Second)
+			if err != nil || !acquired {
+				t.Fatalf("Lock() = %v, %v, want true", acquired, err)
+			}
+
+			tt.run(t, holder, other, server)
+
+			third := newRedisCache(redis.NewClient(&redis.Options{Addr: server.Addr()}))
+			t.Cleanup(func() { third.client.Close() })
+			got, err := third.Lock(ctx, "lock:key", time.Second)
+			if err != nil {
+				t.Fatalf("Lock() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Lock() afterwards = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedisRememberWithLock(t *testing.T) {
+	type stats struct {
+		Total int `json:"total"`
+	}
+
+	tests := []struct {
+		name      string
+		callers   int
+		cached    bool
+		wantCalls int32
+	}{
+		{name: "single caller", callers: 1, wantCalls: 1},
+		{name: "concurrent callers share one fetch", callers: 20, wantCalls: 1},
+		{name: "cache hit skips the fetcher", callers: 5, cached: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			cache, server := newTestRedisCache(t)
+			if tt.cached {
+				if err := cache.Set(ctx, "stats", `{"total":42}`, time.Minute); err != nil {
+					t.Fatalf("Set() error = %v", err)
+				}
+			}
+
+			var calls atomic.Int32
+			fetcher := func() (interface{}, error) {
+				calls.Add(1)
+				time.Sleep(100 * time.Millisecond) // long enough for every caller to miss
+				return stats{Total: 42}, nil
+			}
+
+			start := make(chan struct{})
+			results := make([]stats, tt.callers)
+			errs := make([]error, tt.callers)
+			var wg sync.WaitGroup
+			for i := 0; i < tt.callers; i++ {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					<-start
+					errs[i] = cache.RememberWithLock(ctx, "stats", time.Minute, &results[i], fetcher)
+				}(i)
+			}
+			close(start)
+			wg.Wait()
+
+			if got := calls.Load(); got != tt.wantCalls {
+				t.Errorf("fetcher ran %d times, want %d", got, tt.wantCalls)
+			}
+			for i := range results {
+				if errs[i] != nil {
+					t.Errorf("caller %d error = %v", i, errs[i])
+				}
+				if results[i].Total != 42 {
+					t.Errorf("caller %d got %+v, want total 42", i, results[i])
+				}
+			}
+			if server.Exists(lockKeyPrefix + "stats") {
+				t.Error("lock still held after RememberWithLock returned")
+			}
+		})
+	}
+}
+
+func TestRemember(t *testing.T) {
+	type profile struct {
+		Name  string    `json:"name"`
+		Roles []string  `json:"roles"`
+		Seen  time.Time `json:"seen"`
+	}
+	want := profile{Name: "alice", Roles: []string{"user", "admin"}, Seen: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)}
+
+	caches := []struct {
+		name string
+		new  func(t *testing.T) interfaces.CacheInterface
+	}{
+		{name: "redis", new: func(t *testing.T) interfaces.CacheInterface {
+			cache, _ := newTestRedisCache(t)
+			return cache
+		}},
+		{name: "memory", new: func(t *testing.T) interfaces.CacheInterface {
+			cache := NewMemoryCache()
+			t.Cleanup(func() { cache.Close() })
+			return cache
+		}},
+	}
+
+	for _, c := range caches {
+		t.Run(c.name, func(t *testing.T) {
+			ctx := context.Background()
+			cache := c.new(t)
+
+			calls := 0
+			fetcher := func() (interface{}, error) {
+				calls++
+				return want, nil
+			}
+
+			var miss, hit profile
+			if err := cache.Remember(ctx, "profile", time.Minute, &miss, fetcher); err != nil {
+				t.Fatalf("Remember() on a miss error = %v", err)
+			}
+			if err := cache.Remember(ctx, "profile", time.Minute, &hit, fetcher); err != nil {
+				t.Fatalf("Remember() on a hit error = %v", err)
+			}
+
+			if calls != 1 {
+				t.Errorf("fetcher ran %d times, want 1", calls)
+			}
+			if !reflect.DeepEqual(miss, want) {
+				t.Errorf("miss = %+v, want %+v", miss, want)
+			}
+			if !reflect.DeepEqual(hit, miss) {
+				t.Errorf("hit = %+v, want it identical to the miss %+v", hit, miss)
+			}
+
+			// The value is stored as JSON before Remember returns
+			var stored profile
+			if err := getJSON(ctx, cache, "profile", &stored); err != nil || !reflect.DeepEqual(stored, want) {
+				t.Errorf("stored value = %+v, %v, want %+v", stored, err, want)
+			}
+		})
+	}
+}
+
+func TestRememberFetcherError(t *testing.T) {
+	ctx := context.Background()
+	cache, server := newTestRedisCache(t)
+	fetchErr := errors.New("database down")
+
+	var dest struct{ Name string }
+	err := cache.Remember(ctx, "profile", time.Minute, &dest, func() (interface{}, error) {
+		return nil, fetchErr
+	})
+	if !errors.Is(err, fetchErr) {
+		t.Errorf("Remember() error = %v, want %v", err, fetchErr)
+	}
+	if server.Exists("profile") {
+		t.Error("failed fetch was cached")
+	}
+}
+
+func TestRememberHonorsCancellation(t *testing.T) {
+	caches := []struct {
+		name string
+		new  func(t *testing.T) interfaces.CacheInterface
+	}{
+		{name: "redis", new: func(t *testing.T) interfaces.CacheInterface {
+			cache, _ := newTestRedisCache(t)
+			return cache
+		}},
+		{name: "memory", new: func(t *testing.T) interfaces.CacheInterface {
+			cache := NewMemoryCache()
+			t.Cleanup(func() { cache.Close() })
+			return cache
+		}},
+	}
+	paths := []struct {
+		name   string
+		cached bool
+	}{
+		{name: "hit", cached: true},
+		{name: "miss", cached: false},
+	}
+
+	for _, c := range caches {
+		for _, p := range paths {
+			t.Run(c.name+" "+p.name, func(t *testing.T) {
+				cache := c.new(t)
+				if p.cached {
+					if err := cache.Set(context.Background(), "profile", `{"Name":"alice"}`, time.Minute); err != nil {
+						t.Fatalf("Set() error = %v", err)
+					}
+				}
+
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+
+				calls := 0
+				var dest struct{ Name string }
+				err := cache.Remember(ctx, "profile", time.Minute, &dest, func() (interface{}, error) {
+					calls++
+					return struct{ Name string }{Name: "bob"}, nil
+				})
+				if !errors.Is(err, context.Canceled) {
+					t.Errorf("Remember() error = %v, want %v", err, context.Canceled)
+				}
+				if calls != 0 {
+					t.Errorf("fetcher ran %d times after cancellation, want 0", calls)
+				}
+				if dest.Name != "" {
+					t.Errorf("dest = %+v, want it untouched", dest)
+				}
+			})
+		}
+	}
+}
+
+func TestRememberDoesNotLeakGoroutines(t *testing.T) {
+	caches := []struct {
+		name string
+		new  func(t *testing.T) interfaces.CacheInterface
+	}{
+		{name: "redis", new: func(t *testing.T) interfaces.CacheInterface {
+			cache, _ := newTestRedisCache(t)
+			return cache
+		}},
+		{name: "memory", new: func(t *testing.T) interfaces.CacheInterface {
+			cache := NewMemoryCache()
+			t.Cleanup(func() { cache.Close() })
+			return cache
+		}},
+	}
+
+	for _, c := range caches {
+		t.Run(c.name, func(t *testing.T) {
+			ctx := context.Background()
+			cache := c.new(t)
+			// Warm the connection pool so its goroutines exist before the baseline is taken
+			if err := cache.Set(ctx, "warmup", "1", time.Minute); err != nil {
+				t.Fatalf("Set() error = %v", err)
+			}
+			ignore := goleak.IgnoreCurrent()
+
+			for i := 0; i < 200; i++ {
+				// Keys repeat, so the loop exercises both hits and misses
+				key := fmt.Sprintf("profile:%d", i%50)
+				var dest struct{ ID int }
+				if err := cache.Remember(ctx, key, time.Minute, &dest, func() (interface{}, error) {
+					return struct{ ID int }{ID: i}, nil
+				}); err != nil {
+					t.Fatalf("Remember(%s) error = %v", key, err)
+				}
+			}
+
+			goleak.VerifyNone(t, ignore)
+		})
+	}
+}
+
+func TestRedisCacheSpans(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	ctx := context.Background()
+	cache, _ := newTestRedisCache(t)
+	if err := cache.Set(ctx, "user:1", "alice", time.Minute); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	cache.Get(ctx, "user:1")
+	cache.Get(ctx, "user:2")
+
+	tests := []struct {
+		name      string
+		operation string
+		key       string
+		hit       string // expected cache.hit attribute, if any
+	}{
+		{name: "set", operation: "Set", key: "user:1"},
+		{name: "hit", operation: "Get", key: "user:1", hit: "true"},
+		{name: "miss", operation: "Get", key: "user:2", hit: "false"},
+	}
+
+	ended := recorder.Ended()
+	if len(ended) != len(tests) {
+		t.Fatalf("got %d spans, want %d", len(ended), len(tests))
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			span := ended[i]
+			if span.Name() != "cache."+tt.operation {
+				t.Errorf("span name = %q, want %q", span.Name(), "cache."+tt.operation)
+			}
+			attrs := map[string]string{}
+			for _, attr := range span.Attributes() {
+				attrs[string(attr.Key)] = attr.Value.Emit()
+			}
+			if attrs["db.system"] != "redis" || attrs["db.operation"] != tt.operation || attrs["cache.key"] != tt.key {
+				t.Errorf("attributes = %v", attrs)
+			}
+			if tt.hit != "" && attrs["cache.hit"] != tt.hit {
+				t.Errorf("cache.hit = %q, want %q", attrs["cache.hit"], tt.hit)
+			}
+		})
+	}
+}
+
+func TestRedisOptions(t *testing.T) {
+	tests := []struct {
+		name         string
+		db           int
+		poolSize     int
+		minIdleConns int
+	}{
+		{name: "default pool", db: 0, poolSize: 100, minIdleConns: 10},
+		{name: "small pool without idle connections", db: 3, poolSize: 5, minIdleConns: 0},
+		{name: "idle connections equal to pool size", db: 15, poolSize: 8, minIdleConns: 8},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := redisOptions("localhost:6379", "secret", tt.db, tt.poolSize, tt.minIdleConns)
+
+			if opts.Addr != "localhost:6379" || opts.Password != "secret" || opts.DB != tt.db {
+				t.Errorf("connection = %s/%s/%d, want localhost:6379/secret/%d", opts.Addr, opts.Password, opts.DB, tt.db)
+			}
+			if opts.PoolSize != tt.poolSize {
+				t.Errorf("PoolSize = %d, want %d", opts.PoolSize, tt.poolSize)
+			}
+			if opts.MinIdleConns != tt.minIdleConns {
+				t.Errorf("MinIdleConns = %d, want %d", opts.MinIdleConns, tt.minIdleConns)
+			}
+		})
+	}
+}
+
+func TestOpenRedisAppliesPoolSizes(t *testing.T) {
+	server := miniredis.RunT(t)
+
+	cache := OpenRedis(server.Addr(), "", 0, 7, 2).(*RedisCache)
+	t.Cleanup(func() { cache.client.Close() })
+
+	opts := cache.client.(*redis.Client).Options()
+	if opts.PoolSize != 7 || opts.MinIdleConns != 2 {
+		t.Errorf("pool = %d/%d, want 7/2", opts.PoolSize, opts.MinIdleConns)
+	}
+	if err := cache.Set(context.Background(), "key", "value", time.Minute); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+}
@@ -2,14 +2,18 @@ package interfaces
 
 import (
 	"context"
+	"errors"
 	"time"
-
-	"github.com/redis/go-redis/v9"
 )
 
+// ErrCacheMiss is returned by Get and GetDel when the key does not exist
+// Any other error means the cache itself failed and callers should fall back to the source of truth
+var ErrCacheMiss = errors.New("key not found")
+
 // CacheInterface defines the contract for cache operations
 type CacheInterface interface {
 	Get(ctx context.Context, key string) (string, error)
+	GetDel(ctx context.Context, key string) (string, error)
 	Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error
 	Delete(ctx context.Context, keys ...string) error
 	Exists(ctx context.Context, key string) (bool, error)
@@ -22,5 +26,31 @@ type CacheInterface interface {
 	Ping(ctx context.Context) error
 	Close() error
 	Publish(ctx context.Context, channel string, message interface{}) error
-	Subscribe(ctx context.Context, channels ...string) *redis.PubSub
-} 
\ No newline at end of file
+	Subscribe(ctx context.Context, channels ...string) (Subscription, error)
+	
+	// Distributed locking
+	Lock(ctx context.Context, key string, ttl time.Duration) (bool, error)
+	Unlock(ctx context.Context, key string) error
+	
+	// Cache-aside helpers (results are unmarshaled into dest on hit and miss alike)
+	Remember(ctx context.Context, key string, expiration time.Duration, dest interface{}, fetcher func() (interface{}, error)) error
+	RememberWithLock(ctx context.Context, key string, expiration time.Duration, dest interface{}, fetcher func() (interface{}, error)) error
+} 
+
+// Message is a payload received on a subscribed channel
+type Message struct {
+	Channel string
+	Payload string
+}
+
+// Subscription delivers messages published to the channels it was created for
+type Subscription interface {
+	// Messages returns the channel messages arrive on; it is closed when the subscription ends
+	Messages() <-chan *Message
+	Close() error
+}
+
+// CacheInvalidator removes keys from the cache on every running instance
+type CacheInvalidator interface {
+	Invalidate(ctx context.Context, keys ...string) error
+}
@@ -0,0 +1,9 @@
+package interfaces
+
+import "time"
+
+// Clock defines the source of the current time
+// Code that stamps records takes the time from a Clock so tests can fix it.
+type Clock interface {
+	Now() time.Time
+}
@@ -0,0 +1,21 @@
+package interfaces
+
+import "errors"
+
+// Sentinel errors shared by repositories and services
+// Errors are wrapped with %w so their message keeps its context (e.g. "user not found") while
+// handlers can classify them with errors.Is instead of matching on the message.
+var (
+	// ErrNotFound means the requested record does not exist
+	ErrNotFound = errors.New("not found")
+
+	// ErrAlreadyExists means a record with the same unique value, such as an email, exists
+	ErrAlreadyExists = errors.New("already exists")
+
+	// ErrValidation means the input was rejected; the message says why
+	ErrValidation = errors.New("validation failed")
+
+	// ErrInvalidState means the record is not in a state that allows the operation,
+	// such as activating a user that is already active
+	ErrInvalidState = errors.New("invalid state")
+)
@@ -0,0 +1,10 @@
+package interfaces
+
+import "context"
+
+// EventPublisher defines the contract for announcing domain events such as "user.created"
+type EventPublisher interface {
+	// Publish queues an event for delivery to subscribers without waiting for it
+	// data must be JSON serializable; it becomes the event payload
+	Publish(ctx context.Context, eventType string, data interface{}) error
+}
@@ -0,0 +1,9 @@
+package interfaces
+
+// CacheMetrics counts cache lookups by key category, such as "user" or "list"
+// A lookup is a hit, a miss, or an error when the cache could not answer.
+type CacheMetrics interface {
+	CacheHit(category string)
+	CacheMiss(category string)
+	CacheError(category string)
+}
@@ -0,0 +1,16 @@
+package interfaces
+
+import (
+	"context"
+	"io"
+)
+
+// FileStorage defines the contract for storing user-uploaded files
+type FileStorage interface {
+	// Save stores the content under key and returns its public URL
+	Save(ctx context.Context, key string, content io.Reader) (string, error)
+	// Delete removes the file stored under key; missing files are not an error
+	Delete(ctx context.Context, key string) error
+	// KeyFromURL returns the key for a URL produced by Save, or false if it is not managed by this storage
+	KeyFromURL(url string) (string, bool)
+}
@@ -5,6 +5,8 @@ import (
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"go-template/internal/shared/utils"
 )
 
 // BaseModel contains common fields for all models
@@ -13,11 +15,14 @@ type BaseModel struct {
 	CreatedAt time.Time         `json:"created_at" bson:"created_at"`
 	UpdatedAt time.Time         `json:"updated_at" bson:"updated_at"`
 	DeletedAt *time.Time        `json:"deleted_at,omitempty" bson:"deleted_at,omitempty"`
+	Version   int64             `json:"version" bson:"version"` // Incremented on every update for optimistic concurrency
+	CreatedBy string            `json:"created_by,omitempty" bson:"created_by,omitempty"` // ID of the user who created the record
+	UpdatedBy string            `json:"updated_by,omitempty" bson:"updated_by,omitempty"` // ID of the user who last changed the record
 }
 
 // NewBaseModel creates a new base model with current timestamps
 func NewBaseModel() *BaseModel {
-	now := time.Now().UTC()
+	now := utils.Now()
 	return &BaseModel{
 		ID:        primitive.NewObjectID(),
 		CreatedAt: now,
@@ -25,14 +30,20 @@ func NewBaseModel() *BaseModel {
 	}
 }
 
+// SetCreatedBy records the acting user as both creator and last updater
+func (b *BaseModel) SetCreatedBy(userID string) {
+	b.CreatedBy = userID
+	b.UpdatedBy = userID
+}
+
 // UpdateTimestamp updates the UpdatedAt field to current time
 func (b *BaseModel) UpdateTimestamp() {
-	b.UpdatedAt = time.Now().UTC()
+	b.UpdatedAt = utils.Now()
 }
 
 // SoftDelete marks the model as deleted by setting DeletedAt
 func (b *BaseModel) SoftDelete() {
-	now := time.Now().UTC()
+	now := utils.Now()
 	b.DeletedAt = &now
 	b.UpdatedAt = now
 }
@@ -42,6 +53,11 @@ func (b *BaseModel) IsDeleted() bool {
 	return b.DeletedAt != nil
 }
 
+// SetID sets the document ID, e.g. after the database generated one on insert
+func (b *BaseModel) SetID(id primitive.ObjectID) {
+	b.ID = id
+}
+
 // GetIDString returns the ID as a string
 func (b *BaseModel) GetIDString() string {
 	return b.ID.Hex()
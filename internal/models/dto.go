@@ -3,8 +3,13 @@ package models
 
 import (
 	"encoding/json"
+	"fmt"
+	"strconv"
 	"strings"
 	"time"
+
+	"go-template/internal/interfaces"
+	"go-template/internal/shared/utils"
 )
 
 // CreateUserRequest represents the request payload for creating a user
@@ -25,6 +30,23 @@ type UpdateUserRequest struct {
 	Bio       *string `json:"bio,omitempty" validate:"omitempty,max=500" example:"Software developer and coffee enthusiast"`
 	Location  *string `json:"location,omitempty" validate:"omitempty,max=100" example:"San Francisco, CA"`
 	Website   *string `json:"website,omitempty" validate:"omitempty,url,max=255" example:"https://johndoe.dev"`
+	DateOfBirth *string `json:"date_of_birth,omitempty" example:"1990-05-17"` // YYYY-MM-DD or RFC3339; empty clears it
+	Version   *int64  `json:"version,omitempty" example:"3"` // Expected current version; the update fails with 409 if it is stale
+}
+
+// ReplaceUserRequest represents the request payload for replacing a user's profile
+// Unlike UpdateUserRequest every mutable profile field is written, so omitted optional fields are
+// cleared. Roles, verification and account status are not part of the profile and are untouched.
+type ReplaceUserRequest struct {
+	Username    string `json:"username" validate:"required,min=3,max=30" example:"janedoe"`
+	Email       string `json:"email" validate:"required,email,max=255" example:"jane@example.com"`
+	FirstName   string `json:"first_name" validate:"max=50" example:"Jane"`
+	LastName    string `json:"last_name" validate:"max=50" example:"Smith"`
+	Bio         string `json:"bio" validate:"max=500" example:"Software developer and coffee enthusiast"`
+	Location    string `json:"location" validate:"max=100" example:"San Francisco, CA"`
+	Website     string `json:"website" validate:"omitempty,url,max=255" example:"https://johndoe.dev"`
+	DateOfBirth string `json:"date_of_birth" example:"1990-05-17"` // YYYY-MM-DD or RFC3339
+	Version     *int64 `json:"version,omitempty" example:"3"`      // Expected current version; the update fails with 409 if it is stale
 }
 
 // ChangePasswordRequest represents the request payload for changing password
@@ -38,6 +60,47 @@ type ChangePasswordRequest struct {
 type LoginRequest struct {
 	Username string `json:"username" validate:"required" example:"johndoe"`
 	Password string `json:"password" validate:"required" example:"SecurePass123"`
+	// RememberMe requests an extended refresh token lifetime
+	RememberMe bool `json:"remember_me,omitempty" example:"false"`
+}
+
+// VerifyEmailRequest represents the request payload for confirming an email address
+type VerifyEmailRequest struct {
+	Token string `json:"token" validate:"required" example:"9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"`
+}
+
+// ForgotPasswordRequest represents the request payload for starting a password reset
+type ForgotPasswordRequest struct {
+	Email string `json:"email" validate:"required,email" example:"john.doe@example.com"`
+}
+
+// ResetPasswordRequest represents the request payload for completing a password reset
+type ResetPasswordRequest struct {
+	Token           string `json:"token" validate:"required" example:"9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"`
+	NewPassword     string `json:"new_password" validate:"required,min=8,max=128" example:"NewSecurePassword456"`
+	ConfirmPassword string `json:"confirm_password" validate:"required" example:"NewSecurePassword456"`
+}
+
+// SetRolesRequest represents the request payload for replacing a user's roles
+type SetRolesRequest struct {
+	Roles []string `json:"roles" validate:"required,min=1,dive,oneof=user admin moderator" example:"user,moderator"`
+}
+
+// MaxBatchGetSize caps the number of IDs accepted by a single batch lookup
+const MaxBatchGetSize = 100
+
+// BatchGetUsersRequest represents the request payload for resolving many user IDs at once
+type BatchGetUsersRequest struct {
+	IDs []string `json:"ids" validate:"required,min=1,max=100" example:"507f1f77bcf86cd799439011,507f191e810c19729de860ea"`
+}
+
+// MaxBulkDeleteSize caps the number of IDs accepted by a single bulk delete
+const MaxBulkDeleteSize = 100
+
+// BulkDeleteRequest represents the request payload for soft-deleting many users at once
+type BulkDeleteRequest struct {
+	IDs    []string `json:"ids" validate:"required,min=1,max=100" example:"507f1f77bcf86cd799439011,507f191e810c19729de860ea"`
+	DryRun bool     `json:"dry_run" example:"true"` // Report what would be deleted without deleting anything
 }
 
 // UserResponse represents the response payload for user data
@@ -59,9 +122,13 @@ type UserResponse struct {
 	LastLoginAt     *time.Time             `json:"last_login_at"`
 	EmailVerifiedAt *time.Time             `json:"email_verified_at"`
 	LoginCount      int                    `json:"login_count"`
+	ProfileViews    int                    `json:"profile_views"`
 	Preferences     map[string]interface{} `json:"preferences"`
 	CreatedAt       time.Time              `json:"created_at"`
 	UpdatedAt       time.Time              `json:"updated_at"`
+	Version         int64                  `json:"version"`
+	CreatedBy       string                 `json:"created_by,omitempty"` // Only populated for admin callers
+	UpdatedBy       string                 `json:"updated_by,omitempty"` // Only populated for admin callers
 }
 
 // UserListResponse represents the response for user list queries
@@ -84,6 +151,7 @@ type UserProfileResponse struct {
 	IsVerified  bool       `json:"is_verified"`
 	CreatedAt   time.Time  `json:"created_at"`
 	LastLoginAt *time.Time `json:"last_login_at,omitempty"`
+	IsOnline    bool       `json:"is_online"` // Active within the configured online window
 }
 
 // LoginResponse represents the response payload for successful login
@@ -92,18 +160,224 @@ type LoginResponse struct {
 	RefreshToken string       `json:"refresh_token"`
 	TokenType    string       `json:"token_type"`
 	ExpiresIn    int          `json:"expires_in"`
+	RefreshExpiresIn int      `json:"refresh_expires_in"`
 	User         UserResponse `json:"user"`
 }
 
+// ImpersonationResponse represents the access token issued to an admin acting as a user
+// There is no refresh token; the admin impersonates again once the access token expires.
+type ImpersonationResponse struct {
+	AccessToken    string       `json:"access_token"`
+	TokenType      string       `json:"token_type"`
+	ExpiresIn      int          `json:"expires_in"`
+	ImpersonatedBy string       `json:"impersonated_by"`
+	User           UserResponse `json:"user"`
+}
+
+// BulkCreateResult represents the outcome of a single item in a bulk user import
+type BulkCreateResult struct {
+	Index   int           `json:"index"`
+	Success bool          `json:"success"`
+	User    *UserResponse `json:"user,omitempty"`
+	Error   string        `json:"error,omitempty"`
+}
+
+// BulkCreateResponse represents the response for a bulk user import
+type BulkCreateResponse struct {
+	Results   []BulkCreateResult `json:"results"`
+	Succeeded int                `json:"succeeded"`
+	Failed    int                `json:"failed"`
+}
+
+// BulkDeleteResponse represents the outcome of a bulk delete
+type BulkDeleteResponse struct {
+	DryRun   bool     `json:"dry_run"`
+	Deleted  []string `json:"deleted"`   // IDs deleted, or that would be deleted on a dry run
+	NotFound []string `json:"not_found"` // IDs that do not exist or are already deleted
+}
+
+// Page size limits for user listing
+const (
+	DefaultPageLimit    = 20
+	DefaultMaxPageLimit = 100
+)
+
+// maxPageLimit is the largest page size a client may request; set it through SetMaxPageLimit
+var maxPageLimit = DefaultMaxPageLimit
+
+// SetMaxPageLimit sets the largest page size a client may request
+// Call it during startup, before requests are served.
+func SetMaxPageLimit(limit int) {
+	maxPageLimit = limit
+}
+
+// MaxPageLimit returns the largest page size a client may request
+func MaxPageLimit() int {
+	return maxPageLimit
+}
+
 // UsersQueryParams represents query parameters for user listing
 type UsersQueryParams struct {
-	Page     int    `json:"page" validate:"min=1"`
-	Limit    int    `json:"limit" validate:"min=1,max=100"`
-	Search   string `json:"search,omitempty"`
-	Role     string `json:"role,omitempty"`
-	IsActive *bool  `json:"is_active,omitempty"`
-	SortBy   string `json:"sort_by,omitempty"`
-	SortDir  string `json:"sort_dir,omitempty"`
+	Page     int    `json:"page" query:"page" min:"1" validate:"min=1"`
+	Limit    int    `json:"limit" query:"limit" min:"1" validate:"min=1,max=100"` // The upper bound is configured, see MaxPageLimit
+	Search   string `json:"search,omitempty" query:"search"`
+	Role     string `json:"role,omitempty" query:"role"`
+	IsActive *bool  `json:"is_active,omitempty" query:"is_active"`
+	SortBy   string `json:"sort_by,omitempty" query:"sort_by"`
+	SortDir  string `json:"sort_dir,omitempty" query:"sort_dir"`
+	
+	// Additional filters
+	IsVerified    *bool      `json:"is_verified,omitempty" query:"is_verified"`
+	HasRoles      []string   `json:"has_role,omitempty" query:"has_role"`             // User must hold every listed role
+	CreatedAfter  *time.Time `json:"created_after,omitempty" query:"created_after"`   // Inclusive
+	CreatedBefore *time.Time `json:"created_before,omitempty" query:"created_before"` // Exclusive
+	
+	// Fields limits the returned user fields; empty means all fields
+	Fields []string `json:"fields,omitempty"`
+	
+	// Sort is the parsed form of SortBy, derived by SetDefaults
+	Sort []SortField `json:"-"`
+}
+
+// HasFilters reports whether any filter beyond pagination and sorting is set
+func (q *UsersQueryParams) HasFilters() bool {
+	return q.Search != "" || q.Role != "" || q.IsActive != nil || q.IsVerified != nil ||
+		len(q.HasRoles) > 0 || q.CreatedAfter != nil || q.CreatedBefore != nil
+}
+
+// SortField represents a single key of a multi-field sort
+type SortField struct {
+	Field      string
+	Descending bool
+}
+
+// UserSortFields lists the fields users can be sorted by
+var UserSortFields = []string{"created_at", "updated_at", "username", "email", "first_name", "last_name", "login_count"}
+
+// ParseSortFields parses a comma-separated sort specification such as "last_name,-created_at"
+// A "-" prefix sorts that field descending; other fields use defaultDir ("asc" or "desc")
+func ParseSortFields(sortBy, defaultDir string) []SortField {
+	var fields []SortField
+	for _, part := range strings.Split(sortBy, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		
+		field := SortField{Field: part, Descending: defaultDir == "desc"}
+		if strings.HasPrefix(part, "-") {
+			field = SortField{Field: strings.TrimSpace(part[1:]), Descending: true}
+		}
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+// UserSelectableFields maps each UserResponse field clients may select with ?fields=
+// to the document fields needed to compute it. Sensitive fields are deliberately absent.
+var UserSelectableFields = map[string][]string{
+	"id":                {"_id"},
+	"username":          {"username"},
+	"email":             {"email"},
+	"first_name":        {"first_name"},
+	"last_name":         {"last_name"},
+	"full_name":         {"first_name", "last_name", "username"},
+	"avatar":            {"avatar"},
+	"bio":               {"bio"},
+	"location":          {"location"},
+	"website":           {"website"},
+	"date_of_birth":     {"date_of_birth"},
+	"is_active":         {"is_active"},
+	"is_verified":       {"is_verified"},
+	"roles":             {"roles"},
+	"last_login_at":     {"last_login_at"},
+	"email_verified_at": {"email_verified_at"},
+	"login_count":       {"login_count"},
+	"preferences":       {"preferences"},
+	"created_at":        {"created_at"},
+	"updated_at":        {"updated_at"},
+	"version":           {"version"},
+}
+
+// ParseUserFields parses a comma-separated list of selectable user fields
+// The id field is always included so clients can correlate results
+func ParseUserFields(raw string) ([]string, error) {
+	fields := []string{"id"}
+	seen := map[string]bool{"id": true}
+	for _, part := range strings.Split(raw, ",") {
+		field := strings.ToLower(strings.TrimSpace(part))
+		if field == "" || seen[field] {
+			continue
+		}
+		if _, ok := UserSelectableFields[field]; !ok {
+			return nil, fmt.Errorf("unknown field %q", field)
+		}
+		seen[field] = true
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// Search modes for user search
+const (
+	SearchModeAuto  = "auto"  // text search, falling back to regex for short queries
+	SearchModeText  = "text"  // full-text search using the text index
+	SearchModeRegex = "regex" // case-insensitive substring match
+)
+
+// MinTextSearchLength is the shortest query for which auto mode uses text search
+const MinTextSearchLength = 3
+
+// IsValidSearchMode checks if a search mode is supported
+func IsValidSearchMode(mode string) bool {
+	return mode == SearchModeAuto || mode == SearchModeText || mode == SearchModeRegex
+}
+
+// UserStatsParams represents the optional filters for user statistics
+// From is inclusive and To is exclusive; a nil bound leaves that side open
+type UserStatsParams struct {
+	From *time.Time `json:"from,omitempty"`
+	To   *time.Time `json:"to,omitempty"`
+}
+
+// HasDateRange reports whether any date bound is set
+func (p *UserStatsParams) HasDateRange() bool {
+	return p != nil && (p.From != nil || p.To != nil)
+}
+
+// UserCountParams holds the optional filters for counting users
+type UserCountParams struct {
+	IsActive *bool  `json:"is_active,omitempty" query:"is_active"`
+	Role     string `json:"role,omitempty" query:"role"`
+}
+
+// HasFilters reports whether any filter is set
+func (p *UserCountParams) HasFilters() bool {
+	return p != nil && (p.IsActive != nil || p.Role != "")
+}
+
+// UserCountResponse is the number of users matching a count request
+type UserCountResponse struct {
+	Count int `json:"count" example:"42"`
+}
+
+// DayFormat is the layout of the dates in a signup series
+const DayFormat = "2006-01-02"
+
+// DayCount is the number of users who signed up on one UTC day
+type DayCount struct {
+	Date  string `json:"date" example:"2024-01-15"` // YYYY-MM-DD
+	Count int    `json:"count" example:"12"`
+}
+
+// UserStatsResponse represents aggregated user statistics
+type UserStatsResponse struct {
+	TotalUsers        int            `json:"total_users"`
+	ActiveUsers       int            `json:"active_users"`
+	VerifiedUsers     int            `json:"verified_users"`
+	AvgLoginCount     float64        `json:"avg_login_count"`
+	ByRole            map[string]int `json:"by_role"`
+	NewUsersLast7Days int            `json:"new_users_last_7_days"`
 }
 
 // Conversion methods
@@ -128,12 +402,22 @@ func (u *User) ToUserResponse() UserResponse {
 		LastLoginAt:     u.LastLoginAt,
 		EmailVerifiedAt: u.EmailVerifiedAt,
 		LoginCount:      u.LoginCount,
+		ProfileViews:    u.ProfileViews,
 		Preferences:     u.Preferences,
 		CreatedAt:       u.CreatedAt,
 		UpdatedAt:       u.UpdatedAt,
+		Version:         u.Version,
 	}
 }
 
+// ToAdminUserResponse converts a User model to UserResponse DTO including audit fields
+func (u *User) ToAdminUserResponse() UserResponse {
+	resp := u.ToUserResponse()
+	resp.CreatedBy = u.CreatedBy
+	resp.UpdatedBy = u.UpdatedBy
+	return resp
+}
+
 // ToUserProfileResponse converts a User model to UserProfileResponse DTO (public profile)
 func (u *User) ToUserProfileResponse() UserProfileResponse {
 	profile := UserProfileResponse{
@@ -156,15 +440,65 @@ func (u *User) ToUserProfileResponse() UserProfileResponse {
 	return profile
 }
 
+// Select returns only the given fields of the response, keyed by their JSON names
+func (r UserResponse) Select(fields []string) map[string]interface{} {
+	payload, err := json.Marshal(r)
+	if err != nil {
+		return map[string]interface{}{}
+	}
+	var all map[string]interface{}
+	if err := json.Unmarshal(payload, &all); err != nil {
+		return map[string]interface{}{}
+	}
+	
+	selected := make(map[string]interface{}, len(fields))
+	for _, field := range fields {
+		if value, ok := all[field]; ok {
+			selected[field] = value
+		}
+	}
+	return selected
+}
+
+// UserCSVHeaders lists the columns produced by UserResponse.ToCSVRow
+var UserCSVHeaders = []string{
+	"id", "username", "email", "first_name", "last_name", "roles",
+	"is_active", "is_verified", "login_count", "last_login_at", "created_at", "updated_at",
+}
+
+// ToCSVRow converts a UserResponse to a CSV row matching UserCSVHeaders
+func (r UserResponse) ToCSVRow() []string {
+	lastLogin := ""
+	if r.LastLoginAt != nil {
+		lastLogin = r.LastLoginAt.Format(time.RFC3339)
+	}
+	
+	return []string{
+		r.ID,
+		r.Username,
+		r.Email,
+		r.FirstName,
+		r.LastName,
+		strings.Join(r.Roles, ";"),
+		strconv.FormatBool(r.IsActive),
+		strconv.FormatBool(r.IsVerified),
+		strconv.Itoa(r.LoginCount),
+		lastLogin,
+		r.CreatedAt.Format(time.RFC3339),
+		r.UpdatedAt.Format(time.RFC3339),
+	}
+}
+
 // ToMap converts UpdateUserRequest to a map for partial updates
 func (r *UpdateUserRequest) ToMap() map[string]interface{} {
 	updates := make(map[string]interface{})
 	
+	// Usernames and emails are stored lowercased, as NewUser does, so uniqueness is case-insensitive
 	if r.Username != nil {
-		updates["username"] = strings.TrimSpace(*r.Username)
+		updates["username"] = NormalizeUsername(*r.Username)
 	}
 	if r.Email != nil {
-		updates["email"] = strings.TrimSpace(*r.Email)
+		updates["email"] = strings.ToLower(strings.TrimSpace(*r.Email))
 	}
 	if r.FirstName != nil {
 		updates["first_name"] = strings.TrimSpace(*r.FirstName)
@@ -181,13 +515,64 @@ func (r *UpdateUserRequest) ToMap() map[string]interface{} {
 	if r.Website != nil {
 		updates["website"] = strings.TrimSpace(*r.Website)
 	}
+	if r.DateOfBirth != nil {
+		if strings.TrimSpace(*r.DateOfBirth) == "" {
+			updates["date_of_birth"] = nil
+		} else if dob, err := ParseDateOfBirth(*r.DateOfBirth, utils.Now()); err == nil {
+			updates["date_of_birth"] = dob
+		}
+	}
 	
 	return updates
 }
 
+// ToUpdateRequest converts the replacement into an update that sets every profile field
+func (r *ReplaceUserRequest) ToUpdateRequest() *UpdateUserRequest {
+	return &UpdateUserRequest{
+		Username:    &r.Username,
+		Email:       &r.Email,
+		FirstName:   &r.FirstName,
+		LastName:    &r.LastName,
+		Bio:         &r.Bio,
+		Location:    &r.Location,
+		Website:     &r.Website,
+		DateOfBirth: &r.DateOfBirth,
+		Version:     r.Version,
+	}
+}
+
+// FieldError describes why a single request field failed validation
+type FieldError struct {
+	Field   string
+	Message string
+}
+
+// FieldErrors is returned by validators that report which field failed
+// It is an error matching interfaces.ErrValidation, with a "validation failed: ..." message.
+type FieldErrors []FieldError
+
+// Error joins the messages of every field error
+func (e FieldErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, fieldErr := range e {
+		messages[i] = fieldErr.Message
+	}
+	return "validation failed: " + strings.Join(messages, ", ")
+}
+
+// Unwrap classifies field errors as validation failures
+func (e FieldErrors) Unwrap() error {
+	return interfaces.ErrValidation
+}
+
+// add records a validation failure on field
+func (e *FieldErrors) add(field, message string) {
+	*e = append(*e, FieldError{Field: field, Message: message})
+}
+
 // Validate validates the CreateUserRequest
-func (r *CreateUserRequest) Validate() []string {
-	var errors []string
+func (r *CreateUserRequest) Validate() FieldErrors {
+	var errors FieldErrors
 	
 	// Trim spaces
 	r.Username = strings.TrimSpace(r.Username)
@@ -197,81 +582,90 @@ func (r *CreateUserRequest) Validate() []string {
 	
 	// Validate username
 	if err := ValidateUsername(r.Username); err != nil {
-		errors = append(errors, err.Error())
+		errors.add("username", err.Error())
 	}
 	
 	// Validate email
 	if err := ValidateEmail(r.Email); err != nil {
-		errors = append(errors, err.Error())
+		errors.add("email", err.Error())
 	}
 	
 	// Validate password
 	if err := ValidatePassword(r.Password); err != nil {
-		errors = append(errors, err.Error())
+		errors.add("password", err.Error())
 	}
 	
 	// Validate optional fields
 	if r.FirstName != "" && len(r.FirstName) > 50 {
-		errors = append(errors, "first name cannot exceed 50 characters")
+		errors.add("first_name", "first name cannot exceed 50 characters")
 	}
 	
 	if r.LastName != "" && len(r.LastName) > 50 {
-		errors = append(errors, "last name cannot exceed 50 characters")
+		errors.add("last_name", "last name cannot exceed 50 characters")
 	}
 	
 	return errors
 }
 
 // Validate validates the UpdateUserRequest
-func (r *UpdateUserRequest) Validate() []string {
-	var errors []string
+func (r *UpdateUserRequest) Validate() FieldErrors {
+	var errors FieldErrors
 	
 	if r.Username != nil {
 		*r.Username = strings.TrimSpace(*r.Username)
 		if err := ValidateUsername(*r.Username); err != nil {
-			errors = append(errors, err.Error())
+			errors.add("username", err.Error())
 		}
 	}
 	
 	if r.Email != nil {
 		*r.Email = strings.TrimSpace(*r.Email)
 		if err := ValidateEmail(*r.Email); err != nil {
-			errors = append(errors, err.Error())
+			errors.add("email", err.Error())
 		}
 	}
 	
 	if r.FirstName != nil {
 		*r.FirstName = strings.TrimSpace(*r.FirstName)
 		if len(*r.FirstName) > 50 {
-			errors = append(errors, "first name cannot exceed 50 characters")
+			errors.add("first_name", "first name cannot exceed 50 characters")
 		}
 	}
 	
 	if r.LastName != nil {
 		*r.LastName = strings.TrimSpace(*r.LastName)
 		if len(*r.LastName) > 50 {
-			errors = append(errors, "last name cannot exceed 50 characters")
+			errors.add("last_name", "last name cannot exceed 50 characters")
 		}
 	}
 	
 	if r.Bio != nil {
 		*r.Bio = strings.TrimSpace(*r.Bio)
 		if len(*r.Bio) > 500 {
-			errors = append(errors, "bio cannot exceed 500 characters")
+			errors.add("bio", "bio cannot exceed 500 characters")
 		}
 	}
 	
 	if r.Location != nil {
 		*r.Location = strings.TrimSpace(*r.Location)
 		if len(*r.Location) > 100 {
-			errors = append(errors, "location cannot exceed 100 characters")
+			errors.add("location", "location cannot exceed 100 characters")
 		}
 	}
 	
 	if r.Website != nil {
 		*r.Website = strings.TrimSpace(*r.Website)
 		if *r.Website != "" && !isValidURL(*r.Website) {
-			errors = append(errors, "invalid website URL format")
+			errors.add("website", "invalid website URL format")
+		}
+	}
+	
+	if r.DateOfBirth != nil {
+		*r.DateOfBirth = strings.TrimSpace(*r.DateOfBirth)
+		if *r.DateOfBirth != "" {
+			if _, err := ParseDateOfBirth(*r.DateOfBirth, utils.Now()); err != nil {
+				errors.add("date_of_birth", err.Error())
+			}
 		}
 	}
 	
@@ -318,19 +712,175 @@ func (r *LoginRequest) Validate() []string {
 	return errors
 }
 
+// Validate validates the VerifyEmailRequest
+func (r *VerifyEmailRequest) Validate() []string {
+	var errors []string
+	
+	r.Token = strings.TrimSpace(r.Token)
+	
+	if r.Token == "" {
+		errors = append(errors, "token is required")
+	}
+	
+	return errors
+}
+
+// Validate validates the ForgotPasswordRequest
+func (r *ForgotPasswordRequest) Validate() []string {
+	var errors []string
+	
+	r.Email = strings.ToLower(strings.TrimSpace(r.Email))
+	
+	if err := ValidateEmail(r.Email); err != nil {
+		errors = append(errors, err.Error())
+	}
+	
+	return errors
+}
+
+// Validate validates the ResetPasswordRequest
+func (r *ResetPasswordRequest) Validate() []string {
+	var errors []string
+	
+	r.Token = strings.TrimSpace(r.Token)
+	
+	if r.Token == "" {
+		errors = append(errors, "token is required")
+	}
+	
+	if err := ValidatePassword(r.NewPassword); err != nil {
+		errors = append(errors, err.Error())
+	}
+	
+	if r.NewPassword != r.ConfirmPassword {
+		errors = append(errors, "new password and confirm password do not match")
+	}
+	
+	return errors
+}
+
+// Validate validates the SetRolesRequest and removes duplicate roles
+func (r *SetRolesRequest) Validate() []string {
+	var errors []string
+	
+	if len(r.Roles) == 0 {
+		errors = append(errors, "at least one role is required")
+		return errors
+	}
+	
+	seen := make(map[string]bool, len(r.Roles))
+	roles := make([]string, 0, len(r.Roles))
+	for _, role := range r.Roles {
+		role = strings.ToLower(strings.TrimSpace(role))
+		if !IsValidRole(role) {
+			errors = append(errors, fmt.Sprintf("invalid role: %q", role))
+			continue
+		}
+		if !seen[role] {
+			seen[role] = true
+			roles = append(roles, role)
+		}
+	}
+	r.Roles = roles
+	
+	return errors
+}
+
+// Validate validates the BatchGetUsersRequest and removes duplicate IDs
+func (r *BatchGetUsersRequest) Validate() []string {
+	var errors []string
+	
+	if len(r.IDs) == 0 {
+		errors = append(errors, "at least one ID is required")
+		return errors
+	}
+	if len(r.IDs) > MaxBatchGetSize {
+		errors = append(errors, fmt.Sprintf("cannot request more than %d IDs", MaxBatchGetSize))
+		return errors
+	}
+	
+	seen := make(map[string]bool, len(r.IDs))
+	ids := make([]string, 0, len(r.IDs))
+	for i, id := range r.IDs {
+		id = strings.TrimSpace(id)
+		if !IsValidObjectID(id) {
+			errors = append(errors, fmt.Sprintf("ids[%d] is not a valid user ID: %q", i, id))
+			continue
+		}
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	r.IDs = ids
+	
+	return errors
+}
+
+// Validate validates the BulkDeleteRequest, trimming and de-duplicating the IDs
+func (r *BulkDeleteRequest) Validate() []string {
+	var errors []string
+	
+	if len(r.IDs) == 0 {
+		errors = append(errors, "at least one ID is required")
+		return errors
+	}
+	if len(r.IDs) > MaxBulkDeleteSize {
+		errors = append(errors, fmt.Sprintf("cannot delete more than %d users at once", MaxBulkDeleteSize))
+		return errors
+	}
+	
+	seen := make(map[string]bool, len(r.IDs))
+	ids := make([]string, 0, len(r.IDs))
+	for i, id := range r.IDs {
+		id = strings.TrimSpace(id)
+		if !IsValidObjectID(id) {
+			errors = append(errors, fmt.Sprintf("ids[%d] is not a valid user ID: %q", i, id))
+			continue
+		}
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	r.IDs = ids
+	
+	return errors
+}
+
+// Validate checks the pagination parameters; a limit of 0 means the default
+func (q *UsersQueryParams) Validate() []string {
+	var errors []string
+	
+	if q.Page < 0 {
+		errors = append(errors, "page must be positive")
+	}
+	if q.Limit < 0 || q.Limit > maxPageLimit {
+		errors = append(errors, fmt.Sprintf("limit must be between 1 and %d", maxPageLimit))
+	}
+	
+	return errors
+}
+
 // Default values for query parameters
+// A limit above the maximum is lowered to it; callers reject such limits with Validate first.
 func (q *UsersQueryParams) SetDefaults() {
 	if q.Page < 1 {
 		q.Page = 1
 	}
-	if q.Limit < 1 || q.Limit > 100 {
-		q.Limit = 20
+	if q.Limit < 1 {
+		q.Limit = DefaultPageLimit
+	} else if q.Limit > maxPageLimit {
+		q.Limit = maxPageLimit
 	}
 	if q.SortBy == "" {
-		q.SortBy = "created_at"
+		q.SortBy = "-created_at"
 	}
 	if q.SortDir == "" {
-		q.SortDir = "desc"
+		q.SortDir = "asc"
+	}
+	if len(q.Sort) == 0 {
+		q.Sort = ParseSortFields(q.SortBy, q.SortDir)
 	}
 }
 
@@ -0,0 +1,318 @@
+// internal/models/dto_test.go
+package models
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"go-template/internal/interfaces"
+)
+
+func TestSetRolesRequestValidate(t *testing.T) {
+	tests := []struct {
+		name      string
+		roles     []string
+		wantErrs  int
+		wantRoles []string
+	}{
+		{name: "valid roles", roles: []string{"user", "admin"}, wantRoles: []string{"user", "admin"}},
+		{name: "normalized and deduplicated", roles: []string{" Admin ", "admin", "MODERATOR"}, wantRoles: []string{"admin", "moderator"}},
+		{name: "unknown role", roles: []string{"user", "superuser"}, wantErrs: 1},
+		{name: "empty", roles: []string{}, wantErrs: 1},
+		{name: "missing", wantErrs: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := SetRolesRequest{Roles: tt.roles}
+			errs := req.Validate()
+			if len(errs) != tt.wantErrs {
+				t.Fatalf("Validate() = %v, want %d errors", errs, tt.wantErrs)
+			}
+			if tt.wantErrs == 0 && !slices.Equal(req.Roles, tt.wantRoles) {
+				t.Errorf("Roles = %v, want %v", req.Roles, tt.wantRoles)
+			}
+		})
+	}
+}
+
+func TestParseUserFields(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		want    []string
+		wantErr bool
+	}{
+		{name: "id is always included", raw: "username", want: []string{"id", "username"}},
+		{name: "explicit id is not repeated", raw: "id,email", want: []string{"id", "email"}},
+		{name: "trimmed, lowercased and deduplicated", raw: " Email , email,,username", want: []string{"id", "email", "username"}},
+		{name: "empty list", raw: ",", want: []string{"id"}},
+		{name: "password", raw: "username,password", wantErr: true},
+		{name: "salt", raw: "salt", wantErr: true},
+		{name: "unknown", raw: "nickname", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseUserFields(tt.raw)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseUserFields(%q) error = %v, wantErr %v", tt.raw, err, tt.wantErr)
+			}
+			if !tt.wantErr && !slices.Equal(got, tt.want) {
+				t.Errorf("ParseUserFields(%q) = %v, want %v", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUserSelectableFieldsExcludeSecrets(t *testing.T) {
+	for field, sources := range UserSelectableFields {
+		for _, source := range sources {
+			if source == "password" || source == "salt" {
+				t.Errorf("selectable field %q loads %q", field, source)
+			}
+		}
+	}
+}
+
+// fieldNames lists the fields of errs in order
+func fieldNames(errs FieldErrors) []string {
+	fields := make([]string, len(errs))
+	for i, fieldErr := range errs {
+		fields[i] = fieldErr.Field
+	}
+	return fields
+}
+
+func TestCreateUserRequestValidateFields(t *testing.T) {
+	valid := CreateUserRequest{Username: "alice", Email: "alice@example.com", Password: "SecurePass123"}
+
+	tests := []struct {
+		name   string
+		modify func(r *CreateUserRequest)
+		want   []string
+	}{
+		{name: "valid", modify: func(r *CreateUserRequest) {}},
+		{name: "bad email", modify: func(r *CreateUserRequest) { r.Email = "alice" }, want: []string{"email"}},
+		{name: "short password", modify: func(r *CreateUserRequest) { r.Password = "Sh0rt" }, want: []string{"password"}},
+		{name: "bad email and short password", modify: func(r *CreateUserRequest) { r.Email = "alice"; r.Password = "Sh0rt" }, want: []string{"email", "password"}},
+		{name: "short username", modify: func(r *CreateUserRequest) { r.Username = "a" }, want: []string{"username"}},
+		{name: "long names", modify: func(r *CreateUserRequest) {
+			r.FirstName = strings.Repeat("a", 51)
+			r.LastName = strings.Repeat("b", 51)
+		}, want: []string{"first_name", "last_name"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := valid
+			tt.modify(&req)
+			if got := fieldNames(req.Validate()); !slices.Equal(got, tt.want) {
+				t.Errorf("Validate() fields = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpdateUserRequestValidateFields(t *testing.T) {
+	str := func(s string) *string { return &s }
+
+	tests := []struct {
+		name string
+		req  UpdateUserRequest
+		want []string
+	}{
+		{name: "empty", req: UpdateUserRequest{}},
+		{name: "valid", req: UpdateUserRequest{Username: str("bob"), Email: str("bob@example.com"), Website: str("https://example.com")}},
+		{name: "cleared website", req: UpdateUserRequest{Website: str("")}},
+		{name: "bad username and email", req: UpdateUserRequest{Username: str("a"), Email: str("bob")}, want: []string{"username", "email"}},
+		{name: "long bio", req: UpdateUserRequest{Bio: str(strings.Repeat("x", 501))}, want: []string{"bio"}},
+		{name: "long location", req: UpdateUserRequest{Location: str(strings.Repeat("x", 101))}, want: []string{"location"}},
+		{name: "bad website", req: UpdateUserRequest{Website: str("not a url")}, want: []string{"website"}},
+		{name: "valid date of birth", req: UpdateUserRequest{DateOfBirth: str("1990-05-17")}},
+		{name: "cleared date of birth", req: UpdateUserRequest{DateOfBirth: str(" ")}},
+		{name: "future date of birth", req: UpdateUserRequest{DateOfBirth: str("2999-01-01")}, want: []string{"date_of_birth"}},
+		{name: "malformed date of birth", req: UpdateUserRequest{DateOfBirth: str("17/05/1990")}, want: []string{"date_of_birth"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := fieldNames(tt.req.Validate()); !slices.Equal(got, tt.want) {
+				t.Errorf("Validate() fields = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReplaceUserRequestToUpdateRequest(t *testing.T) {
+	version := int64(3)
+
+	tests := []struct {
+		name        string
+		req         ReplaceUserRequest
+		want        map[string]interface{}
+		wantVersion *int64
+	}{
+		{
+			name: "omitted fields are cleared",
+			req:  ReplaceUserRequest{Username: "JaneDoe", Email: "Jane@Example.com"},
+			want: map[string]interface{}{
+				"username": "janedoe", "email": "jane@example.com",
+				"first_name": "", "last_name": "", "bio": "", "location": "", "website": "",
+				"date_of_birth": nil,
+			},
+		},
+		{
+			name: "every field is written",
+			req: ReplaceUserRequest{
+				Username: "janedoe", Email: "jane@example.com", FirstName: " Jane ", LastName: "Doe",
+				Bio: "Coffee", Location: "Lisbon", Website: "https://jane.dev", DateOfBirth: "1990-05-17",
+				Version: &version,
+			},
+			want: map[string]interface{}{
+				"username": "janedoe", "email": "jane@example.com",
+				"first_name": "Jane", "last_name": "Doe", "bio": "Coffee", "location": "Lisbon", "website": "https://jane.dev",
+				"date_of_birth": time.Date(1990, 5, 17, 0, 0, 0, 0, time.UTC),
+			},
+			wantVersion: &version,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			update := tt.req.ToUpdateRequest()
+			if got := update.ToMap(); !maps.Equal(got, tt.want) {
+				t.Errorf("ToMap() = %v, want %v", got, tt.want)
+			}
+			if update.Version != tt.wantVersion {
+				t.Errorf("Version = %v, want %v", update.Version, tt.wantVersion)
+			}
+			// The replacement validates like a partial update that sends every field
+			if errs := update.Validate(); errs != nil {
+				t.Errorf("Validate() = %v, want no errors", errs)
+			}
+		})
+	}
+}
+
+func TestFieldErrorsError(t *testing.T) {
+	errs := FieldErrors{
+		{Field: "email", Message: "invalid email format"},
+		{Field: "password", Message: "password must be at least 8 characters long"},
+	}
+	want := "validation failed: invalid email format, password must be at least 8 characters long"
+	if got := errs.Error(); got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+}
+
+func TestFieldErrorsMatchValidation(t *testing.T) {
+	var err error = FieldErrors{{Field: "email", Message: "invalid email format"}}
+	if !errors.Is(err, interfaces.ErrValidation) {
+		t.Errorf("errors.Is(%v, ErrValidation) = false, want true", err)
+	}
+	if wrapped := fmt.Errorf("failed to create user: %w", err); !errors.Is(wrapped, interfaces.ErrValidation) {
+		t.Errorf("errors.Is(%v, ErrValidation) = false, want true", wrapped)
+	}
+	var fieldErrs FieldErrors
+	if !errors.As(fmt.Errorf("failed to create user: %w", err), &fieldErrs) || fieldErrs[0].Field != "email" {
+		t.Errorf("errors.As() = %v, want the field errors back", fieldErrs)
+	}
+}
+
+func TestBulkDeleteRequestValidate(t *testing.T) {
+	a, b := "507f1f77bcf86cd799439011", "507f1f77bcf86cd799439012"
+	tooMany := make([]string, MaxBulkDeleteSize+1)
+	for i := range tooMany {
+		tooMany[i] = a
+	}
+
+	tests := []struct {
+		name     string
+		ids      []string
+		wantErrs int
+		wantIDs  []string
+	}{
+		{name: "valid", ids: []string{a, b}, wantIDs: []string{a, b}},
+		{name: "trimmed and deduplicated", ids: []string{" " + a, a, b}, wantIDs: []string{a, b}},
+		{name: "invalid ids are each reported", ids: []string{a, "nope", ""}, wantErrs: 2},
+		{name: "empty", ids: []string{}, wantErrs: 1},
+		{name: "too many", ids: tooMany, wantErrs: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := BulkDeleteRequest{IDs: tt.ids}
+			errs := req.Validate()
+			if len(errs) != tt.wantErrs {
+				t.Fatalf("Validate() = %v, want %d errors", errs, tt.wantErrs)
+			}
+			if tt.wantErrs == 0 && !slices.Equal(req.IDs, tt.wantIDs) {
+				t.Errorf("IDs = %v, want %v", req.IDs, tt.wantIDs)
+			}
+		})
+	}
+}
+
+func TestUsersQueryParamsLimit(t *testing.T) {
+	tests := []struct {
+		name        string
+		maxLimit    int
+		limit       int
+		wantErrs    int
+		wantDefault int
+	}{
+		{name: "omitted", limit: 0, wantDefault: DefaultPageLimit},
+		{name: "at the maximum", limit: DefaultMaxPageLimit, wantDefault: DefaultMaxPageLimit},
+		{name: "above the maximum", limit: DefaultMaxPageLimit + 1, wantErrs: 1, wantDefault: DefaultMaxPageLimit},
+		{name: "negative", limit: -1, wantErrs: 1, wantDefault: DefaultPageLimit},
+		{name: "configured maximum", maxLimit: 30, limit: 30, wantDefault: 30},
+		{name: "above the configured maximum", maxLimit: 30, limit: 31, wantErrs: 1, wantDefault: 30},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.maxLimit != 0 {
+				SetMaxPageLimit(tt.maxLimit)
+				t.Cleanup(func() { SetMaxPageLimit(DefaultMaxPageLimit) })
+			}
+			params := UsersQueryParams{Limit: tt.limit}
+			if errs := params.Validate(); len(errs) != tt.wantErrs {
+				t.Errorf("Validate() = %v, want %d errors", errs, tt.wantErrs)
+			}
+			// SetDefaults alone never leaves a limit above the maximum
+			params.SetDefaults()
+			if params.Limit != tt.wantDefault {
+				t.Errorf("SetDefaults() limit = %d, want %d", params.Limit, tt.wantDefault)
+			}
+		})
+	}
+}
+
+func TestUserCountParamsHasFilters(t *testing.T) {
+	active := false
+
+	tests := []struct {
+		name   string
+		params *UserCountParams
+		want   bool
+	}{
+		{name: "nil", want: false},
+		{name: "empty", params: &UserCountParams{}, want: false},
+		{name: "status", params: &UserCountParams{IsActive: &active}, want: true},
+		{name: "role", params: &UserCountParams{Role: RoleUser}, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.params.HasFilters(); got != tt.want {
+				t.Errorf("HasFilters() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+// internal/models/factory.go
+package models
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+
+	"go-template/internal/shared/utils"
+)
+
+// TestUserPassword is the plain-text password of every user built by NewTestUser
+const TestUserPassword = "Password123"
+
+var (
+	testPasswordHashOnce sync.Once
+	testPasswordHash     string
+)
+
+// TestUserOption customizes a user built by NewTestUser
+type TestUserOption func(*User)
+
+// WithUsername sets the username; the email is derived from it unless set explicitly
+func WithUsername(username string) TestUserOption {
+	return func(u *User) {
+		u.Username = username
+		u.Email = username + "@example.com"
+	}
+}
+
+// WithEmail sets the email address
+func WithEmail(email string) TestUserOption {
+	return func(u *User) {
+		u.Email = email
+	}
+}
+
+// WithName sets the first and last name
+func WithName(firstName, lastName string) TestUserOption {
+	return func(u *User) {
+		u.FirstName = firstName
+		u.LastName = lastName
+	}
+}
+
+// WithRoles replaces the default user role
+func WithRoles(roles ...string) TestUserOption {
+	return func(u *User) {
+		u.Roles = roles
+	}
+}
+
+// WithActive sets whether the account is active
+func WithActive(active bool) TestUserOption {
+	return func(u *User) {
+		u.IsActive = active
+	}
+}
+
+// WithVerified marks the account as verified or not
+func WithVerified(verified bool) TestUserOption {
+	return func(u *User) {
+		if verified {
+			u.VerifyEmail()
+			return
+		}
+		u.IsVerified = false
+		u.EmailVerifiedAt = nil
+	}
+}
+
+// NewTestUser builds a valid, active user with a random username for tests and local data.
+// Unlike NewUser it skips validation and reuses one password hash for TestUserPassword,
+// so building many users stays fast. It panics if the password cannot be hashed.
+func NewTestUser(opts ...TestUserOption) *User {
+	testPasswordHashOnce.Do(func() {
+		hash, err := utils.HashPassword(TestUserPassword)
+		if err != nil {
+			panic("models: failed to hash test user password: " + err.Error())
+		}
+		testPasswordHash = hash
+	})
+
+	username := "user_" + randomHex(8)
+	user := &User{
+		BaseModel:   *NewBaseModel(),
+		Username:    username,
+		Email:       username + "@example.com",
+		Password:    testPasswordHash,
+		IsActive:    true,
+		Roles:       []string{RoleUser},
+		Preferences: make(map[string]interface{}),
+	}
+
+	for _, opt := range opts {
+		opt(user)
+	}
+	return user
+}
+
+// randomHex returns n random bytes encoded as hex
+func randomHex(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic("models: failed to read random bytes: " + err.Error())
+	}
+	return hex.EncodeToString(b)
+}
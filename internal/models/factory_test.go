@@ -0,0 +1,92 @@
+// internal/models/factory_test.go
+package models
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestNewTestUser(t *testing.T) {
+	tests := []struct {
+		name  string
+		opts  []TestUserOption
+		check func(t *testing.T, u *User)
+	}{
+		{
+			name: "defaults",
+			check: func(t *testing.T, u *User) {
+				if !u.IsActive || u.IsVerified || !slices.Equal(u.Roles, []string{RoleUser}) {
+					t.Errorf("active = %v, verified = %v, roles = %v, want an active unverified user", u.IsActive, u.IsVerified, u.Roles)
+				}
+				if err := ValidateUsername(u.Username); err != nil {
+					t.Errorf("username %q is invalid: %v", u.Username, err)
+				}
+				if err := ValidateEmail(u.Email); err != nil {
+					t.Errorf("email %q is invalid: %v", u.Email, err)
+				}
+			},
+		},
+		{
+			name: "username derives the email",
+			opts: []TestUserOption{WithUsername("alice")},
+			check: func(t *testing.T, u *User) {
+				if u.Username != "alice" || u.Email != "alice@example.com" {
+					t.Errorf("username = %q, email = %q", u.Username, u.Email)
+				}
+			},
+		},
+		{
+			name: "explicit email wins when applied later",
+			opts: []TestUserOption{WithUsername("alice"), WithEmail("a@example.org")},
+			check: func(t *testing.T, u *User) {
+				if u.Email != "a@example.org" {
+					t.Errorf("email = %q, want a@example.org", u.Email)
+				}
+			},
+		},
+		{
+			name: "verified",
+			opts: []TestUserOption{WithVerified(true)},
+			check: func(t *testing.T, u *User) {
+				if !u.IsVerified || u.EmailVerifiedAt == nil {
+					t.Errorf("verified = %v, verified at = %v, want both set", u.IsVerified, u.EmailVerifiedAt)
+				}
+			},
+		},
+		{
+			name: "verification can be undone",
+			opts: []TestUserOption{WithVerified(true), WithVerified(false)},
+			check: func(t *testing.T, u *User) {
+				if u.IsVerified || u.EmailVerifiedAt != nil {
+					t.Errorf("verified = %v, verified at = %v, want neither", u.IsVerified, u.EmailVerifiedAt)
+				}
+			},
+		},
+		{
+			name: "inactive admin",
+			opts: []TestUserOption{WithActive(false), WithRoles(RoleUser, RoleAdmin), WithName("Ada", "Lovelace")},
+			check: func(t *testing.T, u *User) {
+				if u.IsActive || !u.IsAdmin() || u.FirstName != "Ada" || u.LastName != "Lovelace" {
+					t.Errorf("user = %+v, want inactive admin Ada Lovelace", u)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := NewTestUser(tt.opts...)
+			if !u.CheckPassword(TestUserPassword) {
+				t.Error("user does not accept TestUserPassword")
+			}
+			tt.check(t, u)
+		})
+	}
+}
+
+func TestNewTestUserIsUnique(t *testing.T) {
+	a, b := NewTestUser(), NewTestUser()
+	if a.Username == b.Username || a.ID == b.ID {
+		t.Errorf("two users share a username or ID: %s/%s, %s/%s", a.Username, a.ID.Hex(), b.Username, b.ID.Hex())
+	}
+}
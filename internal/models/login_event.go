@@ -0,0 +1,56 @@
+// internal/models/login_event.go
+package models
+
+import (
+	"time"
+
+	"go-template/internal/shared/utils"
+)
+
+// MaxLoginEvents is the number of recent login attempts kept per user
+const MaxLoginEvents = 20
+
+// maxUserAgentLength bounds the user agent stored with a login attempt
+const maxUserAgentLength = 512
+
+// Reasons a login attempt on an existing account failed
+const (
+	LoginFailureInvalidPassword = "invalid_password"
+	LoginFailureAccountLocked   = "account_locked"
+	LoginFailureAccountInactive = "account_inactive"
+)
+
+// LoginClient identifies where a login attempt came from
+type LoginClient struct {
+	IP        string
+	UserAgent string
+}
+
+// LoginEvent records one login attempt on an account
+type LoginEvent struct {
+	Timestamp time.Time `json:"timestamp" bson:"timestamp" example:"2024-01-15T10:30:00Z"`
+	IP        string    `json:"ip" bson:"ip" example:"203.0.113.7"`
+	UserAgent string    `json:"user_agent" bson:"user_agent" example:"Mozilla/5.0"`
+	Success   bool      `json:"success" bson:"success" example:"false"`
+	Reason    string    `json:"reason,omitempty" bson:"reason,omitempty" example:"invalid_password"` // Why a failed attempt failed
+}
+
+// NewLoginEvent creates a login event for an attempt from client, made now
+// reason is ignored for successful attempts.
+func NewLoginEvent(client LoginClient, success bool, reason string) LoginEvent {
+	userAgent := client.UserAgent
+	if len(userAgent) > maxUserAgentLength {
+		userAgent = userAgent[:maxUserAgentLength]
+	}
+	if success {
+		reason = ""
+	}
+
+	return LoginEvent{
+		Timestamp: utils.Now(),
+		IP:        client.IP,
+		UserAgent: userAgent,
+		Success:   success,
+		Reason:    reason,
+	}
+}
@@ -0,0 +1,73 @@
+// internal/models/login_event_test.go
+package models
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"go-template/internal/shared/utils"
+)
+
+func TestNewLoginEvent(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	utils.SetClock(utils.NewFixedClock(now))
+	t.Cleanup(func() { utils.SetClock(nil) })
+
+	tests := []struct {
+		name          string
+		client        LoginClient
+		success       bool
+		reason        string
+		wantUserAgent string
+		wantReason    string
+	}{
+		{
+			name:          "failed attempt keeps its reason",
+			client:        LoginClient{IP: "203.0.113.7", UserAgent: "Mozilla/5.0"},
+			reason:        LoginFailureInvalidPassword,
+			wantUserAgent: "Mozilla/5.0",
+			wantReason:    LoginFailureInvalidPassword,
+		},
+		{
+			name:          "successful attempt drops the reason",
+			client:        LoginClient{IP: "203.0.113.7", UserAgent: "Mozilla/5.0"},
+			success:       true,
+			reason:        LoginFailureInvalidPassword,
+			wantUserAgent: "Mozilla/5.0",
+		},
+		{
+			name:          "user agent at the limit",
+			client:        LoginClient{IP: "203.0.113.7", UserAgent: strings.Repeat("a", maxUserAgentLength)},
+			success:       true,
+			wantUserAgent: strings.Repeat("a", maxUserAgentLength),
+		},
+		{
+			name:          "long user agent is truncated",
+			client:        LoginClient{IP: "203.0.113.7", UserAgent: strings.Repeat("a", maxUserAgentLength+100)},
+			success:       true,
+			wantUserAgent: strings.Repeat("a", maxUserAgentLength),
+		},
+		{
+			name:       "missing client details",
+			reason:     LoginFailureAccountLocked,
+			wantReason: LoginFailureAccountLocked,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event := NewLoginEvent(tt.client, tt.success, tt.reason)
+			want := LoginEvent{
+				Timestamp: now,
+				IP:        tt.client.IP,
+				UserAgent: tt.wantUserAgent,
+				Success:   tt.success,
+				Reason:    tt.wantReason,
+			}
+			if event != want {
+				t.Errorf("NewLoginEvent() = %+v, want %+v", event, want)
+			}
+		})
+	}
+}
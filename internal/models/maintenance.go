@@ -0,0 +1,13 @@
+// internal/models/maintenance.go
+package models
+
+// MaintenanceRequest represents the request payload for switching maintenance mode
+type MaintenanceRequest struct {
+	Enabled *bool `json:"enabled" example:"true"`
+}
+
+// MaintenanceStatus reports whether maintenance mode is on
+type MaintenanceStatus struct {
+	Enabled    bool `json:"enabled" example:"true"`
+	AllowReads bool `json:"allow_reads" example:"true"` // Reads are still served while enabled
+}
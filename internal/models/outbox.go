@@ -0,0 +1,42 @@
+// internal/models/outbox.go
+package models
+
+import "time"
+
+// Outbox event statuses
+const (
+	OutboxStatusPending = "pending" // waiting for (re)delivery
+	OutboxStatusSent    = "sent"    // delivered to every subscriber
+	OutboxStatusDead    = "dead"    // abandoned after a permanent failure or too many attempts
+)
+
+// OutboxEvent is a domain event persisted for reliable, at-least-once delivery
+// The payload is stored as JSON so it is delivered exactly as it was published.
+type OutboxEvent struct {
+	BaseModel `bson:",inline"`
+
+	EventID    string    `json:"event_id" bson:"event_id"`
+	Type       string    `json:"type" bson:"type"`
+	Payload    string    `json:"payload" bson:"payload"`
+	OccurredAt time.Time `json:"occurred_at" bson:"occurred_at"`
+
+	// Delivery state
+	Status        string     `json:"status" bson:"status"`
+	Attempts      int        `json:"attempts" bson:"attempts"`
+	NextAttemptAt time.Time  `json:"next_attempt_at" bson:"next_attempt_at"`
+	LastError     string     `json:"last_error,omitempty" bson:"last_error,omitempty"`
+	SentAt        *time.Time `json:"sent_at,omitempty" bson:"sent_at,omitempty"`
+}
+
+// NewOutboxEvent creates a pending outbox event that is due for delivery right away
+func NewOutboxEvent(eventID, eventType, payload string, occurredAt time.Time) *OutboxEvent {
+	return &OutboxEvent{
+		BaseModel:     *NewBaseModel(),
+		EventID:       eventID,
+		Type:          eventType,
+		Payload:       payload,
+		OccurredAt:    occurredAt,
+		Status:        OutboxStatusPending,
+		NextAttemptAt: occurredAt,
+	}
+}
@@ -0,0 +1,120 @@
+// internal/models/preferences.go
+package models
+
+// PreferenceKeyNotifications is the key of the notification settings inside User.Preferences
+// Other preference groups live next to it, so updates must never replace the whole map.
+const PreferenceKeyNotifications = "notifications"
+
+// NotificationPreferences controls which notifications a user receives
+type NotificationPreferences struct {
+	Email     bool `json:"email" bson:"email" example:"true"`
+	Push      bool `json:"push" bson:"push" example:"true"`
+	Marketing bool `json:"marketing" bson:"marketing" example:"false"`
+}
+
+// DefaultNotificationPreferences returns the settings of a user who never changed them
+// Marketing messages are opt-in.
+func DefaultNotificationPreferences() NotificationPreferences {
+	return NotificationPreferences{
+		Email:     true,
+		Push:      true,
+		Marketing: false,
+	}
+}
+
+// GetNotificationPreferences returns the user's notification settings
+// Settings that were never stored take their default value.
+func (u *User) GetNotificationPreferences() NotificationPreferences {
+	prefs := DefaultNotificationPreferences()
+
+	stored, ok := u.Preferences[PreferenceKeyNotifications].(map[string]interface{})
+	if !ok {
+		return prefs
+	}
+
+	if email, ok := stored["email"].(bool); ok {
+		prefs.Email = email
+	}
+	if push, ok := stored["push"].(bool); ok {
+		prefs.Push = push
+	}
+	if marketing, ok := stored["marketing"].(bool); ok {
+		prefs.Marketing = marketing
+	}
+	return prefs
+}
+
+// PreferencesResponse represents the response payload for a user's preferences
+type PreferencesResponse struct {
+	Notifications NotificationPreferences `json:"notifications"`
+}
+
+// ToPreferencesResponse converts the user's preferences to the response DTO
+func (u *User) ToPreferencesResponse() PreferencesResponse {
+	return PreferencesResponse{
+		Notifications: u.GetNotificationPreferences(),
+	}
+}
+
+// UpdateNotificationPreferencesRequest holds the notification settings to change
+// Omitted settings keep their current value.
+type UpdateNotificationPreferencesRequest struct {
+	Email     *bool `json:"email,omitempty" example:"false"`
+	Push      *bool `json:"push,omitempty" example:"true"`
+	Marketing *bool `json:"marketing,omitempty" example:"true"`
+}
+
+// UpdatePreferencesRequest represents the request payload for updating a user's preferences
+// Unknown keys are rejected when the body is decoded.
+type UpdatePreferencesRequest struct {
+	Notifications *UpdateNotificationPreferencesRequest `json:"notifications,omitempty"`
+}
+
+// Validate validates the UpdatePreferencesRequest
+func (r *UpdatePreferencesRequest) Validate() FieldErrors {
+	var errors FieldErrors
+
+	if len(r.notificationUpdates()) == 0 {
+		errors.add("notifications", "at least one preference must be provided")
+	}
+
+	return errors
+}
+
+// ToUpdates converts the request to repository updates for a user whose preferences are current
+// Only the provided settings are set, leaving every other preference untouched.
+func (r *UpdatePreferencesRequest) ToUpdates(current map[string]interface{}) map[string]interface{} {
+	changes := r.notificationUpdates()
+
+	// A missing preferences document cannot be updated with dotted paths, so create it whole
+	if current == nil {
+		return map[string]interface{}{
+			"preferences": map[string]interface{}{PreferenceKeyNotifications: changes},
+		}
+	}
+
+	updates := make(map[string]interface{}, len(changes))
+	for key, value := range changes {
+		updates["preferences."+PreferenceKeyNotifications+"."+key] = value
+	}
+	return updates
+}
+
+// notificationUpdates returns the notification settings present in the request
+func (r *UpdatePreferencesRequest) notificationUpdates() map[string]interface{} {
+	changes := make(map[string]interface{})
+	if r.Notifications == nil {
+		return changes
+	}
+
+	if r.Notifications.Email != nil {
+		changes["email"] = *r.Notifications.Email
+	}
+	if r.Notifications.Push != nil {
+		changes["push"] = *r.Notifications.Push
+	}
+	if r.Notifications.Marketing != nil {
+		changes["marketing"] = *r.Notifications.Marketing
+	}
+	return changes
+}
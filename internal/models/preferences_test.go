@@ -0,0 +1,106 @@
+// internal/models/preferences_test.go
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGetNotificationPreferences(t *testing.T) {
+	tests := []struct {
+		name        string
+		preferences map[string]interface{}
+		want        NotificationPreferences
+	}{
+		{name: "no preferences", want: DefaultNotificationPreferences()},
+		{name: "only unrelated groups", preferences: map[string]interface{}{"theme": "dark"}, want: DefaultNotificationPreferences()},
+		{
+			name:        "partially stored",
+			preferences: map[string]interface{}{PreferenceKeyNotifications: map[string]interface{}{"marketing": true}},
+			want:        NotificationPreferences{Email: true, Push: true, Marketing: true},
+		},
+		{
+			name:        "fully stored",
+			preferences: map[string]interface{}{PreferenceKeyNotifications: map[string]interface{}{"email": false, "push": false, "marketing": true}},
+			want:        NotificationPreferences{Email: false, Push: false, Marketing: true},
+		},
+		{
+			name:        "values of the wrong type take the default",
+			preferences: map[string]interface{}{PreferenceKeyNotifications: map[string]interface{}{"email": "no", "push": 0}},
+			want:        DefaultNotificationPreferences(),
+		},
+		{
+			name:        "group of the wrong type",
+			preferences: map[string]interface{}{PreferenceKeyNotifications: "off"},
+			want:        DefaultNotificationPreferences(),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user := &User{Preferences: tt.preferences}
+			if got := user.GetNotificationPreferences(); got != tt.want {
+				t.Errorf("GetNotificationPreferences() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpdatePreferencesRequestValidate(t *testing.T) {
+	yes := true
+	tests := []struct {
+		name    string
+		req     UpdatePreferencesRequest
+		wantErr bool
+	}{
+		{name: "one setting", req: UpdatePreferencesRequest{Notifications: &UpdateNotificationPreferencesRequest{Push: &yes}}},
+		{name: "no notifications group", req: UpdatePreferencesRequest{}, wantErr: true},
+		{name: "empty notifications group", req: UpdatePreferencesRequest{Notifications: &UpdateNotificationPreferencesRequest{}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := tt.req.Validate()
+			if got := len(errs) > 0; got != tt.wantErr {
+				t.Fatalf("Validate() = %v, want error %v", errs, tt.wantErr)
+			}
+			if tt.wantErr && errs[0].Field != "notifications" {
+				t.Errorf("field = %q, want notifications", errs[0].Field)
+			}
+		})
+	}
+}
+
+func TestUpdatePreferencesRequestToUpdates(t *testing.T) {
+	yes, no := true, false
+	tests := []struct {
+		name    string
+		req     UpdatePreferencesRequest
+		current map[string]interface{}
+		want    map[string]interface{}
+	}{
+		{
+			name:    "no stored preferences creates the document",
+			req:     UpdatePreferencesRequest{Notifications: &UpdateNotificationPreferencesRequest{Email: &no}},
+			current: nil,
+			want:    map[string]interface{}{"preferences": map[string]interface{}{"notifications": map[string]interface{}{"email": false}}},
+		},
+		{
+			name:    "stored preferences are updated by path",
+			req:     UpdatePreferencesRequest{Notifications: &UpdateNotificationPreferencesRequest{Push: &no, Marketing: &yes}},
+			current: map[string]interface{}{"theme": "dark"},
+			want: map[string]interface{}{
+				"preferences.notifications.push":      false,
+				"preferences.notifications.marketing": true,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.req.ToUpdates(tt.current); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ToUpdates() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
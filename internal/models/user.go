@@ -6,10 +6,15 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"errors"
+	"fmt"
 	"go-template/internal/shared/utils"
 	"regexp"
 	"strings"
 	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
 )
 
 // User represents a user in the system
@@ -24,6 +29,8 @@ type User struct {
 	
 	// Authentication
 	Password    string `json:"-" bson:"password"`
+	// Tokens carrying an older version were issued before the last logout-all or password change
+	TokenVersion int64 `json:"-" bson:"token_version"`
 	
 	// Profile Information
 	Avatar      string    `json:"avatar" bson:"avatar"`
@@ -39,10 +46,12 @@ type User struct {
 	
 	// Timestamps for specific actions
 	LastLoginAt    *time.Time `json:"last_login_at" bson:"last_login_at"`
+	LastActivityAt *time.Time `json:"last_activity_at,omitempty" bson:"last_activity_at,omitempty"`
 	EmailVerifiedAt *time.Time `json:"email_verified_at" bson:"email_verified_at"`
 	
 	// Metadata
 	LoginCount     int               `json:"login_count" bson:"login_count"`
+	ProfileViews   int               `json:"profile_views" bson:"profile_views"`
 	FailedLogins   int               `json:"-" bson:"failed_logins"`
 	LastFailedAt   *time.Time        `json:"-" bson:"last_failed_at"`
 	Preferences    map[string]interface{} `json:"preferences" bson:"preferences"`
@@ -55,6 +64,25 @@ const (
 	RoleMod   = "moderator"
 )
 
+// ValidRoles lists every role that can be assigned to a user
+var ValidRoles = []string{RoleUser, RoleAdmin, RoleMod}
+
+// IsValidRole checks if a role is one of the known user roles
+func IsValidRole(role string) bool {
+	for _, r := range ValidRoles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// Default account lockout policy
+const (
+	DefaultMaxFailedLogins = 5
+	DefaultLockoutDuration = 30 * time.Minute
+)
+
 // NewUser creates a new user with default values
 func NewUser(username, email, password string) (*User, error) {
 	// Validate input
@@ -77,7 +105,7 @@ func NewUser(username, email, password string) (*User, error) {
 	
 	user := &User{
 		BaseModel: *NewBaseModel(),
-		Username:  strings.ToLower(strings.TrimSpace(username)),
+		Username:  NormalizeUsername(username),
 		Email:     strings.ToLower(strings.TrimSpace(email)),
 		Password:  hashedPassword,
 		IsActive:  true,
@@ -100,7 +128,7 @@ func (u *User) UpdateUser(updates map[string]interface{}) error {
 		if err := ValidateUsername(username); err != nil {
 			return err
 		}
-		u.Username = strings.ToLower(strings.TrimSpace(username))
+		u.Username = NormalizeUsername(username)
 	}
 	
 	if email, ok := updates["email"].(string); ok {
@@ -141,6 +169,19 @@ func (u *User) UpdateUser(updates map[string]interface{}) error {
 		u.Website = strings.TrimSpace(website)
 	}
 	
+	if dateOfBirth, ok := updates["date_of_birth"]; ok {
+		switch dob := dateOfBirth.(type) {
+		case time.Time:
+			parsed, err := ParseDateOfBirth(dob.Format(DateOfBirthFormat), utils.Now())
+			if err != nil {
+				return err
+			}
+			u.DateOfBirth = &parsed
+		case nil:
+			u.DateOfBirth = nil
+		}
+	}
+	
 	return nil
 }
 
@@ -191,7 +232,7 @@ func (u *User) RehashPasswordIfNeeded(currentPassword string) (bool, error) {
 
 // RecordLogin updates login-related fields
 func (u *User) RecordLogin() {
-	now := time.Now().UTC()
+	now := utils.Now()
 	u.LastLoginAt = &now
 	u.LoginCount++
 	u.FailedLogins = 0 // Reset failed login attempts
@@ -200,31 +241,44 @@ func (u *User) RecordLogin() {
 
 // RecordFailedLogin increments failed login counter
 func (u *User) RecordFailedLogin() {
-	now := time.Now().UTC()
+	now := utils.Now()
 	u.FailedLogins++
 	u.LastFailedAt = &now
 	u.UpdateTimestamp()
 }
 
 // IsLocked returns true if user account is locked due to failed logins
+// using the default lockout policy
 func (u *User) IsLocked() bool {
-	const maxFailedLogins = 5
-	const lockoutDuration = 30 * time.Minute
-	
+	return u.IsLockedWith(DefaultMaxFailedLogins, DefaultLockoutDuration)
+}
+
+// IsLockedWith returns true if user account is locked under the given lockout policy
+func (u *User) IsLockedWith(maxFailedLogins int, lockoutDuration time.Duration) bool {
+	return u.LockoutRemaining(maxFailedLogins, lockoutDuration) > 0
+}
+
+// LockoutRemaining returns how long the account stays locked, or zero if it is not locked
+func (u *User) LockoutRemaining(maxFailedLogins int, lockoutDuration time.Duration) time.Duration {
 	if u.FailedLogins < maxFailedLogins {
-		return false
+		return 0
 	}
 	
 	if u.LastFailedAt == nil {
-		return false
+		return 0
+	}
+	
+	remaining := lockoutDuration - utils.Now().Sub(*u.LastFailedAt)
+	if remaining < 0 {
+		return 0
 	}
 	
-	return time.Since(*u.LastFailedAt) < lockoutDuration
+	return remaining
 }
 
 // VerifyEmail marks the user's email as verified
 func (u *User) VerifyEmail() {
-	now := time.Now().UTC()
+	now := utils.Now()
 	u.IsVerified = true
 	u.EmailVerifiedAt = &now
 	u.UpdateTimestamp()
@@ -273,20 +327,54 @@ func (u *User) IsAdmin() bool {
 	return u.HasRole(RoleAdmin)
 }
 
+// IsOnline reports whether the user was active within window of now
+// Activity exactly window ago still counts as online
+func (u *User) IsOnline(window time.Duration, now time.Time) bool {
+	if u.LastActivityAt == nil {
+		return false
+	}
+	return now.Sub(*u.LastActivityAt) <= window
+}
+
 // Validation functions
 
+// allowUnicodeUsernames lets usernames use letters and digits from any script, not only ASCII
+var allowUnicodeUsernames = false
+
+// SetAllowUnicodeUsernames sets whether usernames may contain non-ASCII letters and digits
+// Call it during startup, before requests are served.
+func SetAllowUnicodeUsernames(allow bool) {
+	allowUnicodeUsernames = allow
+}
+
+// NormalizeUsername returns the stored form of a username: trimmed, NFC-normalized and lowercased
+// NFC makes a precomposed "é" and "e" followed by a combining accent the same username.
+func NormalizeUsername(username string) string {
+	return strings.ToLower(norm.NFC.String(strings.TrimSpace(username)))
+}
+
 // ValidateUsername validates username format and length
+// Lengths count characters after NFC normalization, so accented letters count once.
 func ValidateUsername(username string) error {
-	username = strings.TrimSpace(username)
+	username = norm.NFC.String(strings.TrimSpace(username))
 	
-	if len(username) < 3 {
+	if utf8.RuneCountInString(username) < 3 {
 		return errors.New("username must be at least 3 characters long")
 	}
 	
-	if len(username) > 30 {
+	if utf8.RuneCountInString(username) > 30 {
 		return errors.New("username cannot exceed 30 characters")
 	}
 	
+	if allowUnicodeUsernames {
+		for _, r := range username {
+			if !isUnicodeUsernameRune(r) {
+				return errors.New("username can only contain letters, numbers, and underscores")
+			}
+		}
+		return nil
+	}
+	
 	// Username can only contain letters, numbers, and underscores
 	matched, _ := regexp.MatchString(`^[a-zA-Z0-9_]+$`, username)
 	if !matched {
@@ -296,6 +384,12 @@ func ValidateUsername(username string) error {
 	return nil
 }
 
+// isUnicodeUsernameRune reports whether r may appear in a username when Unicode usernames are allowed
+// Letters keep their combining marks; whitespace, control characters, punctuation and symbols are refused.
+func isUnicodeUsernameRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsMark(r) || unicode.IsDigit(r)
+}
+
 // ValidateEmail validates email format
 func ValidateEmail(email string) error {
 	email = strings.TrimSpace(email)
@@ -318,26 +412,46 @@ func ValidateEmail(email string) error {
 	return nil
 }
 
-// ValidatePassword validates password strength
-func ValidatePassword(password string) error {
-	if len(password) < 8 {
-		return errors.New("password must be at least 8 characters long")
-	}
+// Date of birth bounds; the minimum age follows COPPA
+const (
+	DateOfBirthFormat = "2006-01-02"
+	MinimumAge        = 13
+	MaximumAge        = 130
+)
+
+// ParseDateOfBirth parses a date of birth given as YYYY-MM-DD or RFC3339 and checks it against now
+// The result is the calendar date at midnight UTC. Future dates, ages under MinimumAge and
+// ages over MaximumAge are rejected.
+func ParseDateOfBirth(value string, now time.Time) (time.Time, error) {
+	value = strings.TrimSpace(value)
 	
-	if len(password) > 128 {
-		return errors.New("password cannot exceed 128 characters")
+	dob, err := time.Parse(DateOfBirthFormat, value)
+	if err != nil {
+		withTime, rfcErr := time.Parse(time.RFC3339, value)
+		if rfcErr != nil {
+			return time.Time{}, errors.New("date of birth must be a date in YYYY-MM-DD or RFC3339 format")
+		}
+		// Keep the calendar date the client meant, whatever its offset
+		dob = time.Date(withTime.Year(), withTime.Month(), withTime.Day(), 0, 0, 0, 0, time.UTC)
 	}
 	
-	// Check for at least one uppercase, one lowercase, and one digit
-	hasUpper := regexp.MustCompile(`[A-Z]`).MatchString(password)
-	hasLower := regexp.MustCompile(`[a-z]`).MatchString(password)
-	hasDigit := regexp.MustCompile(`\d`).MatchString(password)
-	
-	if !hasUpper || !hasLower || !hasDigit {
-		return errors.New("password must contain at least one uppercase letter, one lowercase letter, and one digit")
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if dob.After(today) {
+		return time.Time{}, errors.New("date of birth cannot be in the future")
+	}
+	if dob.After(today.AddDate(-MinimumAge, 0, 0)) {
+		return time.Time{}, fmt.Errorf("users must be at least %d years old", MinimumAge)
+	}
+	if dob.Before(today.AddDate(-MaximumAge, 0, 0)) {
+		return time.Time{}, errors.New("date of birth is not plausible")
 	}
 	
-	return nil
+	return dob, nil
+}
+
+// ValidatePassword validates password strength against the configured password policy
+func ValidatePassword(password string) error {
+	return utils.ValidatePassword(password)
 }
 
 // Helper functions
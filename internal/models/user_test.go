@@ -0,0 +1,332 @@
+// internal/models/user_test.go
+package models
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"go-template/internal/shared/utils"
+)
+
+func TestUserIsOnline(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	window := 5 * time.Minute
+	at := func(ago time.Duration) *time.Time {
+		t := now.Add(-ago)
+		return &t
+	}
+
+	tests := []struct {
+		name         string
+		lastActivity *time.Time
+		want         bool
+	}{
+		{name: "never active", want: false},
+		{name: "active just now", lastActivity: at(0), want: true},
+		{name: "active inside the window", lastActivity: at(window - time.Second), want: true},
+		{name: "active exactly at the window", lastActivity: at(window), want: true},
+		{name: "active just outside the window", lastActivity: at(window + time.Nanosecond), want: false},
+		{name: "active long ago", lastActivity: at(24 * time.Hour), want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user := &User{LastActivityAt: tt.lastActivity}
+			if got := user.IsOnline(window, now); got != tt.want {
+				t.Errorf("IsOnline() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestModelTimestampsUseClock(t *testing.T) {
+	now := time.Date(2026, 3, 1, 9, 30, 0, 0, time.UTC)
+	later := now.Add(time.Hour)
+
+	tests := []struct {
+		name string
+		run  func(t *testing.T, clock *utils.FixedClock)
+	}{
+		{
+			name: "new user",
+			run: func(t *testing.T, clock *utils.FixedClock) {
+				user, err := NewUser("alice", "alice@example.com", "SecurePass123")
+				if err != nil {
+					t.Fatalf("NewUser() error = %v", err)
+				}
+				if !user.CreatedAt.Equal(now) || !user.UpdatedAt.Equal(now) {
+					t.Errorf("created_at = %v, updated_at = %v, want both %v", user.CreatedAt, user.UpdatedAt, now)
+				}
+			},
+		},
+		{
+			name: "update keeps created_at",
+			run: func(t *testing.T, clock *utils.FixedClock) {
+				user := NewTestUser()
+				clock.Set(later)
+				user.UpdateTimestamp()
+				if !user.CreatedAt.Equal(now) || !user.UpdatedAt.Equal(later) {
+					t.Errorf("created_at = %v, updated_at = %v, want %v and %v", user.CreatedAt, user.UpdatedAt, now, later)
+				}
+			},
+		},
+		{
+			name: "soft delete",
+			run: func(t *testing.T, clock *utils.FixedClock) {
+				user := NewTestUser()
+				clock.Set(later)
+				user.SoftDelete()
+				if user.DeletedAt == nil || !user.DeletedAt.Equal(later) || !user.UpdatedAt.Equal(later) {
+					t.Errorf("deleted_at = %v, updated_at = %v, want both %v", user.DeletedAt, user.UpdatedAt, later)
+				}
+			},
+		},
+		{
+			name: "login and verification",
+			run: func(t *testing.T, clock *utils.FixedClock) {
+				user := NewTestUser()
+				user.RecordLogin()
+				user.RecordFailedLogin()
+				user.VerifyEmail()
+				for name, got := range map[string]*time.Time{"last_login_at": user.LastLoginAt, "last_failed_at": user.LastFailedAt, "email_verified_at": user.EmailVerifiedAt} {
+					if got == nil || !got.Equal(now) {
+						t.Errorf("%s = %v, want %v", name, got, now)
+					}
+				}
+			},
+		},
+		{
+			name: "login event",
+			run: func(t *testing.T, clock *utils.FixedClock) {
+				event := NewLoginEvent(LoginClient{IP: "127.0.0.1"}, true, "")
+				if !event.Timestamp.Equal(now) {
+					t.Errorf("timestamp = %v, want %v", event.Timestamp, now)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := utils.NewFixedClock(now)
+			utils.SetClock(clock)
+			t.Cleanup(func() { utils.SetClock(nil) })
+			tt.run(t, clock)
+		})
+	}
+}
+
+func TestDateOfBirthUsesClock(t *testing.T) {
+	// With the clock in 2050 a 2035 birth date is 14 years old; against the real time it is in the future
+	utils.SetClock(utils.NewFixedClock(time.Date(2050, 1, 1, 0, 0, 0, 0, time.UTC)))
+	t.Cleanup(func() { utils.SetClock(nil) })
+
+	tests := []struct {
+		name    string
+		dob     string
+		wantErr bool
+	}{
+		{name: "old enough", dob: "2035-06-01"},
+		{name: "exactly the minimum age", dob: "2037-01-01"},
+		{name: "one day too young", dob: "2037-01-02", wantErr: true},
+		{name: "future", dob: "2050-01-02", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dob := tt.dob
+			req := UpdateUserRequest{DateOfBirth: &dob}
+			errs := req.Validate()
+			if (len(errs) > 0) != tt.wantErr {
+				t.Fatalf("Validate() = %v, wantErr %v", errs, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			updates := req.ToMap()
+			if _, ok := updates["date_of_birth"].(time.Time); !ok {
+				t.Fatalf("ToMap() date_of_birth = %v, want a date", updates["date_of_birth"])
+			}
+			user := NewTestUser()
+			if err := user.UpdateUser(updates); err != nil {
+				t.Fatalf("UpdateUser() error = %v", err)
+			}
+			if user.DateOfBirth == nil || user.DateOfBirth.Format(DateOfBirthFormat) != tt.dob {
+				t.Errorf("date of birth = %v, want %s", user.DateOfBirth, tt.dob)
+			}
+		})
+	}
+}
+
+func TestParseDateOfBirth(t *testing.T) {
+	now := time.Date(2026, 3, 15, 18, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		value   string
+		want    string
+		wantErr string
+	}{
+		{name: "date", value: "1990-05-17", want: "1990-05-17"},
+		{name: "surrounding spaces", value: " 1990-05-17 ", want: "1990-05-17"},
+		{name: "RFC3339 keeps the calendar date", value: "1990-05-17T23:30:00-05:00", want: "1990-05-17"},
+		{name: "RFC3339 ahead of UTC", value: "1990-05-17T01:00:00+09:00", want: "1990-05-17"},
+		{name: "thirteenth birthday today", value: "2013-03-15", want: "2013-03-15"},
+		{name: "thirteenth birthday tomorrow", value: "2013-03-16", wantErr: "users must be at least 13 years old"},
+		{name: "under 13", value: "2020-01-01", wantErr: "users must be at least 13 years old"},
+		{name: "tomorrow", value: "2026-03-16", wantErr: "date of birth cannot be in the future"},
+		{name: "oldest plausible", value: "1896-03-15", want: "1896-03-15"},
+		{name: "implausibly old", value: "1896-03-14", wantErr: "date of birth is not plausible"},
+		{name: "wrong layout", value: "17/05/1990", wantErr: "YYYY-MM-DD or RFC3339"},
+		{name: "not a calendar date", value: "1990-02-30", wantErr: "YYYY-MM-DD or RFC3339"},
+		{name: "empty", value: "", wantErr: "YYYY-MM-DD or RFC3339"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseDateOfBirth(tt.value, now)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ParseDateOfBirth(%q) error = %v, want it to mention %q", tt.value, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseDateOfBirth(%q) error = %v", tt.value, err)
+			}
+			if got.Format(DateOfBirthFormat) != tt.want || got.Location() != time.UTC || got.Hour() != 0 {
+				t.Errorf("ParseDateOfBirth(%q) = %v, want %s at midnight UTC", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpdateUserRequestDateOfBirthToMap(t *testing.T) {
+	tests := []struct {
+		name    string
+		dob     *string
+		wantSet bool
+		want    interface{}
+	}{
+		{name: "omitted"},
+		{name: "cleared", dob: new(string), wantSet: true, want: nil},
+		{name: "date", dob: func() *string { s := "1990-05-17"; return &s }(), wantSet: true, want: time.Date(1990, 5, 17, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user := NewTestUser()
+			birthday := time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+			user.DateOfBirth = &birthday
+
+			req := UpdateUserRequest{DateOfBirth: tt.dob}
+			updates := req.ToMap()
+			got, ok := updates["date_of_birth"]
+			if ok != tt.wantSet || got != tt.want {
+				t.Fatalf("ToMap() date_of_birth = %v (set %v), want %v (set %v)", got, ok, tt.want, tt.wantSet)
+			}
+
+			if err := user.UpdateUser(updates); err != nil {
+				t.Fatalf("UpdateUser() error = %v", err)
+			}
+			switch {
+			case !tt.wantSet:
+				if user.DateOfBirth == nil || !user.DateOfBirth.Equal(birthday) {
+					t.Errorf("date of birth = %v, want it unchanged", user.DateOfBirth)
+				}
+			case tt.want == nil:
+				if user.DateOfBirth != nil {
+					t.Errorf("date of birth = %v, want it cleared", user.DateOfBirth)
+				}
+			default:
+				if user.DateOfBirth == nil || !user.DateOfBirth.Equal(tt.want.(time.Time)) {
+					t.Errorf("date of birth = %v, want %v", user.DateOfBirth, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateUsername(t *testing.T) {
+	const invalidChars = "can only contain letters, numbers, and underscores"
+
+	tests := []struct {
+		name        string
+		username    string
+		wantASCII   string // error expected with ASCII-only usernames, empty when valid
+		wantUnicode string // error expected with Unicode usernames, empty when valid
+	}{
+		{name: "ascii", username: "john_doe42"},
+		{name: "surrounding spaces are trimmed", username: "  john  "},
+		{name: "accented latin", username: "josé_müller", wantASCII: invalidChars},
+		{name: "decomposed accent", username: "jose\u0301", wantASCII: invalidChars},
+		{name: "other scripts", username: "пользователь", wantASCII: invalidChars},
+		{name: "ideographs", username: "用户名", wantASCII: invalidChars},
+		{name: "non-ascii digits", username: "user_٣٤٥", wantASCII: invalidChars},
+		{name: "inner space", username: "jo sé", wantASCII: invalidChars, wantUnicode: invalidChars},
+		{name: "no-break space", username: "jos\u00e9\u00a0m", wantASCII: invalidChars, wantUnicode: invalidChars},
+		{name: "tab", username: "jose\tm", wantASCII: invalidChars, wantUnicode: invalidChars},
+		{name: "control character", username: "josé\u0007", wantASCII: invalidChars, wantUnicode: invalidChars},
+		{name: "zero-width space", username: "jo\u200bs\u00e9", wantASCII: invalidChars, wantUnicode: invalidChars},
+		{name: "punctuation", username: "josé-m", wantASCII: invalidChars, wantUnicode: invalidChars},
+		{name: "symbols", username: "josé☃", wantASCII: invalidChars, wantUnicode: invalidChars},
+		{name: "too short", username: "jo", wantASCII: "at least 3 characters", wantUnicode: "at least 3 characters"},
+		// Lengths count characters, so three two-byte letters are long enough
+		{name: "three accented letters", username: "ééé", wantASCII: invalidChars},
+		{name: "decomposed letters count once", username: "e\u0301e\u0301e\u0301", wantASCII: invalidChars},
+		{name: "thirty multi-byte letters", username: strings.Repeat("é", 30), wantASCII: invalidChars},
+		{name: "thirty-one letters", username: strings.Repeat("é", 31), wantASCII: "cannot exceed 30 characters", wantUnicode: "cannot exceed 30 characters"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, mode := range []struct {
+				allowUnicode bool
+				wantErr      string
+			}{
+				{allowUnicode: false, wantErr: tt.wantASCII},
+				{allowUnicode: true, wantErr: tt.wantUnicode},
+			} {
+				SetAllowUnicodeUsernames(mode.allowUnicode)
+				t.Cleanup(func() { SetAllowUnicodeUsernames(false) })
+
+				err := ValidateUsername(tt.username)
+				if mode.wantErr == "" {
+					if err != nil {
+						t.Errorf("unicode %v: ValidateUsername(%q) error = %v", mode.allowUnicode, tt.username, err)
+					}
+					continue
+				}
+				if err == nil || !strings.Contains(err.Error(), mode.wantErr) {
+					t.Errorf("unicode %v: ValidateUsername(%q) error = %v, want it to mention %q", mode.allowUnicode, tt.username, err, mode.wantErr)
+				}
+			}
+		})
+	}
+}
+
+func TestNormalizeUsername(t *testing.T) {
+	tests := []struct {
+		name     string
+		username string
+		want     string
+	}{
+		{name: "ascii", username: "John_Doe", want: "john_doe"},
+		{name: "trimmed", username: "  john \n", want: "john"},
+		{name: "accented uppercase", username: "ÉMILE", want: "émile"},
+		// The decomposed spelling is stored like the precomposed one, so both are the same username
+		{name: "decomposed accent", username: "Jose\u0301", want: "jos\u00e9"},
+		{name: "precomposed accent", username: "Jos\u00e9", want: "jos\u00e9"},
+		{name: "other scripts", username: "Пользователь", want: "пользователь"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NormalizeUsername(tt.username); got != tt.want {
+				t.Errorf("NormalizeUsername(%q) = %q, want %q", tt.username, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,336 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go-template/internal/interfaces"
+	"go-template/internal/models"
+	"go-template/internal/shared/middleware"
+	"go-template/internal/shared/response"
+	"go-template/internal/shared/utils"
+)
+
+// AuthHandler handles HTTP requests for authentication
+type AuthHandler struct {
+	service  *AuthService
+	throttle *loginThrottle
+	tokens   *utils.TokenService
+	logger   interfaces.LoggerInterface
+	
+	// trustedProxies may set forwarding headers; see middleware.ClientIP
+	trustedProxies middleware.TrustedProxies
+}
+
+// jwksMaxAge is how long verifiers may cache the published signing keys
+const jwksMaxAge = time.Hour
+
+// NewAuthHandler creates a new AuthHandler instance
+func NewAuthHandler(service *AuthService, throttle *loginThrottle, tokens *utils.TokenService, trustedProxies []string, logger interfaces.LoggerInterface) *AuthHandler {
+	return &AuthHandler{
+		service:  service,
+		throttle: throttle,
+		tokens:   tokens,
+		logger:   logger.With("handler", "auth"),
+		
+		trustedProxies: middleware.ParseTrustedProxies(trustedProxies),
+	}
+}
+
+// Login handles POST /api/v1/auth/login
+// @Summary Log in
+// @Description Authenticate with a username or email and password. Set remember_me to receive a longer-lived refresh token. Accounts are temporarily locked after repeated failed attempts, and clients are throttled after repeated failed attempts from one IP address across any accounts.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param credentials body models.LoginRequest true "Login credentials"
+// @Success 200 {object} response.Response{data=models.LoginResponse} "Login successful"
+// @Failure 400 {object} response.Response{error=response.ErrorInfo} "Validation error or invalid request body"
+// @Failure 401 {object} response.Response{error=response.ErrorInfo} "Invalid credentials"
+// @Failure 403 {object} response.Response{error=response.ErrorInfo} "Account is inactive"
+// @Failure 423 {object} response.Response{error=response.ErrorInfo} "Account is locked"
+// @Failure 429 {object} response.Response{error=response.ErrorInfo} "Too many failed logins from this IP address"
+// @Failure 500 {object} response.Response{error=response.ErrorInfo} "Internal server error"
+// @Router /api/v1/auth/login [post]
+func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
+	h.logger.Info("Login request received")
+
+	// Throttle clients spraying passwords across accounts; cache failures let the attempt through
+	clientIP := h.trustedProxies.ClientIP(r)
+	if retryAfter, err := h.throttle.retryAfter(r.Context(), clientIP); err != nil {
+		h.logger.Warn("Failed to check login throttle", "client_ip", clientIP, "error", err.Error())
+	} else if retryAfter > 0 {
+		h.logger.Warn("Login throttled for client IP", "client_ip", clientIP, "retry_after", retryAfter.String())
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+		response.ErrorWithCode(w, response.ErrorCodeRateLimit,
+			"Too many failed login attempts from this address", http.StatusTooManyRequests)
+		return
+	}
+
+	// Parse request body
+	var req models.LoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Warn("Invalid request body", "error", err.Error())
+		response.BadRequest(w, "Invalid request body format")
+		return
+	}
+
+	client := models.LoginClient{IP: clientIP, UserAgent: r.UserAgent()}
+	loginResponse, err := h.service.Login(r.Context(), &req, client)
+	if errors.Is(err, ErrInvalidCredentials) || errors.Is(err, ErrAccountLocked) {
+		if err := h.throttle.recordFailure(r.Context(), clientIP); err != nil {
+			h.logger.Warn("Failed to record failed login for client IP", "client_ip", clientIP, "error", err.Error())
+		}
+	}
+	if err != nil {
+		var lockedErr *LockedError
+		if errors.As(err, &lockedErr) {
+			retryAfter := int(math.Ceil(lockedErr.RetryAfter.Seconds()))
+			w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+			response.ErrorWithCode(w, response.ErrorCodeAccountLocked,
+				"Account is temporarily locked due to too many failed login attempts", http.StatusLocked)
+			return
+		}
+		if errors.Is(err, ErrInvalidCredentials) {
+			response.Unauthorized(w, err.Error())
+			return
+		}
+		if errors.Is(err, ErrAccountInactive) {
+			response.Forbidden(w, err.Error())
+			return
+		}
+		if errors.Is(err, interfaces.ErrValidation) {
+			h.logger.Warn("Login validation failed", "error", err.Error())
+			response.BadRequest(w, err.Error())
+			return
+		}
+		h.logFailure("Failed to log in", err)
+		response.HandleError(w, r, err)
+		return
+	}
+
+	response.JSONWithMessage(w, loginResponse, "Login successful", http.StatusOK)
+	h.logger.Info("Login successful", "user_id", loginResponse.User.ID)
+}
+
+// GetLoginHistory handles GET /api/v1/users/{id}/login-history
+// @Summary Get login history
+// @Description Get the most recent login attempts on a user's account, newest first, with the client IP address and user agent of each. Only the user and admins may view it.
+// @Tags Auth
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID" format(objectid) example(507f1f77bcf86cd799439011)
+// @Success 200 {object} response.Response{data=[]models.LoginEvent} "Recent login attempts"
+// @Failure 401 {object} response.Response{error=response.ErrorInfo} "Missing or invalid access token"
+// @Failure 403 {object} response.Response{error=response.ErrorInfo} "Not the user or an admin"
+// @Failure 404 {object} response.Response{error=response.ErrorInfo} "User not found"
+// @Failure 500 {object} response.Response{error=response.ErrorInfo} "Internal server error"
+// @Router /api/v1/users/{id}/login-history [get]
+func (h *AuthHandler) GetLoginHistory(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	events, err := h.service.GetLoginHistory(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, interfaces.ErrNotFound) {
+			response.NotFound(w, "User")
+			return
+		}
+		h.logFailure("Failed to get login history", err, "user_id", id)
+		response.HandleError(w, r, err)
+		return
+	}
+
+	response.JSON(w, events, http.StatusOK)
+}
+
+// Impersonate handles POST /api/v1/users/{id}/impersonate
+// @Summary Impersonate a user
+// @Description Issue a 15-minute access token for acting as the user, for support staff debugging an account. The token carries the admin's ID in the impersonated_by claim and every request made with it is logged with both IDs. Admin only; impersonation tokens cannot start another impersonation.
+// @Tags Auth
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID" format(objectid) example(507f1f77bcf86cd799439011)
+// @Success 200 {object} response.Response{data=models.ImpersonationResponse} "Impersonation token"
+// @Failure 400 {object} response.Response{error=response.ErrorInfo} "Impersonating yourself or an inactive user"
+// @Failure 401 {object} response.Response{error=response.ErrorInfo} "Missing or invalid access token"
+// @Failure 403 {object} response.Response{error=response.ErrorInfo} "Not an admin, or already impersonating"
+// @Failure 404 {object} response.Response{error=response.ErrorInfo} "User not found"
+// @Failure 500 {object} response.Response{error=response.ErrorInfo} "Internal server error"
+// @Router /api/v1/users/{id}/impersonate [post]
+func (h *AuthHandler) Impersonate(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	claims, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok {
+		response.Unauthorized(w, "Authentication required")
+		return
+	}
+	// Impersonating an admin yields the admin role, so impersonations must not chain
+	if claims.ImpersonatedBy != "" {
+		response.Forbidden(w, "Impersonation tokens cannot be used to impersonate")
+		return
+	}
+
+	result, err := h.service.Impersonate(r.Context(), claims.Subject, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, interfaces.ErrNotFound):
+			response.NotFound(w, "User")
+		case errors.Is(err, interfaces.ErrValidation), errors.Is(err, interfaces.ErrInvalidState):
+			response.BadRequest(w, err.Error())
+		default:
+			h.logFailure("Failed to impersonate user", err, "admin_id", claims.Subject, "user_id", id)
+			response.HandleError(w, r, err)
+		}
+		return
+	}
+
+	response.JSON(w, result, http.StatusOK)
+}
+
+// JWKS handles GET /.well-known/jwks.json
+// @Summary Get token signing keys
+// @Description Get the public keys access tokens can be verified with, as a JSON Web Key Set. Only available when tokens are signed with RS256; HS256 keys are secret.
+// @Tags Auth
+// @Produce json
+// @Success 200 {object} utils.JSONWebKeySet "Public signing keys"
+// @Failure 404 {object} response.Response{error=response.ErrorInfo} "Tokens are not signed with a public key algorithm"
+// @Router /.well-known/jwks.json [get]
+func (h *AuthHandler) JWKS(w http.ResponseWriter, r *http.Request) {
+	keys, ok := h.tokens.JWKS()
+	if !ok {
+		response.NotFound(w, "")
+		return
+	}
+
+	// Verifiers fetch the key set on their own schedule; it is the raw JWKS document, not an envelope
+	response.SetPublicCache(w, jwksMaxAge)
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(keys); err != nil {
+		h.logger.Error("Failed to encode JWKS", err)
+	}
+}
+
+// VerifyEmail handles POST /api/v1/auth/verify-email
+// @Summary Verify email address
+// @Description Confirm ownership of an email address with a token sent by POST /api/v1/users/{id}/verification/send. Tokens are single-use and expire after 24 hours.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param token body models.VerifyEmailRequest true "Verification token"
+// @Success 200 {object} response.Response "Email verified successfully"
+// @Failure 400 {object} response.Response{error=response.ErrorInfo} "Invalid, expired or already used token"
+// @Failure 500 {object} response.Response{error=response.ErrorInfo} "Internal server error"
+// @Router /api/v1/auth/verify-email [post]
+func (h *AuthHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	h.logger.Info("Email verification request received")
+
+	// Parse request body
+	var req models.VerifyEmailRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Warn("Invalid request body", "error", err.Error())
+		response.BadRequest(w, "Invalid request body format")
+		return
+	}
+
+	if err := h.service.VerifyEmail(r.Context(), &req); err != nil {
+		if errors.Is(err, utils.ErrInvalidActionToken) {
+			response.BadRequest(w, "Invalid or expired verification token")
+			return
+		}
+		if errors.Is(err, interfaces.ErrValidation) {
+			response.BadRequest(w, err.Error())
+			return
+		}
+		h.logFailure("Failed to verify email", err)
+		response.HandleError(w, r, err)
+		return
+	}
+
+	response.JSONWithMessage(w, nil, "Email verified successfully", http.StatusOK)
+}
+
+// ForgotPassword handles POST /api/v1/auth/forgot-password
+// @Summary Request a password reset
+// @Description Email a single-use password reset token, valid for 30 minutes, if an account exists for the address. Always responds with 200 so registered addresses cannot be discovered.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body models.ForgotPasswordRequest true "Account email"
+// @Success 200 {object} response.Response "Reset instructions sent if the account exists"
+// @Failure 400 {object} response.Response{error=response.ErrorInfo} "Invalid email format or request body"
+// @Router /api/v1/auth/forgot-password [post]
+func (h *AuthHandler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	h.logger.Info("Forgot password request received")
+
+	// Parse request body
+	var req models.ForgotPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Warn("Invalid request body", "error", err.Error())
+		response.BadRequest(w, "Invalid request body format")
+		return
+	}
+
+	if err := h.service.ForgotPassword(r.Context(), &req); err != nil {
+		if errors.Is(err, interfaces.ErrValidation) {
+			response.BadRequest(w, err.Error())
+			return
+		}
+		h.logFailure("Failed to process forgot password request", err)
+	}
+
+	response.JSONWithMessage(w, nil, "If an account exists for this email, password reset instructions have been sent", http.StatusOK)
+}
+
+// ResetPassword handles POST /api/v1/auth/reset-password
+// @Summary Reset password
+// @Description Set a new password using a token sent by POST /api/v1/auth/forgot-password. Tokens are single-use.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body models.ResetPasswordRequest true "Reset token and new password"
+// @Success 200 {object} response.Response "Password reset successfully"
+// @Failure 400 {object} response.Response{error=response.ErrorInfo} "Validation error or invalid, expired or already used token"
+// @Failure 500 {object} response.Response{error=response.ErrorInfo} "Internal server error"
+// @Router /api/v1/auth/reset-password [post]
+func (h *AuthHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	h.logger.Info("Reset password request received")
+
+	// Parse request body
+	var req models.ResetPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Warn("Invalid request body", "error", err.Error())
+		response.BadRequest(w, "Invalid request body format")
+		return
+	}
+
+	if err := h.service.ResetPassword(r.Context(), &req); err != nil {
+		if errors.Is(err, utils.ErrInvalidActionToken) {
+			response.BadRequest(w, "Invalid or expired reset token")
+			return
+		}
+		if errors.Is(err, interfaces.ErrValidation) {
+			response.BadRequest(w, err.Error())
+			return
+		}
+		h.logFailure("Failed to reset password", err)
+		response.HandleError(w, r, err)
+		return
+	}
+
+	response.JSONWithMessage(w, nil, "Password reset successfully", http.StatusOK)
+}
+
+// logFailure logs an error the handler could not map to a client error
+// Canceled requests and expired deadlines are not server faults, so they are logged as warnings.
+func (h *AuthHandler) logFailure(msg string, err error, args ...interface{}) {
+	if response.IsContextError(err) {
+		h.logger.Warn(msg, append(args, "error", err.Error())...)
+		return
+	}
+	h.logger.Error(msg, err, args...)
+}
@@ -0,0 +1,556 @@
+// internal/modules/auth/handler_test.go
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"go-template/internal/models"
+	"go-template/internal/shared/middleware"
+	"go-template/internal/shared/response"
+	"go-template/internal/shared/utils"
+)
+
+// newTestHandler builds an AuthHandler over ta with IP throttling limited to maxFailuresPerIP
+func newTestHandler(ta *testAuth, maxFailuresPerIP int) *AuthHandler {
+	throttle := newLoginThrottle(ta.cache, maxFailuresPerIP, 15*time.Minute)
+	return NewAuthHandler(ta.service, throttle, ta.tokens, nil, ta.logger)
+}
+
+// postLogin sends a login request to h from remoteAddr and decodes the response envelope
+func postLogin(t *testing.T, h *AuthHandler, remoteAddr, username, password string) (*httptest.ResponseRecorder, response.Response) {
+	t.Helper()
+
+	body, _ := json.Marshal(models.LoginRequest{Username: username, Password: password})
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/login", strings.NewReader(string(body)))
+	req.Header.Set("Content-Type", "application/json")
+	req.RemoteAddr = remoteAddr
+	rec := httptest.NewRecorder()
+	h.Login(rec, req)
+
+	var resp response.Response
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid response body %q: %v", rec.Body.String(), err)
+	}
+	return rec, resp
+}
+
+func TestLoginHandlerLockout(t *testing.T) {
+	tests := []struct {
+		name           string
+		failures       int
+		password       string
+		wantStatus     int
+		wantCode       string
+		wantRetryAfter string
+	}{
+		{
+			name:       "correct password below threshold",
+			failures:   4,
+			password:   models.TestUserPassword,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "wrong password below threshold",
+			failures:   4,
+			password:   "wrong-password",
+			wantStatus: http.StatusUnauthorized,
+			wantCode:   response.ErrorCodeUnauthorized,
+		},
+		{
+			name:           "locked account",
+			failures:       5,
+			password:       models.TestUserPassword,
+			wantStatus:     http.StatusLocked,
+			wantCode:       response.ErrorCodeAccountLocked,
+			wantRetryAfter: "1800",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ta := newTestAuth(t)
+			h := newTestHandler(ta, 0)
+			user := ta.createUser(t)
+
+			for i := 0; i < tt.failures; i++ {
+				postLogin(t, h, "192.0.2.1:1234", user.Username, "wrong-password")
+			}
+
+			rec, resp := postLogin(t, h, "192.0.2.1:1234", user.Username, tt.password)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantCode != "" && (resp.Error == nil || resp.Error.Code != tt.wantCode) {
+				t.Errorf("error = %+v, want code %s", resp.Error, tt.wantCode)
+			}
+			if got := rec.Header().Get("Retry-After"); got != tt.wantRetryAfter {
+				t.Errorf("Retry-After = %q, want %q", got, tt.wantRetryAfter)
+			}
+		})
+	}
+}
+
+func TestLoginHandlerRememberMe(t *testing.T) {
+	tests := []struct {
+		name            string
+		body            string
+		wantRefreshLife time.Duration
+	}{
+		{name: "remember me", body: `{"username":"%s","password":"%s","remember_me":true}`, wantRefreshLife: 30 * 24 * time.Hour},
+		{name: "remember me off", body: `{"username":"%s","password":"%s","remember_me":false}`, wantRefreshLife: 24 * time.Hour},
+		{name: "remember me omitted", body: `{"username":"%s","password":"%s"}`, wantRefreshLife: 24 * time.Hour},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ta := newTestAuth(t)
+			h := newTestHandler(ta, 100)
+			user := ta.createUser(t)
+
+			body := fmt.Sprintf(tt.body, user.Username, models.TestUserPassword)
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/login", strings.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+			h.Login(rec, req)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+			}
+
+			var resp struct {
+				Data models.LoginResponse `json:"data"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("invalid response body %q: %v", rec.Body.String(), err)
+			}
+
+			lifetimes := map[string]time.Duration{
+				"access":  tokenLifetime(t, ta, resp.Data.AccessToken),
+				"refresh": tokenLifetime(t, ta, resp.Data.RefreshToken),
+			}
+			want := map[string]time.Duration{"access": time.Hour, "refresh": tt.wantRefreshLife}
+			for kind, got := range lifetimes {
+				if got != want[kind] {
+					t.Errorf("%s token lifetime = %v, want %v", kind, got, want[kind])
+				}
+			}
+			if got := time.Duration(resp.Data.RefreshExpiresIn) * time.Second; got != tt.wantRefreshLife {
+				t.Errorf("refresh_expires_in = %v, want %v", got, tt.wantRefreshLife)
+			}
+		})
+	}
+}
+
+// tokenLifetime validates token and returns how long it was issued for
+func tokenLifetime(t *testing.T, ta *testAuth, token string) time.Duration {
+	t.Helper()
+	claims, err := ta.tokens.ValidateToken(token)
+	if err != nil {
+		t.Fatalf("ValidateToken() error = %v", err)
+	}
+	return time.Duration(claims.ExpiresAt-claims.IssuedAt) * time.Second
+}
+
+func TestLoginHandlerIPThrottle(t *testing.T) {
+	const attacker, bystander = "192.0.2.1:1234", "198.51.100.7:4321"
+
+	tests := []struct {
+		name           string
+		maxPerIP       int
+		failures       int // spread over as many different accounts, all from the attacker
+		from           string
+		password       string
+		wantStatus     int
+		wantCode       string
+		wantRetryAfter string
+	}{
+		{name: "below threshold", maxPerIP: 3, failures: 2, from: attacker, password: models.TestUserPassword, wantStatus: http.StatusOK},
+		{
+			name:           "spraying across accounts is throttled",
+			maxPerIP:       3,
+			failures:       3,
+			from:           attacker,
+			password:       "wrong-password",
+			wantStatus:     http.StatusTooManyRequests,
+			wantCode:       response.ErrorCodeRateLimit,
+			wantRetryAfter: "900",
+		},
+		{
+			name:           "correct password is throttled too",
+			maxPerIP:       3,
+			failures:       3,
+			from:           attacker,
+			password:       models.TestUserPassword,
+			wantStatus:     http.StatusTooManyRequests,
+			wantCode:       response.ErrorCodeRateLimit,
+			wantRetryAfter: "900",
+		},
+		{name: "other addresses are unaffected", maxPerIP: 3, failures: 3, from: bystander, password: models.TestUserPassword, wantStatus: http.StatusOK},
+		{name: "disabled", maxPerIP: 0, failures: 10, from: attacker, password: models.TestUserPassword, wantStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ta := newTestAuth(t)
+			h := newTestHandler(ta, tt.maxPerIP)
+			target := ta.createUser(t, models.WithUsername("target"), models.WithEmail("target@example.com"))
+
+			// One failure per account stays far below the per-account lockout
+			for i := 0; i < tt.failures; i++ {
+				victim := ta.createUser(t, models.WithUsername(fmt.Sprintf("victim%d", i)), models.WithEmail(fmt.Sprintf("victim%d@example.com", i)))
+				if rec, _ := postLogin(t, h, attacker, victim.Username, "wrong-password"); rec.Code != http.StatusUnauthorized {
+					t.Fatalf("failure %d status = %d, want 401", i+1, rec.Code)
+				}
+			}
+
+			rec, resp := postLogin(t, h, tt.from, target.Username, tt.password)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantCode != "" && (resp.Error == nil || resp.Error.Code != tt.wantCode) {
+				t.Errorf("error = %+v, want code %s", resp.Error, tt.wantCode)
+			}
+			if got := rec.Header().Get("Retry-After"); got != tt.wantRetryAfter {
+				t.Errorf("Retry-After = %q, want %q", got, tt.wantRetryAfter)
+			}
+		})
+	}
+}
+
+func TestLoginHandlerIPThrottleCountsLockouts(t *testing.T) {
+	ta := newTestAuth(t)
+	h := newTestHandler(ta, 7)
+	user := ta.createUser(t)
+
+	// The fifth failure locks the account; attempts against it while locked keep counting for the address
+	statuses := make([]int, 0, 8)
+	for i := 0; i < 8; i++ {
+		rec, _ := postLogin(t, h, "192.0.2.1:1234", user.Username, "wrong-password")
+		statuses = append(statuses, rec.Code)
+	}
+
+	want := []int{401, 401, 401, 401, 401, 423, 423, 429}
+	if !slices.Equal(statuses, want) {
+		t.Errorf("statuses = %v, want %v", statuses, want)
+	}
+}
+
+func TestLoginHandlerIPThrottleBehindProxy(t *testing.T) {
+	const proxy, attacker = "10.0.0.2:443", "192.0.2.1:1234"
+
+	tests := []struct {
+		name           string
+		trustedProxies []string
+		peer           string
+		failuresFor    []string // X-Forwarded-For of each failed attempt, one account each
+		loginFor       string
+		wantStatus     int
+	}{
+		{
+			name:           "forwarded clients are told apart",
+			trustedProxies: []string{"10.0.0.0/8"},
+			peer:           proxy,
+			failuresFor:    []string{"192.0.2.1", "192.0.2.1", "192.0.2.1"},
+			loginFor:       "198.51.100.7",
+			wantStatus:     http.StatusOK,
+		},
+		{
+			name:           "forwarded attacker is throttled",
+			trustedProxies: []string{"10.0.0.0/8"},
+			peer:           proxy,
+			failuresFor:    []string{"192.0.2.1", "192.0.2.1", "192.0.2.1"},
+			loginFor:       "192.0.2.1",
+			wantStatus:     http.StatusTooManyRequests,
+		},
+		{
+			name:        "spoofed headers do not dodge the throttle",
+			peer:        attacker,
+			failuresFor: []string{"198.51.100.1", "198.51.100.2", "198.51.100.3"},
+			loginFor:    "198.51.100.4",
+			wantStatus:  http.StatusTooManyRequests,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ta := newTestAuth(t)
+			h := NewAuthHandler(ta.service, newLoginThrottle(ta.cache, 3, 15*time.Minute), ta.tokens, tt.trustedProxies, ta.logger)
+			target := ta.createUser(t, models.WithUsername("target"), models.WithEmail("target@example.com"))
+
+			login := func(username, password, forwardedFor string) int {
+				body, _ := json.Marshal(models.LoginRequest{Username: username, Password: password})
+				req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/login", strings.NewReader(string(body)))
+				req.Header.Set("Content-Type", "application/json")
+				req.Header.Set("X-Forwarded-For", forwardedFor)
+				req.RemoteAddr = tt.peer
+				rec := httptest.NewRecorder()
+				h.Login(rec, req)
+				return rec.Code
+			}
+
+			for i, forwardedFor := range tt.failuresFor {
+				victim := ta.createUser(t, models.WithUsername(fmt.Sprintf("victim%d", i)), models.WithEmail(fmt.Sprintf("victim%d@example.com", i)))
+				if status := login(victim.Username, "wrong-password", forwardedFor); status != http.StatusUnauthorized {
+					t.Fatalf("failure %d status = %d, want 401", i+1, status)
+				}
+			}
+
+			if status := login(target.Username, models.TestUserPassword, tt.loginFor); status != tt.wantStatus {
+				t.Errorf("status = %d, want %d", status, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestGetLoginHistoryHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		caller     string // "self", "other" or "admin"
+		missing    bool
+		wantStatus int
+		wantEvents int
+	}{
+		{name: "own history", caller: "self", wantStatus: http.StatusOK, wantEvents: 2},
+		{name: "admin", caller: "admin", wantStatus: http.StatusOK, wantEvents: 2},
+		{name: "another user", caller: "other", wantStatus: http.StatusForbidden},
+		{name: "unknown user", caller: "admin", missing: true, wantStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ta := newTestAuth(t)
+			h := newTestHandler(ta, 0)
+			user := ta.createUser(t)
+			other := ta.createUser(t)
+
+			// Recorded through the login handler, so the history carries the request's client
+			postLogin(t, h, "192.0.2.1:1234", user.Username, "wrong-password")
+			postLogin(t, h, "192.0.2.1:1234", user.Username, models.TestUserPassword)
+
+			claims := &utils.TokenClaims{Subject: other.GetIDString(), Roles: []string{models.RoleUser}}
+			switch tt.caller {
+			case "self":
+				claims.Subject = user.GetIDString()
+			case "admin":
+				claims.Roles = []string{models.RoleAdmin}
+			}
+			id := user.GetIDString()
+			if tt.missing {
+				id = "507f1f77bcf86cd799439011"
+			}
+
+			mux := http.NewServeMux()
+			mux.Handle("GET /api/v1/users/{id}/login-history",
+				middleware.RequireSelfOrRole("id", models.RoleAdmin)(http.HandlerFunc(h.GetLoginHistory)))
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/users/"+id+"/login-history", nil)
+			req = req.WithContext(middleware.WithClaims(req.Context(), claims))
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var resp struct {
+				Data []models.LoginEvent `json:"data"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("invalid response body %q: %v", rec.Body.String(), err)
+			}
+			if len(resp.Data) != tt.wantEvents {
+				t.Fatalf("returned %d events, want %d", len(resp.Data), tt.wantEvents)
+			}
+			latest, first := resp.Data[0], resp.Data[1]
+			if !latest.Success || first.Success || first.Reason != models.LoginFailureInvalidPassword {
+				t.Errorf("events = %+v, want the success then the failed attempt", resp.Data)
+			}
+			if latest.IP != "192.0.2.1" {
+				t.Errorf("IP = %q, want the request's remote address", latest.IP)
+			}
+		})
+	}
+}
+
+func TestGetLoginHistoryHandlerClientGone(t *testing.T) {
+	ta := newTestAuth(t)
+	h := newTestHandler(ta, 0)
+	user := ta.createUser(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/users/{id}/login-history", h.GetLoginHistory)
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/users/"+user.GetIDString()+"/login-history", nil).WithContext(ctx)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+
+	if rec.Code != response.StatusClientClosedRequest || rec.Body.Len() != 0 {
+		t.Errorf("response = %d %q, want a bare %d", rec.Code, rec.Body.String(), response.StatusClientClosedRequest)
+	}
+	if ta.logger.Has(slog.LevelError, "Failed to get login history") || !ta.logger.Has(slog.LevelWarn, "Failed to get login history") {
+		t.Error("canceled request not logged at warn")
+	}
+}
+
+func TestJWKSHandler(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		tokens     *utils.TokenService
+		wantStatus int
+	}{
+		{name: "HS256 has nothing to publish", wantStatus: http.StatusNotFound},
+		{name: "RS256 publishes the public key", tokens: utils.NewRSATokenService(key, time.Hour, 24*time.Hour, 30*24*time.Hour), wantStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ta := newTestAuth(t)
+			h := newTestHandler(ta, 0)
+			if tt.tokens != nil {
+				h.tokens = tt.tokens
+			}
+
+			rec := httptest.NewRecorder()
+			h.JWKS(rec, httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil))
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				if cc := rec.Header().Get("Cache-Control"); cc != "" {
+					t.Errorf("Cache-Control = %q, want the 404 uncached", cc)
+				}
+				return
+			}
+
+			if cc := rec.Header().Get("Cache-Control"); cc != "public, max-age=3600" {
+				t.Errorf("Cache-Control = %q, want public, max-age=3600", cc)
+			}
+			if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+				t.Errorf("Content-Type = %q, want application/json", ct)
+			}
+			// The body is the raw key set verifiers expect, not the response envelope
+			var set utils.JSONWebKeySet
+			if err := json.Unmarshal(rec.Body.Bytes(), &set); err != nil {
+				t.Fatalf("invalid JWKS %q: %v", rec.Body.String(), err)
+			}
+			want, _ := tt.tokens.JWKS()
+			if !slices.Equal(set.Keys, want.Keys) {
+				t.Errorf("JWKS = %+v, want %+v", set.Keys, want.Keys)
+			}
+		})
+	}
+}
+
+func TestImpersonateHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		caller     string // "admin", "user", "impersonator" or "" for no token
+		target     string // "user", "inactive", "self" or "missing"
+		wantStatus int
+	}{
+		{name: "admin", caller: "admin", target: "user", wantStatus: http.StatusOK},
+		{name: "not an admin", caller: "user", target: "user", wantStatus: http.StatusForbidden},
+		{name: "no token", target: "user", wantStatus: http.StatusUnauthorized},
+		{name: "impersonations do not chain", caller: "impersonator", target: "user", wantStatus: http.StatusForbidden},
+		{name: "yourself", caller: "admin", target: "self", wantStatus: http.StatusBadRequest},
+		{name: "inactive user", caller: "admin", target: "inactive", wantStatus: http.StatusBadRequest},
+		{name: "unknown user", caller: "admin", target: "missing", wantStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ta := newTestAuth(t)
+			h := newTestHandler(ta, 0)
+			admin := ta.createUser(t, models.WithRoles(models.RoleUser, models.RoleAdmin))
+			user := ta.createUser(t)
+			targets := map[string]string{
+				"user":     user.GetIDString(),
+				"inactive": ta.createUser(t, models.WithActive(false)).GetIDString(),
+				"self":     admin.GetIDString(),
+				"missing":  "507f1f77bcf86cd799439011",
+			}
+
+			var token string
+			var err error
+			switch tt.caller {
+			case "admin":
+				token, err = ta.tokens.GenerateAccessToken(admin.GetIDString(), admin.Roles, admin.TokenVersion)
+			case "user":
+				token, err = ta.tokens.GenerateAccessToken(user.GetIDString(), user.Roles, user.TokenVersion)
+			case "impersonator":
+				// Another admin acting as the admin keeps the admin role
+				token, err = ta.tokens.GenerateImpersonationToken(admin.GetIDString(), admin.Roles, admin.TokenVersion, "507f1f77bcf86cd799439012", ImpersonationExpiration)
+			}
+			if err != nil {
+				t.Fatalf("generating the caller's token: %v", err)
+			}
+
+			// Wired as the auth module registers it
+			requireAdmin := middleware.ChainFunc(middleware.RequireAuth(ta.tokens, nil, ta.logger), middleware.RequireRole(models.RoleAdmin))
+			mux := http.NewServeMux()
+			mux.Handle("POST /api/v1/users/{id}/impersonate", requireAdmin(h.Impersonate))
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/users/"+targets[tt.target]+"/impersonate", nil)
+			if token != "" {
+				req.Header.Set("Authorization", "Bearer "+token)
+			}
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var resp struct {
+				Data models.ImpersonationResponse `json:"data"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("invalid response body %q: %v", rec.Body.String(), err)
+			}
+			claims, err := ta.tokens.ValidateToken(resp.Data.AccessToken)
+			if err != nil {
+				t.Fatalf("ValidateToken() error = %v", err)
+			}
+			if claims.Subject != user.GetIDString() || claims.ImpersonatedBy != admin.GetIDString() {
+				t.Errorf("claims = %+v, want %s impersonated by %s", claims, user.GetIDString(), admin.GetIDString())
+			}
+
+			// The issued token authenticates as the user, and its requests are logged with both IDs
+			protected := middleware.RequireAuth(ta.tokens, nil, ta.logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				userID, _ := middleware.UserIDFromContext(r.Context())
+				impersonator, _ := middleware.ImpersonatorFromContext(r.Context())
+				fmt.Fprintf(w, "%s %s", userID, impersonator)
+			}))
+			req = httptest.NewRequest(http.MethodGet, "/api/v1/users/me", nil)
+			req.Header.Set("Authorization", "Bearer "+resp.Data.AccessToken)
+			rec = httptest.NewRecorder()
+			protected.ServeHTTP(rec, req)
+			if want := user.GetIDString() + " " + admin.GetIDString(); rec.Code != http.StatusOK || rec.Body.String() != want {
+				t.Errorf("request with the token = %d %q, want 200 %q", rec.Code, rec.Body.String(), want)
+			}
+			if !ta.logger.Has(slog.LevelInfo, "Impersonated request") {
+				t.Error("request with the impersonation token was not logged")
+			}
+		})
+	}
+}
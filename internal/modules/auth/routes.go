@@ -1,25 +1,53 @@
+// internal/modules/auth/routes.go
 package auth
 
 import (
 	"go-template/internal/container"
-	"net/http"
+	"go-template/internal/models"
+	"go-template/internal/modules/users"
+	"go-template/internal/repositories"
+	"go-template/internal/shared/middleware"
 )
 
+// RegisterRoutes registers all authentication routes
+// This function is completely self-contained and handles its own dependency injection
 func RegisterRoutes(deps *container.Dependencies) {
+	logger := deps.GetLogger("auth")
+	logger.Info("Registering auth module routes")
 
-		logger := deps.GetLogger("auth")
-		logger.Info("Registering auth module routes")
-		
-		mux := deps.Mux
-		
-		mux.HandleFunc("POST /api/v1/auth/login", (func(w http.ResponseWriter, r *http.Request) {
-			logger.Info("Login request received")
-			w.WriteHeader(http.StatusOK)
-			w.Write([]byte("Login request received"))
-		}))
-
-		logger.Info("✅ Auth module routes registered successfully", 
-			"endpoints", 1, 
-			"base_path", "/api/v1/auth")
-
-}
\ No newline at end of file
+	// Internal dependency injection for the auth module
+	repo := repositories.NewUserRepository(deps.GetDB(), logger)
+	loginEvents := repositories.NewLoginEventRepository(deps.GetDB())
+	service := NewAuthService(repo, loginEvents, deps.GetCache(), deps.GetCacheInvalidator(), deps.GetMailer(), logger, deps.GetTokenService(), deps.GetConfig())
+	throttle := newLoginThrottle(deps.GetCache(), deps.GetConfig().MaxFailedLoginsPerIP, deps.GetConfig().GetLoginIPWindow())
+	handler := NewAuthHandler(service, throttle, deps.GetTokenService(), deps.GetConfig().GetTrustedProxies(), logger)
+
+	// Get the HTTP multiplexer
+	mux := deps.Mux
+
+	// Authentication endpoints
+	mux.HandleFunc("POST /api/v1/auth/login", handler.Login)
+	mux.HandleFunc("POST /api/v1/auth/verify-email", handler.VerifyEmail)
+	mux.HandleFunc("POST /api/v1/auth/forgot-password", handler.ForgotPassword)
+	mux.HandleFunc("POST /api/v1/auth/reset-password", handler.ResetPassword)
+
+	// Public signing keys, for services verifying RS256 tokens
+	mux.HandleFunc("GET /.well-known/jwks.json", handler.JWKS)
+
+	authenticate := middleware.RequireAuth(deps.GetTokenService(), users.NewTokenVersions(repo, deps.GetCache(), logger), logger)
+
+	// Login history of an account, for the user and admins
+	selfOrAdmin := middleware.ChainFunc(
+		authenticate,
+		middleware.RequireSelfOrRole("id", models.RoleAdmin),
+	)
+	mux.Handle("GET /api/v1/users/{id}/login-history", selfOrAdmin(handler.GetLoginHistory))
+
+	// Admins acting as a user, for support
+	requireAdmin := middleware.ChainFunc(authenticate, middleware.RequireRole(models.RoleAdmin))
+	mux.Handle("POST /api/v1/users/{id}/impersonate", requireAdmin(handler.Impersonate))
+
+	logger.Info("✅ Auth module routes registered successfully",
+		"endpoints", 7,
+		"base_path", "/api/v1/auth")
+}
@@ -1,19 +1,408 @@
+// internal/modules/auth/service.go
+package auth
 
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
 
+	"go-template/internal/config"
+	"go-template/internal/interfaces"
+	"go-template/internal/models"
+	"go-template/internal/modules/users"
+	"go-template/internal/repositories"
+	"go-template/internal/shared/utils"
+)
+
+// Authentication errors
+var (
+	ErrInvalidCredentials = errors.New("invalid username or password")
+	ErrAccountLocked      = errors.New("account is locked due to too many failed login attempts")
+	ErrAccountInactive    = errors.New("account is inactive")
+)
+
+// ImpersonationExpiration is the lifetime of impersonation access tokens
+const ImpersonationExpiration = 15 * time.Minute
+
+// LockedError is returned when a login is attempted on a locked account
+// It carries the remaining lockout time so callers can advise clients when to retry
+type LockedError struct {
+	RetryAfter time.Duration
+}
+
+// Error implements the error interface
+func (e *LockedError) Error() string {
+	return ErrAccountLocked.Error()
+}
+
+// Unwrap allows errors.Is(err, ErrAccountLocked) to match
+func (e *LockedError) Unwrap() error {
+	return ErrAccountLocked
+}
+
+// AuthService handles business logic for authentication
 type AuthService struct {
-	repo repositories.UserRepositoryInterface
-	logger interfaces.LoggerInterface
+	repo            repositories.UserRepositoryInterface
+	loginEvents     repositories.LoginEventRepositoryInterface
+	cache           interfaces.CacheInterface
+	invalidator     interfaces.CacheInvalidator
+	mailer          interfaces.Mailer
+	logger          interfaces.LoggerInterface
+	tokens          *utils.TokenService
+	maxFailedLogins int
+	lockoutDuration time.Duration
+
+	verificationTokens *utils.ActionTokenStore
+	resetTokens        *utils.ActionTokenStore
 }
 
+// NewAuthService creates a new AuthService instance
 func NewAuthService(
-	repo repositories.UserRepositoryInterface, 
-	logger interfaces.LoggerInterface) *AuthService {
-	return &AuthService{repo: repo, logger: logger.With("service", "auth")}
+	repo repositories.UserRepositoryInterface,
+	loginEvents repositories.LoginEventRepositoryInterface,
+	cache interfaces.CacheInterface,
+	invalidator interfaces.CacheInvalidator,
+	mailer interfaces.Mailer,
+	logger interfaces.LoggerInterface,
+	tokens *utils.TokenService,
+	cfg *config.Config,
+) *AuthService {
+	return &AuthService{
+		repo:            repo,
+		loginEvents:     loginEvents,
+		cache:           cache,
+		invalidator:     invalidator,
+		mailer:          mailer,
+		logger:          logger.With("service", "auth"),
+		tokens:          tokens,
+		maxFailedLogins: cfg.MaxFailedLogins,
+		lockoutDuration: cfg.GetLockoutDuration(),
+
+		verificationTokens: utils.NewEmailVerificationTokens(cache),
+		resetTokens:        utils.NewPasswordResetTokens(cache),
+	}
+}
+
+// Login authenticates a user by username or email and password
+// Locked accounts are rejected before the password is checked. Attempts on existing accounts
+// are added to their login history along with the client they came from.
+func (s *AuthService) Login(ctx context.Context, req *models.LoginRequest, client models.LoginClient) (*models.LoginResponse, error) {
+	s.logger.Info("Login request received", "username", req.Username)
+
+	// Validate request
+	if errs := req.Validate(); len(errs) > 0 {
+		s.logger.Warn("Login validation failed", "errors", errs)
+		return nil, fmt.Errorf("%w: %s", interfaces.ErrValidation, strings.Join(errs, ", "))
+	}
+
+	user, err := s.findUser(ctx, req.Username)
+	if err != nil {
+		if errors.Is(err, interfaces.ErrNotFound) {
+			s.logger.Warn("Login attempt for unknown user", "username", req.Username)
+			return nil, ErrInvalidCredentials
+		}
+		s.logger.Error("Failed to look up user for login", err)
+		return nil, fmt.Errorf("failed to look up user: %w", err)
+	}
+
+	// Enforce lockout before verifying the password
+	if remaining := user.LockoutRemaining(s.maxFailedLogins, s.lockoutDuration); remaining > 0 {
+		s.logger.Warn("Login attempt on locked account", "user_id", user.GetIDString(), "retry_after", remaining.String())
+		s.recordLoginEvent(ctx, user, client, false, models.LoginFailureAccountLocked)
+		return nil, &LockedError{RetryAfter: remaining}
+	}
+
+	if !user.CheckPassword(req.Password) {
+		if err := s.repo.RecordFailedLogin(ctx, user.GetIDString()); err != nil {
+			s.logger.Error("Failed to record failed login", err, "user_id", user.GetIDString())
+		}
+		s.logger.Warn("Invalid password provided", "user_id", user.GetIDString(), "failed_logins", user.FailedLogins+1)
+		s.recordLoginEvent(ctx, user, client, false, models.LoginFailureInvalidPassword)
+		return nil, ErrInvalidCredentials
+	}
+
+	if !user.IsActive {
+		s.logger.Warn("Login attempt on inactive account", "user_id", user.GetIDString())
+		s.recordLoginEvent(ctx, user, client, false, models.LoginFailureAccountInactive)
+		return nil, ErrAccountInactive
+	}
+
+	// Upgrade hashes produced by a previous algorithm or cost while the plain password is at hand
+	if rehashed, err := user.RehashPasswordIfNeeded(req.Password); err != nil {
+		s.logger.Warn("Failed to rehash password", "user_id", user.GetIDString(), "error", err.Error())
+	} else if rehashed {
+		if err := s.repo.Update(ctx, user.GetIDString(), map[string]interface{}{"password": user.Password}); err != nil {
+			s.logger.Error("Failed to store rehashed password", err, "user_id", user.GetIDString())
+		} else {
+			s.logger.Info("Password rehashed with current settings", "user_id", user.GetIDString())
+		}
+	}
+
+	// Successful login resets the failed counters
+	if user.FailedLogins > 0 || user.LastFailedAt != nil {
+		if err := s.repo.ResetFailedLogins(ctx, user.GetIDString()); err != nil {
+			s.logger.Error("Failed to reset failed logins", err, "user_id", user.GetIDString())
+		}
+	}
+
+	if err := s.repo.UpdateLastLogin(ctx, user.GetIDString()); err != nil {
+		s.logger.Error("Failed to update last login", err, "user_id", user.GetIDString())
+	}
+	if err := s.repo.IncrementLoginCount(ctx, user.GetIDString()); err != nil {
+		s.logger.Error("Failed to increment login count", err, "user_id", user.GetIDString())
+	}
+	user.RecordLogin()
+	s.recordLoginEvent(ctx, user, client, true, "")
+
+	loginResponse, err := s.issueTokens(user, req.RememberMe)
+	if err != nil {
+		s.logger.Error("Failed to issue tokens", err, "user_id", user.GetIDString())
+		return nil, fmt.Errorf("failed to issue tokens: %w", err)
+	}
+
+	s.logger.Info("User logged in successfully", "user_id", user.GetIDString(), "remember_me", req.RememberMe)
+	return loginResponse, nil
+}
+
+// GetLoginHistory returns the recent login attempts on a user's account, newest first
+func (s *AuthService) GetLoginHistory(ctx context.Context, userID string) ([]models.LoginEvent, error) {
+	if _, err := s.repo.GetByID(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	events, err := s.loginEvents.Recent(ctx, userID)
+	if err != nil {
+		s.logger.Error("Failed to get login history", err, "user_id", userID)
+		return nil, fmt.Errorf("failed to get login history: %w", err)
+	}
+	return events, nil
+}
+
+// VerifyEmail consumes an email verification token and marks its user as verified
+// Unknown, expired and already used tokens all return utils.ErrInvalidActionToken.
+func (s *AuthService) VerifyEmail(ctx context.Context, req *models.VerifyEmailRequest) error {
+	// Validate request
+	if errs := req.Validate(); len(errs) > 0 {
+		s.logger.Warn("Verify email validation failed", "errors", errs)
+		return fmt.Errorf("%w: %s", interfaces.ErrValidation, strings.Join(errs, ", "))
+	}
+
+	userID, err := s.verificationTokens.Consume(ctx, req.Token)
+	if err != nil {
+		s.logger.Warn("Invalid email verification token")
+		return err
+	}
+
+	user, err := s.repo.GetByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, interfaces.ErrNotFound) {
+			s.logger.Warn("Verification token issued to missing user", "user_id", userID)
+			return utils.ErrInvalidActionToken
+		}
+		s.logger.Error("Failed to look up user for email verification", err, "user_id", userID)
+		return fmt.Errorf("failed to look up user: %w", err)
+	}
+
+	if !user.IsVerified {
+		if err := s.repo.MarkAsVerified(ctx, userID); err != nil {
+			s.logger.Error("Failed to verify user", err, "user_id", userID)
+			return fmt.Errorf("failed to verify user: %w", err)
+		}
+	}
+
+	s.invalidateUserCaches(ctx, user)
+	if err := s.invalidator.Invalidate(ctx, users.CacheKeyUserStats); err != nil {
+		s.logger.Error("Failed to invalidate user stats cache", err)
+	}
+
+	s.logger.Info("Email verified successfully", "user_id", userID)
+	return nil
+}
+
+// ForgotPassword emails a password reset token if an account exists for the address
+// Failures after validation are logged rather than returned so callers cannot
+// distinguish registered from unknown addresses.
+func (s *AuthService) ForgotPassword(ctx context.Context, req *models.ForgotPasswordRequest) error {
+	// Validate request
+	if errs := req.Validate(); len(errs) > 0 {
+		s.logger.Warn("Forgot password validation failed", "errors", errs)
+		return fmt.Errorf("%w: %s", interfaces.ErrValidation, strings.Join(errs, ", "))
+	}
+
+	user, err := s.repo.GetByEmail(ctx, req.Email)
+	if err != nil {
+		if errors.Is(err, interfaces.ErrNotFound) {
+			s.logger.Info("Password reset requested for unknown email")
+		} else {
+			s.logger.Error("Failed to look up user for password reset", err)
+		}
+		return nil
+	}
+
+	if !user.IsActive {
+		s.logger.Warn("Password reset requested for inactive account", "user_id", user.GetIDString())
+		return nil
+	}
+
+	token, err := s.resetTokens.Issue(ctx, user.GetIDString())
+	if err != nil {
+		s.logger.Error("Failed to issue password reset token", err, "user_id", user.GetIDString())
+		return nil
+	}
+
+	body := fmt.Sprintf(
+		"Hi %s,\n\nUse the following token to reset your password:\n\n%s\n\nThe token expires in %s. If you did not request a reset, you can ignore this email.\n",
+		user.GetFullName(), token, s.resetTokens.TTL(),
+	)
+	if err := s.mailer.Send(ctx, user.Email, "Reset your password", body); err != nil {
+		s.logger.Error("Failed to send password reset email", err, "user_id", user.GetIDString())
+		return nil
+	}
+
+	s.logger.Info("Password reset email sent", "user_id", user.GetIDString())
+	return nil
+}
+
+// ResetPassword consumes a password reset token and sets the user's new password
+// The request is validated before the token is consumed so a weak password does not burn it.
+// Unknown, expired and already used tokens all return utils.ErrInvalidActionToken.
+func (s *AuthService) ResetPassword(ctx context.Context, req *models.ResetPasswordRequest) error {
+	// Validate request
+	if errs := req.Validate(); len(errs) > 0 {
+		s.logger.Warn("Reset password validation failed", "errors", errs)
+		return fmt.Errorf("%w: %s", interfaces.ErrValidation, strings.Join(errs, ", "))
+	}
+
+	userID, err := s.resetTokens.Consume(ctx, req.Token)
+	if err != nil {
+		s.logger.Warn("Invalid password reset token")
+		return err
+	}
+
+	user, err := s.repo.GetByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, interfaces.ErrNotFound) {
+			s.logger.Warn("Password reset token issued to missing user", "user_id", userID)
+			return utils.ErrInvalidActionToken
+		}
+		s.logger.Error("Failed to look up user for password reset", err, "user_id", userID)
+		return fmt.Errorf("failed to look up user: %w", err)
+	}
+
+	if err := user.SetPassword(req.NewPassword); err != nil {
+		s.logger.Error("Failed to set new password", err, "user_id", userID)
+		return fmt.Errorf("failed to set new password: %w", err)
+	}
+
+	if err := s.repo.Update(ctx, userID, map[string]interface{}{"password": user.Password}); err != nil {
+		s.logger.Error("Failed to update password in database", err, "user_id", userID)
+		return fmt.Errorf("failed to update password: %w", err)
+	}
+
+	// Proving control of the email also lifts any lockout
+	if user.FailedLogins > 0 || user.LastFailedAt != nil {
+		if err := s.repo.ResetFailedLogins(ctx, userID); err != nil {
+			s.logger.Error("Failed to reset failed logins", err, "user_id", userID)
+		}
+	}
+
+	// Whoever knew the old password may still hold tokens
+	if err := s.repo.IncrementTokenVersion(ctx, userID); err != nil {
+		s.logger.Error("Failed to revoke tokens after password reset", err, "user_id", userID)
+		return fmt.Errorf("failed to revoke existing sessions: %w", err)
+	}
+
+	s.invalidateUserCaches(ctx, user)
+
+	s.logger.Info("Password reset successfully", "user_id", userID)
+	return nil
+}
+
+// recordLoginEvent adds a login attempt to the user's history
+// Failures are logged only; the audit trail must not block logins.
+func (s *AuthService) recordLoginEvent(ctx context.Context, user *models.User, client models.LoginClient, success bool, reason string) {
+	event := models.NewLoginEvent(client, success, reason)
+	if err := s.loginEvents.Append(ctx, user.GetIDString(), event); err != nil {
+		s.logger.Error("Failed to record login event", err, "user_id", user.GetIDString())
+	}
+}
+
+// invalidateUserCaches removes the cached copies of a user kept by the users module on every instance
+func (s *AuthService) invalidateUserCaches(ctx context.Context, user *models.User) {
+	keys := []string{
+		fmt.Sprintf(users.CacheKeyUser, user.GetIDString()),
+		fmt.Sprintf(users.CacheKeyUserByEmail, user.Email),
+		fmt.Sprintf(users.CacheKeyUserUsername, user.Username),
+		fmt.Sprintf(users.CacheKeyUserTokenVersion, user.GetIDString()),
+	}
+
+	if err := s.invalidator.Invalidate(ctx, keys...); err != nil {
+		s.logger.Error("Failed to invalidate user cache", err, "user_id", user.GetIDString())
+	}
+}
+
+// Impersonate issues a short-lived access token letting an admin act as another user
+// Every request made with the token is logged with both IDs by the auth middleware.
+func (s *AuthService) Impersonate(ctx context.Context, adminID, userID string) (*models.ImpersonationResponse, error) {
+	if adminID == userID {
+		return nil, fmt.Errorf("%w: cannot impersonate yourself", interfaces.ErrValidation)
+	}
+
+	user, err := s.repo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !user.IsActive {
+		return nil, fmt.Errorf("%w: cannot impersonate an inactive user", interfaces.ErrInvalidState)
+	}
+
+	token, err := s.tokens.GenerateImpersonationToken(user.GetIDString(), user.Roles, user.TokenVersion, adminID, ImpersonationExpiration)
+	if err != nil {
+		s.logger.Error("Failed to generate impersonation token", err, "admin_id", adminID, "user_id", userID)
+		return nil, fmt.Errorf("failed to generate impersonation token: %w", err)
+	}
+
+	s.logger.Info("Impersonation started", "admin_id", adminID, "user_id", userID, "expires_in", ImpersonationExpiration)
+
+	return &models.ImpersonationResponse{
+		AccessToken:    token,
+		TokenType:      "Bearer",
+		ExpiresIn:      int(ImpersonationExpiration.Seconds()),
+		ImpersonatedBy: adminID,
+		User:           user.ToUserResponse(),
+	}, nil
 }
 
+// issueTokens generates an access and refresh token pair for a user
+// rememberMe extends the refresh token lifetime; the access token lifetime is unchanged
+func (s *AuthService) issueTokens(user *models.User, rememberMe bool) (*models.LoginResponse, error) {
+	accessToken, err := s.tokens.GenerateAccessToken(user.GetIDString(), user.Roles, user.TokenVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := s.tokens.GenerateRefreshToken(user.GetIDString(), user.TokenVersion, rememberMe)
+	if err != nil {
+		return nil, err
+	}
 
-func (s *AuthService) Login(ctx context.Context, req *models.LoginRequest) (*models.User, error) {
-	s.logger.Info("Login request received", "email", req.Email)
+	return &models.LoginResponse{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(s.tokens.AccessExpiration().Seconds()),
+		RefreshExpiresIn: int(s.tokens.RefreshExpiration(rememberMe).Seconds()),
+		User:         user.ToUserResponse(),
+	}, nil
+}
 
-	
-}
\ No newline at end of file
+// findUser resolves the login identifier as an email or a username
+func (s *AuthService) findUser(ctx context.Context, identifier string) (*models.User, error) {
+	identifier = strings.ToLower(strings.TrimSpace(identifier))
+	if strings.Contains(identifier, "@") {
+		return s.repo.GetByEmail(ctx, identifier)
+	}
+	return s.repo.GetByUsername(ctx, models.NormalizeUsername(identifier))
+}
@@ -0,0 +1,640 @@
+// internal/modules/auth/service_test.go
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"regexp"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"go-template/internal/config"
+	"go-template/internal/database"
+	"go-template/internal/interfaces"
+	"go-template/internal/models"
+	"go-template/internal/modules/users"
+	"go-template/internal/repositories"
+	"go-template/internal/shared/logtest"
+	"go-template/internal/shared/mail"
+	"go-template/internal/shared/utils"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestMain(m *testing.M) {
+	// Hash test passwords at the lowest cost so repeated logins stay fast
+	utils.SetDefaultPasswordService(utils.NewPasswordServiceWithCost(bcrypt.MinCost))
+	os.Exit(m.Run())
+}
+
+// memoryLoginEvents is an in-memory LoginEventRepositoryInterface
+type memoryLoginEvents struct {
+	mu     sync.Mutex
+	events map[string][]models.LoginEvent
+}
+
+func (m *memoryLoginEvents) Append(ctx context.Context, userID string, event models.LoginEvent) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.events == nil {
+		m.events = map[string][]models.LoginEvent{}
+	}
+	events := append([]models.LoginEvent{event}, m.events[userID]...)
+	if len(events) > models.MaxLoginEvents {
+		events = events[:models.MaxLoginEvents]
+	}
+	m.events[userID] = events
+	return nil
+}
+
+func (m *memoryLoginEvents) Recent(ctx context.Context, userID string) ([]models.LoginEvent, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]models.LoginEvent{}, m.events[userID]...), nil
+}
+
+// testAuth bundles an AuthService with the doubles it was built from
+type testAuth struct {
+	service *AuthService
+	repo    *repositories.MemoryUserRepository
+	cache   *database.MemoryCache
+	mailer  *mail.MemoryMailer
+	events  *memoryLoginEvents
+	tokens  *utils.TokenService
+	logger  *logtest.Logger
+	clock   *utils.FixedClock
+}
+
+// newTestAuth builds an AuthService over in-memory doubles with a fixed clock
+// Lockout triggers after 5 failed logins and lasts 30 minutes.
+func newTestAuth(t *testing.T) *testAuth {
+	t.Helper()
+
+	clock := utils.NewFixedClock(time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC))
+	utils.SetClock(clock)
+	t.Cleanup(func() { utils.SetClock(nil) })
+
+	logger := logtest.New()
+	cache := database.NewMemoryCache()
+	t.Cleanup(func() { cache.Close() })
+
+	ta := &testAuth{
+		repo:   repositories.NewMemoryUserRepository(),
+		cache:  cache,
+		mailer: mail.NewMemoryMailer(),
+		events: &memoryLoginEvents{},
+		tokens: utils.NewTokenService("test-secret-test-secret-test-secret", time.Hour, 24*time.Hour, 30*24*time.Hour),
+		logger: logger,
+		clock:  clock,
+	}
+	cfg := &config.Config{MaxFailedLogins: 5, LockoutDurationMinutes: 30}
+	ta.service = NewAuthService(ta.repo, ta.events, cache, database.NewInvalidator(cache, logger),
+		ta.mailer, logger, ta.tokens, cfg)
+	return ta
+}
+
+// createUser stores a test user and returns it
+func (ta *testAuth) createUser(t *testing.T, opts ...models.TestUserOption) *models.User {
+	t.Helper()
+	user := models.NewTestUser(opts...)
+	if err := ta.repo.Create(context.Background(), user); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	return user
+}
+
+// login attempts a login with the given password
+func (ta *testAuth) login(username, password string) (*models.LoginResponse, error) {
+	return ta.service.Login(context.Background(),
+		&models.LoginRequest{Username: username, Password: password},
+		models.LoginClient{IP: "192.0.2.1", UserAgent: "test"})
+}
+
+func TestLoginLockout(t *testing.T) {
+	tests := []struct {
+		name       string
+		failures   int
+		advance    time.Duration
+		password   string
+		wantErr    error
+		wantRetry  time.Duration
+		wantFailed int
+	}{
+		{
+			name:       "below threshold checks the password",
+			failures:   4,
+			password:   models.TestUserPassword,
+			wantFailed: 0,
+		},
+		{
+			name:       "below threshold wrong password counts",
+			failures:   4,
+			password:   "wrong-password",
+			wantErr:    ErrInvalidCredentials,
+			wantFailed: 5,
+		},
+		{
+			name:       "locked account rejects the correct password",
+			failures:   5,
+			password:   models.TestUserPassword,
+			wantErr:    ErrAccountLocked,
+			wantRetry:  30 * time.Minute,
+			wantFailed: 5,
+		},
+		{
+			name:       "locked account reports the remaining time",
+			failures:   5,
+			advance:    20 * time.Minute,
+			password:   models.TestUserPassword,
+			wantErr:    ErrAccountLocked,
+			wantRetry:  10 * time.Minute,
+			wantFailed: 5,
+		},
+		{
+			name:       "locked account does not count further attempts",
+			failures:   5,
+			password:   "wrong-password",
+			wantErr:    ErrAccountLocked,
+			wantRetry:  30 * time.Minute,
+			wantFailed: 5,
+		},
+		{
+			name:       "lock expires after the lockout duration",
+			failures:   5,
+			advance:    30 * time.Minute,
+			password:   models.TestUserPassword,
+			wantFailed: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ta := newTestAuth(t)
+			user := ta.createUser(t)
+
+			for i := 0; i < tt.failures; i++ {
+				if _, err := ta.login(user.Username, "wrong-password"); !errors.Is(err, ErrInvalidCredentials) {
+					t.Fatalf("failed login %d error = %v, want ErrInvalidCredentials", i+1, err)
+				}
+			}
+			ta.clock.Advance(tt.advance)
+
+			resp, err := ta.login(user.Username, tt.password)
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Fatalf("Login() error = %v", err)
+				}
+				if resp.AccessToken == "" {
+					t.Error("Login() returned no access token")
+				}
+			} else if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Login() error = %v, want %v", err, tt.wantErr)
+			}
+
+			var lockedErr *LockedError
+			if errors.As(err, &lockedErr) != (tt.wantRetry > 0) {
+				t.Fatalf("Login() error = %v, want LockedError: %v", err, tt.wantRetry > 0)
+			}
+			if lockedErr != nil && lockedErr.RetryAfter != tt.wantRetry {
+				t.Errorf("RetryAfter = %v, want %v", lockedErr.RetryAfter, tt.wantRetry)
+			}
+
+			stored, err := ta.repo.GetByID(context.Background(), user.GetIDString())
+			if err != nil {
+				t.Fatalf("GetByID() error = %v", err)
+			}
+			if stored.FailedLogins != tt.wantFailed {
+				t.Errorf("FailedLogins = %d, want %d", stored.FailedLogins, tt.wantFailed)
+			}
+		})
+	}
+}
+
+func TestLoginLockoutRecordsEvent(t *testing.T) {
+	ta := newTestAuth(t)
+	user := ta.createUser(t)
+
+	for i := 0; i < 5; i++ {
+		ta.login(user.Username, "wrong-password")
+	}
+	if _, err := ta.login(user.Username, models.TestUserPassword); !errors.Is(err, ErrAccountLocked) {
+		t.Fatalf("Login() error = %v, want ErrAccountLocked", err)
+	}
+
+	events, _ := ta.events.Recent(context.Background(), user.GetIDString())
+	if len(events) != 6 {
+		t.Fatalf("recorded %d login events, want 6", len(events))
+	}
+	if events[0].Success || events[0].Reason != models.LoginFailureAccountLocked {
+		t.Errorf("latest event = %+v, want a failed %q attempt", events[0], models.LoginFailureAccountLocked)
+	}
+}
+
+func TestVerifyEmail(t *testing.T) {
+	tests := []struct {
+		name string
+		// token prepares the token to verify with for userID
+		token        func(t *testing.T, ta *testAuth, userID string) string
+		wantErr      error
+		wantVerified bool
+	}{
+		{
+			name: "valid token",
+			token: func(t *testing.T, ta *testAuth, userID string) string {
+				return issueToken(t, utils.NewEmailVerificationTokens(ta.cache), userID)
+			},
+			wantVerified: true,
+		},
+		{
+			name: "expired token",
+			token: func(t *testing.T, ta *testAuth, userID string) string {
+				store := utils.NewActionTokenStore(ta.cache, utils.PurposeEmailVerification, time.Millisecond)
+				token := issueToken(t, store, userID)
+				time.Sleep(10 * time.Millisecond)
+				return token
+			},
+			wantErr: utils.ErrInvalidActionToken,
+		},
+		{
+			name: "reused token",
+			token: func(t *testing.T, ta *testAuth, userID string) string {
+				token := issueToken(t, utils.NewEmailVerificationTokens(ta.cache), userID)
+				if err := ta.service.VerifyEmail(context.Background(), &models.VerifyEmailRequest{Token: token}); err != nil {
+					t.Fatalf("first VerifyEmail() error = %v", err)
+				}
+				// Undo the first use so only the token decides the outcome
+				ta.repo.Update(context.Background(), userID, map[string]interface{}{"is_verified": false})
+				return token
+			},
+			wantErr: utils.ErrInvalidActionToken,
+		},
+		{
+			name: "token for another purpose",
+			token: func(t *testing.T, ta *testAuth, userID string) string {
+				return issueToken(t, utils.NewPasswordResetTokens(ta.cache), userID)
+			},
+			wantErr: utils.ErrInvalidActionToken,
+		},
+		{
+			name: "empty token",
+			token: func(t *testing.T, ta *testAuth, userID string) string {
+				return " "
+			},
+			wantErr: interfaces.ErrValidation,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ta := newTestAuth(t)
+			user := ta.createUser(t, models.WithVerified(false))
+			token := tt.token(t, ta, user.GetIDString())
+
+			err := ta.service.VerifyEmail(context.Background(), &models.VerifyEmailRequest{Token: token})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("VerifyEmail() error = %v, want %v", err, tt.wantErr)
+			}
+
+			stored, err := ta.repo.GetByID(context.Background(), user.GetIDString())
+			if err != nil {
+				t.Fatalf("GetByID() error = %v", err)
+			}
+			if stored.IsVerified != tt.wantVerified {
+				t.Errorf("IsVerified = %v, want %v", stored.IsVerified, tt.wantVerified)
+			}
+		})
+	}
+}
+
+// issueToken issues a token for userID from store
+func issueToken(t *testing.T, store *utils.ActionTokenStore, userID string) string {
+	t.Helper()
+	token, err := store.Issue(context.Background(), userID)
+	if err != nil {
+		t.Fatalf("Issue() error = %v", err)
+	}
+	return token
+}
+
+func TestForgotPassword(t *testing.T) {
+	tests := []struct {
+		name      string
+		email     string
+		opts      []models.TestUserOption
+		wantMails int
+	}{
+		{name: "registered email", email: "reset@example.com", wantMails: 1},
+		{name: "email is normalized", email: " Reset@Example.com ", wantMails: 1},
+		{name: "unknown email", email: "nobody@example.com"},
+		{name: "inactive account", email: "reset@example.com", opts: []models.TestUserOption{models.WithActive(false)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ta := newTestAuth(t)
+			ta.createUser(t, append([]models.TestUserOption{models.WithEmail("reset@example.com")}, tt.opts...)...)
+
+			// Unknown addresses succeed too so callers cannot enumerate accounts
+			if err := ta.service.ForgotPassword(context.Background(), &models.ForgotPasswordRequest{Email: tt.email}); err != nil {
+				t.Fatalf("ForgotPassword() error = %v", err)
+			}
+			sent := ta.mailer.Sent()
+			if len(sent) != tt.wantMails {
+				t.Fatalf("sent %d emails, want %d", len(sent), tt.wantMails)
+			}
+			if tt.wantMails > 0 && sent[0].To != "reset@example.com" {
+				t.Errorf("email sent to %q, want %q", sent[0].To, "reset@example.com")
+			}
+		})
+	}
+}
+
+func TestResetPassword(t *testing.T) {
+	const newPassword = "NewSecurePassword456"
+
+	tests := []struct {
+		name string
+		// token prepares the reset token for userID
+		token       func(t *testing.T, ta *testAuth, userID string) string
+		newPassword string
+		wantErr     error
+		// wantReusable reports whether the token must still work after the call
+		wantReusable bool
+	}{
+		{
+			name:        "valid token",
+			token:       mailedResetToken,
+			newPassword: newPassword,
+		},
+		{
+			name: "expired token",
+			token: func(t *testing.T, ta *testAuth, userID string) string {
+				store := utils.NewActionTokenStore(ta.cache, utils.PurposePasswordReset, time.Millisecond)
+				token := issueToken(t, store, userID)
+				time.Sleep(10 * time.Millisecond)
+				return token
+			},
+			newPassword: newPassword,
+			wantErr:     utils.ErrInvalidActionToken,
+		},
+		{
+			name:         "weak new password keeps the token",
+			token:        mailedResetToken,
+			newPassword:  "short",
+			wantErr:      interfaces.ErrValidation,
+			wantReusable: true,
+		},
+		{
+			name: "verification token",
+			token: func(t *testing.T, ta *testAuth, userID string) string {
+				return issueToken(t, utils.NewEmailVerificationTokens(ta.cache), userID)
+			},
+			newPassword: newPassword,
+			wantErr:     utils.ErrInvalidActionToken,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ta := newTestAuth(t)
+			ctx := context.Background()
+			user := ta.createUser(t, models.WithEmail("reset@example.com"))
+			token := tt.token(t, ta, user.GetIDString())
+
+			err := ta.service.ResetPassword(ctx, &models.ResetPasswordRequest{
+				Token:           token,
+				NewPassword:     tt.newPassword,
+				ConfirmPassword: tt.newPassword,
+			})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ResetPassword() error = %v, want %v", err, tt.wantErr)
+			}
+
+			stored, err := ta.repo.GetByID(ctx, user.GetIDString())
+			if err != nil {
+				t.Fatalf("GetByID() error = %v", err)
+			}
+			if changed := stored.Password != user.Password; changed != (tt.wantErr == nil) {
+				t.Fatalf("password changed = %v, want %v", changed, tt.wantErr == nil)
+			}
+			if tt.wantErr == nil {
+				if !stored.CheckPassword(newPassword) {
+					t.Error("new password does not verify")
+				}
+				if stored.TokenVersion == user.TokenVersion {
+					t.Error("TokenVersion unchanged, want existing sessions revoked")
+				}
+			}
+
+			_, err = utils.NewPasswordResetTokens(ta.cache).Consume(ctx, token)
+			if reusable := err == nil; reusable != tt.wantReusable {
+				t.Errorf("token reusable = %v, want %v", reusable, tt.wantReusable)
+			}
+		})
+	}
+}
+
+func TestResetPasswordInvalidatesUserCache(t *testing.T) {
+	ta := newTestAuth(t)
+	ctx := context.Background()
+	user := ta.createUser(t, models.WithEmail("reset@example.com"))
+	key := fmt.Sprintf(users.CacheKeyUser, user.GetIDString())
+	if err := ta.cache.Set(ctx, key, "cached", time.Hour); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+
+	err := ta.service.ResetPassword(ctx, &models.ResetPasswordRequest{
+		Token:           mailedResetToken(t, ta, user.GetIDString()),
+		NewPassword:     "NewSecurePassword456",
+		ConfirmPassword: "NewSecurePassword456",
+	})
+	if err != nil {
+		t.Fatalf("ResetPassword() error = %v", err)
+	}
+	if exists, _ := ta.cache.Exists(ctx, key); exists {
+		t.Errorf("cache key %q still set after a password reset", key)
+	}
+}
+
+// mailedResetToken requests a password reset and returns the token from the email
+func mailedResetToken(t *testing.T, ta *testAuth, userID string) string {
+	t.Helper()
+	user, err := ta.repo.GetByID(context.Background(), userID)
+	if err != nil {
+		t.Fatalf("GetByID() error = %v", err)
+	}
+	if err := ta.service.ForgotPassword(context.Background(), &models.ForgotPasswordRequest{Email: user.Email}); err != nil {
+		t.Fatalf("ForgotPassword() error = %v", err)
+	}
+	sent := ta.mailer.Sent()
+	if len(sent) == 0 {
+		t.Fatal("no reset email sent")
+	}
+	token := regexp.MustCompile(`[0-9a-f]{64}`).FindString(sent[len(sent)-1].Body)
+	if token == "" {
+		t.Fatalf("no token in email body %q", sent[len(sent)-1].Body)
+	}
+	return token
+}
+
+func TestLoginRecordsEvents(t *testing.T) {
+	tests := []struct {
+		name        string
+		opts        []models.TestUserOption
+		failures    int
+		username    string // defaults to the test user
+		password    string
+		wantEvents  int
+		wantSuccess bool
+		wantReason  string
+	}{
+		{name: "success", password: models.TestUserPassword, wantEvents: 1, wantSuccess: true},
+		{name: "wrong password", password: "wrong-password", wantEvents: 1, wantReason: models.LoginFailureInvalidPassword},
+		{name: "locked account", failures: 5, password: models.TestUserPassword, wantEvents: 6, wantReason: models.LoginFailureAccountLocked},
+		{name: "inactive account", opts: []models.TestUserOption{models.WithActive(false)}, password: models.TestUserPassword, wantEvents: 1, wantReason: models.LoginFailureAccountInactive},
+		{name: "unknown account", username: "nobody", password: models.TestUserPassword},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ta := newTestAuth(t)
+			user := ta.createUser(t, tt.opts...)
+			username := tt.username
+			if username == "" {
+				username = user.Username
+			}
+
+			for i := 0; i < tt.failures; i++ {
+				ta.login(username, "wrong-password")
+			}
+			ta.clock.Advance(time.Minute)
+			ta.service.Login(context.Background(),
+				&models.LoginRequest{Username: username, Password: tt.password},
+				models.LoginClient{IP: "203.0.113.7", UserAgent: "Mozilla/5.0"})
+
+			events, err := ta.service.GetLoginHistory(context.Background(), user.GetIDString())
+			if err != nil {
+				t.Fatalf("GetLoginHistory() error = %v", err)
+			}
+			if len(events) != tt.wantEvents {
+				t.Fatalf("recorded %d login events, want %d", len(events), tt.wantEvents)
+			}
+			if tt.wantEvents == 0 {
+				return
+			}
+
+			want := models.LoginEvent{
+				Timestamp: ta.clock.Now().UTC(),
+				IP:        "203.0.113.7",
+				UserAgent: "Mozilla/5.0",
+				Success:   tt.wantSuccess,
+				Reason:    tt.wantReason,
+			}
+			if events[0] != want {
+				t.Errorf("latest event = %+v, want %+v", events[0], want)
+			}
+		})
+	}
+}
+
+func TestGetLoginHistory(t *testing.T) {
+	ta := newTestAuth(t)
+	user := ta.createUser(t)
+	other := ta.createUser(t)
+
+	if events, err := ta.service.GetLoginHistory(context.Background(), user.GetIDString()); err != nil || events == nil || len(events) != 0 {
+		t.Errorf("GetLoginHistory() before any login = %v, %v, want an empty list", events, err)
+	}
+
+	ta.login(user.Username, "wrong-password")
+	ta.clock.Advance(time.Minute)
+	ta.login(user.Username, models.TestUserPassword)
+	ta.login(other.Username, models.TestUserPassword)
+
+	events, err := ta.service.GetLoginHistory(context.Background(), user.GetIDString())
+	if err != nil {
+		t.Fatalf("GetLoginHistory() error = %v", err)
+	}
+	if len(events) != 2 || !events[0].Success || events[1].Success || !events[0].Timestamp.After(events[1].Timestamp) {
+		t.Errorf("GetLoginHistory() = %+v, want the success then the failure, newest first", events)
+	}
+
+	if _, err := ta.service.GetLoginHistory(context.Background(), "507f1f77bcf86cd799439011"); !errors.Is(err, interfaces.ErrNotFound) {
+		t.Errorf("GetLoginHistory(unknown user) error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestImpersonate(t *testing.T) {
+	tests := []struct {
+		name    string
+		target  string // "user", "admin", "inactive", "deleted", "missing" or "self"
+		wantErr error
+	}{
+		{name: "active user", target: "user"},
+		{name: "another admin", target: "admin"},
+		{name: "yourself", target: "self", wantErr: interfaces.ErrValidation},
+		{name: "inactive user", target: "inactive", wantErr: interfaces.ErrInvalidState},
+		{name: "deleted user", target: "deleted", wantErr: interfaces.ErrNotFound},
+		{name: "unknown user", target: "missing", wantErr: interfaces.ErrNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			ta := newTestAuth(t)
+			admin := ta.createUser(t, models.WithRoles(models.RoleUser, models.RoleAdmin))
+			targets := map[string]*models.User{
+				"user":     ta.createUser(t),
+				"admin":    ta.createUser(t, models.WithRoles(models.RoleUser, models.RoleAdmin)),
+				"inactive": ta.createUser(t, models.WithActive(false)),
+				"deleted":  ta.createUser(t),
+				"self":     admin,
+			}
+			if err := ta.repo.SoftDelete(ctx, targets["deleted"].GetIDString()); err != nil {
+				t.Fatalf("SoftDelete() error = %v", err)
+			}
+			targetID := "507f1f77bcf86cd799439011"
+			target, ok := targets[tt.target]
+			if ok {
+				targetID = target.GetIDString()
+			}
+
+			result, err := ta.service.Impersonate(ctx, admin.GetIDString(), targetID)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Impersonate() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				if ta.logger.Has(slog.LevelInfo, "Impersonation started") {
+					t.Error("refused impersonation was logged as started")
+				}
+				return
+			}
+
+			if result.ImpersonatedBy != admin.GetIDString() || result.User.ID != targetID || result.TokenType != "Bearer" {
+				t.Errorf("Impersonate() = %+v, want a Bearer token for %s impersonated by %s", result, targetID, admin.GetIDString())
+			}
+			if result.ExpiresIn != int(ImpersonationExpiration.Seconds()) {
+				t.Errorf("expires_in = %d, want %d", result.ExpiresIn, int(ImpersonationExpiration.Seconds()))
+			}
+			claims, err := ta.tokens.ValidateToken(result.AccessToken)
+			if err != nil {
+				t.Fatalf("ValidateToken() error = %v", err)
+			}
+			// The token acts as the target user, roles included
+			if claims.Subject != targetID || claims.ImpersonatedBy != admin.GetIDString() || !slices.Equal(claims.Roles, target.Roles) {
+				t.Errorf("claims = %+v, want the target's subject and roles impersonated by %s", claims, admin.GetIDString())
+			}
+			if got := time.Duration(claims.ExpiresAt-claims.IssuedAt) * time.Second; got != ImpersonationExpiration {
+				t.Errorf("token lifetime = %v, want %v", got, ImpersonationExpiration)
+			}
+			if !ta.logger.Has(slog.LevelInfo, "Impersonation started") {
+				t.Error("impersonation was not logged")
+			}
+		})
+	}
+}
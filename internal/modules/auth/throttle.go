@@ -0,0 +1,87 @@
+// internal/modules/auth/throttle.go
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"go-template/internal/interfaces"
+)
+
+const cacheKeyLoginFailuresByIP = "auth:login:failures:ip:%s" // client IP
+
+// loginThrottle limits failed logins per client IP, whichever accounts they targeted
+// Account lockout stops guessing against one account; this stops one client from spraying
+// passwords across many. Failures are counted in a fixed window that starts with the first one.
+type loginThrottle struct {
+	cache       interfaces.CacheInterface
+	maxFailures int
+	window      time.Duration
+}
+
+// newLoginThrottle creates a throttle allowing maxFailures failed logins per IP per window
+// A non-positive maxFailures disables it.
+func newLoginThrottle(cache interfaces.CacheInterface, maxFailures int, window time.Duration) *loginThrottle {
+	return &loginThrottle{
+		cache:       cache,
+		maxFailures: maxFailures,
+		window:      window,
+	}
+}
+
+// retryAfter returns how long ip must wait before attempting another login, zero if it may now
+func (t *loginThrottle) retryAfter(ctx context.Context, ip string) (time.Duration, error) {
+	if t.maxFailures <= 0 {
+		return 0, nil
+	}
+
+	key := fmt.Sprintf(cacheKeyLoginFailuresByIP, ip)
+	value, err := t.cache.Get(ctx, key)
+	if err != nil {
+		if errors.Is(err, interfaces.ErrCacheMiss) {
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	failures, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid failed login count for %s: %w", ip, err)
+	}
+	if failures < int64(t.maxFailures) {
+		return 0, nil
+	}
+
+	// Fall back to the full window when the remaining time is unknown
+	ttl, err := t.cache.TTL(ctx, key)
+	if err != nil || ttl <= 0 {
+		return t.window, nil
+	}
+	return ttl, nil
+}
+
+// recordFailure counts a failed login from ip
+func (t *loginThrottle) recordFailure(ctx context.Context, ip string) error {
+	if t.maxFailures <= 0 {
+		return nil
+	}
+
+	key := fmt.Sprintf(cacheKeyLoginFailuresByIP, ip)
+	count, err := t.cache.Increment(ctx, key)
+	if err != nil {
+		return err
+	}
+	if count != 1 {
+		return nil
+	}
+
+	if err := t.cache.Expire(ctx, key, t.window); err != nil {
+		// Without an expiry the key would throttle the address forever
+		_ = t.cache.Delete(ctx, key)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+// internal/modules/auth/throttle_test.go
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+
+	"go-template/internal/database"
+	"go-template/internal/interfaces"
+)
+
+// failingCache fails every operation the throttle uses, as if Redis were down
+type failingCache struct {
+	interfaces.CacheInterface
+}
+
+func (failingCache) Get(ctx context.Context, key string) (string, error) {
+	return "", errors.New("redis unavailable")
+}
+
+func (failingCache) Increment(ctx context.Context, key string) (int64, error) {
+	return 0, errors.New("redis unavailable")
+}
+
+func TestLoginThrottle(t *testing.T) {
+	const ip = "192.0.2.1"
+
+	tests := []struct {
+		name          string
+		maxFailures   int
+		failures      int
+		elapsed       time.Duration // time passed after the last failure
+		wantThrottled bool
+	}{
+		{name: "no failures", maxFailures: 3},
+		{name: "below threshold", maxFailures: 3, failures: 2},
+		{name: "at threshold", maxFailures: 3, failures: 3, wantThrottled: true},
+		{name: "still within the window", maxFailures: 3, failures: 3, elapsed: 14 * time.Minute, wantThrottled: true},
+		{name: "window over", maxFailures: 3, failures: 3, elapsed: 15 * time.Minute},
+		{name: "disabled", maxFailures: 0, failures: 10},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			server := miniredis.RunT(t)
+			cache, err := database.ConnectRedis(server.Addr(), "", 0, 10, 0)
+			if err != nil {
+				t.Fatalf("ConnectRedis() error = %v", err)
+			}
+			t.Cleanup(func() { cache.Close() })
+			throttle := newLoginThrottle(cache, tt.maxFailures, 15*time.Minute)
+
+			for i := 0; i < tt.failures; i++ {
+				if err := throttle.recordFailure(ctx, ip); err != nil {
+					t.Fatalf("recordFailure() error = %v", err)
+				}
+			}
+			server.FastForward(tt.elapsed)
+
+			retryAfter, err := throttle.retryAfter(ctx, ip)
+			if err != nil {
+				t.Fatalf("retryAfter() error = %v", err)
+			}
+			if throttled := retryAfter > 0; throttled != tt.wantThrottled {
+				t.Fatalf("retryAfter() = %v, want throttled %v", retryAfter, tt.wantThrottled)
+			}
+			if tt.wantThrottled && retryAfter != 15*time.Minute-tt.elapsed {
+				t.Errorf("retryAfter() = %v, want the rest of the window %v", retryAfter, 15*time.Minute-tt.elapsed)
+			}
+			if other, _ := throttle.retryAfter(ctx, "198.51.100.7"); other != 0 {
+				t.Errorf("retryAfter(other IP) = %v, want 0", other)
+			}
+		})
+	}
+}
+
+func TestLoginThrottleCacheErrors(t *testing.T) {
+	ctx := context.Background()
+	throttle := newLoginThrottle(failingCache{}, 3, 15*time.Minute)
+
+	if retryAfter, err := throttle.retryAfter(ctx, "192.0.2.1"); err == nil || retryAfter != 0 {
+		t.Errorf("retryAfter() = %v, %v, want no throttling and the error", retryAfter, err)
+	}
+	if err := throttle.recordFailure(ctx, "192.0.2.1"); err == nil {
+		t.Error("recordFailure() error = nil, want the cache error")
+	}
+}
@@ -0,0 +1,83 @@
+package system
+
+import (
+	"net/http"
+	"sync/atomic"
+
+	"go-template/internal/interfaces"
+	"go-template/internal/models"
+	"go-template/internal/shared/middleware"
+	"go-template/internal/shared/response"
+)
+
+// SystemHandler handles HTTP requests for operating the service
+type SystemHandler struct {
+	maintenance *atomic.Bool
+	allowReads  bool
+	logger      interfaces.LoggerInterface
+}
+
+// NewSystemHandler creates a new SystemHandler instance
+// maintenance is the flag read by the Maintenance middleware; allowReads is only reported
+func NewSystemHandler(maintenance *atomic.Bool, allowReads bool, logger interfaces.LoggerInterface) *SystemHandler {
+	return &SystemHandler{
+		maintenance: maintenance,
+		allowReads:  allowReads,
+		logger:      logger.With("handler", "system"),
+	}
+}
+
+// GetMaintenance handles GET /api/v1/system/maintenance
+// @Summary Get maintenance mode
+// @Description Report whether maintenance mode is on and whether reads are still served. Admin only.
+// @Tags System
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.Response{data=models.MaintenanceStatus} "Maintenance mode status"
+// @Failure 401 {object} response.Response{error=response.ErrorInfo} "Missing or invalid access token"
+// @Failure 403 {object} response.Response{error=response.ErrorInfo} "Caller is not an admin"
+// @Router /api/v1/system/maintenance [get]
+func (h *SystemHandler) GetMaintenance(w http.ResponseWriter, r *http.Request) {
+	response.JSON(w, h.status(), http.StatusOK)
+}
+
+// SetMaintenance handles POST /api/v1/system/maintenance
+// @Summary Switch maintenance mode
+// @Description Turn maintenance mode on or off on this instance. While it is on, POST, PUT, PATCH and DELETE requests (and reads too unless MAINTENANCE_ALLOW_READS is set) are answered with 503 and a Retry-After header. This endpoint and the health probes are always served. The switch is not persisted: restarts go back to MAINTENANCE_MODE. Admin only.
+// @Tags System
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.MaintenanceRequest true "Whether maintenance mode should be on"
+// @Success 200 {object} response.Response{data=models.MaintenanceStatus} "Maintenance mode updated"
+// @Failure 400 {object} response.Response{error=response.ErrorInfo} "Missing enabled field or invalid request body"
+// @Failure 401 {object} response.Response{error=response.ErrorInfo} "Missing or invalid access token"
+// @Failure 403 {object} response.Response{error=response.ErrorInfo} "Caller is not an admin"
+// @Router /api/v1/system/maintenance [post]
+func (h *SystemHandler) SetMaintenance(w http.ResponseWriter, r *http.Request) {
+	var req models.MaintenanceRequest
+	if err := response.DecodeJSON(w, r, &req, response.DefaultMaxBodyBytes); err != nil {
+		h.logger.Warn("Invalid request body", "error", err.Error())
+		return
+	}
+	if req.Enabled == nil {
+		response.BadRequest(w, "enabled is required")
+		return
+	}
+
+	previous := h.maintenance.Swap(*req.Enabled)
+	if previous != *req.Enabled {
+		adminID, _ := middleware.UserIDFromContext(r.Context())
+		h.logger.Warn("Maintenance mode switched", "enabled", *req.Enabled, "admin_id", adminID)
+	}
+
+	response.JSONWithMessage(w, h.status(), "Maintenance mode updated", http.StatusOK)
+}
+
+// status returns the current maintenance mode status
+func (h *SystemHandler) status() models.MaintenanceStatus {
+	return models.MaintenanceStatus{
+		Enabled:    h.maintenance.Load(),
+		AllowReads: h.allowReads,
+	}
+}
@@ -0,0 +1,106 @@
+// internal/modules/system/handler_test.go
+package system
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"go-template/internal/models"
+	"go-template/internal/shared/logtest"
+	"go-template/internal/shared/middleware"
+	"go-template/internal/shared/response"
+	"go-template/internal/shared/utils"
+)
+
+func TestMaintenanceHandlers(t *testing.T) {
+	tests := []struct {
+		name        string
+		roles       []string
+		method      string
+		body        string
+		initially   bool
+		wantStatus  int
+		wantEnabled bool
+		wantLogged  bool
+	}{
+		{name: "get status", roles: []string{models.RoleAdmin}, method: http.MethodGet, initially: true, wantStatus: http.StatusOK, wantEnabled: true},
+		{name: "switch on", roles: []string{models.RoleAdmin}, method: http.MethodPost, body: `{"enabled":true}`, wantStatus: http.StatusOK, wantEnabled: true, wantLogged: true},
+		{name: "switch off", roles: []string{models.RoleAdmin}, method: http.MethodPost, body: `{"enabled":false}`, initially: true, wantStatus: http.StatusOK, wantLogged: true},
+		{name: "unchanged is not logged", roles: []string{models.RoleAdmin}, method: http.MethodPost, body: `{"enabled":true}`, initially: true, wantStatus: http.StatusOK, wantEnabled: true},
+		{name: "enabled is required", roles: []string{models.RoleAdmin}, method: http.MethodPost, body: `{}`, initially: true, wantStatus: http.StatusBadRequest, wantEnabled: true},
+		{name: "unknown field", roles: []string{models.RoleAdmin}, method: http.MethodPost, body: `{"enabled":false,"allow_reads":false}`, initially: true, wantStatus: http.StatusBadRequest, wantEnabled: true},
+		{name: "malformed body", roles: []string{models.RoleAdmin}, method: http.MethodPost, body: `{"enabled":`, wantStatus: http.StatusBadRequest},
+		{name: "users cannot switch it", roles: []string{models.RoleUser}, method: http.MethodPost, body: `{"enabled":true}`, wantStatus: http.StatusForbidden},
+		{name: "users cannot read it", roles: []string{models.RoleUser}, method: http.MethodGet, initially: true, wantStatus: http.StatusForbidden, wantEnabled: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			maintenance := new(atomic.Bool)
+			maintenance.Store(tt.initially)
+			logger := logtest.New()
+			h := NewSystemHandler(maintenance, true, logger)
+
+			requireAdmin := middleware.RequireRole(models.RoleAdmin)
+			mux := http.NewServeMux()
+			mux.Handle("GET "+MaintenancePath, requireAdmin(http.HandlerFunc(h.GetMaintenance)))
+			mux.Handle("POST "+MaintenancePath, requireAdmin(http.HandlerFunc(h.SetMaintenance)))
+
+			req := httptest.NewRequest(tt.method, MaintenancePath, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			req = req.WithContext(middleware.WithClaims(req.Context(), &utils.TokenClaims{Subject: "admin-1", Roles: tt.roles}))
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if got := maintenance.Load(); got != tt.wantEnabled {
+				t.Errorf("maintenance = %v, want %v", got, tt.wantEnabled)
+			}
+			if got := logger.Has(slog.LevelWarn, "Maintenance mode switched"); got != tt.wantLogged {
+				t.Errorf("switch logged = %v, want %v", got, tt.wantLogged)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var resp struct {
+				Data models.MaintenanceStatus `json:"data"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("invalid response body %q: %v", rec.Body.String(), err)
+			}
+			if want := (models.MaintenanceStatus{Enabled: tt.wantEnabled, AllowReads: true}); resp.Data != want {
+				t.Errorf("status = %+v, want %+v", resp.Data, want)
+			}
+		})
+	}
+}
+
+func TestMaintenanceEndpointIsExempt(t *testing.T) {
+	// The endpoint that switches maintenance mode off must stay reachable while it is on, even when reads are refused too
+	maintenance := new(atomic.Bool)
+	maintenance.Store(true)
+	h := NewSystemHandler(maintenance, false, logtest.New())
+	handler := middleware.Maintenance(maintenance, false, MaintenancePath)(http.HandlerFunc(h.SetMaintenance))
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, MaintenancePath, strings.NewReader(`{"enabled":false}`))
+	req.Header.Set("Content-Type", "application/json")
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK || maintenance.Load() {
+		t.Fatalf("status = %d, maintenance = %v, want maintenance switched off: %s", rec.Code, maintenance.Load(), rec.Body.String())
+	}
+
+	var resp response.Response
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil || !resp.Success {
+		t.Errorf("response = %+v (%v), want success", resp, err)
+	}
+}
@@ -0,0 +1,36 @@
+// internal/modules/system/routes.go
+package system
+
+import (
+	"go-template/internal/container"
+	"go-template/internal/models"
+	"go-template/internal/modules/users"
+	"go-template/internal/repositories"
+	"go-template/internal/shared/middleware"
+)
+
+// MaintenancePath is the maintenance mode endpoint, which must stay reachable in maintenance mode
+const MaintenancePath = "/api/v1/system/maintenance"
+
+// RegisterRoutes registers the admin endpoints for operating the service
+func RegisterRoutes(deps *container.Dependencies) {
+	logger := deps.GetLogger("system")
+	logger.Info("Registering system module routes")
+
+	handler := NewSystemHandler(deps.Maintenance, deps.GetConfig().MaintenanceAllowReads, logger)
+
+	// Admin-only; the token version check needs the user repository
+	versions := users.NewTokenVersions(repositories.NewUserRepository(deps.GetDB(), logger), deps.GetCache(), logger)
+	requireAdmin := middleware.ChainFunc(
+		middleware.RequireAuth(deps.GetTokenService(), versions, logger),
+		middleware.RequireRole(models.RoleAdmin),
+	)
+
+	mux := deps.Mux
+	mux.Handle("GET "+MaintenancePath, requireAdmin(handler.GetMaintenance))
+	mux.Handle("POST "+MaintenancePath, requireAdmin(handler.SetMaintenance))
+
+	logger.Info("✅ System module routes registered successfully",
+		"endpoints", 2,
+		"base_path", "/api/v1/system")
+}
@@ -0,0 +1,80 @@
+// internal/modules/users/deletion_hooks.go
+package users
+
+import (
+	"context"
+	"fmt"
+)
+
+// DeletionHook lets other modules react to a user being soft-deleted or restored
+// Hooks run asynchronously after the change is stored, so they cannot veto it; errors are logged.
+type DeletionHook interface {
+	// Name identifies the hook in logs
+	Name() string
+	OnUserDeleted(ctx context.Context, userID string) error
+	OnUserRestored(ctx context.Context, userID string) error
+}
+
+// RegisterDeletionHook adds a hook to run after every user soft delete and restore
+func (s *UserService) RegisterDeletionHook(hook DeletionHook) {
+	s.hooksMu.Lock()
+	defer s.hooksMu.Unlock()
+	
+	s.deletionHooks = append(s.deletionHooks, hook)
+}
+
+// runDeletionHooks runs the registered hooks for a user in the background
+// The request context is detached from cancellation so hooks outlive the request that triggered them.
+func (s *UserService) runDeletionHooks(ctx context.Context, userID string, restored bool) {
+	s.hooksMu.RLock()
+	hooks := append([]DeletionHook(nil), s.deletionHooks...)
+	s.hooksMu.RUnlock()
+	
+	if len(hooks) == 0 {
+		return
+	}
+	
+	ctx = context.WithoutCancel(ctx)
+	for _, hook := range hooks {
+		go func() {
+			var err error
+			if restored {
+				err = hook.OnUserRestored(ctx, userID)
+			} else {
+				err = hook.OnUserDeleted(ctx, userID)
+			}
+			if err != nil {
+				s.logger.Error("Deletion hook failed", err, "hook", hook.Name(), "user_id", userID, "restored", restored)
+			}
+		}()
+	}
+}
+
+// tokenRevocationHook stops a deleted user's tokens from authenticating
+type tokenRevocationHook struct {
+	service *UserService
+}
+
+// NewTokenRevocationHook creates a DeletionHook that revokes a user's tokens when they are deleted
+func NewTokenRevocationHook(service *UserService) DeletionHook {
+	return &tokenRevocationHook{service: service}
+}
+
+// Name identifies the hook in logs
+func (h *tokenRevocationHook) Name() string {
+	return "token_revocation"
+}
+
+// OnUserDeleted drops the cached token version, so the user's tokens fail their next
+// version lookup now that the user is deleted rather than when the cache entry expires
+func (h *tokenRevocationHook) OnUserDeleted(ctx context.Context, userID string) error {
+	if err := h.service.invalidator.Invalidate(ctx, fmt.Sprintf(CacheKeyUserTokenVersion, userID)); err != nil {
+		return fmt.Errorf("failed to invalidate token version: %w", err)
+	}
+	return nil
+}
+
+// OnUserRestored bumps the token version so tokens issued before the deletion stay revoked
+func (h *tokenRevocationHook) OnUserRestored(ctx context.Context, userID string) error {
+	return h.service.revokeTokens(ctx, userID)
+}
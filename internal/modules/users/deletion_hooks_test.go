@@ -0,0 +1,244 @@
+// internal/modules/users/deletion_hooks_test.go
+package users
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"testing"
+	"time"
+
+	"go-template/internal/interfaces"
+	"go-template/internal/models"
+)
+
+// hookCall is one invocation of a recordingHook
+type hookCall struct {
+	userID   string
+	restored bool
+	ctxErr   error // the context's error when the hook ran
+}
+
+// recordingHook is a DeletionHook that reports its calls on a channel and returns err
+type recordingHook struct {
+	calls chan hookCall
+	err   error
+}
+
+func newRecordingHook(err error) *recordingHook {
+	return &recordingHook{calls: make(chan hookCall, 10), err: err}
+}
+
+func (h *recordingHook) Name() string { return "recording" }
+
+func (h *recordingHook) OnUserDeleted(ctx context.Context, userID string) error {
+	h.calls <- hookCall{userID: userID, ctxErr: ctx.Err()}
+	return h.err
+}
+
+func (h *recordingHook) OnUserRestored(ctx context.Context, userID string) error {
+	h.calls <- hookCall{userID: userID, restored: true, ctxErr: ctx.Err()}
+	return h.err
+}
+
+// next waits for the hook's next call
+func (h *recordingHook) next(t *testing.T) hookCall {
+	t.Helper()
+	select {
+	case call := <-h.calls:
+		return call
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for the deletion hook")
+		return hookCall{}
+	}
+}
+
+// assertNoCall fails if the hook is called within a short grace period
+func (h *recordingHook) assertNoCall(t *testing.T) {
+	t.Helper()
+	select {
+	case call := <-h.calls:
+		t.Errorf("unexpected deletion hook call %+v", call)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestDeletionHooks(t *testing.T) {
+	tests := []struct {
+		name         string
+		act          func(ctx context.Context, tu *testUsers, users []*models.User) error
+		wantRestored bool
+		wantUsers    []int // indexes of the users the hook is called for
+	}{
+		{
+			name: "delete",
+			act: func(ctx context.Context, tu *testUsers, users []*models.User) error {
+				return tu.service.DeleteUser(ctx, users[0].GetIDString())
+			},
+			wantUsers: []int{0},
+		},
+		{
+			name: "delete by username",
+			act: func(ctx context.Context, tu *testUsers, users []*models.User) error {
+				return tu.service.DeleteUserByUsername(ctx, users[1].Username)
+			},
+			wantUsers: []int{1},
+		},
+		{
+			name: "bulk delete",
+			act: func(ctx context.Context, tu *testUsers, users []*models.User) error {
+				_, err := tu.service.BulkDeleteUsers(ctx, &models.BulkDeleteRequest{IDs: []string{users[0].GetIDString(), users[2].GetIDString()}})
+				return err
+			},
+			wantUsers: []int{0, 2},
+		},
+		{
+			name: "bulk delete dry run",
+			act: func(ctx context.Context, tu *testUsers, users []*models.User) error {
+				_, err := tu.service.BulkDeleteUsers(ctx, &models.BulkDeleteRequest{IDs: []string{users[0].GetIDString()}, DryRun: true})
+				return err
+			},
+		},
+		{
+			name: "restore",
+			act: func(ctx context.Context, tu *testUsers, users []*models.User) error {
+				if err := tu.repo.SoftDelete(ctx, users[1].GetIDString()); err != nil {
+					return err
+				}
+				_, err := tu.service.RestoreUser(ctx, users[1].GetIDString())
+				return err
+			},
+			wantRestored: true,
+			wantUsers:    []int{1},
+		},
+		{
+			name: "failed delete",
+			act: func(ctx context.Context, tu *testUsers, users []*models.User) error {
+				err := tu.service.DeleteUser(ctx, "507f1f77bcf86cd799439011")
+				if !errors.Is(err, interfaces.ErrNotFound) {
+					return fmt.Errorf("DeleteUser(missing) error = %v, want ErrNotFound", err)
+				}
+				return nil
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tu := newTestUsers(t)
+			users := []*models.User{tu.createUser(t), tu.createUser(t), tu.createUser(t)}
+			// Every registered hook runs
+			hooks := []*recordingHook{newRecordingHook(nil), newRecordingHook(nil)}
+			for _, hook := range hooks {
+				tu.service.RegisterDeletionHook(hook)
+			}
+
+			// Hooks outlive the request, so cancelling it right away does not reach them
+			ctx, cancel := context.WithCancel(context.Background())
+			err := tt.act(ctx, tu, users)
+			cancel()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var want []string
+			for _, i := range tt.wantUsers {
+				want = append(want, users[i].GetIDString())
+			}
+			for i, hook := range hooks {
+				var got []string
+				for range tt.wantUsers {
+					call := hook.next(t)
+					if call.restored != tt.wantRestored {
+						t.Errorf("hook %d: restored = %v, want %v", i, call.restored, tt.wantRestored)
+					}
+					if call.ctxErr != nil {
+						t.Errorf("hook %d: context error = %v, want a context detached from the request", i, call.ctxErr)
+					}
+					got = append(got, call.userID)
+				}
+				hook.assertNoCall(t)
+				slices.Sort(got)
+				slices.Sort(want)
+				if !slices.Equal(got, want) {
+					t.Errorf("hook %d called for %v, want %v", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestDeletionHookErrorsAreLogged(t *testing.T) {
+	tu := newTestUsers(t)
+	user := tu.createUser(t)
+	failing := newRecordingHook(errors.New("orders service unavailable"))
+	succeeding := newRecordingHook(nil)
+	tu.service.RegisterDeletionHook(failing)
+	tu.service.RegisterDeletionHook(succeeding)
+
+	// The hook cannot veto the delete
+	if err := tu.service.DeleteUser(context.Background(), user.GetIDString()); err != nil {
+		t.Fatalf("DeleteUser() error = %v", err)
+	}
+	failing.next(t)
+	if call := succeeding.next(t); call.userID != user.GetIDString() {
+		t.Errorf("other hook called for %q, want %q", call.userID, user.GetIDString())
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for !tu.logger.Has(slog.LevelError, "Deletion hook failed") {
+		if time.Now().After(deadline) {
+			t.Fatal("hook error was not logged")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if stored := tu.storedUser(t, user.GetIDString()); stored.DeletedAt == nil {
+		t.Error("user was not deleted")
+	}
+}
+
+func TestTokenRevocationHook(t *testing.T) {
+	ctx := context.Background()
+	tu := newTestUsers(t)
+	hook := NewTokenRevocationHook(tu.service)
+	user := tu.createUser(t)
+	id := user.GetIDString()
+	versions := NewTokenVersions(tu.repo, tu.cache, tu.logger)
+	versionKey := fmt.Sprintf(CacheKeyUserTokenVersion, id)
+
+	// Cache the version, as authenticating a request would
+	if _, err := versions.TokenVersion(ctx, id); err != nil {
+		t.Fatalf("TokenVersion() error = %v", err)
+	}
+
+	if err := tu.repo.SoftDelete(ctx, id); err != nil {
+		t.Fatalf("SoftDelete() error = %v", err)
+	}
+	if err := hook.OnUserDeleted(ctx, id); err != nil {
+		t.Fatalf("OnUserDeleted() error = %v", err)
+	}
+	// Without the cached version the deleted user's tokens fail the next lookup
+	if cached, err := tu.cache.Get(ctx, versionKey); !errors.Is(err, interfaces.ErrCacheMiss) {
+		t.Errorf("cached version = %q, %v, want it dropped", cached, err)
+	}
+	if _, err := versions.TokenVersion(ctx, id); !errors.Is(err, interfaces.ErrNotFound) {
+		t.Errorf("TokenVersion(deleted user) error = %v, want ErrNotFound", err)
+	}
+
+	if err := tu.repo.Restore(ctx, id); err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+	if err := hook.OnUserRestored(ctx, id); err != nil {
+		t.Fatalf("OnUserRestored() error = %v", err)
+	}
+	// Tokens issued before the deletion stay revoked after the restore
+	got, err := versions.TokenVersion(ctx, id)
+	if err != nil {
+		t.Fatalf("TokenVersion() error = %v", err)
+	}
+	if got != user.TokenVersion+1 {
+		t.Errorf("token version = %d, want %d", got, user.TokenVersion+1)
+	}
+}
@@ -1,45 +1,90 @@
 package users
 
 import (
+	"encoding/csv"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"go-template/internal/interfaces"
 	"go-template/internal/models"
+	"go-template/internal/repositories"
+	"go-template/internal/shared/middleware"
+	"go-template/internal/shared/request"
 	"go-template/internal/shared/response"
+	"go-template/internal/shared/utils"
 )
 
+// ExportPath is the streaming export endpoint, which must bypass response-buffering middleware
+const ExportPath = "/api/v1/users/export"
+
+const (
+	// exportFlushInterval is how many users are written between flushes of an export
+	exportFlushInterval = 100
+	
+	// exportWriteTimeout is how long the client has to accept each flushed chunk of an export
+	exportWriteTimeout = 30 * time.Second
+)
+
+// Date range limits for the signup series
+const (
+	defaultSignupDays = 30
+	maxSignupDays     = 366
+)
+
+// maxBulkBodyBytes bounds bulk import bodies, which carry up to MaxBulkCreateSize users
+const maxBulkBodyBytes int64 = 4 << 20 // 4MB
+
+// selfProtectedFields are fields a user may never change on their own account
+var selfProtectedFields = []string{"roles", "is_verified", "email_verified_at", "is_active"}
+
 // UserHandler handles HTTP requests for user operations
 type UserHandler struct {
-	service *UserService
-	logger  interfaces.LoggerInterface
+	service       *UserService
+	onlineWindow  time.Duration
+	profileMaxAge time.Duration
+	logger        interfaces.LoggerInterface
 }
 
 // NewUserHandler creates a new UserHandler instance
-func NewUserHandler(service *UserService, logger interfaces.LoggerInterface) *UserHandler {
+// onlineWindow is how recently a user must have been active to be reported as online;
+// profileMaxAge is how long shared caches may serve a public profile
+func NewUserHandler(service *UserService, onlineWindow, profileMaxAge time.Duration, logger interfaces.LoggerInterface) *UserHandler {
 	return &UserHandler{
-		service: service,
-		logger:  logger.With("handler", "users"),
+		service:       service,
+		onlineWindow:  onlineWindow,
+		profileMaxAge: profileMaxAge,
+		logger:        logger.With("handler", "users"),
 	}
 }
 
 // GetUsers handles GET /api/v1/users
 // @Summary Get all users
-// @Description Get all users with pagination and filtering options
+// @Description Get all users with pagination and filtering options. Send Accept: text/csv or ?format=csv to receive CSV instead of JSON.
 // @Tags Users
 // @Accept json
 // @Produce json
+// @Produce text/csv
+// @Param format query string false "Response format" default(json) Enums(json, csv)
 // @Param page query int false "Page number" default(1) minimum(1)
-// @Param limit query int false "Items per page" default(20) minimum(1) maximum(100)
+// @Param limit query int false "Items per page; the maximum is configured with MAX_PAGE_LIMIT" default(20) minimum(1) maximum(100)
 // @Param search query string false "Search in username, email, first_name, last_name"
 // @Param role query string false "Filter by role" Enums(user, admin, moderator)
 // @Param is_active query bool false "Filter by active status"
-// @Param sort_by query string false "Sort field" default(created_at) Enums(created_at, updated_at, username, email, first_name, last_name, login_count)
-// @Param sort_dir query string false "Sort direction" default(desc) Enums(asc, desc)
-// @Success 200 {object} response.Response{data=models.UserListResponse,meta=response.Meta} "List of users with pagination metadata"
+// @Param is_verified query bool false "Filter by email verification status"
+// @Param has_role query string false "Comma-separated roles the user must all hold" example(admin,moderator)
+// @Param created_after query string false "Only users created at or after this time (RFC 3339 or YYYY-MM-DD)"
+// @Param created_before query string false "Only users created before this time (RFC 3339 or YYYY-MM-DD)"
+// @Param fields query string false "Comma-separated fields to return; id is always included (password and other sensitive fields cannot be selected)" example(id,username)
+// @Param sort_by query string false "Comma-separated sort fields; prefix a field with - to sort it descending (allowed: created_at, updated_at, username, email, first_name, last_name, login_count)" default(-created_at) example(last_name,-created_at)
+// @Param sort_dir query string false "Direction for sort fields without a - prefix" default(asc) Enums(asc, desc)
+// @Success 200 {object} response.Response{data=[]models.UserResponse,meta=response.Meta} "List of users with pagination metadata"
+// @Header 200 {string} Link "Absolute URLs of the first, prev, next and last pages (RFC 8288); prev and next are omitted at the boundaries"
 // @Failure 400 {object} response.Response{error=response.ErrorInfo} "Invalid query parameters"
 // @Failure 500 {object} response.Response{error=response.ErrorInfo} "Internal server error"
 // @Router /api/v1/users [get]
@@ -57,45 +102,734 @@ func (h *UserHandler) GetUsers(w http.ResponseWriter, r *http.Request) {
 	// Get users from service
 	users, total, err := h.service.GetUsers(r.Context(), params)
 	if err != nil {
-		h.logger.Error("Failed to get users", err)
-		response.InternalServerError(w)
+		h.handleServiceError(w, "Failed to get users", err)
 		return
 	}
 	
 	// Convert to response DTOs
 	userResponses := make([]models.UserResponse, len(users))
 	for i, user := range users {
-		userResponses[i] = user.ToUserResponse()
+		userResponses[i] = toUserResponse(r, user)
 	}
 	
-	// Create response with metadata
-	userList := models.UserListResponse{
-		Users: userResponses,
-		Total: total,
-		Page:  params.Page,
-		Limit: params.Limit,
+	// Link headers let clients page through results in either format
+	response.SetPaginationLinks(w, r, params.Page, params.Limit, total)
+	
+	// CSV has no envelope, so pagination totals travel in a header
+	if response.NegotiateFormat(r) == response.FormatCSV {
+		headers, columns := selectCSVColumns(params.Fields)
+		rows := make([][]string, len(userResponses))
+		for i, userResponse := range userResponses {
+			row := userResponse.ToCSVRow()
+			rows[i] = make([]string, len(columns))
+			for j, column := range columns {
+				rows[i][j] = row[column]
+			}
+		}
+		w.Header().Set("X-Total-Count", strconv.Itoa(total))
+		response.CSV(w, headers, rows, http.StatusOK)
+		h.logger.Info("Users exported as CSV", "count", len(users), "total", total)
+		return
 	}
 	
-	// Create pagination metadata
-	meta := response.NewMeta(params.Page, params.Limit, total)
+	// Trim the output to the requested fields
+	if len(params.Fields) > 0 {
+		selected := make([]map[string]interface{}, len(userResponses))
+		for i, userResponse := range userResponses {
+			selected[i] = userResponse.Select(params.Fields)
+		}
+		response.Paginated(w, selected, params.Page, params.Limit, total, http.StatusOK)
+		h.logger.Info("Users retrieved successfully", "count", len(users), "total", total, "fields", params.Fields)
+		return
+	}
 	
-	response.JSONWithMeta(w, userList, meta, http.StatusOK)
+	response.Paginated(w, userResponses, params.Page, params.Limit, total, http.StatusOK)
 	h.logger.Info("Users retrieved successfully", "count", len(users), "total", total)
 }
 
 // GetUser handles GET /api/v1/users/{id}
 // @Summary Get user by ID
-// @Description Get a specific user by their unique identifier
+// @Description Get a specific user by their unique identifier. Responses carry an ETag; send it back in If-None-Match to receive 304 when unchanged.
 // @Tags Users
 // @Accept json
 // @Produce json
 // @Param id path string true "User ID" format(objectid) example(507f1f77bcf86cd799439011)
+// @Param If-None-Match header string false "ETag from a previous response"
 // @Success 200 {object} response.Response{data=models.UserResponse} "User information"
+// @Success 304 "User has not been modified"
+// @Failure 400 {object} response.Response{error=response.ErrorInfo} "Invalid user ID format"
+// @Failure 404 {object} response.Response{error=response.ErrorInfo} "User not found"
+// @Failure 500 {object} response.Response{error=response.ErrorInfo} "Internal server error"
+// @Router /api/v1/users/{id} [get]
+func (h *UserHandler) GetUser(w http.ResponseWriter, r *http.Request) {
+	// Extract user ID from path
+	id := r.PathValue("id")
+	if id == "" {
+		response.BadRequest(w, "User ID is required")
+		return
+	}
+	
+	h.writeUser(w, r, id)
+}
+
+// GetMe handles GET /api/v1/users/me
+// @Summary Get the authenticated user
+// @Description Get the user identified by the access token. Supports the same ETag/If-None-Match handling as GET /users/{id}
+// @Tags Users
+// @Produce json
+// @Security BearerAuth
+// @Param If-None-Match header string false "ETag from a previous response"
+// @Success 200 {object} response.Response{data=models.UserResponse} "Authenticated user"
+// @Success 304 "User has not been modified"
+// @Failure 401 {object} response.Response{error=response.ErrorInfo} "Missing or invalid access token"
+// @Failure 404 {object} response.Response{error=response.ErrorInfo} "User not found"
+// @Failure 500 {object} response.Response{error=response.ErrorInfo} "Internal server error"
+// @Router /api/v1/users/me [get]
+func (h *UserHandler) GetMe(w http.ResponseWriter, r *http.Request) {
+	id, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		response.Unauthorized(w, "")
+		return
+	}
+	
+	h.writeUser(w, r, id)
+}
+
+// writeUser loads a user and writes it as a conditional GET response
+func (h *UserHandler) writeUser(w http.ResponseWriter, r *http.Request, id string) {
+	h.logger.Info("Getting user", "user_id", id)
+	
+	// Get user from service
+	user, err := h.service.GetUserByID(r.Context(), id)
+	if err != nil {
+		h.handleServiceError(w, "Failed to get user", err, "user_id", id)
+		return
+	}
+	
+	// Convert to response DTO
+	userResponse := toUserResponse(r, user)
+	
+	// Conditional GET: skip the body if the client already has this version
+	etag := response.ETagFor(userResponse)
+	if etag != "" {
+		response.SetETag(w, etag)
+		if response.ETagMatches(r, etag) {
+			response.NotModified(w)
+			h.logger.Info("User not modified", "user_id", id)
+			return
+		}
+	}
+	
+	response.JSON(w, userResponse, http.StatusOK)
+	h.logger.Info("User retrieved successfully", "user_id", id)
+}
+
+// CreateUser handles POST /api/v1/users
+// @Summary Create a new user
+// @Description Create a new user account with validation
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Param Idempotency-Key header string false "Unique key making retries safe; repeat requests with the same key and body replay the original response"
+// @Param user body models.CreateUserRequest true "User creation data"
+// @Success 201 {object} response.Response{data=models.UserResponse} "User created successfully"
+// @Failure 400 {object} response.Response{error=response.ErrorInfo{details=[]response.ValidationError}} "Validation error with the invalid fields in details, or invalid request body"
+// @Failure 409 {object} response.Response{error=response.ErrorInfo} "Username or email already exists, or a request with the same Idempotency-Key is still in progress"
+// @Failure 413 {object} response.Response{error=response.ErrorInfo} "Request body sent with an Idempotency-Key is too large"
+// @Failure 422 {object} response.Response{error=response.ErrorInfo} "Idempotency-Key reused with a different request body"
+// @Failure 500 {object} response.Response{error=response.ErrorInfo} "Internal server error"
+// @Router /api/v1/users [post]
+func (h *UserHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
+	h.logger.Info("Creating new user")
+	
+	// Parse request body
+	var req models.CreateUserRequest
+	if err := response.DecodeJSON(w, r, &req, response.DefaultMaxBodyBytes); err != nil {
+		h.logger.Warn("Invalid request body", "error", err.Error())
+		return
+	}
+	
+	// Create user through service
+	user, err := h.service.CreateUser(r.Context(), &req)
+	if err != nil {
+		var fieldErrs models.FieldErrors
+		if errors.As(err, &fieldErrs) {
+			h.logger.Warn("User creation validation failed", "error", err.Error())
+			response.ValidationErrors(w, toValidationErrors(fieldErrs))
+			return
+		}
+		h.handleServiceError(w, "Failed to create user", err)
+		return
+	}
+	
+	// Convert to response DTO
+	userResponse := toUserResponse(r, user)
+	
+	response.CreatedWithLocation(w, userResponse, "/api/v1/users/"+user.GetIDString(), "User created successfully")
+	h.logger.Info("User created successfully", "user_id", user.GetIDString(), "username", user.Username)
+}
+
+// BulkCreateUsers handles POST /api/v1/users/bulk
+// @Summary Bulk import users
+// @Description Create up to 500 users in one request. Each item is validated independently; the response reports per-item results and uses 207 Multi-Status when some items fail
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Param users body []models.CreateUserRequest true "Users to create"
+// @Success 201 {object} response.Response{data=models.BulkCreateResponse} "All users created successfully"
+// @Success 207 {object} response.Response{data=models.BulkCreateResponse} "Some users could not be created"
+// @Failure 400 {object} response.Response{error=response.ErrorInfo} "Invalid request body, empty or oversized batch"
+// @Failure 500 {object} response.Response{error=response.ErrorInfo} "Internal server error"
+// @Router /api/v1/users/bulk [post]
+func (h *UserHandler) BulkCreateUsers(w http.ResponseWriter, r *http.Request) {
+	h.logger.Info("Bulk creating users")
+	
+	// Parse request body
+	var reqs []models.CreateUserRequest
+	if err := response.DecodeJSON(w, r, &reqs, maxBulkBodyBytes); err != nil {
+		h.logger.Warn("Invalid request body", "error", err.Error())
+		return
+	}
+	
+	if len(reqs) > MaxBulkCreateSize {
+		response.BadRequest(w, fmt.Sprintf("Batch size cannot exceed %d users", MaxBulkCreateSize))
+		return
+	}
+	
+	// Create users through service
+	result, err := h.service.BulkCreateUsers(r.Context(), reqs)
+	if err != nil {
+		h.handleServiceError(w, "Failed to bulk create users", err)
+		return
+	}
+	
+	if result.Failed > 0 {
+		response.JSONWithMessage(w, result, "Some users could not be created", http.StatusMultiStatus)
+	} else {
+		response.Created(w, result, "Users created successfully")
+	}
+	h.logger.Info("Bulk user creation completed", "succeeded", result.Succeeded, "failed", result.Failed)
+}
+
+// BatchGetUsers handles POST /api/v1/users/batch-get
+// @Summary Get users by IDs
+// @Description Resolve up to 100 user IDs in one request. Users are returned in the requested order; IDs that do not exist or belong to deleted users are omitted
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Param request body models.BatchGetUsersRequest true "User IDs to resolve"
+// @Success 200 {object} response.Response{data=[]models.UserResponse} "Matching users"
+// @Failure 400 {object} response.Response{error=response.ErrorInfo} "Invalid request body, invalid ID or too many IDs"
+// @Failure 500 {object} response.Response{error=response.ErrorInfo} "Internal server error"
+// @Router /api/v1/users/batch-get [post]
+func (h *UserHandler) BatchGetUsers(w http.ResponseWriter, r *http.Request) {
+	// Parse request body
+	var req models.BatchGetUsersRequest
+	if err := response.DecodeJSON(w, r, &req, response.DefaultMaxBodyBytes); err != nil {
+		h.logger.Warn("Invalid request body", "error", err.Error())
+		return
+	}
+	
+	users, err := h.service.GetUsersByIDs(r.Context(), &req)
+	if err != nil {
+		h.handleServiceError(w, "Failed to get users by IDs", err)
+		return
+	}
+	
+	// Convert to response DTOs
+	userResponses := make([]models.UserResponse, len(users))
+	for i, user := range users {
+		userResponses[i] = toUserResponse(r, user)
+	}
+	
+	response.JSON(w, userResponses, http.StatusOK)
+}
+
+// BulkDeleteUsers handles POST /api/v1/users/bulk-delete
+// @Summary Bulk delete users
+// @Description Soft delete up to 100 users in one request. Set dry_run to see which IDs would be deleted and which do not exist without deleting anything. Requires the admin role.
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.BulkDeleteRequest true "User IDs to delete"
+// @Success 200 {object} response.Response{data=models.BulkDeleteResponse} "Users deleted, or the dry-run result"
+// @Failure 400 {object} response.Response{error=response.ErrorInfo} "Invalid request body, invalid ID or too many IDs"
+// @Failure 401 {object} response.Response{error=response.ErrorInfo} "Missing or invalid token"
+// @Failure 403 {object} response.Response{error=response.ErrorInfo} "Caller is not an admin"
+// @Failure 500 {object} response.Response{error=response.ErrorInfo} "Internal server error"
+// @Router /api/v1/users/bulk-delete [post]
+func (h *UserHandler) BulkDeleteUsers(w http.ResponseWriter, r *http.Request) {
+	// Parse request body
+	var req models.BulkDeleteRequest
+	if err := response.DecodeJSON(w, r, &req, response.DefaultMaxBodyBytes); err != nil {
+		h.logger.Warn("Invalid request body", "error", err.Error())
+		return
+	}
+	
+	result, err := h.service.BulkDeleteUsers(r.Context(), &req)
+	if err != nil {
+		h.handleServiceError(w, "Failed to bulk delete users", err)
+		return
+	}
+	
+	message := "Users deleted successfully"
+	if result.DryRun {
+		message = "Dry run: no users were deleted"
+	}
+	response.JSONWithMessage(w, result, message, http.StatusOK)
+	h.logger.Info("Bulk delete completed", "dry_run", result.DryRun, "deleted", len(result.Deleted), "not_found", len(result.NotFound))
+}
+
+// ExportUsers handles GET /api/v1/users/export
+// @Summary Export all users
+// @Description Stream every user as a downloadable file: newline-delimited JSON by default, or CSV with Accept: text/csv or ?format=csv. The export is written incrementally, so it can be arbitrarily large
+// @Tags Users
+// @Produce application/x-ndjson
+// @Produce text/csv
+// @Security BearerAuth
+// @Param format query string false "Export format" default(json) Enums(json, csv)
+// @Success 200 {file} file "User export"
+// @Failure 401 {object} response.Response{error=response.ErrorInfo} "Missing or invalid access token"
+// @Failure 403 {object} response.Response{error=response.ErrorInfo} "Caller is not an admin"
+// @Failure 500 {object} response.Response{error=response.ErrorInfo} "Internal server error"
+// @Router /api/v1/users/export [get]
+func (h *UserHandler) ExportUsers(w http.ResponseWriter, r *http.Request) {
+	rc := http.NewResponseController(w)
+	format := response.NegotiateFormat(r)
+	
+	contentType, extension := "application/x-ndjson", "ndjson"
+	if format == response.FormatCSV {
+		contentType, extension = "text/csv; charset=utf-8", "csv"
+	}
+	filename := fmt.Sprintf("users-%s.%s", time.Now().UTC().Format("20060102-150405"), extension)
+	
+	// Headers are sent with the first user so an early failure can still be reported as JSON
+	started := false
+	start := func() {
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.WriteHeader(http.StatusOK)
+		started = true
+	}
+	
+	csvWriter := csv.NewWriter(w)
+	encoder := json.NewEncoder(w)
+	written := 0
+	
+	err := h.service.ExportUsers(r.Context(), func(user *models.User) error {
+		if !started {
+			start()
+			if format == response.FormatCSV {
+				if err := csvWriter.Write(models.UserCSVHeaders); err != nil {
+					return err
+				}
+			}
+		}
+		
+		userResponse := toUserResponse(r, user)
+		if format == response.FormatCSV {
+			if err := csvWriter.Write(userResponse.ToCSVRow()); err != nil {
+				return err
+			}
+		} else if err := encoder.Encode(userResponse); err != nil {
+			return err
+		}
+		
+		written++
+		if written%exportFlushInterval == 0 {
+			return flushExport(rc, csvWriter)
+		}
+		return nil
+	})
+	if err != nil {
+		if !started {
+			h.handleServiceError(w, "Failed to export users", err)
+			return
+		}
+		// The status line is already sent; the client sees a truncated download
+		h.logFailure("User export aborted mid-stream", err, "written", written)
+		return
+	}
+	
+	if !started {
+		start()
+		if format == response.FormatCSV {
+			if err := csvWriter.Write(models.UserCSVHeaders); err != nil {
+				h.logFailure("User export aborted mid-stream", err, "written", written)
+				return
+			}
+		}
+	}
+	if err := flushExport(rc, csvWriter); err != nil {
+		h.logger.Warn("Failed to flush user export", "error", err.Error())
+	}
+	h.logger.Info("Users exported", "format", format, "count", written)
+}
+
+// flushExport pushes buffered export data to the client and extends the write deadline
+// so the server's WriteTimeout does not cut off long exports
+func flushExport(rc *http.ResponseController, csvWriter *csv.Writer) error {
+	csvWriter.Flush()
+	if err := csvWriter.Error(); err != nil {
+		return err
+	}
+	if err := rc.SetWriteDeadline(time.Now().Add(exportWriteTimeout)); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+	if err := rc.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+	return nil
+}
+
+// UpdateUser handles PATCH /api/v1/users/{id}
+// @Summary Update user
+// @Description Partially update user information with validation: only the fields sent are changed and omitted fields keep their value. Use PUT to replace the whole profile instead. Send the user's current version in If-Match or the version field to reject the update with 409 if someone else modified the user first. Changing the email marks the user unverified and sends a verification email to the new address.
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Param id path string true "User ID" format(objectid) example(507f1f77bcf86cd799439011)
+// @Param If-Match header string false "Expected user version, e.g. \"3\""
+// @Param user body models.UpdateUserRequest true "User update data (partial)"
+// @Success 200 {object} response.Response{data=models.UserResponse} "User updated successfully"
+// @Failure 400 {object} response.Response{error=response.ErrorInfo{details=[]response.ValidationError}} "Validation error with the invalid fields in details, or invalid request body"
+// @Failure 404 {object} response.Response{error=response.ErrorInfo} "User not found"
+// @Failure 409 {object} response.Response{error=response.ErrorInfo} "Username or email already exists, or version conflict"
+// @Failure 500 {object} response.Response{error=response.ErrorInfo} "Internal server error"
+// @Router /api/v1/users/{id} [patch]
+func (h *UserHandler) UpdateUser(w http.ResponseWriter, r *http.Request) {
+	// Extract user ID from path
+	id := r.PathValue("id")
+	if id == "" {
+		response.BadRequest(w, "User ID is required")
+		return
+	}
+	
+	h.logger.Info("Updating user", "user_id", id)
+	
+	// Parse request body
+	var req models.UpdateUserRequest
+	if err := response.DecodeJSON(w, r, &req, response.DefaultMaxBodyBytes); err != nil {
+		h.logger.Warn("Invalid request body", "error", err.Error())
+		return
+	}
+	
+	h.updateUser(w, r, id, &req)
+}
+
+// ReplaceUser handles PUT /api/v1/users/{id}
+// @Summary Replace user profile
+// @Description Replace every mutable profile field of a user. Unlike PATCH, optional fields left out of the body (first_name, last_name, bio, location, website, date_of_birth) are cleared; username and email are required. Roles, verification and account status are not profile fields and cannot be sent. Send the user's current version in If-Match or the version field to reject the update with 409 if someone else modified the user first. Changing the email marks the user unverified and sends a verification email to the new address.
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Param id path string true "User ID" format(objectid) example(507f1f77bcf86cd799439011)
+// @Param If-Match header string false "Expected user version, e.g. \"3\""
+// @Param user body models.ReplaceUserRequest true "Complete user profile"
+// @Success 200 {object} response.Response{data=models.UserResponse} "User updated successfully"
+// @Failure 400 {object} response.Response{error=response.ErrorInfo{details=[]response.ValidationError}} "Validation error with the invalid fields in details, or invalid request body"
+// @Failure 404 {object} response.Response{error=response.ErrorInfo} "User not found"
+// @Failure 409 {object} response.Response{error=response.ErrorInfo} "Username or email already exists, or version conflict"
+// @Failure 500 {object} response.Response{error=response.ErrorInfo} "Internal server error"
+// @Router /api/v1/users/{id} [put]
+func (h *UserHandler) ReplaceUser(w http.ResponseWriter, r *http.Request) {
+	// Extract user ID from path
+	id := r.PathValue("id")
+	if id == "" {
+		response.BadRequest(w, "User ID is required")
+		return
+	}
+	
+	h.logger.Info("Replacing user profile", "user_id", id)
+	
+	// Unknown fields, including roles and verification, are rejected while decoding
+	var req models.ReplaceUserRequest
+	if err := response.DecodeJSON(w, r, &req, response.DefaultMaxBodyBytes); err != nil {
+		h.logger.Warn("Invalid request body", "error", err.Error())
+		return
+	}
+	
+	h.updateUser(w, r, id, req.ToUpdateRequest())
+}
+
+// UpdateMe handles PATCH /api/v1/users/me
+// @Summary Update the authenticated user
+// @Description Partially update the user identified by the access token. Roles, verification and account status cannot be changed through this endpoint
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param If-Match header string false "Expected user version, e.g. \"3\""
+// @Param user body models.UpdateUserRequest true "User update data (partial)"
+// @Success 200 {object} response.Response{data=models.UserResponse} "User updated successfully"
+// @Failure 400 {object} response.Response{error=response.ErrorInfo{details=[]response.ValidationError}} "Validation error with the invalid fields in details, or invalid request body"
+// @Failure 401 {object} response.Response{error=response.ErrorInfo} "Missing or invalid access token"
+// @Failure 403 {object} response.Response{error=response.ErrorInfo} "Attempt to change a protected field"
+// @Failure 409 {object} response.Response{error=response.ErrorInfo} "Username or email already exists, or version conflict"
+// @Failure 500 {object} response.Response{error=response.ErrorInfo} "Internal server error"
+// @Router /api/v1/users/me [patch]
+func (h *UserHandler) UpdateMe(w http.ResponseWriter, r *http.Request) {
+	id, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		response.Unauthorized(w, "")
+		return
+	}
+	
+	var body json.RawMessage
+	if err := response.DecodeJSON(w, r, &body, response.DefaultMaxBodyBytes); err != nil {
+		h.logger.Warn("Invalid request body", "error", err.Error())
+		return
+	}
+	
+	// Reject privilege changes outright instead of silently dropping them
+	var fields map[string]json.RawMessage
+	if err := response.DecodeJSONBytes(w, body, &fields); err != nil {
+		h.logger.Warn("Invalid request body", "error", err.Error())
+		return
+	}
+	for _, field := range selfProtectedFields {
+		if _, ok := fields[field]; ok {
+			h.logger.Warn("Rejected self-update of protected field", "user_id", id, "field", field)
+			response.Forbidden(w, fmt.Sprintf("Field '%s' cannot be changed on your own account", field))
+			return
+		}
+	}
+	
+	var req models.UpdateUserRequest
+	if err := response.DecodeJSONBytes(w, body, &req); err != nil {
+		h.logger.Warn("Invalid request body", "error", err.Error())
+		return
+	}
+	
+	h.logger.Info("Updating own user", "user_id", id)
+	h.updateUser(w, r, id, &req)
+}
+
+// updateUser applies a parsed update request and writes the updated user
+func (h *UserHandler) updateUser(w http.ResponseWriter, r *http.Request, id string, req *models.UpdateUserRequest) {
+	// An If-Match header carries the expected version; it must agree with the body if both are sent
+	if version, ok, err := parseIfMatchVersion(r); err != nil {
+		response.BadRequest(w, err.Error())
+		return
+	} else if ok {
+		if req.Version != nil && *req.Version != version {
+			response.BadRequest(w, "If-Match header and version field disagree")
+			return
+		}
+		req.Version = &version
+	}
+	
+	// Update user through service
+	user, err := h.service.UpdateUser(r.Context(), id, req)
+	if err != nil {
+		if errors.Is(err, repositories.ErrVersionConflict) {
+			response.ErrorWithCode(w, "VERSION_CONFLICT", "User was modified by another request; reload it and retry", http.StatusConflict)
+			return
+		}
+		var fieldErrs models.FieldErrors
+		if errors.As(err, &fieldErrs) {
+			h.logger.Warn("User update validation failed", "error", err.Error())
+			response.ValidationErrors(w, toValidationErrors(fieldErrs))
+			return
+		}
+		h.handleServiceError(w, "Failed to update user", err, "user_id", id)
+		return
+	}
+	
+	// Convert to response DTO
+	userResponse := toUserResponse(r, user)
+	
+	response.Updated(w, userResponse, "User updated successfully")
+	h.logger.Info("User updated successfully", "user_id", id)
+}
+
+// DeleteUser handles DELETE /api/v1/users/{id}
+// @Summary Delete user
+// @Description Soft delete a user account (user data is preserved but marked as deleted)
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Param id path string true "User ID" format(objectid) example(507f1f77bcf86cd799439011)
+// @Success 200 {object} response.Response "User deleted successfully"
 // @Failure 400 {object} response.Response{error=response.ErrorInfo} "Invalid user ID format"
 // @Failure 404 {object} response.Response{error=response.ErrorInfo} "User not found"
 // @Failure 500 {object} response.Response{error=response.ErrorInfo} "Internal server error"
-// @Router /api/v1/users/{id} [get]
-func (h *UserHandler) GetUser(w http.ResponseWriter, r *http.Request) {
+// @Router /api/v1/users/{id} [delete]
+func (h *UserHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
+	// Extract user ID from path
+	id := r.PathValue("id")
+	if id == "" {
+		response.BadRequest(w, "User ID is required")
+		return
+	}
+	
+	h.logger.Info("Deleting user", "user_id", id)
+	
+	// Delete user through service
+	err := h.service.DeleteUser(r.Context(), id)
+	if err != nil {
+		h.handleServiceError(w, "Failed to delete user", err, "user_id", id)
+		return
+	}
+	
+	response.Deleted(w, "User deleted successfully")
+	h.logger.Info("User deleted successfully", "user_id", id)
+}
+
+// DeleteUserBySelector handles DELETE /api/v1/users?email= or ?username=
+// @Summary Delete user by email or username
+// @Description Soft delete the user matching exactly one of email or username (admin only)
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param email query string false "User email" example(john.doe@example.com)
+// @Param username query string false "Username" example(johndoe)
+// @Success 200 {object} response.Response "User deleted successfully"
+// @Failure 400 {object} response.Response{error=response.ErrorInfo} "Exactly one of email or username is required"
+// @Failure 401 {object} response.Response{error=response.ErrorInfo} "Authentication required"
+// @Failure 403 {object} response.Response{error=response.ErrorInfo} "Admin role required"
+// @Failure 404 {object} response.Response{error=response.ErrorInfo} "User not found"
+// @Failure 500 {object} response.Response{error=response.ErrorInfo} "Internal server error"
+// @Router /api/v1/users [delete]
+func (h *UserHandler) DeleteUserBySelector(w http.ResponseWriter, r *http.Request) {
+	email := strings.TrimSpace(r.URL.Query().Get("email"))
+	username := strings.TrimSpace(r.URL.Query().Get("username"))
+	
+	if (email == "") == (username == "") {
+		response.BadRequest(w, "Exactly one of email or username is required")
+		return
+	}
+	
+	var err error
+	if email != "" {
+		h.logger.Info("Deleting user by email", "email", email)
+		err = h.service.DeleteUserByEmail(r.Context(), email)
+	} else {
+		h.logger.Info("Deleting user by username", "username", username)
+		err = h.service.DeleteUserByUsername(r.Context(), username)
+	}
+	if err != nil {
+		h.handleServiceError(w, "Failed to delete user", err, "email", email, "username", username)
+		return
+	}
+	
+	response.Deleted(w, "User deleted successfully")
+	h.logger.Info("User deleted successfully", "email", email, "username", username)
+}
+
+// RestoreUser handles POST /api/v1/users/{id}/restore
+// @Summary Restore deleted user
+// @Description Restore a soft-deleted user account and reactivate it
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Param id path string true "User ID" format(objectid) example(507f1f77bcf86cd799439011)
+// @Success 200 {object} response.Response{data=models.UserResponse} "User restored successfully"
+// @Failure 400 {object} response.Response{error=response.ErrorInfo} "User is not deleted or invalid ID"
+// @Failure 404 {object} response.Response{error=response.ErrorInfo} "User not found"
+// @Failure 500 {object} response.Response{error=response.ErrorInfo} "Internal server error"
+// @Router /api/v1/users/{id}/restore [post]
+func (h *UserHandler) RestoreUser(w http.ResponseWriter, r *http.Request) {
+	// Extract user ID from path
+	id := r.PathValue("id")
+	if id == "" {
+		response.BadRequest(w, "User ID is required")
+		return
+	}
+	
+	h.logger.Info("Restoring user", "user_id", id)
+	
+	// Restore user through service
+	user, err := h.service.RestoreUser(r.Context(), id)
+	if err != nil {
+		h.handleServiceError(w, "Failed to restore user", err, "user_id", id)
+		return
+	}
+	
+	response.JSONWithMessage(w, toUserResponse(r, user), "User restored successfully", http.StatusOK)
+	h.logger.Info("User restored successfully", "user_id", id)
+}
+
+// ActivateUser handles POST /api/v1/users/{id}/activate
+// @Summary Activate user
+// @Description Reactivate a deactivated user account. Requires the admin role.
+// @Tags Users
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID" format(objectid) example(507f1f77bcf86cd799439011)
+// @Success 200 {object} response.Response{data=models.UserResponse} "User activated successfully"
+// @Failure 400 {object} response.Response{error=response.ErrorInfo} "User is already active"
+// @Failure 401 {object} response.Response{error=response.ErrorInfo} "Missing or invalid token"
+// @Failure 403 {object} response.Response{error=response.ErrorInfo} "Caller is not an admin"
+// @Failure 404 {object} response.Response{error=response.ErrorInfo} "User not found"
+// @Failure 500 {object} response.Response{error=response.ErrorInfo} "Internal server error"
+// @Router /api/v1/users/{id}/activate [post]
+func (h *UserHandler) ActivateUser(w http.ResponseWriter, r *http.Request) {
+	h.setUserActive(w, r, true)
+}
+
+// DeactivateUser handles POST /api/v1/users/{id}/deactivate
+// @Summary Deactivate user
+// @Description Deactivate a user account without deleting it; it can be reactivated later. Requires the admin role.
+// @Tags Users
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID" format(objectid) example(507f1f77bcf86cd799439011)
+// @Success 200 {object} response.Response{data=models.UserResponse} "User deactivated successfully"
+// @Failure 400 {object} response.Response{error=response.ErrorInfo} "User is already inactive"
+// @Failure 401 {object} response.Response{error=response.ErrorInfo} "Missing or invalid token"
+// @Failure 403 {object} response.Response{error=response.ErrorInfo} "Caller is not an admin"
+// @Failure 404 {object} response.Response{error=response.ErrorInfo} "User not found"
+// @Failure 500 {object} response.Response{error=response.ErrorInfo} "Internal server error"
+// @Router /api/v1/users/{id}/deactivate [post]
+func (h *UserHandler) DeactivateUser(w http.ResponseWriter, r *http.Request) {
+	h.setUserActive(w, r, false)
+}
+
+// setUserActive applies an activate or deactivate request
+func (h *UserHandler) setUserActive(w http.ResponseWriter, r *http.Request, active bool) {
+	// Extract user ID from path
+	id := r.PathValue("id")
+	if id == "" {
+		response.BadRequest(w, "User ID is required")
+		return
+	}
+	
+	action := "deactivated"
+	if active {
+		action = "activated"
+	}
+	
+	user, err := h.service.SetUserActive(r.Context(), id, active)
+	if err != nil {
+		h.handleServiceError(w, "Failed to update user status", err, "user_id", id)
+		return
+	}
+	
+	response.Updated(w, toUserResponse(r, user), "User "+action+" successfully")
+	h.logger.Info("User "+action+" successfully", "user_id", id)
+}
+
+// SetUserRoles handles PUT /api/v1/users/{id}/roles
+// @Summary Set user roles
+// @Description Replace the roles assigned to a user. Requires the admin role.
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID" format(objectid) example(507f1f77bcf86cd799439011)
+// @Param roles body models.SetRolesRequest true "New set of roles"
+// @Success 200 {object} response.Response{data=models.UserResponse} "User roles updated successfully"
+// @Failure 400 {object} response.Response{error=response.ErrorInfo} "Validation error or invalid request body"
+// @Failure 401 {object} response.Response{error=response.ErrorInfo} "Missing or invalid token"
+// @Failure 403 {object} response.Response{error=response.ErrorInfo} "Caller is not an admin"
+// @Failure 404 {object} response.Response{error=response.ErrorInfo} "User not found"
+// @Failure 500 {object} response.Response{error=response.ErrorInfo} "Internal server error"
+// @Router /api/v1/users/{id}/roles [put]
+func (h *UserHandler) SetUserRoles(w http.ResponseWriter, r *http.Request) {
 	// Extract user ID from path
 	id := r.PathValue("id")
 	if id == "" {
@@ -103,91 +837,69 @@ func (h *UserHandler) GetUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	
-	h.logger.Info("Getting user", "user_id", id)
+	h.logger.Info("Setting user roles", "user_id", id)
 	
-	// Get user from service
-	user, err := h.service.GetUserByID(r.Context(), id)
-	if err != nil {
-		if strings.Contains(err.Error(), "not found") {
-			h.logger.Warn("User not found", "user_id", id)
-			response.NotFound(w, "User")
-			return
-		}
-		h.logger.Error("Failed to get user", err, "user_id", id)
-		response.InternalServerError(w)
+	// Parse request body
+	var req models.SetRolesRequest
+	if err := response.DecodeJSON(w, r, &req, response.DefaultMaxBodyBytes); err != nil {
+		h.logger.Warn("Invalid request body", "error", err.Error())
 		return
 	}
 	
-	// Convert to response DTO
-	userResponse := user.ToUserResponse()
+	// Set roles through service
+	user, err := h.service.SetUserRoles(r.Context(), id, &req)
+	if err != nil {
+		h.handleServiceError(w, "Failed to set user roles", err, "user_id", id)
+		return
+	}
 	
-	response.JSON(w, userResponse, http.StatusOK)
-	h.logger.Info("User retrieved successfully", "user_id", id)
+	response.Updated(w, toUserResponse(r, user), "User roles updated successfully")
+	h.logger.Info("User roles updated successfully", "user_id", id)
 }
 
-// CreateUser handles POST /api/v1/users
-// @Summary Create a new user
-// @Description Create a new user account with validation
+// GetUserPreferences handles GET /api/v1/users/{id}/preferences
+// @Summary Get user preferences
+// @Description Get a user's notification preferences. Settings never changed are reported with their defaults
 // @Tags Users
-// @Accept json
 // @Produce json
-// @Param user body models.CreateUserRequest true "User creation data"
-// @Success 201 {object} response.Response{data=models.UserResponse} "User created successfully"
-// @Failure 400 {object} response.Response{error=response.ErrorInfo} "Validation error or invalid request body"
-// @Failure 409 {object} response.Response{error=response.ErrorInfo} "Username or email already exists"
+// @Param id path string true "User ID" format(objectid) example(507f1f77bcf86cd799439011)
+// @Success 200 {object} response.Response{data=models.PreferencesResponse} "User preferences"
+// @Failure 404 {object} response.Response{error=response.ErrorInfo} "User not found"
 // @Failure 500 {object} response.Response{error=response.ErrorInfo} "Internal server error"
-// @Router /api/v1/users [post]
-func (h *UserHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
-	h.logger.Info("Creating new user")
-	
-	// Parse request body
-	var req models.CreateUserRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.logger.Warn("Invalid request body", "error", err.Error())
-		response.BadRequest(w, "Invalid request body format")
+// @Router /api/v1/users/{id}/preferences [get]
+func (h *UserHandler) GetUserPreferences(w http.ResponseWriter, r *http.Request) {
+	// Extract user ID from path
+	id := r.PathValue("id")
+	if id == "" {
+		response.BadRequest(w, "User ID is required")
 		return
 	}
 	
-	// Create user through service
-	user, err := h.service.CreateUser(r.Context(), &req)
+	h.logger.Info("Getting user preferences", "user_id", id)
+	
+	prefs, err := h.service.GetUserPreferences(r.Context(), id)
 	if err != nil {
-		if strings.Contains(err.Error(), "already exists") {
-			h.logger.Warn("User creation conflict", "error", err.Error())
-			response.ErrorWithCode(w, "CONFLICT", err.Error(), http.StatusConflict)
-			return
-		}
-		if strings.Contains(err.Error(), "validation failed") {
-			h.logger.Warn("User creation validation failed", "error", err.Error())
-			response.BadRequest(w, err.Error())
-			return
-		}
-		h.logger.Error("Failed to create user", err)
-		response.InternalServerError(w)
+		h.handleServiceError(w, "Failed to get user preferences", err, "user_id", id)
 		return
 	}
 	
-	// Convert to response DTO
-	userResponse := user.ToUserResponse()
-	
-	response.Created(w, userResponse, "User created successfully")
-	h.logger.Info("User created successfully", "user_id", user.GetIDString(), "username", user.Username)
+	response.JSON(w, prefs, http.StatusOK)
 }
 
-// UpdateUser handles PATCH /api/v1/users/{id}
-// @Summary Update user
-// @Description Partially update user information with validation (only provided fields are updated)
+// UpdateUserPreferences handles PUT /api/v1/users/{id}/preferences
+// @Summary Update user preferences
+// @Description Change the notification preferences present in the body. Omitted settings keep their value and unknown keys are rejected
 // @Tags Users
 // @Accept json
 // @Produce json
 // @Param id path string true "User ID" format(objectid) example(507f1f77bcf86cd799439011)
-// @Param user body models.UpdateUserRequest true "User update data (partial)"
-// @Success 200 {object} response.Response{data=models.UserResponse} "User updated successfully"
-// @Failure 400 {object} response.Response{error=response.ErrorInfo} "Validation error or invalid request body"
+// @Param preferences body models.UpdatePreferencesRequest true "Preferences to change"
+// @Success 200 {object} response.Response{data=models.PreferencesResponse} "User preferences updated successfully"
+// @Failure 400 {object} response.Response{error=response.ErrorInfo} "Validation error, unknown key or invalid request body"
 // @Failure 404 {object} response.Response{error=response.ErrorInfo} "User not found"
-// @Failure 409 {object} response.Response{error=response.ErrorInfo} "Username or email already exists"
 // @Failure 500 {object} response.Response{error=response.ErrorInfo} "Internal server error"
-// @Router /api/v1/users/{id} [patch]
-func (h *UserHandler) UpdateUser(w http.ResponseWriter, r *http.Request) {
+// @Router /api/v1/users/{id}/preferences [put]
+func (h *UserHandler) UpdateUserPreferences(w http.ResponseWriter, r *http.Request) {
 	// Extract user ID from path
 	id := r.PathValue("id")
 	if id == "" {
@@ -195,59 +907,48 @@ func (h *UserHandler) UpdateUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	
-	h.logger.Info("Updating user", "user_id", id)
+	h.logger.Info("Updating user preferences", "user_id", id)
 	
-	// Parse request body
-	var req models.UpdateUserRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	// Parse request body; unknown preference keys fail here
+	var req models.UpdatePreferencesRequest
+	if err := response.DecodeJSON(w, r, &req, response.DefaultMaxBodyBytes); err != nil {
 		h.logger.Warn("Invalid request body", "error", err.Error())
-		response.BadRequest(w, "Invalid request body format")
 		return
 	}
 	
-	// Update user through service
-	user, err := h.service.UpdateUser(r.Context(), id, &req)
+	prefs, err := h.service.UpdateUserPreferences(r.Context(), id, &req)
 	if err != nil {
-		if strings.Contains(err.Error(), "not found") {
-			h.logger.Warn("User not found for update", "user_id", id)
-			response.NotFound(w, "User")
-			return
-		}
-		if strings.Contains(err.Error(), "already exists") {
-			h.logger.Warn("User update conflict", "error", err.Error())
-			response.ErrorWithCode(w, "CONFLICT", err.Error(), http.StatusConflict)
-			return
-		}
-		if strings.Contains(err.Error(), "validation failed") {
-			h.logger.Warn("User update validation failed", "error", err.Error())
-			response.BadRequest(w, err.Error())
+		var fieldErrs models.FieldErrors
+		if errors.As(err, &fieldErrs) {
+			h.logger.Warn("Update preferences validation failed", "error", err.Error())
+			response.ValidationErrors(w, toValidationErrors(fieldErrs))
 			return
 		}
-		h.logger.Error("Failed to update user", err, "user_id", id)
-		response.InternalServerError(w)
+		h.handleServiceError(w, "Failed to update user preferences", err, "user_id", id)
 		return
 	}
 	
-	// Convert to response DTO
-	userResponse := user.ToUserResponse()
-	
-	response.Updated(w, userResponse, "User updated successfully")
-	h.logger.Info("User updated successfully", "user_id", id)
+	response.Updated(w, prefs, "User preferences updated successfully")
+	h.logger.Info("User preferences updated successfully", "user_id", id)
 }
 
-// DeleteUser handles DELETE /api/v1/users/{id}
-// @Summary Delete user
-// @Description Soft delete a user account (user data is preserved but marked as deleted)
+// avatarFormOverhead allows room for multipart boundaries and headers on top of the image itself
+const avatarFormOverhead = 64 << 10
+
+// UploadAvatar handles POST /api/v1/users/{id}/avatar
+// @Summary Upload user avatar
+// @Description Upload a PNG or JPEG avatar image (max 2MB) as the "avatar" field of a multipart form
 // @Tags Users
-// @Accept json
+// @Accept multipart/form-data
 // @Produce json
 // @Param id path string true "User ID" format(objectid) example(507f1f77bcf86cd799439011)
-// @Success 200 {object} response.Response "User deleted successfully"
-// @Failure 400 {object} response.Response{error=response.ErrorInfo} "Invalid user ID format"
+// @Param avatar formData file true "Avatar image (PNG or JPEG, max 2MB)"
+// @Success 200 {object} response.Response{data=models.UserResponse} "Avatar uploaded successfully"
+// @Failure 400 {object} response.Response{error=response.ErrorInfo} "Missing file, unsupported image type or file too large"
 // @Failure 404 {object} response.Response{error=response.ErrorInfo} "User not found"
 // @Failure 500 {object} response.Response{error=response.ErrorInfo} "Internal server error"
-// @Router /api/v1/users/{id} [delete]
-func (h *UserHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
+// @Router /api/v1/users/{id}/avatar [post]
+func (h *UserHandler) UploadAvatar(w http.ResponseWriter, r *http.Request) {
 	// Extract user ID from path
 	id := r.PathValue("id")
 	if id == "" {
@@ -255,33 +956,56 @@ func (h *UserHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	
-	h.logger.Info("Deleting user", "user_id", id)
+	h.logger.Info("Uploading user avatar", "user_id", id)
 	
-	// Delete user through service
-	err := h.service.DeleteUser(r.Context(), id)
-	if err != nil {
-		if strings.Contains(err.Error(), "not found") {
-			h.logger.Warn("User not found for deletion", "user_id", id)
-			response.NotFound(w, "User")
+	// Parse multipart form, refusing bodies larger than the avatar limit
+	r.Body = http.MaxBytesReader(w, r.Body, MaxAvatarSize+avatarFormOverhead)
+	if err := r.ParseMultipartForm(MaxAvatarSize); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			response.BadRequest(w, fmt.Sprintf("Avatar must not exceed %d bytes", MaxAvatarSize))
 			return
 		}
-		h.logger.Error("Failed to delete user", err, "user_id", id)
-		response.InternalServerError(w)
+		h.logger.Warn("Invalid multipart form", "error", err.Error())
+		response.BadRequest(w, "Invalid multipart form")
 		return
 	}
+	defer r.MultipartForm.RemoveAll()
 	
-	response.Deleted(w, "User deleted successfully")
-	h.logger.Info("User deleted successfully", "user_id", id)
+	file, _, err := r.FormFile("avatar")
+	if err != nil {
+		response.BadRequest(w, "Avatar file is required")
+		return
+	}
+	defer file.Close()
+	
+	content, err := io.ReadAll(io.LimitReader(file, MaxAvatarSize+1))
+	if err != nil {
+		h.logger.Warn("Failed to read avatar file", "error", err.Error())
+		response.BadRequest(w, "Failed to read avatar file")
+		return
+	}
+	
+	// Upload through service
+	user, err := h.service.UploadAvatar(r.Context(), id, content)
+	if err != nil {
+		h.handleServiceError(w, "Failed to upload avatar", err, "user_id", id)
+		return
+	}
+	
+	response.Updated(w, toUserResponse(r, user), "Avatar uploaded successfully")
+	h.logger.Info("User avatar uploaded successfully", "user_id", id)
 }
 
 // SearchUsers handles GET /api/v1/users/search
 // @Summary Search users
-// @Description Search users by username, email, first name, or last name
+// @Description Search users by username, email, first name, or last name using full-text or substring matching
 // @Tags Users
 // @Accept json
 // @Produce json
 // @Param q query string true "Search query" minlength(1) maxlength(100) example(john)
 // @Param limit query int false "Maximum results" default(10) minimum(1) maximum(50)
+// @Param mode query string false "Search mode: auto uses full-text search and falls back to regex for queries under 3 characters" default(auto) Enums(auto, text, regex)
 // @Success 200 {object} response.Response{data=[]models.UserProfileResponse} "List of matching user profiles"
 // @Failure 400 {object} response.Response{error=response.ErrorInfo} "Missing or invalid search query"
 // @Failure 500 {object} response.Response{error=response.ErrorInfo} "Internal server error"
@@ -302,13 +1026,22 @@ func (h *UserHandler) SearchUsers(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 	
-	h.logger.Info("Searching users", "query", query, "limit", limit)
+	// Get search mode
+	mode := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("mode")))
+	if mode == "" {
+		mode = models.SearchModeAuto
+	}
+	if !models.IsValidSearchMode(mode) {
+		response.BadRequest(w, "Invalid mode parameter (must be 'auto', 'text' or 'regex')")
+		return
+	}
+	
+	h.logger.Info("Searching users", "query", query, "limit", limit, "mode", mode)
 	
 	// Search users through service
-	users, err := h.service.SearchUsers(r.Context(), query, limit)
+	users, err := h.service.SearchUsers(r.Context(), query, limit, mode)
 	if err != nil {
-		h.logger.Error("Failed to search users", err, "query", query)
-		response.InternalServerError(w)
+		h.handleServiceError(w, "Failed to search users", err, "query", query)
 		return
 	}
 	
@@ -324,7 +1057,7 @@ func (h *UserHandler) SearchUsers(w http.ResponseWriter, r *http.Request) {
 
 // ChangePassword handles PATCH /api/v1/users/{id}/password
 // @Summary Change user password
-// @Description Change a user's password with current password verification
+// @Description Change a user's password with current password verification. Every token issued to the user before the change is revoked.
 // @Tags Users
 // @Accept json
 // @Produce json
@@ -347,26 +1080,15 @@ func (h *UserHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
 	
 	// Parse request body
 	var req models.ChangePasswordRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	if err := response.DecodeJSON(w, r, &req, response.DefaultMaxBodyBytes); err != nil {
 		h.logger.Warn("Invalid request body", "error", err.Error())
-		response.BadRequest(w, "Invalid request body format")
 		return
 	}
 	
 	// Change password through service
 	err := h.service.ChangePassword(r.Context(), id, &req)
 	if err != nil {
-		if strings.Contains(err.Error(), "not found") {
-			response.NotFound(w, "User")
-			return
-		}
-		if strings.Contains(err.Error(), "validation failed") || strings.Contains(err.Error(), "incorrect") {
-			h.logger.Warn("Password change validation failed", "error", err.Error())
-			response.BadRequest(w, err.Error())
-			return
-		}
-		h.logger.Error("Failed to change password", err, "user_id", id)
-		response.InternalServerError(w)
+		h.handleServiceError(w, "Failed to change password", err, "user_id", id)
 		return
 	}
 	
@@ -374,6 +1096,30 @@ func (h *UserHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
 	h.logger.Info("Password changed successfully", "user_id", id)
 }
 
+// LogoutAll handles POST /api/v1/users/{id}/logout-all
+// @Summary Log out all sessions
+// @Description Revoke every access and refresh token issued to the user so far, e.g. when the account may be compromised. Only the user and admins may do this.
+// @Tags Users
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID" format(objectid) example(507f1f77bcf86cd799439011)
+// @Success 200 {object} response.Response "All sessions logged out"
+// @Failure 401 {object} response.Response{error=response.ErrorInfo} "Missing or invalid access token"
+// @Failure 403 {object} response.Response{error=response.ErrorInfo} "Not the user or an admin"
+// @Failure 404 {object} response.Response{error=response.ErrorInfo} "User not found"
+// @Failure 500 {object} response.Response{error=response.ErrorInfo} "Internal server error"
+// @Router /api/v1/users/{id}/logout-all [post]
+func (h *UserHandler) LogoutAll(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	
+	if err := h.service.LogoutAll(r.Context(), id); err != nil {
+		h.handleServiceError(w, "Failed to log out all sessions", err, "user_id", id)
+		return
+	}
+	
+	response.JSONWithMessage(w, nil, "All sessions logged out", http.StatusOK)
+}
+
 // VerifyUser handles PATCH /api/v1/users/{id}/verify
 // @Summary Verify user email
 // @Description Mark a user's email as verified
@@ -399,16 +1145,7 @@ func (h *UserHandler) VerifyUser(w http.ResponseWriter, r *http.Request) {
 	// Verify user through service
 	err := h.service.VerifyUser(r.Context(), id)
 	if err != nil {
-		if strings.Contains(err.Error(), "not found") {
-			response.NotFound(w, "User")
-			return
-		}
-		if strings.Contains(err.Error(), "already verified") {
-			response.BadRequest(w, err.Error())
-			return
-		}
-		h.logger.Error("Failed to verify user", err, "user_id", id)
-		response.InternalServerError(w)
+		h.handleServiceError(w, "Failed to verify user", err, "user_id", id)
 		return
 	}
 	
@@ -416,23 +1153,66 @@ func (h *UserHandler) VerifyUser(w http.ResponseWriter, r *http.Request) {
 	h.logger.Info("User verified successfully", "user_id", id)
 }
 
+// SendVerificationEmail handles POST /api/v1/users/{id}/verification/send
+// @Summary Send verification email
+// @Description Email the user a single-use token, valid for 24 hours, that confirms ownership of their email address via POST /api/v1/auth/verify-email. Sending again invalidates the previous token.
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Param id path string true "User ID" format(objectid) example(507f1f77bcf86cd799439011)
+// @Success 200 {object} response.Response "Verification email sent"
+// @Failure 400 {object} response.Response{error=response.ErrorInfo} "User is already verified"
+// @Failure 404 {object} response.Response{error=response.ErrorInfo} "User not found"
+// @Failure 500 {object} response.Response{error=response.ErrorInfo} "Internal server error"
+// @Router /api/v1/users/{id}/verification/send [post]
+func (h *UserHandler) SendVerificationEmail(w http.ResponseWriter, r *http.Request) {
+	// Extract user ID from path
+	id := r.PathValue("id")
+	if id == "" {
+		response.BadRequest(w, "User ID is required")
+		return
+	}
+	
+	h.logger.Info("Sending verification email", "user_id", id)
+	
+	// Send through service
+	err := h.service.SendVerificationEmail(r.Context(), id)
+	if err != nil {
+		h.handleServiceError(w, "Failed to send verification email", err, "user_id", id)
+		return
+	}
+	
+	response.JSONWithMessage(w, nil, "Verification email sent", http.StatusOK)
+	h.logger.Info("Verification email sent", "user_id", id)
+}
+
 // GetUserStats handles GET /api/v1/users/stats
 // @Summary Get user statistics
-// @Description Get aggregated user statistics including total users, active users, verified users, etc.
+// @Description Get aggregated user statistics including total, active and verified users, a per-role breakdown and users created in the last 7 days. Optionally restrict to users created within a date range.
 // @Tags Users
 // @Accept json
 // @Produce json
-// @Success 200 {object} response.Response{data=object} "User statistics"
+// @Param from query string false "Only count users created on or after this date (YYYY-MM-DD)" example(2024-01-01)
+// @Param to query string false "Only count users created on or before this date (YYYY-MM-DD)" example(2024-12-31)
+// @Success 200 {object} response.Response{data=models.UserStatsResponse} "User statistics"
+// @Failure 400 {object} response.Response{error=response.ErrorInfo} "Invalid date range"
 // @Failure 500 {object} response.Response{error=response.ErrorInfo} "Internal server error"
 // @Router /api/v1/users/stats [get]
 func (h *UserHandler) GetUserStats(w http.ResponseWriter, r *http.Request) {
 	h.logger.Info("Getting user statistics")
 	
+	// Parse query parameters
+	params, err := h.parseUserStatsParams(r)
+	if err != nil {
+		h.logger.Warn("Invalid stats parameters", "error", err.Error())
+		response.BadRequest(w, err.Error())
+		return
+	}
+	
 	// Get stats from service
-	stats, err := h.service.GetUserStats(r.Context())
+	stats, err := h.service.GetUserStats(r.Context(), params)
 	if err != nil {
-		h.logger.Error("Failed to get user stats", err)
-		response.InternalServerError(w)
+		h.handleServiceError(w, "Failed to get user stats", err)
 		return
 	}
 	
@@ -440,14 +1220,118 @@ func (h *UserHandler) GetUserStats(w http.ResponseWriter, r *http.Request) {
 	h.logger.Info("User statistics retrieved successfully")
 }
 
+// CountUsers handles GET /api/v1/users/count
+// @Summary Count users
+// @Description Get the number of users, optionally filtered by active status and role. Cheaper than the statistics endpoint when only a total is needed; the unfiltered total may be up to 30 seconds old.
+// @Tags Users
+// @Produce json
+// @Param is_active query bool false "Filter by active status"
+// @Param role query string false "Filter by role" Enums(user, admin, moderator)
+// @Success 200 {object} response.Response{data=models.UserCountResponse} "Number of users"
+// @Failure 400 {object} response.Response{error=response.ErrorInfo} "Invalid query parameters"
+// @Failure 500 {object} response.Response{error=response.ErrorInfo} "Internal server error"
+// @Router /api/v1/users/count [get]
+func (h *UserHandler) CountUsers(w http.ResponseWriter, r *http.Request) {
+	params := &models.UserCountParams{}
+	if err := request.BindQuery(r.URL.Query(), params); err != nil {
+		h.logger.Warn("Invalid count parameters", "error", err.Error())
+		response.BadRequest(w, err.Error())
+		return
+	}
+	
+	params.Role = strings.ToLower(params.Role)
+	if params.Role != "" && !models.IsValidRole(params.Role) {
+		response.BadRequest(w, fmt.Sprintf("invalid role value %q (allowed: %v)", params.Role, models.ValidRoles))
+		return
+	}
+	
+	count, err := h.service.CountUsers(r.Context(), params)
+	if err != nil {
+		h.handleServiceError(w, "Failed to count users", err)
+		return
+	}
+	
+	response.JSON(w, models.UserCountResponse{Count: count}, http.StatusOK)
+}
+
+// GetSignupsByDay handles GET /api/v1/users/stats/signups
+// @Summary Get signups by day
+// @Description Get the number of users who signed up on each UTC day of a date range, oldest first. Days without signups are included with a count of 0. Defaults to the last 30 days; the range may span at most 366 days.
+// @Tags Users
+// @Produce json
+// @Param from query string false "First day of the range (YYYY-MM-DD)" example(2024-01-01)
+// @Param to query string false "Last day of the range, inclusive (YYYY-MM-DD); defaults to today" example(2024-01-31)
+// @Success 200 {object} response.Response{data=[]models.DayCount} "Signups per day"
+// @Failure 400 {object} response.Response{error=response.ErrorInfo} "Invalid or too long date range"
+// @Failure 500 {object} response.Response{error=response.ErrorInfo} "Internal server error"
+// @Router /api/v1/users/stats/signups [get]
+func (h *UserHandler) GetSignupsByDay(w http.ResponseWriter, r *http.Request) {
+	// Parse query parameters
+	params, err := h.parseUserStatsParams(r)
+	if err != nil {
+		h.logger.Warn("Invalid signup series parameters", "error", err.Error())
+		response.BadRequest(w, err.Error())
+		return
+	}
+	
+	// Default to the last 30 days, today included
+	to := utils.Now().Truncate(24 * time.Hour).AddDate(0, 0, 1)
+	if params.To != nil {
+		to = *params.To
+	}
+	from := to.AddDate(0, 0, -defaultSignupDays)
+	if params.From != nil {
+		from = *params.From
+	}
+	if !from.Before(to) {
+		response.BadRequest(w, "from must not be after to")
+		return
+	}
+	if to.Sub(from) > maxSignupDays*24*time.Hour {
+		response.BadRequest(w, fmt.Sprintf("date range cannot exceed %d days", maxSignupDays))
+		return
+	}
+	
+	series, err := h.service.GetSignupsByDay(r.Context(), from, to)
+	if err != nil {
+		h.handleServiceError(w, "Failed to get signups by day", err)
+		return
+	}
+	
+	response.JSON(w, series, http.StatusOK)
+}
+
+// RecordProfileView handles POST /api/v1/users/{id}/profile-view
+// @Summary Record a profile view
+// @Description Increment the user's profile_views counter. The user is otherwise left unchanged, so updated_at and version stay the same.
+// @Tags Users
+// @Param id path string true "User ID" format(objectid) example(507f1f77bcf86cd799439011)
+// @Success 204 "Profile view recorded"
+// @Failure 404 {object} response.Response{error=response.ErrorInfo} "User not found"
+// @Failure 500 {object} response.Response{error=response.ErrorInfo} "Internal server error"
+// @Router /api/v1/users/{id}/profile-view [post]
+func (h *UserHandler) RecordProfileView(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	
+	if err := h.service.RecordProfileView(r.Context(), id); err != nil {
+		h.handleServiceError(w, "Failed to record profile view", err, "user_id", id)
+		return
+	}
+	
+	response.NoContent(w)
+}
+
 // GetUserProfile handles GET /api/v1/users/{id}/profile
 // @Summary Get user public profile
-// @Description Get a user's public profile information (limited data for privacy)
+// @Description Get a user's public profile information (limited data for privacy). is_online reports activity within the configured online window.
+// @Description Responses are publicly cacheable and carry Last-Modified; send it back in If-Modified-Since to receive 304 when unchanged.
 // @Tags Users
 // @Accept json
 // @Produce json
 // @Param id path string true "User ID" format(objectid) example(507f1f77bcf86cd799439011)
+// @Param If-Modified-Since header string false "Last-Modified from a previous response"
 // @Success 200 {object} response.Response{data=models.UserProfileResponse} "User public profile"
+// @Success 304 "Profile has not been modified"
 // @Failure 400 {object} response.Response{error=response.ErrorInfo} "Invalid user ID format"
 // @Failure 404 {object} response.Response{error=response.ErrorInfo} "User not found"
 // @Failure 500 {object} response.Response{error=response.ErrorInfo} "Internal server error"
@@ -465,17 +1349,26 @@ func (h *UserHandler) GetUserProfile(w http.ResponseWriter, r *http.Request) {
 	// Get user from service
 	user, err := h.service.GetUserByID(r.Context(), id)
 	if err != nil {
-		if strings.Contains(err.Error(), "not found") {
-			response.NotFound(w, "User")
-			return
-		}
-		h.logger.Error("Failed to get user profile", err, "user_id", id)
-		response.InternalServerError(w)
+		h.handleServiceError(w, "Failed to get user profile", err, "user_id", id)
+		return
+	}
+	
+	// Conditional GET: activity counts as a modification since it drives is_online
+	lastModified := user.UpdatedAt
+	if user.LastActivityAt != nil && user.LastActivityAt.After(lastModified) {
+		lastModified = *user.LastActivityAt
+	}
+	response.SetPublicCache(w, h.profileMaxAge)
+	response.SetLastModified(w, lastModified)
+	if response.NotModifiedSince(r, lastModified) {
+		response.NotModified(w)
+		h.logger.Info("User profile not modified", "user_id", id)
 		return
 	}
 	
 	// Convert to public profile response
 	profile := user.ToUserProfileResponse()
+	profile.IsOnline = user.IsActive && user.IsOnline(h.onlineWindow, utils.Now())
 	
 	response.JSON(w, profile, http.StatusOK)
 	h.logger.Info("User profile retrieved successfully", "user_id", id)
@@ -487,64 +1380,174 @@ func (h *UserHandler) GetUserProfile(w http.ResponseWriter, r *http.Request) {
 func (h *UserHandler) parseUsersQueryParams(r *http.Request) (*models.UsersQueryParams, error) {
 	params := &models.UsersQueryParams{}
 	
-	// Parse page
-	if pageStr := r.URL.Query().Get("page"); pageStr != "" {
-		if page, err := strconv.Atoi(pageStr); err == nil && page > 0 {
-			params.Page = page
-		} else {
-			return nil, fmt.Errorf("invalid page parameter")
-		}
+	// Bind the simple parameters; the rest need checks against the user model
+	if err := request.BindQuery(r.URL.Query(), params); err != nil {
+		return nil, err
 	}
 	
-	// Parse limit
-	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
-		if limit, err := strconv.Atoi(limitStr); err == nil && limit > 0 && limit <= 100 {
-			params.Limit = limit
-		} else {
-			return nil, fmt.Errorf("invalid limit parameter (must be between 1 and 100)")
-		}
+	if params.Limit > models.MaxPageLimit() {
+		return nil, fmt.Errorf("invalid limit parameter (must be between 1 and %d)", models.MaxPageLimit())
 	}
 	
-	// Parse search
-	params.Search = strings.TrimSpace(r.URL.Query().Get("search"))
+	// Every has_role entry must be a known role
+	for i, role := range params.HasRoles {
+		role = strings.ToLower(role)
+		if !models.IsValidRole(role) {
+			return nil, fmt.Errorf("invalid has_role value %q (allowed: %v)", role, models.ValidRoles)
+		}
+		params.HasRoles[i] = role
+	}
 	
-	// Parse role
-	params.Role = strings.TrimSpace(r.URL.Query().Get("role"))
+	if params.CreatedAfter != nil && params.CreatedBefore != nil && !params.CreatedAfter.Before(*params.CreatedBefore) {
+		return nil, fmt.Errorf("created_after must be before created_before")
+	}
 	
-	// Parse is_active
-	if activeStr := r.URL.Query().Get("is_active"); activeStr != "" {
-		if active, err := strconv.ParseBool(activeStr); err == nil {
-			params.IsActive = &active
-		} else {
-			return nil, fmt.Errorf("invalid is_active parameter (must be true or false)")
+	// Parse fields
+	if fieldsStr := strings.TrimSpace(r.URL.Query().Get("fields")); fieldsStr != "" {
+		fields, err := models.ParseUserFields(fieldsStr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid fields parameter: %v", err)
 		}
+		params.Fields = fields
+	}
+	
+	params.SortDir = strings.ToLower(params.SortDir)
+	if params.SortDir != "" && params.SortDir != "asc" && params.SortDir != "desc" {
+		return nil, fmt.Errorf("invalid sort_dir parameter (must be 'asc' or 'desc')")
 	}
 	
-	// Parse sort_by
-	params.SortBy = strings.TrimSpace(r.URL.Query().Get("sort_by"))
 	if params.SortBy != "" {
-		// Validate allowed sort fields
-		allowedSortFields := []string{"created_at", "updated_at", "username", "email", "first_name", "last_name", "login_count"}
-		validSort := false
-		for _, field := range allowedSortFields {
-			if params.SortBy == field {
-				validSort = true
-				break
+		params.Sort = models.ParseSortFields(params.SortBy, params.SortDir)
+		if len(params.Sort) == 0 {
+			return nil, fmt.Errorf("invalid sort_by parameter (allowed: %v)", models.UserSortFields)
+		}
+		
+		// Validate each sort field against the allowed set
+		seen := make(map[string]bool, len(params.Sort))
+		for _, sortField := range params.Sort {
+			validSort := false
+			for _, field := range models.UserSortFields {
+				if sortField.Field == field {
+					validSort = true
+					break
+				}
+			}
+			if !validSort {
+				return nil, fmt.Errorf("invalid sort_by field %q (allowed: %v)", sortField.Field, models.UserSortFields)
+			}
+			if seen[sortField.Field] {
+				return nil, fmt.Errorf("duplicate sort_by field %q", sortField.Field)
 			}
+			seen[sortField.Field] = true
+		}
+	}
+	
+	// Set defaults
+	params.SetDefaults()
+	
+	return params, nil
+}
+
+// selectCSVColumns returns the CSV headers and their column indexes for the selected fields
+// All columns are kept when no fields are selected
+func selectCSVColumns(fields []string) ([]string, []int) {
+	selected := make(map[string]bool, len(fields))
+	for _, field := range fields {
+		selected[field] = true
+	}
+	
+	var headers []string
+	var columns []int
+	for i, header := range models.UserCSVHeaders {
+		if len(fields) == 0 || selected[header] {
+			headers = append(headers, header)
+			columns = append(columns, i)
 		}
-		if !validSort {
-			return nil, fmt.Errorf("invalid sort_by parameter (allowed: %v)", allowedSortFields)
+	}
+	return headers, columns
+}
+
+// parseUserStatsParams parses the optional from/to date range for user statistics
+// Dates are YYYY-MM-DD and both bounds are inclusive of the whole day
+func (h *UserHandler) parseUserStatsParams(r *http.Request) (*models.UserStatsParams, error) {
+	params := &models.UserStatsParams{}
+	
+	if fromStr := strings.TrimSpace(r.URL.Query().Get("from")); fromStr != "" {
+		from, err := time.Parse("2006-01-02", fromStr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid from parameter (expected YYYY-MM-DD)")
 		}
+		params.From = &from
 	}
 	
-	// Parse sort_dir
-	params.SortDir = strings.ToLower(strings.TrimSpace(r.URL.Query().Get("sort_dir")))
-	if params.SortDir != "" && params.SortDir != "asc" && params.SortDir != "desc" {
-		return nil, fmt.Errorf("invalid sort_dir parameter (must be 'asc' or 'desc')")
+	if toStr := strings.TrimSpace(r.URL.Query().Get("to")); toStr != "" {
+		to, err := time.Parse("2006-01-02", toStr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid to parameter (expected YYYY-MM-DD)")
+		}
+		// Include the entire end date
+		to = to.Add(24 * time.Hour)
+		params.To = &to
 	}
 	
-	// Set defaults
-	params.SetDefaults()
+	if params.From != nil && params.To != nil && !params.From.Before(*params.To) {
+		return nil, fmt.Errorf("from must not be after to")
+	}
 	
 	return params, nil
-}
\ No newline at end of file
+}
+
+// toValidationErrors converts field errors from the service into response details
+// Values are not echoed back since they may include passwords.
+func toValidationErrors(fieldErrs models.FieldErrors) []response.ValidationError {
+	details := make([]response.ValidationError, len(fieldErrs))
+	for i, fieldErr := range fieldErrs {
+		details[i] = response.NewValidationError(fieldErr.Field, fieldErr.Message, "")
+	}
+	return details
+}
+
+// toUserResponse converts a user to its response DTO, including audit fields for admin callers
+func toUserResponse(r *http.Request, user *models.User) models.UserResponse {
+	if claims, ok := middleware.ClaimsFromContext(r.Context()); ok && claims.HasRole(models.RoleAdmin) {
+		return user.ToAdminUserResponse()
+	}
+	return user.ToUserResponse()
+}
+
+// parseIfMatchVersion reads the expected user version from the If-Match header
+// The header holds a single quoted version number such as "3"; ok is false when it is absent
+func parseIfMatchVersion(r *http.Request) (version int64, ok bool, err error) {
+	header := strings.TrimSpace(r.Header.Get("If-Match"))
+	if header == "" {
+		return 0, false, nil
+	}
+
+	value := strings.Trim(strings.TrimPrefix(header, "W/"), `"`)
+	version, err = strconv.ParseInt(value, 10, 64)
+	if err != nil || version < 0 {
+		return 0, false, fmt.Errorf("If-Match must contain the user version")
+	}
+	return version, true, nil
+}
+
+// handleServiceError logs a failed service call and sends the matching response
+// Errors the client caused are logged as warnings, since they are not server faults.
+func (h *UserHandler) handleServiceError(w http.ResponseWriter, msg string, err error, args ...interface{}) {
+	if response.ServiceErrorStatus(err) < http.StatusInternalServerError {
+		h.logger.Warn(msg, append(args, "error", err.Error())...)
+	} else {
+		h.logFailure(msg, err, args...)
+	}
+	response.HandleServiceError(w, err)
+}
+
+// logFailure logs an error the handler could not map to a client error
+// Canceled requests and expired deadlines are not server faults, so they are logged as warnings.
+func (h *UserHandler) logFailure(msg string, err error, args ...interface{}) {
+	if response.IsContextError(err) {
+		h.logger.Warn(msg, append(args, "error", err.Error())...)
+		return
+	}
+	h.logger.Error(msg, err, args...)
+}
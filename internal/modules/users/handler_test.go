@@ -0,0 +1,2736 @@
+// internal/modules/users/handler_test.go
+package users
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"log/slog"
+	"maps"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"go-template/internal/models"
+	"go-template/internal/shared/middleware"
+	"go-template/internal/shared/response"
+	"go-template/internal/shared/utils"
+)
+
+// testRequest describes a request served through a single handler route
+type testRequest struct {
+	pattern string // route pattern, e.g. "GET /api/v1/users/{id}"
+	handler http.HandlerFunc
+	method  string
+	target  string
+	body    string
+	header  map[string]string
+	claims  *utils.TokenClaims // authenticated caller, if any
+}
+
+// newTestHandler builds a UserHandler over tu's service
+func newTestHandler(tu *testUsers) *UserHandler {
+	return NewUserHandler(tu.service, 5*time.Minute, time.Minute, tu.logger)
+}
+
+// serve sends req through a mux holding only its route and decodes the response envelope
+func serve(t *testing.T, req testRequest) (*httptest.ResponseRecorder, response.Response) {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.Handle(req.pattern, req.handler)
+
+	var body io.Reader
+	if req.body != "" {
+		body = strings.NewReader(req.body)
+	}
+	r := httptest.NewRequest(req.method, req.target, body)
+	if req.body != "" {
+		r.Header.Set("Content-Type", "application/json")
+	}
+	for name, value := range req.header {
+		r.Header.Set(name, value)
+	}
+	if req.claims != nil {
+		r = r.WithContext(middleware.WithClaims(r.Context(), req.claims))
+	}
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, r)
+
+	var resp response.Response
+	if strings.HasPrefix(rec.Header().Get("Content-Type"), "application/json") {
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("invalid response body %q: %v", rec.Body.String(), err)
+		}
+	}
+	return rec, resp
+}
+
+// mustJSON encodes v, failing the test on error
+func mustJSON(t *testing.T, v interface{}) string {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	return string(data)
+}
+
+// createRequestBodies converts reqs to JSON objects including the password,
+// which CreateUserRequest leaves out when it is marshaled
+func createRequestBodies(reqs []models.CreateUserRequest) []map[string]string {
+	bodies := make([]map[string]string, len(reqs))
+	for i, req := range reqs {
+		bodies[i] = map[string]string{
+			"username": req.Username,
+			"email":    req.Email,
+			"password": req.Password,
+		}
+	}
+	return bodies
+}
+
+func TestBulkCreateUsersHandler(t *testing.T) {
+	oversized := make([]models.CreateUserRequest, MaxBulkCreateSize+1)
+	for i := range oversized {
+		oversized[i] = createRequest("user" + strings.Repeat("x", i%20))
+	}
+
+	tests := []struct {
+		name          string
+		existing      []string
+		reqs          []models.CreateUserRequest
+		wantStatus    int
+		wantSucceeded int
+		wantFailed    int
+	}{
+		{
+			name:          "valid batch",
+			reqs:          []models.CreateUserRequest{createRequest("alice"), createRequest("bob")},
+			wantStatus:    http.StatusCreated,
+			wantSucceeded: 2,
+		},
+		{
+			name:          "mixed batch with a duplicate",
+			existing:      []string{"bob"},
+			reqs:          []models.CreateUserRequest{createRequest("alice"), createRequest("bob")},
+			wantStatus:    http.StatusMultiStatus,
+			wantSucceeded: 1,
+			wantFailed:    1,
+		},
+		{
+			name:       "oversized batch",
+			reqs:       oversized,
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tu := newTestUsers(t)
+			h := newTestHandler(tu)
+			for _, username := range tt.existing {
+				tu.createUser(t, models.WithUsername(username))
+			}
+
+			rec, resp := serve(t, testRequest{
+				pattern: "POST /api/v1/users/bulk",
+				handler: h.BulkCreateUsers,
+				method:  http.MethodPost,
+				target:  "/api/v1/users/bulk",
+				body:    mustJSON(t, createRequestBodies(tt.reqs)),
+			})
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantStatus == http.StatusBadRequest {
+				return
+			}
+
+			var result models.BulkCreateResponse
+			if err := json.Unmarshal([]byte(mustJSON(t, resp.Data)), &result); err != nil {
+				t.Fatalf("invalid bulk result: %v", err)
+			}
+			if result.Succeeded != tt.wantSucceeded || result.Failed != tt.wantFailed {
+				t.Errorf("succeeded, failed = %d, %d, want %d, %d", result.Succeeded, result.Failed, tt.wantSucceeded, tt.wantFailed)
+			}
+			if len(result.Results) != len(tt.reqs) {
+				t.Errorf("got %d results, want %d", len(result.Results), len(tt.reqs))
+			}
+		})
+	}
+}
+
+func TestRestoreUserHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		deleted    bool
+		id         string
+		wantStatus int
+	}{
+		{name: "deleted user", deleted: true, wantStatus: http.StatusOK},
+		{name: "user that is not deleted", wantStatus: http.StatusBadRequest},
+		{name: "unknown user", id: "507f1f77bcf86cd799439011", wantStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tu := newTestUsers(t)
+			h := newTestHandler(tu)
+			user := tu.createUser(t)
+			id := user.GetIDString()
+			if tt.id != "" {
+				id = tt.id
+			}
+			if tt.deleted {
+				if err := tu.service.DeleteUser(context.Background(), id); err != nil {
+					t.Fatalf("DeleteUser() error = %v", err)
+				}
+			}
+
+			rec, resp := serve(t, testRequest{
+				pattern: "POST /api/v1/users/{id}/restore",
+				handler: h.RestoreUser,
+				method:  http.MethodPost,
+				target:  "/api/v1/users/" + id + "/restore",
+			})
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantStatus == http.StatusOK && !resp.Success {
+				t.Errorf("response = %+v, want success", resp)
+			}
+		})
+	}
+}
+
+// authenticate wraps handler in the access token checks the routes use
+func authenticate(tu *testUsers, handler http.Handler) http.Handler {
+	versions := NewTokenVersions(tu.repo, tu.cache, tu.logger)
+	return middleware.RequireAuth(tu.tokens, versions, tu.logger)(handler)
+}
+
+// accessToken issues an access token for user with their current roles and token version
+func accessToken(t *testing.T, tu *testUsers, user *models.User) string {
+	t.Helper()
+	token, err := tu.tokens.GenerateAccessToken(user.GetIDString(), user.Roles, user.TokenVersion)
+	if err != nil {
+		t.Fatalf("GenerateAccessToken() error = %v", err)
+	}
+	return token
+}
+
+func TestSetUserRolesHandler(t *testing.T) {
+	tests := []struct {
+		name        string
+		callerRoles []string
+		body        string
+		wantStatus  int
+	}{
+		{
+			name:        "valid change",
+			callerRoles: []string{models.RoleAdmin},
+			body:        `{"roles":["user","moderator"]}`,
+			wantStatus:  http.StatusOK,
+		},
+		{
+			name:        "unknown role",
+			callerRoles: []string{models.RoleAdmin},
+			body:        `{"roles":["user","superuser"]}`,
+			wantStatus:  http.StatusBadRequest,
+		},
+		{
+			name:        "empty roles",
+			callerRoles: []string{models.RoleAdmin},
+			body:        `{"roles":[]}`,
+			wantStatus:  http.StatusBadRequest,
+		},
+		{
+			name:        "non-admin caller",
+			callerRoles: []string{models.RoleUser},
+			body:        `{"roles":["admin"]}`,
+			wantStatus:  http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tu := newTestUsers(t)
+			h := newTestHandler(tu)
+			user := tu.createUser(t)
+			caller := tu.createUser(t, models.WithRoles(tt.callerRoles...))
+
+			requireAdmin := middleware.RequireRole(models.RoleAdmin)
+			rec, _ := serve(t, testRequest{
+				pattern: "PUT /api/v1/users/{id}/roles",
+				handler: authenticate(tu, requireAdmin(http.HandlerFunc(h.SetUserRoles))).ServeHTTP,
+				method:  http.MethodPut,
+				target:  "/api/v1/users/" + user.GetIDString() + "/roles",
+				body:    tt.body,
+				header:  map[string]string{"Authorization": "Bearer " + accessToken(t, tu, caller)},
+			})
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+
+			wantRoles := user.Roles
+			if tt.wantStatus == http.StatusOK {
+				wantRoles = []string{models.RoleUser, models.RoleMod}
+			}
+			if stored := tu.storedUser(t, user.GetIDString()); !slices.Equal(stored.Roles, wantRoles) {
+				t.Errorf("stored roles = %v, want %v", stored.Roles, wantRoles)
+			}
+		})
+	}
+}
+
+func TestRemovedRoleRejectsExistingTokens(t *testing.T) {
+	tu := newTestUsers(t)
+	admin := tu.createUser(t, models.WithRoles(models.RoleUser, models.RoleAdmin))
+	token := accessToken(t, tu, admin)
+
+	protected := authenticate(tu, middleware.RequireRole(models.RoleAdmin)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})))
+	request := testRequest{
+		pattern: "GET /admin",
+		handler: protected.ServeHTTP,
+		method:  http.MethodGet,
+		target:  "/admin",
+		header:  map[string]string{"Authorization": "Bearer " + token},
+	}
+
+	if rec, _ := serve(t, request); rec.Code != http.StatusNoContent {
+		t.Fatalf("status before role removal = %d, want %d", rec.Code, http.StatusNoContent)
+	}
+
+	if _, err := tu.service.SetUserRoles(context.Background(), admin.GetIDString(), &models.SetRolesRequest{Roles: []string{models.RoleUser}}); err != nil {
+		t.Fatalf("SetUserRoles() error = %v", err)
+	}
+
+	if rec, _ := serve(t, request); rec.Code != http.StatusUnauthorized {
+		t.Errorf("status after role removal = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestGetUserStatsHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+		wantTotal  int
+	}{
+		{name: "all users", wantStatus: http.StatusOK, wantTotal: 3},
+		{name: "range includes the end date", query: "?from=2026-03-01&to=2026-03-14", wantStatus: http.StatusOK, wantTotal: 2},
+		{name: "single day", query: "?from=2026-03-14&to=2026-03-14", wantStatus: http.StatusOK, wantTotal: 1},
+		{name: "to only", query: "?to=2026-02-28", wantStatus: http.StatusOK, wantTotal: 1},
+		{name: "invalid from", query: "?from=03/01/2026", wantStatus: http.StatusBadRequest},
+		{name: "invalid to", query: "?to=2026-13-01", wantStatus: http.StatusBadRequest},
+		{name: "from after to", query: "?from=2026-03-10&to=2026-03-01", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tu := newTestUsers(t)
+			h := newTestHandler(tu)
+			for _, createdAt := range []time.Time{
+				time.Date(2026, 2, 1, 9, 0, 0, 0, time.UTC),
+				time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC),
+				time.Date(2026, 3, 14, 23, 0, 0, 0, time.UTC),
+			} {
+				user := models.NewTestUser()
+				user.CreatedAt = createdAt
+				if err := tu.repo.Create(context.Background(), user); err != nil {
+					t.Fatalf("Create() error = %v", err)
+				}
+			}
+
+			rec, resp := serve(t, testRequest{
+				pattern: "GET /api/v1/users/stats",
+				handler: h.GetUserStats,
+				method:  http.MethodGet,
+				target:  "/api/v1/users/stats" + tt.query,
+			})
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var stats models.UserStatsResponse
+			if err := json.Unmarshal([]byte(mustJSON(t, resp.Data)), &stats); err != nil {
+				t.Fatalf("invalid stats: %v", err)
+			}
+			if stats.TotalUsers != tt.wantTotal {
+				t.Errorf("TotalUsers = %d, want %d", stats.TotalUsers, tt.wantTotal)
+			}
+		})
+	}
+}
+
+func TestGetUsersHandlerSort(t *testing.T) {
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+		want       []string
+	}{
+		{name: "default is newest first", wantStatus: http.StatusOK, want: []string{"carol", "bob", "alice"}},
+		{name: "multiple fields", query: "?sort_by=last_name,-created_at", wantStatus: http.StatusOK, want: []string{"carol", "alice", "bob"}},
+		{name: "sort_dir applies to unprefixed fields", query: "?sort_by=last_name,username&sort_dir=desc", wantStatus: http.StatusOK, want: []string{"bob", "carol", "alice"}},
+		{name: "unknown field", query: "?sort_by=last_name,password", wantStatus: http.StatusBadRequest},
+		{name: "duplicate field", query: "?sort_by=username,-username", wantStatus: http.StatusBadRequest},
+		{name: "empty field list", query: "?sort_by=,", wantStatus: http.StatusBadRequest},
+		{name: "invalid sort_dir", query: "?sort_by=username&sort_dir=up", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tu := newTestUsers(t)
+			h := newTestHandler(tu)
+			created := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+			for i, u := range []struct{ username, lastName string }{
+				{"alice", "Adams"}, {"bob", "Brown"}, {"carol", "Adams"},
+			} {
+				user := models.NewTestUser(models.WithUsername(u.username), models.WithName("Test", u.lastName))
+				user.CreatedAt = created.Add(time.Duration(i) * time.Hour)
+				if err := tu.repo.Create(context.Background(), user); err != nil {
+					t.Fatalf("Create() error = %v", err)
+				}
+			}
+
+			rec, resp := serve(t, testRequest{
+				pattern: "GET /api/v1/users",
+				handler: h.GetUsers,
+				method:  http.MethodGet,
+				target:  "/api/v1/users" + tt.query,
+			})
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var users []models.UserResponse
+			if err := json.Unmarshal([]byte(mustJSON(t, resp.Data)), &users); err != nil {
+				t.Fatalf("invalid users: %v", err)
+			}
+			got := make([]string, len(users))
+			for i, user := range users {
+				got[i] = user.Username
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("order = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetUsersHandlerPagination(t *testing.T) {
+	tests := []struct {
+		name      string
+		users     int
+		query     string
+		wantItems int
+		wantMeta  response.Meta
+		wantRels  []string // rel values of the Link header, in order
+	}{
+		{name: "first page", users: 5, query: "?page=1&limit=2", wantItems: 2, wantMeta: response.Meta{Page: 1, Limit: 2, Total: 5, TotalPages: 3, HasNext: true}, wantRels: []string{"first", "next", "last"}},
+		{name: "middle page", users: 5, query: "?page=2&limit=2", wantItems: 2, wantMeta: response.Meta{Page: 2, Limit: 2, Total: 5, TotalPages: 3, HasNext: true, HasPrev: true}, wantRels: []string{"first", "prev", "next", "last"}},
+		{name: "last page", users: 5, query: "?page=3&limit=2", wantItems: 1, wantMeta: response.Meta{Page: 3, Limit: 2, Total: 5, TotalPages: 3, HasPrev: true}, wantRels: []string{"first", "prev", "last"}},
+		{name: "no users", query: "?page=1&limit=2", wantMeta: response.Meta{Page: 1, Limit: 2}, wantRels: []string{"first", "last"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tu := newTestUsers(t)
+			h := newTestHandler(tu)
+			for i := 0; i < tt.users; i++ {
+				tu.createUser(t)
+			}
+
+			rec, resp := serve(t, testRequest{
+				pattern: "GET /api/v1/users",
+				handler: h.GetUsers,
+				method:  http.MethodGet,
+				target:  "/api/v1/users" + tt.query,
+			})
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+			}
+
+			var users []models.UserResponse
+			if err := json.Unmarshal([]byte(mustJSON(t, resp.Data)), &users); err != nil {
+				t.Fatalf("invalid users: %v", err)
+			}
+			if len(users) != tt.wantItems {
+				t.Errorf("got %d users, want %d", len(users), tt.wantItems)
+			}
+			if resp.Meta == nil || *resp.Meta != tt.wantMeta {
+				t.Errorf("meta = %+v, want %+v", resp.Meta, tt.wantMeta)
+			}
+
+			var rels []string
+			for _, link := range strings.Split(rec.Header().Get("Link"), ", ") {
+				_, rel, _ := strings.Cut(link, `; rel=`)
+				rels = append(rels, strings.Trim(rel, `"`))
+			}
+			if !slices.Equal(rels, tt.wantRels) {
+				t.Errorf("Link rels = %v, want %v (Link: %s)", rels, tt.wantRels, rec.Header().Get("Link"))
+			}
+		})
+	}
+}
+
+func TestGetUserHandlerConditional(t *testing.T) {
+	tests := []struct {
+		name        string
+		ifNoneMatch func(first string) string
+		update      bool
+		wantStatus  int
+	}{
+		{name: "first request", ifNoneMatch: func(string) string { return "" }, wantStatus: http.StatusOK},
+		{name: "unchanged", ifNoneMatch: func(first string) string { return first }, wantStatus: http.StatusNotModified},
+		{name: "stale tag", ifNoneMatch: func(string) string { return `"stale"` }, wantStatus: http.StatusOK},
+		{name: "user changed", ifNoneMatch: func(first string) string { return first }, update: true, wantStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tu := newTestUsers(t)
+			h := newTestHandler(tu)
+			user := tu.createUser(t)
+			request := testRequest{
+				pattern: "GET /api/v1/users/{id}",
+				handler: h.GetUser,
+				method:  http.MethodGet,
+				target:  "/api/v1/users/" + user.GetIDString(),
+			}
+
+			first, _ := serve(t, request)
+			etag := first.Header().Get("ETag")
+			if first.Code != http.StatusOK || etag == "" {
+				t.Fatalf("first response status = %d, ETag = %q, want 200 with an ETag", first.Code, etag)
+			}
+
+			if tt.update {
+				bio := "Updated bio"
+				if _, err := tu.service.UpdateUser(context.Background(), user.GetIDString(), &models.UpdateUserRequest{Bio: &bio}); err != nil {
+					t.Fatalf("UpdateUser() error = %v", err)
+				}
+			}
+
+			if tag := tt.ifNoneMatch(etag); tag != "" {
+				request.header = map[string]string{"If-None-Match": tag}
+			}
+			rec, _ := serve(t, request)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if rec.Header().Get("ETag") == "" {
+				t.Error("response has no ETag")
+			}
+
+			switch tt.wantStatus {
+			case http.StatusNotModified:
+				if rec.Body.Len() != 0 {
+					t.Errorf("304 body = %q, want empty", rec.Body.String())
+				}
+				if rec.Header().Get("ETag") != etag {
+					t.Errorf("ETag = %q, want %q", rec.Header().Get("ETag"), etag)
+				}
+			default:
+				if rec.Body.Len() == 0 {
+					t.Error("200 response has no body")
+				}
+				if tt.update && rec.Header().Get("ETag") == etag {
+					t.Errorf("ETag %q unchanged after an update", etag)
+				}
+			}
+		})
+	}
+}
+
+func TestGetUsersHandlerFormat(t *testing.T) {
+	tests := []struct {
+		name            string
+		query           string
+		accept          string
+		wantContentType string
+	}{
+		{name: "default JSON", wantContentType: "application/json"},
+		{name: "format query", query: "?format=csv", wantContentType: "text/csv"},
+		{name: "Accept header", accept: "text/csv", wantContentType: "text/csv"},
+		{name: "unknown format falls back to JSON", query: "?format=xml", wantContentType: "application/json"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tu := newTestUsers(t)
+			h := newTestHandler(tu)
+			tu.createUser(t, models.WithUsername("alice"), models.WithName("Alice", "Smith, Jr."))
+
+			req := testRequest{
+				pattern: "GET /api/v1/users",
+				handler: h.GetUsers,
+				method:  http.MethodGet,
+				target:  "/api/v1/users" + tt.query,
+			}
+			if tt.accept != "" {
+				req.header = map[string]string{"Accept": tt.accept}
+			}
+			rec, _ := serve(t, req)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+			}
+			if got := rec.Header().Get("Content-Type"); !strings.HasPrefix(got, tt.wantContentType) {
+				t.Fatalf("Content-Type = %q, want %s", got, tt.wantContentType)
+			}
+			if tt.wantContentType != "text/csv" {
+				return
+			}
+
+			records, err := csv.NewReader(rec.Body).ReadAll()
+			if err != nil {
+				t.Fatalf("invalid CSV: %v", err)
+			}
+			if len(records) != 2 || !slices.Equal(records[0], models.UserCSVHeaders) {
+				t.Fatalf("records = %v, want a header line and one user", records)
+			}
+			row := map[string]string{}
+			for i, header := range records[0] {
+				row[header] = records[1][i]
+			}
+			if row["username"] != "alice" || row["last_name"] != "Smith, Jr." {
+				t.Errorf("row = %v, want alice with last name %q", row, "Smith, Jr.")
+			}
+			if rec.Header().Get("X-Total-Count") != "1" {
+				t.Errorf("X-Total-Count = %q, want 1", rec.Header().Get("X-Total-Count"))
+			}
+		})
+	}
+}
+
+func TestSearchUsersHandlerModes(t *testing.T) {
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+		want       []string
+	}{
+		{name: "short query falls back to regex", query: "?q=al", wantStatus: http.StatusOK, want: []string{"alice", "bob"}},
+		{name: "longer query uses text search", query: "?q=alison", wantStatus: http.StatusOK, want: []string{"bob"}},
+		{name: "text search needs whole words", query: "?q=alis", wantStatus: http.StatusOK, want: []string{}},
+		{name: "explicit regex mode", query: "?q=alis&mode=regex", wantStatus: http.StatusOK, want: []string{"bob"}},
+		{name: "explicit text mode", query: "?q=al&mode=text", wantStatus: http.StatusOK, want: []string{}},
+		{name: "invalid mode", query: "?q=alice&mode=fuzzy", wantStatus: http.StatusBadRequest},
+		{name: "missing query", query: "?mode=text", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tu := newTestUsers(t)
+			h := newTestHandler(tu)
+			tu.createUser(t, models.WithUsername("alice"), models.WithName("Alice", "Smith"))
+			tu.createUser(t, models.WithUsername("bob"), models.WithName("Bob", "Alison"))
+
+			rec, resp := serve(t, testRequest{
+				pattern: "GET /api/v1/users/search",
+				handler: h.SearchUsers,
+				method:  http.MethodGet,
+				target:  "/api/v1/users/search" + tt.query,
+			})
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var profiles []models.UserProfileResponse
+			if err := json.Unmarshal([]byte(mustJSON(t, resp.Data)), &profiles); err != nil {
+				t.Fatalf("invalid profiles: %v", err)
+			}
+			got := []string{}
+			for _, profile := range profiles {
+				got = append(got, profile.Username)
+			}
+			slices.Sort(got)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("results = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// pngImage encodes a small PNG image
+func pngImage(t *testing.T) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 4, 4))); err != nil {
+		t.Fatalf("png.Encode() error = %v", err)
+	}
+	return buf.Bytes()
+}
+
+// multipartBody builds a multipart form holding content as the given file field
+func multipartBody(t *testing.T, field string, content []byte) (string, string) {
+	t.Helper()
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+	part, err := writer.CreateFormFile(field, "avatar.png")
+	if err != nil {
+		t.Fatalf("CreateFormFile() error = %v", err)
+	}
+	part.Write(content)
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	return buf.String(), writer.FormDataContentType()
+}
+
+func TestUploadAvatarHandler(t *testing.T) {
+	valid := pngImage(t)
+	oversized := append(append([]byte{}, valid...), make([]byte, MaxAvatarSize)...)
+
+	tests := []struct {
+		name       string
+		field      string
+		content    []byte
+		wantStatus int
+	}{
+		{name: "small png", field: "avatar", content: valid, wantStatus: http.StatusOK},
+		{name: "oversized image", field: "avatar", content: oversized, wantStatus: http.StatusBadRequest},
+		{name: "wrong content type", field: "avatar", content: []byte("GIF89a not really an image"), wantStatus: http.StatusBadRequest},
+		{name: "empty file", field: "avatar", content: []byte{}, wantStatus: http.StatusBadRequest},
+		{name: "missing file field", field: "picture", content: valid, wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tu := newTestUsers(t)
+			h := newTestHandler(tu)
+			user := tu.createUser(t)
+
+			body, contentType := multipartBody(t, tt.field, tt.content)
+			rec, _ := serve(t, testRequest{
+				pattern: AvatarUploadPattern,
+				handler: h.UploadAvatar,
+				method:  http.MethodPost,
+				target:  "/api/v1/users/" + user.GetIDString() + "/avatar",
+				body:    body,
+				header:  map[string]string{"Content-Type": contentType},
+			})
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+
+			stored := tu.storedUser(t, user.GetIDString())
+			if tt.wantStatus != http.StatusOK {
+				if stored.Avatar != user.Avatar {
+					t.Errorf("avatar = %q after a rejected upload, want it unchanged", stored.Avatar)
+				}
+				return
+			}
+
+			key, ok := tu.files.KeyFromURL(stored.Avatar)
+			if !ok {
+				t.Fatalf("avatar = %q, want a URL under the storage base URL", stored.Avatar)
+			}
+			served := httptest.NewRecorder()
+			tu.files.Handler().ServeHTTP(served, httptest.NewRequest(http.MethodGet, "/"+key, nil))
+			if served.Code != http.StatusOK || !bytes.Equal(served.Body.Bytes(), tt.content) {
+				t.Errorf("stored avatar status = %d, %d bytes, want the uploaded %d bytes", served.Code, served.Body.Len(), len(tt.content))
+			}
+		})
+	}
+}
+
+func TestUpdateUserHandlerVersionConflict(t *testing.T) {
+	tests := []struct {
+		name string
+		// between runs after the first update and before the second
+		between    func(t *testing.T, tu *testUsers, id string)
+		sameBase   bool
+		wantSecond int
+	}{
+		{name: "second writer from the same version conflicts", sameBase: true, wantSecond: http.StatusConflict},
+		{name: "second writer from the new version succeeds", wantSecond: http.StatusOK},
+		{
+			name: "login bookkeeping does not conflict",
+			between: func(t *testing.T, tu *testUsers, id string) {
+				ctx := context.Background()
+				if err := tu.repo.UpdateLastLogin(ctx, id); err != nil {
+					t.Fatalf("UpdateLastLogin() error = %v", err)
+				}
+				if err := tu.repo.IncrementLoginCount(ctx, id); err != nil {
+					t.Fatalf("IncrementLoginCount() error = %v", err)
+				}
+			},
+			wantSecond: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tu := newTestUsers(t)
+			h := newTestHandler(tu)
+			user := tu.createUser(t)
+			id := user.GetIDString()
+
+			patch := func(version int64, firstName string) *httptest.ResponseRecorder {
+				rec, _ := serve(t, testRequest{
+					pattern: "PATCH /api/v1/users/{id}",
+					handler: h.UpdateUser,
+					method:  http.MethodPatch,
+					target:  "/api/v1/users/" + id,
+					body:    `{"first_name":"` + firstName + `"}`,
+					header:  map[string]string{"If-Match": fmt.Sprintf("%q", strconv.FormatInt(version, 10))},
+				})
+				return rec
+			}
+
+			base := tu.storedUser(t, id).Version
+			if rec := patch(base, "First"); rec.Code != http.StatusOK {
+				t.Fatalf("first update status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+			}
+			// The second writer read the user before any write made after the first update
+			secondBase := tu.storedUser(t, id).Version
+			if tt.sameBase {
+				secondBase = base
+			}
+			if tt.between != nil {
+				tt.between(t, tu, id)
+			}
+			rec := patch(secondBase, "Second")
+			if rec.Code != tt.wantSecond {
+				t.Fatalf("second update status = %d, want %d: %s", rec.Code, tt.wantSecond, rec.Body.String())
+			}
+
+			want := "Second"
+			if tt.wantSecond != http.StatusOK {
+				want = "First"
+			}
+			if got := tu.storedUser(t, id).FirstName; got != want {
+				t.Errorf("stored first_name = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestGetUserHandlerAuditFields(t *testing.T) {
+	const creator = "665f00000000000000000001"
+
+	tests := []struct {
+		name      string
+		claims    *utils.TokenClaims
+		wantAudit bool
+	}{
+		{name: "admin caller", claims: &utils.TokenClaims{Subject: creator, Roles: []string{models.RoleAdmin}}, wantAudit: true},
+		{name: "regular caller", claims: &utils.TokenClaims{Subject: creator, Roles: []string{models.RoleUser}}},
+		{name: "anonymous caller"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tu := newTestUsers(t)
+			h := newTestHandler(tu)
+			req := createRequest("audited")
+			user, err := tu.service.CreateUser(callerContext(creator, models.RoleAdmin), &req)
+			if err != nil {
+				t.Fatalf("CreateUser() error = %v", err)
+			}
+
+			rec, resp := serve(t, testRequest{
+				pattern: "GET /api/v1/users/{id}",
+				handler: h.GetUser,
+				method:  http.MethodGet,
+				target:  "/api/v1/users/" + user.GetIDString(),
+				claims:  tt.claims,
+			})
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+			}
+
+			var got models.UserResponse
+			if err := json.Unmarshal([]byte(mustJSON(t, resp.Data)), &got); err != nil {
+				t.Fatalf("invalid user: %v", err)
+			}
+			want := ""
+			if tt.wantAudit {
+				want = creator
+			}
+			if got.CreatedBy != want || got.UpdatedBy != want {
+				t.Errorf("created_by = %q, updated_by = %q, want both %q", got.CreatedBy, got.UpdatedBy, want)
+			}
+		})
+	}
+}
+
+func TestGetUsersHandlerCachedAuditFields(t *testing.T) {
+	const creator = "665f00000000000000000001"
+
+	tests := []struct {
+		name string
+		// warm is the caller whose request fills the list cache
+		warm      *utils.TokenClaims
+		claims    *utils.TokenClaims
+		wantAudit bool
+	}{
+		{name: "admin after anonymous", claims: &utils.TokenClaims{Subject: creator, Roles: []string{models.RoleAdmin}}, wantAudit: true},
+		{name: "admin after admin", warm: &utils.TokenClaims{Subject: creator, Roles: []string{models.RoleAdmin}}, claims: &utils.TokenClaims{Subject: creator, Roles: []string{models.RoleAdmin}}, wantAudit: true},
+		{name: "regular caller after admin", warm: &utils.TokenClaims{Subject: creator, Roles: []string{models.RoleAdmin}}, claims: &utils.TokenClaims{Subject: creator, Roles: []string{models.RoleUser}}},
+		{name: "anonymous after admin", warm: &utils.TokenClaims{Subject: creator, Roles: []string{models.RoleAdmin}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tu := newTestUsers(t)
+			h := newTestHandler(tu)
+			req := createRequest("audited")
+			if _, err := tu.service.CreateUser(callerContext(creator, models.RoleAdmin), &req); err != nil {
+				t.Fatalf("CreateUser() error = %v", err)
+			}
+
+			list := func(claims *utils.TokenClaims) models.UserResponse {
+				t.Helper()
+				rec, resp := serve(t, testRequest{
+					pattern: "GET /api/v1/users",
+					handler: h.GetUsers,
+					method:  http.MethodGet,
+					target:  "/api/v1/users",
+					claims:  claims,
+				})
+				if rec.Code != http.StatusOK {
+					t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+				}
+				var users []models.UserResponse
+				if err := json.Unmarshal([]byte(mustJSON(t, resp.Data)), &users); err != nil {
+					t.Fatalf("invalid users: %v", err)
+				}
+				if len(users) != 1 {
+					t.Fatalf("got %d users, want 1", len(users))
+				}
+				return users[0]
+			}
+
+			list(tt.warm)
+			got := list(tt.claims)
+			if hits := tu.metrics.snapshot()["list/hit"]; hits != 1 {
+				t.Fatalf("list cache hits = %d, want 1", hits)
+			}
+
+			want := ""
+			if tt.wantAudit {
+				want = creator
+			}
+			if got.CreatedBy != want || got.UpdatedBy != want {
+				t.Errorf("created_by = %q, updated_by = %q, want both %q", got.CreatedBy, got.UpdatedBy, want)
+			}
+		})
+	}
+}
+
+func TestBatchGetUsersHandler(t *testing.T) {
+	const missing = "665f000000000000000000ff"
+
+	tests := []struct {
+		name string
+		// ids builds the requested IDs from the seeded users: alice, bob and the deleted carol
+		ids        func(alice, bob, carol string) []string
+		wantStatus int
+		wantNames  []string
+	}{
+		{
+			name:       "input order is preserved",
+			ids:        func(alice, bob, carol string) []string { return []string{bob, alice} },
+			wantStatus: http.StatusOK,
+			wantNames:  []string{"bob", "alice"},
+		},
+		{
+			name:       "nonexistent and deleted IDs are omitted",
+			ids:        func(alice, bob, carol string) []string { return []string{missing, alice, carol, bob} },
+			wantStatus: http.StatusOK,
+			wantNames:  []string{"alice", "bob"},
+		},
+		{
+			name:       "duplicates are returned once",
+			ids:        func(alice, bob, carol string) []string { return []string{alice, " " + alice + " ", alice} },
+			wantStatus: http.StatusOK,
+			wantNames:  []string{"alice"},
+		},
+		{
+			name:       "only unknown IDs",
+			ids:        func(alice, bob, carol string) []string { return []string{missing} },
+			wantStatus: http.StatusOK,
+			wantNames:  []string{},
+		},
+		{
+			name:       "invalid ID",
+			ids:        func(alice, bob, carol string) []string { return []string{alice, "not-an-id"} },
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "no IDs",
+			ids:        func(alice, bob, carol string) []string { return []string{} },
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name: "too many IDs",
+			ids: func(alice, bob, carol string) []string {
+				ids := make([]string, models.MaxBatchGetSize+1)
+				for i := range ids {
+					ids[i] = alice
+				}
+				return ids
+			},
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tu := newTestUsers(t)
+			h := newTestHandler(tu)
+			alice := tu.createUser(t, models.WithUsername("alice"), models.WithEmail("alice@example.com"))
+			bob := tu.createUser(t, models.WithUsername("bob"), models.WithEmail("bob@example.com"))
+			carol := tu.createUser(t, models.WithUsername("carol"), models.WithEmail("carol@example.com"))
+			if err := tu.repo.SoftDelete(context.Background(), carol.GetIDString()); err != nil {
+				t.Fatalf("SoftDelete() error = %v", err)
+			}
+
+			ids := tt.ids(alice.GetIDString(), bob.GetIDString(), carol.GetIDString())
+			rec, resp := serve(t, testRequest{
+				pattern: "POST /api/v1/users/batch-get",
+				handler: h.BatchGetUsers,
+				method:  http.MethodPost,
+				target:  "/api/v1/users/batch-get",
+				body:    mustJSON(t, map[string][]string{"ids": ids}),
+			})
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var users []models.UserResponse
+			if err := json.Unmarshal([]byte(mustJSON(t, resp.Data)), &users); err != nil {
+				t.Fatalf("invalid users: %v", err)
+			}
+			names := []string{}
+			for _, user := range users {
+				names = append(names, user.Username)
+			}
+			if !slices.Equal(names, tt.wantNames) {
+				t.Errorf("usernames = %v, want %v", names, tt.wantNames)
+			}
+		})
+	}
+}
+
+func TestMeHandlers(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		body       string
+		anonymous  bool
+		wantStatus int
+		// wantFirstName is the caller's stored first name afterwards
+		wantFirstName string
+	}{
+		{name: "get me", method: http.MethodGet, wantStatus: http.StatusOK, wantFirstName: "Me"},
+		{name: "get me unauthenticated", method: http.MethodGet, anonymous: true, wantStatus: http.StatusUnauthorized, wantFirstName: "Me"},
+		{name: "update me", method: http.MethodPatch, body: `{"first_name":"Changed"}`, wantStatus: http.StatusOK, wantFirstName: "Changed"},
+		{name: "update me unauthenticated", method: http.MethodPatch, body: `{"first_name":"Changed"}`, anonymous: true, wantStatus: http.StatusUnauthorized, wantFirstName: "Me"},
+		{name: "roles cannot be changed", method: http.MethodPatch, body: `{"first_name":"Changed","roles":["admin"]}`, wantStatus: http.StatusForbidden, wantFirstName: "Me"},
+		{name: "verification cannot be changed", method: http.MethodPatch, body: `{"is_verified":true}`, wantStatus: http.StatusForbidden, wantFirstName: "Me"},
+		{name: "verification date cannot be changed", method: http.MethodPatch, body: `{"email_verified_at":"2026-01-01T00:00:00Z"}`, wantStatus: http.StatusForbidden, wantFirstName: "Me"},
+		{name: "status cannot be changed", method: http.MethodPatch, body: `{"is_active":true}`, wantStatus: http.StatusForbidden, wantFirstName: "Me"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tu := newTestUsers(t)
+			h := newTestHandler(tu)
+			me := tu.createUser(t, models.WithUsername("me"), models.WithEmail("me@example.com"),
+				models.WithName("Me", "Myself"), models.WithVerified(false))
+			other := tu.createUser(t, models.WithUsername("other"), models.WithEmail("other@example.com"))
+
+			handler := h.GetMe
+			if tt.method == http.MethodPatch {
+				handler = h.UpdateMe
+			}
+			req := testRequest{
+				pattern: tt.method + " /api/v1/users/me",
+				handler: handler,
+				method:  tt.method,
+				target:  "/api/v1/users/me",
+				body:    tt.body,
+			}
+			if !tt.anonymous {
+				req.claims = &utils.TokenClaims{Subject: me.GetIDString(), Roles: me.Roles}
+			}
+
+			rec, resp := serve(t, req)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if rec.Code == http.StatusOK {
+				var got models.UserResponse
+				if err := json.Unmarshal([]byte(mustJSON(t, resp.Data)), &got); err != nil {
+					t.Fatalf("invalid user: %v", err)
+				}
+				if got.ID != me.GetIDString() {
+					t.Errorf("returned user %s, want the caller %s", got.ID, me.GetIDString())
+				}
+			}
+
+			stored := tu.storedUser(t, me.GetIDString())
+			if stored.FirstName != tt.wantFirstName {
+				t.Errorf("stored first_name = %q, want %q", stored.FirstName, tt.wantFirstName)
+			}
+			if !slices.Equal(stored.Roles, me.Roles) || stored.IsVerified || !stored.IsActive {
+				t.Errorf("stored roles = %v, verified = %v, active = %v, want them unchanged", stored.Roles, stored.IsVerified, stored.IsActive)
+			}
+			if tu.storedUser(t, other.GetIDString()).FirstName != other.FirstName {
+				t.Error("another user was modified")
+			}
+		})
+	}
+}
+
+func TestCreateUserHandlerStrictBody(t *testing.T) {
+	valid := `{"username":"alice","email":"alice@example.com","password":"` + createRequest("alice").Password + `"}`
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantCode   string
+	}{
+		{name: "valid body", body: valid, wantStatus: http.StatusCreated},
+		{name: "oversized body", body: `{"username":"` + strings.Repeat("a", int(response.DefaultMaxBodyBytes)) + `"}`, wantStatus: http.StatusBadRequest, wantCode: "INVALID_BODY"},
+		{name: "unknown field", body: `{"username":"alice","emial":"alice@example.com"}`, wantStatus: http.StatusBadRequest, wantCode: "INVALID_BODY"},
+		{name: "trailing garbage", body: valid + `garbage`, wantStatus: http.StatusBadRequest, wantCode: "INVALID_BODY"},
+		{name: "malformed JSON", body: `{"username":`, wantStatus: http.StatusBadRequest, wantCode: "INVALID_BODY"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tu := newTestUsers(t)
+			h := newTestHandler(tu)
+
+			rec, resp := serve(t, testRequest{
+				pattern: "POST /api/v1/users",
+				handler: h.CreateUser,
+				method:  http.MethodPost,
+				target:  "/api/v1/users",
+				body:    tt.body,
+			})
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantCode != "" && (resp.Error == nil || resp.Error.Code != tt.wantCode) {
+				t.Errorf("error = %+v, want code %s", resp.Error, tt.wantCode)
+			}
+
+			exists, err := tu.repo.ExistsByUsername(context.Background(), "alice")
+			if err != nil {
+				t.Fatalf("ExistsByUsername() error = %v", err)
+			}
+			if want := tt.wantStatus == http.StatusCreated; exists != want {
+				t.Errorf("user stored = %v, want %v", exists, want)
+			}
+
+			// Clients find the new user through the Location header
+			wantLocation := ""
+			if exists {
+				user, err := tu.repo.GetByUsername(context.Background(), "alice")
+				if err != nil {
+					t.Fatalf("GetByUsername() error = %v", err)
+				}
+				wantLocation = "/api/v1/users/" + user.GetIDString()
+			}
+			if got := rec.Header().Get("Location"); got != wantLocation {
+				t.Errorf("Location = %q, want %q", got, wantLocation)
+			}
+		})
+	}
+}
+
+func TestParseUsersQueryParamsFilters(t *testing.T) {
+	after := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	before := time.Date(2026, 2, 1, 12, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		query   string
+		wantErr bool
+		check   func(t *testing.T, params *models.UsersQueryParams)
+	}{
+		{
+			name:  "no filters",
+			query: "",
+			check: func(t *testing.T, params *models.UsersQueryParams) {
+				if params.HasFilters() {
+					t.Errorf("params = %+v, want no filters", params)
+				}
+			},
+		},
+		{
+			name:  "verified",
+			query: "?is_verified=true",
+			check: func(t *testing.T, params *models.UsersQueryParams) {
+				if params.IsVerified == nil || !*params.IsVerified {
+					t.Errorf("IsVerified = %v, want true", params.IsVerified)
+				}
+			},
+		},
+		{
+			name:  "unverified",
+			query: "?is_verified=false",
+			check: func(t *testing.T, params *models.UsersQueryParams) {
+				if params.IsVerified == nil || *params.IsVerified {
+					t.Errorf("IsVerified = %v, want false", params.IsVerified)
+				}
+			},
+		},
+		{
+			name:  "has_role is normalized",
+			query: "?has_role=Admin,moderator",
+			check: func(t *testing.T, params *models.UsersQueryParams) {
+				if !slices.Equal(params.HasRoles, []string{"admin", "moderator"}) {
+					t.Errorf("HasRoles = %v, want [admin moderator]", params.HasRoles)
+				}
+			},
+		},
+		{
+			name:  "created range",
+			query: "?created_after=2026-01-01&created_before=2026-02-01T12:30:00Z",
+			check: func(t *testing.T, params *models.UsersQueryParams) {
+				if params.CreatedAfter == nil || !params.CreatedAfter.Equal(after) {
+					t.Errorf("CreatedAfter = %v, want %v", params.CreatedAfter, after)
+				}
+				if params.CreatedBefore == nil || !params.CreatedBefore.Equal(before) {
+					t.Errorf("CreatedBefore = %v, want %v", params.CreatedBefore, before)
+				}
+			},
+		},
+		{name: "invalid is_verified", query: "?is_verified=maybe", wantErr: true},
+		{name: "unknown has_role", query: "?has_role=admin,superuser", wantErr: true},
+		{name: "invalid created_after", query: "?created_after=yesterday", wantErr: true},
+		{name: "empty created range", query: "?created_after=2026-02-01&created_before=2026-02-01", wantErr: true},
+		{name: "reversed created range", query: "?created_after=2026-03-01&created_before=2026-02-01", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newTestHandler(newTestUsers(t))
+			r := httptest.NewRequest(http.MethodGet, "/api/v1/users"+tt.query, nil)
+
+			params, err := h.parseUsersQueryParams(r)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseUsersQueryParams() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr {
+				tt.check(t, params)
+			}
+		})
+	}
+}
+
+func TestGetUsersHandlerFields(t *testing.T) {
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+		wantKeys   []string // sorted keys of every returned user
+	}{
+		{name: "id and username", query: "?fields=username", wantStatus: http.StatusOK, wantKeys: []string{"id", "username"}},
+		{name: "computed field", query: "?fields=full_name, email", wantStatus: http.StatusOK, wantKeys: []string{"email", "full_name", "id"}},
+		{name: "duplicates and case", query: "?fields=Username,username", wantStatus: http.StatusOK, wantKeys: []string{"id", "username"}},
+		{name: "password is not selectable", query: "?fields=username,password", wantStatus: http.StatusBadRequest},
+		{name: "salt is not selectable", query: "?fields=salt", wantStatus: http.StatusBadRequest},
+		{name: "unknown field", query: "?fields=nickname", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tu := newTestUsers(t)
+			h := newTestHandler(tu)
+			alice := tu.createUser(t, models.WithUsername("alice"), models.WithEmail("alice@example.com"), models.WithName("Alice", "Adams"))
+
+			rec, resp := serve(t, testRequest{
+				pattern: "GET /api/v1/users",
+				handler: h.GetUsers,
+				method:  http.MethodGet,
+				target:  "/api/v1/users" + strings.ReplaceAll(tt.query, " ", "%20"),
+			})
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			if strings.Contains(rec.Body.String(), "password") || strings.Contains(rec.Body.String(), "salt") {
+				t.Errorf("response leaks credentials: %s", rec.Body.String())
+			}
+
+			var users []map[string]interface{}
+			if err := json.Unmarshal([]byte(mustJSON(t, resp.Data)), &users); err != nil {
+				t.Fatalf("invalid users: %v", err)
+			}
+			if len(users) != 1 {
+				t.Fatalf("got %d users, want 1", len(users))
+			}
+			keys := make([]string, 0, len(users[0]))
+			for key := range users[0] {
+				keys = append(keys, key)
+			}
+			slices.Sort(keys)
+			if !slices.Equal(keys, tt.wantKeys) {
+				t.Errorf("fields = %v, want %v", keys, tt.wantKeys)
+			}
+			if users[0]["id"] != alice.GetIDString() {
+				t.Errorf("id = %v, want %s", users[0]["id"], alice.GetIDString())
+			}
+		})
+	}
+}
+
+func TestExportUsersHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		accept     string
+		users      int
+		deleted    int
+		wantType   string
+		wantExt    string
+		wantHeader bool // CSV starts with a header row
+	}{
+		{name: "ndjson", users: 2*exportFlushInterval + 7, deleted: 3, wantType: "application/x-ndjson", wantExt: ".ndjson"},
+		{name: "csv", accept: "text/csv", users: 2*exportFlushInterval + 7, deleted: 3, wantType: "text/csv; charset=utf-8", wantExt: ".csv", wantHeader: true},
+		{name: "empty ndjson", wantType: "application/x-ndjson", wantExt: ".ndjson"},
+		{name: "empty csv", accept: "text/csv", wantType: "text/csv; charset=utf-8", wantExt: ".csv", wantHeader: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tu := newTestUsers(t)
+			h := newTestHandler(tu)
+
+			want := make(map[string]bool, tt.users)
+			for i := 0; i < tt.users; i++ {
+				username := fmt.Sprintf("user%04d", i)
+				tu.createUser(t, models.WithUsername(username), models.WithEmail(username+"@example.com"))
+				want[username] = true
+			}
+			for i := 0; i < tt.deleted; i++ {
+				user := tu.createUser(t, models.WithUsername(fmt.Sprintf("gone%d", i)), models.WithEmail(fmt.Sprintf("gone%d@example.com", i)))
+				if err := tu.repo.SoftDelete(context.Background(), user.GetIDString()); err != nil {
+					t.Fatalf("SoftDelete() error = %v", err)
+				}
+			}
+
+			header := map[string]string{}
+			if tt.accept != "" {
+				header["Accept"] = tt.accept
+			}
+			rec, _ := serve(t, testRequest{
+				pattern: "GET " + ExportPath,
+				handler: h.ExportUsers,
+				method:  http.MethodGet,
+				target:  ExportPath,
+				header:  header,
+			})
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+			}
+			if got := rec.Header().Get("Content-Type"); got != tt.wantType {
+				t.Errorf("Content-Type = %q, want %q", got, tt.wantType)
+			}
+			disposition := rec.Header().Get("Content-Disposition")
+			if !strings.HasPrefix(disposition, `attachment; filename="users-`) || !strings.HasSuffix(disposition, tt.wantExt+`"`) {
+				t.Errorf("Content-Disposition = %q, want an attachment named users-*%s", disposition, tt.wantExt)
+			}
+
+			got := make(map[string]bool)
+			if tt.wantHeader {
+				rows, err := csv.NewReader(rec.Body).ReadAll()
+				if err != nil {
+					t.Fatalf("invalid CSV: %v", err)
+				}
+				if len(rows) == 0 || !slices.Equal(rows[0], models.UserCSVHeaders) {
+					t.Fatalf("CSV header = %v, want %v", rows, models.UserCSVHeaders)
+				}
+				column := slices.Index(models.UserCSVHeaders, "username")
+				for _, row := range rows[1:] {
+					got[row[column]] = true
+				}
+			} else {
+				decoder := json.NewDecoder(rec.Body)
+				for decoder.More() {
+					var user models.UserResponse
+					if err := decoder.Decode(&user); err != nil {
+						t.Fatalf("invalid NDJSON line: %v", err)
+					}
+					if got[user.Username] {
+						t.Errorf("user %s exported twice", user.Username)
+					}
+					got[user.Username] = true
+				}
+			}
+
+			if len(got) != len(want) {
+				t.Errorf("exported %d users, want %d", len(got), len(want))
+			}
+			for username := range want {
+				if !got[username] {
+					t.Errorf("user %s missing from the export", username)
+				}
+			}
+			if strings.Contains(rec.Body.String(), "gone") {
+				t.Error("export includes deleted users")
+			}
+		})
+	}
+}
+
+func TestUpdateUserHandlerCaseInsensitiveConflict(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+	}{
+		{name: "username in another case", body: `{"username":"JohnDoe"}`, wantStatus: http.StatusConflict},
+		{name: "email in another case", body: `{"email":"JOHNDOE@example.com"}`, wantStatus: http.StatusConflict},
+		{name: "free username", body: `{"username":"JaneRoe"}`, wantStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tu := newTestUsers(t)
+			h := newTestHandler(tu)
+			tu.createUser(t, models.WithUsername("johndoe"), models.WithEmail("johndoe@example.com"))
+			other := tu.createUser(t, models.WithUsername("other"))
+
+			rec, resp := serve(t, testRequest{
+				pattern: "PATCH /api/v1/users/{id}",
+				handler: h.UpdateUser,
+				method:  http.MethodPatch,
+				target:  "/api/v1/users/" + other.GetIDString(),
+				body:    tt.body,
+			})
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantStatus == http.StatusConflict && (resp.Error == nil || resp.Error.Code != "CONFLICT") {
+				t.Errorf("error = %+v, want code CONFLICT", resp.Error)
+			}
+		})
+	}
+}
+
+// validationDetails decodes the field errors of a validation error response
+func validationDetails(t *testing.T, rec *httptest.ResponseRecorder) []response.ValidationError {
+	t.Helper()
+	var body struct {
+		Error struct {
+			Code    string                     `json:"code"`
+			Details []response.ValidationError `json:"details"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if body.Error.Code != response.ErrorCodeValidation {
+		t.Errorf("error code = %q, want %q", body.Error.Code, response.ErrorCodeValidation)
+	}
+	return body.Error.Details
+}
+
+func TestUserHandlersValidationDetails(t *testing.T) {
+	tests := []struct {
+		name       string
+		create     bool
+		body       string
+		wantFields []string
+	}{
+		{
+			name:       "create with bad email and short password",
+			create:     true,
+			body:       `{"username":"alice","email":"not-an-email","password":"Sh0rt"}`,
+			wantFields: []string{"email", "password"},
+		},
+		{
+			name:       "create with every required field invalid",
+			create:     true,
+			body:       `{"username":"a","email":"","password":"weak"}`,
+			wantFields: []string{"username", "email", "password"},
+		},
+		{
+			name:       "update with bad email and website",
+			body:       `{"email":"not-an-email","website":"not a url"}`,
+			wantFields: []string{"email", "website"},
+		},
+		{
+			name:       "update with an overlong name",
+			body:       `{"first_name":"` + strings.Repeat("a", 51) + `"}`,
+			wantFields: []string{"first_name"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tu := newTestUsers(t)
+			h := newTestHandler(tu)
+
+			req := testRequest{
+				pattern: "POST /api/v1/users",
+				handler: h.CreateUser,
+				method:  http.MethodPost,
+				target:  "/api/v1/users",
+				body:    tt.body,
+			}
+			if !tt.create {
+				user := tu.createUser(t)
+				req = testRequest{
+					pattern: "PATCH /api/v1/users/{id}",
+					handler: h.UpdateUser,
+					method:  http.MethodPatch,
+					target:  "/api/v1/users/" + user.GetIDString(),
+					body:    tt.body,
+				}
+			}
+
+			rec, _ := serve(t, req)
+			if rec.Code != http.StatusBadRequest {
+				t.Fatalf("status = %d, want 400: %s", rec.Code, rec.Body.String())
+			}
+
+			details := validationDetails(t, rec)
+			fields := make([]string, len(details))
+			for i, detail := range details {
+				fields[i] = detail.Field
+				if detail.Message == "" {
+					t.Errorf("detail for %q has no message", detail.Field)
+				}
+				if detail.Value != "" {
+					t.Errorf("detail for %q echoes value %q", detail.Field, detail.Value)
+				}
+			}
+			if !slices.Equal(fields, tt.wantFields) {
+				t.Errorf("detail fields = %v, want %v", fields, tt.wantFields)
+			}
+		})
+	}
+}
+
+func TestSetUserActiveHandlers(t *testing.T) {
+	tests := []struct {
+		name        string
+		callerRoles []string
+		active      bool // current status
+		action      string
+		wantStatus  int
+		wantActive  bool
+	}{
+		{name: "deactivate", callerRoles: []string{models.RoleAdmin}, active: true, action: "deactivate", wantStatus: http.StatusOK},
+		{name: "activate", callerRoles: []string{models.RoleAdmin}, active: false, action: "activate", wantStatus: http.StatusOK, wantActive: true},
+		{name: "already active", callerRoles: []string{models.RoleAdmin}, active: true, action: "activate", wantStatus: http.StatusBadRequest, wantActive: true},
+		{name: "already inactive", callerRoles: []string{models.RoleAdmin}, active: false, action: "deactivate", wantStatus: http.StatusBadRequest},
+		{name: "non-admin", callerRoles: []string{models.RoleUser}, active: true, action: "deactivate", wantStatus: http.StatusForbidden, wantActive: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tu := newTestUsers(t)
+			h := newTestHandler(tu)
+			user := tu.createUser(t, models.WithActive(tt.active))
+			caller := tu.createUser(t, models.WithRoles(tt.callerRoles...))
+
+			handler := h.ActivateUser
+			if tt.action == "deactivate" {
+				handler = h.DeactivateUser
+			}
+			requireAdmin := middleware.RequireRole(models.RoleAdmin)
+			rec, resp := serve(t, testRequest{
+				pattern: "POST /api/v1/users/{id}/" + tt.action,
+				handler: authenticate(tu, requireAdmin(http.HandlerFunc(handler))).ServeHTTP,
+				method:  http.MethodPost,
+				target:  "/api/v1/users/" + user.GetIDString() + "/" + tt.action,
+				header:  map[string]string{"Authorization": "Bearer " + accessToken(t, tu, caller)},
+			})
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantStatus == http.StatusBadRequest && (resp.Error == nil || !strings.Contains(resp.Error.Message, "already")) {
+				t.Errorf("error = %+v, want it to say the user is already in that state", resp.Error)
+			}
+			if stored := tu.storedUser(t, user.GetIDString()); stored.IsActive != tt.wantActive {
+				t.Errorf("stored active = %v, want %v", stored.IsActive, tt.wantActive)
+			}
+		})
+	}
+}
+
+func TestDeactivationRejectsExistingTokens(t *testing.T) {
+	tu := newTestUsers(t)
+	user := tu.createUser(t)
+	token := accessToken(t, tu, user)
+
+	protected := authenticate(tu, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	request := testRequest{
+		pattern: "GET /me",
+		handler: protected.ServeHTTP,
+		method:  http.MethodGet,
+		target:  "/me",
+		header:  map[string]string{"Authorization": "Bearer " + token},
+	}
+
+	if rec, _ := serve(t, request); rec.Code != http.StatusNoContent {
+		t.Fatalf("status before deactivation = %d, want %d", rec.Code, http.StatusNoContent)
+	}
+
+	if _, err := tu.service.SetUserActive(context.Background(), user.GetIDString(), false); err != nil {
+		t.Fatalf("SetUserActive() error = %v", err)
+	}
+
+	if rec, _ := serve(t, request); rec.Code != http.StatusUnauthorized {
+		t.Errorf("status after deactivation = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestBulkDeleteUsersHandler(t *testing.T) {
+	missing := "507f1f77bcf86cd799439011"
+
+	tests := []struct {
+		name         string
+		body         func(id string) string
+		wantStatus   int
+		wantMessage  string
+		wantDeleted  bool
+		wantNotFound []string
+	}{
+		{
+			name:         "dry run",
+			body:         func(id string) string { return `{"ids":["` + id + `","` + missing + `"],"dry_run":true}` },
+			wantStatus:   http.StatusOK,
+			wantMessage:  "Dry run: no users were deleted",
+			wantNotFound: []string{missing},
+		},
+		{
+			name:         "delete",
+			body:         func(id string) string { return `{"ids":["` + id + `","` + missing + `"]}` },
+			wantStatus:   http.StatusOK,
+			wantMessage:  "Users deleted successfully",
+			wantDeleted:  true,
+			wantNotFound: []string{missing},
+		},
+		{name: "invalid id", body: func(id string) string { return `{"ids":["` + id + `","nope"]}` }, wantStatus: http.StatusBadRequest},
+		{name: "no ids", body: func(id string) string { return `{"ids":[]}` }, wantStatus: http.StatusBadRequest},
+		{
+			name: "too many ids",
+			body: func(id string) string {
+				ids := strings.Repeat(`"`+id+`",`, models.MaxBulkDeleteSize)
+				return `{"ids":[` + ids + `"` + id + `"]}`
+			},
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tu := newTestUsers(t)
+			h := newTestHandler(tu)
+			user := tu.createUser(t)
+
+			rec, resp := serve(t, testRequest{
+				pattern: "POST /api/v1/users/bulk-delete",
+				handler: h.BulkDeleteUsers,
+				method:  http.MethodPost,
+				target:  "/api/v1/users/bulk-delete",
+				body:    tt.body(user.GetIDString()),
+			})
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if deleted := tu.storedUser(t, user.GetIDString()).DeletedAt != nil; deleted != tt.wantDeleted {
+				t.Errorf("user deleted = %v, want %v", deleted, tt.wantDeleted)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			if resp.Message != tt.wantMessage {
+				t.Errorf("message = %q, want %q", resp.Message, tt.wantMessage)
+			}
+			var result models.BulkDeleteResponse
+			if err := json.Unmarshal([]byte(mustJSON(t, resp.Data)), &result); err != nil {
+				t.Fatalf("decode data: %v", err)
+			}
+			if !slices.Equal(result.Deleted, []string{user.GetIDString()}) || !slices.Equal(result.NotFound, tt.wantNotFound) {
+				t.Errorf("result = %+v, want %s deleted and %v not found", result, user.GetIDString(), tt.wantNotFound)
+			}
+		})
+	}
+}
+
+func TestGetUsersHandlerLimit(t *testing.T) {
+	tests := []struct {
+		name       string
+		maxLimit   int
+		query      string
+		wantStatus int
+		wantLimit  int
+	}{
+		{name: "limit omitted", query: "", wantStatus: http.StatusOK, wantLimit: models.DefaultPageLimit},
+		{name: "limit at the maximum", query: "?limit=100", wantStatus: http.StatusOK, wantLimit: 100},
+		{name: "limit above the maximum", query: "?limit=101", wantStatus: http.StatusBadRequest},
+		{name: "zero limit", query: "?limit=0", wantStatus: http.StatusBadRequest},
+		{name: "configured maximum", maxLimit: 50, query: "?limit=50", wantStatus: http.StatusOK, wantLimit: 50},
+		{name: "above the configured maximum", maxLimit: 50, query: "?limit=51", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.maxLimit != 0 {
+				models.SetMaxPageLimit(tt.maxLimit)
+				t.Cleanup(func() { models.SetMaxPageLimit(models.DefaultMaxPageLimit) })
+			}
+			tu := newTestUsers(t)
+			h := newTestHandler(tu)
+			tu.createUser(t)
+
+			rec, resp := serve(t, testRequest{
+				pattern: "GET /api/v1/users",
+				handler: h.GetUsers,
+				method:  http.MethodGet,
+				target:  "/api/v1/users" + tt.query,
+			})
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantStatus == http.StatusOK && (resp.Meta == nil || resp.Meta.Limit != tt.wantLimit) {
+				t.Errorf("meta = %+v, want limit %d", resp.Meta, tt.wantLimit)
+			}
+		})
+	}
+}
+
+func TestGetSignupsByDayHandler(t *testing.T) {
+	now := time.Date(2026, 3, 31, 15, 0, 0, 0, time.UTC)
+	utils.SetClock(utils.NewFixedClock(now))
+	t.Cleanup(func() { utils.SetClock(nil) })
+
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+		wantLen    int
+		wantFirst  models.DayCount
+		wantLast   models.DayCount
+	}{
+		{name: "explicit range includes the end date", query: "?from=2026-03-01&to=2026-03-03", wantStatus: http.StatusOK, wantLen: 3, wantFirst: models.DayCount{Date: "2026-03-01", Count: 2}, wantLast: models.DayCount{Date: "2026-03-03", Count: 1}},
+		{name: "defaults to the last 30 days", wantStatus: http.StatusOK, wantLen: 30, wantFirst: models.DayCount{Date: "2026-03-02"}, wantLast: models.DayCount{Date: "2026-03-31", Count: 1}},
+		{name: "to only", query: "?to=2026-03-03", wantStatus: http.StatusOK, wantLen: 30, wantFirst: models.DayCount{Date: "2026-02-02"}, wantLast: models.DayCount{Date: "2026-03-03", Count: 1}},
+		{name: "longest range", query: "?from=2025-03-31&to=2026-03-31", wantStatus: http.StatusOK, wantLen: 366, wantFirst: models.DayCount{Date: "2025-03-31"}, wantLast: models.DayCount{Date: "2026-03-31", Count: 1}},
+		{name: "range too long", query: "?from=2025-03-30&to=2026-03-31", wantStatus: http.StatusBadRequest},
+		{name: "from after to", query: "?from=2026-03-10&to=2026-03-01", wantStatus: http.StatusBadRequest},
+		{name: "invalid date", query: "?from=03/01/2026", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tu := newTestUsers(t)
+			h := newTestHandler(tu)
+			seedSignups(t, tu, []time.Time{
+				time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC),
+				time.Date(2026, 3, 1, 23, 59, 0, 0, time.UTC),
+				time.Date(2026, 3, 3, 0, 0, 0, 0, time.UTC),
+				time.Date(2026, 3, 31, 12, 0, 0, 0, time.UTC),
+			})
+
+			rec, resp := serve(t, testRequest{
+				pattern: "GET /api/v1/users/stats/signups",
+				handler: h.GetSignupsByDay,
+				method:  http.MethodGet,
+				target:  "/api/v1/users/stats/signups" + tt.query,
+			})
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var series []models.DayCount
+			if err := json.Unmarshal([]byte(mustJSON(t, resp.Data)), &series); err != nil {
+				t.Fatalf("invalid series: %v", err)
+			}
+			if len(series) != tt.wantLen {
+				t.Fatalf("got %d days, want %d", len(series), tt.wantLen)
+			}
+			if series[0] != tt.wantFirst || series[len(series)-1] != tt.wantLast {
+				t.Errorf("series runs %v to %v, want %v to %v", series[0], series[len(series)-1], tt.wantFirst, tt.wantLast)
+			}
+		})
+	}
+}
+
+func TestGetUsersHandlerVerificationFilter(t *testing.T) {
+	seeds := []struct {
+		username         string
+		active, verified bool
+		roles            []string
+	}{
+		{username: "active-verified", active: true, verified: true, roles: []string{models.RoleUser}},
+		{username: "active-unverified", active: true, roles: []string{models.RoleUser}},
+		{username: "inactive-verified", verified: true, roles: []string{models.RoleUser}},
+		{username: "inactive-unverified", roles: []string{models.RoleUser}},
+		{username: "admin-verified", active: true, verified: true, roles: []string{models.RoleUser, models.RoleAdmin}},
+	}
+
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+		want       []string
+	}{
+		{name: "verified only", query: "?is_verified=true", wantStatus: http.StatusOK, want: []string{"active-verified", "admin-verified", "inactive-verified"}},
+		{name: "unverified only", query: "?is_verified=false", wantStatus: http.StatusOK, want: []string{"active-unverified", "inactive-unverified"}},
+		{name: "verified and active", query: "?is_verified=true&is_active=true", wantStatus: http.StatusOK, want: []string{"active-verified", "admin-verified"}},
+		{name: "verified and inactive", query: "?is_verified=true&is_active=false", wantStatus: http.StatusOK, want: []string{"inactive-verified"}},
+		{name: "unverified and active", query: "?is_verified=false&is_active=true", wantStatus: http.StatusOK, want: []string{"active-unverified"}},
+		{name: "verified with role", query: "?is_verified=true&role=admin", wantStatus: http.StatusOK, want: []string{"admin-verified"}},
+		{name: "verified with search", query: "?is_verified=false&search=inactive", wantStatus: http.StatusOK, want: []string{"inactive-unverified"}},
+		{name: "invalid value", query: "?is_verified=maybe", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tu := newTestUsers(t)
+			h := newTestHandler(tu)
+			for _, seed := range seeds {
+				tu.createUser(t, models.WithUsername(seed.username), models.WithActive(seed.active),
+					models.WithVerified(seed.verified), models.WithRoles(seed.roles...))
+			}
+
+			rec, resp := serve(t, testRequest{
+				pattern: "GET /api/v1/users",
+				handler: h.GetUsers,
+				method:  http.MethodGet,
+				target:  "/api/v1/users" + tt.query,
+			})
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var users []models.UserResponse
+			if err := json.Unmarshal([]byte(mustJSON(t, resp.Data)), &users); err != nil {
+				t.Fatalf("invalid users: %v", err)
+			}
+			got := make([]string, len(users))
+			for i, user := range users {
+				got[i] = user.Username
+			}
+			slices.Sort(got)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("users = %v, want %v", got, tt.want)
+			}
+			if resp.Meta == nil || resp.Meta.Total != len(tt.want) {
+				t.Errorf("meta = %+v, want total %d", resp.Meta, len(tt.want))
+			}
+		})
+	}
+}
+
+func TestUserPreferencesHandlers(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		body       string
+		missing    bool
+		wantStatus int
+		want       models.NotificationPreferences // stored settings afterwards
+	}{
+		{name: "read defaults", method: http.MethodGet, wantStatus: http.StatusOK, want: models.DefaultNotificationPreferences()},
+		{
+			name:       "partial update",
+			method:     http.MethodPut,
+			body:       `{"notifications":{"push":false}}`,
+			wantStatus: http.StatusOK,
+			want:       models.NotificationPreferences{Email: true, Push: false, Marketing: false},
+		},
+		{
+			name:       "unknown notification key",
+			method:     http.MethodPut,
+			body:       `{"notifications":{"sms":true}}`,
+			wantStatus: http.StatusBadRequest,
+			want:       models.DefaultNotificationPreferences(),
+		},
+		{
+			name:       "unknown preference group",
+			method:     http.MethodPut,
+			body:       `{"theme":"dark"}`,
+			wantStatus: http.StatusBadRequest,
+			want:       models.DefaultNotificationPreferences(),
+		},
+		{
+			name:       "no settings",
+			method:     http.MethodPut,
+			body:       `{"notifications":{}}`,
+			wantStatus: http.StatusBadRequest,
+			want:       models.DefaultNotificationPreferences(),
+		},
+		{name: "read missing user", method: http.MethodGet, missing: true, wantStatus: http.StatusNotFound},
+		{name: "update missing user", method: http.MethodPut, body: `{"notifications":{"email":false}}`, missing: true, wantStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tu := newTestUsers(t)
+			h := newTestHandler(tu)
+			user := tu.createUser(t)
+			id := user.GetIDString()
+			if tt.missing {
+				id = "507f1f77bcf86cd799439011"
+			}
+
+			handler := h.GetUserPreferences
+			if tt.method == http.MethodPut {
+				handler = h.UpdateUserPreferences
+			}
+			rec, resp := serve(t, testRequest{
+				pattern: tt.method + " /api/v1/users/{id}/preferences",
+				handler: handler,
+				method:  tt.method,
+				target:  "/api/v1/users/" + id + "/preferences",
+				body:    tt.body,
+			})
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.missing {
+				return
+			}
+
+			if tt.wantStatus == http.StatusOK {
+				var got models.PreferencesResponse
+				if err := json.Unmarshal([]byte(mustJSON(t, resp.Data)), &got); err != nil {
+					t.Fatalf("invalid preferences %v: %v", resp.Data, err)
+				}
+				if got.Notifications != tt.want {
+					t.Errorf("response = %+v, want %+v", got.Notifications, tt.want)
+				}
+			}
+			if stored := tu.storedUser(t, id).GetNotificationPreferences(); stored != tt.want {
+				t.Errorf("stored = %+v, want %+v", stored, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetUserProfileCacheHeaders(t *testing.T) {
+	created := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	active := created.Add(10 * time.Minute)
+
+	tests := []struct {
+		name             string
+		activeLater      bool // the user was active after their last update
+		ifModifiedSince  time.Time
+		missing          bool
+		wantStatus       int
+		wantLastModified time.Time
+	}{
+		{name: "first request", wantStatus: http.StatusOK, wantLastModified: created},
+		{name: "unchanged since", ifModifiedSince: created, wantStatus: http.StatusNotModified, wantLastModified: created},
+		{name: "checked after the change", ifModifiedSince: created.Add(time.Hour), wantStatus: http.StatusNotModified, wantLastModified: created},
+		{name: "changed since", ifModifiedSince: created.Add(-time.Second), wantStatus: http.StatusOK, wantLastModified: created},
+		{name: "activity counts as a change", activeLater: true, ifModifiedSince: created, wantStatus: http.StatusOK, wantLastModified: active},
+		{name: "unknown user", missing: true, wantStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := utils.NewFixedClock(created)
+			utils.SetClock(clock)
+			t.Cleanup(func() { utils.SetClock(nil) })
+
+			tu := newTestUsers(t)
+			h := newTestHandler(tu)
+			user := tu.createUser(t)
+			id := user.GetIDString()
+			if tt.activeLater {
+				clock.Set(active)
+				if err := tu.service.RecordActivity(context.Background(), id); err != nil {
+					t.Fatalf("RecordActivity() error = %v", err)
+				}
+			}
+			if tt.missing {
+				id = "507f1f77bcf86cd799439011"
+			}
+
+			header := map[string]string{}
+			if !tt.ifModifiedSince.IsZero() {
+				header["If-Modified-Since"] = tt.ifModifiedSince.Format(http.TimeFormat)
+			}
+			rec, _ := serve(t, testRequest{
+				pattern: "GET /api/v1/users/{id}/profile",
+				handler: h.GetUserProfile,
+				method:  http.MethodGet,
+				target:  "/api/v1/users/" + id + "/profile",
+				header:  header,
+			})
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.missing {
+				if got := rec.Header().Get("Cache-Control"); strings.Contains(got, "public") {
+					t.Errorf("Cache-Control = %q, want errors kept out of shared caches", got)
+				}
+				return
+			}
+
+			if got := rec.Header().Get("Cache-Control"); got != "public, max-age=60" {
+				t.Errorf("Cache-Control = %q, want public, max-age=60", got)
+			}
+			if got, want := rec.Header().Get("Last-Modified"), tt.wantLastModified.Format(http.TimeFormat); got != want {
+				t.Errorf("Last-Modified = %q, want %q", got, want)
+			}
+			if tt.wantStatus == http.StatusNotModified && rec.Body.Len() != 0 {
+				t.Errorf("304 body = %q, want empty", rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestGetUserProfileOnline(t *testing.T) {
+	active := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		neverSeen  bool
+		elapsed    time.Duration // time since the user's last activity when the profile is read
+		wantOnline bool
+	}{
+		{name: "just active", wantOnline: true},
+		{name: "end of the online window", elapsed: 5 * time.Minute, wantOnline: true},
+		{name: "past the online window", elapsed: 5*time.Minute + time.Second},
+		{name: "never active", neverSeen: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The online check follows the application clock, not the wall clock
+			clock := utils.NewFixedClock(active)
+			utils.SetClock(clock)
+			t.Cleanup(func() { utils.SetClock(nil) })
+
+			tu := newTestUsers(t)
+			h := newTestHandler(tu)
+			id := tu.createUser(t).GetIDString()
+			if !tt.neverSeen {
+				if err := tu.service.RecordActivity(context.Background(), id); err != nil {
+					t.Fatalf("RecordActivity() error = %v", err)
+				}
+			}
+			clock.Set(active.Add(tt.elapsed))
+
+			rec, resp := serve(t, testRequest{
+				pattern: "GET /api/v1/users/{id}/profile",
+				handler: h.GetUserProfile,
+				method:  http.MethodGet,
+				target:  "/api/v1/users/" + id + "/profile",
+			})
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+			}
+			var profile models.UserProfileResponse
+			if err := json.Unmarshal([]byte(mustJSON(t, resp.Data)), &profile); err != nil {
+				t.Fatalf("invalid profile: %v", err)
+			}
+			if profile.IsOnline != tt.wantOnline {
+				t.Errorf("is_online = %v, want %v", profile.IsOnline, tt.wantOnline)
+			}
+		})
+	}
+}
+
+func TestDeleteUserBySelectorHandler(t *testing.T) {
+	tests := []struct {
+		name        string
+		query       string
+		callerRoles []string
+		wantStatus  int
+		wantDeleted bool
+	}{
+		{name: "by email", query: "?email=alice@example.com", callerRoles: []string{models.RoleAdmin}, wantStatus: http.StatusOK, wantDeleted: true},
+		{name: "by username", query: "?username=alice", callerRoles: []string{models.RoleAdmin}, wantStatus: http.StatusOK, wantDeleted: true},
+		{name: "both selectors", query: "?email=alice@example.com&username=alice", callerRoles: []string{models.RoleAdmin}, wantStatus: http.StatusBadRequest},
+		{name: "no selector", callerRoles: []string{models.RoleAdmin}, wantStatus: http.StatusBadRequest},
+		{name: "blank selector", query: "?email=%20", callerRoles: []string{models.RoleAdmin}, wantStatus: http.StatusBadRequest},
+		{name: "unknown email", query: "?email=dave@example.com", callerRoles: []string{models.RoleAdmin}, wantStatus: http.StatusNotFound},
+		{name: "non-admin", query: "?username=alice", callerRoles: []string{models.RoleUser}, wantStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tu := newTestUsers(t)
+			h := newTestHandler(tu)
+			alice := tu.createUser(t, models.WithUsername("alice"), models.WithEmail("alice@example.com"))
+			caller := tu.createUser(t, models.WithRoles(tt.callerRoles...))
+
+			requireAdmin := middleware.RequireRole(models.RoleAdmin)
+			rec, resp := serve(t, testRequest{
+				pattern: "DELETE /api/v1/users",
+				handler: authenticate(tu, requireAdmin(http.HandlerFunc(h.DeleteUserBySelector))).ServeHTTP,
+				method:  http.MethodDelete,
+				target:  "/api/v1/users" + tt.query,
+				header:  map[string]string{"Authorization": "Bearer " + accessToken(t, tu, caller)},
+			})
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantStatus == http.StatusBadRequest && (resp.Error == nil || !strings.Contains(resp.Error.Message, "Exactly one of email or username")) {
+				t.Errorf("error = %+v, want it to ask for exactly one selector", resp.Error)
+			}
+			if deleted := tu.storedUser(t, alice.GetIDString()).DeletedAt != nil; deleted != tt.wantDeleted {
+				t.Errorf("alice deleted = %v, want %v", deleted, tt.wantDeleted)
+			}
+		})
+	}
+}
+
+func TestRevocationRejectsExistingTokens(t *testing.T) {
+	tests := []struct {
+		name        string
+		revoke      func(ctx context.Context, tu *testUsers, user, other *models.User) error
+		wantRevoked bool
+	}{
+		{
+			name: "logout all",
+			revoke: func(ctx context.Context, tu *testUsers, user, other *models.User) error {
+				return tu.service.LogoutAll(ctx, user.GetIDString())
+			},
+			wantRevoked: true,
+		},
+		{
+			name: "password change",
+			revoke: func(ctx context.Context, tu *testUsers, user, other *models.User) error {
+				return tu.service.ChangePassword(ctx, user.GetIDString(), &models.ChangePasswordRequest{
+					CurrentPassword: models.TestUserPassword,
+					NewPassword:     "NewSecurePass456",
+					ConfirmPassword: "NewSecurePass456",
+				})
+			},
+			wantRevoked: true,
+		},
+		{
+			name: "rejected password change",
+			revoke: func(ctx context.Context, tu *testUsers, user, other *models.User) error {
+				err := tu.service.ChangePassword(ctx, user.GetIDString(), &models.ChangePasswordRequest{
+					CurrentPassword: "wrong-password",
+					NewPassword:     "NewSecurePass456",
+					ConfirmPassword: "NewSecurePass456",
+				})
+				if err == nil {
+					return fmt.Errorf("ChangePassword() with the wrong current password succeeded")
+				}
+				return nil
+			},
+		},
+		{
+			name: "another user logs out",
+			revoke: func(ctx context.Context, tu *testUsers, user, other *models.User) error {
+				return tu.service.LogoutAll(ctx, other.GetIDString())
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			tu := newTestUsers(t)
+			user := tu.createUser(t)
+			other := tu.createUser(t)
+
+			protected := authenticate(tu, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusNoContent)
+			}))
+			request := func(token string) testRequest {
+				return testRequest{
+					pattern: "GET /me",
+					handler: protected.ServeHTTP,
+					method:  http.MethodGet,
+					target:  "/me",
+					header:  map[string]string{"Authorization": "Bearer " + token},
+				}
+			}
+
+			// Served once first so the token version is cached, as it would be in production
+			oldToken := accessToken(t, tu, user)
+			if rec, _ := serve(t, request(oldToken)); rec.Code != http.StatusNoContent {
+				t.Fatalf("status before revocation = %d, want %d", rec.Code, http.StatusNoContent)
+			}
+
+			if err := tt.revoke(ctx, tu, user, other); err != nil {
+				t.Fatalf("revoke error = %v", err)
+			}
+
+			wantStatus := http.StatusNoContent
+			if tt.wantRevoked {
+				wantStatus = http.StatusUnauthorized
+			}
+			if rec, _ := serve(t, request(oldToken)); rec.Code != wantStatus {
+				t.Errorf("old token status = %d, want %d", rec.Code, wantStatus)
+			}
+
+			// Tokens issued after the revocation carry the new version and are accepted
+			newToken := accessToken(t, tu, tu.storedUser(t, user.GetIDString()))
+			if rec, _ := serve(t, request(newToken)); rec.Code != http.StatusNoContent {
+				t.Errorf("new token status = %d, want %d", rec.Code, http.StatusNoContent)
+			}
+		})
+	}
+}
+
+func TestLogoutAllHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		caller     string // "self", "other" or "admin"
+		missing    bool
+		wantStatus int
+	}{
+		{name: "own sessions", caller: "self", wantStatus: http.StatusOK},
+		{name: "admin", caller: "admin", wantStatus: http.StatusOK},
+		{name: "another user", caller: "other", wantStatus: http.StatusForbidden},
+		{name: "unknown user", caller: "admin", missing: true, wantStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tu := newTestUsers(t)
+			h := newTestHandler(tu)
+			user := tu.createUser(t)
+			callers := map[string]*models.User{
+				"self":  user,
+				"other": tu.createUser(t),
+				"admin": tu.createUser(t, models.WithRoles(models.RoleUser, models.RoleAdmin)),
+			}
+			id := user.GetIDString()
+			if tt.missing {
+				id = "507f1f77bcf86cd799439011"
+			}
+
+			logoutAll := authenticate(tu, middleware.RequireSelfOrRole("id", models.RoleAdmin)(http.HandlerFunc(h.LogoutAll)))
+			rec, resp := serve(t, testRequest{
+				pattern: "POST /api/v1/users/{id}/logout-all",
+				handler: logoutAll.ServeHTTP,
+				method:  http.MethodPost,
+				target:  "/api/v1/users/" + id + "/logout-all",
+				header:  map[string]string{"Authorization": "Bearer " + accessToken(t, tu, callers[tt.caller])},
+			})
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+
+			wantVersion := user.TokenVersion
+			if tt.wantStatus == http.StatusOK {
+				wantVersion++
+				if !resp.Success {
+					t.Errorf("response = %+v, want success", resp)
+				}
+			}
+			if got := tu.storedUser(t, user.GetIDString()).TokenVersion; got != wantVersion {
+				t.Errorf("token version = %d, want %d", got, wantVersion)
+			}
+		})
+	}
+}
+
+func TestHandlersMapContextErrors(t *testing.T) {
+	canceled := func() (context.Context, context.CancelFunc) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		return ctx, cancel
+	}
+	expired := func() (context.Context, context.CancelFunc) {
+		return context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	}
+
+	tests := []struct {
+		name       string
+		ctx        func() (context.Context, context.CancelFunc)
+		pattern    string
+		handler    func(h *UserHandler) http.HandlerFunc
+		target     string // %s is replaced by the user's ID
+		logMessage string
+		wantStatus int
+	}{
+		{
+			name:       "get user, client gone",
+			ctx:        canceled,
+			pattern:    "GET /api/v1/users/{id}",
+			handler:    func(h *UserHandler) http.HandlerFunc { return h.GetUser },
+			target:     "/api/v1/users/%s",
+			logMessage: "Failed to get user",
+			wantStatus: response.StatusClientClosedRequest,
+		},
+		{
+			name:       "get user, deadline exceeded",
+			ctx:        expired,
+			pattern:    "GET /api/v1/users/{id}",
+			handler:    func(h *UserHandler) http.HandlerFunc { return h.GetUser },
+			target:     "/api/v1/users/%s",
+			logMessage: "Failed to get user",
+			wantStatus: http.StatusGatewayTimeout,
+		},
+		{
+			name:       "list users, client gone",
+			ctx:        canceled,
+			pattern:    "GET /api/v1/users",
+			handler:    func(h *UserHandler) http.HandlerFunc { return h.GetUsers },
+			target:     "/api/v1/users",
+			logMessage: "Failed to get users",
+			wantStatus: response.StatusClientClosedRequest,
+		},
+		{
+			name:       "list users, deadline exceeded",
+			ctx:        expired,
+			pattern:    "GET /api/v1/users",
+			handler:    func(h *UserHandler) http.HandlerFunc { return h.GetUsers },
+			target:     "/api/v1/users",
+			logMessage: "Failed to get users",
+			wantStatus: http.StatusGatewayTimeout,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tu := newTestUsers(t)
+			h := newTestHandler(tu)
+			user := tu.createUser(t)
+
+			ctx, cancel := tt.ctx()
+			defer cancel()
+			mux := http.NewServeMux()
+			mux.Handle(tt.pattern, tt.handler(h))
+			target := tt.target
+			if strings.Contains(target, "%s") {
+				target = fmt.Sprintf(target, user.GetIDString())
+			}
+			r := httptest.NewRequest(http.MethodGet, target, nil).WithContext(ctx)
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, r)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantStatus == response.StatusClientClosedRequest && rec.Body.Len() != 0 {
+				t.Errorf("body = %q, want nothing written for a client that went away", rec.Body.String())
+			}
+			if !tu.logger.Has(slog.LevelWarn, tt.logMessage) {
+				t.Errorf("%q not logged at warn", tt.logMessage)
+			}
+			if tu.logger.Has(slog.LevelError, tt.logMessage) {
+				t.Errorf("%q logged at error, want context failures kept off error dashboards", tt.logMessage)
+			}
+		})
+	}
+}
+
+func TestUserHandlersMapServiceErrors(t *testing.T) {
+	missing := "507f1f77bcf86cd799439011"
+
+	tests := []struct {
+		name        string
+		pattern     string
+		handler     func(h *UserHandler) http.HandlerFunc
+		method      string
+		target      string // %s is replaced by the user's ID
+		body        string
+		wantStatus  int
+		wantCode    string
+		wantMessage string
+	}{
+		{
+			name:    "missing user",
+			pattern: "GET /api/v1/users/{id}", handler: func(h *UserHandler) http.HandlerFunc { return h.GetUser },
+			method: http.MethodGet, target: "/api/v1/users/" + missing,
+			wantStatus: http.StatusNotFound, wantCode: "NOT_FOUND", wantMessage: "User not found",
+		},
+		{
+			name:    "duplicate username",
+			pattern: "PATCH /api/v1/users/{id}", handler: func(h *UserHandler) http.HandlerFunc { return h.UpdateUser },
+			method: http.MethodPatch, target: "/api/v1/users/%s", body: `{"username":"taken"}`,
+			wantStatus: http.StatusConflict, wantCode: "CONFLICT", wantMessage: "Username 'taken' already exists",
+		},
+		{
+			name:    "already active",
+			pattern: "POST /api/v1/users/{id}/activate", handler: func(h *UserHandler) http.HandlerFunc { return h.ActivateUser },
+			method: http.MethodPost, target: "/api/v1/users/%s/activate",
+			wantStatus: http.StatusBadRequest, wantCode: "BAD_REQUEST", wantMessage: "Invalid state: user is already active",
+		},
+		{
+			name:    "restoring a live user",
+			pattern: "POST /api/v1/users/{id}/restore", handler: func(h *UserHandler) http.HandlerFunc { return h.RestoreUser },
+			method: http.MethodPost, target: "/api/v1/users/%s/restore",
+			wantStatus: http.StatusBadRequest, wantCode: "BAD_REQUEST", wantMessage: "Invalid state: user is not deleted",
+		},
+		{
+			name:    "already verified",
+			pattern: "POST /api/v1/users/{id}/verification/send", handler: func(h *UserHandler) http.HandlerFunc { return h.SendVerificationEmail },
+			method: http.MethodPost, target: "/api/v1/users/%s/verification/send",
+			wantStatus: http.StatusBadRequest, wantCode: "BAD_REQUEST", wantMessage: "Invalid state: user is already verified",
+		},
+		{
+			name:    "password change for a missing user",
+			pattern: "PATCH /api/v1/users/{id}/password", handler: func(h *UserHandler) http.HandlerFunc { return h.ChangePassword },
+			method: http.MethodPatch, target: "/api/v1/users/" + missing + "/password",
+			body:       `{"current_password":"SecurePass123","new_password":"NewSecurePass456","confirm_password":"NewSecurePass456"}`,
+			wantStatus: http.StatusNotFound, wantCode: "NOT_FOUND", wantMessage: "User not found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tu := newTestUsers(t)
+			h := newTestHandler(tu)
+			user := tu.createUser(t, models.WithVerified(true))
+			tu.createUser(t, models.WithUsername("taken"))
+
+			target := tt.target
+			if strings.Contains(target, "%s") {
+				target = fmt.Sprintf(target, user.GetIDString())
+			}
+			rec, resp := serve(t, testRequest{
+				pattern: tt.pattern,
+				handler: tt.handler(h),
+				method:  tt.method,
+				target:  target,
+				body:    tt.body,
+			})
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if resp.Error == nil || resp.Error.Code != tt.wantCode || resp.Error.Message != tt.wantMessage {
+				t.Errorf("error = %+v, want %s %q", resp.Error, tt.wantCode, tt.wantMessage)
+			}
+		})
+	}
+}
+
+func TestPatchMergesPutReplaces(t *testing.T) {
+	// fullProfile fills every optional profile field and the fields outside the profile
+	fullProfile := func(u *models.User) {
+		dob := time.Date(1990, 5, 17, 0, 0, 0, 0, time.UTC)
+		u.Username = "janedoe"
+		u.Email = "jane@example.com"
+		u.FirstName, u.LastName = "Jane", "Doe"
+		u.Bio, u.Location, u.Website = "Coffee", "Lisbon", "https://jane.dev"
+		u.DateOfBirth = &dob
+		u.Roles = []string{models.RoleUser, models.RoleAdmin}
+		u.VerifyEmail()
+	}
+
+	tests := []struct {
+		name       string
+		method     string
+		handler    func(h *UserHandler) http.HandlerFunc
+		body       string
+		wantStatus int
+		wantField  string // field named in the validation error
+		check      func(t *testing.T, stored *models.User)
+	}{
+		{
+			name:       "PATCH keeps unspecified fields",
+			method:     http.MethodPatch,
+			handler:    func(h *UserHandler) http.HandlerFunc { return h.UpdateUser },
+			body:       `{"bio":"Tea"}`,
+			wantStatus: http.StatusOK,
+			check: func(t *testing.T, stored *models.User) {
+				if stored.Bio != "Tea" {
+					t.Errorf("bio = %q, want Tea", stored.Bio)
+				}
+				if stored.FirstName != "Jane" || stored.LastName != "Doe" || stored.Location != "Lisbon" || stored.Website != "https://jane.dev" || stored.DateOfBirth == nil {
+					t.Errorf("profile = %+v, want the unspecified fields intact", stored)
+				}
+			},
+		},
+		{
+			name:       "PUT clears omitted fields",
+			method:     http.MethodPut,
+			handler:    func(h *UserHandler) http.HandlerFunc { return h.ReplaceUser },
+			body:       `{"username":"janedoe","email":"jane@example.com","bio":"Tea"}`,
+			wantStatus: http.StatusOK,
+			check: func(t *testing.T, stored *models.User) {
+				if stored.Bio != "Tea" {
+					t.Errorf("bio = %q, want Tea", stored.Bio)
+				}
+				if stored.FirstName != "" || stored.LastName != "" || stored.Location != "" || stored.Website != "" || stored.DateOfBirth != nil {
+					t.Errorf("profile = %+v, want the omitted fields cleared", stored)
+				}
+			},
+		},
+		{
+			name:       "PUT sets every field sent",
+			method:     http.MethodPut,
+			handler:    func(h *UserHandler) http.HandlerFunc { return h.ReplaceUser },
+			body:       `{"username":"JaneRoe","email":"jane@example.com","first_name":"Janet","last_name":"Roe","bio":"Tea","location":"Porto","website":"https://roe.dev","date_of_birth":"1991-02-03"}`,
+			wantStatus: http.StatusOK,
+			check: func(t *testing.T, stored *models.User) {
+				got := []string{stored.Username, stored.FirstName, stored.LastName, stored.Bio, stored.Location, stored.Website}
+				want := []string{"janeroe", "Janet", "Roe", "Tea", "Porto", "https://roe.dev"}
+				if !slices.Equal(got, want) {
+					t.Errorf("profile = %v, want %v", got, want)
+				}
+				if stored.DateOfBirth == nil || stored.DateOfBirth.Format(models.DateOfBirthFormat) != "1991-02-03" {
+					t.Errorf("date of birth = %v, want 1991-02-03", stored.DateOfBirth)
+				}
+			},
+		},
+		{
+			name:       "PUT requires the username",
+			method:     http.MethodPut,
+			handler:    func(h *UserHandler) http.HandlerFunc { return h.ReplaceUser },
+			body:       `{"email":"jane@example.com"}`,
+			wantStatus: http.StatusBadRequest,
+			wantField:  "username",
+		},
+		{
+			name:       "PUT requires the email",
+			method:     http.MethodPut,
+			handler:    func(h *UserHandler) http.HandlerFunc { return h.ReplaceUser },
+			body:       `{"username":"janedoe"}`,
+			wantStatus: http.StatusBadRequest,
+			wantField:  "email",
+		},
+		{
+			name:       "PUT cannot send roles",
+			method:     http.MethodPut,
+			handler:    func(h *UserHandler) http.HandlerFunc { return h.ReplaceUser },
+			body:       `{"username":"janedoe","email":"jane@example.com","roles":["user"]}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "PUT cannot send verification",
+			method:     http.MethodPut,
+			handler:    func(h *UserHandler) http.HandlerFunc { return h.ReplaceUser },
+			body:       `{"username":"janedoe","email":"jane@example.com","is_verified":false}`,
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tu := newTestUsers(t)
+			h := newTestHandler(tu)
+			user := tu.createUser(t, fullProfile)
+
+			rec, _ := serve(t, testRequest{
+				pattern: tt.method + " /api/v1/users/{id}",
+				handler: tt.handler(h),
+				method:  tt.method,
+				target:  "/api/v1/users/" + user.GetIDString(),
+				body:    tt.body,
+			})
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantField != "" {
+				details := validationDetails(t, rec)
+				if len(details) != 1 || details[0].Field != tt.wantField {
+					t.Errorf("details = %+v, want a validation error on %s", details, tt.wantField)
+				}
+			}
+
+			stored := tu.storedUser(t, user.GetIDString())
+			// Neither method touches the fields outside the profile
+			if !slices.Equal(stored.Roles, user.Roles) || !stored.IsVerified || !stored.IsActive {
+				t.Errorf("roles = %v, verified = %v, active = %v, want them unchanged", stored.Roles, stored.IsVerified, stored.IsActive)
+			}
+			if tt.check != nil {
+				tt.check(t, stored)
+			} else if stored.Version != user.Version || stored.FirstName != "Jane" {
+				t.Errorf("rejected request changed the user: %+v", stored)
+			}
+		})
+	}
+}
+
+func TestCountUsersHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+		wantCount  int
+	}{
+		{name: "unfiltered", wantStatus: http.StatusOK, wantCount: 3},
+		{name: "active", query: "?is_active=true", wantStatus: http.StatusOK, wantCount: 2},
+		{name: "inactive", query: "?is_active=false", wantStatus: http.StatusOK, wantCount: 1},
+		{name: "role", query: "?role=admin", wantStatus: http.StatusOK, wantCount: 1},
+		{name: "role in another case", query: "?role=ADMIN", wantStatus: http.StatusOK, wantCount: 1},
+		{name: "role and status", query: "?role=user&is_active=true", wantStatus: http.StatusOK, wantCount: 2},
+		{name: "unknown role", query: "?role=owner", wantStatus: http.StatusBadRequest},
+		{name: "invalid status", query: "?is_active=maybe", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tu := newTestUsers(t)
+			h := newTestHandler(tu)
+			tu.createUser(t)
+			tu.createUser(t, models.WithActive(false))
+			tu.createUser(t, models.WithRoles(models.RoleUser, models.RoleAdmin))
+
+			rec, resp := serve(t, testRequest{
+				pattern: "GET /api/v1/users/count",
+				handler: h.CountUsers,
+				method:  http.MethodGet,
+				target:  "/api/v1/users/count" + tt.query,
+			})
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				if resp.Error == nil || resp.Error.Code != response.ErrorCodeBadRequest {
+					t.Errorf("error = %+v, want a bad request", resp.Error)
+				}
+				return
+			}
+
+			// The body is just the number, not a statistics report
+			var body struct {
+				Data map[string]int `json:"data"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatalf("invalid response body %q: %v", rec.Body.String(), err)
+			}
+			if want := map[string]int{"count": tt.wantCount}; !maps.Equal(body.Data, want) {
+				t.Errorf("data = %v, want %v", body.Data, want)
+			}
+		})
+	}
+}
+
+func TestRecordProfileViewHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		deleted    bool
+		missing    bool
+		wantStatus int
+		wantViews  int
+	}{
+		{name: "counts the view", wantStatus: http.StatusNoContent, wantViews: 1},
+		{name: "deleted user", deleted: true, wantStatus: http.StatusNotFound},
+		{name: "unknown user", missing: true, wantStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tu := newTestUsers(t)
+			h := newTestHandler(tu)
+			user := tu.createUser(t)
+			id := user.GetIDString()
+			if tt.deleted {
+				if err := tu.repo.SoftDelete(context.Background(), id); err != nil {
+					t.Fatalf("SoftDelete() error = %v", err)
+				}
+			}
+			if tt.missing {
+				id = "507f1f77bcf86cd799439011"
+			}
+
+			rec, _ := serve(t, testRequest{
+				pattern: "POST /api/v1/users/{id}/profile-view",
+				handler: h.RecordProfileView,
+				method:  http.MethodPost,
+				target:  "/api/v1/users/" + id + "/profile-view",
+			})
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantStatus == http.StatusNoContent && rec.Body.Len() != 0 {
+				t.Errorf("body = %q, want none", rec.Body.String())
+			}
+			if got := tu.storedUser(t, user.GetIDString()).ProfileViews; got != tt.wantViews {
+				t.Errorf("profile_views = %d, want %d", got, tt.wantViews)
+			}
+		})
+	}
+}
+
+func TestCreateUserHandlerUnicodeUsernames(t *testing.T) {
+	tests := []struct {
+		name         string
+		allowUnicode bool
+		username     string
+		wantStatus   int
+		wantUsername string
+	}{
+		{name: "accents refused by default", username: "Jos\u00e9", wantStatus: http.StatusBadRequest},
+		{name: "accents allowed", allowUnicode: true, username: "Jos\u00e9", wantStatus: http.StatusCreated, wantUsername: "jos\u00e9"},
+		{name: "decomposed accents normalized", allowUnicode: true, username: "Jose\u0301", wantStatus: http.StatusCreated, wantUsername: "jos\u00e9"},
+		{name: "whitespace refused", allowUnicode: true, username: "jos\u00e9\u3000m", wantStatus: http.StatusBadRequest},
+		{name: "control characters refused", allowUnicode: true, username: "jos\u00e9\u001b", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			models.SetAllowUnicodeUsernames(tt.allowUnicode)
+			t.Cleanup(func() { models.SetAllowUnicodeUsernames(false) })
+			tu := newTestUsers(t)
+			h := newTestHandler(tu)
+
+			body, err := json.Marshal(map[string]string{"username": tt.username, "email": "jose@example.com", "password": models.TestUserPassword})
+			if err != nil {
+				t.Fatal(err)
+			}
+			rec, resp := serve(t, testRequest{
+				pattern: "POST /api/v1/users",
+				handler: h.CreateUser,
+				method:  http.MethodPost,
+				target:  "/api/v1/users",
+				body:    string(body),
+			})
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantStatus != http.StatusCreated {
+				if details := validationDetails(t, rec); len(details) != 1 || details[0].Field != "username" {
+					t.Errorf("details = %+v, want a username error", details)
+				}
+				return
+			}
+
+			var user models.UserResponse
+			if err := json.Unmarshal([]byte(mustJSON(t, resp.Data)), &user); err != nil {
+				t.Fatalf("invalid user: %v", err)
+			}
+			if user.Username != tt.wantUsername {
+				t.Errorf("username = %q, want %q", user.Username, tt.wantUsername)
+			}
+		})
+	}
+}
@@ -2,10 +2,18 @@
 package users
 
 import (
+	"net/http"
+	"strings"
+
 	"go-template/internal/container"
+	"go-template/internal/models"
 	"go-template/internal/repositories"
+	"go-template/internal/shared/middleware"
 )
 
+// AvatarUploadPattern is the route for avatar uploads, which take a multipart body rather than JSON
+const AvatarUploadPattern = "POST /api/v1/users/{id}/avatar"
+
 // RegisterRoutes registers all user-related routes
 // This function is completely self-contained and handles its own dependency injection
 func RegisterRoutes(deps *container.Dependencies) {
@@ -13,34 +21,102 @@ func RegisterRoutes(deps *container.Dependencies) {
 	logger.Info("Registering user module routes")
 
 	// Internal dependency injection for the users module
-	repo := repositories.NewUserRepository(deps.GetDB())
-	service := NewUserService(repo, deps.GetCache(), logger)
-	handler := NewUserHandler(service, logger)
+	repo := repositories.NewUserRepository(deps.GetDB(), logger, repositories.WithReadDatabase(deps.GetReadDB()))
+	service := NewUserService(repo, deps.GetCache(), deps.GetCacheInvalidator(), deps.GetStorage(), deps.GetMailer(), deps.GetEventPublisher(), deps.GetCacheMetrics(), deps.GetConfig().GetCacheTTLJitter(), logger)
+	// Deleted users' tokens stop working immediately rather than when their cached version expires
+	service.RegisterDeletionHook(NewTokenRevocationHook(service))
+	handler := NewUserHandler(service, deps.GetConfig().GetOnlineWindow(), deps.GetConfig().GetProfileCacheMaxAge(), logger)
+
+	// Pre-populate the user cache in the background so startup is not delayed
+	if count := deps.GetConfig().CacheWarmCount; count > 0 {
+		go service.WarmCache(deps.Context, count)
+	}
+	
+	// Get the HTTP multiplexer; registrations are counted for the startup log
+	mux := &routeCounter{ServeMux: deps.Mux}
 
-	// Get the HTTP multiplexer
-	mux := deps.Mux
+	// Authorization middleware; authenticated requests also record the caller's last activity
+	trackActivity := middleware.TrackActivity(deps.GetCache(), service, logger)
+	versions := NewTokenVersions(repo, deps.GetCache(), logger)
+	authenticate := middleware.RequireAuth(deps.GetTokenService(), versions, logger)
+	requireAuth := middleware.ChainFunc(authenticate, trackActivity)
+	requireAdmin := middleware.ChainFunc(authenticate, trackActivity, middleware.RequireRole(models.RoleAdmin))
+	// identify attaches the caller's claims when a token is sent, for audit fields
+	identify := middleware.ChainFunc(middleware.OptionalAuth(deps.GetTokenService(), versions, logger), trackActivity)
+
+	// Endpoints for the authenticated user; the literal /me pattern takes precedence over /{id}
+	mux.Handle("GET /api/v1/users/me", requireAuth(handler.GetMe))
+	mux.Handle("PATCH /api/v1/users/me", requireAuth(handler.UpdateMe))
 
 	// User CRUD endpoints
-	mux.HandleFunc("GET /api/v1/users", handler.GetUsers)
-	mux.HandleFunc("GET /api/v1/users/{id}", handler.GetUser)
-	mux.HandleFunc("POST /api/v1/users", handler.CreateUser)
-	mux.HandleFunc("PATCH /api/v1/users/{id}", handler.UpdateUser)  
-	mux.HandleFunc("DELETE /api/v1/users/{id}", handler.DeleteUser)
+	mux.Handle("GET /api/v1/users", identify(handler.GetUsers))
+	mux.Handle("GET /api/v1/users/{id}", identify(handler.GetUser))
+	// Idempotency runs inside identify so stored responses are scoped to the caller
+	createUser := middleware.Idempotency(
+		deps.GetCache(), "users:create", middleware.DefaultIdempotencyTTL, deps.GetConfig().GetTrustedProxies(), logger,
+	)(http.HandlerFunc(handler.CreateUser))
+	mux.Handle("POST /api/v1/users", identify(createUser.ServeHTTP))
+	mux.Handle("PATCH /api/v1/users/{id}", identify(handler.UpdateUser))
+	mux.Handle("PUT /api/v1/users/{id}", identify(handler.ReplaceUser))
+	mux.Handle("DELETE /api/v1/users/{id}", identify(handler.DeleteUser))
+
+	// Bulk operations
+	mux.Handle("POST /api/v1/users/bulk", identify(handler.BulkCreateUsers))
+	mux.Handle("POST /api/v1/users/batch-get", identify(handler.BatchGetUsers))
 
 	// User search endpoint
 	mux.HandleFunc("GET /api/v1/users/search", handler.SearchUsers)
 
 	// User statistics endpoint
 	mux.HandleFunc("GET /api/v1/users/stats", handler.GetUserStats)
+	mux.HandleFunc("GET /api/v1/users/stats/signups", handler.GetSignupsByDay)
+	mux.HandleFunc("GET /api/v1/users/count", handler.CountUsers)
 
 	// User profile endpoints
 	mux.HandleFunc("GET /api/v1/users/{id}/profile", handler.GetUserProfile)
+	mux.HandleFunc("POST /api/v1/users/{id}/profile-view", handler.RecordProfileView)
 
 	// User account management endpoints
-	mux.HandleFunc("PATCH /api/v1/users/{id}/password", handler.ChangePassword)
-	mux.HandleFunc("PATCH /api/v1/users/{id}/verify", handler.VerifyUser)
+	mux.Handle("PATCH /api/v1/users/{id}/password", identify(handler.ChangePassword))
+	mux.Handle("PATCH /api/v1/users/{id}/verify", identify(handler.VerifyUser))
+	mux.HandleFunc("POST /api/v1/users/{id}/verification/send", handler.SendVerificationEmail)
+	mux.Handle("POST /api/v1/users/{id}/restore", identify(handler.RestoreUser))
+	mux.Handle(AvatarUploadPattern, identify(handler.UploadAvatar))
+	mux.Handle("GET /api/v1/users/{id}/preferences", identify(handler.GetUserPreferences))
+	mux.Handle("PUT /api/v1/users/{id}/preferences", identify(handler.UpdateUserPreferences))
+	mux.Handle("POST /api/v1/users/{id}/logout-all", middleware.ChainFunc(
+		authenticate, trackActivity, middleware.RequireSelfOrRole("id", models.RoleAdmin),
+	)(handler.LogoutAll))
+
+	// Admin-only endpoints
+	mux.Handle("PUT /api/v1/users/{id}/roles", requireAdmin(handler.SetUserRoles))
+	mux.Handle("POST /api/v1/users/{id}/activate", requireAdmin(handler.ActivateUser))
+	mux.Handle("POST /api/v1/users/{id}/deactivate", requireAdmin(handler.DeactivateUser))
+	mux.Handle("DELETE /api/v1/users", requireAdmin(handler.DeleteUserBySelector))
+	mux.Handle("POST /api/v1/users/bulk-delete", requireAdmin(handler.BulkDeleteUsers))
+	mux.Handle("GET "+ExportPath, requireAdmin(handler.ExportUsers))
+
+	// Serve locally stored avatars when they are exposed under a path on this server
+	if avatarPath := strings.TrimRight(deps.GetConfig().AvatarBaseURL, "/"); strings.HasPrefix(avatarPath, "/") {
+		mux.Handle("GET "+avatarPath+"/", http.StripPrefix(avatarPath, deps.GetStorage().Handler()))
+	}
 
 	logger.Info("✅ User module routes registered successfully", 
-		"endpoints", 9, 
+		"endpoints", mux.count, 
 		"base_path", "/api/v1/users")
-}
\ No newline at end of file
+}
+// routeCounter wraps a ServeMux and counts the routes registered through it
+type routeCounter struct {
+	*http.ServeMux
+	count int
+}
+
+func (m *routeCounter) Handle(pattern string, handler http.Handler) {
+	m.ServeMux.Handle(pattern, handler)
+	m.count++
+}
+
+func (m *routeCounter) HandleFunc(pattern string, handler func(http.ResponseWriter, *http.Request)) {
+	m.ServeMux.HandleFunc(pattern, handler)
+	m.count++
+}
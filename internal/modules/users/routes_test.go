@@ -0,0 +1,49 @@
+// internal/modules/users/routes_test.go
+package users
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRouteCounter(t *testing.T) {
+	noop := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNoContent) }
+	tests := []struct {
+		name     string
+		register func(m *routeCounter)
+		want     int
+	}{
+		{
+			name:     "none",
+			register: func(m *routeCounter) {},
+		},
+		{
+			name: "handle and handle func",
+			register: func(m *routeCounter) {
+				m.Handle("GET /a", http.HandlerFunc(noop))
+				m.HandleFunc("POST /a", noop)
+				m.HandleFunc("GET /b/{id}", noop)
+			},
+			want: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mux := &routeCounter{ServeMux: http.NewServeMux()}
+			tt.register(mux)
+			if mux.count != tt.want {
+				t.Errorf("count = %d, want %d", mux.count, tt.want)
+			}
+			// Routes still reach the wrapped mux
+			if tt.want > 0 {
+				rec := httptest.NewRecorder()
+				mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/b/1", nil))
+				if rec.Code != http.StatusNoContent {
+					t.Errorf("GET /b/1 status = %d, want %d", rec.Code, http.StatusNoContent)
+				}
+			}
+		})
+	}
+}
@@ -2,22 +2,44 @@
 package users
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"slices"
 	"strings"
+	"sync"
 	"time"
 
 	"go-template/internal/interfaces"
 	"go-template/internal/models"
 	"go-template/internal/repositories"
+	"go-template/internal/shared/events"
+	"go-template/internal/shared/middleware"
+	"go-template/internal/shared/utils"
 )
 
 // UserService handles business logic for user operations
 type UserService struct {
-	repo   repositories.UserRepositoryInterface
-	cache  interfaces.CacheInterface
-	logger interfaces.LoggerInterface
+	repo        repositories.UserRepositoryInterface
+	cache       interfaces.CacheInterface
+	invalidator interfaces.CacheInvalidator
+	storage     interfaces.FileStorage
+	mailer      interfaces.Mailer
+	events      interfaces.EventPublisher
+	metrics     interfaces.CacheMetrics
+	logger      interfaces.LoggerInterface
+	
+	verificationTokens *utils.ActionTokenStore
+	
+	hooksMu       sync.RWMutex
+	deletionHooks []DeletionHook
+	
+	// cacheJitter is the fraction by which cache TTLs are randomly varied
+	cacheJitter float64
 }
 
 // Cache key constants
@@ -26,56 +48,88 @@ const (
 	CacheKeyUserByEmail  = "user:email:%s"
 	CacheKeyUserUsername = "user:username:%s"
 	CacheKeyUserStats    = "user:stats"
+	CacheKeyUserCount    = "user:count" // Unfiltered total only
 	CacheKeyUserList     = "user:list:%s" // Hash of query params
 	CacheKeyUserExists   = "user:exists:%s:%s" // type:value (email:user@example.com)
+	CacheKeyUserTokenVersion = "user:token_version:%s"
+	
+	// Cache key categories reported to CacheMetrics
+	CacheCategoryUser   = "user"
+	CacheCategoryList   = "list"
+	CacheCategoryStats  = "stats"
+	CacheCategoryExists = "exists"
 	
 	// Cache expiration times
 	UserCacheExpiration      = 15 * time.Minute
 	UserListCacheExpiration  = 5 * time.Minute
 	UserStatsCacheExpiration = 30 * time.Minute
+	UserCountCacheExpiration = 30 * time.Second
 	UserExistsCacheExpiration = 10 * time.Minute
+	
+	// MaxBulkCreateSize caps the number of users accepted by a single bulk import
+	MaxBulkCreateSize = 500
+	
+	// MaxAvatarSize caps the size of an uploaded avatar image
+	MaxAvatarSize = 2 << 20 // 2MB
 )
 
+// avatarExtensions maps accepted avatar content types to their file extension
+var avatarExtensions = map[string]string{
+	"image/png":  ".png",
+	"image/jpeg": ".jpg",
+}
+
 // NewUserService creates a new UserService instance
+// cacheJitter is the fraction by which cache TTLs are randomly varied, e.g. 0.1 for ±10%
 func NewUserService(
 	repo repositories.UserRepositoryInterface,
 	cache interfaces.CacheInterface,
+	invalidator interfaces.CacheInvalidator,
+	storage interfaces.FileStorage,
+	mailer interfaces.Mailer,
+	publisher interfaces.EventPublisher,
+	metrics interfaces.CacheMetrics,
+	cacheJitter float64,
 	logger interfaces.LoggerInterface,
 ) *UserService {
 	return &UserService{
-		repo:   repo,
-		cache:  cache,
-		logger: logger.With("service", "users"),
+		repo:        repo,
+		cache:       cache,
+		invalidator: invalidator,
+		storage:     storage,
+		mailer:      mailer,
+		events:      publisher,
+		metrics:     metrics,
+		logger:      logger.With("service", "users"),
+		
+		verificationTokens: utils.NewEmailVerificationTokens(cache),
+		cacheJitter:        cacheJitter,
 	}
 }
 
 // CreateUser creates a new user with validation and cache management
 func (s *UserService) CreateUser(ctx context.Context, req *models.CreateUserRequest) (*models.User, error) {
+	ctx = withActor(ctx)
+	
 	s.logger.Info("Creating new user", "username", req.Username, "email", req.Email)
 	
 	// Validate request
 	if errors := req.Validate(); len(errors) > 0 {
-		s.logger.Warn("User creation validation failed", "errors", errors)
-		return nil, fmt.Errorf("validation failed: %s", strings.Join(errors, ", "))
+		s.logger.Warn("User creation validation failed", "errors", errors.Error())
+		return nil, errors
 	}
 	
 	// Check if username or email already exists (with cache)
-	exists, err := s.checkUserExists(ctx, "username", req.Username)
+	usernameExists, emailExists, err := s.checkIdentityExists(ctx, req.Username, req.Email)
 	if err != nil {
-		s.logger.Error("Failed to check username existence", err)
-		return nil, fmt.Errorf("failed to validate username: %w", err)
+		s.logger.Error("Failed to check username and email existence", err)
+		return nil, fmt.Errorf("failed to validate username and email: %w", err)
 	}
-	if exists {
-		return nil, fmt.Errorf("username '%s' already exists", req.Username)
+	if usernameExists {
+		return nil, fmt.Errorf("username '%s' %w", req.Username, interfaces.ErrAlreadyExists)
 	}
-	
-	exists, err = s.checkUserExists(ctx, "email", req.Email)
-	if err != nil {
-		s.logger.Error("Failed to check email existence", err)
-		return nil, fmt.Errorf("failed to validate email: %w", err)
-	}
-	if exists {
-		return nil, fmt.Errorf("email '%s' already exists", req.Email)
+	if emailExists {
+		return nil, fmt.Errorf("email '%s' %w", req.Email, interfaces.ErrAlreadyExists)
 	}
 	
 	// Create user model
@@ -88,6 +142,9 @@ func (s *UserService) CreateUser(ctx context.Context, req *models.CreateUserRequ
 	// Set optional fields
 	user.FirstName = req.FirstName
 	user.LastName = req.LastName
+	if actor, ok := repositories.ActorFromContext(ctx); ok {
+		user.SetCreatedBy(actor)
+	}
 	
 	// Save to database
 	if err := s.repo.Create(ctx, user); err != nil {
@@ -99,13 +156,140 @@ func (s *UserService) CreateUser(ctx context.Context, req *models.CreateUserRequ
 	s.cacheUser(ctx, user)
 	
 	// Invalidate related caches
+	s.invalidateUserExists(ctx, user)
 	s.invalidateUserListCaches(ctx)
 	s.invalidateUserStats(ctx)
 	
+	s.publishUserEvent(ctx, events.UserCreated, user)
+	
 	s.logger.Info("User created successfully", "user_id", user.GetIDString(), "username", user.Username)
 	return user, nil
 }
 
+// BulkCreateUsers validates and creates many users in a single batch insert
+// Items that fail validation or collide with existing users are reported individually
+// while the remaining items are still created
+func (s *UserService) BulkCreateUsers(ctx context.Context, reqs []models.CreateUserRequest) (*models.BulkCreateResponse, error) {
+	ctx = withActor(ctx)
+	
+	s.logger.Info("Bulk creating users", "count", len(reqs))
+	
+	if len(reqs) == 0 {
+		return nil, fmt.Errorf("%w: at least one user is required", interfaces.ErrValidation)
+	}
+	if len(reqs) > MaxBulkCreateSize {
+		return nil, fmt.Errorf("%w: batch size %d exceeds maximum of %d", interfaces.ErrValidation, len(reqs), MaxBulkCreateSize)
+	}
+	
+	result := &models.BulkCreateResponse{
+		Results: make([]models.BulkCreateResult, len(reqs)),
+	}
+	
+	fail := func(i int, message string) {
+		result.Results[i] = models.BulkCreateResult{Index: i, Success: false, Error: message}
+	}
+	
+	// Track usernames and emails within the batch to catch duplicates before inserting
+	seenUsernames := make(map[string]bool)
+	seenEmails := make(map[string]bool)
+	
+	var toCreate []*models.User
+	var toCreateIndexes []int
+	
+	for i := range reqs {
+		req := &reqs[i]
+		
+		if errors := req.Validate(); len(errors) > 0 {
+			fail(i, errors.Error())
+			continue
+		}
+		
+		username := models.NormalizeUsername(req.Username)
+		email := strings.ToLower(req.Email)
+		
+		if seenUsernames[username] {
+			fail(i, fmt.Sprintf("username '%s' is duplicated in the batch", req.Username))
+			continue
+		}
+		if seenEmails[email] {
+			fail(i, fmt.Sprintf("email '%s' is duplicated in the batch", req.Email))
+			continue
+		}
+		
+		usernameExists, emailExists, err := s.checkIdentityExists(ctx, username, email)
+		if err != nil {
+			s.logger.Error("Failed to check username and email existence", err)
+			return nil, fmt.Errorf("failed to validate username and email: %w", err)
+		}
+		if usernameExists {
+			fail(i, fmt.Sprintf("username '%s' already exists", req.Username))
+			continue
+		}
+		if emailExists {
+			fail(i, fmt.Sprintf("email '%s' already exists", req.Email))
+			continue
+		}
+		
+		user, err := models.NewUser(req.Username, req.Email, req.Password)
+		if err != nil {
+			fail(i, err.Error())
+			continue
+		}
+		user.FirstName = req.FirstName
+		user.LastName = req.LastName
+		if actor, ok := repositories.ActorFromContext(ctx); ok {
+			user.SetCreatedBy(actor)
+		}
+		
+		seenUsernames[username] = true
+		seenEmails[email] = true
+		toCreate = append(toCreate, user)
+		toCreateIndexes = append(toCreateIndexes, i)
+	}
+	
+	// Insert all valid users in one round trip; users created concurrently by others are
+	// rejected individually while the rest of the batch is still inserted
+	created := 0
+	if len(toCreate) > 0 {
+		var insertErr *repositories.BulkInsertError
+		if err := s.repo.CreateMany(ctx, toCreate); err != nil && !errors.As(err, &insertErr) {
+			s.logger.Error("Failed to bulk save users to database", err)
+			return nil, fmt.Errorf("failed to save users: %w", err)
+		}
+		
+		// Existence checks cached as false above are now stale
+		s.invalidateUserExists(ctx, toCreate...)
+		
+		for j, user := range toCreate {
+			if insertErr != nil {
+				if err, failed := insertErr.Failures[j]; failed {
+					fail(toCreateIndexes[j], err.Error())
+					continue
+				}
+			}
+			created++
+			
+			userResponse := user.ToUserResponse()
+			result.Results[toCreateIndexes[j]] = models.BulkCreateResult{
+				Index:   toCreateIndexes[j],
+				Success: true,
+				User:    &userResponse,
+			}
+			s.cacheUser(ctx, user)
+			s.publishUserEvent(ctx, events.UserCreated, user)
+		}
+		
+		s.invalidateUserListCaches(ctx)
+		s.invalidateUserStats(ctx)
+	}
+	
+	result.Succeeded = created
+	result.Failed = len(reqs) - created
+	
+	s.logger.Info("Bulk user creation completed", "succeeded", result.Succeeded, "failed", result.Failed)
+	return result, nil
+}
+
 // GetUserByID retrieves a user by ID with caching
 func (s *UserService) GetUserByID(ctx context.Context, id string) (*models.User, error) {
 	s.logger.Debug("Getting user by ID", "user_id", id)
@@ -131,6 +315,38 @@ func (s *UserService) GetUserByID(ctx context.Context, id string) (*models.User,
 	return user, nil
 }
 
+// GetUsersByIDs resolves many user IDs with a single database query
+// Users are returned in the order their IDs were requested; unknown or deleted IDs are omitted
+func (s *UserService) GetUsersByIDs(ctx context.Context, req *models.BatchGetUsersRequest) ([]*models.User, error) {
+	// Validate request
+	if errors := req.Validate(); len(errors) > 0 {
+		s.logger.Warn("Batch user lookup validation failed", "errors", errors)
+		return nil, fmt.Errorf("%w: %s", interfaces.ErrValidation, strings.Join(errors, ", "))
+	}
+	
+	s.logger.Debug("Getting users by IDs", "count", len(req.IDs))
+	
+	found, err := s.repo.GetByIDs(ctx, req.IDs)
+	if err != nil {
+		s.logger.Error("Failed to get users by IDs", err, "count", len(req.IDs))
+		return nil, fmt.Errorf("failed to get users: %w", err)
+	}
+	
+	// Restore the requested order
+	byID := make(map[string]*models.User, len(found))
+	for _, user := range found {
+		byID[user.GetIDString()] = user
+	}
+	users := make([]*models.User, 0, len(found))
+	for _, id := range req.IDs {
+		if user, ok := byID[id]; ok {
+			users = append(users, user)
+		}
+	}
+	
+	return users, nil
+}
+
 // GetUserByEmail retrieves a user by email with caching
 func (s *UserService) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
 	s.logger.Debug("Getting user by email", "email", email)
@@ -160,6 +376,9 @@ func (s *UserService) GetUserByEmail(ctx context.Context, email string) (*models
 func (s *UserService) GetUserByUsername(ctx context.Context, username string) (*models.User, error) {
 	s.logger.Debug("Getting user by username", "username", username)
 	
+	// Cache entries are keyed by the stored form, which invalidation uses too
+	username = models.NormalizeUsername(username)
+	
 	// Try cache first
 	cacheKey := fmt.Sprintf(CacheKeyUserUsername, username)
 	if cached, err := s.getUserFromCache(ctx, cacheKey); err == nil && cached != nil {
@@ -183,12 +402,14 @@ func (s *UserService) GetUserByUsername(ctx context.Context, username string) (*
 
 // UpdateUser updates a user with validation and cache management
 func (s *UserService) UpdateUser(ctx context.Context, id string, req *models.UpdateUserRequest) (*models.User, error) {
+	ctx = withActor(ctx)
+	
 	s.logger.Info("Updating user", "user_id", id)
 	
 	// Validate request
 	if errors := req.Validate(); len(errors) > 0 {
-		s.logger.Warn("User update validation failed", "errors", errors)
-		return nil, fmt.Errorf("validation failed: %s", strings.Join(errors, ", "))
+		s.logger.Warn("User update validation failed", "errors", errors.Error())
+		return nil, errors
 	}
 	
 	// Get current user
@@ -206,22 +427,37 @@ func (s *UserService) UpdateUser(ctx context.Context, id string, req *models.Upd
 			return nil, fmt.Errorf("failed to validate username: %w", err)
 		}
 		if exists {
-			return nil, fmt.Errorf("username '%s' already exists", newUsername)
+			return nil, fmt.Errorf("username '%s' %w", newUsername, interfaces.ErrAlreadyExists)
 		}
 	}
 	
+	emailChanged := false
 	if newEmail, ok := updates["email"].(string); ok && newEmail != user.Email {
 		exists, err := s.checkUserExists(ctx, "email", newEmail)
 		if err != nil {
 			return nil, fmt.Errorf("failed to validate email: %w", err)
 		}
 		if exists {
-			return nil, fmt.Errorf("email '%s' already exists", newEmail)
+			return nil, fmt.Errorf("email '%s' %w", newEmail, interfaces.ErrAlreadyExists)
 		}
+		
+		// The new address has not been verified, whatever the old one was
+		updates["is_verified"] = false
+		updates["email_verified_at"] = nil
+		emailChanged = true
 	}
 	
-	// Update in database
-	if err := s.repo.Update(ctx, id, updates); err != nil {
+	// Update in database, guarding against lost updates when the caller sent a version
+	if req.Version != nil {
+		err = s.repo.UpdateWithVersion(ctx, id, *req.Version, updates)
+	} else {
+		err = s.repo.Update(ctx, id, updates)
+	}
+	if err != nil {
+		if errors.Is(err, repositories.ErrVersionConflict) {
+			s.logger.Warn("User update version conflict", "user_id", id, "expected_version", *req.Version)
+			return nil, err
+		}
 		s.logger.Error("Failed to update user in database", err, "user_id", id)
 		return nil, fmt.Errorf("failed to update user: %w", err)
 	}
@@ -240,12 +476,23 @@ func (s *UserService) UpdateUser(ctx context.Context, id string, req *models.Upd
 	// Cache updated user
 	s.cacheUser(ctx, updatedUser)
 	
+	s.publishUserEvent(ctx, events.UserUpdated, updatedUser)
+	
+	if emailChanged {
+		// The update stands even if the email cannot be sent; the user can ask for another
+		if err := s.SendVerificationEmail(ctx, id); err != nil {
+			s.logger.Warn("Failed to send verification email for changed address", "user_id", id, "error", err.Error())
+		}
+	}
+	
 	s.logger.Info("User updated successfully", "user_id", id)
 	return updatedUser, nil
 }
 
 // DeleteUser soft deletes a user and manages cache
 func (s *UserService) DeleteUser(ctx context.Context, id string) error {
+	ctx = withActor(ctx)
+	
 	s.logger.Info("Deleting user", "user_id", id)
 	
 	// Get user for cache invalidation
@@ -254,6 +501,41 @@ func (s *UserService) DeleteUser(ctx context.Context, id string) error {
 		return err
 	}
 	
+	return s.deleteUser(ctx, user)
+}
+
+// DeleteUserByEmail soft deletes the user with the given email and manages cache
+func (s *UserService) DeleteUserByEmail(ctx context.Context, email string) error {
+	ctx = withActor(ctx)
+	
+	s.logger.Info("Deleting user by email", "email", email)
+	
+	user, err := s.GetUserByEmail(ctx, email)
+	if err != nil {
+		return err
+	}
+	
+	return s.deleteUser(ctx, user)
+}
+
+// DeleteUserByUsername soft deletes the user with the given username and manages cache
+func (s *UserService) DeleteUserByUsername(ctx context.Context, username string) error {
+	ctx = withActor(ctx)
+	
+	s.logger.Info("Deleting user by username", "username", username)
+	
+	user, err := s.GetUserByUsername(ctx, username)
+	if err != nil {
+		return err
+	}
+	
+	return s.deleteUser(ctx, user)
+}
+
+// deleteUser soft deletes a resolved user and invalidates its caches
+func (s *UserService) deleteUser(ctx context.Context, user *models.User) error {
+	id := user.GetIDString()
+	
 	// Soft delete in database
 	if err := s.repo.SoftDelete(ctx, id); err != nil {
 		s.logger.Error("Failed to delete user", err, "user_id", id)
@@ -265,14 +547,424 @@ func (s *UserService) DeleteUser(ctx context.Context, id string) error {
 	s.invalidateUserListCaches(ctx)
 	s.invalidateUserStats(ctx)
 	
+	s.publishUserEvent(ctx, events.UserDeleted, user)
+	s.runDeletionHooks(ctx, id, false)
+	
 	s.logger.Info("User deleted successfully", "user_id", id)
 	return nil
 }
 
+// BulkDeleteUsers soft deletes many users at once and manages cache
+// IDs that do not exist or are already deleted are reported rather than failing the request.
+// With DryRun set nothing is deleted; the response shows what would have been.
+func (s *UserService) BulkDeleteUsers(ctx context.Context, req *models.BulkDeleteRequest) (*models.BulkDeleteResponse, error) {
+	ctx = withActor(ctx)
+	
+	// Validate request
+	if errors := req.Validate(); len(errors) > 0 {
+		s.logger.Warn("Bulk delete validation failed", "errors", errors)
+		return nil, fmt.Errorf("%w: %s", interfaces.ErrValidation, strings.Join(errors, ", "))
+	}
+	
+	s.logger.Info("Bulk deleting users", "count", len(req.IDs), "dry_run", req.DryRun)
+	
+	// Resolve which users exist; deleted ones are not returned
+	found, err := s.repo.GetByIDs(ctx, req.IDs)
+	if err != nil {
+		s.logger.Error("Failed to get users for bulk delete", err, "count", len(req.IDs))
+		return nil, fmt.Errorf("failed to get users: %w", err)
+	}
+	
+	byID := make(map[string]*models.User, len(found))
+	for _, user := range found {
+		byID[user.GetIDString()] = user
+	}
+	
+	result := &models.BulkDeleteResponse{
+		DryRun:   req.DryRun,
+		Deleted:  make([]string, 0, len(found)),
+		NotFound: []string{},
+	}
+	for _, id := range req.IDs {
+		if _, ok := byID[id]; ok {
+			result.Deleted = append(result.Deleted, id)
+		} else {
+			result.NotFound = append(result.NotFound, id)
+		}
+	}
+	
+	if req.DryRun || len(result.Deleted) == 0 {
+		return result, nil
+	}
+	
+	// Soft delete in database
+	deleted, err := s.repo.SoftDeleteMany(ctx, result.Deleted)
+	if err != nil {
+		s.logger.Error("Failed to bulk delete users", err, "count", len(result.Deleted))
+		return nil, fmt.Errorf("failed to delete users: %w", err)
+	}
+	
+	// Invalidate caches
+	for _, id := range result.Deleted {
+		s.invalidateUserCaches(ctx, byID[id])
+	}
+	s.invalidateUserListCaches(ctx)
+	s.invalidateUserStats(ctx)
+	
+	for _, id := range result.Deleted {
+		s.publishUserEvent(ctx, events.UserDeleted, byID[id])
+		s.runDeletionHooks(ctx, id, false)
+	}
+	
+	s.logger.Info("Users bulk deleted successfully", "deleted", deleted, "not_found", len(result.NotFound))
+	return result, nil
+}
+
+// RestoreUser reverses a soft delete and manages cache
+func (s *UserService) RestoreUser(ctx context.Context, id string) (*models.User, error) {
+	ctx = withActor(ctx)
+	
+	s.logger.Info("Restoring user", "user_id", id)
+	
+	// Soft-deleted users are hidden from GetByID, so look them up directly
+	user, err := s.repo.GetByIDIncludingDeleted(ctx, id)
+	if err != nil {
+		s.logger.Error("Failed to get user for restore", err, "user_id", id)
+		return nil, err
+	}
+	
+	if !user.IsDeleted() {
+		return nil, fmt.Errorf("%w: user is not deleted", interfaces.ErrInvalidState)
+	}
+	
+	// Restore in database
+	if err := s.repo.Restore(ctx, id); err != nil {
+		s.logger.Error("Failed to restore user", err, "user_id", id)
+		return nil, fmt.Errorf("failed to restore user: %w", err)
+	}
+	
+	// Invalidate caches
+	s.invalidateUserCaches(ctx, user)
+	s.invalidateUserListCaches(ctx)
+	s.invalidateUserStats(ctx)
+	
+	// Get restored user
+	restoredUser, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		s.logger.Error("Failed to get restored user", err, "user_id", id)
+		return nil, fmt.Errorf("failed to retrieve restored user: %w", err)
+	}
+	
+	// Cache restored user
+	s.cacheUser(ctx, restoredUser)
+	
+	s.publishUserEvent(ctx, events.UserRestored, restoredUser)
+	s.runDeletionHooks(ctx, id, true)
+	
+	s.logger.Info("User restored successfully", "user_id", id)
+	return restoredUser, nil
+}
+
+// SetUserActive activates or deactivates a user and manages cache
+// Unlike DeleteUser it leaves deleted_at untouched, so the user stays listed and can be reactivated.
+func (s *UserService) SetUserActive(ctx context.Context, id string, active bool) (*models.User, error) {
+	ctx = withActor(ctx)
+	
+	s.logger.Info("Setting user active status", "user_id", id, "active", active)
+	
+	// Get existing user
+	user, err := s.GetUserByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	
+	if user.IsActive == active {
+		if active {
+			return nil, fmt.Errorf("%w: user is already active", interfaces.ErrInvalidState)
+		}
+		return nil, fmt.Errorf("%w: user is already inactive", interfaces.ErrInvalidState)
+	}
+	
+	// Update in database
+	if err := s.repo.UpdateStatus(ctx, id, active); err != nil {
+		s.logger.Error("Failed to update user status", err, "user_id", id)
+		return nil, fmt.Errorf("failed to update user status: %w", err)
+	}
+	
+	// A deactivated user must not keep using the sessions issued while active
+	if !active {
+		if err := s.revokeTokens(ctx, id); err != nil {
+			s.logger.Error("Failed to revoke tokens after deactivation", err, "user_id", id)
+			return nil, fmt.Errorf("failed to revoke existing sessions: %w", err)
+		}
+	}
+	
+	// Invalidate caches
+	s.invalidateUserCaches(ctx, user)
+	s.invalidateUserListCaches(ctx)
+	s.invalidateUserStats(ctx)
+	
+	// Get updated user
+	updatedUser, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		s.logger.Error("Failed to get updated user", err, "user_id", id)
+		return nil, fmt.Errorf("failed to retrieve updated user: %w", err)
+	}
+	
+	// Cache updated user
+	s.cacheUser(ctx, updatedUser)
+	
+	s.publishUserEvent(ctx, events.UserUpdated, updatedUser)
+	
+	s.logger.Info("User active status updated successfully", "user_id", id, "active", active)
+	return updatedUser, nil
+}
+
+// SetUserRoles replaces a user's roles and manages cache
+func (s *UserService) SetUserRoles(ctx context.Context, id string, req *models.SetRolesRequest) (*models.User, error) {
+	ctx = withActor(ctx)
+	
+	s.logger.Info("Setting user roles", "user_id", id)
+	
+	// Validate request
+	if errors := req.Validate(); len(errors) > 0 {
+		s.logger.Warn("Set roles validation failed", "errors", errors)
+		return nil, fmt.Errorf("%w: %s", interfaces.ErrValidation, strings.Join(errors, ", "))
+	}
+	
+	// Get existing user
+	user, err := s.GetUserByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	
+	// Update in database
+	if err := s.repo.Update(ctx, id, map[string]interface{}{"roles": req.Roles}); err != nil {
+		s.logger.Error("Failed to update user roles", err, "user_id", id)
+		return nil, fmt.Errorf("failed to update user roles: %w", err)
+	}
+	
+	// Tokens carry the roles they were issued with, so a removed role must end existing sessions
+	if rolesRemoved(user.Roles, req.Roles) {
+		if err := s.revokeTokens(ctx, id); err != nil {
+			s.logger.Error("Failed to revoke tokens after role removal", err, "user_id", id)
+			return nil, fmt.Errorf("failed to revoke existing sessions: %w", err)
+		}
+	}
+	
+	// Invalidate caches
+	s.invalidateUserCaches(ctx, user)
+	s.invalidateUserListCaches(ctx)
+	s.invalidateUserStats(ctx)
+	
+	// Get updated user
+	updatedUser, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		s.logger.Error("Failed to get updated user", err, "user_id", id)
+		return nil, fmt.Errorf("failed to retrieve updated user: %w", err)
+	}
+	
+	// Cache updated user
+	s.cacheUser(ctx, updatedUser)
+	
+	s.publishUserEvent(ctx, events.UserUpdated, updatedUser)
+	
+	s.logger.Info("User roles updated successfully", "user_id", id, "roles", req.Roles)
+	return updatedUser, nil
+}
+
+// rolesRemoved reports whether any of the current roles is missing from next
+func rolesRemoved(current, next []string) bool {
+	for _, role := range current {
+		if !slices.Contains(next, role) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetUserPreferences retrieves a user's preferences, with defaults for settings never stored
+func (s *UserService) GetUserPreferences(ctx context.Context, id string) (*models.PreferencesResponse, error) {
+	user, err := s.GetUserByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	
+	prefs := user.ToPreferencesResponse()
+	return &prefs, nil
+}
+
+// UpdateUserPreferences changes the preferences present in the request
+// Preferences the request does not mention, including unrelated groups, are kept.
+func (s *UserService) UpdateUserPreferences(ctx context.Context, id string, req *models.UpdatePreferencesRequest) (*models.PreferencesResponse, error) {
+	ctx = withActor(ctx)
+	
+	s.logger.Info("Updating user preferences", "user_id", id)
+	
+	// Validate request
+	if errors := req.Validate(); len(errors) > 0 {
+		s.logger.Warn("Update preferences validation failed", "errors", errors.Error())
+		return nil, errors
+	}
+	
+	// Get existing user
+	user, err := s.GetUserByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	
+	// Update in database
+	if err := s.repo.Update(ctx, id, req.ToUpdates(user.Preferences)); err != nil {
+		s.logger.Error("Failed to update user preferences", err, "user_id", id)
+		return nil, fmt.Errorf("failed to update user preferences: %w", err)
+	}
+	
+	// Invalidate caches
+	s.invalidateUserCaches(ctx, user)
+	s.invalidateUserListCaches(ctx)
+	
+	// Get updated user
+	updatedUser, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		s.logger.Error("Failed to get updated user", err, "user_id", id)
+		return nil, fmt.Errorf("failed to retrieve updated user: %w", err)
+	}
+	
+	// Cache updated user
+	s.cacheUser(ctx, updatedUser)
+	
+	s.publishUserEvent(ctx, events.UserUpdated, updatedUser)
+	
+	s.logger.Info("User preferences updated successfully", "user_id", id)
+	prefs := updatedUser.ToPreferencesResponse()
+	return &prefs, nil
+}
+
+// UploadAvatar stores a new avatar image for a user and replaces the previous one
+// The content type is sniffed from the data rather than trusted from the client.
+func (s *UserService) UploadAvatar(ctx context.Context, id string, content []byte) (*models.User, error) {
+	ctx = withActor(ctx)
+	
+	s.logger.Info("Uploading user avatar", "user_id", id, "size", len(content))
+	
+	// Validate image
+	if len(content) == 0 {
+		return nil, fmt.Errorf("%w: avatar file is empty", interfaces.ErrValidation)
+	}
+	if len(content) > MaxAvatarSize {
+		return nil, fmt.Errorf("%w: avatar must not exceed %d bytes", interfaces.ErrValidation, MaxAvatarSize)
+	}
+	contentType := http.DetectContentType(content)
+	ext, ok := avatarExtensions[contentType]
+	if !ok {
+		s.logger.Warn("Rejected avatar upload", "user_id", id, "content_type", contentType)
+		return nil, fmt.Errorf("%w: avatar must be a PNG or JPEG image", interfaces.ErrValidation)
+	}
+	
+	// Get existing user
+	user, err := s.GetUserByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	
+	// Store the file under a unique key so clients and CDNs never serve a stale image
+	key := fmt.Sprintf("%s-%d%s", id, time.Now().UnixNano(), ext)
+	url, err := s.storage.Save(ctx, key, bytes.NewReader(content))
+	if err != nil {
+		s.logger.Error("Failed to store avatar", err, "user_id", id)
+		return nil, fmt.Errorf("failed to store avatar: %w", err)
+	}
+	
+	// Update in database
+	if err := s.repo.Update(ctx, id, map[string]interface{}{"avatar": url}); err != nil {
+		s.logger.Error("Failed to update user avatar", err, "user_id", id)
+		if delErr := s.storage.Delete(ctx, key); delErr != nil {
+			s.logger.Warn("Failed to remove orphaned avatar", "key", key, "error", delErr.Error())
+		}
+		return nil, fmt.Errorf("failed to update user avatar: %w", err)
+	}
+	
+	// Remove the previous avatar if it was stored by us
+	if oldKey, ok := s.storage.KeyFromURL(user.Avatar); ok {
+		if err := s.storage.Delete(ctx, oldKey); err != nil {
+			s.logger.Warn("Failed to remove previous avatar", "key", oldKey, "error", err.Error())
+		}
+	}
+	
+	// Invalidate caches
+	s.invalidateUserCaches(ctx, user)
+	s.invalidateUserListCaches(ctx)
+	
+	// Get updated user
+	updatedUser, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		s.logger.Error("Failed to get updated user", err, "user_id", id)
+		return nil, fmt.Errorf("failed to retrieve updated user: %w", err)
+	}
+	
+	// Cache updated user
+	s.cacheUser(ctx, updatedUser)
+	
+	s.publishUserEvent(ctx, events.UserUpdated, updatedUser)
+	
+	s.logger.Info("User avatar uploaded successfully", "user_id", id, "avatar", url)
+	return updatedUser, nil
+}
+
+// RecordActivity stores the time the user was last active and drops their cached copy so
+// presence is reflected right away. It is called at most once a minute per user by the
+// activity middleware. Only the ID-keyed entry is invalidated since profiles are read by ID.
+func (s *UserService) RecordActivity(ctx context.Context, id string) error {
+	if err := s.repo.UpdateLastActivity(ctx, id); err != nil {
+		return fmt.Errorf("failed to record activity: %w", err)
+	}
+	
+	if err := s.invalidator.Invalidate(ctx, fmt.Sprintf(CacheKeyUser, id)); err != nil {
+		s.logger.Warn("Failed to invalidate cached user after activity", "user_id", id, "error", err.Error())
+	}
+	return nil
+}
+
+// RecordProfileView counts a view of the user's profile
+func (s *UserService) RecordProfileView(ctx context.Context, id string) error {
+	if err := s.repo.IncrementField(ctx, id, "profile_views", 1); err != nil {
+		return fmt.Errorf("failed to record profile view: %w", err)
+	}
+	
+	if err := s.invalidator.Invalidate(ctx, fmt.Sprintf(CacheKeyUser, id)); err != nil {
+		s.logger.Warn("Failed to invalidate cached user after profile view", "user_id", id, "error", err.Error())
+	}
+	return nil
+}
+
+// WarmCache loads the count users who logged in most recently into the user cache
+// It runs at startup so the first requests after a deploy do not all miss the cache.
+// Failures are logged; a cold cache only costs latency.
+func (s *UserService) WarmCache(ctx context.Context, count int) {
+	start := time.Now()
+	
+	users, err := s.repo.GetRecentlyLoggedIn(ctx, count)
+	if err != nil {
+		s.logger.Warn("Failed to load users for cache warming", "error", err.Error())
+		return
+	}
+	
+	for _, user := range users {
+		s.cacheUser(ctx, user)
+	}
+	
+	s.logger.Info("User cache warmed", "users", len(users), "duration", time.Since(start))
+}
+
 // GetUsers retrieves users with pagination and caching
 func (s *UserService) GetUsers(ctx context.Context, params *models.UsersQueryParams) ([]*models.User, int, error) {
 	s.logger.Debug("Getting users list", "page", params.Page, "limit", params.Limit)
 	
+	// Validate request
+	if errors := params.Validate(); len(errors) > 0 {
+		return nil, 0, fmt.Errorf("%w: %s", interfaces.ErrValidation, strings.Join(errors, ", "))
+	}
+	
 	// Set defaults
 	params.SetDefaults()
 	
@@ -310,8 +1002,9 @@ func (s *UserService) GetUsers(ctx context.Context, params *models.UsersQueryPar
 			Limit: params.Limit,
 		}
 		
+		// Keep the audit fields; the handler drops them again for non-admin callers
 		for i, user := range users {
-			result.Users[i] = user.ToUserResponse()
+			result.Users[i] = user.ToAdminUserResponse()
 		}
 		
 		s.cacheUserList(ctx, cacheKey, result)
@@ -321,32 +1014,73 @@ func (s *UserService) GetUsers(ctx context.Context, params *models.UsersQueryPar
 	return users, total, nil
 }
 
-// SearchUsers performs search on users
-func (s *UserService) SearchUsers(ctx context.Context, query string, limit int) ([]*models.User, error) {
-	s.logger.Debug("Searching users", "query", query, "limit", limit)
+// ExportUsers streams every user to fn without loading the whole collection into memory
+// Users are read straight from the database; the cache is bypassed
+func (s *UserService) ExportUsers(ctx context.Context, fn func(*models.User) error) error {
+	s.logger.Info("Exporting users")
+	
+	count := 0
+	err := s.repo.StreamAll(ctx, func(user *models.User) error {
+		count++
+		return fn(user)
+	})
+	if err != nil {
+		s.logger.Error("User export failed", err, "exported", count)
+		return err
+	}
+	
+	s.logger.Info("User export completed", "exported", count)
+	return nil
+}
+
+// SearchUsers searches users using the given mode (auto, text or regex)
+// Auto mode uses the text index and falls back to regex for queries too short for text search
+func (s *UserService) SearchUsers(ctx context.Context, query string, limit int, mode string) ([]*models.User, error) {
+	s.logger.Debug("Searching users", "query", query, "limit", limit, "mode", mode)
 	
 	if query == "" {
 		return []*models.User{}, nil
 	}
 	
-	users, err := s.repo.Search(ctx, query, limit)
+	if mode == "" {
+		mode = models.SearchModeAuto
+	}
+	if mode == models.SearchModeAuto {
+		mode = models.SearchModeText
+		if len([]rune(query)) < models.MinTextSearchLength {
+			mode = models.SearchModeRegex
+		}
+	}
+	
+	var users []*models.User
+	var err error
+	switch mode {
+	case models.SearchModeText:
+		users, err = s.repo.SearchText(ctx, query, limit)
+	case models.SearchModeRegex:
+		users, err = s.repo.Search(ctx, query, limit)
+	default:
+		return nil, fmt.Errorf("%w: invalid search mode %q", interfaces.ErrValidation, mode)
+	}
 	if err != nil {
-		s.logger.Error("Failed to search users", err, "query", query)
+		s.logger.Error("Failed to search users", err, "query", query, "mode", mode)
 		return nil, fmt.Errorf("failed to search users: %w", err)
 	}
 	
-	s.logger.Debug("User search completed", "query", query, "count", len(users))
+	s.logger.Debug("User search completed", "query", query, "mode", mode, "count", len(users))
 	return users, nil
 }
 
 // ChangePassword changes a user's password
 func (s *UserService) ChangePassword(ctx context.Context, id string, req *models.ChangePasswordRequest) error {
+	ctx = withActor(ctx)
+	
 	s.logger.Info("Changing user password", "user_id", id)
 	
 	// Validate request
 	if errors := req.Validate(); len(errors) > 0 {
 		s.logger.Warn("Password change validation failed", "errors", errors)
-		return fmt.Errorf("validation failed: %s", strings.Join(errors, ", "))
+		return fmt.Errorf("%w: %s", interfaces.ErrValidation, strings.Join(errors, ", "))
 	}
 	
 	// Get user
@@ -358,7 +1092,7 @@ func (s *UserService) ChangePassword(ctx context.Context, id string, req *models
 	// Verify current password
 	if !user.CheckPassword(req.CurrentPassword) {
 		s.logger.Warn("Invalid current password provided", "user_id", id)
-		return fmt.Errorf("current password is incorrect")
+		return fmt.Errorf("%w: current password is incorrect", interfaces.ErrValidation)
 	}
 	
 	// Set new password
@@ -380,12 +1114,50 @@ func (s *UserService) ChangePassword(ctx context.Context, id string, req *models
 	// Invalidate user caches
 	s.invalidateUserCaches(ctx, user)
 	
+	// Sessions started with the old password must not outlive it
+	if err := s.revokeTokens(ctx, id); err != nil {
+		s.logger.Error("Failed to revoke tokens after password change", err, "user_id", id)
+		return fmt.Errorf("failed to revoke existing sessions: %w", err)
+	}
+	
 	s.logger.Info("Password changed successfully", "user_id", id)
 	return nil
 }
 
+// LogoutAll revokes every access and refresh token issued to a user so far
+func (s *UserService) LogoutAll(ctx context.Context, id string) error {
+	ctx = withActor(ctx)
+	
+	s.logger.Info("Logging out all sessions", "user_id", id)
+	
+	if err := s.revokeTokens(ctx, id); err != nil {
+		if errors.Is(err, interfaces.ErrNotFound) {
+			return err
+		}
+		s.logger.Error("Failed to revoke tokens", err, "user_id", id)
+		return fmt.Errorf("failed to log out sessions: %w", err)
+	}
+	
+	s.logger.Info("All sessions logged out", "user_id", id)
+	return nil
+}
+
+// revokeTokens bumps the user's token version and drops the cached one on every instance
+func (s *UserService) revokeTokens(ctx context.Context, id string) error {
+	if err := s.repo.IncrementTokenVersion(ctx, id); err != nil {
+		return err
+	}
+	
+	if err := s.invalidator.Invalidate(ctx, fmt.Sprintf(CacheKeyUserTokenVersion, id), fmt.Sprintf(CacheKeyUser, id)); err != nil {
+		return fmt.Errorf("failed to invalidate token version: %w", err)
+	}
+	return nil
+}
+
 // VerifyUser marks a user as verified
 func (s *UserService) VerifyUser(ctx context.Context, id string) error {
+	ctx = withActor(ctx)
+	
 	s.logger.Info("Verifying user", "user_id", id)
 	
 	// Get user
@@ -395,7 +1167,7 @@ func (s *UserService) VerifyUser(ctx context.Context, id string) error {
 	}
 	
 	if user.IsVerified {
-		return fmt.Errorf("user is already verified")
+		return fmt.Errorf("%w: user is already verified", interfaces.ErrInvalidState)
 	}
 	
 	// Mark as verified in database
@@ -412,50 +1184,210 @@ func (s *UserService) VerifyUser(ctx context.Context, id string) error {
 	return nil
 }
 
+// SendVerificationEmail issues an email verification token and mails it to the user
+// Any token sent previously is invalidated.
+func (s *UserService) SendVerificationEmail(ctx context.Context, id string) error {
+	s.logger.Info("Sending verification email", "user_id", id)
+	
+	// Get user
+	user, err := s.GetUserByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	
+	if user.IsVerified {
+		return fmt.Errorf("%w: user is already verified", interfaces.ErrInvalidState)
+	}
+	
+	token, err := s.verificationTokens.Issue(ctx, id)
+	if err != nil {
+		s.logger.Error("Failed to issue verification token", err, "user_id", id)
+		return fmt.Errorf("failed to issue verification token: %w", err)
+	}
+	
+	body := fmt.Sprintf(
+		"Hi %s,\n\nUse the following token to verify your email address:\n\n%s\n\nThe token expires in %s.\n",
+		user.GetFullName(), token, s.verificationTokens.TTL(),
+	)
+	if err := s.mailer.Send(ctx, user.Email, "Verify your email address", body); err != nil {
+		s.logger.Error("Failed to send verification email", err, "user_id", id)
+		return fmt.Errorf("failed to send verification email: %w", err)
+	}
+	
+	s.logger.Info("Verification email sent", "user_id", id)
+	return nil
+}
+
+// GetSignupsByDay returns the number of signups per UTC day in [from, to)
+// The series has one entry per day, including days without signups.
+func (s *UserService) GetSignupsByDay(ctx context.Context, from, to time.Time) ([]models.DayCount, error) {
+	s.logger.Debug("Getting signups by day", "from", from, "to", to)
+	
+	counts, err := s.repo.CountByDay(ctx, from, to)
+	if err != nil {
+		s.logger.Error("Failed to count signups by day", err)
+		return nil, fmt.Errorf("failed to get signups by day: %w", err)
+	}
+	
+	byDay := make(map[string]int, len(counts))
+	for _, count := range counts {
+		byDay[count.Date] = count.Count
+	}
+	
+	// Fill the gaps so the series is continuous
+	series := []models.DayCount{}
+	start := from.UTC().Truncate(24 * time.Hour)
+	for day := start; day.Before(to); day = day.AddDate(0, 0, 1) {
+		date := day.Format(models.DayFormat)
+		series = append(series, models.DayCount{Date: date, Count: byDay[date]})
+	}
+	
+	return series, nil
+}
+
 // GetUserStats returns user statistics with caching
-func (s *UserService) GetUserStats(ctx context.Context) (map[string]interface{}, error) {
+// Only the unfiltered global stats are cached; date-ranged queries always hit the database
+func (s *UserService) GetUserStats(ctx context.Context, params *models.UserStatsParams) (*models.UserStatsResponse, error) {
 	s.logger.Debug("Getting user statistics")
 	
-	// Try cache first
-	cacheKey := CacheKeyUserStats
-	if cached, err := s.cache.Get(ctx, cacheKey); err == nil {
-		var stats map[string]interface{}
-		if json.Unmarshal([]byte(cached), &stats) == nil {
-			s.logger.Debug("User stats found in cache")
-			return stats, nil
+	// Date-ranged stats are not cached
+	if params.HasDateRange() {
+		stats, err := s.repo.GetUserStats(ctx, params)
+		if err != nil {
+			s.logger.Error("Failed to get user stats", err)
+			return nil, fmt.Errorf("failed to get user stats: %w", err)
 		}
+		
+		s.logger.Debug("User stats for date range retrieved from database")
+		return stats, nil
 	}
 	
-	// Get from database
-	stats, err := s.repo.GetUserStats(ctx)
+	// Global stats go through the cache, with a lock so only one caller hits the database on a miss
+	var stats models.UserStatsResponse
+	loaded := false
+	err := s.cache.RememberWithLock(ctx, CacheKeyUserStats, s.withJitter(UserStatsCacheExpiration), &stats, func() (interface{}, error) {
+		loaded = true
+		return s.repo.GetUserStats(ctx, params)
+	})
+	switch {
+	case loaded:
+		s.metrics.CacheMiss(CacheCategoryStats)
+	case err != nil:
+		s.metrics.CacheError(CacheCategoryStats)
+	default:
+		s.metrics.CacheHit(CacheCategoryStats)
+	}
 	if err != nil {
 		s.logger.Error("Failed to get user stats", err)
 		return nil, fmt.Errorf("failed to get user stats: %w", err)
 	}
 	
-	// Cache the stats
-	if statsJSON, err := json.Marshal(stats); err == nil {
-		s.cache.Set(ctx, cacheKey, statsJSON, UserStatsCacheExpiration)
+	s.logger.Debug("User stats retrieved")
+	return &stats, nil
+}
+
+// CountUsers returns the number of users matching the optional filters
+// The unfiltered total is cached briefly; filtered counts always hit the database.
+func (s *UserService) CountUsers(ctx context.Context, params *models.UserCountParams) (int, error) {
+	if params.HasFilters() {
+		count, err := s.repo.CountUsers(ctx, params)
+		if err != nil {
+			s.logger.Error("Failed to count users", err)
+			return 0, fmt.Errorf("failed to count users: %w", err)
+		}
+		return count, nil
+	}
+	
+	var count int
+	loaded := false
+	err := s.cache.RememberWithLock(ctx, CacheKeyUserCount, s.withJitter(UserCountCacheExpiration), &count, func() (interface{}, error) {
+		loaded = true
+		return s.repo.CountUsers(ctx, params)
+	})
+	switch {
+	case loaded:
+		s.metrics.CacheMiss(CacheCategoryStats)
+	case err != nil:
+		s.metrics.CacheError(CacheCategoryStats)
+	default:
+		s.metrics.CacheHit(CacheCategoryStats)
+	}
+	if err != nil {
+		s.logger.Error("Failed to count users", err)
+		return 0, fmt.Errorf("failed to count users: %w", err)
 	}
 	
-	s.logger.Debug("User stats retrieved from database and cached")
-	return stats, nil
+	return count, nil
 }
 
 // Helper methods for caching
 
+// withActor attaches the authenticated caller's ID to ctx so repository writes
+// record who made them
+func withActor(ctx context.Context) context.Context {
+	if userID, ok := middleware.UserIDFromContext(ctx); ok {
+		return repositories.WithActor(ctx, userID)
+	}
+	return ctx
+}
+
+// publishUserEvent announces a user lifecycle event with the user's public representation
+// The event is stored in the outbox before the call returns. The write has already
+// succeeded, so a failure to store the event is logged rather than returned.
+func (s *UserService) publishUserEvent(ctx context.Context, eventType string, user *models.User) {
+	if err := s.events.Publish(ctx, eventType, user.ToUserResponse()); err != nil {
+		s.logger.Error("Failed to publish user event", err, "event_type", eventType, "user_id", user.GetIDString())
+	}
+}
+
+// logCacheReadError logs cache read failures other than plain misses
+// Callers treat every cache error as a miss and fall back to the database, so an
+// unavailable cache degrades performance rather than failing requests
+func (s *UserService) logCacheReadError(key string, err error) {
+	if !errors.Is(err, interfaces.ErrCacheMiss) {
+		s.logger.Warn("Cache read failed, falling back to database", "cache_key", key, "error", err.Error())
+	}
+}
+
+// recordCacheLookup counts a cache lookup in category as a hit, a miss or an error
+func (s *UserService) recordCacheLookup(category string, err error) {
+	switch {
+	case err == nil:
+		s.metrics.CacheHit(category)
+	case errors.Is(err, interfaces.ErrCacheMiss):
+		s.metrics.CacheMiss(category)
+	default:
+		s.metrics.CacheError(category)
+	}
+}
+
+// withJitter varies base randomly by up to ±cacheJitter of its length
+// Entries cached together then expire at different times instead of all missing at once.
+func (s *UserService) withJitter(base time.Duration) time.Duration {
+	if s.cacheJitter <= 0 || base <= 0 {
+		return base
+	}
+	
+	spread := float64(base) * s.cacheJitter
+	return base + time.Duration((rand.Float64()*2-1)*spread)
+}
+
 // getUserFromCache retrieves a user from cache
 func (s *UserService) getUserFromCache(ctx context.Context, key string) (*models.User, error) {
 	cached, err := s.cache.Get(ctx, key)
 	if err != nil {
+		s.recordCacheLookup(CacheCategoryUser, err)
+		s.logCacheReadError(key, err)
 		return nil, err
 	}
 	
 	var user models.User
 	if err := json.Unmarshal([]byte(cached), &user); err != nil {
+		s.recordCacheLookup(CacheCategoryUser, err)
 		return nil, err
 	}
 	
+	s.recordCacheLookup(CacheCategoryUser, nil)
 	return &user, nil
 }
 
@@ -475,13 +1407,28 @@ func (s *UserService) cacheUser(ctx context.Context, user *models.User) {
 	}
 	
 	for _, key := range keys {
-		if err := s.cache.Set(ctx, key, userJSON, UserCacheExpiration); err != nil {
-			s.logger.Error("Failed to cache user", err, "cache_key", key)
+		if err := s.cache.Set(ctx, key, userJSON, s.withJitter(UserCacheExpiration)); err != nil {
+			s.logger.Warn("Failed to cache user", "cache_key", key, "error", err.Error())
 		}
 	}
 }
 
-// invalidateUserCaches removes user from all cache keys
+// invalidateUserExists removes the cached existence checks for the users' usernames and emails
+func (s *UserService) invalidateUserExists(ctx context.Context, users ...*models.User) {
+	keys := make([]string, 0, 2*len(users))
+	for _, user := range users {
+		keys = append(keys,
+			fmt.Sprintf(CacheKeyUserExists, "email", user.Email),
+			fmt.Sprintf(CacheKeyUserExists, "username", user.Username),
+		)
+	}
+	
+	if err := s.invalidator.Invalidate(ctx, keys...); err != nil {
+		s.logger.Error("Failed to invalidate existence cache", err, "users", len(users))
+	}
+}
+
+// invalidateUserCaches removes user from all cache keys on every instance
 func (s *UserService) invalidateUserCaches(ctx context.Context, user *models.User) {
 	keys := []string{
 		fmt.Sprintf(CacheKeyUser, user.GetIDString()),
@@ -491,10 +1438,8 @@ func (s *UserService) invalidateUserCaches(ctx context.Context, user *models.Use
 		fmt.Sprintf(CacheKeyUserExists, "username", user.Username),
 	}
 	
-	for _, key := range keys {
-		if err := s.cache.Delete(ctx, key); err != nil {
-			s.logger.Error("Failed to invalidate cache", err, "cache_key", key)
-		}
+	if err := s.invalidator.Invalidate(ctx, keys...); err != nil {
+		s.logger.Error("Failed to invalidate cache", err, "user_id", user.GetIDString())
 	}
 }
 
@@ -507,25 +1452,33 @@ func (s *UserService) invalidateUserListCaches(ctx context.Context) {
 	// Note: This is a simplified approach. In production, consider using cache tagging
 }
 
-// invalidateUserStats removes user stats cache
+// invalidateUserStats removes the user stats and total count caches
 func (s *UserService) invalidateUserStats(ctx context.Context) {
-	if err := s.cache.Delete(ctx, CacheKeyUserStats); err != nil {
+	if err := s.invalidator.Invalidate(ctx, CacheKeyUserStats, CacheKeyUserCount); err != nil {
 		s.logger.Error("Failed to invalidate user stats cache", err)
 	}
 }
 
 // checkUserExists checks if a user exists by field with caching
 func (s *UserService) checkUserExists(ctx context.Context, field, value string) (bool, error) {
+	// Matching is case-insensitive, so share one cache entry across spellings
+	if field == "username" {
+		value = models.NormalizeUsername(value)
+	} else {
+		value = strings.ToLower(value)
+	}
 	cacheKey := fmt.Sprintf(CacheKeyUserExists, field, value)
 	
 	// Try cache first
-	if cached, err := s.cache.Get(ctx, cacheKey); err == nil {
+	cached, err := s.cache.Get(ctx, cacheKey)
+	s.recordCacheLookup(CacheCategoryExists, err)
+	if err == nil {
 		return cached == "true", nil
 	}
+	s.logCacheReadError(cacheKey, err)
 	
 	// Check database
 	var exists bool
-	var err error
 	
 	switch field {
 	case "email":
@@ -541,26 +1494,72 @@ func (s *UserService) checkUserExists(ctx context.Context, field, value string)
 	}
 	
 	// Cache the result
+	s.cacheExistence(ctx, cacheKey, exists)
+	
+	return exists, nil
+}
+
+// checkIdentityExists checks whether a username and an email are taken, using cached answers
+// when both are known and a single database query otherwise
+func (s *UserService) checkIdentityExists(ctx context.Context, username, email string) (bool, bool, error) {
+	// Matching is case-insensitive, so share one cache entry across spellings
+	usernameKey := fmt.Sprintf(CacheKeyUserExists, "username", models.NormalizeUsername(username))
+	emailKey := fmt.Sprintf(CacheKeyUserExists, "email", strings.ToLower(email))
+	
+	// Try cache first
+	cachedUsername, usernameErr := s.cache.Get(ctx, usernameKey)
+	cachedEmail, emailErr := s.cache.Get(ctx, emailKey)
+	s.recordCacheLookup(CacheCategoryExists, usernameErr)
+	s.recordCacheLookup(CacheCategoryExists, emailErr)
+	if usernameErr == nil && emailErr == nil {
+		return cachedUsername == "true", cachedEmail == "true", nil
+	}
+	if usernameErr != nil {
+		s.logCacheReadError(usernameKey, usernameErr)
+	}
+	if emailErr != nil {
+		s.logCacheReadError(emailKey, emailErr)
+	}
+	
+	// Check database
+	usernameExists, emailExists, err := s.repo.ExistsByUsernameOrEmail(ctx, username, email)
+	if err != nil {
+		return false, false, err
+	}
+	
+	// Cache the results
+	s.cacheExistence(ctx, usernameKey, usernameExists)
+	s.cacheExistence(ctx, emailKey, emailExists)
+	
+	return usernameExists, emailExists, nil
+}
+
+// cacheExistence caches the answer of an existence check
+func (s *UserService) cacheExistence(ctx context.Context, cacheKey string, exists bool) {
 	cacheValue := "false"
 	if exists {
 		cacheValue = "true"
 	}
-	s.cache.Set(ctx, cacheKey, cacheValue, UserExistsCacheExpiration)
-	
-	return exists, nil
+	if err := s.cache.Set(ctx, cacheKey, cacheValue, s.withJitter(UserExistsCacheExpiration)); err != nil {
+		s.logger.Warn("Failed to cache existence check", "cache_key", cacheKey, "error", err.Error())
+	}
 }
 
 // getUserListFromCache retrieves user list from cache
 func (s *UserService) getUserListFromCache(ctx context.Context, key string) (*models.UserListResponse, error) {
 	cached, err := s.cache.Get(ctx, key)
 	if err != nil {
+		s.recordCacheLookup(CacheCategoryList, err)
+		s.logCacheReadError(key, err)
 		return nil, err
 	}
 	
 	var result models.UserListResponse
 	if err := json.Unmarshal([]byte(cached), &result); err != nil {
+		s.recordCacheLookup(CacheCategoryList, err)
 		return nil, err
 	}
+	s.recordCacheLookup(CacheCategoryList, nil)
 	
 	// Convert back to User models
 	users := make([]*models.User, len(result.Users))
@@ -588,15 +1587,15 @@ func (s *UserService) cacheUserList(ctx context.Context, key string, list *model
 		return
 	}
 	
-	if err := s.cache.Set(ctx, key, listJSON, UserListCacheExpiration); err != nil {
-		s.logger.Error("Failed to cache user list", err)
+	if err := s.cache.Set(ctx, key, listJSON, s.withJitter(UserListCacheExpiration)); err != nil {
+		s.logger.Warn("Failed to cache user list", "cache_key", key, "error", err.Error())
 	}
 }
 
 // isCacheableQuery determines if a query can be cached
 func (s *UserService) isCacheableQuery(params *models.UsersQueryParams) bool {
-	// Only cache simple queries without search or complex filters
-	return params.Search == "" && params.Role == "" && params.IsActive == nil
+	// Only cache simple queries without search, complex filters or projections
+	return !params.HasFilters() && len(params.Fields) == 0
 }
 
 // buildUserListCacheKey creates a cache key for user list queries
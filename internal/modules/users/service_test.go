@@ -0,0 +1,1884 @@
+// internal/modules/users/service_test.go
+package users
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go-template/internal/database"
+	"go-template/internal/interfaces"
+	"go-template/internal/models"
+	"go-template/internal/repositories"
+	"go-template/internal/shared/logtest"
+	"go-template/internal/shared/mail"
+	"go-template/internal/shared/metrics"
+	"go-template/internal/shared/middleware"
+	"go-template/internal/shared/storage"
+	"go-template/internal/shared/utils"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/text/unicode/norm"
+)
+
+func TestMain(m *testing.M) {
+	// Hash test passwords at the lowest cost so creating users stays fast
+	utils.SetDefaultPasswordService(utils.NewPasswordServiceWithCost(bcrypt.MinCost))
+	os.Exit(m.Run())
+}
+
+// testUsers bundles a UserService with the doubles it was built from
+type testUsers struct {
+	service *UserService
+	repo    *hookedRepository
+	cache   *database.MemoryCache
+	mailer  *mail.MemoryMailer
+	events  *recordingPublisher
+	files   *storage.LocalStorage
+	tokens  *utils.TokenService
+	metrics *spyCacheMetrics
+	logger  *logtest.Logger
+}
+
+// spyCacheMetrics is a CacheMetrics that counts lookups as "category/result"
+type spyCacheMetrics struct {
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+func (m *spyCacheMetrics) record(category, result string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.counts == nil {
+		m.counts = make(map[string]int)
+	}
+	m.counts[category+"/"+result]++
+}
+
+func (m *spyCacheMetrics) CacheHit(category string)   { m.record(category, "hit") }
+func (m *spyCacheMetrics) CacheMiss(category string)  { m.record(category, "miss") }
+func (m *spyCacheMetrics) CacheError(category string) { m.record(category, "error") }
+
+// snapshot returns the lookups counted so far
+func (m *spyCacheMetrics) snapshot() map[string]int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return maps.Clone(m.counts)
+}
+
+// recordingPublisher is an EventPublisher that keeps the types of published events
+type recordingPublisher struct {
+	mu    sync.Mutex
+	types []string
+}
+
+func (p *recordingPublisher) Publish(ctx context.Context, eventType string, data interface{}) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.types = append(p.types, eventType)
+	return nil
+}
+
+// published returns the types of the events published so far, oldest first
+func (p *recordingPublisher) published() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string(nil), p.types...)
+}
+
+// hookedRepository is a MemoryUserRepository whose CreateMany can run a hook first,
+// to simulate writes made by other requests between the service's checks and its insert
+type hookedRepository struct {
+	*repositories.MemoryUserRepository
+	beforeCreateMany func()
+}
+
+func (r *hookedRepository) CreateMany(ctx context.Context, users []*models.User) error {
+	if r.beforeCreateMany != nil {
+		r.beforeCreateMany()
+	}
+	return r.MemoryUserRepository.CreateMany(ctx, users)
+}
+
+// newTestUsers builds a UserService over in-memory doubles
+func newTestUsers(t *testing.T) *testUsers {
+	t.Helper()
+
+	logger := logtest.New()
+	cache := database.NewMemoryCache()
+	t.Cleanup(func() { cache.Close() })
+
+	files, err := storage.NewLocalStorage(t.TempDir(), "/uploads/avatars")
+	if err != nil {
+		t.Fatalf("NewLocalStorage() error = %v", err)
+	}
+
+	tu := &testUsers{
+		repo:    &hookedRepository{MemoryUserRepository: repositories.NewMemoryUserRepository()},
+		cache:   cache,
+		mailer:  mail.NewMemoryMailer(),
+		events:  &recordingPublisher{},
+		files:   files,
+		tokens:  utils.NewTokenService("test-secret-test-secret-test-secret", time.Hour, 24*time.Hour, 30*24*time.Hour),
+		metrics: &spyCacheMetrics{},
+		logger:  logger,
+	}
+	tu.service = NewUserService(tu.repo, cache, database.NewInvalidator(cache, logger), files,
+		tu.mailer, tu.events, tu.metrics, 0, logger)
+	return tu
+}
+
+// createUser stores a test user and returns it
+func (tu *testUsers) createUser(t *testing.T, opts ...models.TestUserOption) *models.User {
+	t.Helper()
+	user := models.NewTestUser(opts...)
+	if err := tu.repo.Create(context.Background(), user); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	return user
+}
+
+// storedUser reads a user straight from the repository, bypassing the cache
+func (tu *testUsers) storedUser(t *testing.T, id string) *models.User {
+	t.Helper()
+	user, err := tu.repo.GetByIDIncludingDeleted(context.Background(), id)
+	if err != nil {
+		t.Fatalf("GetByIDIncludingDeleted() error = %v", err)
+	}
+	return user
+}
+
+// createRequest builds a valid CreateUserRequest for username
+func createRequest(username string) models.CreateUserRequest {
+	return models.CreateUserRequest{
+		Username: username,
+		Email:    username + "@example.com",
+		Password: "SecurePass123",
+	}
+}
+
+func TestBulkCreateUsers(t *testing.T) {
+	tests := []struct {
+		name          string
+		existing      []string
+		concurrent    []string // created by another request after the checks, before the insert
+		reqs          []models.CreateUserRequest
+		wantErr       error
+		wantSucceeded int
+		wantFailed    []int
+	}{
+		{
+			name:          "valid batch",
+			reqs:          []models.CreateUserRequest{createRequest("alice"), createRequest("bob"), createRequest("carol")},
+			wantSucceeded: 3,
+		},
+		{
+			name:          "duplicate of an existing user",
+			existing:      []string{"bob"},
+			reqs:          []models.CreateUserRequest{createRequest("alice"), createRequest("bob"), createRequest("carol")},
+			wantSucceeded: 2,
+			wantFailed:    []int{1},
+		},
+		{
+			name:          "duplicate within the batch",
+			reqs:          []models.CreateUserRequest{createRequest("alice"), createRequest("Alice")},
+			wantSucceeded: 1,
+			wantFailed:    []int{1},
+		},
+		{
+			name:          "invalid item",
+			reqs:          []models.CreateUserRequest{createRequest("alice"), {Username: "x", Email: "not-an-email"}},
+			wantSucceeded: 1,
+			wantFailed:    []int{1},
+		},
+		{
+			name:          "duplicate created concurrently",
+			concurrent:    []string{"bob"},
+			reqs:          []models.CreateUserRequest{createRequest("alice"), createRequest("bob"), createRequest("carol")},
+			wantSucceeded: 2,
+			wantFailed:    []int{1},
+		},
+		{
+			name:    "oversized batch",
+			reqs:    make([]models.CreateUserRequest, MaxBulkCreateSize+1),
+			wantErr: interfaces.ErrValidation,
+		},
+		{
+			name:    "empty batch",
+			wantErr: interfaces.ErrValidation,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tu := newTestUsers(t)
+			for _, username := range tt.existing {
+				tu.createUser(t, models.WithUsername(username))
+			}
+			tu.repo.beforeCreateMany = func() {
+				for _, username := range tt.concurrent {
+					tu.createUser(t, models.WithUsername(username))
+				}
+			}
+
+			result, err := tu.service.BulkCreateUsers(context.Background(), tt.reqs)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("BulkCreateUsers() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("BulkCreateUsers() error = %v", err)
+			}
+
+			if result.Succeeded != tt.wantSucceeded || result.Failed != len(tt.wantFailed) {
+				t.Errorf("succeeded, failed = %d, %d, want %d, %d", result.Succeeded, result.Failed, tt.wantSucceeded, len(tt.wantFailed))
+			}
+
+			failed := map[int]bool{}
+			for _, i := range tt.wantFailed {
+				failed[i] = true
+			}
+			for i, item := range result.Results {
+				if item.Index != i {
+					t.Errorf("result %d has index %d", i, item.Index)
+				}
+				if item.Success == failed[i] {
+					t.Errorf("result %d success = %v, want %v (error %q)", i, item.Success, !failed[i], item.Error)
+				}
+				if item.Success {
+					if item.User == nil || item.User.ID == "" {
+						t.Errorf("result %d has no created user", i)
+					} else if _, err := tu.repo.GetByID(context.Background(), item.User.ID); err != nil {
+						t.Errorf("created user %s not stored: %v", item.User.ID, err)
+					}
+				} else if item.Error == "" {
+					t.Errorf("result %d failed without an error", i)
+				}
+			}
+		})
+	}
+}
+
+func TestBulkCreateUsersInvalidatesExistenceCache(t *testing.T) {
+	tu := newTestUsers(t)
+	ctx := context.Background()
+
+	// Cache a negative existence check, as a failed lookup before the import would
+	if exists, err := tu.service.checkUserExists(ctx, "username", "alice"); err != nil || exists {
+		t.Fatalf("checkUserExists() = %v, %v, want false", exists, err)
+	}
+
+	if _, err := tu.service.BulkCreateUsers(ctx, []models.CreateUserRequest{createRequest("alice")}); err != nil {
+		t.Fatalf("BulkCreateUsers() error = %v", err)
+	}
+
+	key := fmt.Sprintf(CacheKeyUserExists, "username", "alice")
+	if _, err := tu.cache.Get(ctx, key); !errors.Is(err, interfaces.ErrCacheMiss) {
+		t.Errorf("cache key %s still present after import (error %v)", key, err)
+	}
+	if exists, err := tu.service.checkUserExists(ctx, "username", "alice"); err != nil || !exists {
+		t.Errorf("checkUserExists() = %v, %v, want true", exists, err)
+	}
+}
+
+func TestRestoreUser(t *testing.T) {
+	tests := []struct {
+		name    string
+		deleted bool
+		id      string // overrides the created user's ID
+		wantErr error
+	}{
+		{name: "deleted user", deleted: true},
+		{name: "user that is not deleted", wantErr: interfaces.ErrInvalidState},
+		{name: "unknown user", id: "507f1f77bcf86cd799439011", wantErr: interfaces.ErrNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tu := newTestUsers(t)
+			ctx := context.Background()
+			user := tu.createUser(t)
+			id := user.GetIDString()
+			if tt.id != "" {
+				id = tt.id
+			}
+
+			if tt.deleted {
+				if err := tu.service.DeleteUser(ctx, id); err != nil {
+					t.Fatalf("DeleteUser() error = %v", err)
+				}
+				if _, err := tu.service.GetUserByID(ctx, id); !errors.Is(err, interfaces.ErrNotFound) {
+					t.Fatalf("GetUserByID() after delete error = %v, want ErrNotFound", err)
+				}
+			}
+			tu.cache.Set(ctx, CacheKeyUserStats, "{}", time.Hour)
+
+			restored, err := tu.service.RestoreUser(ctx, id)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("RestoreUser() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("RestoreUser() error = %v", err)
+			}
+			if restored.IsDeleted() || !restored.IsActive {
+				t.Errorf("restored user deleted = %v, active = %v", restored.IsDeleted(), restored.IsActive)
+			}
+
+			got, err := tu.service.GetUserByID(ctx, id)
+			if err != nil {
+				t.Fatalf("GetUserByID() after restore error = %v", err)
+			}
+			if got.GetIDString() != id {
+				t.Errorf("GetUserByID() = %s, want %s", got.GetIDString(), id)
+			}
+			if _, err := tu.cache.Get(ctx, CacheKeyUserStats); !errors.Is(err, interfaces.ErrCacheMiss) {
+				t.Errorf("stats cache not invalidated on restore (error %v)", err)
+			}
+		})
+	}
+}
+
+func TestSetUserRoles(t *testing.T) {
+	tests := []struct {
+		name        string
+		current     []string
+		roles       []string
+		id          string
+		wantErr     error
+		wantRevoked bool
+	}{
+		{
+			name:    "adding a role",
+			current: []string{models.RoleUser},
+			roles:   []string{models.RoleUser, models.RoleAdmin},
+		},
+		{
+			name:        "removing a role revokes tokens",
+			current:     []string{models.RoleUser, models.RoleAdmin},
+			roles:       []string{models.RoleUser},
+			wantRevoked: true,
+		},
+		{
+			name:        "replacing a role revokes tokens",
+			current:     []string{models.RoleAdmin},
+			roles:       []string{models.RoleMod},
+			wantRevoked: true,
+		},
+		{
+			name:    "unknown role",
+			current: []string{models.RoleUser},
+			roles:   []string{models.RoleUser, "superuser"},
+			wantErr: interfaces.ErrValidation,
+		},
+		{
+			name:    "empty roles",
+			current: []string{models.RoleUser},
+			roles:   []string{},
+			wantErr: interfaces.ErrValidation,
+		},
+		{
+			name:    "unknown user",
+			current: []string{models.RoleUser},
+			roles:   []string{models.RoleAdmin},
+			id:      "507f1f77bcf86cd799439011",
+			wantErr: interfaces.ErrNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tu := newTestUsers(t)
+			user := tu.createUser(t, models.WithRoles(tt.current...))
+			id := user.GetIDString()
+			if tt.id != "" {
+				id = tt.id
+			}
+
+			updated, err := tu.service.SetUserRoles(context.Background(), id, &models.SetRolesRequest{Roles: tt.roles})
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("SetUserRoles() error = %v, want %v", err, tt.wantErr)
+				}
+				if stored := tu.storedUser(t, user.GetIDString()); !slices.Equal(stored.Roles, tt.current) {
+					t.Errorf("stored roles = %v, want unchanged %v", stored.Roles, tt.current)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SetUserRoles() error = %v", err)
+			}
+			if !slices.Equal(updated.Roles, tt.roles) {
+				t.Errorf("roles = %v, want %v", updated.Roles, tt.roles)
+			}
+
+			revoked := tu.storedUser(t, id).TokenVersion > user.TokenVersion
+			if revoked != tt.wantRevoked {
+				t.Errorf("tokens revoked = %v, want %v", revoked, tt.wantRevoked)
+			}
+		})
+	}
+}
+
+func TestGetUserStatsCaching(t *testing.T) {
+	from := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		params     *models.UserStatsParams
+		wantCached bool
+	}{
+		{name: "global stats are cached", params: &models.UserStatsParams{}, wantCached: true},
+		{name: "date-ranged stats are not cached", params: &models.UserStatsParams{From: &from}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			tu := newTestUsers(t)
+			tu.createUser(t, models.WithRoles(models.RoleUser))
+
+			first, err := tu.service.GetUserStats(ctx, tt.params)
+			if err != nil {
+				t.Fatalf("GetUserStats() error = %v", err)
+			}
+			if first.TotalUsers != 1 || first.ByRole[models.RoleUser] != 1 {
+				t.Fatalf("first stats = %+v, want one user", first)
+			}
+
+			// Written straight to the repository, so nothing invalidates the cached stats
+			tu.createUser(t, models.WithRoles(models.RoleAdmin))
+
+			second, err := tu.service.GetUserStats(ctx, tt.params)
+			if err != nil {
+				t.Fatalf("GetUserStats() error = %v", err)
+			}
+			wantTotal := 2
+			if tt.wantCached {
+				wantTotal = 1
+			}
+			if second.TotalUsers != wantTotal {
+				t.Errorf("second TotalUsers = %d, want %d", second.TotalUsers, wantTotal)
+			}
+		})
+	}
+}
+
+// mailedToken extracts the action token from the last email sent to the test mailer
+func (tu *testUsers) mailedToken(t *testing.T) string {
+	t.Helper()
+	sent := tu.mailer.Sent()
+	if len(sent) == 0 {
+		t.Fatal("no email sent")
+	}
+	token := actionTokenPattern.FindString(sent[len(sent)-1].Body)
+	if token == "" {
+		t.Fatalf("no token in email body %q", sent[len(sent)-1].Body)
+	}
+	return token
+}
+
+// actionTokenPattern matches the hex tokens issued by utils.ActionTokenStore
+var actionTokenPattern = regexp.MustCompile(`[0-9a-f]{64}`)
+
+func TestSendVerificationEmail(t *testing.T) {
+	tests := []struct {
+		name      string
+		opts      []models.TestUserOption
+		missing   bool
+		wantErr   error
+		wantMails int
+	}{
+		{name: "unverified user", opts: []models.TestUserOption{models.WithVerified(false)}, wantMails: 1},
+		{name: "already verified", opts: []models.TestUserOption{models.WithVerified(true)}, wantErr: interfaces.ErrInvalidState},
+		{name: "unknown user", missing: true, wantErr: interfaces.ErrNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tu := newTestUsers(t)
+			ctx := context.Background()
+			user := tu.createUser(t, tt.opts...)
+			id := user.GetIDString()
+			if tt.missing {
+				id = primitive.NewObjectID().Hex()
+			}
+
+			err := tu.service.SendVerificationEmail(ctx, id)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("SendVerificationEmail() error = %v, want %v", err, tt.wantErr)
+			}
+			sent := tu.mailer.Sent()
+			if len(sent) != tt.wantMails {
+				t.Fatalf("sent %d emails, want %d", len(sent), tt.wantMails)
+			}
+			if tt.wantMails == 0 {
+				return
+			}
+
+			if sent[0].To != user.Email {
+				t.Errorf("email sent to %q, want %q", sent[0].To, user.Email)
+			}
+			userID, err := utils.NewEmailVerificationTokens(tu.cache).Consume(ctx, tu.mailedToken(t))
+			if err != nil || userID != id {
+				t.Errorf("Consume(mailed token) = %q, %v, want %q", userID, err, id)
+			}
+			if tu.storedUser(t, id).IsVerified {
+				t.Error("user verified by sending the email, want verification to wait for the token")
+			}
+		})
+	}
+}
+
+func TestSendVerificationEmailRevokesPreviousToken(t *testing.T) {
+	tu := newTestUsers(t)
+	ctx := context.Background()
+	user := tu.createUser(t, models.WithVerified(false))
+
+	if err := tu.service.SendVerificationEmail(ctx, user.GetIDString()); err != nil {
+		t.Fatalf("SendVerificationEmail() error = %v", err)
+	}
+	first := tu.mailedToken(t)
+	if err := tu.service.SendVerificationEmail(ctx, user.GetIDString()); err != nil {
+		t.Fatalf("SendVerificationEmail() error = %v", err)
+	}
+	second := tu.mailedToken(t)
+
+	tokens := utils.NewEmailVerificationTokens(tu.cache)
+	if _, err := tokens.Consume(ctx, first); !errors.Is(err, utils.ErrInvalidActionToken) {
+		t.Errorf("Consume(first token) error = %v, want %v", err, utils.ErrInvalidActionToken)
+	}
+	if _, err := tokens.Consume(ctx, second); err != nil {
+		t.Errorf("Consume(second token) error = %v", err)
+	}
+}
+
+// errCacheDown is returned by failingCache for every call
+var errCacheDown = errors.New("dial tcp 127.0.0.1:6379: connect: connection refused")
+
+// failingCache is a cache whose reads and writes all fail, as when Redis is down
+type failingCache struct {
+	*database.MemoryCache
+}
+
+func (c failingCache) Get(ctx context.Context, key string) (string, error) {
+	return "", errCacheDown
+}
+
+func (c failingCache) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error {
+	return errCacheDown
+}
+
+func (c failingCache) Delete(ctx context.Context, keys ...string) error {
+	return errCacheDown
+}
+
+func (c failingCache) Exists(ctx context.Context, key string) (bool, error) {
+	return false, errCacheDown
+}
+
+func TestDegradedCache(t *testing.T) {
+	tests := []struct {
+		name string
+		// call looks up user through the service
+		call func(ctx context.Context, s *UserService, user *models.User) (*models.User, error)
+	}{
+		{
+			name: "GetUserByID",
+			call: func(ctx context.Context, s *UserService, user *models.User) (*models.User, error) {
+				return s.GetUserByID(ctx, user.GetIDString())
+			},
+		},
+		{
+			name: "GetUserByEmail",
+			call: func(ctx context.Context, s *UserService, user *models.User) (*models.User, error) {
+				return s.GetUserByEmail(ctx, user.Email)
+			},
+		},
+		{
+			name: "GetUserByUsername",
+			call: func(ctx context.Context, s *UserService, user *models.User) (*models.User, error) {
+				return s.GetUserByUsername(ctx, user.Username)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger := logtest.New()
+			cache := failingCache{MemoryCache: database.NewMemoryCache()}
+			t.Cleanup(func() { cache.Close() })
+			repo := repositories.NewMemoryUserRepository()
+			service := NewUserService(repo, cache, database.NewInvalidator(cache, logger), nil,
+				mail.NewMemoryMailer(), &recordingPublisher{}, metrics.NopCacheMetrics{}, 0, logger)
+
+			user := models.NewTestUser()
+			if err := repo.Create(context.Background(), user); err != nil {
+				t.Fatalf("Create() error = %v", err)
+			}
+
+			got, err := tt.call(context.Background(), service, user)
+			if err != nil {
+				t.Fatalf("%s() error = %v, want the user from the repository", tt.name, err)
+			}
+			if got.GetIDString() != user.GetIDString() {
+				t.Errorf("%s() = user %s, want %s", tt.name, got.GetIDString(), user.GetIDString())
+			}
+			if !logger.Has(slog.LevelWarn, "Cache read failed, falling back to database") {
+				t.Error("cache read failure not logged at warn")
+			}
+		})
+	}
+}
+
+// callerContext returns a context authenticated as userID with roles
+func callerContext(userID string, roles ...string) context.Context {
+	return middleware.WithClaims(context.Background(), &utils.TokenClaims{Subject: userID, Roles: roles})
+}
+
+func TestAuditFields(t *testing.T) {
+	const creator, editor = "665f00000000000000000001", "665f00000000000000000002"
+
+	tests := []struct {
+		name          string
+		createCtx     context.Context
+		updateCtx     context.Context
+		wantCreatedBy string
+		wantUpdatedBy string
+	}{
+		{
+			name:          "created and updated by the same caller",
+			createCtx:     callerContext(creator, models.RoleAdmin),
+			updateCtx:     callerContext(creator, models.RoleAdmin),
+			wantCreatedBy: creator,
+			wantUpdatedBy: creator,
+		},
+		{
+			name:          "updated by another caller",
+			createCtx:     callerContext(creator, models.RoleAdmin),
+			updateCtx:     callerContext(editor, models.RoleAdmin),
+			wantCreatedBy: creator,
+			wantUpdatedBy: editor,
+		},
+		{
+			name:          "anonymous update keeps the last editor",
+			createCtx:     callerContext(creator, models.RoleAdmin),
+			updateCtx:     context.Background(),
+			wantCreatedBy: creator,
+			wantUpdatedBy: creator,
+		},
+		{
+			name:      "anonymous signup",
+			createCtx: context.Background(),
+			updateCtx: context.Background(),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tu := newTestUsers(t)
+			req := createRequest("audited")
+			user, err := tu.service.CreateUser(tt.createCtx, &req)
+			if err != nil {
+				t.Fatalf("CreateUser() error = %v", err)
+			}
+			created := tu.storedUser(t, user.GetIDString())
+			if created.CreatedBy != tt.wantCreatedBy || created.UpdatedBy != tt.wantCreatedBy {
+				t.Errorf("after create created_by = %q, updated_by = %q, want both %q", created.CreatedBy, created.UpdatedBy, tt.wantCreatedBy)
+			}
+
+			firstName := "Edited"
+			if _, err := tu.service.UpdateUser(tt.updateCtx, user.GetIDString(), &models.UpdateUserRequest{FirstName: &firstName}); err != nil {
+				t.Fatalf("UpdateUser() error = %v", err)
+			}
+			updated := tu.storedUser(t, user.GetIDString())
+			if updated.CreatedBy != tt.wantCreatedBy {
+				t.Errorf("after update created_by = %q, want %q", updated.CreatedBy, tt.wantCreatedBy)
+			}
+			if updated.UpdatedBy != tt.wantUpdatedBy {
+				t.Errorf("after update updated_by = %q, want %q", updated.UpdatedBy, tt.wantUpdatedBy)
+			}
+		})
+	}
+}
+
+func TestUpdateUserCaseInsensitiveUniqueness(t *testing.T) {
+	tests := []struct {
+		name         string
+		username     string
+		email        string
+		self         bool // update johndoe itself instead of another user
+		wantConflict bool
+		wantUsername string
+		wantEmail    string
+	}{
+		{name: "username differing only by case", username: "JohnDoe", wantConflict: true},
+		{name: "username with surrounding spaces", username: "  JOHNDOE ", wantConflict: true},
+		{name: "email differing only by case", email: "JohnDoe@Example.COM", wantConflict: true},
+		{name: "new username is stored lowercased", username: "JaneRoe", wantUsername: "janeroe"},
+		{name: "new email is stored lowercased", email: "Jane.Roe@Example.com", wantEmail: "jane.roe@example.com"},
+		{name: "own username in another case", username: "JohnDoe", self: true, wantUsername: "johndoe"},
+		{name: "own email in another case", email: "JOHNDOE@example.com", self: true, wantEmail: "johndoe@example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tu := newTestUsers(t)
+			johndoe := tu.createUser(t, models.WithUsername("johndoe"), models.WithEmail("johndoe@example.com"))
+			other := tu.createUser(t, models.WithUsername("other"), models.WithEmail("other@example.com"))
+			target := other
+			if tt.self {
+				target = johndoe
+			}
+
+			req := &models.UpdateUserRequest{}
+			if tt.username != "" {
+				req.Username = &tt.username
+			}
+			if tt.email != "" {
+				req.Email = &tt.email
+			}
+
+			_, err := tu.service.UpdateUser(context.Background(), target.GetIDString(), req)
+			if tt.wantConflict {
+				if !errors.Is(err, interfaces.ErrAlreadyExists) {
+					t.Fatalf("UpdateUser() error = %v, want ErrAlreadyExists", err)
+				}
+				stored := tu.storedUser(t, other.GetIDString())
+				if stored.Username != "other" || stored.Email != "other@example.com" {
+					t.Errorf("stored %s <%s>, want the user unchanged", stored.Username, stored.Email)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("UpdateUser() error = %v", err)
+			}
+
+			stored := tu.storedUser(t, target.GetIDString())
+			if tt.wantUsername != "" && stored.Username != tt.wantUsername {
+				t.Errorf("username = %q, want %q", stored.Username, tt.wantUsername)
+			}
+			if tt.wantEmail != "" && stored.Email != tt.wantEmail {
+				t.Errorf("email = %q, want %q", stored.Email, tt.wantEmail)
+			}
+		})
+	}
+}
+
+func TestUserServiceValidationFieldErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		call       func(tu *testUsers, id string) error
+		wantFields []string
+	}{
+		{
+			name: "create",
+			call: func(tu *testUsers, _ string) error {
+				_, err := tu.service.CreateUser(context.Background(), &models.CreateUserRequest{Username: "alice", Email: "nope", Password: "short"})
+				return err
+			},
+			wantFields: []string{"email", "password"},
+		},
+		{
+			name: "update",
+			call: func(tu *testUsers, id string) error {
+				website := "not a url"
+				_, err := tu.service.UpdateUser(context.Background(), id, &models.UpdateUserRequest{Website: &website})
+				return err
+			},
+			wantFields: []string{"website"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tu := newTestUsers(t)
+			user := tu.createUser(t)
+
+			err := tt.call(tu, user.GetIDString())
+			var fieldErrs models.FieldErrors
+			if !errors.As(err, &fieldErrs) {
+				t.Fatalf("error = %v (%T), want models.FieldErrors", err, err)
+			}
+			fields := make([]string, len(fieldErrs))
+			for i, fieldErr := range fieldErrs {
+				fields[i] = fieldErr.Field
+			}
+			if !slices.Equal(fields, tt.wantFields) {
+				t.Errorf("fields = %v, want %v", fields, tt.wantFields)
+			}
+			if exists, _ := tu.repo.ExistsByUsername(context.Background(), "alice"); exists {
+				t.Error("invalid user was stored")
+			}
+			if stored := tu.storedUser(t, user.GetIDString()); stored.Website != user.Website {
+				t.Errorf("website = %q after a failed update, want %q", stored.Website, user.Website)
+			}
+		})
+	}
+}
+
+func TestSetUserActive(t *testing.T) {
+	tests := []struct {
+		name        string
+		active      bool // current status
+		set         bool
+		missing     bool
+		wantErr     error
+		wantRevoked bool
+	}{
+		{name: "deactivate", active: true, set: false, wantRevoked: true},
+		{name: "activate", active: false, set: true},
+		{name: "already active", active: true, set: true, wantErr: interfaces.ErrInvalidState},
+		{name: "already inactive", active: false, set: false, wantErr: interfaces.ErrInvalidState},
+		{name: "missing user", set: false, missing: true, wantErr: interfaces.ErrNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			tu := newTestUsers(t)
+			user := tu.createUser(t, models.WithActive(tt.active))
+			id := user.GetIDString()
+			if tt.missing {
+				id = primitive.NewObjectID().Hex()
+			}
+
+			updated, err := tu.service.SetUserActive(ctx, id, tt.set)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("SetUserActive() error = %v, want %v", err, tt.wantErr)
+				}
+				if stored := tu.storedUser(t, user.GetIDString()); stored.IsActive != tt.active || stored.TokenVersion != user.TokenVersion {
+					t.Errorf("stored active = %v, token version = %d, want unchanged", stored.IsActive, stored.TokenVersion)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SetUserActive() error = %v", err)
+			}
+
+			stored := tu.storedUser(t, id)
+			if updated.IsActive != tt.set || stored.IsActive != tt.set {
+				t.Errorf("active = %v (stored %v), want %v", updated.IsActive, stored.IsActive, tt.set)
+			}
+			if stored.DeletedAt != nil {
+				t.Errorf("deleted_at = %v, want it untouched", stored.DeletedAt)
+			}
+			if revoked := stored.TokenVersion > user.TokenVersion; revoked != tt.wantRevoked {
+				t.Errorf("tokens revoked = %v, want %v", revoked, tt.wantRevoked)
+			}
+			if cached, _ := tu.cache.Get(ctx, fmt.Sprintf(CacheKeyUserTokenVersion, id)); tt.wantRevoked && cached != "" {
+				t.Errorf("cached token version = %q after deactivation, want it invalidated", cached)
+			}
+		})
+	}
+}
+
+func TestSetUserActiveRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	tu := newTestUsers(t)
+	user := tu.createUser(t)
+	id := user.GetIDString()
+
+	for i, active := range []bool{false, true, false, true} {
+		updated, err := tu.service.SetUserActive(ctx, id, active)
+		if err != nil {
+			t.Fatalf("step %d: SetUserActive(%v) error = %v", i, active, err)
+		}
+		if updated.IsActive != active {
+			t.Fatalf("step %d: active = %v, want %v", i, updated.IsActive, active)
+		}
+		// Lookups must not serve the status cached before the change
+		if got, err := tu.service.GetUserByID(ctx, id); err != nil || got.IsActive != active {
+			t.Fatalf("step %d: GetUserByID() active = %v, %v, want %v", i, got.IsActive, err, active)
+		}
+	}
+
+	// Only the two deactivations revoke tokens
+	if got := tu.storedUser(t, id).TokenVersion; got != user.TokenVersion+2 {
+		t.Errorf("token version = %d, want %d", got, user.TokenVersion+2)
+	}
+}
+
+func TestBulkDeleteUsers(t *testing.T) {
+	missing := primitive.NewObjectID().Hex()
+
+	tests := []struct {
+		name        string
+		dryRun      bool
+		ids         func(alive, deleted string) []string
+		wantDeleted int
+		wantMissing int
+		wantErr     error
+	}{
+		{name: "dry run", dryRun: true, ids: func(alive, deleted string) []string { return []string{alive, missing} }, wantDeleted: 1, wantMissing: 1},
+		{name: "delete", ids: func(alive, deleted string) []string { return []string{alive, missing} }, wantDeleted: 1, wantMissing: 1},
+		{name: "already deleted users are not found", ids: func(alive, deleted string) []string { return []string{deleted} }, wantMissing: 1},
+		{name: "invalid id", ids: func(alive, deleted string) []string { return []string{alive, "nope"} }, wantErr: interfaces.ErrValidation},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			tu := newTestUsers(t)
+			alive := tu.createUser(t)
+			deleted := tu.createUser(t)
+			if err := tu.repo.SoftDelete(ctx, deleted.GetIDString()); err != nil {
+				t.Fatalf("SoftDelete() error = %v", err)
+			}
+			// Warm the cache so a stale entry would be noticed
+			if _, err := tu.service.GetUserByID(ctx, alive.GetIDString()); err != nil {
+				t.Fatalf("GetUserByID() error = %v", err)
+			}
+
+			result, err := tu.service.BulkDeleteUsers(ctx, &models.BulkDeleteRequest{
+				IDs:    tt.ids(alive.GetIDString(), deleted.GetIDString()),
+				DryRun: tt.dryRun,
+			})
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("BulkDeleteUsers() error = %v, want %v", err, tt.wantErr)
+				}
+				if tu.storedUser(t, alive.GetIDString()).DeletedAt != nil {
+					t.Error("user was deleted by an invalid request")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("BulkDeleteUsers() error = %v", err)
+			}
+			if result.DryRun != tt.dryRun || len(result.Deleted) != tt.wantDeleted || len(result.NotFound) != tt.wantMissing {
+				t.Fatalf("result = %+v, want dry run %v, %d deleted, %d not found", result, tt.dryRun, tt.wantDeleted, tt.wantMissing)
+			}
+
+			wantGone := tt.wantDeleted > 0 && !tt.dryRun
+			if gone := tu.storedUser(t, alive.GetIDString()).DeletedAt != nil; gone != wantGone {
+				t.Errorf("alive user deleted = %v, want %v", gone, wantGone)
+			}
+			if _, err := tu.service.GetUserByID(ctx, alive.GetIDString()); errors.Is(err, interfaces.ErrNotFound) != wantGone {
+				t.Errorf("GetUserByID() error = %v after bulk delete, want not found = %v", err, wantGone)
+			}
+			wantEvents := 0
+			if wantGone {
+				wantEvents = tt.wantDeleted
+			}
+			if got := tu.events.published(); len(got) != wantEvents {
+				t.Errorf("published events = %v, want %d", got, wantEvents)
+			}
+		})
+	}
+}
+
+func TestGetUsersLimit(t *testing.T) {
+	tests := []struct {
+		name      string
+		limit     int
+		wantErr   bool
+		wantLimit int
+	}{
+		{name: "default", limit: 0, wantLimit: models.DefaultPageLimit},
+		{name: "maximum", limit: models.DefaultMaxPageLimit, wantLimit: models.DefaultMaxPageLimit},
+		{name: "above the maximum", limit: models.DefaultMaxPageLimit + 1, wantErr: true},
+		{name: "negative", limit: -1, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tu := newTestUsers(t)
+			params := &models.UsersQueryParams{Limit: tt.limit}
+
+			_, _, err := tu.service.GetUsers(context.Background(), params)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetUsers() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && params.Limit != tt.wantLimit {
+				t.Errorf("limit = %d, want %d", params.Limit, tt.wantLimit)
+			}
+		})
+	}
+}
+
+// seedSignups stores one user per creation time; users at deleted times are soft deleted
+func seedSignups(t *testing.T, tu *testUsers, created []time.Time, deleted ...time.Time) {
+	t.Helper()
+	for _, createdAt := range append(created, deleted...) {
+		user := models.NewTestUser()
+		user.CreatedAt = createdAt
+		if err := tu.repo.Create(context.Background(), user); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+		if slices.Contains(deleted, createdAt) {
+			if err := tu.repo.SoftDelete(context.Background(), user.GetIDString()); err != nil {
+				t.Fatalf("SoftDelete() error = %v", err)
+			}
+		}
+	}
+}
+
+func TestGetSignupsByDay(t *testing.T) {
+	day := func(d, hour int) time.Time { return time.Date(2026, 3, d, hour, 0, 0, 0, time.UTC) }
+
+	tu := newTestUsers(t)
+	seedSignups(t, tu,
+		[]time.Time{day(1, 0), day(1, 23), day(3, 12), day(5, 8), day(6, 0), day(28, 9)},
+		day(3, 13), // deleted users are not counted
+	)
+
+	tests := []struct {
+		name     string
+		from, to time.Time
+		want     []models.DayCount
+	}{
+		{
+			name: "gaps are filled",
+			from: day(1, 0),
+			to:   day(6, 0),
+			want: []models.DayCount{
+				{Date: "2026-03-01", Count: 2},
+				{Date: "2026-03-02", Count: 0},
+				{Date: "2026-03-03", Count: 1},
+				{Date: "2026-03-04", Count: 0},
+				{Date: "2026-03-05", Count: 1},
+			},
+		},
+		{
+			name: "no signups in range",
+			from: day(10, 0),
+			to:   day(13, 0),
+			want: []models.DayCount{{Date: "2026-03-10"}, {Date: "2026-03-11"}, {Date: "2026-03-12"}},
+		},
+		{
+			name: "single day",
+			from: day(28, 0),
+			to:   day(29, 0),
+			want: []models.DayCount{{Date: "2026-03-28", Count: 1}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tu.service.GetSignupsByDay(context.Background(), tt.from, tt.to)
+			if err != nil {
+				t.Fatalf("GetSignupsByDay() error = %v", err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("GetSignupsByDay() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUserPreferences(t *testing.T) {
+	yes, no := true, false
+	tests := []struct {
+		name        string
+		stored      map[string]interface{} // preferences before the update
+		req         *models.UpdatePreferencesRequest
+		missing     bool
+		want        models.NotificationPreferences
+		wantInvalid bool
+		wantErr     error
+	}{
+		{
+			name: "defaults without an update",
+			want: models.DefaultNotificationPreferences(),
+		},
+		{
+			name: "partial update keeps the other settings",
+			req:  &models.UpdatePreferencesRequest{Notifications: &models.UpdateNotificationPreferencesRequest{Marketing: &yes}},
+			want: models.NotificationPreferences{Email: true, Push: true, Marketing: true},
+		},
+		{
+			name:   "update merges with stored settings and unrelated groups",
+			stored: map[string]interface{}{"theme": "dark", "notifications": map[string]interface{}{"push": false}},
+			req:    &models.UpdatePreferencesRequest{Notifications: &models.UpdateNotificationPreferencesRequest{Email: &no}},
+			want:   models.NotificationPreferences{Email: false, Push: false, Marketing: false},
+		},
+		{
+			name:        "empty update",
+			req:         &models.UpdatePreferencesRequest{},
+			want:        models.DefaultNotificationPreferences(),
+			wantInvalid: true,
+		},
+		{
+			name:    "missing user",
+			missing: true,
+			wantErr: interfaces.ErrNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			tu := newTestUsers(t)
+			user := tu.createUser(t)
+			id := user.GetIDString()
+			if tt.stored != nil {
+				if err := tu.repo.Update(ctx, id, map[string]interface{}{"preferences": tt.stored}); err != nil {
+					t.Fatalf("Update() error = %v", err)
+				}
+			}
+			if tt.missing {
+				id = primitive.NewObjectID().Hex()
+			}
+
+			if tt.req != nil {
+				_, err := tu.service.UpdateUserPreferences(ctx, id, tt.req)
+				var fieldErrs models.FieldErrors
+				if invalid := errors.As(err, &fieldErrs); invalid != tt.wantInvalid || (err != nil && !invalid) {
+					t.Fatalf("UpdateUserPreferences() error = %v, want field errors %v", err, tt.wantInvalid)
+				}
+			}
+
+			got, err := tu.service.GetUserPreferences(ctx, id)
+			if tt.missing {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("GetUserPreferences() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetUserPreferences() error = %v", err)
+			}
+			if got.Notifications != tt.want {
+				t.Errorf("notifications = %+v, want %+v", got.Notifications, tt.want)
+			}
+			if theme, ok := tt.stored["theme"]; ok {
+				if stored := tu.storedUser(t, id); stored.Preferences["theme"] != theme {
+					t.Errorf("theme = %v, want the unrelated preference kept", stored.Preferences["theme"])
+				}
+			}
+		})
+	}
+}
+
+func TestCreateUserIdentityConflicts(t *testing.T) {
+	tests := []struct {
+		name     string
+		username string
+		email    string
+		wantErr  string
+	}{
+		{name: "neither taken", username: "dave", email: "dave@example.com"},
+		{name: "username taken", username: "Alice", email: "dave@example.com", wantErr: "username 'Alice' already exists"},
+		{name: "email taken", username: "dave", email: "ALICE@example.com", wantErr: "email 'ALICE@example.com' already exists"},
+		{name: "both taken reports the username", username: "alice", email: "bob@example.com", wantErr: "username 'alice' already exists"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			tu := newTestUsers(t)
+			tu.createUser(t, models.WithUsername("alice"), models.WithEmail("alice@example.com"))
+			tu.createUser(t, models.WithUsername("bob"), models.WithEmail("bob@example.com"))
+
+			req := models.CreateUserRequest{Username: tt.username, Email: tt.email, Password: "SecurePass123"}
+			user, err := tu.service.CreateUser(ctx, &req)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("CreateUser() error = %v", err)
+				}
+				if user.Username != tt.username {
+					t.Errorf("username = %q, want %q", user.Username, tt.username)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.wantErr {
+				t.Fatalf("CreateUser() error = %v, want %q", err, tt.wantErr)
+			}
+			if exists, _ := tu.repo.ExistsByUsername(ctx, "dave"); exists {
+				t.Error("user dave was created despite the conflict")
+			}
+		})
+	}
+}
+
+func TestWithJitter(t *testing.T) {
+	tests := []struct {
+		name   string
+		jitter float64
+		base   time.Duration
+	}{
+		{name: "disabled", jitter: 0, base: UserCacheExpiration},
+		{name: "ten percent", jitter: 0.1, base: UserCacheExpiration},
+		{name: "half", jitter: 0.5, base: UserStatsCacheExpiration},
+		{name: "zero base", jitter: 0.1, base: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := &UserService{cacheJitter: tt.jitter}
+			spread := time.Duration(float64(tt.base) * tt.jitter)
+			low, high := tt.base-spread, tt.base+spread
+
+			seen := make(map[time.Duration]bool)
+			for i := 0; i < 10000; i++ {
+				got := service.withJitter(tt.base)
+				if got < low || got > high {
+					t.Fatalf("withJitter(%v) = %v, want within [%v, %v]", tt.base, got, low, high)
+				}
+				seen[got] = true
+			}
+
+			// Without a spread every TTL is the base; with one they must actually vary
+			if spread == 0 && len(seen) != 1 {
+				t.Errorf("withJitter() produced %d distinct TTLs, want only the base", len(seen))
+			}
+			if spread > 0 && len(seen) < 100 {
+				t.Errorf("withJitter() produced %d distinct TTLs, want them spread across the band", len(seen))
+			}
+		})
+	}
+}
+
+func TestCacheUserTTLWithinJitterBand(t *testing.T) {
+	ctx := context.Background()
+	tu := newTestUsers(t)
+	tu.service.cacheJitter = 0.1
+	user := tu.createUser(t)
+
+	tu.service.cacheUser(ctx, user)
+
+	ttl, err := tu.cache.TTL(ctx, fmt.Sprintf(CacheKeyUser, user.GetIDString()))
+	if err != nil {
+		t.Fatalf("TTL() error = %v", err)
+	}
+	spread := UserCacheExpiration / 10
+	if ttl <= 0 || ttl > UserCacheExpiration+spread || ttl < UserCacheExpiration-spread-time.Second {
+		t.Errorf("TTL = %v, want within 10%% of %v", ttl, UserCacheExpiration)
+	}
+}
+
+func TestDeleteUserBySelector(t *testing.T) {
+	tests := []struct {
+		name    string
+		delete  func(s *UserService, ctx context.Context) error
+		wantErr error
+	}{
+		{name: "by email", delete: func(s *UserService, ctx context.Context) error { return s.DeleteUserByEmail(ctx, "alice@example.com") }},
+		{name: "by email ignoring case", delete: func(s *UserService, ctx context.Context) error { return s.DeleteUserByEmail(ctx, "Alice@Example.com") }},
+		{name: "by username", delete: func(s *UserService, ctx context.Context) error { return s.DeleteUserByUsername(ctx, "alice") }},
+		{name: "unknown email", delete: func(s *UserService, ctx context.Context) error { return s.DeleteUserByEmail(ctx, "dave@example.com") }, wantErr: interfaces.ErrNotFound},
+		{name: "unknown username", delete: func(s *UserService, ctx context.Context) error { return s.DeleteUserByUsername(ctx, "dave") }, wantErr: interfaces.ErrNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			tu := newTestUsers(t)
+			alice := tu.createUser(t, models.WithUsername("alice"), models.WithEmail("alice@example.com"))
+			bob := tu.createUser(t, models.WithUsername("bob"), models.WithEmail("bob@example.com"))
+
+			// Warm the caches the deletion has to invalidate
+			for _, lookup := range []func() (*models.User, error){
+				func() (*models.User, error) { return tu.service.GetUserByID(ctx, alice.GetIDString()) },
+				func() (*models.User, error) { return tu.service.GetUserByEmail(ctx, alice.Email) },
+				func() (*models.User, error) { return tu.service.GetUserByUsername(ctx, alice.Username) },
+			} {
+				if _, err := lookup(); err != nil {
+					t.Fatalf("lookup error = %v", err)
+				}
+			}
+
+			err := tt.delete(tu.service, ctx)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("delete error = %v, want %v", err, tt.wantErr)
+				}
+				if stored := tu.storedUser(t, alice.GetIDString()); stored.DeletedAt != nil {
+					t.Error("alice was deleted by a lookup that matched nobody")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("delete error = %v", err)
+			}
+
+			if stored := tu.storedUser(t, alice.GetIDString()); stored.DeletedAt == nil {
+				t.Error("alice was not soft-deleted")
+			}
+			if stored := tu.storedUser(t, bob.GetIDString()); stored.DeletedAt != nil {
+				t.Error("bob was deleted too")
+			}
+			if _, err := tu.service.GetUserByEmail(ctx, alice.Email); !errors.Is(err, interfaces.ErrNotFound) {
+				t.Errorf("GetUserByEmail() after deletion error = %v, want not found", err)
+			}
+			if _, err := tu.service.GetUserByUsername(ctx, alice.Username); !errors.Is(err, interfaces.ErrNotFound) {
+				t.Errorf("GetUserByUsername() after deletion error = %v, want not found", err)
+			}
+			if _, err := tu.service.GetUserByID(ctx, alice.GetIDString()); !errors.Is(err, interfaces.ErrNotFound) {
+				t.Errorf("GetUserByID() after deletion error = %v, want not found", err)
+			}
+		})
+	}
+}
+
+func TestUpdateUserEmailResetsVerification(t *testing.T) {
+	tests := []struct {
+		name         string
+		verified     bool
+		email        string
+		firstName    string
+		wantVerified bool
+		wantMailTo   string
+	}{
+		{
+			name:       "verified user changes email",
+			verified:   true,
+			email:      "new@example.com",
+			wantMailTo: "new@example.com",
+		},
+		{
+			name:       "unverified user changes email",
+			email:      "new@example.com",
+			wantMailTo: "new@example.com",
+		},
+		{
+			name:         "same email in another case",
+			verified:     true,
+			email:        "JohnDoe@Example.com",
+			wantVerified: true,
+		},
+		{
+			name:         "other fields only",
+			verified:     true,
+			firstName:    "Jane",
+			wantVerified: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tu := newTestUsers(t)
+			ctx := context.Background()
+			user := tu.createUser(t, models.WithEmail("johndoe@example.com"), models.WithVerified(tt.verified))
+			id := user.GetIDString()
+			// Warm the cache so a stale verified copy would be noticed
+			if _, err := tu.service.GetUserByID(ctx, id); err != nil {
+				t.Fatalf("GetUserByID() error = %v", err)
+			}
+
+			req := &models.UpdateUserRequest{}
+			if tt.email != "" {
+				req.Email = &tt.email
+			}
+			if tt.firstName != "" {
+				req.FirstName = &tt.firstName
+			}
+			updated, err := tu.service.UpdateUser(ctx, id, req)
+			if err != nil {
+				t.Fatalf("UpdateUser() error = %v", err)
+			}
+			if updated.IsVerified != tt.wantVerified {
+				t.Errorf("returned IsVerified = %v, want %v", updated.IsVerified, tt.wantVerified)
+			}
+
+			stored := tu.storedUser(t, id)
+			if stored.IsVerified != tt.wantVerified {
+				t.Errorf("stored IsVerified = %v, want %v", stored.IsVerified, tt.wantVerified)
+			}
+			if tt.wantVerified != (stored.EmailVerifiedAt != nil) {
+				t.Errorf("stored EmailVerifiedAt = %v, want it set only while verified", stored.EmailVerifiedAt)
+			}
+			cached, err := tu.service.GetUserByID(ctx, id)
+			if err != nil {
+				t.Fatalf("GetUserByID() error = %v", err)
+			}
+			if cached.IsVerified != tt.wantVerified {
+				t.Errorf("GetUserByID() IsVerified = %v, want %v", cached.IsVerified, tt.wantVerified)
+			}
+
+			sent := tu.mailer.Sent()
+			if tt.wantMailTo == "" {
+				if len(sent) != 0 {
+					t.Errorf("sent %d emails, want none", len(sent))
+				}
+				return
+			}
+			if len(sent) != 1 || sent[0].To != tt.wantMailTo {
+				t.Fatalf("sent %v, want one email to %q", sent, tt.wantMailTo)
+			}
+			userID, err := utils.NewEmailVerificationTokens(tu.cache).Consume(ctx, tu.mailedToken(t))
+			if err != nil || userID != id {
+				t.Errorf("Consume(mailed token) = %q, %v, want %q", userID, err, id)
+			}
+		})
+	}
+}
+
+func TestCacheMetrics(t *testing.T) {
+	tests := []struct {
+		name string
+		// call runs one lookup through the service; it is made twice
+		call func(ctx context.Context, s *UserService, user *models.User) error
+		// corrupt is the cache key to fill with invalid JSON before the calls
+		corrupt func(user *models.User) string
+		want    map[string]int
+	}{
+		{
+			name: "user by id",
+			call: func(ctx context.Context, s *UserService, user *models.User) error {
+				_, err := s.GetUserByID(ctx, user.GetIDString())
+				return err
+			},
+			want: map[string]int{"user/miss": 1, "user/hit": 1},
+		},
+		{
+			name: "user list",
+			call: func(ctx context.Context, s *UserService, user *models.User) error {
+				_, _, err := s.GetUsers(ctx, &models.UsersQueryParams{Page: 1, Limit: 10})
+				return err
+			},
+			want: map[string]int{"list/miss": 1, "list/hit": 1},
+		},
+		{
+			name: "stats",
+			call: func(ctx context.Context, s *UserService, user *models.User) error {
+				_, err := s.GetUserStats(ctx, &models.UserStatsParams{})
+				return err
+			},
+			want: map[string]int{"stats/miss": 1, "stats/hit": 1},
+		},
+		{
+			name: "existence",
+			call: func(ctx context.Context, s *UserService, user *models.User) error {
+				_, err := s.checkUserExists(ctx, "email", user.Email)
+				return err
+			},
+			want: map[string]int{"exists/miss": 1, "exists/hit": 1},
+		},
+		{
+			name: "corrupted user entry",
+			call: func(ctx context.Context, s *UserService, user *models.User) error {
+				_, err := s.GetUserByID(ctx, user.GetIDString())
+				return err
+			},
+			corrupt: func(user *models.User) string { return fmt.Sprintf(CacheKeyUser, user.GetIDString()) },
+			// The corrupted entry is replaced by the database read, so the second call hits
+			want: map[string]int{"user/error": 1, "user/hit": 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			tu := newTestUsers(t)
+			user := tu.createUser(t)
+			if tt.corrupt != nil {
+				if err := tu.cache.Set(ctx, tt.corrupt(user), "{", time.Minute); err != nil {
+					t.Fatalf("Set() error = %v", err)
+				}
+			}
+
+			for i := 0; i < 2; i++ {
+				if err := tt.call(ctx, tu.service, user); err != nil {
+					t.Fatalf("call %d error = %v", i+1, err)
+				}
+			}
+			if got := tu.metrics.snapshot(); !maps.Equal(got, tt.want) {
+				t.Errorf("cache lookups = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCacheMetricsCountErrors(t *testing.T) {
+	logger := logtest.New()
+	cache := failingCache{MemoryCache: database.NewMemoryCache()}
+	t.Cleanup(func() { cache.Close() })
+	repo := repositories.NewMemoryUserRepository()
+	spy := &spyCacheMetrics{}
+	service := NewUserService(repo, cache, database.NewInvalidator(cache, logger), nil,
+		mail.NewMemoryMailer(), &recordingPublisher{}, spy, 0, logger)
+
+	ctx := context.Background()
+	user := models.NewTestUser()
+	if err := repo.Create(ctx, user); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	if _, err := service.GetUserByID(ctx, user.GetIDString()); err != nil {
+		t.Fatalf("GetUserByID() error = %v", err)
+	}
+	if _, _, err := service.GetUsers(ctx, &models.UsersQueryParams{Page: 1, Limit: 10}); err != nil {
+		t.Fatalf("GetUsers() error = %v", err)
+	}
+	if _, err := service.checkUserExists(ctx, "username", user.Username); err != nil {
+		t.Fatalf("checkUserExists() error = %v", err)
+	}
+
+	want := map[string]int{"user/error": 1, "list/error": 1, "exists/error": 1}
+	if got := spy.snapshot(); !maps.Equal(got, want) {
+		t.Errorf("cache lookups = %v, want %v", got, want)
+	}
+}
+
+func TestLogoutAll(t *testing.T) {
+	tests := []struct {
+		name    string
+		deleted bool
+		missing bool
+		wantErr error
+	}{
+		{name: "bumps the token version"},
+		{name: "deleted user", deleted: true, wantErr: interfaces.ErrNotFound},
+		{name: "unknown user", missing: true, wantErr: interfaces.ErrNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			tu := newTestUsers(t)
+			user := tu.createUser(t)
+			id := user.GetIDString()
+			if tt.deleted {
+				if err := tu.repo.SoftDelete(ctx, id); err != nil {
+					t.Fatalf("SoftDelete() error = %v", err)
+				}
+			}
+			if tt.missing {
+				id = primitive.NewObjectID().Hex()
+			}
+
+			// Cache the version and the user, as authenticated requests and lookups would
+			versionKey := fmt.Sprintf(CacheKeyUserTokenVersion, id)
+			if _, err := NewTokenVersions(tu.repo, tu.cache, tu.logger).TokenVersion(ctx, id); err != nil && tt.wantErr == nil {
+				t.Fatalf("TokenVersion() error = %v", err)
+			}
+
+			err := tu.service.LogoutAll(ctx, id)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("LogoutAll() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				if stored := tu.storedUser(t, user.GetIDString()); stored.TokenVersion != user.TokenVersion {
+					t.Errorf("token version = %d, want unchanged", stored.TokenVersion)
+				}
+				return
+			}
+
+			if stored := tu.storedUser(t, id); stored.TokenVersion != user.TokenVersion+1 {
+				t.Errorf("token version = %d, want %d", stored.TokenVersion, user.TokenVersion+1)
+			}
+			if cached, err := tu.cache.Get(ctx, versionKey); !errors.Is(err, interfaces.ErrCacheMiss) {
+				t.Errorf("cached version = %q, %v, want it dropped", cached, err)
+			}
+		})
+	}
+}
+
+func TestCountUsers(t *testing.T) {
+	yes, no := true, false
+
+	tests := []struct {
+		name   string
+		params *models.UserCountParams
+		want   int
+	}{
+		{name: "every live user", params: &models.UserCountParams{}, want: 3},
+		{name: "nil params", want: 3},
+		{name: "active", params: &models.UserCountParams{IsActive: &yes}, want: 2},
+		{name: "inactive", params: &models.UserCountParams{IsActive: &no}, want: 1},
+		{name: "role", params: &models.UserCountParams{Role: models.RoleAdmin}, want: 1},
+		{name: "role every user holds", params: &models.UserCountParams{Role: models.RoleUser}, want: 3},
+		{name: "role nobody holds", params: &models.UserCountParams{Role: models.RoleMod}, want: 0},
+		{name: "active and role", params: &models.UserCountParams{IsActive: &no, Role: models.RoleAdmin}, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tu := newTestUsers(t)
+			tu.createUser(t)
+			tu.createUser(t, models.WithActive(false))
+			tu.createUser(t, models.WithRoles(models.RoleUser, models.RoleAdmin))
+			deleted := tu.createUser(t, models.WithRoles(models.RoleUser, models.RoleMod))
+			if err := tu.repo.SoftDelete(context.Background(), deleted.GetIDString()); err != nil {
+				t.Fatalf("SoftDelete() error = %v", err)
+			}
+
+			got, err := tu.service.CountUsers(context.Background(), tt.params)
+			if err != nil {
+				t.Fatalf("CountUsers() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("CountUsers() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCountUsersCachesTheTotalOnly(t *testing.T) {
+	ctx := context.Background()
+	yes := true
+	tu := newTestUsers(t)
+	first := tu.createUser(t)
+	tu.createUser(t)
+
+	count := func(params *models.UserCountParams) int {
+		t.Helper()
+		got, err := tu.service.CountUsers(ctx, params)
+		if err != nil {
+			t.Fatalf("CountUsers() error = %v", err)
+		}
+		return got
+	}
+
+	if got := count(&models.UserCountParams{}); got != 2 {
+		t.Fatalf("CountUsers() = %d, want 2", got)
+	}
+
+	// Written behind the service's back, so only uncached counts see the new user
+	tu.createUser(t)
+	if got := count(&models.UserCountParams{}); got != 2 {
+		t.Errorf("CountUsers() = %d, want the cached 2", got)
+	}
+	if got := count(&models.UserCountParams{IsActive: &yes}); got != 3 {
+		t.Errorf("CountUsers(active) = %d, want 3 from the database", got)
+	}
+
+	// Deleting through the service drops the cached total with the stats
+	if err := tu.service.DeleteUser(ctx, first.GetIDString()); err != nil {
+		t.Fatalf("DeleteUser() error = %v", err)
+	}
+	if got := count(&models.UserCountParams{}); got != 2 {
+		t.Errorf("CountUsers() after a delete = %d, want 2", got)
+	}
+
+	// The filtered count never touches the cache
+	if got := tu.metrics.snapshot(); got["stats/miss"] != 2 || got["stats/hit"] != 1 {
+		t.Errorf("cache metrics = %v, want 2 stats misses and 1 hit", got)
+	}
+}
+
+func TestRecordProfileView(t *testing.T) {
+	tests := []struct {
+		name    string
+		deleted bool
+		missing bool
+		wantErr error
+	}{
+		{name: "counts the view"},
+		{name: "deleted user", deleted: true, wantErr: interfaces.ErrNotFound},
+		{name: "unknown user", missing: true, wantErr: interfaces.ErrNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			tu := newTestUsers(t)
+			user := tu.createUser(t)
+			id := user.GetIDString()
+			if tt.deleted {
+				if err := tu.repo.SoftDelete(ctx, id); err != nil {
+					t.Fatalf("SoftDelete() error = %v", err)
+				}
+			}
+			if tt.missing {
+				id = primitive.NewObjectID().Hex()
+			}
+			var before *models.User
+			if tt.wantErr == nil {
+				before = tu.storedUser(t, id)
+				// Cache the user, as a lookup would
+				if _, err := tu.service.GetUserByID(ctx, id); err != nil {
+					t.Fatalf("GetUserByID() error = %v", err)
+				}
+			}
+
+			for range 2 {
+				if err := tu.service.RecordProfileView(ctx, id); !errors.Is(err, tt.wantErr) {
+					t.Fatalf("RecordProfileView() error = %v, want %v", err, tt.wantErr)
+				}
+			}
+			if tt.wantErr != nil {
+				return
+			}
+
+			stored := tu.storedUser(t, id)
+			if stored.ProfileViews != 2 {
+				t.Errorf("profile_views = %d, want 2", stored.ProfileViews)
+			}
+			// Views are bookkeeping, so they neither bump the version nor the update time
+			if stored.Version != before.Version || !stored.UpdatedAt.Equal(before.UpdatedAt) {
+				t.Errorf("version = %d, updated_at = %v, want them unchanged", stored.Version, stored.UpdatedAt)
+			}
+			got, err := tu.service.GetUserByID(ctx, id)
+			if err != nil {
+				t.Fatalf("GetUserByID() error = %v", err)
+			}
+			if got.ProfileViews != 2 {
+				t.Errorf("GetUserByID() profile_views = %d, want the cached user dropped", got.ProfileViews)
+			}
+		})
+	}
+}
+
+func TestCreateUserUnicodeUsernames(t *testing.T) {
+	tests := []struct {
+		name         string
+		allowUnicode bool
+		username     string
+		wantErr      bool
+		wantStored   string
+	}{
+		{name: "ascii only by default", username: "Jos\u00e9", wantErr: true},
+		{name: "ascii username in unicode mode", allowUnicode: true, username: "John_Doe", wantStored: "john_doe"},
+		{name: "precomposed accent", allowUnicode: true, username: "Jos\u00e9", wantStored: "jos\u00e9"},
+		{name: "decomposed accent is stored precomposed", allowUnicode: true, username: "Jose\u0301", wantStored: "jos\u00e9"},
+		{name: "whitespace is still refused", allowUnicode: true, username: "jos\u00e9 m", wantErr: true},
+		{name: "control characters are still refused", allowUnicode: true, username: "jos\u00e9\u0000", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			models.SetAllowUnicodeUsernames(tt.allowUnicode)
+			t.Cleanup(func() { models.SetAllowUnicodeUsernames(false) })
+			ctx := context.Background()
+			tu := newTestUsers(t)
+
+			user, err := tu.service.CreateUser(ctx, &models.CreateUserRequest{Username: tt.username, Email: "jose@example.com", Password: models.TestUserPassword})
+			if tt.wantErr {
+				var fieldErrs models.FieldErrors
+				if !errors.As(err, &fieldErrs) || len(fieldErrs) != 1 || fieldErrs[0].Field != "username" {
+					t.Fatalf("CreateUser() error = %v, want a username field error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CreateUser() error = %v", err)
+			}
+			if stored := tu.storedUser(t, user.GetIDString()); stored.Username != tt.wantStored {
+				t.Errorf("stored username = %q, want %q", stored.Username, tt.wantStored)
+			}
+
+			// Any spelling of the same username finds it and conflicts with it
+			spellings := []string{tt.wantStored, strings.ToUpper(tt.wantStored), norm.NFD.String(tt.wantStored)}
+			for i, spelling := range spellings {
+				found, err := tu.service.GetUserByUsername(ctx, spelling)
+				if err != nil || found.GetIDString() != user.GetIDString() {
+					t.Errorf("GetUserByUsername(%q) = %v, %v, want the created user", spelling, found, err)
+				}
+				_, err = tu.service.CreateUser(ctx, &models.CreateUserRequest{Username: spelling, Email: fmt.Sprintf("other%d@example.com", i), Password: models.TestUserPassword})
+				if !errors.Is(err, interfaces.ErrAlreadyExists) {
+					t.Errorf("CreateUser(%q) error = %v, want ErrAlreadyExists", spelling, err)
+				}
+			}
+		})
+	}
+}
+
+// spyCache is a cache that records the keys written to it
+type spyCache struct {
+	*database.MemoryCache
+	mu  sync.Mutex
+	set []string
+}
+
+func (c *spyCache) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error {
+	c.mu.Lock()
+	c.set = append(c.set, key)
+	c.mu.Unlock()
+	return c.MemoryCache.Set(ctx, key, value, expiration)
+}
+
+// keys returns the keys written so far, in order
+func (c *spyCache) keys() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string(nil), c.set...)
+}
+
+func TestWarmCache(t *testing.T) {
+	tests := []struct {
+		name      string
+		count     int
+		cancelled bool
+		wantUsers []int // indexes of the seeded users, most recent login first
+	}{
+		{name: "fewer than available", count: 2, wantUsers: []int{2, 0}},
+		{name: "more than available", count: 10, wantUsers: []int{2, 0, 1}},
+		{name: "repository fails", count: 10, cancelled: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tu := newTestUsers(t)
+			cache := &spyCache{MemoryCache: tu.cache}
+			service := NewUserService(tu.repo, cache, database.NewInvalidator(cache, tu.logger), tu.files,
+				tu.mailer, tu.events, tu.metrics, 0, tu.logger)
+
+			// Three users who logged in at different times, one who never did and a deleted one
+			loggedIn := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+			var users []*models.User
+			for _, ago := range []time.Duration{time.Hour, 2 * time.Hour, 0} {
+				user := models.NewTestUser()
+				at := loggedIn.Add(-ago)
+				user.LastLoginAt = &at
+				if err := tu.repo.Create(context.Background(), user); err != nil {
+					t.Fatalf("Create() error = %v", err)
+				}
+				users = append(users, user)
+			}
+			tu.createUser(t)
+			deleted := models.NewTestUser()
+			deleted.LastLoginAt = &loggedIn
+			if err := tu.repo.Create(context.Background(), deleted); err != nil {
+				t.Fatalf("Create() error = %v", err)
+			}
+			if err := tu.repo.SoftDelete(context.Background(), deleted.GetIDString()); err != nil {
+				t.Fatalf("SoftDelete() error = %v", err)
+			}
+
+			ctx := context.Background()
+			if tt.cancelled {
+				cancelled, cancel := context.WithCancel(ctx)
+				cancel()
+				ctx = cancelled
+			}
+			service.WarmCache(ctx, tt.count)
+
+			// Exactly the returned users are cached, under each of their keys
+			var want []string
+			for _, i := range tt.wantUsers {
+				want = append(want,
+					fmt.Sprintf(CacheKeyUser, users[i].GetIDString()),
+					fmt.Sprintf(CacheKeyUserByEmail, users[i].Email),
+					fmt.Sprintf(CacheKeyUserUsername, users[i].Username),
+				)
+			}
+			if got := cache.keys(); !slices.Equal(got, want) {
+				t.Errorf("cached keys = %v, want %v", got, want)
+			}
+			if tt.cancelled {
+				if !tu.logger.Has(slog.LevelWarn, "Failed to load users for cache warming") {
+					t.Error("failed warming was not logged")
+				}
+				return
+			}
+
+			// Lookups of warmed users are then served from the cache
+			for _, i := range tt.wantUsers {
+				if _, err := service.GetUserByID(context.Background(), users[i].GetIDString()); err != nil {
+					t.Fatalf("GetUserByID() error = %v", err)
+				}
+			}
+			if got := tu.metrics.snapshot()["user/hit"]; got != len(tt.wantUsers) {
+				t.Errorf("cache hits = %d, want %d", got, len(tt.wantUsers))
+			}
+		})
+	}
+}
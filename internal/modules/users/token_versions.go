@@ -0,0 +1,53 @@
+// internal/modules/users/token_versions.go
+package users
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"go-template/internal/interfaces"
+	"go-template/internal/repositories"
+)
+
+// TokenVersions looks up users' current token versions for middleware.RequireAuth
+// The version is checked on every authenticated request, so it is cached on its own key,
+// which UserService drops whenever it bumps the version.
+type TokenVersions struct {
+	repo   repositories.UserRepositoryInterface
+	cache  interfaces.CacheInterface
+	logger interfaces.LoggerInterface
+}
+
+// NewTokenVersions creates a new TokenVersions instance
+func NewTokenVersions(repo repositories.UserRepositoryInterface, cache interfaces.CacheInterface, logger interfaces.LoggerInterface) *TokenVersions {
+	return &TokenVersions{
+		repo:   repo,
+		cache:  cache,
+		logger: logger,
+	}
+}
+
+// TokenVersion returns the user's current token version; tokens with an older one are revoked
+func (v *TokenVersions) TokenVersion(ctx context.Context, userID string) (int64, error) {
+	cacheKey := fmt.Sprintf(CacheKeyUserTokenVersion, userID)
+	cached, err := v.cache.Get(ctx, cacheKey)
+	if err == nil {
+		if version, parseErr := strconv.ParseInt(cached, 10, 64); parseErr == nil {
+			return version, nil
+		}
+	} else if !errors.Is(err, interfaces.ErrCacheMiss) {
+		v.logger.Warn("Cache read failed, falling back to database", "cache_key", cacheKey, "error", err.Error())
+	}
+
+	user, err := v.repo.GetByID(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := v.cache.Set(ctx, cacheKey, strconv.FormatInt(user.TokenVersion, 10), UserCacheExpiration); err != nil {
+		v.logger.Warn("Failed to cache token version", "cache_key", cacheKey, "error", err.Error())
+	}
+	return user.TokenVersion, nil
+}
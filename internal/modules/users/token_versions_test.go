@@ -0,0 +1,90 @@
+// internal/modules/users/token_versions_test.go
+package users
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"testing"
+	"time"
+
+	"go-template/internal/database"
+	"go-template/internal/interfaces"
+	"go-template/internal/models"
+	"go-template/internal/repositories"
+	"go-template/internal/shared/logtest"
+)
+
+func TestTokenVersions(t *testing.T) {
+	tests := []struct {
+		name        string
+		cached      string // value stored under the token version key before the lookup
+		failing     bool   // the cache is down
+		missing     bool
+		want        int64
+		wantErr     error
+		wantCached  string
+		wantWarning bool
+	}{
+		{name: "cache miss reads and caches the stored version", want: 2, wantCached: "2"},
+		{name: "cached version", cached: "7", want: 7, wantCached: "7"},
+		{name: "corrupted cache entry is replaced", cached: "not-a-number", want: 2, wantCached: "2"},
+		{name: "cache down falls back to the database", failing: true, want: 2, wantWarning: true},
+		{name: "unknown user", missing: true, wantErr: interfaces.ErrNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			logger := logtest.New()
+			memory := database.NewMemoryCache()
+			t.Cleanup(func() { memory.Close() })
+			var cache interfaces.CacheInterface = memory
+			if tt.failing {
+				cache = failingCache{MemoryCache: memory}
+			}
+
+			repo := repositories.NewMemoryUserRepository()
+			user := models.NewTestUser()
+			if err := repo.Create(ctx, user); err != nil {
+				t.Fatalf("Create() error = %v", err)
+			}
+			id := user.GetIDString()
+			for i := 0; i < 2; i++ {
+				if err := repo.IncrementTokenVersion(ctx, id); err != nil {
+					t.Fatalf("IncrementTokenVersion() error = %v", err)
+				}
+			}
+			if tt.missing {
+				id = "507f1f77bcf86cd799439011"
+			}
+			key := fmt.Sprintf(CacheKeyUserTokenVersion, id)
+			if tt.cached != "" {
+				if err := memory.Set(ctx, key, tt.cached, time.Minute); err != nil {
+					t.Fatalf("Set() error = %v", err)
+				}
+			}
+
+			got, err := NewTokenVersions(repo, cache, logger).TokenVersion(ctx, id)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("TokenVersion() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("TokenVersion() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("TokenVersion() = %d, want %d", got, tt.want)
+			}
+			if cached, _ := memory.Get(ctx, key); cached != tt.wantCached {
+				t.Errorf("cached version = %q, want %q", cached, tt.wantCached)
+			}
+			if got := logger.Has(slog.LevelWarn, "Cache read failed, falling back to database"); got != tt.wantWarning {
+				t.Errorf("cache read failure logged = %v, want %v", got, tt.wantWarning)
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+// internal/modules/users/tracing_test.go
+package users
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"go-template/internal/database"
+	"go-template/internal/repositories"
+	"go-template/internal/shared/logtest"
+	"go-template/internal/shared/mail"
+	"go-template/internal/shared/metrics"
+	"go-template/internal/shared/middleware"
+	"go-template/internal/shared/storage"
+)
+
+func TestCreateUserTracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	mt.Run("create user", func(mt *mtest.T) {
+		// Service existence check, then the repository's username and email checks, then the insert
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "test.users", mtest.FirstBatch),
+			mtest.CreateCursorResponse(0, "test.users", mtest.FirstBatch),
+			mtest.CreateCursorResponse(0, "test.users", mtest.FirstBatch),
+			mtest.CreateSuccessResponse(),
+		)
+
+		logger := logtest.New()
+		cache := database.NewMemoryCache()
+		defer cache.Close()
+		files, err := storage.NewLocalStorage(mt.TempDir(), "/uploads/avatars")
+		if err != nil {
+			mt.Fatalf("NewLocalStorage() error = %v", err)
+		}
+		repo := repositories.NewUserRepository(mt.DB, logger)
+		service := NewUserService(repo, cache, database.NewInvalidator(cache, logger), files,
+			mail.NewLogMailer(logger), &recordingPublisher{}, metrics.NopCacheMetrics{}, 0, logger)
+		handler := NewUserHandler(service, 5*time.Minute, time.Minute, logger)
+
+		mux := http.NewServeMux()
+		mux.HandleFunc("POST /api/v1/users", handler.CreateUser)
+		r := httptest.NewRequest(http.MethodPost, "/api/v1/users",
+			strings.NewReader(`{"username":"alice","email":"alice@example.com","password":"SecurePass123"}`))
+		r.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		middleware.Tracing(mux).ServeHTTP(rec, r)
+		if rec.Code != http.StatusCreated {
+			mt.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusCreated, rec.Body.String())
+		}
+	})
+
+	spans := map[string]sdktrace.ReadOnlySpan{}
+	for _, span := range recorder.Ended() {
+		spans[span.Name()] = span
+	}
+
+	server, ok := spans["POST /api/v1/users"]
+	if !ok {
+		t.Fatalf("no server span named after the route; got %v", spanNames(recorder.Ended()))
+	}
+
+	tests := []struct {
+		name      string
+		operation string
+	}{
+		{name: "UserRepository.ExistsByUsernameOrEmail", operation: "ExistsByUsernameOrEmail"},
+		{name: "UserRepository.Create", operation: "Create"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			span, ok := spans[tt.name]
+			if !ok {
+				t.Fatalf("no %s span; got %v", tt.name, spanNames(recorder.Ended()))
+			}
+			if span.Parent().SpanID() != server.SpanContext().SpanID() {
+				t.Errorf("%s is not a child of the server span", tt.name)
+			}
+			attrs := map[attribute.Key]string{}
+			for _, attr := range span.Attributes() {
+				attrs[attr.Key] = attr.Value.Emit()
+			}
+			if attrs["db.operation"] != tt.operation || attrs["db.collection.name"] != "users" {
+				t.Errorf("%s attributes = %v", tt.name, attrs)
+			}
+		})
+	}
+}
+
+// spanNames lists the names of spans, for failure messages
+func spanNames(spans []sdktrace.ReadOnlySpan) []string {
+	names := make([]string, len(spans))
+	for i, span := range spans {
+		names[i] = span.Name()
+	}
+	return names
+}
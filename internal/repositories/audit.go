@@ -0,0 +1,22 @@
+// internal/repositories/audit.go
+package repositories
+
+import "context"
+
+// actorContextKey is an unexported type for the acting user context key
+type actorContextKey struct{}
+
+// WithActor returns a copy of ctx carrying the ID of the user performing writes
+// Repository updates made with the returned context record it in updated_by
+func WithActor(ctx context.Context, userID string) context.Context {
+	if userID == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, actorContextKey{}, userID)
+}
+
+// ActorFromContext returns the ID of the user performing writes, if any
+func ActorFromContext(ctx context.Context) (string, bool) {
+	userID, ok := ctx.Value(actorContextKey{}).(string)
+	return userID, ok && userID != ""
+}
@@ -0,0 +1,311 @@
+// internal/repositories/base.go
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"go-template/internal/interfaces"
+	"go-template/internal/shared/utils"
+)
+
+// ErrVersionConflict is returned by UpdateWithVersion when the document was
+// modified by someone else since the caller read it
+var ErrVersionConflict = errors.New("version conflict")
+
+// streamBatchSize is the number of documents fetched per cursor round trip by Stream
+const streamBatchSize = 500
+
+// versionField is the document field used for optimistic concurrency control
+const versionField = "version"
+
+// idSetter is implemented by models embedding models.BaseModel
+type idSetter interface {
+	SetID(id primitive.ObjectID)
+}
+
+// MongoRepository provides soft-delete aware CRUD operations for a collection of T
+// T is expected to embed models.BaseModel so documents carry _id, timestamps and deleted_at.
+// Missing documents are reported as interfaces.ErrNotFound, prefixed with the entity name
+// (e.g. "user not found").
+type MongoRepository[T any] struct {
+	collection *mongo.Collection
+	entity     string
+}
+
+// NewMongoRepository creates a MongoRepository for the given collection
+// entity is the singular, human readable name used in error messages
+func NewMongoRepository[T any](collection *mongo.Collection, entity string) *MongoRepository[T] {
+	return &MongoRepository[T]{
+		collection: collection,
+		entity:     entity,
+	}
+}
+
+// Collection returns the underlying MongoDB collection
+func (r *MongoRepository[T]) Collection() *mongo.Collection {
+	return r.collection
+}
+
+// Create inserts a new document and sets its generated ID
+func (r *MongoRepository[T]) Create(ctx context.Context, doc *T) error {
+	result, err := r.collection.InsertOne(ctx, doc)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", r.entity, err)
+	}
+
+	if oid, ok := result.InsertedID.(primitive.ObjectID); ok {
+		if setter, ok := any(doc).(idSetter); ok {
+			setter.SetID(oid)
+		}
+	}
+
+	return nil
+}
+
+// GetByID retrieves a document that has not been soft-deleted by its ID
+func (r *MongoRepository[T]) GetByID(ctx context.Context, id string) (*T, error) {
+	objectID, err := r.objectID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.FindOne(ctx, bson.M{"_id": objectID})
+}
+
+// GetByIDs retrieves the documents with the given IDs in a single query, skipping
+// soft-deleted and nonexistent ones. Results are not returned in input order.
+func (r *MongoRepository[T]) GetByIDs(ctx context.Context, ids []string) ([]*T, error) {
+	objectIDs := make(bson.A, 0, len(ids))
+	for _, id := range ids {
+		objectID, err := r.objectID(id)
+		if err != nil {
+			return nil, err
+		}
+		objectIDs = append(objectIDs, objectID)
+	}
+
+	docs := make([]*T, 0, len(objectIDs))
+	if len(objectIDs) == 0 {
+		return docs, nil
+	}
+
+	cursor, err := r.collection.Find(ctx, notDeleted(bson.M{"_id": bson.M{"$in": objectIDs}}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find %ss: %w", r.entity, err)
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var doc T
+		if err := cursor.Decode(&doc); err != nil {
+			return nil, fmt.Errorf("failed to decode %s: %w", r.entity, err)
+		}
+		docs = append(docs, &doc)
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, fmt.Errorf("cursor error: %w", err)
+	}
+
+	return docs, nil
+}
+
+// FindOne retrieves the first document matching filter, excluding soft-deleted documents
+func (r *MongoRepository[T]) FindOne(ctx context.Context, filter bson.M) (*T, error) {
+	var doc T
+	err := r.collection.FindOne(ctx, notDeleted(filter)).Decode(&doc)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("%s %w", r.entity, interfaces.ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to get %s: %w", r.entity, err)
+	}
+
+	return &doc, nil
+}
+
+// Update sets fields on a document that has not been soft-deleted, bumps updated_at
+// and increments its version
+func (r *MongoRepository[T]) Update(ctx context.Context, id string, updates map[string]interface{}) error {
+	objectID, err := r.objectID(id)
+	if err != nil {
+		return err
+	}
+
+	result, err := r.collection.UpdateOne(ctx, notDeleted(bson.M{"_id": objectID}), versionedUpdate(ctx, updates))
+	if err != nil {
+		return fmt.Errorf("failed to update %s: %w", r.entity, err)
+	}
+
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("%s %w", r.entity, interfaces.ErrNotFound)
+	}
+
+	return nil
+}
+
+// UpdateWithVersion behaves like Update but only applies the change when the stored
+// version equals expectedVersion. ErrVersionConflict is returned when the document
+// exists but its version differs.
+func (r *MongoRepository[T]) UpdateWithVersion(ctx context.Context, id string, expectedVersion int64, updates map[string]interface{}) error {
+	objectID, err := r.objectID(id)
+	if err != nil {
+		return err
+	}
+
+	filter := notDeleted(bson.M{"_id": objectID})
+	if expectedVersion == 0 {
+		// Documents written before versioning was introduced have no version field
+		filter[versionField] = bson.M{"$in": bson.A{int64(0), nil}}
+	} else {
+		filter[versionField] = expectedVersion
+	}
+
+	result, err := r.collection.UpdateOne(ctx, filter, versionedUpdate(ctx, updates))
+	if err != nil {
+		return fmt.Errorf("failed to update %s: %w", r.entity, err)
+	}
+
+	if result.MatchedCount == 0 {
+		// Tell a stale version apart from a missing document
+		count, err := r.collection.CountDocuments(ctx, notDeleted(bson.M{"_id": objectID}))
+		if err != nil {
+			return fmt.Errorf("failed to update %s: %w", r.entity, err)
+		}
+		if count > 0 {
+			return ErrVersionConflict
+		}
+		return fmt.Errorf("%s %w", r.entity, interfaces.ErrNotFound)
+	}
+
+	return nil
+}
+
+// SoftDelete marks a document as deleted by setting deleted_at
+// extra holds additional fields to set in the same update, e.g. deactivating the document
+func (r *MongoRepository[T]) SoftDelete(ctx context.Context, id string, extra map[string]interface{}) error {
+	updates := map[string]interface{}{
+		"deleted_at": utils.Now(),
+	}
+	for field, value := range extra {
+		updates[field] = value
+	}
+
+	return r.Update(ctx, id, updates)
+}
+
+// GetAll retrieves a page of documents matching filter, excluding soft-deleted documents
+// page is 1-based; the total count of matching documents is returned alongside the page.
+// A non-empty projection limits the fields loaded from each document.
+func (r *MongoRepository[T]) GetAll(ctx context.Context, filter bson.M, page, limit int, sort bson.D, projection bson.M) ([]*T, int, error) {
+	filter = notDeleted(filter)
+
+	// Count total documents
+	total, err := r.collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count %ss: %w", r.entity, err)
+	}
+
+	// Build options
+	opts := options.Find().
+		SetSkip(int64((page - 1) * limit)).
+		SetLimit(int64(limit))
+	if len(sort) > 0 {
+		opts.SetSort(sort)
+	}
+	if len(projection) > 0 {
+		opts.SetProjection(projection)
+	}
+
+	// Execute query
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to find %ss: %w", r.entity, err)
+	}
+	defer cursor.Close(ctx)
+
+	// Decode results
+	var docs []*T
+	for cursor.Next(ctx) {
+		var doc T
+		if err := cursor.Decode(&doc); err != nil {
+			return nil, 0, fmt.Errorf("failed to decode %s: %w", r.entity, err)
+		}
+		docs = append(docs, &doc)
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, 0, fmt.Errorf("cursor error: %w", err)
+	}
+
+	return docs, int(total), nil
+}
+
+// Stream calls fn for every document matching filter, excluding soft-deleted documents,
+// in _id order. Documents are decoded one at a time from the cursor so memory stays bounded.
+// Iteration stops at the first error returned by fn, which is returned as is.
+func (r *MongoRepository[T]) Stream(ctx context.Context, filter bson.M, fn func(*T) error) error {
+	opts := options.Find().
+		SetSort(bson.D{{Key: "_id", Value: 1}}).
+		SetBatchSize(streamBatchSize)
+
+	cursor, err := r.collection.Find(ctx, notDeleted(filter), opts)
+	if err != nil {
+		return fmt.Errorf("failed to find %ss: %w", r.entity, err)
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var doc T
+		if err := cursor.Decode(&doc); err != nil {
+			return fmt.Errorf("failed to decode %s: %w", r.entity, err)
+		}
+		if err := fn(&doc); err != nil {
+			return err
+		}
+	}
+
+	if err := cursor.Err(); err != nil {
+		return fmt.Errorf("cursor error: %w", err)
+	}
+
+	return nil
+}
+
+// objectID parses a hex ID, reporting failures with the entity name
+func (r *MongoRepository[T]) objectID(id string) (primitive.ObjectID, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return primitive.NilObjectID, fmt.Errorf("invalid %s ID format: %w", r.entity, err)
+	}
+	return objectID, nil
+}
+
+// versionedUpdate builds an update document that sets updates, bumps updated_at,
+// records the acting user from ctx in updated_by and increments the version
+func versionedUpdate(ctx context.Context, updates map[string]interface{}) bson.M {
+	updates["updated_at"] = utils.Now()
+	if actor, ok := ActorFromContext(ctx); ok {
+		updates["updated_by"] = actor
+	}
+	return bson.M{
+		"$set": updates,
+		"$inc": bson.M{versionField: 1},
+	}
+}
+
+// notDeleted returns a copy of filter that excludes soft-deleted documents
+func notDeleted(filter bson.M) bson.M {
+	scoped := bson.M{"deleted_at": bson.M{"$exists": false}}
+	for key, value := range filter {
+		scoped[key] = value
+	}
+	return scoped
+}
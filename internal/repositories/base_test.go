@@ -0,0 +1,251 @@
+// internal/repositories/base_test.go
+package repositories
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+
+	"go-template/internal/interfaces"
+	"go-template/internal/models"
+)
+
+// widget is a minimal model for exercising MongoRepository
+type widget struct {
+	models.BaseModel `bson:",inline"`
+	Name             string `bson:"name"`
+}
+
+// newWidgetRepository returns a MongoRepository of widgets over mt's mocked deployment
+func newWidgetRepository(mt *mtest.T) *MongoRepository[widget] {
+	return NewMongoRepository[widget](mt.Coll, "widget")
+}
+
+// widgetCursor returns a cursor reply holding the given widget documents
+func widgetCursor(mt *mtest.T, docs ...bson.D) bson.D {
+	return mtest.CreateCursorResponse(0, mt.Coll.Database().Name()+"."+mt.Coll.Name(), mtest.FirstBatch, docs...)
+}
+
+// assertNotDeletedFilter checks that filter excludes soft-deleted documents
+func assertNotDeletedFilter(mt *mtest.T, filter bson.Raw) {
+	mt.Helper()
+	exists, err := filter.LookupErr("deleted_at", "$exists")
+	if err != nil || exists.Boolean() {
+		mt.Errorf("filter = %v, want deleted_at $exists false", filter)
+	}
+}
+
+func TestMongoRepositoryCreate(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("sets the generated ID", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateSuccessResponse())
+		doc := &widget{Name: "gear"}
+
+		if err := newWidgetRepository(mt).Create(context.Background(), doc); err != nil {
+			mt.Fatalf("Create() error = %v", err)
+		}
+		if doc.ID.IsZero() {
+			mt.Error("ID not set after Create")
+		}
+		sent := mt.GetStartedEvent().Command.Lookup("documents").Array().Index(0).Value().Document()
+		if got := sent.Lookup("_id").ObjectID(); got != doc.ID {
+			mt.Errorf("inserted _id = %s, want %s", got.Hex(), doc.ID.Hex())
+		}
+	})
+
+	mt.Run("wraps insert errors with the entity", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCommandErrorResponse(mtest.CommandError{Code: 2, Message: "boom"}))
+		err := newWidgetRepository(mt).Create(context.Background(), &widget{Name: "gear"})
+		if err == nil || !strings.Contains(err.Error(), "failed to create widget") {
+			mt.Errorf("Create() error = %v, want it to name the entity", err)
+		}
+	})
+}
+
+func TestMongoRepositoryGetByID(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	id := primitive.NewObjectID()
+
+	tests := []struct {
+		name     string
+		id       string
+		reply    []bson.D
+		wantName string
+		wantErr  error
+		wantMsg  string
+	}{
+		{
+			name:     "found",
+			id:       id.Hex(),
+			reply:    []bson.D{{{Key: "_id", Value: id}, {Key: "name", Value: "gear"}}},
+			wantName: "gear",
+		},
+		{name: "missing", id: id.Hex(), reply: []bson.D{}, wantErr: interfaces.ErrNotFound, wantMsg: "widget not found"},
+		{name: "invalid ID", id: "nope", wantMsg: "invalid widget ID format"},
+	}
+
+	for _, tt := range tests {
+		mt.Run(tt.name, func(mt *mtest.T) {
+			if tt.reply != nil {
+				mt.AddMockResponses(widgetCursor(mt, tt.reply...))
+			}
+
+			got, err := newWidgetRepository(mt).GetByID(context.Background(), tt.id)
+			if tt.wantMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantMsg) || (tt.wantErr != nil && !errors.Is(err, tt.wantErr)) {
+					mt.Fatalf("GetByID() error = %v, want %q", err, tt.wantMsg)
+				}
+				return
+			}
+			if err != nil {
+				mt.Fatalf("GetByID() error = %v", err)
+			}
+			if got.Name != tt.wantName || got.ID != id {
+				mt.Errorf("GetByID() = %+v, want %s named %q", got, id.Hex(), tt.wantName)
+			}
+
+			filter := mt.GetStartedEvent().Command.Lookup("filter").Document()
+			assertNotDeletedFilter(mt, filter)
+			if got := filter.Lookup("_id").ObjectID(); got != id {
+				mt.Errorf("filter _id = %s, want %s", got.Hex(), id.Hex())
+			}
+		})
+	}
+}
+
+func TestMongoRepositoryUpdate(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	id := primitive.NewObjectID().Hex()
+
+	tests := []struct {
+		name string
+		// call runs the write under test
+		call        func(r *MongoRepository[widget]) error
+		matched     int
+		wantErr     error
+		wantSetKeys []string
+	}{
+		{
+			name: "update",
+			call: func(r *MongoRepository[widget]) error {
+				return r.Update(context.Background(), id, map[string]interface{}{"name": "cog"})
+			},
+			matched:     1,
+			wantSetKeys: []string{"name", "updated_at"},
+		},
+		{
+			name: "update missing",
+			call: func(r *MongoRepository[widget]) error {
+				return r.Update(context.Background(), id, map[string]interface{}{"name": "cog"})
+			},
+			wantErr: interfaces.ErrNotFound,
+		},
+		{
+			name: "soft delete",
+			call: func(r *MongoRepository[widget]) error {
+				return r.SoftDelete(context.Background(), id, map[string]interface{}{"is_active": false})
+			},
+			matched:     1,
+			wantSetKeys: []string{"deleted_at", "is_active", "updated_at"},
+		},
+	}
+
+	for _, tt := range tests {
+		mt.Run(tt.name, func(mt *mtest.T) {
+			mt.AddMockResponses(mtest.CreateSuccessResponse(
+				bson.E{Key: "n", Value: tt.matched},
+				bson.E{Key: "nModified", Value: tt.matched},
+			))
+
+			err := tt.call(newWidgetRepository(mt))
+			if !errors.Is(err, tt.wantErr) {
+				mt.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+
+			filter, update := sentUpdate(mt)
+			assertNotDeletedFilter(mt, filter)
+			if inc := update.Lookup("$inc", versionField); inc.Type == 0 {
+				mt.Errorf("update = %v, want the version incremented", update)
+			}
+			for _, key := range tt.wantSetKeys {
+				if _, err := update.LookupErr("$set", key); err != nil {
+					mt.Errorf("update = %v, want $set.%s", update, key)
+				}
+			}
+		})
+	}
+}
+
+func TestMongoRepositoryGetAll(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	tests := []struct {
+		name      string
+		page      int
+		limit     int
+		total     int
+		docs      []bson.D
+		wantSkip  int64
+		wantNames []string
+	}{
+		{
+			name:      "first page",
+			page:      1,
+			limit:     2,
+			total:     3,
+			docs:      []bson.D{{{Key: "name", Value: "a"}}, {{Key: "name", Value: "b"}}},
+			wantNames: []string{"a", "b"},
+		},
+		{
+			name:      "second page",
+			page:      2,
+			limit:     2,
+			total:     3,
+			docs:      []bson.D{{{Key: "name", Value: "c"}}},
+			wantSkip:  2,
+			wantNames: []string{"c"},
+		},
+		{name: "empty", page: 1, limit: 10, docs: []bson.D{}},
+	}
+
+	for _, tt := range tests {
+		mt.Run(tt.name, func(mt *mtest.T) {
+			mt.AddMockResponses(
+				widgetCursor(mt, bson.D{{Key: "n", Value: int32(tt.total)}}),
+				widgetCursor(mt, tt.docs...),
+			)
+
+			docs, total, err := newWidgetRepository(mt).GetAll(context.Background(),
+				bson.M{"name": bson.M{"$ne": ""}}, tt.page, tt.limit, bson.D{{Key: "name", Value: 1}}, nil)
+			if err != nil {
+				mt.Fatalf("GetAll() error = %v", err)
+			}
+			if total != tt.total {
+				mt.Errorf("total = %d, want %d", total, tt.total)
+			}
+			var names []string
+			for _, doc := range docs {
+				names = append(names, doc.Name)
+			}
+			if strings.Join(names, ",") != strings.Join(tt.wantNames, ",") {
+				mt.Errorf("names = %v, want %v", names, tt.wantNames)
+			}
+
+			events := mt.GetAllStartedEvents()
+			find := events[len(events)-1].Command
+			assertNotDeletedFilter(mt, find.Lookup("filter").Document())
+			if skip, ok := find.Lookup("skip").AsInt64OK(); (ok || tt.wantSkip != 0) && skip != tt.wantSkip {
+				mt.Errorf("skip = %d, want %d", skip, tt.wantSkip)
+			}
+			if limit := find.Lookup("limit").AsInt64(); limit != int64(tt.limit) {
+				mt.Errorf("limit = %d, want %d", limit, tt.limit)
+			}
+		})
+	}
+}
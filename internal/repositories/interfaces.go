@@ -4,6 +4,7 @@ package repositories
 import (
 	"context"
 	"go-template/internal/models"
+	"time"
 )
 
 // UserRepositoryInterface defines the contract for user data persistence
@@ -11,19 +12,26 @@ type UserRepositoryInterface interface {
 	// Basic CRUD operations
 	Create(ctx context.Context, user *models.User) error
 	GetByID(ctx context.Context, id string) (*models.User, error)
+	GetByIDs(ctx context.Context, ids []string) ([]*models.User, error)
 	GetByUsername(ctx context.Context, username string) (*models.User, error)
 	GetByEmail(ctx context.Context, email string) (*models.User, error)
 	Update(ctx context.Context, id string, updates map[string]interface{}) error
+	UpdateWithVersion(ctx context.Context, id string, expectedVersion int64, updates map[string]interface{}) error
 	Delete(ctx context.Context, id string) error
 	SoftDelete(ctx context.Context, id string) error
+	Restore(ctx context.Context, id string) error
+	GetByIDIncludingDeleted(ctx context.Context, id string) (*models.User, error)
 	
 	// List and search operations
 	GetAll(ctx context.Context, params *models.UsersQueryParams) ([]*models.User, int, error)
 	Search(ctx context.Context, query string, limit int) ([]*models.User, error)
+	SearchText(ctx context.Context, query string, limit int) ([]*models.User, error)
+	StreamAll(ctx context.Context, fn func(*models.User) error) error
 	
 	// Existence checks
 	ExistsByUsername(ctx context.Context, username string) (bool, error)
 	ExistsByEmail(ctx context.Context, email string) (bool, error)
+	ExistsByUsernameOrEmail(ctx context.Context, username, email string) (usernameExists, emailExists bool, err error) // One round trip for both checks
 	ExistsByID(ctx context.Context, id string) (bool, error)
 	
 	// Role-based queries
@@ -34,12 +42,18 @@ type UserRepositoryInterface interface {
 	GetActiveUsers(ctx context.Context, limit int) ([]*models.User, error)
 	GetInactiveUsers(ctx context.Context, limit int) ([]*models.User, error)
 	CountActiveUsers(ctx context.Context) (int, error)
+	GetRecentlyLoggedIn(ctx context.Context, limit int) ([]*models.User, error) // Most recent last_login_at first
 	
 	// Authentication-related
 	UpdateLastLogin(ctx context.Context, id string) error
+	UpdateLastActivity(ctx context.Context, id string) error
 	IncrementLoginCount(ctx context.Context, id string) error
 	RecordFailedLogin(ctx context.Context, id string) error
 	ResetFailedLogins(ctx context.Context, id string) error
+	IncrementTokenVersion(ctx context.Context, id string) error // Revokes every token issued so far
+	
+	// Counters
+	IncrementField(ctx context.Context, id, field string, by int) error // Only allowlisted counter fields
 	
 	// Verification and status
 	MarkAsVerified(ctx context.Context, id string) error
@@ -49,13 +63,17 @@ type UserRepositoryInterface interface {
 	CreateMany(ctx context.Context, users []*models.User) error
 	UpdateMany(ctx context.Context, filter map[string]interface{}, updates map[string]interface{}) error
 	DeleteMany(ctx context.Context, ids []string) error
+	SoftDeleteMany(ctx context.Context, ids []string) (int64, error)
 	
 	// Statistics and analytics
-	GetUserStats(ctx context.Context) (map[string]interface{}, error)
+	GetUserStats(ctx context.Context, params *models.UserStatsParams) (*models.UserStatsResponse, error)
+	RoleDistribution(ctx context.Context) (map[string]int, error) // Users with several roles count towards each
+	CountUsers(ctx context.Context, params *models.UserCountParams) (int, error) // Cheaper than GetUserStats for a single total
 	GetUsersByDateRange(ctx context.Context, startDate, endDate string) ([]*models.User, error)
+	CountByDay(ctx context.Context, from, to time.Time) ([]models.DayCount, error) // Days without signups are omitted
 	
 	// Database maintenance
-	Cleanup(ctx context.Context) error // Remove soft-deleted users older than X days
+	Cleanup(ctx context.Context, retention time.Duration) (int64, error) // Remove users soft-deleted more than retention ago
 }
 
 // BaseRepositoryInterface defines common repository operations
@@ -69,4 +87,25 @@ type BaseRepositoryInterface interface {
 	
 	// Database statistics
 	GetCollectionStats(ctx context.Context) (map[string]interface{}, error)
-}
\ No newline at end of file
+}
+
+// OutboxRepositoryInterface defines the contract for the transactional event outbox
+type OutboxRepositoryInterface interface {
+	// Enqueue stores a new pending event
+	Enqueue(ctx context.Context, event *models.OutboxEvent) error
+	// ClaimDue leases up to limit pending events that are due, oldest first, so no other
+	// dispatcher picks them up for the lease duration, and counts the delivery attempt
+	ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]*models.OutboxEvent, error)
+	// MarkSent records a successful delivery
+	MarkSent(ctx context.Context, id string) error
+	// MarkFailed records a failed delivery, to be retried at retryAt, or abandoned when retryAt is nil
+	MarkFailed(ctx context.Context, id string, lastError string, retryAt *time.Time) error
+}
+
+// LoginEventRepositoryInterface defines the contract for the per-user login audit trail
+type LoginEventRepositoryInterface interface {
+	// Append records a login attempt, keeping only the most recent models.MaxLoginEvents per user
+	Append(ctx context.Context, userID string, event models.LoginEvent) error
+	// Recent returns a user's recorded login attempts, newest first
+	Recent(ctx context.Context, userID string) ([]models.LoginEvent, error)
+}
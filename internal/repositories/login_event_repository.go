@@ -0,0 +1,76 @@
+// internal/repositories/login_event_repository.go
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"go-template/internal/models"
+)
+
+// LoginEventsCollection is the collection holding each user's recent login attempts
+const LoginEventsCollection = "login_events"
+
+// loginHistory is the single document holding a user's recent login attempts, oldest first
+type loginHistory struct {
+	UserID    string              `bson:"_id"`
+	Events    []models.LoginEvent `bson:"events"`
+	UpdatedAt time.Time           `bson:"updated_at"`
+}
+
+// LoginEventRepository implements LoginEventRepositoryInterface using MongoDB
+// Each user has one document keyed by user ID whose events array is capped on every append,
+// so the history never grows past models.MaxLoginEvents entries.
+type LoginEventRepository struct {
+	collection *mongo.Collection
+}
+
+// NewLoginEventRepository creates a new LoginEventRepository instance
+func NewLoginEventRepository(db *mongo.Database) LoginEventRepositoryInterface {
+	return &LoginEventRepository{
+		collection: db.Collection(LoginEventsCollection),
+	}
+}
+
+// Append records a login attempt for a user, dropping the oldest beyond models.MaxLoginEvents
+func (r *LoginEventRepository) Append(ctx context.Context, userID string, event models.LoginEvent) error {
+	update := bson.M{
+		"$push": bson.M{
+			"events": bson.M{
+				"$each":  []models.LoginEvent{event},
+				"$slice": -models.MaxLoginEvents,
+			},
+		},
+		"$set": bson.M{"updated_at": time.Now().UTC()},
+	}
+
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": userID}, update, options.Update().SetUpsert(true))
+	if err != nil {
+		return fmt.Errorf("failed to record login event: %w", err)
+	}
+	return nil
+}
+
+// Recent returns a user's recorded login attempts, newest first
+func (r *LoginEventRepository) Recent(ctx context.Context, userID string) ([]models.LoginEvent, error) {
+	var history loginHistory
+	err := r.collection.FindOne(ctx, bson.M{"_id": userID}).Decode(&history)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return []models.LoginEvent{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get login events: %w", err)
+	}
+
+	events := make([]models.LoginEvent, len(history.Events))
+	for i, event := range history.Events {
+		events[len(events)-1-i] = event
+	}
+	return events, nil
+}
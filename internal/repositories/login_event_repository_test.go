@@ -0,0 +1,131 @@
+// internal/repositories/login_event_repository_test.go
+package repositories
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+
+	"go-template/internal/models"
+)
+
+func TestLoginEventRepositoryAppend(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	event := models.LoginEvent{
+		Timestamp: time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC),
+		IP:        "203.0.113.7",
+		UserAgent: "Mozilla/5.0",
+		Reason:    models.LoginFailureInvalidPassword,
+	}
+
+	mt.Run("pushes the event and caps the history", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateSuccessResponse())
+
+		if err := NewLoginEventRepository(mt.DB).Append(context.Background(), "user-1", event); err != nil {
+			mt.Fatalf("Append() error = %v", err)
+		}
+
+		started := mt.GetStartedEvent()
+		if started.CommandName != "update" || started.Command.Lookup("update").StringValue() != LoginEventsCollection {
+			mt.Fatalf("command = %s %v, want an update of %s", started.CommandName, started.Command, LoginEventsCollection)
+		}
+		updates, _ := started.Command.Lookup("updates").Array().Values()
+		update := updates[0].Document()
+		if id := update.Lookup("q", "_id").StringValue(); id != "user-1" {
+			mt.Errorf("filter _id = %q, want user-1", id)
+		}
+		if !update.Lookup("upsert").Boolean() {
+			mt.Error("upsert = false, want the first attempt to create the history")
+		}
+		push := update.Lookup("u", "$push", "events").Document()
+		if slice := push.Lookup("$slice").AsInt64(); slice != -models.MaxLoginEvents {
+			mt.Errorf("$slice = %d, want %d to keep the newest events", slice, -models.MaxLoginEvents)
+		}
+		pushed, _ := push.Lookup("$each").Array().Values()
+		if len(pushed) != 1 {
+			mt.Fatalf("$each has %d events, want 1", len(pushed))
+		}
+		var got models.LoginEvent
+		if err := pushed[0].Unmarshal(&got); err != nil || got != event {
+			mt.Errorf("pushed %+v (%v), want %+v", got, err, event)
+		}
+	})
+
+	mt.Run("reports write errors", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCommandErrorResponse(mtest.CommandError{Code: 11600, Name: "InterruptedAtShutdown", Message: "interrupted"}))
+
+		err := NewLoginEventRepository(mt.DB).Append(context.Background(), "user-1", event)
+		if err == nil || !strings.Contains(err.Error(), "failed to record login event") {
+			mt.Errorf("Append() error = %v, want it wrapped", err)
+		}
+	})
+}
+
+func TestLoginEventRepositoryRecent(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	ns := "test." + LoginEventsCollection
+	eventDoc := func(ip string, success bool) bson.D {
+		return bson.D{
+			{Key: "timestamp", Value: time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)},
+			{Key: "ip", Value: ip},
+			{Key: "user_agent", Value: "test"},
+			{Key: "success", Value: success},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		docs    []bson.D
+		fail    bool
+		wantIPs []string
+		wantErr bool
+	}{
+		{name: "no history", wantIPs: []string{}},
+		{
+			name: "newest first",
+			docs: []bson.D{{
+				{Key: "_id", Value: "user-1"},
+				{Key: "events", Value: bson.A{eventDoc("192.0.2.1", false), eventDoc("192.0.2.2", false), eventDoc("192.0.2.3", true)}},
+			}},
+			wantIPs: []string{"192.0.2.3", "192.0.2.2", "192.0.2.1"},
+		},
+		{name: "read error", fail: true, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		mt.Run(tt.name, func(mt *mtest.T) {
+			if tt.fail {
+				mt.AddMockResponses(mtest.CreateCommandErrorResponse(mtest.CommandError{Code: 11600, Name: "InterruptedAtShutdown", Message: "interrupted"}))
+			} else {
+				mt.AddMockResponses(mtest.CreateCursorResponse(0, ns, mtest.FirstBatch, tt.docs...))
+			}
+
+			events, err := NewLoginEventRepository(mt.DB).Recent(context.Background(), "user-1")
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "failed to get login events") {
+					mt.Errorf("Recent() error = %v, want it wrapped", err)
+				}
+				return
+			}
+			if err != nil {
+				mt.Fatalf("Recent() error = %v", err)
+			}
+			if events == nil {
+				mt.Fatal("Recent() = nil, want an empty list so the API returns []")
+			}
+			ips := make([]string, len(events))
+			for i, event := range events {
+				ips[i] = event.IP
+			}
+			if strings.Join(ips, ",") != strings.Join(tt.wantIPs, ",") {
+				mt.Errorf("Recent() IPs = %v, want %v", ips, tt.wantIPs)
+			}
+		})
+	}
+}
@@ -0,0 +1,500 @@
+// internal/repositories/memory_query.go
+package repositories
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// This file evaluates the subset of MongoDB query and update documents the repositories
+// build, so in-memory repositories can reuse the same filters as their Mongo counterparts.
+// Documents are kept in their decoded BSON form (bson.M), which gives values the same
+// types and precision MongoDB would store.
+
+// toDocument converts v to its decoded BSON document form
+func toDocument(v interface{}) (bson.M, error) {
+	data, err := bson.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc bson.M
+	if err := bson.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// fromDocument decodes doc into dst
+func fromDocument(doc bson.M, dst interface{}) error {
+	data, err := bson.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	return bson.Unmarshal(data, dst)
+}
+
+// normalizeValue converts a Go value to the type it would have after a BSON round trip,
+// e.g. time.Time to primitive.DateTime and []string to bson.A
+func normalizeValue(v interface{}) (interface{}, error) {
+	doc, err := toDocument(bson.M{"v": v})
+	if err != nil {
+		return nil, err
+	}
+	return doc["v"], nil
+}
+
+// matchDocument reports whether doc satisfies filter
+func matchDocument(doc bson.M, filter bson.M) (bool, error) {
+	for key, cond := range filter {
+		switch key {
+		case "$or", "$and", "$nor":
+			clauses, err := filterList(cond)
+			if err != nil {
+				return false, fmt.Errorf("%s: %w", key, err)
+			}
+			matched := 0
+			for _, clause := range clauses {
+				ok, err := matchDocument(doc, clause)
+				if err != nil {
+					return false, err
+				}
+				if ok {
+					matched++
+				}
+			}
+			if (key == "$or" && matched == 0) || (key == "$and" && matched != len(clauses)) || (key == "$nor" && matched > 0) {
+				return false, nil
+			}
+		default:
+			value, exists := doc[key]
+			ok, err := matchCondition(value, exists, cond)
+			if err != nil {
+				return false, fmt.Errorf("%s: %w", key, err)
+			}
+			if !ok {
+				return false, nil
+			}
+		}
+	}
+	return true, nil
+}
+
+// matchCondition reports whether a field value satisfies cond, which is either a
+// literal to compare for equality or an operator document such as {"$gte": t}
+func matchCondition(value interface{}, exists bool, cond interface{}) (bool, error) {
+	ops, isOperator := operatorDocument(cond)
+	if !isOperator {
+		target, err := normalizeValue(cond)
+		if err != nil {
+			return false, err
+		}
+		return matchEquals(value, target), nil
+	}
+
+	for op, operand := range ops {
+		var ok bool
+		switch op {
+		case "$options":
+			continue
+		case "$regex":
+			pattern, isString := operand.(string)
+			if !isString {
+				return false, fmt.Errorf("$regex must be a string, got %T", operand)
+			}
+			options, _ := ops["$options"].(string)
+			re, err := compileRegex(pattern, options)
+			if err != nil {
+				return false, err
+			}
+			ok = matchAny(value, func(v interface{}) bool {
+				s, isString := v.(string)
+				return isString && re.MatchString(s)
+			})
+		case "$exists":
+			want, isBool := operand.(bool)
+			if !isBool {
+				return false, fmt.Errorf("$exists must be a bool, got %T", operand)
+			}
+			ok = exists == want
+		default:
+			target, err := normalizeValue(operand)
+			if err != nil {
+				return false, err
+			}
+			ok, err = matchOperator(op, value, target)
+			if err != nil {
+				return false, err
+			}
+		}
+		if !ok {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// matchOperator evaluates a comparison or set operator against a normalized operand
+func matchOperator(op string, value, target interface{}) (bool, error) {
+	switch op {
+	case "$eq":
+		return matchEquals(value, target), nil
+	case "$ne":
+		return !matchEquals(value, target), nil
+	case "$in", "$nin", "$all":
+		items, ok := target.(bson.A)
+		if !ok {
+			return false, fmt.Errorf("%s needs an array, got %T", op, target)
+		}
+		matched := 0
+		for _, item := range items {
+			if matchEquals(value, item) {
+				matched++
+			}
+		}
+		switch op {
+		case "$in":
+			return matched > 0, nil
+		case "$nin":
+			return matched == 0, nil
+		default:
+			return len(items) > 0 && matched == len(items), nil
+		}
+	case "$gt", "$gte", "$lt", "$lte":
+		return matchAny(value, func(v interface{}) bool {
+			if bsonTypeRank(v) != bsonTypeRank(target) {
+				return false
+			}
+			c := compareValues(v, target)
+			switch op {
+			case "$gt":
+				return c > 0
+			case "$gte":
+				return c >= 0
+			case "$lt":
+				return c < 0
+			default:
+				return c <= 0
+			}
+		}), nil
+	default:
+		return false, fmt.Errorf("unsupported query operator %s", op)
+	}
+}
+
+// matchEquals reports whether value equals target or, for arrays, contains it
+// A null target matches missing fields, as in MongoDB.
+func matchEquals(value, target interface{}) bool {
+	if equalValues(value, target) {
+		return true
+	}
+	if items, ok := value.(bson.A); ok {
+		for _, item := range items {
+			if equalValues(item, target) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchAny applies pred to value, or to each element when value is an array
+func matchAny(value interface{}, pred func(interface{}) bool) bool {
+	if items, ok := value.(bson.A); ok {
+		for _, item := range items {
+			if pred(item) {
+				return true
+			}
+		}
+		return false
+	}
+	return pred(value)
+}
+
+// applyUpdate applies the $set, $unset and $inc operators of update to doc in place
+func applyUpdate(doc bson.M, update bson.M) error {
+	for op, spec := range update {
+		fields, ok := asMap(spec)
+		if !ok {
+			return fmt.Errorf("%s needs a document, got %T", op, spec)
+		}
+
+		for field, value := range fields {
+			switch op {
+			case "$set":
+				normalized, err := normalizeValue(value)
+				if err != nil {
+					return err
+				}
+				if err := setPath(doc, field, normalized); err != nil {
+					return err
+				}
+			case "$unset":
+				delete(doc, field)
+			case "$inc":
+				sum, err := addNumbers(doc[field], value)
+				if err != nil {
+					return fmt.Errorf("$inc %s: %w", field, err)
+				}
+				doc[field] = sum
+			default:
+				return fmt.Errorf("unsupported update operator %s", op)
+			}
+		}
+	}
+	return nil
+}
+
+// setPath sets a possibly dotted field of doc, creating embedded documents along the way
+// Embedded documents on the path are copied, so documents shared with stored state are never
+// modified in place.
+func setPath(doc bson.M, field string, value interface{}) error {
+	head, rest, nested := strings.Cut(field, ".")
+	if !nested {
+		doc[head] = value
+		return nil
+	}
+
+	child := bson.M{}
+	if existing, ok := doc[head]; ok && existing != nil {
+		current, ok := asMap(existing)
+		if !ok {
+			return fmt.Errorf("cannot create field %s in non-document field %s", rest, head)
+		}
+		for key, v := range current {
+			child[key] = v
+		}
+	}
+
+	if err := setPath(child, rest, value); err != nil {
+		return err
+	}
+	doc[head] = child
+	return nil
+}
+
+// lessBySort reports whether a sorts before b under sort, using MongoDB's cross-type ordering
+func lessBySort(a, b bson.M, sort bson.D) bool {
+	for _, key := range sort {
+		c := compareValues(a[key.Key], b[key.Key])
+		if c == 0 {
+			continue
+		}
+		if direction, ok := key.Value.(int); ok && direction < 0 {
+			return c > 0
+		}
+		return c < 0
+	}
+	return false
+}
+
+// projectDocument returns a copy of doc holding only _id and the fields set in projection
+func projectDocument(doc bson.M, projection bson.M) bson.M {
+	if len(projection) == 0 {
+		return doc
+	}
+
+	projected := bson.M{"_id": doc["_id"]}
+	for field := range projection {
+		if value, ok := doc[field]; ok {
+			projected[field] = value
+		}
+	}
+	return projected
+}
+
+// bsonTypeRank orders BSON types the way MongoDB does when comparing mixed types
+func bsonTypeRank(v interface{}) int {
+	switch v.(type) {
+	case nil:
+		return 1
+	case int32, int64, float64:
+		return 2
+	case string:
+		return 3
+	case bson.M, bson.D:
+		return 4
+	case bson.A:
+		return 5
+	case primitive.ObjectID:
+		return 7
+	case bool:
+		return 8
+	case primitive.DateTime:
+		return 9
+	default:
+		return 10
+	}
+}
+
+// compareValues returns -1, 0 or 1 comparing two normalized values
+func compareValues(a, b interface{}) int {
+	if ra, rb := bsonTypeRank(a), bsonTypeRank(b); ra != rb {
+		return compareInts(int64(ra), int64(rb))
+	}
+
+	switch av := a.(type) {
+	case int32, int64, float64:
+		af, bf := toFloat(a), toFloat(b)
+		switch {
+		case af < bf:
+			return -1
+		case af > bf:
+			return 1
+		}
+		return 0
+	case string:
+		return strings.Compare(av, b.(string))
+	case primitive.ObjectID:
+		bv := b.(primitive.ObjectID)
+		return bytes.Compare(av[:], bv[:])
+	case bool:
+		bv := b.(bool)
+		switch {
+		case av == bv:
+			return 0
+		case !av:
+			return -1
+		}
+		return 1
+	case primitive.DateTime:
+		return compareInts(int64(av), int64(b.(primitive.DateTime)))
+	case bson.A:
+		bv := b.(bson.A)
+		for i := 0; i < len(av) && i < len(bv); i++ {
+			if c := compareValues(av[i], bv[i]); c != 0 {
+				return c
+			}
+		}
+		return compareInts(int64(len(av)), int64(len(bv)))
+	}
+	return 0
+}
+
+// equalValues reports whether two normalized values are equal
+func equalValues(a, b interface{}) bool {
+	return bsonTypeRank(a) == bsonTypeRank(b) && compareValues(a, b) == 0
+}
+
+func compareInts(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// toFloat converts a BSON numeric value to float64
+func toFloat(v interface{}) float64 {
+	switch n := v.(type) {
+	case int32:
+		return float64(n)
+	case int64:
+		return float64(n)
+	case float64:
+		return n
+	}
+	return 0
+}
+
+// addNumbers adds delta to current as $inc does, treating a missing field as 0
+func addNumbers(current, delta interface{}) (interface{}, error) {
+	normalized, err := normalizeValue(delta)
+	if err != nil {
+		return nil, err
+	}
+	if current == nil {
+		current = int32(0)
+	}
+	if bsonTypeRank(current) != 2 || bsonTypeRank(normalized) != 2 {
+		return nil, fmt.Errorf("cannot increment %T by %T", current, delta)
+	}
+
+	_, currentFloat := current.(float64)
+	_, deltaFloat := normalized.(float64)
+	if currentFloat || deltaFloat {
+		return toFloat(current) + toFloat(normalized), nil
+	}
+	return int64(toFloat(current)) + int64(toFloat(normalized)), nil
+}
+
+// compileRegex compiles a MongoDB regex pattern with its options (i, m and s are supported)
+func compileRegex(pattern, options string) (*regexp.Regexp, error) {
+	flags := ""
+	for _, option := range options {
+		switch option {
+		case 'i', 'm', 's':
+			flags += string(option)
+		default:
+			return nil, fmt.Errorf("unsupported $options flag %q", option)
+		}
+	}
+	if flags != "" {
+		pattern = "(?" + flags + ")" + pattern
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid $regex: %w", err)
+	}
+	return re, nil
+}
+
+// operatorDocument returns cond as a map when it is a non-empty document of $ operators
+func operatorDocument(cond interface{}) (map[string]interface{}, bool) {
+	ops, ok := asMap(cond)
+	if !ok || len(ops) == 0 {
+		return nil, false
+	}
+	for key := range ops {
+		if !strings.HasPrefix(key, "$") {
+			return nil, false
+		}
+	}
+	return ops, true
+}
+
+// asMap returns v as a map when it is a bson.M or plain map
+func asMap(v interface{}) (map[string]interface{}, bool) {
+	switch m := v.(type) {
+	case bson.M:
+		return m, true
+	case map[string]interface{}:
+		return m, true
+	}
+	return nil, false
+}
+
+// filterList returns the clauses of a $or, $and or $nor operand
+func filterList(v interface{}) ([]bson.M, error) {
+	switch list := v.(type) {
+	case []bson.M:
+		return list, nil
+	case []map[string]interface{}:
+		clauses := make([]bson.M, len(list))
+		for i, clause := range list {
+			clauses[i] = clause
+		}
+		return clauses, nil
+	case bson.A:
+		return filterList([]interface{}(list))
+	case []interface{}:
+		clauses := make([]bson.M, len(list))
+		for i, item := range list {
+			clause, ok := asMap(item)
+			if !ok {
+				return nil, fmt.Errorf("clause %d must be a document, got %T", i, item)
+			}
+			clauses[i] = clause
+		}
+		return clauses, nil
+	}
+	return nil, fmt.Errorf("needs an array of documents, got %T", v)
+}
@@ -0,0 +1,1025 @@
+// internal/repositories/memory_repository.go
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"go-template/internal/interfaces"
+	"go-template/internal/models"
+	"go-template/internal/shared/utils"
+)
+
+// uniqueUserFields mirrors the unique indexes on the users collection
+// Like the MongoDB indexes they ignore case and also cover soft-deleted users.
+var uniqueUserFields = []string{"username", "email"}
+
+// MemoryUserRepository implements UserRepositoryInterface in process memory.
+// It is meant for tests of the service and handlers without MongoDB: it evaluates the same
+// filter and update documents as UserRepository and enforces the same unique constraints
+// and soft-delete rules. Full-text search is approximated by matching whole words.
+type MemoryUserRepository struct {
+	mu   sync.RWMutex
+	docs map[primitive.ObjectID]bson.M
+}
+
+// NewMemoryUserRepository creates an empty MemoryUserRepository
+func NewMemoryUserRepository() *MemoryUserRepository {
+	return &MemoryUserRepository{
+		docs: make(map[primitive.ObjectID]bson.M),
+	}
+}
+
+// Create inserts a new user, enforcing unique usernames and emails
+func (r *MemoryUserRepository) Create(ctx context.Context, user *models.User) error {
+	normalizeUserIdentity(user)
+
+	if exists, err := r.ExistsByUsername(ctx, user.Username); err != nil {
+		return fmt.Errorf("failed to check username existence: %w", err)
+	} else if exists {
+		return fmt.Errorf("username %w", interfaces.ErrAlreadyExists)
+	}
+
+	if exists, err := r.ExistsByEmail(ctx, user.Email); err != nil {
+		return fmt.Errorf("failed to check email existence: %w", err)
+	} else if exists {
+		return fmt.Errorf("email %w", interfaces.ErrAlreadyExists)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.insert(ctx, user); err != nil {
+		return fmt.Errorf("failed to create user: %w", err)
+	}
+	return nil
+}
+
+// GetByID retrieves a user that has not been soft-deleted by their ID
+func (r *MemoryUserRepository) GetByID(ctx context.Context, id string) (*models.User, error) {
+	objectID, err := parseUserID(id)
+	if err != nil {
+		return nil, err
+	}
+	return r.findOne(ctx, notDeleted(bson.M{"_id": objectID}))
+}
+
+// GetByIDs retrieves the users with the given IDs, skipping soft-deleted and nonexistent ones
+func (r *MemoryUserRepository) GetByIDs(ctx context.Context, ids []string) ([]*models.User, error) {
+	objectIDs := make(bson.A, 0, len(ids))
+	for _, id := range ids {
+		objectID, err := parseUserID(id)
+		if err != nil {
+			return nil, err
+		}
+		objectIDs = append(objectIDs, objectID)
+	}
+
+	users := make([]*models.User, 0, len(objectIDs))
+	if len(objectIDs) == 0 {
+		return users, nil
+	}
+
+	found, err := r.find(ctx, notDeleted(bson.M{"_id": bson.M{"$in": objectIDs}}), 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find users: %w", err)
+	}
+	return append(users, found...), nil
+}
+
+// GetByUsername retrieves a user by their username, ignoring case
+func (r *MemoryUserRepository) GetByUsername(ctx context.Context, username string) (*models.User, error) {
+	return r.findOne(ctx, notDeleted(bson.M{"username": models.NormalizeUsername(username)}))
+}
+
+// GetByEmail retrieves a user by their email, ignoring case
+func (r *MemoryUserRepository) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	return r.findOne(ctx, notDeleted(bson.M{"email": strings.ToLower(email)}))
+}
+
+// Update sets fields on a user that has not been soft-deleted, bumping updated_at and the version
+// A new username or email is lowercased.
+func (r *MemoryUserRepository) Update(ctx context.Context, id string, updates map[string]interface{}) error {
+	normalizeUserUpdates(updates)
+
+	objectID, err := parseUserID(id)
+	if err != nil {
+		return err
+	}
+
+	matched, err := r.update(ctx, notDeleted(bson.M{"_id": objectID}), versionedUpdate(ctx, updates), true)
+	if err != nil {
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+	if matched == 0 {
+		return fmt.Errorf("user %w", interfaces.ErrNotFound)
+	}
+	return nil
+}
+
+// UpdateWithVersion behaves like Update but only applies the change when the stored
+// version equals expectedVersion, returning ErrVersionConflict otherwise
+func (r *MemoryUserRepository) UpdateWithVersion(ctx context.Context, id string, expectedVersion int64, updates map[string]interface{}) error {
+	normalizeUserUpdates(updates)
+
+	objectID, err := parseUserID(id)
+	if err != nil {
+		return err
+	}
+
+	filter := notDeleted(bson.M{"_id": objectID})
+	if expectedVersion == 0 {
+		filter[versionField] = bson.M{"$in": bson.A{int64(0), nil}}
+	} else {
+		filter[versionField] = expectedVersion
+	}
+
+	matched, err := r.update(ctx, filter, versionedUpdate(ctx, updates), true)
+	if err != nil {
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+	if matched == 0 {
+		if exists, err := r.ExistsByID(ctx, id); err != nil {
+			return fmt.Errorf("failed to update user: %w", err)
+		} else if exists {
+			return ErrVersionConflict
+		}
+		return fmt.Errorf("user %w", interfaces.ErrNotFound)
+	}
+	return nil
+}
+
+// Delete permanently deletes a user
+func (r *MemoryUserRepository) Delete(ctx context.Context, id string) error {
+	objectID, err := parseUserID(id)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("failed to delete user: %w", err)
+	}
+	if _, ok := r.docs[objectID]; !ok {
+		return fmt.Errorf("user %w", interfaces.ErrNotFound)
+	}
+	delete(r.docs, objectID)
+	return nil
+}
+
+// SoftDelete soft deletes a user by setting deleted_at timestamp and deactivating it
+func (r *MemoryUserRepository) SoftDelete(ctx context.Context, id string) error {
+	return r.Update(ctx, id, map[string]interface{}{
+		"deleted_at": utils.Now(),
+		"is_active":  false,
+	})
+}
+
+// Restore reverses a soft delete by clearing deleted_at and reactivating the user
+func (r *MemoryUserRepository) Restore(ctx context.Context, id string) error {
+	objectID, err := parseUserID(id)
+	if err != nil {
+		return err
+	}
+
+	filter := bson.M{
+		"_id":        objectID,
+		"deleted_at": bson.M{"$exists": true},
+	}
+
+	update := versionedUpdate(ctx, map[string]interface{}{"is_active": true})
+	update["$unset"] = bson.M{"deleted_at": ""}
+
+	matched, err := r.update(ctx, filter, update, true)
+	if err != nil {
+		return fmt.Errorf("failed to restore user: %w", err)
+	}
+	if matched == 0 {
+		return fmt.Errorf("deleted user %w", interfaces.ErrNotFound)
+	}
+	return nil
+}
+
+// GetByIDIncludingDeleted retrieves a user by their ID, including soft-deleted users
+func (r *MemoryUserRepository) GetByIDIncludingDeleted(ctx context.Context, id string) (*models.User, error) {
+	objectID, err := parseUserID(id)
+	if err != nil {
+		return nil, err
+	}
+	return r.findOne(ctx, bson.M{"_id": objectID})
+}
+
+// GetAll retrieves users with pagination, filtering, sorting and field selection
+func (r *MemoryUserRepository) GetAll(ctx context.Context, params *models.UsersQueryParams) ([]*models.User, int, error) {
+	params.SetDefaults()
+
+	docs, err := r.matching(ctx, notDeleted(NewUserFilter(params).buildFilter()))
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to find users: %w", err)
+	}
+
+	sortBy := buildSort(params.Sort)
+	sort.SliceStable(docs, func(i, j int) bool {
+		return lessBySort(docs[i], docs[j], sortBy)
+	})
+
+	total := len(docs)
+	start := (params.Page - 1) * params.Limit
+	if start > total {
+		start = total
+	}
+	end := start + params.Limit
+	if end > total {
+		end = total
+	}
+
+	projection := buildProjection(params.Fields)
+	var users []*models.User
+	for _, doc := range docs[start:end] {
+		user, err := decodeUser(projectDocument(doc, projection))
+		if err != nil {
+			return nil, 0, err
+		}
+		users = append(users, user)
+	}
+
+	return users, total, nil
+}
+
+// Search performs a case-insensitive substring search on users
+func (r *MemoryUserRepository) Search(ctx context.Context, query string, limit int) ([]*models.User, error) {
+	filter := notDeleted(NewUserFilter(&models.UsersQueryParams{Search: query}).buildFilter())
+
+	users, err := r.find(ctx, filter, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search users: %w", err)
+	}
+	return users, nil
+}
+
+// SearchText approximates a full-text search: users are ranked by how many query words
+// appear as whole words in their username, email, first or last name
+func (r *MemoryUserRepository) SearchText(ctx context.Context, query string, limit int) ([]*models.User, error) {
+	terms := textSearchWords(query)
+
+	docs, err := r.matching(ctx, notDeleted(bson.M{}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to search users: %w", err)
+	}
+
+	type scoredDoc struct {
+		doc   bson.M
+		score int
+	}
+	var scored []scoredDoc
+	for _, doc := range docs {
+		words := make(map[string]bool)
+		for _, field := range []string{"username", "email", "first_name", "last_name"} {
+			value, _ := doc[field].(string)
+			for _, word := range textSearchWords(value) {
+				words[word] = true
+			}
+		}
+
+		score := 0
+		for _, term := range terms {
+			if words[term] {
+				score++
+			}
+		}
+		if score > 0 {
+			scored = append(scored, scoredDoc{doc: doc, score: score})
+		}
+	}
+
+	sort.SliceStable(scored, func(i, j int) bool {
+		return scored[i].score > scored[j].score
+	})
+	if limit > 0 && len(scored) > limit {
+		scored = scored[:limit]
+	}
+
+	var users []*models.User
+	for _, s := range scored {
+		user, err := decodeUser(s.doc)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	return users, nil
+}
+
+// textSearchWordPattern splits text into words the way the text index tokenizes it, roughly
+var textSearchWordPattern = regexp.MustCompile(`[\p{L}\p{N}]+`)
+
+// textSearchWords returns the lower-cased words of s
+func textSearchWords(s string) []string {
+	return textSearchWordPattern.FindAllString(strings.ToLower(s), -1)
+}
+
+// StreamAll calls fn for every user that has not been soft-deleted, in _id order
+// fn runs without the repository lock held, so it may call back into the repository.
+func (r *MemoryUserRepository) StreamAll(ctx context.Context, fn func(*models.User) error) error {
+	docs, err := r.matching(ctx, notDeleted(bson.M{}))
+	if err != nil {
+		return fmt.Errorf("failed to find users: %w", err)
+	}
+
+	for _, doc := range docs {
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("cursor error: %w", err)
+		}
+		user, err := decodeUser(doc)
+		if err != nil {
+			return err
+		}
+		if err := fn(user); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ExistsByUsername checks if a username already exists, ignoring case
+func (r *MemoryUserRepository) ExistsByUsername(ctx context.Context, username string) (bool, error) {
+	return r.exists(ctx, notDeleted(bson.M{"username": models.NormalizeUsername(username)}))
+}
+
+// ExistsByEmail checks if an email already exists, ignoring case
+func (r *MemoryUserRepository) ExistsByEmail(ctx context.Context, email string) (bool, error) {
+	return r.exists(ctx, notDeleted(bson.M{"email": strings.ToLower(email)}))
+}
+
+// ExistsByUsernameOrEmail reports whether the username and the email are taken, ignoring case
+func (r *MemoryUserRepository) ExistsByUsernameOrEmail(ctx context.Context, username, email string) (bool, bool, error) {
+	username = models.NormalizeUsername(username)
+	matches, err := r.find(ctx, notDeleted(bson.M{
+		"$or": bson.A{
+			bson.M{"username": username},
+			bson.M{"email": strings.ToLower(email)},
+		},
+	}), 2)
+	if err != nil {
+		return false, false, err
+	}
+
+	usernameExists, emailExists := identityCollisions(matches, username, email)
+	return usernameExists, emailExists, nil
+}
+
+// ExistsByID checks if a user ID exists
+func (r *MemoryUserRepository) ExistsByID(ctx context.Context, id string) (bool, error) {
+	objectID, err := parseUserID(id)
+	if err != nil {
+		return false, err
+	}
+	return r.exists(ctx, notDeleted(bson.M{"_id": objectID}))
+}
+
+// GetByRole retrieves users by role
+func (r *MemoryUserRepository) GetByRole(ctx context.Context, role string, limit int) ([]*models.User, error) {
+	users, err := r.find(ctx, notDeleted(bson.M{"roles": bson.M{"$in": []string{role}}}), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get users by role: %w", err)
+	}
+	return users, nil
+}
+
+// CountByRole counts users by role
+func (r *MemoryUserRepository) CountByRole(ctx context.Context, role string) (int, error) {
+	docs, err := r.matching(ctx, notDeleted(bson.M{"roles": bson.M{"$in": []string{role}}}))
+	if err != nil {
+		return 0, fmt.Errorf("failed to count users by role: %w", err)
+	}
+	return len(docs), nil
+}
+
+// CountUsers counts the users matching the optional active status and role filters
+func (r *MemoryUserRepository) CountUsers(ctx context.Context, params *models.UserCountParams) (int, error) {
+	docs, err := r.matching(ctx, userCountFilter(params))
+	if err != nil {
+		return 0, fmt.Errorf("failed to count users: %w", err)
+	}
+	return len(docs), nil
+}
+
+// GetActiveUsers retrieves active users
+func (r *MemoryUserRepository) GetActiveUsers(ctx context.Context, limit int) ([]*models.User, error) {
+	users, err := r.find(ctx, notDeleted(bson.M{"is_active": true}), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get active users: %w", err)
+	}
+	return users, nil
+}
+
+// GetRecentlyLoggedIn retrieves the users who logged in most recently, most recent first
+func (r *MemoryUserRepository) GetRecentlyLoggedIn(ctx context.Context, limit int) ([]*models.User, error) {
+	docs, err := r.matching(ctx, notDeleted(bson.M{"last_login_at": bson.M{"$ne": nil}}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get recently logged in users: %w", err)
+	}
+
+	byLastLogin := bson.D{{Key: "last_login_at", Value: -1}}
+	sort.SliceStable(docs, func(i, j int) bool {
+		return lessBySort(docs[i], docs[j], byLastLogin)
+	})
+	if limit > 0 && len(docs) > limit {
+		docs = docs[:limit]
+	}
+
+	var users []*models.User
+	for _, doc := range docs {
+		user, err := decodeUser(doc)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	return users, nil
+}
+
+// GetInactiveUsers retrieves inactive users
+func (r *MemoryUserRepository) GetInactiveUsers(ctx context.Context, limit int) ([]*models.User, error) {
+	users, err := r.find(ctx, notDeleted(bson.M{"is_active": false}), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get inactive users: %w", err)
+	}
+	return users, nil
+}
+
+// CountActiveUsers counts active users
+func (r *MemoryUserRepository) CountActiveUsers(ctx context.Context) (int, error) {
+	docs, err := r.matching(ctx, notDeleted(bson.M{"is_active": true}))
+	if err != nil {
+		return 0, fmt.Errorf("failed to count active users: %w", err)
+	}
+	return len(docs), nil
+}
+
+// UpdateLastLogin updates user's last login timestamp without touching the version
+func (r *MemoryUserRepository) UpdateLastLogin(ctx context.Context, id string) error {
+	now := utils.Now()
+	return r.bookkeeping(ctx, id, "update last login", bson.M{
+		"$set": bson.M{
+			"last_login_at": now,
+			"updated_at":    now,
+		},
+	})
+}
+
+// UpdateLastActivity records that the user was just active without touching updated_at or the version
+func (r *MemoryUserRepository) UpdateLastActivity(ctx context.Context, id string) error {
+	return r.bookkeeping(ctx, id, "update last activity", bson.M{
+		"$set": bson.M{"last_activity_at": utils.Now()},
+	})
+}
+
+// IncrementLoginCount increments user's login count
+func (r *MemoryUserRepository) IncrementLoginCount(ctx context.Context, id string) error {
+	return r.bookkeeping(ctx, id, "increment login count", bson.M{
+		"$inc": bson.M{"login_count": 1},
+		"$set": bson.M{"updated_at": utils.Now()},
+	})
+}
+
+// IncrementField atomically adds by to one of the allowlisted counter fields
+func (r *MemoryUserRepository) IncrementField(ctx context.Context, id, field string, by int) error {
+	if err := checkIncrementable(field); err != nil {
+		return err
+	}
+	return r.bookkeeping(ctx, id, "increment "+field, bson.M{
+		"$inc": bson.M{field: by},
+	})
+}
+
+// IncrementTokenVersion bumps the user's token version, revoking every token issued before
+func (r *MemoryUserRepository) IncrementTokenVersion(ctx context.Context, id string) error {
+	return r.bookkeeping(ctx, id, "increment token version", bson.M{
+		"$inc": bson.M{"token_version": 1},
+		"$set": bson.M{"updated_at": utils.Now()},
+	})
+}
+
+// RecordFailedLogin records a failed login attempt
+func (r *MemoryUserRepository) RecordFailedLogin(ctx context.Context, id string) error {
+	now := utils.Now()
+	return r.bookkeeping(ctx, id, "record failed login", bson.M{
+		"$inc": bson.M{"failed_logins": 1},
+		"$set": bson.M{
+			"last_failed_at": now,
+			"updated_at":     now,
+		},
+	})
+}
+
+// ResetFailedLogins resets failed login count without touching the version
+func (r *MemoryUserRepository) ResetFailedLogins(ctx context.Context, id string) error {
+	return r.bookkeeping(ctx, id, "reset failed logins", bson.M{
+		"$set": bson.M{
+			"failed_logins":  0,
+			"last_failed_at": nil,
+			"updated_at":     utils.Now(),
+		},
+	})
+}
+
+// MarkAsVerified marks user as email verified without touching the version
+func (r *MemoryUserRepository) MarkAsVerified(ctx context.Context, id string) error {
+	now := utils.Now()
+	return r.bookkeeping(ctx, id, "mark user as verified", bson.M{
+		"$set": bson.M{
+			"is_verified":       true,
+			"email_verified_at": now,
+			"updated_at":        now,
+		},
+	})
+}
+
+// UpdateStatus updates user's active status
+func (r *MemoryUserRepository) UpdateStatus(ctx context.Context, id string, isActive bool) error {
+	return r.Update(ctx, id, map[string]interface{}{
+		"is_active": isActive,
+	})
+}
+
+// CreateMany inserts multiple users like an unordered insert: every user that can be inserted is,
+// and a *BulkInsertError reports the rest
+func (r *MemoryUserRepository) CreateMany(ctx context.Context, users []*models.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	failures := map[int]error{}
+	for i, user := range users {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		normalizeUserIdentity(user)
+		id := user.ID
+		if err := r.insert(ctx, user); err != nil {
+			user.ID = id
+			if strings.Contains(err.Error(), "duplicate key") {
+				failures[i] = duplicateKeyFieldError(err.Error())
+			} else {
+				failures[i] = fmt.Errorf("failed to create user: %w", err)
+			}
+		}
+	}
+
+	if len(failures) > 0 {
+		return &BulkInsertError{Inserted: len(users) - len(failures), Failures: failures}
+	}
+	return nil
+}
+
+// UpdateMany updates multiple users matching the filter
+func (r *MemoryUserRepository) UpdateMany(ctx context.Context, filter map[string]interface{}, updates map[string]interface{}) error {
+	// Ensure we don't update soft-deleted users
+	filter["deleted_at"] = bson.M{"$exists": false}
+
+	normalizeUserUpdates(updates)
+	if _, err := r.update(ctx, filter, versionedUpdate(ctx, updates), false); err != nil {
+		return fmt.Errorf("failed to update multiple users: %w", err)
+	}
+	return nil
+}
+
+// DeleteMany permanently deletes multiple users
+func (r *MemoryUserRepository) DeleteMany(ctx context.Context, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	objectIDs := make([]primitive.ObjectID, len(ids))
+	for i, id := range ids {
+		objectID, err := primitive.ObjectIDFromHex(id)
+		if err != nil {
+			return fmt.Errorf("invalid user ID format at index %d: %w", i, err)
+		}
+		objectIDs[i] = objectID
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("failed to delete multiple users: %w", err)
+	}
+	for _, objectID := range objectIDs {
+		delete(r.docs, objectID)
+	}
+	return nil
+}
+
+// SoftDeleteMany soft deletes and deactivates multiple users, skipping ones already deleted
+func (r *MemoryUserRepository) SoftDeleteMany(ctx context.Context, ids []string) (int64, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	objectIDs := make(bson.A, len(ids))
+	for i, id := range ids {
+		objectID, err := primitive.ObjectIDFromHex(id)
+		if err != nil {
+			return 0, fmt.Errorf("invalid user ID format at index %d: %w", i, err)
+		}
+		objectIDs[i] = objectID
+	}
+
+	update := versionedUpdate(ctx, map[string]interface{}{
+		"deleted_at": utils.Now(),
+		"is_active":  false,
+	})
+
+	matched, err := r.update(ctx, notDeleted(bson.M{"_id": bson.M{"$in": objectIDs}}), update, false)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete multiple users: %w", err)
+	}
+	return int64(matched), nil
+}
+
+// RoleDistribution counts the users holding each role; a user with several roles counts towards each
+func (r *MemoryUserRepository) RoleDistribution(ctx context.Context) (map[string]int, error) {
+	users, err := r.find(ctx, notDeleted(bson.M{}), 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get role distribution: %w", err)
+	}
+
+	distribution := make(map[string]int)
+	for _, user := range users {
+		for _, role := range user.Roles {
+			distribution[role]++
+		}
+	}
+	return distribution, nil
+}
+
+// GetUserStats returns user statistics, optionally restricted to users created within a date range
+func (r *MemoryUserRepository) GetUserStats(ctx context.Context, params *models.UserStatsParams) (*models.UserStatsResponse, error) {
+	filter := bson.M{}
+	if params.HasDateRange() {
+		createdAt := bson.M{}
+		if params.From != nil {
+			createdAt["$gte"] = *params.From
+		}
+		if params.To != nil {
+			createdAt["$lt"] = *params.To
+		}
+		filter["created_at"] = createdAt
+	}
+
+	users, err := r.find(ctx, notDeleted(filter), 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user stats: %w", err)
+	}
+
+	stats := &models.UserStatsResponse{ByRole: make(map[string]int)}
+	weekAgo := utils.Now().AddDate(0, 0, -7)
+	totalLogins := 0
+	for _, user := range users {
+		stats.TotalUsers++
+		if user.IsActive {
+			stats.ActiveUsers++
+		}
+		if user.IsVerified {
+			stats.VerifiedUsers++
+		}
+		for _, role := range user.Roles {
+			stats.ByRole[role]++
+		}
+		if !user.CreatedAt.Before(weekAgo) {
+			stats.NewUsersLast7Days++
+		}
+		totalLogins += user.LoginCount
+	}
+	if stats.TotalUsers > 0 {
+		stats.AvgLoginCount = float64(totalLogins) / float64(stats.TotalUsers)
+	}
+
+	return stats, nil
+}
+
+// CountByDay counts users created in [from, to) per UTC day, ordered by date
+func (r *MemoryUserRepository) CountByDay(ctx context.Context, from, to time.Time) ([]models.DayCount, error) {
+	users, err := r.find(ctx, notDeleted(bson.M{"created_at": bson.M{"$gte": from, "$lt": to}}), 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count users by day: %w", err)
+	}
+
+	byDay := make(map[string]int)
+	for _, user := range users {
+		byDay[user.CreatedAt.UTC().Format(models.DayFormat)]++
+	}
+
+	counts := make([]models.DayCount, 0, len(byDay))
+	for date, count := range byDay {
+		counts = append(counts, models.DayCount{Date: date, Count: count})
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		return counts[i].Date < counts[j].Date
+	})
+	return counts, nil
+}
+
+// GetUsersByDateRange retrieves users created within a date range
+func (r *MemoryUserRepository) GetUsersByDateRange(ctx context.Context, startDate, endDate string) ([]*models.User, error) {
+	start, err := time.Parse("2006-01-02", startDate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid start date format: %w", err)
+	}
+
+	end, err := time.Parse("2006-01-02", endDate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid end date format: %w", err)
+	}
+
+	// Add 24 hours to include the entire end date
+	end = end.Add(24 * time.Hour)
+
+	users, err := r.find(ctx, notDeleted(bson.M{"created_at": bson.M{"$gte": start, "$lt": end}}), 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get users by date range: %w", err)
+	}
+	return users, nil
+}
+
+// Cleanup permanently removes users soft-deleted more than retention ago
+func (r *MemoryUserRepository) Cleanup(ctx context.Context, retention time.Duration) (int64, error) {
+	cutoffDate := utils.Now().Add(-retention)
+	filter := bson.M{
+		"deleted_at": bson.M{
+			"$exists": true,
+			"$lt":     cutoffDate,
+		},
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var deleted int64
+	for id, doc := range r.docs {
+		matched, err := matchDocument(doc, filter)
+		if err != nil {
+			return 0, fmt.Errorf("failed to cleanup users: %w", err)
+		}
+		if matched {
+			delete(r.docs, id)
+			deleted++
+		}
+	}
+
+	return deleted, nil
+}
+
+// Ping always succeeds; there is no connection to check
+func (r *MemoryUserRepository) Ping(ctx context.Context) error {
+	return nil
+}
+
+// EnsureIndexes is a no-op; unique constraints are always enforced
+func (r *MemoryUserRepository) EnsureIndexes(ctx context.Context) error {
+	return nil
+}
+
+// DropIndexes is a no-op; unique constraints are always enforced
+func (r *MemoryUserRepository) DropIndexes(ctx context.Context) error {
+	return nil
+}
+
+// GetCollectionStats returns the number of stored users, including soft-deleted ones
+func (r *MemoryUserRepository) GetCollectionStats(ctx context.Context) (map[string]interface{}, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return map[string]interface{}{
+		"ns":    "users",
+		"count": len(r.docs),
+	}, nil
+}
+
+// Reset removes every user, for reuse between tests
+func (r *MemoryUserRepository) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.docs = make(map[primitive.ObjectID]bson.M)
+}
+
+// insert stores a new user, generating its ID when unset and enforcing the unique constraints
+// The caller must hold the write lock
+func (r *MemoryUserRepository) insert(ctx context.Context, user *models.User) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if user.ID.IsZero() {
+		user.SetID(primitive.NewObjectID())
+	}
+	if _, ok := r.docs[user.ID]; ok {
+		return duplicateKeyError("_id", user.ID.Hex())
+	}
+
+	doc, err := toDocument(user)
+	if err != nil {
+		return err
+	}
+	if err := r.checkUnique(doc, user.ID); err != nil {
+		return err
+	}
+
+	r.docs[user.ID] = doc
+	return nil
+}
+
+// update applies update to the users matching filter, to at most one when single is set,
+// and returns how many matched. Nothing is changed if any result would break a unique constraint.
+func (r *MemoryUserRepository) update(ctx context.Context, filter bson.M, update bson.M, single bool) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	docs, err := r.matchingLocked(filter)
+	if err != nil {
+		return 0, err
+	}
+	if single && len(docs) > 1 {
+		docs = docs[:1]
+	}
+
+	updated := make(map[primitive.ObjectID]bson.M, len(docs))
+	for _, doc := range docs {
+		next := make(bson.M, len(doc))
+		for key, value := range doc {
+			next[key] = value
+		}
+		if err := applyUpdate(next, update); err != nil {
+			return 0, err
+		}
+		updated[doc["_id"].(primitive.ObjectID)] = next
+	}
+
+	for id, doc := range updated {
+		if err := r.checkUnique(doc, id); err != nil {
+			return 0, err
+		}
+	}
+	for id, doc := range updated {
+		r.docs[id] = doc
+	}
+
+	return len(docs), nil
+}
+
+// bookkeeping applies a raw update to a user that has not been soft-deleted, without
+// versioning it, and reports a missing user as not found
+func (r *MemoryUserRepository) bookkeeping(ctx context.Context, id, action string, update bson.M) error {
+	objectID, err := parseUserID(id)
+	if err != nil {
+		return err
+	}
+
+	matched, err := r.update(ctx, notDeleted(bson.M{"_id": objectID}), update, true)
+	if err != nil {
+		return fmt.Errorf("failed to %s: %w", action, err)
+	}
+	if matched == 0 {
+		return fmt.Errorf("user %w", interfaces.ErrNotFound)
+	}
+	return nil
+}
+
+// checkUnique returns a duplicate key error when another stored user shares a unique field with doc
+// The caller must hold the lock
+func (r *MemoryUserRepository) checkUnique(doc bson.M, self primitive.ObjectID) error {
+	for id, other := range r.docs {
+		if id == self {
+			continue
+		}
+		for _, field := range uniqueUserFields {
+			if sameIdentity(doc[field], other[field]) {
+				return duplicateKeyError(field, doc[field])
+			}
+		}
+	}
+	return nil
+}
+
+// sameIdentity reports whether two unique field values collide under the case-insensitive index
+func sameIdentity(a, b interface{}) bool {
+	as, aok := a.(string)
+	bs, bok := b.(string)
+	if aok && bok {
+		return strings.EqualFold(as, bs)
+	}
+	return a != nil && equalValues(a, b)
+}
+
+// duplicateKeyError describes a unique constraint violation like MongoDB's E11000 error
+func duplicateKeyError(field string, value interface{}) error {
+	return fmt.Errorf("E11000 duplicate key error collection: users dup key: { %s: %q }", field, fmt.Sprint(value))
+}
+
+// findOne returns the first user matching filter in _id order
+func (r *MemoryUserRepository) findOne(ctx context.Context, filter bson.M) (*models.User, error) {
+	users, err := r.find(ctx, filter, 1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if len(users) == 0 {
+		return nil, fmt.Errorf("user %w", interfaces.ErrNotFound)
+	}
+	return users[0], nil
+}
+
+// find decodes the users matching filter in _id order; a non-positive limit means no limit
+func (r *MemoryUserRepository) find(ctx context.Context, filter bson.M, limit int) ([]*models.User, error) {
+	docs, err := r.matching(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	if limit > 0 && len(docs) > limit {
+		docs = docs[:limit]
+	}
+
+	var users []*models.User
+	for _, doc := range docs {
+		user, err := decodeUser(doc)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	return users, nil
+}
+
+// exists reports whether any user matches filter
+func (r *MemoryUserRepository) exists(ctx context.Context, filter bson.M) (bool, error) {
+	docs, err := r.matching(ctx, filter)
+	if err != nil {
+		return false, err
+	}
+	return len(docs) > 0, nil
+}
+
+// matching returns the stored documents matching filter in _id order
+// Documents are never modified in place, so they may be read after the lock is released.
+func (r *MemoryUserRepository) matching(ctx context.Context, filter bson.M) ([]bson.M, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return r.matchingLocked(filter)
+}
+
+// matchingLocked is matching for callers already holding the lock
+func (r *MemoryUserRepository) matchingLocked(filter bson.M) ([]bson.M, error) {
+	var docs []bson.M
+	for _, doc := range r.docs {
+		matched, err := matchDocument(doc, filter)
+		if err != nil {
+			return nil, err
+		}
+		if matched {
+			docs = append(docs, doc)
+		}
+	}
+
+	sort.Slice(docs, func(i, j int) bool {
+		return compareValues(docs[i]["_id"], docs[j]["_id"]) < 0
+	})
+	return docs, nil
+}
+
+// decodeUser converts a stored document into a new User
+func decodeUser(doc bson.M) (*models.User, error) {
+	var user models.User
+	if err := fromDocument(doc, &user); err != nil {
+		return nil, fmt.Errorf("failed to decode user: %w", err)
+	}
+	return &user, nil
+}
+
+// parseUserID parses a hex user ID
+func parseUserID(id string) (primitive.ObjectID, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return primitive.NilObjectID, fmt.Errorf("invalid user ID format: %w", err)
+	}
+	return objectID, nil
+}
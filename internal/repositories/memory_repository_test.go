@@ -0,0 +1,535 @@
+// internal/repositories/memory_repository_test.go
+package repositories
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+
+	"go-template/internal/interfaces"
+	"go-template/internal/models"
+	"go-template/internal/shared/utils"
+)
+
+func TestMemoryCreateMany(t *testing.T) {
+	tests := []struct {
+		name         string
+		existing     []string
+		usernames    []string
+		wantFailures []int
+	}{
+		{
+			name:      "all inserted",
+			usernames: []string{"alice", "bob"},
+		},
+		{
+			name:         "duplicate of an existing user",
+			existing:     []string{"bob"},
+			usernames:    []string{"alice", "BOB", "carol"},
+			wantFailures: []int{1},
+		},
+		{
+			name:         "duplicate within the batch",
+			usernames:    []string{"alice", "alice", "carol", "alice"},
+			wantFailures: []int{1, 3},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			repo := NewMemoryUserRepository()
+			for _, username := range tt.existing {
+				if err := repo.Create(ctx, models.NewTestUser(models.WithUsername(username))); err != nil {
+					t.Fatalf("Create() error = %v", err)
+				}
+			}
+
+			users := make([]*models.User, len(tt.usernames))
+			for i, username := range tt.usernames {
+				users[i] = models.NewTestUser(models.WithUsername(username))
+			}
+
+			err := repo.CreateMany(ctx, users)
+
+			failed := map[int]bool{}
+			if len(tt.wantFailures) == 0 {
+				if err != nil {
+					t.Fatalf("CreateMany() error = %v", err)
+				}
+			} else {
+				var bulkErr *BulkInsertError
+				if !errors.As(err, &bulkErr) {
+					t.Fatalf("CreateMany() error = %v, want *BulkInsertError", err)
+				}
+				if bulkErr.Inserted != len(users)-len(tt.wantFailures) {
+					t.Errorf("Inserted = %d, want %d", bulkErr.Inserted, len(users)-len(tt.wantFailures))
+				}
+				for _, i := range tt.wantFailures {
+					failed[i] = true
+					if !errors.Is(bulkErr.Failures[i], interfaces.ErrAlreadyExists) {
+						t.Errorf("failure %d = %v, want ErrAlreadyExists", i, bulkErr.Failures[i])
+					}
+				}
+				if len(bulkErr.Failures) != len(tt.wantFailures) {
+					t.Errorf("got %d failures, want %d", len(bulkErr.Failures), len(tt.wantFailures))
+				}
+			}
+
+			// Items after a failure are still inserted, unlike an ordered insert
+			for i, user := range users {
+				if failed[i] {
+					continue
+				}
+				if _, err := repo.GetByID(ctx, user.GetIDString()); err != nil {
+					t.Errorf("user %d (%s) not inserted: %v", i, user.Username, err)
+				}
+			}
+		})
+	}
+}
+
+func TestDuplicateKeyFieldError(t *testing.T) {
+	tests := []struct {
+		message string
+		want    string
+	}{
+		{
+			message: `E11000 duplicate key error collection: app.users index: username_1 dup key: { username: "alice" }`,
+			want:    "username already exists",
+		},
+		{
+			message: `E11000 duplicate key error collection: app.users index: email_1 dup key: { email: "alice@example.com" }`,
+			want:    "email already exists",
+		},
+		{
+			message: `E11000 duplicate key error collection: app.users index: _id_ dup key: { _id: ObjectId('507f1f77bcf86cd799439011') }`,
+			want:    "user already exists",
+		},
+		{
+			message: "E11000 duplicate key error",
+			want:    "user already exists",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			err := duplicateKeyFieldError(tt.message)
+			if !errors.Is(err, interfaces.ErrAlreadyExists) {
+				t.Errorf("duplicateKeyFieldError() = %v, want ErrAlreadyExists", err)
+			}
+			if err.Error() != tt.want {
+				t.Errorf("duplicateKeyFieldError() = %q, want %q", err.Error(), tt.want)
+			}
+		})
+	}
+}
+
+func TestMemorySoftDeleteAndRestore(t *testing.T) {
+	tests := []struct {
+		name       string
+		softDelete bool
+		wantErr    error
+	}{
+		{name: "deleted user reappears", softDelete: true},
+		{name: "user that is not deleted", wantErr: interfaces.ErrNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			repo := NewMemoryUserRepository()
+			user := models.NewTestUser()
+			if err := repo.Create(ctx, user); err != nil {
+				t.Fatalf("Create() error = %v", err)
+			}
+			id := user.GetIDString()
+
+			if tt.softDelete {
+				if err := repo.SoftDelete(ctx, id); err != nil {
+					t.Fatalf("SoftDelete() error = %v", err)
+				}
+				if _, err := repo.GetByID(ctx, id); !errors.Is(err, interfaces.ErrNotFound) {
+					t.Fatalf("GetByID() after delete error = %v, want ErrNotFound", err)
+				}
+				deleted, err := repo.GetByIDIncludingDeleted(ctx, id)
+				if err != nil {
+					t.Fatalf("GetByIDIncludingDeleted() error = %v", err)
+				}
+				if !deleted.IsDeleted() || deleted.IsActive {
+					t.Errorf("deleted user deleted = %v, active = %v", deleted.IsDeleted(), deleted.IsActive)
+				}
+			}
+
+			if err := repo.Restore(ctx, id); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Restore() error = %v, want %v", err, tt.wantErr)
+			}
+
+			restored, err := repo.GetByID(ctx, id)
+			if err != nil {
+				t.Fatalf("GetByID() after restore error = %v", err)
+			}
+			if restored.IsDeleted() || !restored.IsActive {
+				t.Errorf("restored user deleted = %v, active = %v", restored.IsDeleted(), restored.IsActive)
+			}
+		})
+	}
+}
+
+// statsSeed describes a user seeded for the stats tests
+type statsSeed struct {
+	roles     []string
+	createdAt time.Time
+	active    bool
+	verified  bool
+	logins    int
+	deleted   bool
+}
+
+// seedStatsUsers stores users across roles and signup dates, relative to now
+func seedStatsUsers(t *testing.T, repo UserRepositoryInterface, seeds []statsSeed) {
+	t.Helper()
+	ctx := context.Background()
+	for _, seed := range seeds {
+		user := models.NewTestUser(models.WithRoles(seed.roles...), models.WithActive(seed.active), models.WithVerified(seed.verified))
+		user.CreatedAt = seed.createdAt
+		user.LoginCount = seed.logins
+		if err := repo.Create(ctx, user); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+		if seed.deleted {
+			if err := repo.SoftDelete(ctx, user.GetIDString()); err != nil {
+				t.Fatalf("SoftDelete() error = %v", err)
+			}
+		}
+	}
+}
+
+func TestMemoryGetUserStats(t *testing.T) {
+	now := time.Date(2026, 3, 15, 12, 0, 0, 0, time.UTC)
+	utils.SetClock(utils.NewFixedClock(now))
+	defer utils.SetClock(nil)
+
+	day := func(month time.Month, d int) time.Time { return time.Date(2026, month, d, 9, 0, 0, 0, time.UTC) }
+	ptr := func(t time.Time) *time.Time { return &t }
+
+	seeds := []statsSeed{
+		{roles: []string{models.RoleUser}, createdAt: day(3, 14), active: true, verified: true, logins: 4},
+		{roles: []string{models.RoleUser, models.RoleAdmin}, createdAt: day(3, 1), active: true, logins: 2},
+		{roles: []string{models.RoleMod}, createdAt: day(2, 1), verified: true},
+		{roles: []string{models.RoleUser}, createdAt: day(3, 10), active: true, deleted: true},
+	}
+
+	tests := []struct {
+		name   string
+		params *models.UserStatsParams
+		want   models.UserStatsResponse
+	}{
+		{
+			name:   "all users",
+			params: &models.UserStatsParams{},
+			want: models.UserStatsResponse{
+				TotalUsers: 3, ActiveUsers: 2, VerifiedUsers: 2, AvgLoginCount: 2,
+				ByRole:            map[string]int{models.RoleUser: 2, models.RoleAdmin: 1, models.RoleMod: 1},
+				NewUsersLast7Days: 1,
+			},
+		},
+		{
+			name:   "date range",
+			params: &models.UserStatsParams{From: ptr(day(3, 1)), To: ptr(day(3, 16))},
+			want: models.UserStatsResponse{
+				TotalUsers: 2, ActiveUsers: 2, VerifiedUsers: 1, AvgLoginCount: 3,
+				ByRole:            map[string]int{models.RoleUser: 2, models.RoleAdmin: 1},
+				NewUsersLast7Days: 1,
+			},
+		},
+		{
+			name:   "from only",
+			params: &models.UserStatsParams{From: ptr(day(3, 2))},
+			want: models.UserStatsResponse{
+				TotalUsers: 1, ActiveUsers: 1, VerifiedUsers: 1, AvgLoginCount: 4,
+				ByRole:            map[string]int{models.RoleUser: 1},
+				NewUsersLast7Days: 1,
+			},
+		},
+		{
+			name:   "to only, exclusive",
+			params: &models.UserStatsParams{To: ptr(day(3, 1))},
+			want: models.UserStatsResponse{
+				TotalUsers: 1, VerifiedUsers: 1,
+				ByRole: map[string]int{models.RoleMod: 1},
+			},
+		},
+		{
+			name:   "empty range",
+			params: &models.UserStatsParams{From: ptr(day(1, 1)), To: ptr(day(1, 2))},
+			want:   models.UserStatsResponse{ByRole: map[string]int{}},
+		},
+	}
+
+	repo := NewMemoryUserRepository()
+	seedStatsUsers(t, repo, seeds)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stats, err := repo.GetUserStats(context.Background(), tt.params)
+			if err != nil {
+				t.Fatalf("GetUserStats() error = %v", err)
+			}
+			if !reflect.DeepEqual(*stats, tt.want) {
+				t.Errorf("GetUserStats() = %+v, want %+v", *stats, tt.want)
+			}
+		})
+	}
+}
+
+func TestMemorySearchRegexVsText(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryUserRepository()
+	seed := []struct {
+		username, first, last string
+		deleted               bool
+	}{
+		{username: "alice", first: "Alice", last: "Smith"},
+		{username: "bob", first: "Bob", last: "Alison"},
+		{username: "carol", first: "Carol", last: "Jones"},
+		{username: "alicia", first: "Alicia", last: "Smith", deleted: true},
+	}
+	for _, s := range seed {
+		user := models.NewTestUser(models.WithUsername(s.username), models.WithName(s.first, s.last))
+		if err := repo.Create(ctx, user); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+		if s.deleted {
+			if err := repo.SoftDelete(ctx, user.GetIDString()); err != nil {
+				t.Fatalf("SoftDelete() error = %v", err)
+			}
+		}
+	}
+
+	tests := []struct {
+		name      string
+		query     string
+		wantRegex []string
+		wantText  []string // in relevance order
+	}{
+		{name: "substring only matches regex", query: "ali", wantRegex: []string{"alice", "bob"}},
+		{name: "whole word matches both", query: "smith", wantRegex: []string{"alice"}, wantText: []string{"alice"}},
+		{name: "case-insensitive", query: "JONES", wantRegex: []string{"carol"}, wantText: []string{"carol"}},
+		{name: "several words rank by matches", query: "alice smith jones", wantText: []string{"alice", "carol"}},
+		{name: "regex metacharacters are literal", query: "a.*", wantRegex: nil},
+		{name: "no match", query: "zed"},
+	}
+
+	usernames := func(users []*models.User) []string {
+		var names []string
+		for _, user := range users {
+			names = append(names, user.Username)
+		}
+		return names
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			regex, err := repo.Search(ctx, tt.query, 10)
+			if err != nil {
+				t.Fatalf("Search() error = %v", err)
+			}
+			text, err := repo.SearchText(ctx, tt.query, 10)
+			if err != nil {
+				t.Fatalf("SearchText() error = %v", err)
+			}
+
+			gotRegex := usernames(regex)
+			slices.Sort(gotRegex)
+			if !slices.Equal(gotRegex, tt.wantRegex) {
+				t.Errorf("Search(%q) = %v, want %v", tt.query, gotRegex, tt.wantRegex)
+			}
+			if got := usernames(text); !slices.Equal(got, tt.wantText) {
+				t.Errorf("SearchText(%q) = %v, want %v", tt.query, got, tt.wantText)
+			}
+		})
+	}
+}
+
+// bookkeepingWrites are the repository writes that must not bump a user's version
+var bookkeepingWrites = map[string]func(ctx context.Context, repo UserRepositoryInterface, id string) error{
+	"UpdateLastLogin": func(ctx context.Context, repo UserRepositoryInterface, id string) error {
+		return repo.UpdateLastLogin(ctx, id)
+	},
+	"UpdateLastActivity": func(ctx context.Context, repo UserRepositoryInterface, id string) error {
+		return repo.UpdateLastActivity(ctx, id)
+	},
+	"IncrementLoginCount": func(ctx context.Context, repo UserRepositoryInterface, id string) error {
+		return repo.IncrementLoginCount(ctx, id)
+	},
+	"RecordFailedLogin": func(ctx context.Context, repo UserRepositoryInterface, id string) error {
+		return repo.RecordFailedLogin(ctx, id)
+	},
+	"ResetFailedLogins": func(ctx context.Context, repo UserRepositoryInterface, id string) error {
+		return repo.ResetFailedLogins(ctx, id)
+	},
+	"MarkAsVerified": func(ctx context.Context, repo UserRepositoryInterface, id string) error {
+		return repo.MarkAsVerified(ctx, id)
+	},
+	"IncrementField": func(ctx context.Context, repo UserRepositoryInterface, id string) error {
+		return repo.IncrementField(ctx, id, "profile_views", 1)
+	},
+	"IncrementTokenVersion": func(ctx context.Context, repo UserRepositoryInterface, id string) error {
+		return repo.IncrementTokenVersion(ctx, id)
+	},
+}
+
+func TestMemoryOptimisticConcurrency(t *testing.T) {
+	type testCase struct {
+		name string
+		// between runs after both writers read the user and before the second one writes
+		between      func(ctx context.Context, repo UserRepositoryInterface, id string) error
+		wantConflict bool
+	}
+
+	tests := []testCase{
+		{name: "no other write"},
+		{
+			name: "another writer updated first",
+			between: func(ctx context.Context, repo UserRepositoryInterface, id string) error {
+				return repo.UpdateWithVersion(ctx, id, 0, map[string]interface{}{"first_name": "First"})
+			},
+			wantConflict: true,
+		},
+		{
+			name: "unversioned update",
+			between: func(ctx context.Context, repo UserRepositoryInterface, id string) error {
+				return repo.Update(ctx, id, map[string]interface{}{"bio": "changed"})
+			},
+			wantConflict: true,
+		},
+	}
+	for name, fn := range bookkeepingWrites {
+		tests = append(tests, testCase{name: "bookkeeping " + name, between: fn})
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			repo := NewMemoryUserRepository()
+			user := models.NewTestUser()
+			if err := repo.Create(ctx, user); err != nil {
+				t.Fatalf("Create() error = %v", err)
+			}
+			id := user.GetIDString()
+
+			// Both writers read the same base version
+			base, err := repo.GetByID(ctx, id)
+			if err != nil {
+				t.Fatalf("GetByID() error = %v", err)
+			}
+			if tt.between != nil {
+				if err := tt.between(ctx, repo, id); err != nil {
+					t.Fatalf("concurrent write error = %v", err)
+				}
+			}
+
+			err = repo.UpdateWithVersion(ctx, id, base.Version, map[string]interface{}{"first_name": "Second"})
+			if gotConflict := errors.Is(err, ErrVersionConflict); gotConflict != tt.wantConflict {
+				t.Fatalf("UpdateWithVersion() error = %v, want conflict: %v", err, tt.wantConflict)
+			}
+			if !tt.wantConflict && err != nil {
+				t.Fatalf("UpdateWithVersion() error = %v", err)
+			}
+
+			stored, err := repo.GetByID(ctx, id)
+			if err != nil {
+				t.Fatalf("GetByID() error = %v", err)
+			}
+			if !tt.wantConflict && (stored.FirstName != "Second" || stored.Version != base.Version+1) {
+				t.Errorf("stored first_name = %q version %d, want %q version %d", stored.FirstName, stored.Version, "Second", base.Version+1)
+			}
+			if tt.wantConflict && stored.FirstName == "Second" {
+				t.Error("the stale update was applied")
+			}
+		})
+	}
+}
+
+func TestMemoryBookkeepingKeepsVersion(t *testing.T) {
+	for name, fn := range bookkeepingWrites {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			repo := NewMemoryUserRepository()
+			user := models.NewTestUser()
+			if err := repo.Create(ctx, user); err != nil {
+				t.Fatalf("Create() error = %v", err)
+			}
+
+			if err := fn(ctx, repo, user.GetIDString()); err != nil {
+				t.Fatalf("%s() error = %v", name, err)
+			}
+			stored, err := repo.GetByID(ctx, user.GetIDString())
+			if err != nil {
+				t.Fatalf("GetByID() error = %v", err)
+			}
+			if stored.Version != user.Version {
+				t.Errorf("Version = %d after %s, want %d", stored.Version, name, user.Version)
+			}
+
+			if err := fn(ctx, repo, "507f1f77bcf86cd799439011"); !errors.Is(err, interfaces.ErrNotFound) {
+				t.Errorf("%s(missing user) error = %v, want %v", name, err, interfaces.ErrNotFound)
+			}
+		})
+	}
+}
+
+func TestMemoryStreamAll(t *testing.T) {
+	errStop := errors.New("stop")
+
+	tests := []struct {
+		name    string
+		stopAt  string
+		want    []string
+		wantErr error
+	}{
+		{name: "every live user in creation order", want: []string{"alice", "bob", "carol"}},
+		{name: "callback error stops the stream", stopAt: "bob", want: []string{"alice", "bob"}, wantErr: errStop},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			repo := NewMemoryUserRepository()
+			for _, username := range []string{"alice", "bob", "dave", "carol"} {
+				user := models.NewTestUser(models.WithUsername(username), models.WithEmail(username+"@example.com"))
+				if err := repo.Create(ctx, user); err != nil {
+					t.Fatalf("Create() error = %v", err)
+				}
+				if username == "dave" {
+					if err := repo.SoftDelete(ctx, user.GetIDString()); err != nil {
+						t.Fatalf("SoftDelete() error = %v", err)
+					}
+				}
+			}
+
+			var got []string
+			err := repo.StreamAll(ctx, func(user *models.User) error {
+				got = append(got, user.Username)
+				// The repository lock is not held, so calling back in must not deadlock
+				if _, err := repo.GetByID(ctx, user.GetIDString()); err != nil {
+					return err
+				}
+				if user.Username == tt.stopAt {
+					return errStop
+				}
+				return nil
+			})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("StreamAll() error = %v, want %v", err, tt.wantErr)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("streamed %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
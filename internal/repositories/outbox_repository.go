@@ -0,0 +1,99 @@
+// internal/repositories/outbox_repository.go
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"go-template/internal/models"
+)
+
+// OutboxCollection is the collection holding events awaiting delivery
+const OutboxCollection = "outbox"
+
+// OutboxRepository implements OutboxRepositoryInterface using MongoDB
+type OutboxRepository struct {
+	*MongoRepository[models.OutboxEvent]
+	collection *mongo.Collection
+}
+
+// NewOutboxRepository creates a new OutboxRepository instance
+func NewOutboxRepository(db *mongo.Database) OutboxRepositoryInterface {
+	// Indexes are managed by the migration runner (internal/database/migrations)
+	collection := db.Collection(OutboxCollection)
+	return &OutboxRepository{
+		MongoRepository: NewMongoRepository[models.OutboxEvent](collection, "outbox event"),
+		collection:      collection,
+	}
+}
+
+// Enqueue stores a new pending event
+func (r *OutboxRepository) Enqueue(ctx context.Context, event *models.OutboxEvent) error {
+	return r.Create(ctx, event)
+}
+
+// ClaimDue leases up to limit due pending events, oldest first
+// Each event is claimed with an atomic find-and-modify that pushes next_attempt_at past the
+// lease, so concurrent dispatchers never claim the same event and an event whose dispatcher
+// crashed mid-delivery becomes due again once the lease runs out.
+func (r *OutboxRepository) ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]*models.OutboxEvent, error) {
+	var claimed []*models.OutboxEvent
+	for len(claimed) < limit {
+		now := time.Now().UTC()
+		filter := notDeleted(bson.M{
+			"status":          models.OutboxStatusPending,
+			"next_attempt_at": bson.M{"$lte": now},
+		})
+		update := bson.M{
+			"$set": bson.M{
+				"next_attempt_at": now.Add(lease),
+				"updated_at":      now,
+			},
+			"$inc": bson.M{"attempts": 1},
+		}
+		opts := options.FindOneAndUpdate().
+			SetSort(bson.D{{Key: "next_attempt_at", Value: 1}}).
+			SetReturnDocument(options.After)
+
+		var event models.OutboxEvent
+		err := r.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&event)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			break
+		}
+		if err != nil {
+			return claimed, fmt.Errorf("failed to claim outbox events: %w", err)
+		}
+		claimed = append(claimed, &event)
+	}
+
+	return claimed, nil
+}
+
+// MarkSent records a successful delivery
+func (r *OutboxRepository) MarkSent(ctx context.Context, id string) error {
+	return r.Update(ctx, id, map[string]interface{}{
+		"status":  models.OutboxStatusSent,
+		"sent_at": time.Now().UTC(),
+	})
+}
+
+// MarkFailed records a failed delivery attempt
+// The event is retried at retryAt, or abandoned as dead when retryAt is nil.
+func (r *OutboxRepository) MarkFailed(ctx context.Context, id string, lastError string, retryAt *time.Time) error {
+	updates := map[string]interface{}{
+		"last_error": lastError,
+	}
+	if retryAt != nil {
+		updates["next_attempt_at"] = retryAt.UTC()
+	} else {
+		updates["status"] = models.OutboxStatusDead
+	}
+
+	return r.Update(ctx, id, updates)
+}
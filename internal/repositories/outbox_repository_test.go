@@ -0,0 +1,180 @@
+// internal/repositories/outbox_repository_test.go
+package repositories
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+
+	"go-template/internal/models"
+)
+
+func TestOutboxRepositoryEnqueue(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("stores a pending event", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateSuccessResponse())
+
+		event := models.NewOutboxEvent("evt-1", "user.created", `{"id":"42"}`, time.Now().UTC())
+		if err := NewOutboxRepository(mt.DB).Enqueue(context.Background(), event); err != nil {
+			mt.Fatalf("Enqueue() error = %v", err)
+		}
+
+		started := mt.GetStartedEvent()
+		if started.CommandName != "insert" || started.Command.Lookup("insert").StringValue() != OutboxCollection {
+			mt.Fatalf("command = %s %v, want an insert into %s", started.CommandName, started.Command, OutboxCollection)
+		}
+		docs, _ := started.Command.Lookup("documents").Array().Values()
+		doc := docs[0].Document()
+		if doc.Lookup("status").StringValue() != models.OutboxStatusPending || doc.Lookup("payload").StringValue() != `{"id":"42"}` {
+			mt.Errorf("inserted %v, want the pending event", doc)
+		}
+	})
+}
+
+func TestOutboxRepositoryClaimDue(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	eventDoc := func(eventID string) bson.D {
+		return bson.D{
+			{Key: "_id", Value: primitive.NewObjectID()},
+			{Key: "event_id", Value: eventID},
+			{Key: "status", Value: models.OutboxStatusPending},
+			{Key: "attempts", Value: int32(1)},
+		}
+	}
+
+	tests := []struct {
+		name        string
+		limit       int
+		available   []string
+		wantClaimed []string
+		wantCalls   int
+	}{
+		{name: "nothing due", limit: 5, wantCalls: 1},
+		{name: "claims until none are left", limit: 5, available: []string{"evt-1", "evt-2"}, wantClaimed: []string{"evt-1", "evt-2"}, wantCalls: 3},
+		{name: "stops at the limit", limit: 2, available: []string{"evt-1", "evt-2", "evt-3"}, wantClaimed: []string{"evt-1", "evt-2"}, wantCalls: 2},
+	}
+
+	for _, tt := range tests {
+		mt.Run(tt.name, func(mt *mtest.T) {
+			var replies []bson.D
+			for _, id := range tt.available {
+				replies = append(replies, mtest.CreateSuccessResponse(bson.E{Key: "value", Value: eventDoc(id)}))
+			}
+			replies = append(replies, mtest.CreateSuccessResponse(bson.E{Key: "value", Value: nil}))
+			mt.AddMockResponses(replies...)
+
+			before := time.Now().UTC()
+			claimed, err := NewOutboxRepository(mt.DB).ClaimDue(context.Background(), tt.limit, time.Minute)
+			if err != nil {
+				mt.Fatalf("ClaimDue() error = %v", err)
+			}
+			var ids []string
+			for _, event := range claimed {
+				ids = append(ids, event.EventID)
+			}
+			if !slices.Equal(ids, tt.wantClaimed) {
+				mt.Errorf("claimed %v, want %v", ids, tt.wantClaimed)
+			}
+
+			events := mt.GetAllStartedEvents()
+			if len(events) != tt.wantCalls {
+				mt.Fatalf("sent %d commands, want %d", len(events), tt.wantCalls)
+			}
+			cmd := events[0].Command
+			if events[0].CommandName != "findAndModify" {
+				mt.Fatalf("command = %s, want findAndModify", events[0].CommandName)
+			}
+			query := cmd.Lookup("query").Document()
+			if query.Lookup("status").StringValue() != models.OutboxStatusPending {
+				mt.Errorf("query %v does not select pending events", query)
+			}
+			if due, ok := query.Lookup("next_attempt_at", "$lte").TimeOK(); !ok || due.Before(before.Truncate(time.Millisecond)) {
+				mt.Errorf("query %v does not select due events", query)
+			}
+			if _, err := query.LookupErr("deleted_at", "$exists"); err != nil {
+				mt.Errorf("query %v does not exclude deleted events", query)
+			}
+			update := cmd.Lookup("update").Document()
+			if update.Lookup("$inc", "attempts").AsInt64() != 1 {
+				mt.Errorf("update %v does not count the attempt", update)
+			}
+			if next, ok := update.Lookup("$set", "next_attempt_at").TimeOK(); !ok || next.Before(before.Add(time.Minute-time.Second)) {
+				mt.Errorf("update %v does not lease the event", update)
+			}
+		})
+	}
+}
+
+func TestOutboxRepositoryMark(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	retryAt := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		mark        func(repo OutboxRepositoryInterface, id string) error
+		wantStatus  string // empty when the status must not change
+		wantError   string
+		wantRetryAt bool
+	}{
+		{
+			name:       "sent",
+			mark:       func(repo OutboxRepositoryInterface, id string) error { return repo.MarkSent(context.Background(), id) },
+			wantStatus: models.OutboxStatusSent,
+		},
+		{
+			name: "failed with a retry",
+			mark: func(repo OutboxRepositoryInterface, id string) error {
+				return repo.MarkFailed(context.Background(), id, "status 503", &retryAt)
+			},
+			wantError:   "status 503",
+			wantRetryAt: true,
+		},
+		{
+			name: "failed for good",
+			mark: func(repo OutboxRepositoryInterface, id string) error {
+				return repo.MarkFailed(context.Background(), id, "status 400", nil)
+			},
+			wantStatus: models.OutboxStatusDead,
+			wantError:  "status 400",
+		},
+	}
+
+	for _, tt := range tests {
+		mt.Run(tt.name, func(mt *mtest.T) {
+			mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}))
+
+			if err := tt.mark(NewOutboxRepository(mt.DB), primitive.NewObjectID().Hex()); err != nil {
+				mt.Fatalf("mark error = %v", err)
+			}
+
+			_, update := sentUpdate(mt)
+			set := update.Lookup("$set").Document()
+			status, hasStatus := set.Lookup("status").StringValueOK()
+			if tt.wantStatus == "" && hasStatus {
+				mt.Errorf("status set to %q, want it unchanged", status)
+			}
+			if tt.wantStatus != "" && status != tt.wantStatus {
+				mt.Errorf("status = %q, want %q", status, tt.wantStatus)
+			}
+			if got, _ := set.Lookup("last_error").StringValueOK(); got != tt.wantError {
+				mt.Errorf("last_error = %q, want %q", got, tt.wantError)
+			}
+			next, hasNext := set.Lookup("next_attempt_at").TimeOK()
+			if hasNext != tt.wantRetryAt || (hasNext && !next.Equal(retryAt)) {
+				mt.Errorf("next_attempt_at = %v, want retry at %v: %v", next, tt.wantRetryAt, set)
+			}
+			if tt.wantStatus == models.OutboxStatusSent {
+				if _, ok := set.Lookup("sent_at").TimeOK(); !ok {
+					mt.Errorf("update %v does not record sent_at", set)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,770 @@
+// internal/repositories/suite_test.go
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"go-template/internal/database/migrations"
+	"go-template/internal/interfaces"
+	"go-template/internal/models"
+	"go-template/internal/shared/logtest"
+	"go-template/internal/shared/utils"
+)
+
+// repositoryBackend opens an empty UserRepositoryInterface for one contract test
+type repositoryBackend struct {
+	name string
+	open func(t *testing.T) UserRepositoryInterface
+}
+
+// repositoryBackends returns the repositories the contract suite runs against.
+// The Mongo repository joins when MONGO_TEST_URL points at a disposable server; each test
+// then gets its own database, migrated like production and dropped afterwards.
+func repositoryBackends() []repositoryBackend {
+	backends := []repositoryBackend{{
+		name: "memory",
+		open: func(t *testing.T) UserRepositoryInterface { return NewMemoryUserRepository() },
+	}}
+
+	url := os.Getenv("MONGO_TEST_URL")
+	if url == "" {
+		return backends
+	}
+	return append(backends, repositoryBackend{
+		name: "mongo",
+		open: func(t *testing.T) UserRepositoryInterface {
+			t.Helper()
+			ctx := context.Background()
+			client, err := mongo.Connect(ctx, options.Client().ApplyURI(url))
+			if err != nil {
+				t.Fatalf("mongo.Connect() error = %v", err)
+			}
+			db := client.Database(fmt.Sprintf("go_template_test_%s", primitive.NewObjectID().Hex()))
+			t.Cleanup(func() {
+				db.Drop(ctx)
+				client.Disconnect(ctx)
+			})
+			if err := migrations.Migrate(ctx, db); err != nil {
+				t.Fatalf("Migrate() error = %v", err)
+			}
+			return NewUserRepository(db, logtest.New())
+		},
+	})
+}
+
+// seedContractUsers creates alice, bob and carol with increasing creation times
+func seedContractUsers(t *testing.T, repo UserRepositoryInterface) []*models.User {
+	t.Helper()
+	created := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	var users []*models.User
+	for i, u := range []struct{ username, lastName string }{
+		{"alice", "Smith"}, {"bob", "Jones"}, {"carol", "Smith"},
+	} {
+		user := models.NewTestUser(models.WithUsername(u.username), models.WithName("Test", u.lastName))
+		user.CreatedAt = created.Add(time.Duration(i) * time.Hour)
+		if err := repo.Create(context.Background(), user); err != nil {
+			t.Fatalf("Create(%s) error = %v", u.username, err)
+		}
+		users = append(users, user)
+	}
+	return users
+}
+
+// usernames returns the usernames of users in order
+func usernames(users []*models.User) []string {
+	names := make([]string, len(users))
+	for i, user := range users {
+		names[i] = user.Username
+	}
+	return names
+}
+
+func TestUserRepositoryContract(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		run  func(t *testing.T, repo UserRepositoryInterface, users []*models.User)
+	}{
+		{
+			name: "lookups ignore case",
+			run: func(t *testing.T, repo UserRepositoryInterface, users []*models.User) {
+				byUsername, err := repo.GetByUsername(ctx, "ALICE")
+				if err != nil || byUsername.ID != users[0].ID {
+					t.Errorf("GetByUsername(ALICE) = %v, %v, want alice", byUsername, err)
+				}
+				byEmail, err := repo.GetByEmail(ctx, "Bob@Example.com")
+				if err != nil || byEmail.ID != users[1].ID {
+					t.Errorf("GetByEmail(Bob@Example.com) = %v, %v, want bob", byEmail, err)
+				}
+			},
+		},
+		{
+			name: "cleanup purges users deleted before the retention boundary",
+			run: func(t *testing.T, repo UserRepositoryInterface, users []*models.User) {
+				now := time.Date(2026, 4, 1, 12, 0, 0, 0, time.UTC)
+				clock := utils.NewFixedClock(now.Add(-30*24*time.Hour - time.Second))
+				utils.SetClock(clock)
+				t.Cleanup(func() { utils.SetClock(nil) })
+
+				// alice is deleted just past the retention, bob exactly at it, carol within it
+				for _, user := range users {
+					if err := repo.SoftDelete(ctx, user.GetIDString()); err != nil {
+						t.Fatalf("SoftDelete(%s) error = %v", user.Username, err)
+					}
+					clock.Advance(time.Second)
+				}
+				clock.Set(now)
+
+				purged, err := repo.Cleanup(ctx, 30*24*time.Hour)
+				if err != nil {
+					t.Fatalf("Cleanup() error = %v", err)
+				}
+				if purged != 1 {
+					t.Errorf("Cleanup() purged %d users, want 1", purged)
+				}
+				for i, wantKept := range []bool{false, true, true} {
+					_, err := repo.GetByIDIncludingDeleted(ctx, users[i].GetIDString())
+					if kept := err == nil; kept != wantKept {
+						t.Errorf("%s kept = %v (err %v), want %v", users[i].Username, kept, err, wantKept)
+					}
+				}
+			},
+		},
+		{
+			name: "cleanup ignores users that are not deleted",
+			run: func(t *testing.T, repo UserRepositoryInterface, users []*models.User) {
+				purged, err := repo.Cleanup(ctx, time.Nanosecond)
+				if err != nil || purged != 0 {
+					t.Errorf("Cleanup() = %d, %v, want nothing purged", purged, err)
+				}
+			},
+		},
+		{
+			name: "soft delete many skips users already deleted",
+			run: func(t *testing.T, repo UserRepositoryInterface, users []*models.User) {
+				if err := repo.SoftDelete(ctx, users[0].GetIDString()); err != nil {
+					t.Fatalf("SoftDelete() error = %v", err)
+				}
+				deleted, err := repo.SoftDeleteMany(ctx, []string{users[0].GetIDString(), users[1].GetIDString()})
+				if err != nil || deleted != 1 {
+					t.Fatalf("SoftDeleteMany() = %d, %v, want 1", deleted, err)
+				}
+				stored, err := repo.GetByIDIncludingDeleted(ctx, users[1].GetIDString())
+				if err != nil || stored.DeletedAt == nil || stored.IsActive {
+					t.Errorf("bob = %+v, %v, want deleted and inactive", stored, err)
+				}
+				if _, err := repo.GetByID(ctx, users[2].GetIDString()); err != nil {
+					t.Errorf("GetByID(carol) error = %v, want her untouched", err)
+				}
+				if _, err := repo.SoftDeleteMany(ctx, []string{"nope"}); err == nil {
+					t.Error("SoftDeleteMany(invalid id) error = nil")
+				}
+			},
+		},
+		{
+			name: "timestamps come from the clock",
+			run: func(t *testing.T, repo UserRepositoryInterface, users []*models.User) {
+				created := time.Date(2026, 5, 1, 8, 0, 0, 0, time.UTC)
+				clock := utils.NewFixedClock(created)
+				utils.SetClock(clock)
+				t.Cleanup(func() { utils.SetClock(nil) })
+
+				user := models.NewTestUser(models.WithUsername("dave"))
+				if err := repo.Create(ctx, user); err != nil {
+					t.Fatalf("Create() error = %v", err)
+				}
+				id := user.GetIDString()
+
+				updated := created.Add(time.Hour)
+				clock.Set(updated)
+				if err := repo.Update(ctx, id, map[string]interface{}{"first_name": "Dave"}); err != nil {
+					t.Fatalf("Update() error = %v", err)
+				}
+				stored, err := repo.GetByID(ctx, id)
+				if err != nil {
+					t.Fatalf("GetByID() error = %v", err)
+				}
+				if !stored.CreatedAt.Equal(created) || !stored.UpdatedAt.Equal(updated) {
+					t.Errorf("created_at = %v, updated_at = %v, want %v and %v", stored.CreatedAt, stored.UpdatedAt, created, updated)
+				}
+
+				deleted := updated.Add(time.Hour)
+				clock.Set(deleted)
+				if err := repo.SoftDelete(ctx, id); err != nil {
+					t.Fatalf("SoftDelete() error = %v", err)
+				}
+				stored, err = repo.GetByIDIncludingDeleted(ctx, id)
+				if err != nil {
+					t.Fatalf("GetByIDIncludingDeleted() error = %v", err)
+				}
+				if stored.DeletedAt == nil || !stored.DeletedAt.Equal(deleted) || !stored.UpdatedAt.Equal(deleted) {
+					t.Errorf("deleted_at = %v, updated_at = %v, want both %v", stored.DeletedAt, stored.UpdatedAt, deleted)
+				}
+			},
+		},
+		{
+			name: "count by day groups signups per UTC day",
+			run: func(t *testing.T, repo UserRepositoryInterface, users []*models.User) {
+				// alice, bob and carol were created on 2026-01-01 at 00:00, 01:00 and 02:00
+				for i, createdAt := range []time.Time{
+					time.Date(2026, 1, 1, 23, 59, 0, 0, time.UTC),
+					time.Date(2026, 1, 3, 0, 0, 0, 0, time.UTC),
+					time.Date(2026, 1, 4, 0, 0, 0, 0, time.UTC), // outside the range
+				} {
+					user := models.NewTestUser(models.WithUsername(fmt.Sprintf("late%d", i)))
+					user.CreatedAt = createdAt
+					if err := repo.Create(ctx, user); err != nil {
+						t.Fatalf("Create() error = %v", err)
+					}
+				}
+				if err := repo.SoftDelete(ctx, users[2].GetIDString()); err != nil {
+					t.Fatalf("SoftDelete() error = %v", err)
+				}
+
+				got, err := repo.CountByDay(ctx, time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 1, 4, 0, 0, 0, 0, time.UTC))
+				if err != nil {
+					t.Fatalf("CountByDay() error = %v", err)
+				}
+				want := []models.DayCount{{Date: "2026-01-01", Count: 3}, {Date: "2026-01-03", Count: 1}}
+				if !slices.Equal(got, want) {
+					t.Errorf("CountByDay() = %v, want %v", got, want)
+				}
+			},
+		},
+		{
+			name: "verification filter combines with the active filter",
+			run: func(t *testing.T, repo UserRepositoryInterface, users []*models.User) {
+				// alice: verified and active, bob: unverified and active, carol: verified and inactive
+				if err := repo.MarkAsVerified(ctx, users[0].GetIDString()); err != nil {
+					t.Fatalf("MarkAsVerified() error = %v", err)
+				}
+				if err := repo.MarkAsVerified(ctx, users[2].GetIDString()); err != nil {
+					t.Fatalf("MarkAsVerified() error = %v", err)
+				}
+				if err := repo.UpdateStatus(ctx, users[2].GetIDString(), false); err != nil {
+					t.Fatalf("UpdateStatus() error = %v", err)
+				}
+
+				yes, no := true, false
+				for i, tc := range []struct {
+					verified, active *bool
+					want             []string
+				}{
+					{verified: &yes, want: []string{"alice", "carol"}},
+					{verified: &no, want: []string{"bob"}},
+					{verified: &yes, active: &yes, want: []string{"alice"}},
+					{verified: &yes, active: &no, want: []string{"carol"}},
+					{verified: &no, active: &no, want: nil},
+				} {
+					params := &models.UsersQueryParams{Page: 1, Limit: 10, IsVerified: tc.verified, IsActive: tc.active, Sort: []models.SortField{{Field: "username"}}}
+					got, total, err := repo.GetAll(ctx, params)
+					if err != nil {
+						t.Fatalf("GetAll() error = %v", err)
+					}
+					var names []string
+					for _, user := range got {
+						names = append(names, user.Username)
+					}
+					if !slices.Equal(names, tc.want) || total != len(tc.want) {
+						t.Errorf("case %d: GetAll() = %v (total %d), want %v", i, names, total, tc.want)
+					}
+				}
+			},
+		},
+		{
+			name: "dotted updates merge into embedded documents",
+			run: func(t *testing.T, repo UserRepositoryInterface, users []*models.User) {
+				id := users[0].GetIDString()
+				if err := repo.Update(ctx, id, map[string]interface{}{"preferences": map[string]interface{}{"theme": "dark"}}); err != nil {
+					t.Fatalf("Update() error = %v", err)
+				}
+				if err := repo.Update(ctx, id, map[string]interface{}{"preferences.notifications.email": false}); err != nil {
+					t.Fatalf("Update() error = %v", err)
+				}
+				if err := repo.Update(ctx, id, map[string]interface{}{"preferences.notifications.push": true}); err != nil {
+					t.Fatalf("Update() error = %v", err)
+				}
+
+				got, err := repo.GetByID(ctx, id)
+				if err != nil {
+					t.Fatalf("GetByID() error = %v", err)
+				}
+				if got.Preferences["theme"] != "dark" {
+					t.Errorf("theme = %v, want the unrelated preference kept", got.Preferences["theme"])
+				}
+				if prefs := got.GetNotificationPreferences(); prefs != (models.NotificationPreferences{Email: false, Push: true, Marketing: false}) {
+					t.Errorf("notifications = %+v, want email off and push on", prefs)
+				}
+				if other, _ := repo.GetByID(ctx, users[1].GetIDString()); len(other.Preferences) != 0 {
+					t.Errorf("other user preferences = %v, want untouched", other.Preferences)
+				}
+			},
+		},
+		{
+			name: "username or email existence reports each collision",
+			run: func(t *testing.T, repo UserRepositoryInterface, users []*models.User) {
+				if err := repo.SoftDelete(ctx, users[2].GetIDString()); err != nil {
+					t.Fatalf("SoftDelete() error = %v", err)
+				}
+
+				for _, tc := range []struct {
+					username, email         string
+					wantUsername, wantEmail bool
+				}{
+					{username: "dave", email: "dave@example.com"},
+					{username: "Alice", email: "dave@example.com", wantUsername: true},
+					{username: "dave", email: "BOB@example.com", wantEmail: true},
+					{username: "alice", email: "alice@example.com", wantUsername: true, wantEmail: true},
+					{username: "alice", email: "bob@example.com", wantUsername: true, wantEmail: true},
+					{username: "carol", email: "carol@example.com"},
+				} {
+					usernameExists, emailExists, err := repo.ExistsByUsernameOrEmail(ctx, tc.username, tc.email)
+					if err != nil {
+						t.Fatalf("ExistsByUsernameOrEmail(%s, %s) error = %v", tc.username, tc.email, err)
+					}
+					if usernameExists != tc.wantUsername || emailExists != tc.wantEmail {
+						t.Errorf("ExistsByUsernameOrEmail(%s, %s) = %v, %v, want %v, %v", tc.username, tc.email, usernameExists, emailExists, tc.wantUsername, tc.wantEmail)
+					}
+				}
+			},
+		},
+		{
+			name: "token version increments for live users only",
+			run: func(t *testing.T, repo UserRepositoryInterface, users []*models.User) {
+				id := users[0].GetIDString()
+				for i := 0; i < 2; i++ {
+					if err := repo.IncrementTokenVersion(ctx, id); err != nil {
+						t.Fatalf("IncrementTokenVersion() error = %v", err)
+					}
+				}
+
+				got, err := repo.GetByID(ctx, id)
+				if err != nil {
+					t.Fatalf("GetByID() error = %v", err)
+				}
+				if got.TokenVersion != users[0].TokenVersion+2 {
+					t.Errorf("TokenVersion = %d, want %d", got.TokenVersion, users[0].TokenVersion+2)
+				}
+				if got.Version != users[0].Version {
+					t.Errorf("Version = %d, want %d; revoking tokens is not a profile change", got.Version, users[0].Version)
+				}
+				if other, _ := repo.GetByID(ctx, users[1].GetIDString()); other.TokenVersion != users[1].TokenVersion {
+					t.Errorf("other user TokenVersion = %d, want untouched", other.TokenVersion)
+				}
+
+				if err := repo.SoftDelete(ctx, users[2].GetIDString()); err != nil {
+					t.Fatalf("SoftDelete() error = %v", err)
+				}
+				if err := repo.IncrementTokenVersion(ctx, users[2].GetIDString()); !errors.Is(err, interfaces.ErrNotFound) {
+					t.Errorf("IncrementTokenVersion(deleted user) error = %v, want ErrNotFound", err)
+				}
+				if err := repo.IncrementTokenVersion(ctx, primitive.NewObjectID().Hex()); !errors.Is(err, interfaces.ErrNotFound) {
+					t.Errorf("IncrementTokenVersion(missing user) error = %v, want ErrNotFound", err)
+				}
+			},
+		},
+		{
+			name: "role distribution counts users with several roles towards each",
+			run: func(t *testing.T, repo UserRepositoryInterface, users []*models.User) {
+				// alice is an admin and moderator as well as a user; carol's roles stop counting once deleted
+				if err := repo.Update(ctx, users[0].GetIDString(), map[string]interface{}{"roles": []string{models.RoleUser, models.RoleAdmin, models.RoleMod}}); err != nil {
+					t.Fatalf("Update(alice) error = %v", err)
+				}
+				if err := repo.Update(ctx, users[2].GetIDString(), map[string]interface{}{"roles": []string{models.RoleUser, models.RoleMod}}); err != nil {
+					t.Fatalf("Update(carol) error = %v", err)
+				}
+
+				distribution := func() map[string]int {
+					t.Helper()
+					got, err := repo.RoleDistribution(ctx)
+					if err != nil {
+						t.Fatalf("RoleDistribution() error = %v", err)
+					}
+					return got
+				}
+				if got, want := distribution(), map[string]int{models.RoleUser: 3, models.RoleAdmin: 1, models.RoleMod: 2}; !maps.Equal(got, want) {
+					t.Errorf("RoleDistribution() = %v, want %v", got, want)
+				}
+
+				if err := repo.SoftDelete(ctx, users[2].GetIDString()); err != nil {
+					t.Fatalf("SoftDelete() error = %v", err)
+				}
+				if got, want := distribution(), map[string]int{models.RoleUser: 2, models.RoleAdmin: 1, models.RoleMod: 1}; !maps.Equal(got, want) {
+					t.Errorf("RoleDistribution() after deleting carol = %v, want %v", got, want)
+				}
+
+				// The stats facet shares the aggregation, so both agree
+				stats, err := repo.GetUserStats(ctx, &models.UserStatsParams{})
+				if err != nil {
+					t.Fatalf("GetUserStats() error = %v", err)
+				}
+				if got := distribution(); !maps.Equal(stats.ByRole, got) {
+					t.Errorf("GetUserStats() ByRole = %v, want the role distribution %v", stats.ByRole, got)
+				}
+
+				for _, user := range users[:2] {
+					if err := repo.SoftDelete(ctx, user.GetIDString()); err != nil {
+						t.Fatalf("SoftDelete() error = %v", err)
+					}
+				}
+				if got := distribution(); got == nil || len(got) != 0 {
+					t.Errorf("RoleDistribution() with no users = %#v, want an empty map", got)
+				}
+			},
+		},
+		{
+			name: "count users by status and role",
+			run: func(t *testing.T, repo UserRepositoryInterface, users []*models.User) {
+				// alice is an admin, bob is inactive and carol is deleted
+				if err := repo.Update(ctx, users[0].GetIDString(), map[string]interface{}{"roles": []string{models.RoleUser, models.RoleAdmin}}); err != nil {
+					t.Fatalf("Update(alice) error = %v", err)
+				}
+				if err := repo.Update(ctx, users[1].GetIDString(), map[string]interface{}{"is_active": false}); err != nil {
+					t.Fatalf("Update(bob) error = %v", err)
+				}
+				if err := repo.SoftDelete(ctx, users[2].GetIDString()); err != nil {
+					t.Fatalf("SoftDelete(carol) error = %v", err)
+				}
+
+				active, inactive := true, false
+				for _, tc := range []struct {
+					params *models.UserCountParams
+					want   int
+				}{
+					{params: nil, want: 2},
+					{params: &models.UserCountParams{}, want: 2},
+					{params: &models.UserCountParams{IsActive: &active}, want: 1},
+					{params: &models.UserCountParams{IsActive: &inactive}, want: 1},
+					{params: &models.UserCountParams{Role: models.RoleUser}, want: 2},
+					{params: &models.UserCountParams{Role: models.RoleAdmin}, want: 1},
+					{params: &models.UserCountParams{Role: models.RoleMod}, want: 0},
+					{params: &models.UserCountParams{IsActive: &inactive, Role: models.RoleAdmin}, want: 0},
+				} {
+					got, err := repo.CountUsers(ctx, tc.params)
+					if err != nil {
+						t.Fatalf("CountUsers(%+v) error = %v", tc.params, err)
+					}
+					if got != tc.want {
+						t.Errorf("CountUsers(%+v) = %d, want %d", tc.params, got, tc.want)
+					}
+				}
+			},
+		},
+		{
+			name: "increment field changes allowlisted counters only",
+			run: func(t *testing.T, repo UserRepositoryInterface, users []*models.User) {
+				id := users[0].GetIDString()
+				for _, by := range []int{1, 2} {
+					if err := repo.IncrementField(ctx, id, "profile_views", by); err != nil {
+						t.Fatalf("IncrementField(profile_views, %d) error = %v", by, err)
+					}
+				}
+				if err := repo.IncrementField(ctx, id, "login_count", 1); err != nil {
+					t.Fatalf("IncrementField(login_count) error = %v", err)
+				}
+
+				for _, field := range []string{"failed_logins", "token_version", "version", "username", "profile_views.$", ""} {
+					if err := repo.IncrementField(ctx, id, field, 1); !errors.Is(err, interfaces.ErrValidation) {
+						t.Errorf("IncrementField(%q) error = %v, want ErrValidation", field, err)
+					}
+				}
+
+				got, err := repo.GetByID(ctx, id)
+				if err != nil {
+					t.Fatalf("GetByID() error = %v", err)
+				}
+				if got.ProfileViews != 3 || got.LoginCount != users[0].LoginCount+1 {
+					t.Errorf("profile_views = %d, login_count = %d, want 3 and %d", got.ProfileViews, got.LoginCount, users[0].LoginCount+1)
+				}
+				if got.FailedLogins != users[0].FailedLogins || got.TokenVersion != users[0].TokenVersion || got.Version != users[0].Version {
+					t.Errorf("failed_logins = %d, token_version = %d, version = %d, want them unchanged", got.FailedLogins, got.TokenVersion, got.Version)
+				}
+
+				if err := repo.SoftDelete(ctx, users[1].GetIDString()); err != nil {
+					t.Fatalf("SoftDelete() error = %v", err)
+				}
+				if err := repo.IncrementField(ctx, users[1].GetIDString(), "profile_views", 1); !errors.Is(err, interfaces.ErrNotFound) {
+					t.Errorf("IncrementField(deleted user) error = %v, want ErrNotFound", err)
+				}
+				if err := repo.IncrementField(ctx, primitive.NewObjectID().Hex(), "profile_views", 1); !errors.Is(err, interfaces.ErrNotFound) {
+					t.Errorf("IncrementField(missing user) error = %v, want ErrNotFound", err)
+				}
+			},
+		},
+		{
+			name: "usernames match in any normalization form",
+			run: func(t *testing.T, repo UserRepositoryInterface, users []*models.User) {
+				// Stored precomposed, whichever form it was created with
+				user := models.NewTestUser(models.WithUsername("Jose\u0301"))
+				if err := repo.Create(ctx, user); err != nil {
+					t.Fatalf("Create() error = %v", err)
+				}
+				if user.Username != "jos\u00e9" {
+					t.Errorf("stored username = %q, want the NFC form %q", user.Username, "jos\u00e9")
+				}
+
+				for _, spelling := range []string{"jos\u00e9", "JOS\u00c9", "jose\u0301", "JOSE\u0301"} {
+					got, err := repo.GetByUsername(ctx, spelling)
+					if err != nil || got.GetIDString() != user.GetIDString() {
+						t.Errorf("GetByUsername(%q) = %v, %v, want the user", spelling, got, err)
+					}
+					if exists, err := repo.ExistsByUsername(ctx, spelling); err != nil || !exists {
+						t.Errorf("ExistsByUsername(%q) = %v, %v, want true", spelling, exists, err)
+					}
+					if taken, _, err := repo.ExistsByUsernameOrEmail(ctx, spelling, "nobody@example.com"); err != nil || !taken {
+						t.Errorf("ExistsByUsernameOrEmail(%q) = %v, %v, want the username taken", spelling, taken, err)
+					}
+				}
+
+				duplicate := models.NewTestUser(models.WithUsername("JOS\u00c9"))
+				if err := repo.Create(ctx, duplicate); !errors.Is(err, interfaces.ErrAlreadyExists) {
+					t.Errorf("Create(duplicate) error = %v, want ErrAlreadyExists", err)
+				}
+			},
+		},
+		{
+			name: "recently logged in users come most recent first",
+			run: func(t *testing.T, repo UserRepositoryInterface, users []*models.User) {
+				// carol logged in last and bob first; alice never did
+				loggedIn := time.Date(2026, 2, 1, 12, 0, 0, 0, time.UTC)
+				for i, at := range map[int]time.Time{1: loggedIn.Add(-time.Hour), 2: loggedIn} {
+					if err := repo.Update(ctx, users[i].GetIDString(), map[string]interface{}{"last_login_at": at}); err != nil {
+						t.Fatalf("Update(%s) error = %v", users[i].Username, err)
+					}
+				}
+
+				recent := func(limit int) []string {
+					t.Helper()
+					got, err := repo.GetRecentlyLoggedIn(ctx, limit)
+					if err != nil {
+						t.Fatalf("GetRecentlyLoggedIn(%d) error = %v", limit, err)
+					}
+					return usernames(got)
+				}
+				if got, want := recent(10), []string{"carol", "bob"}; !slices.Equal(got, want) {
+					t.Errorf("GetRecentlyLoggedIn(10) = %v, want %v", got, want)
+				}
+				if got, want := recent(1), []string{"carol"}; !slices.Equal(got, want) {
+					t.Errorf("GetRecentlyLoggedIn(1) = %v, want %v", got, want)
+				}
+
+				if err := repo.SoftDelete(ctx, users[2].GetIDString()); err != nil {
+					t.Fatalf("SoftDelete() error = %v", err)
+				}
+				if got, want := recent(10), []string{"bob"}; !slices.Equal(got, want) {
+					t.Errorf("GetRecentlyLoggedIn(10) after deleting carol = %v, want %v", got, want)
+				}
+			},
+		},
+		{
+			name: "missing users are not found",
+			run: func(t *testing.T, repo UserRepositoryInterface, users []*models.User) {
+				if _, err := repo.GetByID(ctx, primitive.NewObjectID().Hex()); !errors.Is(err, interfaces.ErrNotFound) {
+					t.Errorf("GetByID() error = %v, want ErrNotFound", err)
+				}
+				if _, err := repo.GetByUsername(ctx, "nobody"); !errors.Is(err, interfaces.ErrNotFound) {
+					t.Errorf("GetByUsername() error = %v, want ErrNotFound", err)
+				}
+			},
+		},
+		{
+			name: "username is unique regardless of case",
+			run: func(t *testing.T, repo UserRepositoryInterface, users []*models.User) {
+				dup := models.NewTestUser(models.WithUsername("Alice"), models.WithEmail("other@example.com"))
+				if err := repo.Create(ctx, dup); !errors.Is(err, interfaces.ErrAlreadyExists) {
+					t.Errorf("Create() error = %v, want ErrAlreadyExists", err)
+				}
+			},
+		},
+		{
+			name: "email is unique regardless of case",
+			run: func(t *testing.T, repo UserRepositoryInterface, users []*models.User) {
+				dup := models.NewTestUser(models.WithUsername("alice2"), models.WithEmail("ALICE@example.com"))
+				if err := repo.Create(ctx, dup); !errors.Is(err, interfaces.ErrAlreadyExists) {
+					t.Errorf("Create() error = %v, want ErrAlreadyExists", err)
+				}
+			},
+		},
+		{
+			name: "soft-deleted users are hidden",
+			run: func(t *testing.T, repo UserRepositoryInterface, users []*models.User) {
+				id := users[1].GetIDString()
+				if err := repo.SoftDelete(ctx, id); err != nil {
+					t.Fatalf("SoftDelete() error = %v", err)
+				}
+				if _, err := repo.GetByID(ctx, id); !errors.Is(err, interfaces.ErrNotFound) {
+					t.Errorf("GetByID() error = %v, want ErrNotFound", err)
+				}
+				if exists, _ := repo.ExistsByID(ctx, id); exists {
+					t.Error("ExistsByID() = true for a deleted user")
+				}
+				if _, err := repo.GetByIDIncludingDeleted(ctx, id); err != nil {
+					t.Errorf("GetByIDIncludingDeleted() error = %v", err)
+				}
+				listed, total, err := repo.GetAll(ctx, &models.UsersQueryParams{})
+				if err != nil {
+					t.Fatalf("GetAll() error = %v", err)
+				}
+				if total != 2 || slices.Contains(usernames(listed), "bob") {
+					t.Errorf("GetAll() = %v (total %d), want bob excluded", usernames(listed), total)
+				}
+			},
+		},
+		{
+			name: "pagination",
+			run: func(t *testing.T, repo UserRepositoryInterface, users []*models.User) {
+				params := &models.UsersQueryParams{Page: 2, Limit: 2, Sort: []models.SortField{{Field: "username"}}}
+				listed, total, err := repo.GetAll(ctx, params)
+				if err != nil {
+					t.Fatalf("GetAll() error = %v", err)
+				}
+				if total != 3 || !slices.Equal(usernames(listed), []string{"carol"}) {
+					t.Errorf("GetAll() = %v (total %d), want [carol] of 3", usernames(listed), total)
+				}
+			},
+		},
+		{
+			name: "sorting by several fields",
+			run: func(t *testing.T, repo UserRepositoryInterface, users []*models.User) {
+				params := &models.UsersQueryParams{Sort: models.ParseSortFields("last_name,-created_at", "asc")}
+				listed, _, err := repo.GetAll(ctx, params)
+				if err != nil {
+					t.Fatalf("GetAll() error = %v", err)
+				}
+				if got, want := usernames(listed), []string{"bob", "carol", "alice"}; !slices.Equal(got, want) {
+					t.Errorf("GetAll() order = %v, want %v", got, want)
+				}
+			},
+		},
+		{
+			name: "search filter",
+			run: func(t *testing.T, repo UserRepositoryInterface, users []*models.User) {
+				params := &models.UsersQueryParams{Search: "SMITH", Sort: []models.SortField{{Field: "username"}}}
+				listed, total, err := repo.GetAll(ctx, params)
+				if err != nil {
+					t.Fatalf("GetAll() error = %v", err)
+				}
+				if total != 2 || !slices.Equal(usernames(listed), []string{"alice", "carol"}) {
+					t.Errorf("GetAll() = %v (total %d), want [alice carol]", usernames(listed), total)
+				}
+				found, err := repo.Search(ctx, "bo", 10)
+				if err != nil || !slices.Equal(usernames(found), []string{"bob"}) {
+					t.Errorf("Search(bo) = %v, %v, want [bob]", usernames(found), err)
+				}
+			},
+		},
+		{
+			name: "search matches terms literally",
+			run: func(t *testing.T, repo UserRepositoryInterface, users []*models.User) {
+				for i, lastName := range []string{"O.Neil", "OxNeil", "Smith (Jr.)"} {
+					if err := repo.Update(ctx, users[i].GetIDString(), map[string]interface{}{"last_name": lastName}); err != nil {
+						t.Fatalf("Update(%s) error = %v", users[i].Username, err)
+					}
+				}
+
+				tests := []struct {
+					query string
+					want  []string
+				}{
+					{query: "o.neil", want: []string{"alice"}},
+					{query: "O.N", want: []string{"alice"}},
+					{query: "(JR.)", want: []string{"carol"}},
+					{query: ".*"},
+					{query: "(a+)+$"},
+					{query: "["},
+					{query: `\`},
+					{query: "^alice$"},
+				}
+				for _, tt := range tests {
+					found, err := repo.Search(ctx, tt.query, 10)
+					if err != nil {
+						t.Errorf("Search(%q) error = %v", tt.query, err)
+					} else if got := usernames(found); !slices.Equal(got, tt.want) {
+						t.Errorf("Search(%q) = %v, want %v", tt.query, got, tt.want)
+					}
+
+					listed, total, err := repo.GetAll(ctx, &models.UsersQueryParams{Search: tt.query, Sort: []models.SortField{{Field: "username"}}})
+					if err != nil {
+						t.Errorf("GetAll(search %q) error = %v", tt.query, err)
+					} else if got := usernames(listed); total != len(tt.want) || !slices.Equal(got, tt.want) {
+						t.Errorf("GetAll(search %q) = %v (total %d), want %v", tt.query, got, total, tt.want)
+					}
+				}
+			},
+		},
+		{
+			name: "existence checks ignore case",
+			run: func(t *testing.T, repo UserRepositoryInterface, users []*models.User) {
+				if exists, err := repo.ExistsByUsername(ctx, "JohnDoe"); err != nil || exists {
+					t.Errorf("ExistsByUsername(JohnDoe) = %v, %v before creation", exists, err)
+				}
+				if exists, _ := repo.ExistsByUsername(ctx, "Alice"); !exists {
+					t.Error("ExistsByUsername(Alice) = false, want true")
+				}
+				if exists, _ := repo.ExistsByEmail(ctx, "ALICE@EXAMPLE.COM"); !exists {
+					t.Error("ExistsByEmail(ALICE@EXAMPLE.COM) = false, want true")
+				}
+				usernameExists, emailExists, err := repo.ExistsByUsernameOrEmail(ctx, "BOB", "Carol@Example.com")
+				if err != nil || !usernameExists || !emailExists {
+					t.Errorf("ExistsByUsernameOrEmail() = %v, %v, %v, want both found", usernameExists, emailExists, err)
+				}
+			},
+		},
+		{
+			name: "update stores username and email lowercased",
+			run: func(t *testing.T, repo UserRepositoryInterface, users []*models.User) {
+				id := users[0].GetIDString()
+				if err := repo.Update(ctx, id, map[string]interface{}{"username": "JohnDoe", "email": "John.Doe@Example.com"}); err != nil {
+					t.Fatalf("Update() error = %v", err)
+				}
+				stored, err := repo.GetByID(ctx, id)
+				if err != nil {
+					t.Fatalf("GetByID() error = %v", err)
+				}
+				if stored.Username != "johndoe" || stored.Email != "john.doe@example.com" {
+					t.Errorf("stored %s <%s>, want both lowercased", stored.Username, stored.Email)
+				}
+				if exists, _ := repo.ExistsByUsername(ctx, "JOHNDOE"); !exists {
+					t.Error("ExistsByUsername(JOHNDOE) = false after the rename")
+				}
+			},
+		},
+		{
+			name: "update bumps the version",
+			run: func(t *testing.T, repo UserRepositoryInterface, users []*models.User) {
+				id := users[0].GetIDString()
+				if err := repo.Update(ctx, id, map[string]interface{}{"first_name": "Alicia"}); err != nil {
+					t.Fatalf("Update() error = %v", err)
+				}
+				stored, err := repo.GetByID(ctx, id)
+				if err != nil {
+					t.Fatalf("GetByID() error = %v", err)
+				}
+				if stored.FirstName != "Alicia" || stored.Version != users[0].Version+1 {
+					t.Errorf("first_name = %q, version = %d, want Alicia at %d", stored.FirstName, stored.Version, users[0].Version+1)
+				}
+			},
+		},
+	}
+
+	for _, backend := range repositoryBackends() {
+		for _, tt := range tests {
+			t.Run(backend.name+"/"+tt.name, func(t *testing.T) {
+				repo := backend.open(t)
+				tt.run(t, repo, seedContractUsers(t, repo))
+			})
+		}
+	}
+}
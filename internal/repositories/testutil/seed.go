@@ -0,0 +1,55 @@
+// Package testutil provides helpers for populating a database with realistic data
+// in tests and local development
+package testutil
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"strings"
+
+	"go-template/internal/models"
+	"go-template/internal/repositories"
+)
+
+// seedBatchSize is the number of users inserted per round trip
+const seedBatchSize = 500
+
+var (
+	firstNames = []string{"Ada", "Alan", "Grace", "Linus", "Margaret", "Dennis", "Barbara", "Ken", "Radia", "Edsger"}
+	lastNames  = []string{"Lovelace", "Turing", "Hopper", "Torvalds", "Hamilton", "Ritchie", "Liskov", "Thompson", "Perlman", "Dijkstra"}
+)
+
+// SeedUsers inserts n randomized, valid users built with models.NewTestUser and returns them
+// with their database IDs set. Every user's password is models.TestUserPassword.
+// opts are applied to every user after the random defaults.
+func SeedUsers(ctx context.Context, repo repositories.UserRepositoryInterface, n int, opts ...models.TestUserOption) ([]*models.User, error) {
+	users := make([]*models.User, 0, n)
+	for i := 0; i < n; i++ {
+		users = append(users, RandomUser(opts...))
+	}
+
+	for start := 0; start < len(users); start += seedBatchSize {
+		end := min(start+seedBatchSize, len(users))
+		if err := repo.CreateMany(ctx, users[start:end]); err != nil {
+			return nil, fmt.Errorf("failed to seed users %d-%d: %w", start, end-1, err)
+		}
+	}
+
+	return users, nil
+}
+
+// RandomUser builds an unsaved user with a random name and a username derived from it
+// About a third of the generated users are unverified
+func RandomUser(opts ...models.TestUserOption) *models.User {
+	firstName := firstNames[rand.IntN(len(firstNames))]
+	lastName := lastNames[rand.IntN(len(lastNames))]
+	username := fmt.Sprintf("%s_%s_%08d", strings.ToLower(firstName), strings.ToLower(lastName), rand.IntN(100_000_000))
+
+	defaults := []models.TestUserOption{
+		models.WithUsername(username),
+		models.WithName(firstName, lastName),
+		models.WithVerified(rand.IntN(3) != 0),
+	}
+	return models.NewTestUser(append(defaults, opts...)...)
+}
@@ -0,0 +1,105 @@
+// internal/repositories/testutil/seed_test.go
+package testutil
+
+import (
+	"context"
+	"testing"
+
+	"go-template/internal/models"
+	"go-template/internal/repositories"
+)
+
+func TestSeedUsers(t *testing.T) {
+	tests := []struct {
+		name  string
+		n     int
+		opts  []models.TestUserOption
+		check func(t *testing.T, user *models.User)
+	}{
+		{name: "none", n: 0},
+		{name: "a few", n: 25},
+		{name: "more than one batch", n: seedBatchSize + 3},
+		{
+			name: "options apply to every user",
+			n:    5,
+			opts: []models.TestUserOption{models.WithRoles(models.RoleUser, models.RoleAdmin), models.WithVerified(true)},
+			check: func(t *testing.T, user *models.User) {
+				if !user.IsAdmin() || !user.IsVerified {
+					t.Errorf("user %s: roles = %v, verified = %v, want a verified admin", user.Username, user.Roles, user.IsVerified)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			repo := repositories.NewMemoryUserRepository()
+
+			users, err := SeedUsers(ctx, repo, tt.n, tt.opts...)
+			if err != nil {
+				t.Fatalf("SeedUsers() error = %v", err)
+			}
+			if len(users) != tt.n {
+				t.Fatalf("SeedUsers() returned %d users, want %d", len(users), tt.n)
+			}
+
+			seen := make(map[string]bool, len(users))
+			for _, user := range users {
+				if user.ID.IsZero() {
+					t.Fatalf("user %s has no ID", user.Username)
+				}
+				if seen[user.Username] {
+					t.Errorf("username %s seeded twice", user.Username)
+				}
+				seen[user.Username] = true
+
+				req := models.CreateUserRequest{
+					Username:  user.Username,
+					Email:     user.Email,
+					Password:  models.TestUserPassword,
+					FirstName: user.FirstName,
+					LastName:  user.LastName,
+				}
+				if errs := req.Validate(); len(errs) > 0 {
+					t.Errorf("user %s fails validation: %v", user.Username, errs)
+				}
+				if tt.check != nil {
+					tt.check(t, user)
+				}
+			}
+
+			stored, err := repo.GetByIDs(ctx, userIDs(users))
+			if err != nil {
+				t.Fatalf("GetByIDs() error = %v", err)
+			}
+			if len(stored) != tt.n {
+				t.Errorf("stored %d users, want %d", len(stored), tt.n)
+			}
+		})
+	}
+}
+
+func TestSeededUsersCanLogIn(t *testing.T) {
+	users, err := SeedUsers(context.Background(), repositories.NewMemoryUserRepository(), 3)
+	if err != nil {
+		t.Fatalf("SeedUsers() error = %v", err)
+	}
+	for _, user := range users {
+		if !user.CheckPassword(models.TestUserPassword) {
+			t.Errorf("user %s does not accept models.TestUserPassword", user.Username)
+		}
+		if !user.IsActive {
+			t.Errorf("user %s is not active", user.Username)
+		}
+	}
+}
+
+// userIDs returns the hex IDs of users
+func userIDs(users []*models.User) []string {
+	ids := make([]string, len(users))
+	for i, user := range users {
+		ids[i] = user.GetIDString()
+	}
+	return ids
+}
@@ -0,0 +1,196 @@
+// internal/repositories/tracing.go
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"go-template/internal/models"
+	"go-template/internal/shared/tracing"
+)
+
+// tracedUserRepository wraps a UserRepositoryInterface and records a child span
+// around the most frequently used operations. All other methods pass through untouched.
+type tracedUserRepository struct {
+	UserRepositoryInterface
+	tracer     trace.Tracer
+	collection string
+}
+
+// newTracedUserRepository wraps repo with tracing spans
+func newTracedUserRepository(repo UserRepositoryInterface, collection string) UserRepositoryInterface {
+	return &tracedUserRepository{
+		UserRepositoryInterface: repo,
+		tracer:                  tracing.Tracer("go-template/repositories"),
+		collection:              collection,
+	}
+}
+
+// startSpan starts a client span for a repository operation
+func (t *tracedUserRepository) startSpan(ctx context.Context, operation string) (context.Context, trace.Span) {
+	return t.tracer.Start(ctx, "UserRepository."+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", "mongodb"),
+			attribute.String("db.operation", operation),
+			attribute.String("db.collection.name", t.collection),
+		),
+	)
+}
+
+// Create traces UserRepository.Create
+func (t *tracedUserRepository) Create(ctx context.Context, user *models.User) error {
+	ctx, span := t.startSpan(ctx, "Create")
+	err := t.UserRepositoryInterface.Create(ctx, user)
+	tracing.EndSpan(span, err)
+	return err
+}
+
+// GetByID traces UserRepository.GetByID
+func (t *tracedUserRepository) GetByID(ctx context.Context, id string) (*models.User, error) {
+	ctx, span := t.startSpan(ctx, "GetByID")
+	user, err := t.UserRepositoryInterface.GetByID(ctx, id)
+	tracing.EndSpan(span, err)
+	return user, err
+}
+
+// GetByIDs traces UserRepository.GetByIDs
+func (t *tracedUserRepository) GetByIDs(ctx context.Context, ids []string) ([]*models.User, error) {
+	ctx, span := t.startSpan(ctx, "GetByIDs")
+	users, err := t.UserRepositoryInterface.GetByIDs(ctx, ids)
+	tracing.EndSpan(span, err)
+	return users, err
+}
+
+// GetByUsername traces UserRepository.GetByUsername
+func (t *tracedUserRepository) GetByUsername(ctx context.Context, username string) (*models.User, error) {
+	ctx, span := t.startSpan(ctx, "GetByUsername")
+	user, err := t.UserRepositoryInterface.GetByUsername(ctx, username)
+	tracing.EndSpan(span, err)
+	return user, err
+}
+
+// GetByEmail traces UserRepository.GetByEmail
+func (t *tracedUserRepository) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	ctx, span := t.startSpan(ctx, "GetByEmail")
+	user, err := t.UserRepositoryInterface.GetByEmail(ctx, email)
+	tracing.EndSpan(span, err)
+	return user, err
+}
+
+// ExistsByUsernameOrEmail traces UserRepository.ExistsByUsernameOrEmail
+func (t *tracedUserRepository) ExistsByUsernameOrEmail(ctx context.Context, username, email string) (bool, bool, error) {
+	ctx, span := t.startSpan(ctx, "ExistsByUsernameOrEmail")
+	usernameExists, emailExists, err := t.UserRepositoryInterface.ExistsByUsernameOrEmail(ctx, username, email)
+	tracing.EndSpan(span, err)
+	return usernameExists, emailExists, err
+}
+
+// Update traces UserRepository.Update
+func (t *tracedUserRepository) Update(ctx context.Context, id string, updates map[string]interface{}) error {
+	ctx, span := t.startSpan(ctx, "Update")
+	err := t.UserRepositoryInterface.Update(ctx, id, updates)
+	tracing.EndSpan(span, err)
+	return err
+}
+
+// UpdateWithVersion traces UserRepository.UpdateWithVersion
+func (t *tracedUserRepository) UpdateWithVersion(ctx context.Context, id string, expectedVersion int64, updates map[string]interface{}) error {
+	ctx, span := t.startSpan(ctx, "UpdateWithVersion")
+	err := t.UserRepositoryInterface.UpdateWithVersion(ctx, id, expectedVersion, updates)
+	tracing.EndSpan(span, err)
+	return err
+}
+
+// StreamAll traces UserRepository.StreamAll; the span covers the whole iteration
+func (t *tracedUserRepository) StreamAll(ctx context.Context, fn func(*models.User) error) error {
+	ctx, span := t.startSpan(ctx, "StreamAll")
+	err := t.UserRepositoryInterface.StreamAll(ctx, fn)
+	tracing.EndSpan(span, err)
+	return err
+}
+
+// SoftDelete traces UserRepository.SoftDelete
+func (t *tracedUserRepository) SoftDelete(ctx context.Context, id string) error {
+	ctx, span := t.startSpan(ctx, "SoftDelete")
+	err := t.UserRepositoryInterface.SoftDelete(ctx, id)
+	tracing.EndSpan(span, err)
+	return err
+}
+
+// GetAll traces UserRepository.GetAll
+func (t *tracedUserRepository) GetAll(ctx context.Context, params *models.UsersQueryParams) ([]*models.User, int, error) {
+	ctx, span := t.startSpan(ctx, "GetAll")
+	users, total, err := t.UserRepositoryInterface.GetAll(ctx, params)
+	tracing.EndSpan(span, err)
+	return users, total, err
+}
+
+// Search traces UserRepository.Search
+func (t *tracedUserRepository) Search(ctx context.Context, query string, limit int) ([]*models.User, error) {
+	ctx, span := t.startSpan(ctx, "Search")
+	users, err := t.UserRepositoryInterface.Search(ctx, query, limit)
+	tracing.EndSpan(span, err)
+	return users, err
+}
+
+// SearchText traces UserRepository.SearchText
+func (t *tracedUserRepository) SearchText(ctx context.Context, query string, limit int) ([]*models.User, error) {
+	ctx, span := t.startSpan(ctx, "SearchText")
+	users, err := t.UserRepositoryInterface.SearchText(ctx, query, limit)
+	tracing.EndSpan(span, err)
+	return users, err
+}
+
+// CreateMany traces UserRepository.CreateMany
+func (t *tracedUserRepository) CreateMany(ctx context.Context, users []*models.User) error {
+	ctx, span := t.startSpan(ctx, "CreateMany")
+	span.SetAttributes(attribute.Int("db.batch_size", len(users)))
+	err := t.UserRepositoryInterface.CreateMany(ctx, users)
+	tracing.EndSpan(span, err)
+	return err
+}
+
+// CountByDay traces UserRepository.CountByDay
+func (t *tracedUserRepository) CountByDay(ctx context.Context, from, to time.Time) ([]models.DayCount, error) {
+	ctx, span := t.startSpan(ctx, "CountByDay")
+	counts, err := t.UserRepositoryInterface.CountByDay(ctx, from, to)
+	tracing.EndSpan(span, err)
+	return counts, err
+}
+
+// RoleDistribution traces UserRepository.RoleDistribution
+func (t *tracedUserRepository) RoleDistribution(ctx context.Context) (map[string]int, error) {
+	ctx, span := t.startSpan(ctx, "RoleDistribution")
+	distribution, err := t.UserRepositoryInterface.RoleDistribution(ctx)
+	tracing.EndSpan(span, err)
+	return distribution, err
+}
+
+// IncrementField traces UserRepository.IncrementField
+func (t *tracedUserRepository) IncrementField(ctx context.Context, id, field string, by int) error {
+	ctx, span := t.startSpan(ctx, "IncrementField")
+	span.SetAttributes(attribute.String("db.field", field))
+	err := t.UserRepositoryInterface.IncrementField(ctx, id, field, by)
+	tracing.EndSpan(span, err)
+	return err
+}
+
+// CountUsers traces UserRepository.CountUsers
+func (t *tracedUserRepository) CountUsers(ctx context.Context, params *models.UserCountParams) (int, error) {
+	ctx, span := t.startSpan(ctx, "CountUsers")
+	count, err := t.UserRepositoryInterface.CountUsers(ctx, params)
+	tracing.EndSpan(span, err)
+	return count, err
+}
+
+// GetUserStats traces UserRepository.GetUserStats
+func (t *tracedUserRepository) GetUserStats(ctx context.Context, params *models.UserStatsParams) (*models.UserStatsResponse, error) {
+	ctx, span := t.startSpan(ctx, "GetUserStats")
+	stats, err := t.UserRepositoryInterface.GetUserStats(ctx, params)
+	tracing.EndSpan(span, err)
+	return stats, err
+}
@@ -0,0 +1,108 @@
+// internal/repositories/user_filter.go
+package repositories
+
+import (
+	"regexp"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"go-template/internal/models"
+)
+
+// UserFilter holds optional criteria for listing users; zero values are ignored
+// Soft-deleted users are always excluded by MongoRepository, so the filter never has to
+type UserFilter struct {
+	Search        string     // Case-insensitive substring match on username, email, first or last name
+	Roles         []string   // User must hold every listed role
+	IsActive      *bool
+	IsVerified    *bool
+	CreatedAfter  *time.Time // Inclusive
+	CreatedBefore *time.Time // Exclusive
+}
+
+// NewUserFilter builds a UserFilter from list query parameters
+func NewUserFilter(params *models.UsersQueryParams) UserFilter {
+	filter := UserFilter{
+		Search:        params.Search,
+		IsActive:      params.IsActive,
+		IsVerified:    params.IsVerified,
+		CreatedAfter:  params.CreatedAfter,
+		CreatedBefore: params.CreatedBefore,
+	}
+
+	if params.Role != "" {
+		filter.Roles = append(filter.Roles, params.Role)
+	}
+	filter.Roles = append(filter.Roles, params.HasRoles...)
+
+	return filter
+}
+
+// buildFilter assembles the MongoDB filter document for the set criteria
+func (f UserFilter) buildFilter() bson.M {
+	filter := bson.M{}
+
+	if f.Search != "" {
+		filter["$or"] = searchConditions(f.Search)
+	}
+
+	if len(f.Roles) > 0 {
+		filter["roles"] = bson.M{"$all": f.Roles}
+	}
+
+	if f.IsActive != nil {
+		filter["is_active"] = *f.IsActive
+	}
+
+	if f.IsVerified != nil {
+		filter["is_verified"] = *f.IsVerified
+	}
+
+	if f.CreatedAfter != nil || f.CreatedBefore != nil {
+		createdAt := bson.M{}
+		if f.CreatedAfter != nil {
+			createdAt["$gte"] = *f.CreatedAfter
+		}
+		if f.CreatedBefore != nil {
+			createdAt["$lt"] = *f.CreatedBefore
+		}
+		filter["created_at"] = createdAt
+	}
+
+	return filter
+}
+
+// sensitiveUserFields may never be loaded through a projection, whatever the allowlist says
+var sensitiveUserFields = map[string]bool{"password": true, "salt": true}
+
+// buildProjection converts selected UserResponse fields into a MongoDB projection
+// It returns nil, loading whole documents, when no fields are selected
+func buildProjection(fields []string) bson.M {
+	if len(fields) == 0 {
+		return nil
+	}
+
+	projection := bson.M{"_id": 1}
+	for _, field := range fields {
+		for _, docField := range models.UserSelectableFields[field] {
+			if !sensitiveUserFields[docField] {
+				projection[docField] = 1
+			}
+		}
+	}
+	return projection
+}
+
+// searchConditions matches users whose username, email, first or last name contains query
+// Regex metacharacters are escaped so the query is matched literally: a search for "a.b" does
+// not match "axb", and patterns built to backtrack catastrophically are harmless.
+func searchConditions(query string) []bson.M {
+	pattern := regexp.QuoteMeta(query)
+	return []bson.M{
+		{"username": bson.M{"$regex": pattern, "$options": "i"}},
+		{"email": bson.M{"$regex": pattern, "$options": "i"}},
+		{"first_name": bson.M{"$regex": pattern, "$options": "i"}},
+		{"last_name": bson.M{"$regex": pattern, "$options": "i"}},
+	}
+}
@@ -0,0 +1,204 @@
+// internal/repositories/user_filter_test.go
+package repositories
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"go-template/internal/models"
+)
+
+func TestUserFilterBuildFilter(t *testing.T) {
+	yes, no := true, false
+	after := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	before := time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		params models.UsersQueryParams
+		want   bson.M
+	}{
+		{name: "no criteria", want: bson.M{}},
+		{name: "search", params: models.UsersQueryParams{Search: "a.b"}, want: bson.M{"$or": searchConditions("a.b")}},
+		{name: "role", params: models.UsersQueryParams{Role: "admin"}, want: bson.M{"roles": bson.M{"$all": []string{"admin"}}}},
+		{name: "has_role", params: models.UsersQueryParams{HasRoles: []string{"admin", "moderator"}}, want: bson.M{"roles": bson.M{"$all": []string{"admin", "moderator"}}}},
+		{name: "role and has_role", params: models.UsersQueryParams{Role: "user", HasRoles: []string{"admin"}}, want: bson.M{"roles": bson.M{"$all": []string{"user", "admin"}}}},
+		{name: "active", params: models.UsersQueryParams{IsActive: &yes}, want: bson.M{"is_active": true}},
+		{name: "inactive", params: models.UsersQueryParams{IsActive: &no}, want: bson.M{"is_active": false}},
+		{name: "verified", params: models.UsersQueryParams{IsVerified: &yes}, want: bson.M{"is_verified": true}},
+		{name: "unverified", params: models.UsersQueryParams{IsVerified: &no}, want: bson.M{"is_verified": false}},
+		{name: "created after", params: models.UsersQueryParams{CreatedAfter: &after}, want: bson.M{"created_at": bson.M{"$gte": after}}},
+		{name: "created before", params: models.UsersQueryParams{CreatedBefore: &before}, want: bson.M{"created_at": bson.M{"$lt": before}}},
+		{name: "created range", params: models.UsersQueryParams{CreatedAfter: &after, CreatedBefore: &before}, want: bson.M{"created_at": bson.M{"$gte": after, "$lt": before}}},
+		{
+			name: "every criterion",
+			params: models.UsersQueryParams{
+				Search:        "alice",
+				Role:          "admin",
+				IsActive:      &yes,
+				IsVerified:    &no,
+				CreatedAfter:  &after,
+				CreatedBefore: &before,
+			},
+			want: bson.M{
+				"$or":         searchConditions("alice"),
+				"roles":       bson.M{"$all": []string{"admin"}},
+				"is_active":   true,
+				"is_verified": false,
+				"created_at":  bson.M{"$gte": after, "$lt": before},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NewUserFilter(&tt.params).buildFilter()
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("buildFilter() = %v, want %v", got, tt.want)
+			}
+			if _, ok := got["deleted_at"]; ok {
+				t.Error("buildFilter() handles soft deletes, want it left to the repository")
+			}
+		})
+	}
+}
+
+func TestSearchConditions(t *testing.T) {
+	tests := []struct {
+		name      string
+		query     string
+		matches   []string
+		unmatched []string
+	}{
+		{name: "plain term", query: "smith", matches: []string{"Smith", "blacksmith"}, unmatched: []string{"smyth"}},
+		{name: "dot is literal", query: "a.b", matches: []string{"a.b", "XA.BY"}, unmatched: []string{"axb", "ab"}},
+		{name: "wildcard is literal", query: ".*", matches: []string{"x.*y"}, unmatched: []string{"anything", ""}},
+		{name: "anchors are literal", query: "^bob$", matches: []string{"^bob$"}, unmatched: []string{"bob"}},
+		{name: "catastrophic backtracking", query: "(a+)+$", matches: []string{"(a+)+$"}, unmatched: []string{strings.Repeat("a", 30) + "!"}},
+		{name: "unbalanced bracket", query: "[", matches: []string{"a[b"}, unmatched: []string{"ab"}},
+		{name: "backslash", query: `\d`, matches: []string{`a\d`}, unmatched: []string{"1"}},
+		{name: "plus in an email", query: "jo+test@", matches: []string{"jo+test@example.com"}, unmatched: []string{"joootest@example.com"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conditions := searchConditions(tt.query)
+
+			fields := make([]string, 0, len(conditions))
+			for _, condition := range conditions {
+				for field, value := range condition {
+					fields = append(fields, field)
+					regex := value.(bson.M)
+					if regex["$options"] != "i" {
+						t.Errorf("%s $options = %v, want case-insensitive", field, regex["$options"])
+					}
+
+					// compileRegex applies MongoDB's options the way the memory repository does
+					re, err := compileRegex(regex["$regex"].(string), "i")
+					if err != nil {
+						t.Fatalf("%s $regex %q does not compile: %v", field, regex["$regex"], err)
+					}
+					for _, s := range tt.matches {
+						if !re.MatchString(s) {
+							t.Errorf("%s $regex %q does not match %q", field, regex["$regex"], s)
+						}
+					}
+					for _, s := range tt.unmatched {
+						if re.MatchString(s) {
+							t.Errorf("%s $regex %q matches %q", field, regex["$regex"], s)
+						}
+					}
+				}
+			}
+			if want := []string{"username", "email", "first_name", "last_name"}; !reflect.DeepEqual(fields, want) {
+				t.Errorf("fields = %v, want %v", fields, want)
+			}
+		})
+	}
+}
+
+func TestNotDeleted(t *testing.T) {
+	filter := bson.M{"is_active": true}
+	got := notDeleted(filter)
+
+	want := bson.M{"is_active": true, "deleted_at": bson.M{"$exists": false}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("notDeleted() = %v, want %v", got, want)
+	}
+	if _, ok := filter["deleted_at"]; ok {
+		t.Error("notDeleted() modified its argument")
+	}
+}
+
+func TestUserCountFilter(t *testing.T) {
+	active, inactive := true, false
+	notDeletedFilter := bson.M{"$exists": false}
+
+	tests := []struct {
+		name   string
+		params *models.UserCountParams
+		want   bson.M
+	}{
+		{name: "nil params", want: bson.M{"deleted_at": notDeletedFilter}},
+		{name: "no filters", params: &models.UserCountParams{}, want: bson.M{"deleted_at": notDeletedFilter}},
+		{name: "active", params: &models.UserCountParams{IsActive: &active}, want: bson.M{"is_active": true, "deleted_at": notDeletedFilter}},
+		{name: "inactive", params: &models.UserCountParams{IsActive: &inactive}, want: bson.M{"is_active": false, "deleted_at": notDeletedFilter}},
+		{
+			name:   "role",
+			params: &models.UserCountParams{Role: models.RoleAdmin},
+			want:   bson.M{"roles": bson.M{"$in": []string{models.RoleAdmin}}, "deleted_at": notDeletedFilter},
+		},
+		{
+			name:   "role and status",
+			params: &models.UserCountParams{IsActive: &active, Role: models.RoleMod},
+			want:   bson.M{"is_active": true, "roles": bson.M{"$in": []string{models.RoleMod}}, "deleted_at": notDeletedFilter},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := userCountFilter(tt.params); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("userCountFilter() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildProjection(t *testing.T) {
+	tests := []struct {
+		name   string
+		fields []string
+		want   bson.M
+	}{
+		{name: "no fields loads whole documents"},
+		{name: "id only", fields: []string{"id"}, want: bson.M{"_id": 1}},
+		{name: "simple fields", fields: []string{"id", "username", "email"}, want: bson.M{"_id": 1, "username": 1, "email": 1}},
+		{name: "computed field loads its sources", fields: []string{"id", "full_name"}, want: bson.M{"_id": 1, "first_name": 1, "last_name": 1, "username": 1}},
+		{name: "unknown fields are ignored", fields: []string{"id", "password", "salt"}, want: bson.M{"_id": 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := buildProjection(tt.fields)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("buildProjection(%v) = %v, want %v", tt.fields, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildProjectionNeverLoadsSensitiveFields(t *testing.T) {
+	// Even a misconfigured allowlist entry must not pull secrets into memory
+	models.UserSelectableFields["leaky"] = []string{"username", "password", "salt"}
+	t.Cleanup(func() { delete(models.UserSelectableFields, "leaky") })
+
+	got := buildProjection([]string{"id", "leaky"})
+	want := bson.M{"_id": 1, "username": 1}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("buildProjection() = %v, want %v", got, want)
+	}
+}
@@ -5,7 +5,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log"
+	"strings"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -13,42 +13,70 @@ import (
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 
+	"go-template/internal/interfaces"
 	"go-template/internal/models"
+	"go-template/internal/shared/utils"
 )
 
+// caseInsensitive is the collation of the unique username and email indexes
+// Queries must use it to match those indexes and compare case-insensitively.
+var caseInsensitive = &options.Collation{Locale: "en", Strength: 2}
+
 // UserRepository implements UserRepositoryInterface using MongoDB
+// GetByID and GetByIDs come from the embedded MongoRepository; other basic
+// operations wrap it with user-specific rules
 type UserRepository struct {
+	*MongoRepository[models.User]
 	collection *mongo.Collection
 	db         *mongo.Database
+	// analytics serves the aggregation queries; it is collection unless WithReadDatabase is used
+	analytics *mongo.Collection
+	logger    interfaces.LoggerInterface
+}
+
+// UserRepositoryOption customizes a UserRepository
+type UserRepositoryOption func(*UserRepository)
+
+// WithReadDatabase runs the analytics queries (stats, signups by day) against readDB
+// Use it with a connection that reads from secondaries; writes always go to the primary.
+func WithReadDatabase(readDB *mongo.Database) UserRepositoryOption {
+	return func(r *UserRepository) {
+		if readDB != nil {
+			r.analytics = readDB.Collection(r.collection.Name())
+		}
+	}
 }
 
 // NewUserRepository creates a new UserRepository instance
-func NewUserRepository(db *mongo.Database) UserRepositoryInterface {
+// Repository logs go through logger so they carry the request ID of the context they run in.
+func NewUserRepository(db *mongo.Database, logger interfaces.LoggerInterface, opts ...UserRepositoryOption) UserRepositoryInterface {
+	// Indexes are managed by the migration runner (internal/database/migrations)
+	collection := db.Collection("users")
 	repo := &UserRepository{
-		collection: db.Collection("users"),
-		db:         db,
+		MongoRepository: NewMongoRepository[models.User](collection, "user"),
+		collection:      collection,
+		db:              db,
+		analytics:       collection,
+		logger:          logger.With("repository", "users"),
 	}
-	
-	// Ensure indexes on startup
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
-	
-	if err := repo.EnsureIndexes(ctx); err != nil {
-		log.Printf("Warning: Failed to ensure indexes: %v", err)
+	for _, opt := range opts {
+		opt(repo)
 	}
 	
-	return repo
+	return newTracedUserRepository(repo, repo.collection.Name())
 }
 
 // Create inserts a new user into the database
 func (r *UserRepository) Create(ctx context.Context, user *models.User) error {
+	normalizeUserIdentity(user)
+	
 	// Check if username already exists
 	exists, err := r.ExistsByUsername(ctx, user.Username)
 	if err != nil {
 		return fmt.Errorf("failed to check username existence: %w", err)
 	}
 	if exists {
-		return errors.New("username already exists")
+		return fmt.Errorf("username %w", interfaces.ErrAlreadyExists)
 	}
 	
 	// Check if email already exists
@@ -57,143 +85,123 @@ func (r *UserRepository) Create(ctx context.Context, user *models.User) error {
 		return fmt.Errorf("failed to check email existence: %w", err)
 	}
 	if exists {
-		return errors.New("email already exists")
+		return fmt.Errorf("email %w", interfaces.ErrAlreadyExists)
 	}
 	
 	// Insert user
-	result, err := r.collection.InsertOne(ctx, user)
-	if err != nil {
-		return fmt.Errorf("failed to create user: %w", err)
+	return r.MongoRepository.Create(ctx, user)
+}
+
+// GetByUsername retrieves a user by their username, ignoring case
+func (r *UserRepository) GetByUsername(ctx context.Context, username string) (*models.User, error) {
+	return r.FindOne(ctx, bson.M{"username": models.NormalizeUsername(username)})
+}
+
+// GetByEmail retrieves a user by their email, ignoring case
+func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	return r.FindOne(ctx, bson.M{"email": strings.ToLower(email)})
+}
+
+// Update sets fields on a user, lowercasing a new username or email
+func (r *UserRepository) Update(ctx context.Context, id string, updates map[string]interface{}) error {
+	normalizeUserUpdates(updates)
+	return r.MongoRepository.Update(ctx, id, updates)
+}
+
+// UpdateWithVersion sets fields on a user if its version matches, lowercasing a new username or email
+func (r *UserRepository) UpdateWithVersion(ctx context.Context, id string, expectedVersion int64, updates map[string]interface{}) error {
+	normalizeUserUpdates(updates)
+	return r.MongoRepository.UpdateWithVersion(ctx, id, expectedVersion, updates)
+}
+
+// normalizeUserIdentity normalizes a user's username and lowercases the email as NewUser does
+func normalizeUserIdentity(user *models.User) {
+	user.Username = models.NormalizeUsername(user.Username)
+	user.Email = strings.ToLower(user.Email)
+}
+
+// normalizeUserUpdates normalizes username and lowercases email values in an update map
+func normalizeUserUpdates(updates map[string]interface{}) {
+	if username, ok := updates["username"].(string); ok {
+		updates["username"] = models.NormalizeUsername(username)
 	}
-	
-	// Update user ID with the generated one
-	if oid, ok := result.InsertedID.(primitive.ObjectID); ok {
-		user.ID = oid
+	if email, ok := updates["email"].(string); ok {
+		updates["email"] = strings.ToLower(email)
 	}
-	
-	return nil
 }
 
-// GetByID retrieves a user by their ID
-func (r *UserRepository) GetByID(ctx context.Context, id string) (*models.User, error) {
+// Delete permanently deletes a user
+func (r *UserRepository) Delete(ctx context.Context, id string) error {
 	objectID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
-		return nil, fmt.Errorf("invalid user ID format: %w", err)
+		return fmt.Errorf("invalid user ID format: %w", err)
 	}
 	
-	var user models.User
-	filter := bson.M{
-		"_id":        objectID,
-		"deleted_at": bson.M{"$exists": false}, // Exclude soft-deleted users
-	}
+	filter := bson.M{"_id": objectID}
 	
-	err = r.collection.FindOne(ctx, filter).Decode(&user)
+	result, err := r.collection.DeleteOne(ctx, filter)
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			return nil, errors.New("user not found")
-		}
-		return nil, fmt.Errorf("failed to get user by ID: %w", err)
-	}
-	
-	return &user, nil
-}
-
-// GetByUsername retrieves a user by their username
-func (r *UserRepository) GetByUsername(ctx context.Context, username string) (*models.User, error) {
-	var user models.User
-	filter := bson.M{
-		"username":   username,
-		"deleted_at": bson.M{"$exists": false},
+		return fmt.Errorf("failed to delete user: %w", err)
 	}
 	
-	err := r.collection.FindOne(ctx, filter).Decode(&user)
-	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			return nil, errors.New("user not found")
-		}
-		return nil, fmt.Errorf("failed to get user by username: %w", err)
+	if result.DeletedCount == 0 {
+		return fmt.Errorf("user %w", interfaces.ErrNotFound)
 	}
 	
-	return &user, nil
+	return nil
 }
 
-// GetByEmail retrieves a user by their email
-func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*models.User, error) {
-	var user models.User
-	filter := bson.M{
-		"email":      email,
-		"deleted_at": bson.M{"$exists": false},
-	}
-	
-	err := r.collection.FindOne(ctx, filter).Decode(&user)
-	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			return nil, errors.New("user not found")
-		}
-		return nil, fmt.Errorf("failed to get user by email: %w", err)
-	}
-	
-	return &user, nil
+// SoftDelete soft deletes a user by setting deleted_at timestamp and deactivating it
+func (r *UserRepository) SoftDelete(ctx context.Context, id string) error {
+	return r.MongoRepository.SoftDelete(ctx, id, map[string]interface{}{"is_active": false})
 }
 
-// Update updates a user's fields
-func (r *UserRepository) Update(ctx context.Context, id string, updates map[string]interface{}) error {
+// Restore reverses a soft delete by clearing deleted_at and reactivating the user
+func (r *UserRepository) Restore(ctx context.Context, id string) error {
 	objectID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
 		return fmt.Errorf("invalid user ID format: %w", err)
 	}
 	
-	// Add updated_at timestamp
-	updates["updated_at"] = time.Now().UTC()
-	
 	filter := bson.M{
 		"_id":        objectID,
-		"deleted_at": bson.M{"$exists": false},
+		"deleted_at": bson.M{"$exists": true},
 	}
 	
-	update := bson.M{"$set": updates}
+	update := versionedUpdate(ctx, map[string]interface{}{"is_active": true})
+	update["$unset"] = bson.M{"deleted_at": ""}
 	
 	result, err := r.collection.UpdateOne(ctx, filter, update)
 	if err != nil {
-		return fmt.Errorf("failed to update user: %w", err)
+		return fmt.Errorf("failed to restore user: %w", err)
 	}
 	
 	if result.MatchedCount == 0 {
-		return errors.New("user not found")
+		return fmt.Errorf("deleted user %w", interfaces.ErrNotFound)
 	}
 	
 	return nil
 }
 
-// Delete permanently deletes a user
-func (r *UserRepository) Delete(ctx context.Context, id string) error {
+// GetByIDIncludingDeleted retrieves a user by their ID, including soft-deleted users
+func (r *UserRepository) GetByIDIncludingDeleted(ctx context.Context, id string) (*models.User, error) {
 	objectID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
-		return fmt.Errorf("invalid user ID format: %w", err)
+		return nil, fmt.Errorf("invalid user ID format: %w", err)
 	}
 	
+	var user models.User
 	filter := bson.M{"_id": objectID}
 	
-	result, err := r.collection.DeleteOne(ctx, filter)
+	err = r.collection.FindOne(ctx, filter).Decode(&user)
 	if err != nil {
-		return fmt.Errorf("failed to delete user: %w", err)
-	}
-	
-	if result.DeletedCount == 0 {
-		return errors.New("user not found")
-	}
-	
-	return nil
-}
-
-// SoftDelete soft deletes a user by setting deleted_at timestamp
-func (r *UserRepository) SoftDelete(ctx context.Context, id string) error {
-	updates := map[string]interface{}{
-		"deleted_at": time.Now().UTC(),
-		"is_active":  false,
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("user %w", interfaces.ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to get user by ID: %w", err)
 	}
 	
-	return r.Update(ctx, id, updates)
+	return &user, nil
 }
 
 // GetAll retrieves users with pagination and filtering
@@ -201,86 +209,62 @@ func (r *UserRepository) GetAll(ctx context.Context, params *models.UsersQueryPa
 	// Set defaults
 	params.SetDefaults()
 	
-	// Build filter
-	filter := bson.M{"deleted_at": bson.M{"$exists": false}}
+	filter := NewUserFilter(params).buildFilter()
 	
-	// Add search filter
-	if params.Search != "" {
-		filter["$or"] = []bson.M{
-			{"username": bson.M{"$regex": params.Search, "$options": "i"}},
-			{"email": bson.M{"$regex": params.Search, "$options": "i"}},
-			{"first_name": bson.M{"$regex": params.Search, "$options": "i"}},
-			{"last_name": bson.M{"$regex": params.Search, "$options": "i"}},
+	return r.MongoRepository.GetAll(ctx, filter, params.Page, params.Limit, buildSort(params.Sort), buildProjection(params.Fields))
+}
+
+// buildSort converts parsed sort fields into an ordered MongoDB sort document
+func buildSort(fields []models.SortField) bson.D {
+	sort := bson.D{}
+	for _, field := range fields {
+		direction := 1
+		if field.Descending {
+			direction = -1
 		}
+		sort = append(sort, bson.E{Key: field.Field, Value: direction})
 	}
-	
-	// Add role filter
-	if params.Role != "" {
-		filter["roles"] = bson.M{"$in": []string{params.Role}}
-	}
-	
-	// Add status filter
-	if params.IsActive != nil {
-		filter["is_active"] = *params.IsActive
-	}
-	
-	// Count total documents
-	total, err := r.collection.CountDocuments(ctx, filter)
-	if err != nil {
-		return nil, 0, fmt.Errorf("failed to count users: %w", err)
-	}
-	
-	// Build sort
-	sort := bson.D{}
-	sortDirection := 1
-	if params.SortDir == "desc" {
-		sortDirection = -1
+	return sort
+}
+
+// Search performs a case-insensitive substring search on users
+func (r *UserRepository) Search(ctx context.Context, query string, limit int) ([]*models.User, error) {
+	filter := bson.M{
+		"deleted_at": bson.M{"$exists": false},
+		"$or":        searchConditions(query),
 	}
-	sort = append(sort, bson.E{Key: params.SortBy, Value: sortDirection})
 	
-	// Build options
-	opts := options.Find().
-		SetSkip(int64((params.Page - 1) * params.Limit)).
-		SetLimit(int64(params.Limit)).
-		SetSort(sort)
+	opts := options.Find().SetLimit(int64(limit))
 	
-	// Execute query
 	cursor, err := r.collection.Find(ctx, filter, opts)
 	if err != nil {
-		return nil, 0, fmt.Errorf("failed to find users: %w", err)
+		return nil, fmt.Errorf("failed to search users: %w", err)
 	}
 	defer cursor.Close(ctx)
 	
-	// Decode results
 	var users []*models.User
 	for cursor.Next(ctx) {
 		var user models.User
 		if err := cursor.Decode(&user); err != nil {
-			return nil, 0, fmt.Errorf("failed to decode user: %w", err)
+			return nil, fmt.Errorf("failed to decode user: %w", err)
 		}
 		users = append(users, &user)
 	}
 	
-	if err := cursor.Err(); err != nil {
-		return nil, 0, fmt.Errorf("cursor error: %w", err)
-	}
-	
-	return users, int(total), nil
+	return users, nil
 }
 
-// Search performs a text search on users
-func (r *UserRepository) Search(ctx context.Context, query string, limit int) ([]*models.User, error) {
+// SearchText performs a full-text search on users using the text index, ordered by relevance
+func (r *UserRepository) SearchText(ctx context.Context, query string, limit int) ([]*models.User, error) {
 	filter := bson.M{
 		"deleted_at": bson.M{"$exists": false},
-		"$or": []bson.M{
-			{"username": bson.M{"$regex": query, "$options": "i"}},
-			{"email": bson.M{"$regex": query, "$options": "i"}},
-			{"first_name": bson.M{"$regex": query, "$options": "i"}},
-			{"last_name": bson.M{"$regex": query, "$options": "i"}},
-		},
+		"$text":      bson.M{"$search": query},
 	}
 	
-	opts := options.Find().SetLimit(int64(limit))
+	opts := options.Find().
+		SetProjection(bson.M{"score": bson.M{"$meta": "textScore"}}).
+		SetSort(bson.D{{Key: "score", Value: bson.M{"$meta": "textScore"}}}).
+		SetLimit(int64(limit))
 	
 	cursor, err := r.collection.Find(ctx, filter, opts)
 	if err != nil {
@@ -300,14 +284,14 @@ func (r *UserRepository) Search(ctx context.Context, query string, limit int) ([
 	return users, nil
 }
 
-// ExistsByUsername checks if a username already exists
+// ExistsByUsername checks if a username already exists, ignoring case
 func (r *UserRepository) ExistsByUsername(ctx context.Context, username string) (bool, error) {
 	filter := bson.M{
-		"username":   username,
+		"username":   models.NormalizeUsername(username),
 		"deleted_at": bson.M{"$exists": false},
 	}
 	
-	count, err := r.collection.CountDocuments(ctx, filter)
+	count, err := r.collection.CountDocuments(ctx, filter, options.Count().SetCollation(caseInsensitive))
 	if err != nil {
 		return false, fmt.Errorf("failed to check username existence: %w", err)
 	}
@@ -315,14 +299,14 @@ func (r *UserRepository) ExistsByUsername(ctx context.Context, username string)
 	return count > 0, nil
 }
 
-// ExistsByEmail checks if an email already exists
+// ExistsByEmail checks if an email already exists, ignoring case
 func (r *UserRepository) ExistsByEmail(ctx context.Context, email string) (bool, error) {
 	filter := bson.M{
 		"email":      email,
 		"deleted_at": bson.M{"$exists": false},
 	}
 	
-	count, err := r.collection.CountDocuments(ctx, filter)
+	count, err := r.collection.CountDocuments(ctx, filter, options.Count().SetCollation(caseInsensitive))
 	if err != nil {
 		return false, fmt.Errorf("failed to check email existence: %w", err)
 	}
@@ -330,6 +314,52 @@ func (r *UserRepository) ExistsByEmail(ctx context.Context, email string) (bool,
 	return count > 0, nil
 }
 
+// ExistsByUsernameOrEmail reports whether the username and the email are taken, ignoring case
+// Both are checked with a single query instead of one count per field.
+func (r *UserRepository) ExistsByUsernameOrEmail(ctx context.Context, username, email string) (bool, bool, error) {
+	username = models.NormalizeUsername(username)
+	filter := bson.M{
+		"$or": bson.A{
+			bson.M{"username": username},
+			bson.M{"email": email},
+		},
+		"deleted_at": bson.M{"$exists": false},
+	}
+	
+	// At most one user holds each value, so two documents reveal both collisions
+	opts := options.Find().
+		SetCollation(caseInsensitive).
+		SetLimit(2).
+		SetProjection(bson.M{"username": 1, "email": 1})
+	
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return false, false, fmt.Errorf("failed to check username and email existence: %w", err)
+	}
+	defer cursor.Close(ctx)
+	
+	var matches []*models.User
+	if err := cursor.All(ctx, &matches); err != nil {
+		return false, false, fmt.Errorf("failed to decode existence check results: %w", err)
+	}
+	
+	usernameExists, emailExists := identityCollisions(matches, username, email)
+	return usernameExists, emailExists, nil
+}
+
+// identityCollisions reports which of username and email the matched users hold, ignoring case
+func identityCollisions(matches []*models.User, username, email string) (usernameExists, emailExists bool) {
+	for _, user := range matches {
+		if strings.EqualFold(user.Username, username) {
+			usernameExists = true
+		}
+		if strings.EqualFold(user.Email, email) {
+			emailExists = true
+		}
+	}
+	return usernameExists, emailExists
+}
+
 // ExistsByID checks if a user ID exists
 func (r *UserRepository) ExistsByID(ctx context.Context, id string) (bool, error) {
 	objectID, err := primitive.ObjectIDFromHex(id)
@@ -350,6 +380,11 @@ func (r *UserRepository) ExistsByID(ctx context.Context, id string) (bool, error
 	return count > 0, nil
 }
 
+// StreamAll calls fn for every user that has not been soft-deleted, one document at a time
+func (r *UserRepository) StreamAll(ctx context.Context, fn func(*models.User) error) error {
+	return r.MongoRepository.Stream(ctx, bson.M{}, fn)
+}
+
 // GetByRole retrieves users by role
 func (r *UserRepository) GetByRole(ctx context.Context, role string, limit int) ([]*models.User, error) {
 	filter := bson.M{
@@ -419,6 +454,32 @@ func (r *UserRepository) GetActiveUsers(ctx context.Context, limit int) ([]*mode
 	return users, nil
 }
 
+// GetRecentlyLoggedIn retrieves the users who logged in most recently, most recent first
+// Users who never logged in are left out
+func (r *UserRepository) GetRecentlyLoggedIn(ctx context.Context, limit int) ([]*models.User, error) {
+	filter := bson.M{
+		"last_login_at": bson.M{"$ne": nil},
+		"deleted_at":    bson.M{"$exists": false},
+	}
+	
+	opts := options.Find().
+		SetSort(bson.D{{Key: "last_login_at", Value: -1}}).
+		SetLimit(int64(limit))
+	
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get recently logged in users: %w", err)
+	}
+	defer cursor.Close(ctx)
+	
+	var users []*models.User
+	if err := cursor.All(ctx, &users); err != nil {
+		return nil, fmt.Errorf("failed to decode users: %w", err)
+	}
+	
+	return users, nil
+}
+
 // GetInactiveUsers retrieves inactive users
 func (r *UserRepository) GetInactiveUsers(ctx context.Context, limit int) ([]*models.User, error) {
 	filter := bson.M{
@@ -461,94 +522,151 @@ func (r *UserRepository) CountActiveUsers(ctx context.Context) (int, error) {
 	return int(count), nil
 }
 
-// UpdateLastLogin updates user's last login timestamp
-func (r *UserRepository) UpdateLastLogin(ctx context.Context, id string) error {
-	updates := map[string]interface{}{
-		"last_login_at": time.Now().UTC(),
+// CountUsers counts the users matching the optional active status and role filters
+func (r *UserRepository) CountUsers(ctx context.Context, params *models.UserCountParams) (int, error) {
+	count, err := r.collection.CountDocuments(ctx, userCountFilter(params))
+	if err != nil {
+		return 0, fmt.Errorf("failed to count users: %w", err)
 	}
 	
-	return r.Update(ctx, id, updates)
+	return int(count), nil
+}
+
+// userCountFilter builds the filter for CountUsers, excluding soft-deleted users
+func userCountFilter(params *models.UserCountParams) bson.M {
+	filter := bson.M{}
+	if params != nil {
+		if params.IsActive != nil {
+			filter["is_active"] = *params.IsActive
+		}
+		if params.Role != "" {
+			filter["roles"] = bson.M{"$in": []string{params.Role}}
+		}
+	}
+	return notDeleted(filter)
+}
+
+// UpdateLastLogin updates user's last login timestamp
+// It is bookkeeping, so the version is not touched
+func (r *UserRepository) UpdateLastLogin(ctx context.Context, id string) error {
+	return r.bookkeeping(ctx, id, "update last login", bson.M{
+		"$set": bson.M{
+			"last_login_at": utils.Now(),
+			"updated_at":    utils.Now(),
+		},
+	})
+}
+
+// UpdateLastActivity records that the user was just active
+// It is bookkeeping, so neither updated_at nor the version are touched
+func (r *UserRepository) UpdateLastActivity(ctx context.Context, id string) error {
+	return r.bookkeeping(ctx, id, "update last activity", bson.M{
+		"$set": bson.M{"last_activity_at": utils.Now()},
+	})
 }
 
 // IncrementLoginCount increments user's login count
+// It is bookkeeping, so the version is not touched
 func (r *UserRepository) IncrementLoginCount(ctx context.Context, id string) error {
-	objectID, err := primitive.ObjectIDFromHex(id)
-	if err != nil {
-		return fmt.Errorf("invalid user ID format: %w", err)
-	}
-	
-	filter := bson.M{
-		"_id":        objectID,
-		"deleted_at": bson.M{"$exists": false},
-	}
-	
-	update := bson.M{
+	return r.bookkeeping(ctx, id, "increment login count", bson.M{
 		"$inc": bson.M{"login_count": 1},
-		"$set": bson.M{"updated_at": time.Now().UTC()},
-	}
-	
-	result, err := r.collection.UpdateOne(ctx, filter, update)
-	if err != nil {
-		return fmt.Errorf("failed to increment login count: %w", err)
+		"$set": bson.M{"updated_at": utils.Now()},
+	})
+}
+
+// incrementableFields are the counters IncrementField may change
+// Security-relevant counters such as failed_logins and token_version are deliberately absent.
+var incrementableFields = map[string]bool{
+	"login_count":   true,
+	"profile_views": true,
+}
+
+// checkIncrementable rejects fields that IncrementField may not change
+func checkIncrementable(field string) error {
+	if !incrementableFields[field] {
+		return fmt.Errorf("%w: field %q cannot be incremented", interfaces.ErrValidation, field)
 	}
-	
-	if result.MatchedCount == 0 {
-		return errors.New("user not found")
+	return nil
+}
+
+// IncrementField atomically adds by to one of the allowlisted counter fields
+// It is bookkeeping, so neither updated_at nor the version are touched
+func (r *UserRepository) IncrementField(ctx context.Context, id, field string, by int) error {
+	if err := checkIncrementable(field); err != nil {
+		return err
 	}
 	
-	return nil
+	return r.bookkeeping(ctx, id, "increment "+field, bson.M{
+		"$inc": bson.M{field: by},
+	})
+}
+
+// IncrementTokenVersion bumps the user's token version, revoking every token issued before
+func (r *UserRepository) IncrementTokenVersion(ctx context.Context, id string) error {
+	return r.bookkeeping(ctx, id, "increment token version", bson.M{
+		"$inc": bson.M{"token_version": 1},
+		"$set": bson.M{"updated_at": utils.Now()},
+	})
 }
 
 // RecordFailedLogin records a failed login attempt
+// It is bookkeeping, so the version is not touched
 func (r *UserRepository) RecordFailedLogin(ctx context.Context, id string) error {
-	objectID, err := primitive.ObjectIDFromHex(id)
-	if err != nil {
-		return fmt.Errorf("invalid user ID format: %w", err)
-	}
-	
-	filter := bson.M{
-		"_id":        objectID,
-		"deleted_at": bson.M{"$exists": false},
-	}
-	
-	update := bson.M{
+	now := utils.Now()
+	return r.bookkeeping(ctx, id, "record failed login", bson.M{
 		"$inc": bson.M{"failed_logins": 1},
 		"$set": bson.M{
-			"last_failed_at": time.Now().UTC(),
-			"updated_at":     time.Now().UTC(),
+			"last_failed_at": now,
+			"updated_at":     now,
 		},
-	}
-	
-	result, err := r.collection.UpdateOne(ctx, filter, update)
-	if err != nil {
-		return fmt.Errorf("failed to record failed login: %w", err)
-	}
-	
-	if result.MatchedCount == 0 {
-		return errors.New("user not found")
-	}
-	
-	return nil
+	})
 }
 
 // ResetFailedLogins resets failed login count
+// It is bookkeeping, so the version is not touched
 func (r *UserRepository) ResetFailedLogins(ctx context.Context, id string) error {
-	updates := map[string]interface{}{
-		"failed_logins":  0,
-		"last_failed_at": nil,
-	}
-	
-	return r.Update(ctx, id, updates)
+	return r.bookkeeping(ctx, id, "reset failed logins", bson.M{
+		"$set": bson.M{
+			"failed_logins":  0,
+			"last_failed_at": nil,
+			"updated_at":     utils.Now(),
+		},
+	})
 }
 
 // MarkAsVerified marks user as email verified
+// It is bookkeeping, so the version is not touched
 func (r *UserRepository) MarkAsVerified(ctx context.Context, id string) error {
-	updates := map[string]interface{}{
-		"is_verified":        true,
-		"email_verified_at": time.Now().UTC(),
+	now := utils.Now()
+	return r.bookkeeping(ctx, id, "mark user as verified", bson.M{
+		"$set": bson.M{
+			"is_verified":       true,
+			"email_verified_at": now,
+			"updated_at":        now,
+		},
+	})
+}
+
+// bookkeeping applies a raw update to a user that has not been soft-deleted, without
+// versioning it, and reports a missing user as not found
+// Bookkeeping writes record what the system observed rather than edits a client made,
+// so they must not make a client's pending If-Match update fail with a version conflict.
+func (r *UserRepository) bookkeeping(ctx context.Context, id, action string, update bson.M) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return fmt.Errorf("invalid user ID format: %w", err)
 	}
 	
-	return r.Update(ctx, id, updates)
+	result, err := r.collection.UpdateOne(ctx, notDeleted(bson.M{"_id": objectID}), update)
+	if err != nil {
+		return fmt.Errorf("failed to %s: %w", action, err)
+	}
+	
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("user %w", interfaces.ErrNotFound)
+	}
+	
+	return nil
 }
 
 // UpdateStatus updates user's active status
@@ -560,7 +678,22 @@ func (r *UserRepository) UpdateStatus(ctx context.Context, id string, isActive b
 	return r.Update(ctx, id, updates)
 }
 
-// CreateMany creates multiple users in a single operation
+// BulkInsertError is returned by CreateMany when some users of the batch could not be inserted
+// The rest of the batch was inserted. Failures maps the index of each rejected user in the batch
+// to the reason; duplicate usernames and emails wrap interfaces.ErrAlreadyExists.
+type BulkInsertError struct {
+	Inserted int
+	Failures map[int]error
+}
+
+// Error implements the error interface
+func (e *BulkInsertError) Error() string {
+	return fmt.Sprintf("failed to create %d of %d users", len(e.Failures), e.Inserted+len(e.Failures))
+}
+
+// CreateMany inserts multiple users in a single unordered operation
+// Every user that can be inserted is, and gets its ID set; if any are rejected a
+// *BulkInsertError reports which. Other failures abort the batch.
 func (r *UserRepository) CreateMany(ctx context.Context, users []*models.User) error {
 	if len(users) == 0 {
 		return nil
@@ -568,33 +701,64 @@ func (r *UserRepository) CreateMany(ctx context.Context, users []*models.User) e
 	
 	documents := make([]interface{}, len(users))
 	for i, user := range users {
+		normalizeUserIdentity(user)
 		documents[i] = user
 	}
 	
-	result, err := r.collection.InsertMany(ctx, documents)
+	result, err := r.collection.InsertMany(ctx, documents, options.InsertMany().SetOrdered(false))
+	
+	var failures map[int]error
 	if err != nil {
-		return fmt.Errorf("failed to create multiple users: %w", err)
+		var bulkErr mongo.BulkWriteException
+		if !errors.As(err, &bulkErr) || bulkErr.WriteConcernError != nil || len(bulkErr.WriteErrors) == 0 {
+			return fmt.Errorf("failed to create multiple users: %w", err)
+		}
+		
+		failures = make(map[int]error, len(bulkErr.WriteErrors))
+		for _, writeErr := range bulkErr.WriteErrors {
+			if mongo.IsDuplicateKeyError(writeErr.WriteError) {
+				failures[writeErr.Index] = duplicateKeyFieldError(writeErr.Message)
+			} else {
+				failures[writeErr.Index] = fmt.Errorf("failed to create user: %s", writeErr.Message)
+			}
+		}
 	}
 	
-	// Update user IDs with generated ones
+	// Update user IDs with generated ones; rejected users are left unchanged
 	for i, id := range result.InsertedIDs {
+		if _, failed := failures[i]; failed {
+			continue
+		}
 		if oid, ok := id.(primitive.ObjectID); ok && i < len(users) {
 			users[i].ID = oid
 		}
 	}
 	
+	if len(failures) > 0 {
+		return &BulkInsertError{Inserted: len(users) - len(failures), Failures: failures}
+	}
 	return nil
 }
 
+// duplicateKeyFieldError converts a duplicate key error message into an ErrAlreadyExists
+// error naming the field, like the errors Create returns
+func duplicateKeyFieldError(message string) error {
+	field := "user"
+	if _, key, ok := strings.Cut(message, "dup key: { "); ok {
+		if name, _, ok := strings.Cut(key, ":"); ok && strings.TrimSpace(name) != "_id" {
+			field = strings.TrimSpace(name)
+		}
+	}
+	return fmt.Errorf("%s %w", field, interfaces.ErrAlreadyExists)
+}
+
 // UpdateMany updates multiple users matching the filter
 func (r *UserRepository) UpdateMany(ctx context.Context, filter map[string]interface{}, updates map[string]interface{}) error {
-	// Add updated_at timestamp
-	updates["updated_at"] = time.Now().UTC()
-	
 	// Ensure we don't update soft-deleted users
 	filter["deleted_at"] = bson.M{"$exists": false}
 	
-	update := bson.M{"$set": updates}
+	normalizeUserUpdates(updates)
+	update := versionedUpdate(ctx, updates)
 	
 	_, err := r.collection.UpdateMany(ctx, filter, update)
 	if err != nil {
@@ -629,37 +793,191 @@ func (r *UserRepository) DeleteMany(ctx context.Context, ids []string) error {
 	return nil
 }
 
-// GetUserStats returns user statistics
-func (r *UserRepository) GetUserStats(ctx context.Context) (map[string]interface{}, error) {
+// SoftDeleteMany soft deletes and deactivates multiple users
+// Users that are already deleted are left untouched; it returns how many were deleted.
+func (r *UserRepository) SoftDeleteMany(ctx context.Context, ids []string) (int64, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	
+	objectIDs := make([]primitive.ObjectID, len(ids))
+	for i, id := range ids {
+		objectID, err := primitive.ObjectIDFromHex(id)
+		if err != nil {
+			return 0, fmt.Errorf("invalid user ID format at index %d: %w", i, err)
+		}
+		objectIDs[i] = objectID
+	}
+	
+	filter := bson.M{
+		"_id":        bson.M{"$in": objectIDs},
+		"deleted_at": bson.M{"$exists": false},
+	}
+	update := versionedUpdate(ctx, map[string]interface{}{
+		"deleted_at": utils.Now(),
+		"is_active":  false,
+	})
+	
+	result, err := r.collection.UpdateMany(ctx, filter, update)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete multiple users: %w", err)
+	}
+	
+	return result.ModifiedCount, nil
+}
+
+// roleDistributionStages counts users per role; a user with several roles counts towards each
+func roleDistributionStages() []bson.M {
+	return []bson.M{
+		{"$unwind": "$roles"},
+		{"$group": bson.M{"_id": "$roles", "count": bson.M{"$sum": 1}}},
+	}
+}
+
+// roleCount is a result document of roleDistributionStages
+type roleCount struct {
+	Role  string `bson:"_id"`
+	Count int    `bson:"count"`
+}
+
+// RoleDistribution counts the users holding each role in a single aggregation
+// Roles no user holds are absent from the result.
+func (r *UserRepository) RoleDistribution(ctx context.Context) (map[string]int, error) {
+	pipeline := append([]bson.M{{"$match": bson.M{"deleted_at": bson.M{"$exists": false}}}}, roleDistributionStages()...)
+	
+	cursor, err := r.analytics.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get role distribution: %w", err)
+	}
+	defer cursor.Close(ctx)
+	
+	var counts []roleCount
+	if err := cursor.All(ctx, &counts); err != nil {
+		return nil, fmt.Errorf("failed to decode role distribution: %w", err)
+	}
+	
+	distribution := make(map[string]int, len(counts))
+	for _, count := range counts {
+		distribution[count.Role] = count.Count
+	}
+	return distribution, nil
+}
+
+// GetUserStats returns user statistics, optionally restricted to users created within a date range
+func (r *UserRepository) GetUserStats(ctx context.Context, params *models.UserStatsParams) (*models.UserStatsResponse, error) {
+	match := bson.M{"deleted_at": bson.M{"$exists": false}}
+	if params.HasDateRange() {
+		createdAt := bson.M{}
+		if params.From != nil {
+			createdAt["$gte"] = *params.From
+		}
+		if params.To != nil {
+			createdAt["$lt"] = *params.To
+		}
+		match["created_at"] = createdAt
+	}
+	
+	weekAgo := utils.Now().AddDate(0, 0, -7)
+	
 	pipeline := []bson.M{
-		{"$match": bson.M{"deleted_at": bson.M{"$exists": false}}},
-		{"$group": bson.M{
-			"_id": nil,
-			"total_users": bson.M{"$sum": 1},
-			"active_users": bson.M{"$sum": bson.M{"$cond": []interface{}{
-				"$is_active", 1, 0,
-			}}},
-			"verified_users": bson.M{"$sum": bson.M{"$cond": []interface{}{
-				"$is_verified", 1, 0,
-			}}},
-			"avg_login_count": bson.M{"$avg": "$login_count"},
+		{"$match": match},
+		{"$facet": bson.M{
+			"totals": []bson.M{
+				{"$group": bson.M{
+					"_id": nil,
+					"total_users": bson.M{"$sum": 1},
+					"active_users": bson.M{"$sum": bson.M{"$cond": []interface{}{
+						"$is_active", 1, 0,
+					}}},
+					"verified_users": bson.M{"$sum": bson.M{"$cond": []interface{}{
+						"$is_verified", 1, 0,
+					}}},
+					"avg_login_count": bson.M{"$avg": "$login_count"},
+				}},
+			},
+			"by_role": roleDistributionStages(),
+			"new_users": []bson.M{
+				{"$match": bson.M{"created_at": bson.M{"$gte": weekAgo}}},
+				{"$count": "count"},
+			},
 		}},
 	}
 	
-	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	cursor, err := r.analytics.Aggregate(ctx, pipeline)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user stats: %w", err)
 	}
 	defer cursor.Close(ctx)
 	
-	var result map[string]interface{}
+	var result struct {
+		Totals []struct {
+			TotalUsers    int     `bson:"total_users"`
+			ActiveUsers   int     `bson:"active_users"`
+			VerifiedUsers int     `bson:"verified_users"`
+			AvgLoginCount float64 `bson:"avg_login_count"`
+		} `bson:"totals"`
+		ByRole []roleCount `bson:"by_role"`
+		NewUsers []struct {
+			Count int `bson:"count"`
+		} `bson:"new_users"`
+	}
 	if cursor.Next(ctx) {
 		if err := cursor.Decode(&result); err != nil {
 			return nil, fmt.Errorf("failed to decode stats: %w", err)
 		}
 	}
 	
-	return result, nil
+	stats := &models.UserStatsResponse{ByRole: make(map[string]int)}
+	if len(result.Totals) > 0 {
+		stats.TotalUsers = result.Totals[0].TotalUsers
+		stats.ActiveUsers = result.Totals[0].ActiveUsers
+		stats.VerifiedUsers = result.Totals[0].VerifiedUsers
+		stats.AvgLoginCount = result.Totals[0].AvgLoginCount
+	}
+	for _, role := range result.ByRole {
+		stats.ByRole[role.Role] = role.Count
+	}
+	if len(result.NewUsers) > 0 {
+		stats.NewUsersLast7Days = result.NewUsers[0].Count
+	}
+	
+	return stats, nil
+}
+
+// CountByDay counts users created in [from, to) per UTC day, ordered by date
+// Days without signups are omitted.
+func (r *UserRepository) CountByDay(ctx context.Context, from, to time.Time) ([]models.DayCount, error) {
+	pipeline := []bson.M{
+		{"$match": bson.M{
+			"created_at": bson.M{"$gte": from, "$lt": to},
+			"deleted_at": bson.M{"$exists": false},
+		}},
+		{"$group": bson.M{
+			"_id": bson.M{"$dateToString": bson.M{"format": "%Y-%m-%d", "date": "$created_at"}},
+			"count": bson.M{"$sum": 1},
+		}},
+		{"$sort": bson.M{"_id": 1}},
+	}
+	
+	cursor, err := r.analytics.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count users by day: %w", err)
+	}
+	defer cursor.Close(ctx)
+	
+	var rows []struct {
+		Date  string `bson:"_id"`
+		Count int    `bson:"count"`
+	}
+	if err := cursor.All(ctx, &rows); err != nil {
+		return nil, fmt.Errorf("failed to decode daily counts: %w", err)
+	}
+	
+	counts := make([]models.DayCount, len(rows))
+	for i, row := range rows {
+		counts[i] = models.DayCount{Date: row.Date, Count: row.Count}
+	}
+	return counts, nil
 }
 
 // GetUsersByDateRange retrieves users created within a date range
@@ -703,10 +1021,10 @@ func (r *UserRepository) GetUsersByDateRange(ctx context.Context, startDate, end
 	return users, nil
 }
 
-// Cleanup removes soft-deleted users older than specified days
-func (r *UserRepository) Cleanup(ctx context.Context) error {
-	// Remove users soft-deleted more than 30 days ago
-	cutoffDate := time.Now().UTC().AddDate(0, 0, -30)
+// Cleanup permanently removes users soft-deleted more than retention ago
+// It returns how many users were removed.
+func (r *UserRepository) Cleanup(ctx context.Context, retention time.Duration) (int64, error) {
+	cutoffDate := utils.Now().Add(-retention)
 	
 	filter := bson.M{
 		"deleted_at": bson.M{
@@ -717,11 +1035,11 @@ func (r *UserRepository) Cleanup(ctx context.Context) error {
 	
 	result, err := r.collection.DeleteMany(ctx, filter)
 	if err != nil {
-		return fmt.Errorf("failed to cleanup users: %w", err)
+		return 0, fmt.Errorf("failed to cleanup users: %w", err)
 	}
 	
-	log.Printf("Cleaned up %d old soft-deleted users", result.DeletedCount)
-	return nil
+	r.logger.WithContext(ctx).Info("Cleaned up soft-deleted users", "deleted", result.DeletedCount, "retention", retention)
+	return result.DeletedCount, nil
 }
 
 // Ping checks if the database connection is healthy
@@ -734,11 +1052,11 @@ func (r *UserRepository) EnsureIndexes(ctx context.Context) error {
 	indexes := []mongo.IndexModel{
 		{
 			Keys:    bson.D{{Key: "username", Value: 1}},
-			Options: options.Index().SetUnique(true).SetName("idx_users_username"),
+			Options: options.Index().SetUnique(true).SetCollation(caseInsensitive).SetName("idx_users_username_ci"),
 		},
 		{
 			Keys:    bson.D{{Key: "email", Value: 1}},
-			Options: options.Index().SetUnique(true).SetName("idx_users_email"),
+			Options: options.Index().SetUnique(true).SetCollation(caseInsensitive).SetName("idx_users_email_ci"),
 		},
 		{
 			Keys:    bson.D{{Key: "created_at", Value: -1}},
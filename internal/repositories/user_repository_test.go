@@ -0,0 +1,1093 @@
+// internal/repositories/user_repository_test.go
+package repositories
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+
+	"go-template/internal/interfaces"
+	"go-template/internal/models"
+	"go-template/internal/shared/logtest"
+	"go-template/internal/shared/utils"
+)
+
+// newMockUserRepository returns a UserRepository over mt's mocked deployment
+func newMockUserRepository(mt *mtest.T) UserRepositoryInterface {
+	return NewUserRepository(mt.DB, logtest.New())
+}
+
+// sentUpdate returns the filter and update of the first statement of the update command mt
+// last sent
+func sentUpdate(mt *mtest.T) (bson.Raw, bson.Raw) {
+	mt.Helper()
+
+	started := mt.GetStartedEvent()
+	if started == nil || started.CommandName != "update" {
+		mt.Fatalf("started command = %v, want update", started)
+	}
+	statement := started.Command.Lookup("updates").Array().Index(0).Value().Document()
+	return statement.Lookup("q").Document(), statement.Lookup("u").Document()
+}
+
+func TestUserRepositoryCreateMany(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	tests := []struct {
+		name         string
+		reply        bson.D
+		wantFailures map[int]string
+		wantErr      bool
+	}{
+		{
+			name:  "all inserted",
+			reply: mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 3}),
+		},
+		{
+			name: "duplicates are reported per item",
+			reply: mtest.CreateWriteErrorsResponse(
+				mtest.WriteError{Index: 1, Code: 11000, Message: `E11000 duplicate key error collection: app.users index: username_1 dup key: { username: "bob" }`},
+				mtest.WriteError{Index: 2, Code: 11000, Message: `E11000 duplicate key error collection: app.users index: email_1 dup key: { email: "carol@example.com" }`},
+			),
+			wantFailures: map[int]string{1: "username already exists", 2: "email already exists"},
+		},
+		{
+			name:    "other failures abort the batch",
+			reply:   mtest.CreateCommandErrorResponse(mtest.CommandError{Code: 2, Message: "bad value"}),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		mt.Run(tt.name, func(mt *mtest.T) {
+			repo := newMockUserRepository(mt)
+			mt.AddMockResponses(tt.reply)
+
+			users := []*models.User{
+				models.NewTestUser(models.WithUsername("alice")),
+				models.NewTestUser(models.WithUsername("bob")),
+				models.NewTestUser(models.WithUsername("carol")),
+			}
+			err := repo.CreateMany(context.Background(), users)
+
+			// The batch must be sent unordered so one duplicate does not stop the rest
+			if started := mt.GetStartedEvent(); started == nil {
+				mt.Fatal("no insert command sent")
+			} else if ordered, ok := started.Command.Lookup("ordered").BooleanOK(); !ok || ordered {
+				mt.Errorf("insert ordered = %v, want false", started.Command.Lookup("ordered"))
+			}
+
+			var bulkErr *BulkInsertError
+			switch {
+			case tt.wantErr:
+				if err == nil || errors.As(err, &bulkErr) {
+					mt.Fatalf("CreateMany() error = %v, want a batch failure", err)
+				}
+				return
+			case len(tt.wantFailures) == 0:
+				if err != nil {
+					mt.Fatalf("CreateMany() error = %v", err)
+				}
+			default:
+				if !errors.As(err, &bulkErr) {
+					mt.Fatalf("CreateMany() error = %v, want *BulkInsertError", err)
+				}
+				if bulkErr.Inserted != len(users)-len(tt.wantFailures) {
+					mt.Errorf("Inserted = %d, want %d", bulkErr.Inserted, len(users)-len(tt.wantFailures))
+				}
+				for i, want := range tt.wantFailures {
+					got := bulkErr.Failures[i]
+					if !errors.Is(got, interfaces.ErrAlreadyExists) || got.Error() != want {
+						mt.Errorf("failure %d = %v, want %q", i, got, want)
+					}
+				}
+			}
+
+			for i, user := range users {
+				if _, failed := tt.wantFailures[i]; !failed && user.ID.IsZero() {
+					mt.Errorf("inserted user %d has no ID", i)
+				}
+			}
+		})
+	}
+}
+
+func TestUserRepositoryRestore(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	tests := []struct {
+		name    string
+		matched int
+		wantErr error
+	}{
+		{name: "deleted user", matched: 1},
+		{name: "user that is not deleted", matched: 0, wantErr: interfaces.ErrNotFound},
+	}
+
+	for _, tt := range tests {
+		mt.Run(tt.name, func(mt *mtest.T) {
+			repo := newMockUserRepository(mt)
+			mt.AddMockResponses(mtest.CreateSuccessResponse(
+				bson.E{Key: "n", Value: tt.matched},
+				bson.E{Key: "nModified", Value: tt.matched},
+			))
+
+			err := repo.Restore(context.Background(), "507f1f77bcf86cd799439011")
+			if !errors.Is(err, tt.wantErr) {
+				mt.Fatalf("Restore() error = %v, want %v", err, tt.wantErr)
+			}
+
+			filter, update := sentUpdate(mt)
+			if _, err := filter.LookupErr("deleted_at", "$exists"); err != nil {
+				mt.Errorf("filter %v does not require deleted_at", filter)
+			}
+			if _, err := update.LookupErr("$unset", "deleted_at"); err != nil {
+				mt.Errorf("update %v does not unset deleted_at", update)
+			}
+			if active, ok := update.Lookup("$set", "is_active").BooleanOK(); !ok || !active {
+				mt.Errorf("update %v does not set is_active", update)
+			}
+		})
+	}
+}
+
+func TestUserRepositoryGetUserStats(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	from := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2026, 3, 16, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		params   *models.UserStatsParams
+		wantFrom *time.Time
+		wantTo   *time.Time
+	}{
+		{name: "all users", params: &models.UserStatsParams{}},
+		{name: "date range", params: &models.UserStatsParams{From: &from, To: &to}, wantFrom: &from, wantTo: &to},
+		{name: "from only", params: &models.UserStatsParams{From: &from}, wantFrom: &from},
+		{name: "to only", params: &models.UserStatsParams{To: &to}, wantTo: &to},
+	}
+
+	for _, tt := range tests {
+		mt.Run(tt.name, func(mt *mtest.T) {
+			repo := newMockUserRepository(mt)
+			mt.AddMockResponses(mtest.CreateCursorResponse(0, "app.users", mtest.FirstBatch, bson.D{
+				{Key: "totals", Value: bson.A{bson.D{
+					{Key: "_id", Value: nil},
+					{Key: "total_users", Value: 3},
+					{Key: "active_users", Value: 2},
+					{Key: "verified_users", Value: 2},
+					{Key: "avg_login_count", Value: 2.0},
+				}}},
+				{Key: "by_role", Value: bson.A{
+					bson.D{{Key: "_id", Value: models.RoleUser}, {Key: "count", Value: 2}},
+					bson.D{{Key: "_id", Value: models.RoleAdmin}, {Key: "count", Value: 1}},
+				}},
+				{Key: "new_users", Value: bson.A{bson.D{{Key: "count", Value: 1}}}},
+			}))
+
+			stats, err := repo.GetUserStats(context.Background(), tt.params)
+			if err != nil {
+				mt.Fatalf("GetUserStats() error = %v", err)
+			}
+
+			want := models.UserStatsResponse{
+				TotalUsers: 3, ActiveUsers: 2, VerifiedUsers: 2, AvgLoginCount: 2,
+				ByRole:            map[string]int{models.RoleUser: 2, models.RoleAdmin: 1},
+				NewUsersLast7Days: 1,
+			}
+			if !reflect.DeepEqual(*stats, want) {
+				mt.Errorf("GetUserStats() = %+v, want %+v", *stats, want)
+			}
+
+			started := mt.GetStartedEvent()
+			if started == nil || started.CommandName != "aggregate" {
+				mt.Fatalf("started command = %v, want aggregate", started)
+			}
+			match := started.Command.Lookup("pipeline").Array().Index(0).Value().Document().Lookup("$match").Document()
+			if _, err := match.LookupErr("deleted_at", "$exists"); err != nil {
+				mt.Errorf("$match %v does not exclude deleted users", match)
+			}
+			assertTimeBound(mt, match, "$gte", tt.wantFrom)
+			assertTimeBound(mt, match, "$lt", tt.wantTo)
+		})
+	}
+}
+
+// assertTimeBound checks the created_at bound op of a $match stage, or that it is absent
+func assertTimeBound(mt *mtest.T, match bson.Raw, op string, want *time.Time) {
+	mt.Helper()
+
+	value, err := match.LookupErr("created_at", op)
+	if want == nil {
+		if err == nil {
+			mt.Errorf("$match has created_at.%s = %v, want none", op, value)
+		}
+		return
+	}
+	if err != nil {
+		mt.Fatalf("$match %v has no created_at.%s", match, op)
+	}
+	if got := value.Time(); !got.Equal(*want) {
+		mt.Errorf("created_at.%s = %v, want %v", op, got, *want)
+	}
+}
+
+func TestUserRepositoryRoleDistribution(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	roleDoc := func(role string, count int) bson.D {
+		return bson.D{{Key: "_id", Value: role}, {Key: "count", Value: count}}
+	}
+
+	tests := []struct {
+		name    string
+		reply   bson.D
+		want    map[string]int
+		wantErr string
+	}{
+		{
+			name:  "every role present",
+			reply: mtest.CreateCursorResponse(0, "app.users", mtest.FirstBatch, roleDoc(models.RoleUser, 3), roleDoc(models.RoleAdmin, 1), roleDoc(models.RoleMod, 2)),
+			want:  map[string]int{models.RoleUser: 3, models.RoleAdmin: 1, models.RoleMod: 2},
+		},
+		{
+			name:  "no users",
+			reply: mtest.CreateCursorResponse(0, "app.users", mtest.FirstBatch),
+			want:  map[string]int{},
+		},
+		{
+			name:    "aggregation error",
+			reply:   mtest.CreateCommandErrorResponse(mtest.CommandError{Code: 2, Message: "bad value"}),
+			wantErr: "failed to get role distribution",
+		},
+	}
+
+	for _, tt := range tests {
+		mt.Run(tt.name, func(mt *mtest.T) {
+			repo := newMockUserRepository(mt)
+			mt.AddMockResponses(tt.reply)
+
+			got, err := repo.RoleDistribution(context.Background())
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					mt.Fatalf("RoleDistribution() error = %v, want it to mention %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				mt.Fatalf("RoleDistribution() error = %v", err)
+			}
+			if got == nil || !reflect.DeepEqual(got, tt.want) {
+				mt.Errorf("RoleDistribution() = %#v, want %#v", got, tt.want)
+			}
+
+			// One query: live users only, unwound by role and counted
+			started := mt.GetStartedEvent()
+			if started == nil || started.CommandName != "aggregate" {
+				mt.Fatalf("started command = %v, want aggregate", started)
+			}
+			stages, _ := started.Command.Lookup("pipeline").Array().Values()
+			if len(stages) != 3 {
+				mt.Fatalf("pipeline has %d stages, want $match, $unwind and $group", len(stages))
+			}
+			if _, err := stages[0].Document().LookupErr("$match", "deleted_at", "$exists"); err != nil {
+				mt.Errorf("first stage %v does not exclude deleted users", stages[0])
+			}
+			if unwind := stages[1].Document().Lookup("$unwind").StringValue(); unwind != "$roles" {
+				mt.Errorf("$unwind = %q, want $roles", unwind)
+			}
+			group := stages[2].Document().Lookup("$group").Document()
+			if group.Lookup("_id").StringValue() != "$roles" || group.Lookup("count", "$sum").AsInt64() != 1 {
+				mt.Errorf("$group = %v, want a count per role", group)
+			}
+		})
+	}
+}
+
+func TestBuildSort(t *testing.T) {
+	tests := []struct {
+		name    string
+		sortBy  string
+		sortDir string
+		want    bson.D
+	}{
+		{
+			name: "default",
+			want: bson.D{{Key: "created_at", Value: -1}},
+		},
+		{
+			name:   "single field uses sort_dir",
+			sortBy: "username", sortDir: "desc",
+			want: bson.D{{Key: "username", Value: -1}},
+		},
+		{
+			name:   "multiple fields keep their order",
+			sortBy: "last_name,created_at",
+			want:   bson.D{{Key: "last_name", Value: 1}, {Key: "created_at", Value: 1}},
+		},
+		{
+			name:   "per-field direction",
+			sortBy: "last_name, -created_at", sortDir: "asc",
+			want: bson.D{{Key: "last_name", Value: 1}, {Key: "created_at", Value: -1}},
+		},
+		{
+			name:   "prefix overrides sort_dir",
+			sortBy: "-login_count,username", sortDir: "desc",
+			want: bson.D{{Key: "login_count", Value: -1}, {Key: "username", Value: -1}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params := &models.UsersQueryParams{SortBy: tt.sortBy, SortDir: tt.sortDir}
+			params.SetDefaults()
+			if got := buildSort(params.Sort); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("buildSort() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUserRepositorySearchQueries(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	tests := []struct {
+		name   string
+		search func(repo UserRepositoryInterface) ([]*models.User, error)
+		check  func(mt *mtest.T, filter, sort bson.Raw)
+	}{
+		{
+			name: "regex",
+			search: func(repo UserRepositoryInterface) ([]*models.User, error) {
+				return repo.Search(context.Background(), "a.b", 5)
+			},
+			check: func(mt *mtest.T, filter, sort bson.Raw) {
+				conditions, err := filter.LookupErr("$or")
+				if err != nil {
+					mt.Fatalf("filter %v has no $or", filter)
+				}
+				values, _ := conditions.Array().Values()
+				if len(values) != 4 {
+					mt.Errorf("got %d regex conditions, want 4", len(values))
+				}
+				pattern := values[0].Document().Lookup("username", "$regex").StringValue()
+				if pattern != `a\.b` {
+					mt.Errorf("pattern = %q, want the query escaped", pattern)
+				}
+				if len(sort) != 0 {
+					mt.Errorf("sort = %v, want none", sort)
+				}
+			},
+		},
+		{
+			name: "text",
+			search: func(repo UserRepositoryInterface) ([]*models.User, error) {
+				return repo.SearchText(context.Background(), "alice smith", 5)
+			},
+			check: func(mt *mtest.T, filter, sort bson.Raw) {
+				if got := filter.Lookup("$text", "$search").StringValue(); got != "alice smith" {
+					mt.Errorf("$search = %q, want %q", got, "alice smith")
+				}
+				if got := sort.Lookup("score", "$meta").StringValue(); got != "textScore" {
+					mt.Errorf("sort = %v, want textScore relevance", sort)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		mt.Run(tt.name, func(mt *mtest.T) {
+			repo := newMockUserRepository(mt)
+			mt.AddMockResponses(mtest.CreateCursorResponse(0, "test.users", mtest.FirstBatch,
+				bson.D{{Key: "_id", Value: primitive.NewObjectID()}, {Key: "username", Value: "alice"}},
+			))
+
+			users, err := tt.search(repo)
+			if err != nil {
+				mt.Fatalf("search error = %v", err)
+			}
+			if len(users) != 1 || users[0].Username != "alice" {
+				mt.Errorf("users = %v, want alice", users)
+			}
+
+			started := mt.GetStartedEvent()
+			if started == nil || started.CommandName != "find" {
+				mt.Fatalf("started command = %v, want find", started)
+			}
+			filter := started.Command.Lookup("filter").Document()
+			if _, err := filter.LookupErr("deleted_at", "$exists"); err != nil {
+				mt.Errorf("filter %v does not exclude deleted users", filter)
+			}
+			if limit := started.Command.Lookup("limit").AsInt64(); limit != 5 {
+				mt.Errorf("limit = %d, want 5", limit)
+			}
+			sort, _ := started.Command.Lookup("sort").DocumentOK()
+			tt.check(mt, filter, sort)
+		})
+	}
+}
+
+func TestUserRepositoryBookkeepingSkipsVersion(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	id := primitive.NewObjectID().Hex()
+
+	for name, fn := range bookkeepingWrites {
+		mt.Run(name, func(mt *mtest.T) {
+			mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}))
+
+			if err := fn(context.Background(), newMockUserRepository(mt), id); err != nil {
+				mt.Fatalf("%s() error = %v", name, err)
+			}
+			filter, update := sentUpdate(mt)
+			if _, err := filter.LookupErr("deleted_at"); err != nil {
+				mt.Errorf("filter = %v, want soft-deleted users excluded", filter)
+			}
+			if _, err := update.LookupErr("$inc", versionField); err == nil {
+				mt.Errorf("update = %v, want the version left alone", update)
+			}
+		})
+	}
+
+	mt.Run("Update bumps the version", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}))
+
+		if err := newMockUserRepository(mt).Update(context.Background(), id, map[string]interface{}{"bio": "changed"}); err != nil {
+			mt.Fatalf("Update() error = %v", err)
+		}
+		if _, update := sentUpdate(mt); update.Lookup("$inc", versionField).Type == 0 {
+			mt.Errorf("update = %v, want the version incremented", update)
+		}
+	})
+}
+
+func TestUserRepositoryIncrementField(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	id := primitive.NewObjectID().Hex()
+
+	tests := []struct {
+		name     string
+		id       string
+		field    string
+		by       int
+		matched  int32
+		wantSent bool
+		wantErr  error
+	}{
+		{name: "profile views", id: id, field: "profile_views", by: 1, matched: 1, wantSent: true},
+		{name: "login count by several", id: id, field: "login_count", by: 5, matched: 1, wantSent: true},
+		{name: "failed logins are not allowed", id: id, field: "failed_logins", by: 1, wantErr: interfaces.ErrValidation},
+		{name: "token version is not allowed", id: id, field: "token_version", by: 1, wantErr: interfaces.ErrValidation},
+		{name: "operators are not allowed", id: id, field: "$set", by: 1, wantErr: interfaces.ErrValidation},
+		{name: "missing user", id: id, field: "profile_views", by: 1, matched: 0, wantSent: true, wantErr: interfaces.ErrNotFound},
+	}
+
+	for _, tt := range tests {
+		mt.Run(tt.name, func(mt *mtest.T) {
+			mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: tt.matched}, bson.E{Key: "nModified", Value: tt.matched}))
+
+			err := newMockUserRepository(mt).IncrementField(context.Background(), tt.id, tt.field, tt.by)
+			if !errors.Is(err, tt.wantErr) {
+				mt.Fatalf("IncrementField() error = %v, want %v", err, tt.wantErr)
+			}
+			if !tt.wantSent {
+				// Disallowed fields are rejected before reaching the database
+				if started := mt.GetStartedEvent(); started != nil {
+					mt.Errorf("command %s sent for a disallowed field", started.CommandName)
+				}
+				return
+			}
+
+			filter, update := sentUpdate(mt)
+			if got := filter.Lookup("_id").ObjectID().Hex(); got != tt.id {
+				mt.Errorf("filter _id = %s, want %s", got, tt.id)
+			}
+			if got := update.Lookup("$inc", tt.field).AsInt64(); got != int64(tt.by) {
+				mt.Errorf("update = %v, want %s incremented by %d", update, tt.field, tt.by)
+			}
+			if elems, _ := update.Lookup("$inc").Document().Elements(); len(elems) != 1 {
+				mt.Errorf("update = %v, want only %s incremented", update, tt.field)
+			}
+		})
+	}
+
+	mt.Run("invalid ID", func(mt *mtest.T) {
+		err := newMockUserRepository(mt).IncrementField(context.Background(), "not-an-id", "profile_views", 1)
+		if err == nil || !strings.Contains(err.Error(), "invalid user ID format") {
+			mt.Errorf("IncrementField() error = %v, want an invalid ID error", err)
+		}
+	})
+}
+
+func TestUserRepositoryUpdateWithVersion(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	id := primitive.NewObjectID().Hex()
+
+	tests := []struct {
+		name    string
+		matched int
+		// existing is the count reported when the versioned update matched nothing
+		existing int
+		wantErr  error
+	}{
+		{name: "version matches", matched: 1},
+		{name: "stale version", existing: 1, wantErr: ErrVersionConflict},
+		{name: "missing user", wantErr: interfaces.ErrNotFound},
+	}
+
+	for _, tt := range tests {
+		mt.Run(tt.name, func(mt *mtest.T) {
+			mt.AddMockResponses(
+				mtest.CreateSuccessResponse(bson.E{Key: "n", Value: tt.matched}, bson.E{Key: "nModified", Value: tt.matched}),
+				mtest.CreateCursorResponse(0, "test.users", mtest.FirstBatch, bson.D{{Key: "n", Value: int32(tt.existing)}}),
+			)
+
+			err := newMockUserRepository(mt).UpdateWithVersion(context.Background(), id, 3, map[string]interface{}{"bio": "changed"})
+			if !errors.Is(err, tt.wantErr) {
+				mt.Fatalf("UpdateWithVersion() error = %v, want %v", err, tt.wantErr)
+			}
+
+			started := mt.GetAllStartedEvents()
+			if len(started) == 0 || started[0].CommandName != "update" {
+				mt.Fatalf("first command = %v, want update", started)
+			}
+			statement := started[0].Command.Lookup("updates").Array().Index(0).Value().Document()
+			if got := statement.Lookup("q", versionField).AsInt64(); got != 3 {
+				mt.Errorf("filter version = %d, want 3", got)
+			}
+		})
+	}
+}
+
+func TestUserRepositoryUpdateRecordsActor(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	id := primitive.NewObjectID().Hex()
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want string
+	}{
+		{name: "with actor", ctx: WithActor(context.Background(), "665f00000000000000000001"), want: "665f00000000000000000001"},
+		{name: "without actor", ctx: context.Background()},
+		{name: "empty actor", ctx: WithActor(context.Background(), "")},
+	}
+
+	for _, tt := range tests {
+		mt.Run(tt.name, func(mt *mtest.T) {
+			mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}))
+
+			if err := newMockUserRepository(mt).Update(tt.ctx, id, map[string]interface{}{"bio": "changed"}); err != nil {
+				mt.Fatalf("Update() error = %v", err)
+			}
+			_, update := sentUpdate(mt)
+			got, _ := update.Lookup("$set", "updated_by").StringValueOK()
+			if got != tt.want {
+				mt.Errorf("$set.updated_by = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUserRepositoryGetByIDs(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	first, second := primitive.NewObjectID(), primitive.NewObjectID()
+
+	tests := []struct {
+		name      string
+		ids       []string
+		wantQuery bool
+		wantErr   bool
+	}{
+		{name: "single $in query", ids: []string{first.Hex(), second.Hex()}, wantQuery: true},
+		{name: "invalid ID", ids: []string{first.Hex(), "nope"}, wantErr: true},
+		{name: "no IDs", ids: []string{}},
+	}
+
+	for _, tt := range tests {
+		mt.Run(tt.name, func(mt *mtest.T) {
+			mt.AddMockResponses(mtest.CreateCursorResponse(0, "test.users", mtest.FirstBatch,
+				bson.D{{Key: "_id", Value: first}, {Key: "username", Value: "alice"}}))
+
+			users, err := newMockUserRepository(mt).GetByIDs(context.Background(), tt.ids)
+			if (err != nil) != tt.wantErr {
+				mt.Fatalf("GetByIDs() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			started := mt.GetAllStartedEvents()
+			if !tt.wantQuery {
+				if len(started) != 0 {
+					mt.Errorf("sent %d commands, want none", len(started))
+				}
+				return
+			}
+			if len(started) != 1 || started[0].CommandName != "find" {
+				mt.Fatalf("commands = %v, want a single find", started)
+			}
+			filter := started[0].Command.Lookup("filter").Document()
+			in, err := filter.LookupErr("_id", "$in")
+			if err != nil {
+				mt.Fatalf("filter = %v, want _id $in", filter)
+			}
+			if values, _ := in.Array().Values(); len(values) != len(tt.ids) {
+				mt.Errorf("$in has %d IDs, want %d", len(values), len(tt.ids))
+			}
+			if _, err := filter.LookupErr("deleted_at"); err != nil {
+				mt.Errorf("filter = %v, want soft-deleted users excluded", filter)
+			}
+			if len(users) != 1 || users[0].Username != "alice" {
+				mt.Errorf("GetByIDs() = %v, want alice", users)
+			}
+		})
+	}
+}
+
+func TestUserRepositoryGetAllProjection(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	tests := []struct {
+		name   string
+		fields []string
+		want   bson.M // nil when no projection should be sent
+	}{
+		{name: "no fields"},
+		{name: "selected fields", fields: []string{"id", "username"}, want: bson.M{"_id": int32(1), "username": int32(1)}},
+	}
+
+	for _, tt := range tests {
+		mt.Run(tt.name, func(mt *mtest.T) {
+			mt.AddMockResponses(
+				mtest.CreateCursorResponse(0, "test.users", mtest.FirstBatch, bson.D{{Key: "n", Value: int32(1)}}),
+				mtest.CreateCursorResponse(0, "test.users", mtest.FirstBatch,
+					bson.D{{Key: "_id", Value: primitive.NewObjectID()}, {Key: "username", Value: "alice"}}),
+			)
+
+			users, _, err := newMockUserRepository(mt).GetAll(context.Background(), &models.UsersQueryParams{Fields: tt.fields})
+			if err != nil {
+				mt.Fatalf("GetAll() error = %v", err)
+			}
+			if len(users) != 1 || users[0].Username != "alice" {
+				mt.Errorf("users = %v, want alice", users)
+			}
+
+			events := mt.GetAllStartedEvents()
+			find := events[len(events)-1].Command
+			raw, err := find.LookupErr("projection")
+			if tt.want == nil {
+				if err == nil {
+					mt.Errorf("projection = %v, want none", raw)
+				}
+				return
+			}
+			if err != nil {
+				mt.Fatalf("find command %v has no projection", find)
+			}
+			var got bson.M
+			if err := bson.Unmarshal(raw.Document(), &got); err != nil {
+				mt.Fatalf("invalid projection: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				mt.Errorf("projection = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUserRepositoryGetRecentlyLoggedIn(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	tests := []struct {
+		name    string
+		limit   int
+		fail    bool
+		wantErr string
+	}{
+		{name: "sorted and limited", limit: 25},
+		{name: "read error", limit: 25, fail: true, wantErr: "failed to get recently logged in users"},
+	}
+
+	for _, tt := range tests {
+		mt.Run(tt.name, func(mt *mtest.T) {
+			if tt.fail {
+				mt.AddMockResponses(mtest.CreateCommandErrorResponse(mtest.CommandError{Code: 11600, Name: "InterruptedAtShutdown", Message: "interrupted"}))
+			} else {
+				mt.AddMockResponses(mtest.CreateCursorResponse(0, "test.users", mtest.FirstBatch,
+					bson.D{{Key: "_id", Value: primitive.NewObjectID()}, {Key: "username", Value: "alice"}}))
+			}
+
+			users, err := newMockUserRepository(mt).GetRecentlyLoggedIn(context.Background(), tt.limit)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					mt.Fatalf("GetRecentlyLoggedIn() error = %v, want it to mention %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				mt.Fatalf("GetRecentlyLoggedIn() error = %v", err)
+			}
+			if len(users) != 1 || users[0].Username != "alice" {
+				mt.Errorf("users = %v, want alice", users)
+			}
+
+			find := mt.GetStartedEvent().Command
+			if _, err := find.LookupErr("filter", "last_login_at", "$ne"); err != nil {
+				mt.Errorf("filter = %v, want users who never logged in left out", find.Lookup("filter"))
+			}
+			if _, err := find.LookupErr("filter", "deleted_at"); err != nil {
+				mt.Errorf("filter = %v, want soft-deleted users excluded", find.Lookup("filter"))
+			}
+			if sort := find.Lookup("sort"); sort.Document().Lookup("last_login_at").AsInt64() != -1 {
+				mt.Errorf("sort = %v, want last_login_at descending", sort)
+			}
+			if limit := find.Lookup("limit").AsInt64(); limit != int64(tt.limit) {
+				mt.Errorf("limit = %d, want %d", limit, tt.limit)
+			}
+		})
+	}
+}
+
+func TestUserRepositoryStreamAll(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	userDoc := func(username string) bson.D {
+		return bson.D{{Key: "_id", Value: primitive.NewObjectID()}, {Key: "username", Value: username}}
+	}
+	errStop := errors.New("stop")
+
+	tests := []struct {
+		name    string
+		stopAt  string // username at which the callback fails
+		want    []string
+		wantErr error
+	}{
+		{name: "every batch is read", want: []string{"alice", "bob", "carol"}},
+		{name: "callback error stops the stream", stopAt: "bob", want: []string{"alice", "bob"}, wantErr: errStop},
+	}
+
+	for _, tt := range tests {
+		mt.Run(tt.name, func(mt *mtest.T) {
+			mt.AddMockResponses(
+				mtest.CreateCursorResponse(42, "test.users", mtest.FirstBatch, userDoc("alice"), userDoc("bob")),
+				mtest.CreateCursorResponse(0, "test.users", mtest.NextBatch, userDoc("carol")),
+			)
+
+			var got []string
+			err := newMockUserRepository(mt).StreamAll(context.Background(), func(user *models.User) error {
+				got = append(got, user.Username)
+				if user.Username == tt.stopAt {
+					return errStop
+				}
+				return nil
+			})
+			if !errors.Is(err, tt.wantErr) {
+				mt.Fatalf("StreamAll() error = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				mt.Errorf("streamed %v, want %v", got, tt.want)
+			}
+
+			find := mt.GetAllStartedEvents()[0].Command
+			assertNotDeletedFilter(mt, find.Lookup("filter").Document())
+			if batch, ok := find.Lookup("batchSize").AsInt64OK(); !ok || batch != streamBatchSize {
+				mt.Errorf("batchSize = %v, want %d", find.Lookup("batchSize"), streamBatchSize)
+			}
+		})
+	}
+}
+
+func TestNormalizeUserUpdates(t *testing.T) {
+	tests := []struct {
+		name    string
+		updates map[string]interface{}
+		want    map[string]interface{}
+	}{
+		{name: "username", updates: map[string]interface{}{"username": " JohnDoe "}, want: map[string]interface{}{"username": "johndoe"}},
+		{name: "email", updates: map[string]interface{}{"email": "John@Example.COM"}, want: map[string]interface{}{"email": "john@example.com"}},
+		{name: "other fields untouched", updates: map[string]interface{}{"first_name": "John"}, want: map[string]interface{}{"first_name": "John"}},
+		{name: "non-string values untouched", updates: map[string]interface{}{"username": nil}, want: map[string]interface{}{"username": nil}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			normalizeUserUpdates(tt.updates)
+			if !reflect.DeepEqual(tt.updates, tt.want) {
+				t.Errorf("updates = %v, want %v", tt.updates, tt.want)
+			}
+		})
+	}
+}
+
+func TestUserRepositoryUpdateLowercasesIdentity(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("update", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}))
+
+		err := newMockUserRepository(mt).Update(context.Background(), primitive.NewObjectID().Hex(),
+			map[string]interface{}{"username": "JohnDoe", "email": "John@Example.com"})
+		if err != nil {
+			mt.Fatalf("Update() error = %v", err)
+		}
+
+		_, update := sentUpdate(mt)
+		set := update.Lookup("$set").Document()
+		if set.Lookup("username").StringValue() != "johndoe" || set.Lookup("email").StringValue() != "john@example.com" {
+			mt.Errorf("$set = %v, want username and email lowercased", set)
+		}
+	})
+}
+
+func TestUserRepositoryCleanup(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	now := time.Date(2026, 4, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		retention  time.Duration
+		response   bson.D
+		wantCutoff time.Time
+		want       int64
+		wantErr    bool
+	}{
+		{name: "default retention", retention: 30 * 24 * time.Hour, response: mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 2}), wantCutoff: now.AddDate(0, 0, -30), want: 2},
+		{name: "short retention", retention: time.Hour, response: mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}), wantCutoff: now.Add(-time.Hour)},
+		{name: "delete fails", retention: time.Hour, response: mtest.CreateCommandErrorResponse(mtest.CommandError{Code: 1, Message: "boom"}), wantCutoff: now.Add(-time.Hour), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		mt.Run(tt.name, func(mt *mtest.T) {
+			utils.SetClock(utils.NewFixedClock(now))
+			defer utils.SetClock(nil)
+			mt.AddMockResponses(tt.response)
+
+			logger := logtest.New()
+			got, err := NewUserRepository(mt.DB, logger).Cleanup(context.Background(), tt.retention)
+			if (err != nil) != tt.wantErr {
+				mt.Fatalf("Cleanup() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				mt.Errorf("Cleanup() = %d, want %d", got, tt.want)
+			}
+
+			// The purge is logged with its count; a failed one is left to the caller
+			var logged []logtest.Entry
+			for _, entry := range logger.Entries() {
+				if entry.Msg == "Cleaned up soft-deleted users" {
+					logged = append(logged, entry)
+				}
+			}
+			if tt.wantErr {
+				if len(logged) != 0 {
+					mt.Errorf("failed cleanup logged %+v", logged)
+				}
+			} else if len(logged) != 1 || logged[0].Level != slog.LevelInfo {
+				mt.Errorf("cleanup logs = %+v, want one info entry", logged)
+			} else {
+				args := map[interface{}]interface{}{}
+				for i := 0; i+1 < len(logged[0].Args); i += 2 {
+					args[logged[0].Args[i]] = logged[0].Args[i+1]
+				}
+				if args["deleted"] != tt.want || args["retention"] != tt.retention || args["repository"] != "users" {
+					mt.Errorf("cleanup log args = %v, want deleted %d, retention %v and repository users", logged[0].Args, tt.want, tt.retention)
+				}
+			}
+
+			filter := mt.GetStartedEvent().Command.Lookup("deletes").Array().Index(0).Value().Document().Lookup("q").Document()
+			deletedAt := filter.Lookup("deleted_at").Document()
+			if !deletedAt.Lookup("$exists").Boolean() {
+				mt.Errorf("filter = %v, want deleted_at to exist", filter)
+			}
+			if cutoff := deletedAt.Lookup("$lt").Time().UTC(); !cutoff.Equal(tt.wantCutoff) {
+				mt.Errorf("cutoff = %v, want %v", cutoff, tt.wantCutoff)
+			}
+		})
+	}
+}
+
+func TestUserRepositoryReadDatabase(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	countReply := mtest.CreateCursorResponse(0, "test.users", mtest.FirstBatch, bson.D{{Key: "_id", Value: "2026-01-01"}, {Key: "count", Value: 2}})
+	roleReply := mtest.CreateCursorResponse(0, "test.users", mtest.FirstBatch, bson.D{{Key: "_id", Value: models.RoleUser}, {Key: "count", Value: 3}})
+	updateReply := mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1})
+	from, to := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		withRead    bool
+		reply       bson.D
+		call        func(repo UserRepositoryInterface) error
+		wantCommand string
+		wantOnRead  bool
+	}{
+		{
+			name:     "signups by day use the read connection",
+			withRead: true,
+			reply:    countReply,
+			call: func(repo UserRepositoryInterface) error {
+				_, err := repo.CountByDay(context.Background(), from, to)
+				return err
+			},
+			wantCommand: "aggregate",
+			wantOnRead:  true,
+		},
+		{
+			name:     "role distribution uses the read connection",
+			withRead: true,
+			reply:    roleReply,
+			call: func(repo UserRepositoryInterface) error {
+				_, err := repo.RoleDistribution(context.Background())
+				return err
+			},
+			wantCommand: "aggregate",
+			wantOnRead:  true,
+		},
+		{
+			name:     "writes use the primary",
+			withRead: true,
+			reply:    updateReply,
+			call: func(repo UserRepositoryInterface) error {
+				return repo.Update(context.Background(), primitive.NewObjectID().Hex(), map[string]interface{}{"first_name": "Alice"})
+			},
+			wantCommand: "update",
+		},
+		{
+			name:  "analytics fall back to the primary",
+			reply: countReply,
+			call: func(repo UserRepositoryInterface) error {
+				_, err := repo.CountByDay(context.Background(), from, to)
+				return err
+			},
+			wantCommand: "aggregate",
+		},
+	}
+
+	for _, tt := range tests {
+		mt.Run(tt.name, func(primary *mtest.T) {
+			primary.Run("read", func(read *mtest.T) {
+				var opts []UserRepositoryOption
+				if tt.withRead {
+					opts = append(opts, WithReadDatabase(read.DB))
+				}
+				repo := NewUserRepository(primary.DB, logtest.New(), opts...)
+
+				target, other := primary, read
+				if tt.wantOnRead {
+					target, other = read, primary
+				}
+				target.AddMockResponses(tt.reply)
+
+				if err := tt.call(repo); err != nil {
+					read.Fatalf("call error = %v", err)
+				}
+				if started := target.GetStartedEvent(); started == nil || started.CommandName != tt.wantCommand {
+					read.Errorf("command on the expected connection = %v, want %s", started, tt.wantCommand)
+				}
+				if started := other.GetStartedEvent(); started != nil {
+					read.Errorf("unexpected %s on the other connection", started.CommandName)
+				}
+			})
+		})
+	}
+}
+
+func TestUserRepositoryExistsByUsernameOrEmail(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	tests := []struct {
+		name         string
+		matches      []bson.D
+		wantUsername bool
+		wantEmail    bool
+	}{
+		{name: "neither", matches: nil},
+		{
+			name:         "username only",
+			matches:      []bson.D{{{Key: "username", Value: "alice"}, {Key: "email", Value: "other@example.com"}}},
+			wantUsername: true,
+		},
+		{
+			name:      "email only",
+			matches:   []bson.D{{{Key: "username", Value: "other"}, {Key: "email", Value: "alice@example.com"}}},
+			wantEmail: true,
+		},
+		{
+			name: "both on different users",
+			matches: []bson.D{
+				{{Key: "username", Value: "alice"}, {Key: "email", Value: "other@example.com"}},
+				{{Key: "username", Value: "other"}, {Key: "email", Value: "alice@example.com"}},
+			},
+			wantUsername: true,
+			wantEmail:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		mt.Run(tt.name, func(mt *mtest.T) {
+			mt.AddMockResponses(mtest.CreateCursorResponse(0, "test.users", mtest.FirstBatch, tt.matches...))
+
+			usernameExists, emailExists, err := newMockUserRepository(mt).ExistsByUsernameOrEmail(context.Background(), "Alice", "ALICE@example.com")
+			if err != nil {
+				mt.Fatalf("ExistsByUsernameOrEmail() error = %v", err)
+			}
+			if usernameExists != tt.wantUsername || emailExists != tt.wantEmail {
+				mt.Errorf("ExistsByUsernameOrEmail() = %v, %v, want %v, %v", usernameExists, emailExists, tt.wantUsername, tt.wantEmail)
+			}
+
+			started := mt.GetAllStartedEvents()
+			if len(started) != 1 || started[0].CommandName != "find" {
+				mt.Fatalf("commands = %v, want a single find", started)
+			}
+			command := started[0].Command
+			if or, err := command.LookupErr("filter", "$or"); err != nil {
+				mt.Errorf("filter = %v, want an $or over username and email", command.Lookup("filter"))
+			} else if values, _ := or.Array().Values(); len(values) != 2 {
+				mt.Errorf("$or has %d clauses, want 2", len(values))
+			}
+			if _, err := command.LookupErr("filter", "deleted_at"); err != nil {
+				mt.Errorf("filter = %v, want soft-deleted users excluded", command.Lookup("filter"))
+			}
+			if _, err := command.LookupErr("collation"); err != nil {
+				mt.Errorf("command = %v, want a case-insensitive collation", command)
+			}
+		})
+	}
+}
+
+func TestIdentityCollisions(t *testing.T) {
+	tests := []struct {
+		name         string
+		matches      []*models.User
+		wantUsername bool
+		wantEmail    bool
+	}{
+		{name: "no matches"},
+		{name: "username differs in case", matches: []*models.User{{Username: "alice", Email: "x@example.com"}}, wantUsername: true},
+		{name: "email differs in case", matches: []*models.User{{Username: "x", Email: "alice@example.com"}}, wantEmail: true},
+		{name: "one user holds both", matches: []*models.User{{Username: "alice", Email: "alice@example.com"}}, wantUsername: true, wantEmail: true},
+		{
+			name:         "two users hold one each",
+			matches:      []*models.User{{Username: "x", Email: "alice@example.com"}, {Username: "alice", Email: "y@example.com"}},
+			wantUsername: true,
+			wantEmail:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			usernameExists, emailExists := identityCollisions(tt.matches, "Alice", "ALICE@example.com")
+			if usernameExists != tt.wantUsername || emailExists != tt.wantEmail {
+				t.Errorf("identityCollisions() = %v, %v, want %v, %v", usernameExists, emailExists, tt.wantUsername, tt.wantEmail)
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+// internal/shared/events/bus.go
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// User lifecycle event types
+const (
+	UserCreated  = "user.created"
+	UserUpdated  = "user.updated"
+	UserDeleted  = "user.deleted"
+	UserRestored = "user.restored"
+)
+
+// ErrPermanent marks delivery failures that retrying cannot fix, such as a rejected payload
+var ErrPermanent = errors.New("permanent delivery failure")
+
+// Event is a domain event as delivered to subscribers
+type Event struct {
+	ID         string      `json:"id"`
+	Type       string      `json:"type"`
+	OccurredAt time.Time   `json:"occurred_at"`
+	Data       interface{} `json:"data"`
+}
+
+// Subscriber receives events delivered through an EventBus
+type Subscriber interface {
+	// Name identifies the subscriber in logs
+	Name() string
+	// Handle makes one delivery attempt; failures that are not worth retrying wrap ErrPermanent
+	Handle(ctx context.Context, event Event) error
+}
+
+// EventBus fans events out to its subscribers
+// It does no queueing of its own; the Dispatcher feeds it events from the outbox.
+type EventBus struct {
+	mu          sync.RWMutex
+	subscribers []Subscriber
+}
+
+// NewEventBus creates an EventBus without subscribers
+func NewEventBus() *EventBus {
+	return &EventBus{}
+}
+
+// Subscribe registers a subscriber for every event delivered afterwards
+func (b *EventBus) Subscribe(subscriber Subscriber) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.subscribers = append(b.subscribers, subscriber)
+}
+
+// Deliver hands event to every subscriber in the order they subscribed
+// All subscribers are tried even if one fails; their errors are joined. Since a failed event
+// is delivered again as a whole, subscribers must tolerate seeing an event more than once.
+func (b *EventBus) Deliver(ctx context.Context, event Event) error {
+	b.mu.RLock()
+	subscribers := b.subscribers
+	b.mu.RUnlock()
+
+	var errs []error
+	for _, subscriber := range subscribers {
+		if err := subscriber.Handle(ctx, event); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", subscriber.Name(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// isPermanent reports whether err, possibly joined from several subscribers, consists
+// only of permanent failures
+func isPermanent(err error) bool {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		for _, e := range joined.Unwrap() {
+			if !isPermanent(e) {
+				return false
+			}
+		}
+		return true
+	}
+	return errors.Is(err, ErrPermanent)
+}
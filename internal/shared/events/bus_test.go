@@ -0,0 +1,70 @@
+// internal/shared/events/bus_test.go
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+)
+
+// recordingSubscriber records the events it handles and fails with err
+type recordingSubscriber struct {
+	name   string
+	err    error
+	events []Event
+}
+
+func (s *recordingSubscriber) Name() string { return s.name }
+
+func (s *recordingSubscriber) Handle(ctx context.Context, event Event) error {
+	s.events = append(s.events, event)
+	return s.err
+}
+
+func TestEventBusDeliver(t *testing.T) {
+	retryable := errors.New("connection refused")
+	permanent := fmt.Errorf("%w: status 400", ErrPermanent)
+
+	tests := []struct {
+		name          string
+		errs          []error // one subscriber per entry
+		wantErr       bool
+		wantPermanent bool
+	}{
+		{name: "no subscribers"},
+		{name: "every subscriber succeeds", errs: []error{nil, nil}},
+		{name: "one retryable failure", errs: []error{nil, retryable}, wantErr: true},
+		{name: "only permanent failures", errs: []error{permanent, permanent}, wantErr: true, wantPermanent: true},
+		{name: "permanent and retryable failures are retried", errs: []error{permanent, retryable}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bus := NewEventBus()
+			var subscribers []*recordingSubscriber
+			for i, err := range tt.errs {
+				subscriber := &recordingSubscriber{name: fmt.Sprintf("sub%d", i), err: err}
+				subscribers = append(subscribers, subscriber)
+				bus.Subscribe(subscriber)
+			}
+
+			event := Event{ID: "evt-1", Type: UserUpdated}
+			err := bus.Deliver(context.Background(), event)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Deliver() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && isPermanent(err) != tt.wantPermanent {
+				t.Errorf("isPermanent(%v) = %v, want %v", err, !tt.wantPermanent, tt.wantPermanent)
+			}
+
+			// A failing subscriber must not keep the others from seeing the event
+			for _, subscriber := range subscribers {
+				if !slices.Equal(subscriber.events, []Event{event}) {
+					t.Errorf("%s handled %v, want the event once", subscriber.name, subscriber.events)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,175 @@
+// internal/shared/events/dispatcher.go
+package events
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"go-template/internal/interfaces"
+	"go-template/internal/models"
+	"go-template/internal/repositories"
+)
+
+// Outbox delivery policy
+const (
+	// DispatchInterval is how often the outbox is polled when nothing new was published locally
+	DispatchInterval = time.Second
+
+	dispatchBatchSize   = 50
+	dispatchLease       = time.Minute // an event claimed by a crashed dispatcher is retried after this
+	dispatchMaxAttempts = 10
+	dispatchMinBackoff  = 5 * time.Second
+	dispatchMaxBackoff  = 10 * time.Minute
+)
+
+// Dispatcher publishes events through a persistent outbox and delivers them in the background.
+// Publish stores the event in the outbox before returning, so once a write has been
+// acknowledged its event survives a crash; Run claims due events, hands them to the EventBus
+// and marks them sent. Failed deliveries are retried with exponential backoff, which makes
+// delivery at-least-once: subscribers may see an event more than once but never lose it.
+type Dispatcher struct {
+	outbox repositories.OutboxRepositoryInterface
+	bus    *EventBus
+	logger interfaces.LoggerInterface
+	wake   chan struct{}
+}
+
+// NewDispatcher creates a Dispatcher storing events in outbox and delivering them to bus
+func NewDispatcher(outbox repositories.OutboxRepositoryInterface, bus *EventBus, logger interfaces.LoggerInterface) *Dispatcher {
+	return &Dispatcher{
+		outbox: outbox,
+		bus:    bus,
+		logger: logger,
+		wake:   make(chan struct{}, 1),
+	}
+}
+
+// Publish stores an event in the outbox for delivery
+// The payload is encoded now, so later changes to data are not reflected in the event.
+func (d *Dispatcher) Publish(ctx context.Context, eventType string, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to encode event payload: %w", err)
+	}
+
+	id, err := newEventID()
+	if err != nil {
+		return fmt.Errorf("failed to generate event ID: %w", err)
+	}
+
+	event := models.NewOutboxEvent(id, eventType, string(payload), time.Now().UTC())
+	if err := d.outbox.Enqueue(ctx, event); err != nil {
+		return fmt.Errorf("failed to store event in outbox: %w", err)
+	}
+
+	// Deliver promptly instead of waiting for the next poll
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Run delivers due outbox events until ctx is cancelled
+// Several instances may run at once; each event is claimed by one of them at a time.
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(DispatchInterval)
+	defer ticker.Stop()
+
+	for {
+		d.drain(ctx)
+
+		select {
+		case <-ctx.Done():
+			d.logger.Info("Event dispatcher stopped")
+			return
+		case <-ticker.C:
+		case <-d.wake:
+		}
+	}
+}
+
+// drain delivers batches of due events until none are left
+func (d *Dispatcher) drain(ctx context.Context) {
+	for ctx.Err() == nil {
+		claimed, err := d.outbox.ClaimDue(ctx, dispatchBatchSize, dispatchLease)
+		if err != nil && ctx.Err() == nil {
+			d.logger.Error("Failed to claim outbox events", err)
+		}
+
+		for _, event := range claimed {
+			d.deliver(ctx, event)
+		}
+
+		if err != nil || len(claimed) < dispatchBatchSize {
+			return
+		}
+	}
+}
+
+// deliver hands one claimed event to the bus and records the outcome
+func (d *Dispatcher) deliver(ctx context.Context, outboxEvent *models.OutboxEvent) {
+	id := outboxEvent.GetIDString()
+	event := Event{
+		ID:         outboxEvent.EventID,
+		Type:       outboxEvent.Type,
+		OccurredAt: outboxEvent.OccurredAt,
+		Data:       json.RawMessage(outboxEvent.Payload),
+	}
+
+	err := d.bus.Deliver(ctx, event)
+	if err == nil {
+		if err := d.outbox.MarkSent(ctx, id); err != nil {
+			// The lease runs out and the event is delivered again
+			d.logger.Error("Failed to mark outbox event as sent", err, "event_id", event.ID)
+		}
+		return
+	}
+
+	// Shutting down: leave the event claimed so it is retried once the lease runs out
+	if ctx.Err() != nil {
+		return
+	}
+
+	var retryAt *time.Time
+	if !isPermanent(err) && outboxEvent.Attempts < dispatchMaxAttempts {
+		next := time.Now().UTC().Add(dispatchBackoff(outboxEvent.Attempts))
+		retryAt = &next
+		d.logger.Warn("Event delivery failed, retrying",
+			"event_id", event.ID, "event_type", event.Type, "attempt", outboxEvent.Attempts,
+			"error", err.Error(), "retry_at", next)
+	} else {
+		d.logger.Error("Event delivery abandoned", err,
+			"event_id", event.ID, "event_type", event.Type, "attempts", outboxEvent.Attempts)
+	}
+
+	if err := d.outbox.MarkFailed(ctx, id, err.Error(), retryAt); err != nil {
+		d.logger.Error("Failed to record outbox delivery failure", err, "event_id", event.ID)
+	}
+}
+
+// dispatchBackoff returns the delay before retrying after the given number of attempts
+func dispatchBackoff(attempts int) time.Duration {
+	backoff := dispatchMinBackoff
+	for i := 1; i < attempts && backoff < dispatchMaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > dispatchMaxBackoff {
+		backoff = dispatchMaxBackoff
+	}
+	return backoff
+}
+
+// newEventID generates a random event ID
+// Subscribers can use it to discard events they have already processed.
+func newEventID() (string, error) {
+	bytes := make([]byte, 16)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(bytes), nil
+}
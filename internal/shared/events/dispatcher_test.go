@@ -0,0 +1,309 @@
+// internal/shared/events/dispatcher_test.go
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"go-template/internal/models"
+	"go-template/internal/shared/logtest"
+)
+
+// memoryOutbox is an OutboxRepositoryInterface keeping events in a map
+type memoryOutbox struct {
+	mu     sync.Mutex
+	events map[string]*models.OutboxEvent
+}
+
+func newMemoryOutbox() *memoryOutbox {
+	return &memoryOutbox{events: make(map[string]*models.OutboxEvent)}
+}
+
+func (o *memoryOutbox) Enqueue(ctx context.Context, event *models.OutboxEvent) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	stored := *event
+	o.events[event.GetIDString()] = &stored
+	return nil
+}
+
+func (o *memoryOutbox) ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]*models.OutboxEvent, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	now := time.Now().UTC()
+	var due []*models.OutboxEvent
+	for _, event := range o.events {
+		if event.Status == models.OutboxStatusPending && !event.NextAttemptAt.After(now) {
+			due = append(due, event)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i].NextAttemptAt.Before(due[j].NextAttemptAt) })
+	if len(due) > limit {
+		due = due[:limit]
+	}
+
+	claimed := make([]*models.OutboxEvent, len(due))
+	for i, event := range due {
+		event.NextAttemptAt = now.Add(lease)
+		event.Attempts++
+		copied := *event
+		claimed[i] = &copied
+	}
+	return claimed, nil
+}
+
+func (o *memoryOutbox) MarkSent(ctx context.Context, id string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	now := time.Now().UTC()
+	o.events[id].Status = models.OutboxStatusSent
+	o.events[id].SentAt = &now
+	return nil
+}
+
+func (o *memoryOutbox) MarkFailed(ctx context.Context, id string, lastError string, retryAt *time.Time) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	event := o.events[id]
+	event.LastError = lastError
+	if retryAt != nil {
+		event.NextAttemptAt = *retryAt
+	} else {
+		event.Status = models.OutboxStatusDead
+	}
+	return nil
+}
+
+// only returns the single stored event, failing the test if there is not exactly one
+func (o *memoryOutbox) only(t *testing.T) models.OutboxEvent {
+	t.Helper()
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if len(o.events) != 1 {
+		t.Fatalf("outbox holds %d events, want 1", len(o.events))
+	}
+	for _, event := range o.events {
+		return *event
+	}
+	return models.OutboxEvent{}
+}
+
+// makeDue makes every pending event due right away, as if its retry time had passed
+func (o *memoryOutbox) makeDue() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for _, event := range o.events {
+		event.NextAttemptAt = time.Now().UTC().Add(-time.Second)
+	}
+}
+
+func TestDispatcherPublish(t *testing.T) {
+	outbox := newMemoryOutbox()
+	dispatcher := NewDispatcher(outbox, NewEventBus(), logtest.New())
+
+	before := time.Now().UTC()
+	data := map[string]string{"id": "42"}
+	if err := dispatcher.Publish(context.Background(), UserCreated, data); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+	data["id"] = "changed"
+
+	event := outbox.only(t)
+	if event.Type != UserCreated || event.Status != models.OutboxStatusPending || event.Attempts != 0 {
+		t.Errorf("event = %+v, want a pending %s with no attempts", event, UserCreated)
+	}
+	if event.Payload != `{"id":"42"}` {
+		t.Errorf("payload = %s, want the data as published", event.Payload)
+	}
+	if len(event.EventID) != 32 || event.OccurredAt.Before(before) || event.NextAttemptAt.After(time.Now().UTC()) {
+		t.Errorf("event = %+v, want a random ID and to be due now", event)
+	}
+
+	select {
+	case <-dispatcher.wake:
+	default:
+		t.Error("Publish() did not wake the dispatcher")
+	}
+}
+
+func TestDispatcherPublishInvalidPayload(t *testing.T) {
+	outbox := newMemoryOutbox()
+	dispatcher := NewDispatcher(outbox, NewEventBus(), logtest.New())
+
+	if err := dispatcher.Publish(context.Background(), UserCreated, func() {}); err == nil {
+		t.Fatal("Publish() error = nil, want an encoding error")
+	}
+	if len(outbox.events) != 0 {
+		t.Errorf("outbox holds %d events, want none", len(outbox.events))
+	}
+}
+
+func TestDispatcherDeliver(t *testing.T) {
+	retryable := errors.New("connection refused")
+	permanent := fmt.Errorf("%w: status 400", ErrPermanent)
+
+	tests := []struct {
+		name         string
+		errs         []error // subscriber result for each delivery attempt, nil once exhausted
+		attempts     int     // drains to run
+		notDue       bool    // keep retry times in the future between drains
+		wantStatus   string
+		wantAttempts int
+		wantDelivery int
+	}{
+		{name: "successful delivery is marked sent", attempts: 1, wantStatus: models.OutboxStatusSent, wantAttempts: 1, wantDelivery: 1},
+		{name: "failed delivery is retried", errs: []error{retryable}, attempts: 2, wantStatus: models.OutboxStatusSent, wantAttempts: 2, wantDelivery: 2},
+		{name: "failed delivery waits for its retry time", errs: []error{retryable}, attempts: 2, notDue: true, wantStatus: models.OutboxStatusPending, wantAttempts: 1, wantDelivery: 1},
+		{name: "permanent failure is abandoned", errs: []error{permanent}, attempts: 2, wantStatus: models.OutboxStatusDead, wantAttempts: 1, wantDelivery: 1},
+		{
+			name:         "too many attempts are abandoned",
+			errs:         repeatErr(retryable, dispatchMaxAttempts+1),
+			attempts:     dispatchMaxAttempts + 1,
+			wantStatus:   models.OutboxStatusDead,
+			wantAttempts: dispatchMaxAttempts,
+			wantDelivery: dispatchMaxAttempts,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outbox := newMemoryOutbox()
+			bus := NewEventBus()
+			subscriber := &scriptedSubscriber{errs: tt.errs}
+			bus.Subscribe(subscriber)
+			dispatcher := NewDispatcher(outbox, bus, logtest.New())
+
+			if err := dispatcher.Publish(context.Background(), UserDeleted, map[string]string{"id": "42"}); err != nil {
+				t.Fatalf("Publish() error = %v", err)
+			}
+
+			for i := 0; i < tt.attempts; i++ {
+				if i > 0 && !tt.notDue {
+					outbox.makeDue()
+				}
+				dispatcher.drain(context.Background())
+			}
+
+			event := outbox.only(t)
+			if event.Status != tt.wantStatus || event.Attempts != tt.wantAttempts {
+				t.Errorf("status = %s after %d attempts, want %s after %d", event.Status, event.Attempts, tt.wantStatus, tt.wantAttempts)
+			}
+			if len(subscriber.events) != tt.wantDelivery {
+				t.Errorf("delivered %d times, want %d", len(subscriber.events), tt.wantDelivery)
+			}
+			if tt.wantStatus == models.OutboxStatusSent && event.SentAt == nil {
+				t.Error("sent event has no sent_at")
+			}
+			if tt.wantStatus != models.OutboxStatusSent && event.LastError == "" {
+				t.Error("failed event has no last_error")
+			}
+			if tt.wantStatus == models.OutboxStatusPending && time.Until(event.NextAttemptAt) < dispatchMinBackoff-time.Second {
+				t.Errorf("retry at %v, want about %v from now", event.NextAttemptAt, dispatchMinBackoff)
+			}
+
+			// Subscribers see the event exactly as published
+			delivered := subscriber.events[0]
+			if delivered.Type != UserDeleted || delivered.ID != event.EventID || string(delivered.Data.(json.RawMessage)) != `{"id":"42"}` {
+				t.Errorf("delivered %+v, want the published event", delivered)
+			}
+		})
+	}
+}
+
+func TestDispatcherRun(t *testing.T) {
+	outbox := newMemoryOutbox()
+	bus := NewEventBus()
+	subscriber := &scriptedSubscriber{delivered: make(chan Event, 1)}
+	bus.Subscribe(subscriber)
+	dispatcher := NewDispatcher(outbox, bus, logtest.New())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		dispatcher.Run(ctx)
+		close(stopped)
+	}()
+
+	if err := dispatcher.Publish(context.Background(), UserCreated, map[string]string{"id": "42"}); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+	select {
+	case event := <-subscriber.delivered:
+		if event.Type != UserCreated {
+			t.Errorf("delivered %s, want %s", event.Type, UserCreated)
+		}
+	case <-time.After(DispatchInterval / 2):
+		t.Fatal("Publish() did not trigger a prompt delivery")
+	}
+
+	cancel()
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("Run() did not stop after cancellation")
+	}
+}
+
+func TestDispatchBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{attempts: 0, want: dispatchMinBackoff},
+		{attempts: 1, want: dispatchMinBackoff},
+		{attempts: 2, want: 2 * dispatchMinBackoff},
+		{attempts: 3, want: 4 * dispatchMinBackoff},
+		{attempts: 7, want: 64 * dispatchMinBackoff},
+		{attempts: 8, want: dispatchMaxBackoff},
+		{attempts: 50, want: dispatchMaxBackoff},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.attempts), func(t *testing.T) {
+			if got := dispatchBackoff(tt.attempts); got != tt.want {
+				t.Errorf("dispatchBackoff(%d) = %v, want %v", tt.attempts, got, tt.want)
+			}
+		})
+	}
+}
+
+// scriptedSubscriber fails its deliveries with errs in order, then succeeds
+type scriptedSubscriber struct {
+	mu        sync.Mutex
+	errs      []error
+	events    []Event
+	delivered chan Event
+}
+
+func (s *scriptedSubscriber) Name() string { return "scripted" }
+
+func (s *scriptedSubscriber) Handle(ctx context.Context, event Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.events = append(s.events, event)
+	var err error
+	if len(s.errs) > 0 {
+		err, s.errs = s.errs[0], s.errs[1:]
+	}
+	if err == nil && s.delivered != nil {
+		s.delivered <- event
+	}
+	return err
+}
+
+// repeatErr returns a slice holding err n times
+func repeatErr(err error, n int) []error {
+	errs := make([]error, n)
+	for i := range errs {
+		errs[i] = err
+	}
+	return errs
+}
@@ -0,0 +1,109 @@
+// internal/shared/events/webhook.go
+package events
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go-template/internal/interfaces"
+)
+
+// Headers sent with every webhook request
+const (
+	WebhookEventHeader     = "X-Webhook-Event"
+	WebhookIDHeader        = "X-Webhook-ID"
+	WebhookTimestampHeader = "X-Webhook-Timestamp"
+	WebhookSignatureHeader = "X-Webhook-Signature"
+)
+
+// webhookRequestTimeout bounds a single delivery attempt
+const webhookRequestTimeout = 10 * time.Second
+
+// WebhookSubscriber POSTs every event as JSON to a configured URL
+// Requests are signed with an HMAC so receivers can check they came from us, see Sign.
+// Each call makes a single attempt; retries are left to the Dispatcher. Responses outside
+// 2xx other than 429 and 5xx are reported as permanent failures.
+type WebhookSubscriber struct {
+	url    string
+	secret []byte
+	client *http.Client
+	logger interfaces.LoggerInterface
+}
+
+// NewWebhookSubscriber creates a WebhookSubscriber delivering to url, signed with secret
+func NewWebhookSubscriber(url, secret string, logger interfaces.LoggerInterface) *WebhookSubscriber {
+	return &WebhookSubscriber{
+		url:    url,
+		secret: []byte(secret),
+		client: &http.Client{Timeout: webhookRequestTimeout},
+		logger: logger,
+	}
+}
+
+// Name identifies the subscriber in logs
+func (s *WebhookSubscriber) Name() string {
+	return "webhook"
+}
+
+// Handle POSTs event to the webhook URL once
+func (s *WebhookSubscriber) Handle(ctx context.Context, event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("%w: failed to encode event: %v", ErrPermanent, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("%w: failed to build request: %v", ErrPermanent, err)
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookEventHeader, event.Type)
+	req.Header.Set(WebhookIDHeader, event.ID)
+	req.Header.Set(WebhookTimestampHeader, timestamp)
+	req.Header.Set(WebhookSignatureHeader, Sign(s.secret, timestamp, body))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		s.logger.Debug("Webhook delivered", "event_id", event.ID, "event_type", event.Type)
+		return nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return fmt.Errorf("webhook endpoint responded with status %d", resp.StatusCode)
+	default:
+		return fmt.Errorf("%w: webhook endpoint responded with status %d", ErrPermanent, resp.StatusCode)
+	}
+}
+
+// Sign returns the X-Webhook-Signature value for a request body sent at timestamp
+// The signature is "sha256=" followed by the hex HMAC-SHA256 of "<timestamp>.<body>",
+// so a captured request cannot be replayed with a different timestamp.
+func Sign(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifySignature reports whether signature is valid for body and timestamp
+// Receivers should also reject timestamps too far from their own clock.
+func VerifySignature(secret []byte, timestamp string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature))
+}
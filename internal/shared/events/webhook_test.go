@@ -0,0 +1,136 @@
+// internal/shared/events/webhook_test.go
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"go-template/internal/shared/logtest"
+)
+
+// capturedRequest is a webhook request as received by the test server
+type capturedRequest struct {
+	header http.Header
+	body   []byte
+}
+
+func TestWebhookSubscriber(t *testing.T) {
+	const secret = "webhook-secret"
+	event := Event{
+		ID:         "evt-1",
+		Type:       UserCreated,
+		OccurredAt: time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC),
+		Data:       map[string]string{"id": "42", "username": "alice"},
+	}
+
+	tests := []struct {
+		name          string
+		status        int
+		wantErr       bool
+		wantPermanent bool
+	}{
+		{name: "delivered", status: http.StatusOK},
+		{name: "accepted", status: http.StatusAccepted},
+		{name: "server error is retried", status: http.StatusInternalServerError, wantErr: true},
+		{name: "rate limited is retried", status: http.StatusTooManyRequests, wantErr: true},
+		{name: "rejected payload is permanent", status: http.StatusBadRequest, wantErr: true, wantPermanent: true},
+		{name: "missing endpoint is permanent", status: http.StatusNotFound, wantErr: true, wantPermanent: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			received := make(chan capturedRequest, 1)
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				received <- capturedRequest{header: r.Header.Clone(), body: body}
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			subscriber := NewWebhookSubscriber(server.URL, secret, logtest.New())
+			err := subscriber.Handle(context.Background(), event)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Handle() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := errors.Is(err, ErrPermanent); got != tt.wantPermanent {
+				t.Errorf("permanent = %v, want %v (error %v)", got, tt.wantPermanent, err)
+			}
+
+			req := <-received
+			if req.header.Get("Content-Type") != "application/json" {
+				t.Errorf("Content-Type = %q, want application/json", req.header.Get("Content-Type"))
+			}
+			if req.header.Get(WebhookEventHeader) != UserCreated || req.header.Get(WebhookIDHeader) != "evt-1" {
+				t.Errorf("event headers = %q/%q, want %s/evt-1", req.header.Get(WebhookEventHeader), req.header.Get(WebhookIDHeader), UserCreated)
+			}
+
+			timestamp := req.header.Get(WebhookTimestampHeader)
+			sent, err := strconv.ParseInt(timestamp, 10, 64)
+			if err != nil || time.Since(time.Unix(sent, 0)) > time.Minute {
+				t.Errorf("timestamp = %q, want the current unix time", timestamp)
+			}
+			if !VerifySignature([]byte(secret), timestamp, req.body, req.header.Get(WebhookSignatureHeader)) {
+				t.Errorf("signature %q does not verify", req.header.Get(WebhookSignatureHeader))
+			}
+
+			var got Event
+			if err := json.Unmarshal(req.body, &got); err != nil {
+				t.Fatalf("invalid body %s: %v", req.body, err)
+			}
+			if got.ID != event.ID || got.Type != event.Type || !got.OccurredAt.Equal(event.OccurredAt) {
+				t.Errorf("body = %+v, want %+v", got, event)
+			}
+			if data, _ := got.Data.(map[string]interface{}); data["username"] != "alice" {
+				t.Errorf("data = %v, want the event payload", got.Data)
+			}
+		})
+	}
+}
+
+func TestWebhookSubscriberUnreachable(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	url := server.URL
+	server.Close()
+
+	err := NewWebhookSubscriber(url, "secret", logtest.New()).Handle(context.Background(), Event{ID: "evt-1", Type: UserDeleted})
+	if err == nil || errors.Is(err, ErrPermanent) {
+		t.Errorf("Handle() error = %v, want a retryable error", err)
+	}
+}
+
+func TestVerifySignature(t *testing.T) {
+	secret := []byte("secret")
+	body := []byte(`{"id":"evt-1"}`)
+	signature := Sign(secret, "1767268800", body)
+
+	tests := []struct {
+		name      string
+		secret    []byte
+		timestamp string
+		body      []byte
+		signature string
+		want      bool
+	}{
+		{name: "valid", secret: secret, timestamp: "1767268800", body: body, signature: signature, want: true},
+		{name: "wrong secret", secret: []byte("other"), timestamp: "1767268800", body: body, signature: signature},
+		{name: "replayed with another timestamp", secret: secret, timestamp: "1767268801", body: body, signature: signature},
+		{name: "tampered body", secret: secret, timestamp: "1767268800", body: []byte(`{"id":"evt-2"}`), signature: signature},
+		{name: "missing signature", secret: secret, timestamp: "1767268800", body: body},
+		{name: "unprefixed signature", secret: secret, timestamp: "1767268800", body: body, signature: signature[len("sha256="):]},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := VerifySignature(tt.secret, tt.timestamp, tt.body, tt.signature); got != tt.want {
+				t.Errorf("VerifySignature() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,177 @@
+// internal/shared/health/checker.go
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Status represents the health of a single check or of the whole system
+type Status string
+
+// Health statuses
+const (
+	StatusHealthy   Status = "healthy"
+	StatusDegraded  Status = "degraded"
+	StatusUnhealthy Status = "unhealthy"
+)
+
+// DefaultCheckTimeout bounds how long a single check may run
+const DefaultCheckTimeout = 5 * time.Second
+
+// CheckFunc reports whether a dependency is healthy; a nil error means healthy
+// Errors wrapped with Degraded mark the dependency degraded instead of unhealthy.
+type CheckFunc func(ctx context.Context) error
+
+// degradedError marks a check failure that does not make the system unavailable
+type degradedError struct {
+	err error
+}
+
+func (e *degradedError) Error() string { return e.err.Error() }
+
+func (e *degradedError) Unwrap() error { return e.err }
+
+// Degraded wraps err so the check reports a degraded dependency instead of an unhealthy one
+func Degraded(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &degradedError{err: err}
+}
+
+// CheckResult represents the outcome of a single named check
+type CheckResult struct {
+	Status    Status  `json:"status"`
+	LatencyMs float64 `json:"latency_ms,omitempty"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// HealthReport represents the outcome of all registered checks
+type HealthReport struct {
+	Status Status                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks"`
+}
+
+// IsHealthy reports whether no check failed; degraded checks still count as healthy
+func (r HealthReport) IsHealthy() bool {
+	return r.Status != StatusUnhealthy
+}
+
+// WithoutLatencies returns a copy of the report with per-check latencies removed
+func (r HealthReport) WithoutLatencies() HealthReport {
+	checks := make(map[string]CheckResult, len(r.Checks))
+	for name, result := range r.Checks {
+		result.LatencyMs = 0
+		checks[name] = result
+	}
+	return HealthReport{Status: r.Status, Checks: checks}
+}
+
+// Checker runs a set of named health checks
+type Checker struct {
+	mu      sync.RWMutex
+	checks  map[string]CheckFunc
+	timeout time.Duration
+}
+
+// NewChecker creates a new Checker; each check is cancelled after timeout
+func NewChecker(timeout time.Duration) *Checker {
+	if timeout <= 0 {
+		timeout = DefaultCheckTimeout
+	}
+	return &Checker{
+		checks:  make(map[string]CheckFunc),
+		timeout: timeout,
+	}
+}
+
+// Register adds a named check, replacing any existing check with the same name
+func (c *Checker) Register(name string, check CheckFunc) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.checks[name] = check
+}
+
+// RunAll runs every registered check concurrently and aggregates the results
+// The overall status is unhealthy if any check fails, otherwise degraded if any check is degraded
+func (c *Checker) RunAll(ctx context.Context) HealthReport {
+	c.mu.RLock()
+	checks := make(map[string]CheckFunc, len(c.checks))
+	for name, check := range c.checks {
+		checks[name] = check
+	}
+	c.mu.RUnlock()
+
+	report := HealthReport{
+		Status: StatusHealthy,
+		Checks: make(map[string]CheckResult, len(checks)),
+	}
+
+	var (
+		wg sync.WaitGroup
+		mu sync.Mutex
+	)
+	for name, check := range checks {
+		wg.Add(1)
+		go func(name string, check CheckFunc) {
+			defer wg.Done()
+			result := c.run(ctx, check)
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[name] = result
+			switch {
+			case result.Status == StatusUnhealthy:
+				report.Status = StatusUnhealthy
+			case result.Status == StatusDegraded && report.Status == StatusHealthy:
+				report.Status = StatusDegraded
+			}
+		}(name, check)
+	}
+	wg.Wait()
+
+	return report
+}
+
+// run executes a single check with the checker timeout and measures its latency
+func (c *Checker) run(ctx context.Context, check CheckFunc) CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- fmt.Errorf("check panicked: %v", r)
+			}
+		}()
+		done <- check(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = fmt.Errorf("check timed out: %w", ctx.Err())
+	}
+
+	result := CheckResult{
+		Status:    StatusHealthy,
+		LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		result.Status = StatusUnhealthy
+		result.Error = err.Error()
+
+		var degraded *degradedError
+		if errors.As(err, &degraded) {
+			result.Status = StatusDegraded
+		}
+	}
+	return result
+}
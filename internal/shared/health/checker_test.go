@@ -0,0 +1,156 @@
+// internal/shared/health/checker_test.go
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestCheckerRunAll(t *testing.T) {
+	pass := func(ctx context.Context) error { return nil }
+	fail := func(ctx context.Context) error { return errors.New("connection refused") }
+	degrade := func(ctx context.Context) error { return Degraded(errors.New("missing indexes")) }
+	hang := func(ctx context.Context) error { <-ctx.Done(); return ctx.Err() }
+	block := func(ctx context.Context) error { time.Sleep(time.Second); return nil }
+	explode := func(ctx context.Context) error { panic("boom") }
+
+	tests := []struct {
+		name        string
+		checks      map[string]CheckFunc
+		wantStatus  Status
+		wantHealthy bool
+		wantChecks  map[string]Status
+	}{
+		{
+			name:        "no checks",
+			wantStatus:  StatusHealthy,
+			wantHealthy: true,
+			wantChecks:  map[string]Status{},
+		},
+		{
+			name:        "all passing",
+			checks:      map[string]CheckFunc{"database": pass, "cache": pass},
+			wantStatus:  StatusHealthy,
+			wantHealthy: true,
+			wantChecks:  map[string]Status{"database": StatusHealthy, "cache": StatusHealthy},
+		},
+		{
+			name:       "one failing",
+			checks:     map[string]CheckFunc{"database": pass, "cache": fail},
+			wantStatus: StatusUnhealthy,
+			wantChecks: map[string]Status{"database": StatusHealthy, "cache": StatusUnhealthy},
+		},
+		{
+			name:        "degraded only",
+			checks:      map[string]CheckFunc{"database": pass, "indexes": degrade},
+			wantStatus:  StatusDegraded,
+			wantHealthy: true,
+			wantChecks:  map[string]Status{"database": StatusHealthy, "indexes": StatusDegraded},
+		},
+		{
+			name:       "failing wins over degraded",
+			checks:     map[string]CheckFunc{"cache": fail, "indexes": degrade},
+			wantStatus: StatusUnhealthy,
+			wantChecks: map[string]Status{"cache": StatusUnhealthy, "indexes": StatusDegraded},
+		},
+		{
+			name:       "check that honours the timeout",
+			checks:     map[string]CheckFunc{"database": hang},
+			wantStatus: StatusUnhealthy,
+			wantChecks: map[string]Status{"database": StatusUnhealthy},
+		},
+		{
+			name:       "check that ignores the timeout",
+			checks:     map[string]CheckFunc{"database": block},
+			wantStatus: StatusUnhealthy,
+			wantChecks: map[string]Status{"database": StatusUnhealthy},
+		},
+		{
+			name:       "panicking check",
+			checks:     map[string]CheckFunc{"database": explode},
+			wantStatus: StatusUnhealthy,
+			wantChecks: map[string]Status{"database": StatusUnhealthy},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checker := NewChecker(50 * time.Millisecond)
+			for name, check := range tt.checks {
+				checker.Register(name, check)
+			}
+
+			report := checker.RunAll(context.Background())
+
+			if report.Status != tt.wantStatus {
+				t.Errorf("Status = %q, want %q", report.Status, tt.wantStatus)
+			}
+			// IsHealthy decides between 200 and 503
+			if report.IsHealthy() != tt.wantHealthy {
+				t.Errorf("IsHealthy() = %v, want %v", report.IsHealthy(), tt.wantHealthy)
+			}
+			if len(report.Checks) != len(tt.wantChecks) {
+				t.Fatalf("got %d checks, want %d", len(report.Checks), len(tt.wantChecks))
+			}
+			for name, want := range tt.wantChecks {
+				result := report.Checks[name]
+				if result.Status != want {
+					t.Errorf("check %s status = %q, want %q", name, result.Status, want)
+				}
+				if (want == StatusHealthy) != (result.Error == "") {
+					t.Errorf("check %s error = %q with status %q", name, result.Error, result.Status)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckerMeasuresLatency(t *testing.T) {
+	checker := NewChecker(time.Second)
+	checker.Register("slow", func(ctx context.Context) error {
+		time.Sleep(20 * time.Millisecond)
+		return nil
+	})
+
+	report := checker.RunAll(context.Background())
+	if latency := report.Checks["slow"].LatencyMs; latency < 20 {
+		t.Errorf("LatencyMs = %v, want at least 20", latency)
+	}
+
+	quiet := report.WithoutLatencies()
+	if quiet.Checks["slow"].LatencyMs != 0 {
+		t.Errorf("WithoutLatencies() kept LatencyMs = %v", quiet.Checks["slow"].LatencyMs)
+	}
+	if report.Checks["slow"].LatencyMs == 0 {
+		t.Error("WithoutLatencies() modified the original report")
+	}
+	if quiet.Status != report.Status {
+		t.Errorf("WithoutLatencies() Status = %q, want %q", quiet.Status, report.Status)
+	}
+}
+
+func TestDegraded(t *testing.T) {
+	if err := Degraded(nil); err != nil {
+		t.Errorf("Degraded(nil) = %v, want nil so passing checks stay healthy", err)
+	}
+
+	cause := errors.New("missing indexes: idx_users_roles")
+	err := Degraded(cause)
+	if err.Error() != cause.Error() {
+		t.Errorf("Error() = %q, want the cause's message %q", err.Error(), cause.Error())
+	}
+	if !errors.Is(err, cause) {
+		t.Error("Degraded() does not unwrap to its cause")
+	}
+
+	// Wrapping a degraded error again keeps the check degraded
+	checker := NewChecker(time.Second)
+	checker.Register("indexes", func(ctx context.Context) error { return fmt.Errorf("verify: %w", err) })
+	report := checker.RunAll(context.Background())
+	if result := report.Checks["indexes"]; result.Status != StatusDegraded || result.Error != "verify: "+cause.Error() {
+		t.Errorf("check = %+v, want degraded with the wrapped message", result)
+	}
+}
@@ -0,0 +1,100 @@
+// internal/shared/logtest/logger.go
+package logtest
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+
+	"go-template/internal/interfaces"
+)
+
+// Entry is one message recorded by a Logger
+type Entry struct {
+	Level slog.Level
+	Msg   string
+	Err   error
+	Args  []interface{}
+}
+
+// Logger implements interfaces.LoggerInterface by recording entries in memory
+// Loggers derived through With and WithContext share the parent's entries, so tests can
+// hand one to the code under test and assert on everything it logged.
+type Logger struct {
+	mu      *sync.Mutex
+	entries *[]Entry
+	args    []interface{}
+}
+
+// New creates an empty Logger
+func New() *Logger {
+	return &Logger{mu: &sync.Mutex{}, entries: &[]Entry{}}
+}
+
+// Debug records a debug message
+func (l *Logger) Debug(msg string, args ...interface{}) {
+	l.record(slog.LevelDebug, msg, nil, args)
+}
+
+// Info records an info message
+func (l *Logger) Info(msg string, args ...interface{}) {
+	l.record(slog.LevelInfo, msg, nil, args)
+}
+
+// Warn records a warning message
+func (l *Logger) Warn(msg string, args ...interface{}) {
+	l.record(slog.LevelWarn, msg, nil, args)
+}
+
+// Error records an error message
+func (l *Logger) Error(msg string, err error, args ...interface{}) {
+	l.record(slog.LevelError, msg, err, args)
+}
+
+// With returns a logger that adds args to every entry and shares this logger's entries
+func (l *Logger) With(args ...interface{}) interfaces.LoggerInterface {
+	return &Logger{
+		mu:      l.mu,
+		entries: l.entries,
+		args:    append(append([]interface{}{}, l.args...), args...),
+	}
+}
+
+// WithContext returns the logger unchanged
+func (l *Logger) WithContext(ctx context.Context) interfaces.LoggerInterface {
+	return l
+}
+
+// Log records a message at the given level
+func (l *Logger) Log(ctx context.Context, level slog.Level, msg string, args ...interface{}) {
+	l.record(level, msg, nil, args)
+}
+
+// Entries returns a copy of the recorded entries, oldest first
+func (l *Logger) Entries() []Entry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]Entry(nil), *l.entries...)
+}
+
+// Has reports whether a message was recorded at the given level
+func (l *Logger) Has(level slog.Level, msg string) bool {
+	for _, entry := range l.Entries() {
+		if entry.Level == level && entry.Msg == msg {
+			return true
+		}
+	}
+	return false
+}
+
+// record appends an entry carrying the logger's args followed by the call's args
+func (l *Logger) record(level slog.Level, msg string, err error, args []interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	*l.entries = append(*l.entries, Entry{
+		Level: level,
+		Msg:   msg,
+		Err:   err,
+		Args:  append(append([]interface{}{}, l.args...), args...),
+	})
+}
@@ -0,0 +1,27 @@
+// internal/shared/mail/log_mailer.go
+package mail
+
+import (
+	"context"
+
+	"go-template/internal/interfaces"
+)
+
+// LogMailer is a Mailer that writes emails to the log instead of delivering them
+// It is the default until a real provider is configured; bodies are logged at debug level
+// because they carry secrets such as verification tokens.
+type LogMailer struct {
+	logger interfaces.LoggerInterface
+}
+
+// NewLogMailer creates a new LogMailer
+func NewLogMailer(logger interfaces.LoggerInterface) *LogMailer {
+	return &LogMailer{logger: logger}
+}
+
+// Send logs the email and always succeeds
+func (m *LogMailer) Send(ctx context.Context, to, subject, body string) error {
+	m.logger.Info("Email sent", "to", to, "subject", subject)
+	m.logger.Debug("Email body", "to", to, "body", body)
+	return nil
+}
@@ -0,0 +1,40 @@
+// internal/shared/mail/memory_mailer.go
+package mail
+
+import (
+	"context"
+	"sync"
+)
+
+// Message is an email captured by MemoryMailer
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// MemoryMailer is a Mailer that keeps sent emails in memory, for tests
+type MemoryMailer struct {
+	mu       sync.Mutex
+	messages []Message
+}
+
+// NewMemoryMailer creates a new MemoryMailer
+func NewMemoryMailer() *MemoryMailer {
+	return &MemoryMailer{}
+}
+
+// Send records the email and always succeeds
+func (m *MemoryMailer) Send(ctx context.Context, to, subject, body string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messages = append(m.messages, Message{To: to, Subject: subject, Body: body})
+	return nil
+}
+
+// Sent returns the emails sent so far, oldest first
+func (m *MemoryMailer) Sent() []Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Message(nil), m.messages...)
+}
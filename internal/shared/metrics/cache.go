@@ -0,0 +1,56 @@
+// internal/shared/metrics/cache.go
+package metrics
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// Cache lookup results used as the result label
+const (
+	resultHit   = "hit"
+	resultMiss  = "miss"
+	resultError = "error"
+)
+
+// CacheMetrics records cache lookups as Prometheus counters
+type CacheMetrics struct {
+	lookups *prometheus.CounterVec
+}
+
+// NewCacheMetrics creates the cache counters and registers them with the given registerer
+func NewCacheMetrics(registerer prometheus.Registerer) *CacheMetrics {
+	m := &CacheMetrics{
+		lookups: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "cache_lookups_total",
+			Help: "Total number of cache lookups by key category and result (hit, miss or error).",
+		}, []string{"category", "result"}),
+	}
+
+	registerer.MustRegister(m.lookups)
+	return m
+}
+
+// CacheHit counts a lookup answered by the cache
+func (m *CacheMetrics) CacheHit(category string) {
+	m.lookups.WithLabelValues(category, resultHit).Inc()
+}
+
+// CacheMiss counts a lookup for a key that was not cached
+func (m *CacheMetrics) CacheMiss(category string) {
+	m.lookups.WithLabelValues(category, resultMiss).Inc()
+}
+
+// CacheError counts a lookup the cache failed to answer
+func (m *CacheMetrics) CacheError(category string) {
+	m.lookups.WithLabelValues(category, resultError).Inc()
+}
+
+// NopCacheMetrics discards cache lookups, for containers that do not export metrics
+type NopCacheMetrics struct{}
+
+// CacheHit does nothing
+func (NopCacheMetrics) CacheHit(string) {}
+
+// CacheMiss does nothing
+func (NopCacheMetrics) CacheMiss(string) {}
+
+// CacheError does nothing
+func (NopCacheMetrics) CacheError(string) {}
@@ -0,0 +1,75 @@
+// internal/shared/metrics/cache_test.go
+package metrics
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestCacheMetrics(t *testing.T) {
+	tests := []struct {
+		name   string
+		record func(m *CacheMetrics)
+		want   map[[2]string]float64 // category, result
+	}{
+		{
+			name:   "hit",
+			record: func(m *CacheMetrics) { m.CacheHit("user") },
+			want:   map[[2]string]float64{{"user", "hit"}: 1},
+		},
+		{
+			name:   "miss",
+			record: func(m *CacheMetrics) { m.CacheMiss("list") },
+			want:   map[[2]string]float64{{"list", "miss"}: 1},
+		},
+		{
+			name:   "error",
+			record: func(m *CacheMetrics) { m.CacheError("exists") },
+			want:   map[[2]string]float64{{"exists", "error"}: 1},
+		},
+		{
+			name: "categories and results are counted apart",
+			record: func(m *CacheMetrics) {
+				m.CacheHit("user")
+				m.CacheHit("user")
+				m.CacheMiss("user")
+				m.CacheHit("stats")
+			},
+			want: map[[2]string]float64{{"user", "hit"}: 2, {"user", "miss"}: 1, {"stats", "hit"}: 1, {"stats", "miss"}: 0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry := prometheus.NewRegistry()
+			m := NewCacheMetrics(registry)
+			tt.record(m)
+
+			for labels, want := range tt.want {
+				if got := testutil.ToFloat64(m.lookups.WithLabelValues(labels[0], labels[1])); got != want {
+					t.Errorf("cache_lookups_total{category=%q,result=%q} = %v, want %v", labels[0], labels[1], got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestCacheMetricsExposition(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	m := NewCacheMetrics(registry)
+	m.CacheHit("user")
+	m.CacheMiss("user")
+
+	want := `
+# HELP cache_lookups_total Total number of cache lookups by key category and result (hit, miss or error).
+# TYPE cache_lookups_total counter
+cache_lookups_total{category="user",result="hit"} 1
+cache_lookups_total{category="user",result="miss"} 1
+`
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(want), "cache_lookups_total"); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,75 @@
+// internal/shared/middleware/activity.go
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"go-template/internal/interfaces"
+)
+
+const (
+	// ActivityThrottle is the minimum interval between two activity writes for the same user
+	ActivityThrottle = time.Minute
+
+	cacheKeyActivityThrottle = "activity:throttle:%s" // userID
+)
+
+// ActivityRecorder persists the time a user was last active
+type ActivityRecorder interface {
+	RecordActivity(ctx context.Context, userID string) error
+}
+
+// TrackActivity returns a middleware that records the authenticated caller's last activity.
+// Writes are throttled to one per ActivityThrottle per user using the cache, so busy clients
+// do not turn every request into a database write. It must run after RequireAuth or
+// OptionalAuth; anonymous requests pass through untouched. Failures are logged, never surfaced.
+func TrackActivity(cache interfaces.CacheInterface, recorder ActivityRecorder, logger interfaces.LoggerInterface) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if userID, ok := UserIDFromContext(r.Context()); ok {
+				recordActivity(r.Context(), cache, recorder, logger, userID)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// recordActivity records activity for userID unless it was already recorded within the throttle window
+func recordActivity(ctx context.Context, cache interfaces.CacheInterface, recorder ActivityRecorder, logger interfaces.LoggerInterface, userID string) {
+	due, err := activityDue(ctx, cache, userID)
+	if err != nil {
+		// Skip rather than write on every request while the cache is unavailable
+		logger.Warn("Failed to check activity throttle", "user_id", userID, "error", err.Error())
+		return
+	}
+	if !due {
+		return
+	}
+
+	if err := recorder.RecordActivity(ctx, userID); err != nil {
+		logger.Warn("Failed to record user activity", "user_id", userID, "error", err.Error())
+	}
+}
+
+// activityDue reports whether this is the first request from userID in the current throttle window
+func activityDue(ctx context.Context, cache interfaces.CacheInterface, userID string) (bool, error) {
+	key := fmt.Sprintf(cacheKeyActivityThrottle, userID)
+
+	count, err := cache.Increment(ctx, key)
+	if err != nil {
+		return false, err
+	}
+	if count != 1 {
+		return false, nil
+	}
+
+	if err := cache.Expire(ctx, key, ActivityThrottle); err != nil {
+		// Without an expiry the key would suppress activity forever
+		_ = cache.Delete(ctx, key)
+		return false, err
+	}
+	return true, nil
+}
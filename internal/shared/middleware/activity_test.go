@@ -0,0 +1,162 @@
+// internal/shared/middleware/activity_test.go
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+
+	"go-template/internal/database"
+	"go-template/internal/interfaces"
+	"go-template/internal/shared/utils"
+)
+
+// countingRecorder counts RecordActivity calls per user
+type countingRecorder struct {
+	mu    sync.Mutex
+	calls map[string]int
+	err   error
+}
+
+func (r *countingRecorder) RecordActivity(ctx context.Context, userID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.calls == nil {
+		r.calls = make(map[string]int)
+	}
+	r.calls[userID]++
+	return r.err
+}
+
+// brokenCache fails every throttle check as if Redis were down
+type brokenCache struct {
+	interfaces.CacheInterface
+}
+
+func (brokenCache) Increment(ctx context.Context, key string) (int64, error) {
+	return 0, errors.New("redis unavailable")
+}
+
+// activityStep is one request sent through TrackActivity, after advancing the clock by wait
+type activityStep struct {
+	userID string // empty for an anonymous request
+	wait   time.Duration
+}
+
+func TestTrackActivity(t *testing.T) {
+	tests := []struct {
+		name      string
+		steps     []activityStep
+		want      map[string]int
+		cacheDown bool
+	}{
+		{
+			name:  "first request records activity",
+			steps: []activityStep{{userID: "alice"}},
+			want:  map[string]int{"alice": 1},
+		},
+		{
+			name:  "requests within the throttle window are not recorded",
+			steps: []activityStep{{userID: "alice"}, {userID: "alice", wait: 30 * time.Second}, {userID: "alice", wait: 29 * time.Second}},
+			want:  map[string]int{"alice": 1},
+		},
+		{
+			name:  "request after the throttle window is recorded",
+			steps: []activityStep{{userID: "alice"}, {userID: "alice", wait: ActivityThrottle + time.Second}},
+			want:  map[string]int{"alice": 2},
+		},
+		{
+			name:  "users are throttled independently",
+			steps: []activityStep{{userID: "alice"}, {userID: "bob"}, {userID: "alice"}, {userID: "bob"}},
+			want:  map[string]int{"alice": 1, "bob": 1},
+		},
+		{
+			name:  "anonymous requests are not recorded",
+			steps: []activityStep{{}, {}},
+			want:  map[string]int{},
+		},
+		{
+			name:      "cache failure skips the write",
+			steps:     []activityStep{{userID: "alice"}, {userID: "alice"}},
+			want:      map[string]int{},
+			cacheDown: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := miniredis.RunT(t)
+			cache, err := database.ConnectRedis(server.Addr(), "", 0, 10, 0)
+			if err != nil {
+				t.Fatalf("ConnectRedis() error = %v", err)
+			}
+			t.Cleanup(func() { cache.Close() })
+			if tt.cacheDown {
+				cache = brokenCache{cache}
+			}
+
+			logger, _ := newBufferLogger()
+			recorder := &countingRecorder{}
+			called := 0
+			handler := TrackActivity(cache, recorder, logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				called++
+				w.WriteHeader(http.StatusNoContent)
+			}))
+
+			for _, step := range tt.steps {
+				server.FastForward(step.wait)
+				req := httptest.NewRequest(http.MethodGet, "/api/v1/users", nil)
+				if step.userID != "" {
+					req = req.WithContext(WithClaims(req.Context(), &utils.TokenClaims{Subject: step.userID}))
+				}
+				rec := httptest.NewRecorder()
+				handler.ServeHTTP(rec, req)
+				if rec.Code != http.StatusNoContent {
+					t.Fatalf("status = %d, want %d", rec.Code, http.StatusNoContent)
+				}
+			}
+
+			if called != len(tt.steps) {
+				t.Errorf("next handler called %d times, want %d", called, len(tt.steps))
+			}
+			if len(recorder.calls) != len(tt.want) {
+				t.Errorf("recorded activity = %v, want %v", recorder.calls, tt.want)
+			}
+			for userID, want := range tt.want {
+				if got := recorder.calls[userID]; got != want {
+					t.Errorf("RecordActivity(%s) called %d times, want %d", userID, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestTrackActivityRecorderErrorIsNotSurfaced(t *testing.T) {
+	logger, buf := newBufferLogger()
+	recorder := &countingRecorder{err: errors.New("database unavailable")}
+	handler := TrackActivity(database.NewMemoryCache(), recorder, logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/users", nil)
+	req = req.WithContext(WithClaims(req.Context(), &utils.TokenClaims{Subject: "alice"}))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusNoContent)
+	}
+	if recorder.calls["alice"] != 1 {
+		t.Errorf("RecordActivity called %d times, want 1", recorder.calls["alice"])
+	}
+	if !bytes.Contains(buf.Bytes(), []byte("Failed to record user activity")) {
+		t.Errorf("recorder error was not logged: %s", buf.String())
+	}
+}
@@ -0,0 +1,232 @@
+// internal/shared/middleware/auth_test.go
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go-template/internal/interfaces"
+	"go-template/internal/shared/logtest"
+	"go-template/internal/shared/utils"
+)
+
+func TestRequireAuthDisablesCaching(t *testing.T) {
+	tokens := utils.NewTokenService("test-secret-test-secret-test-secret", time.Hour, 24*time.Hour, 30*24*time.Hour)
+	access, err := tokens.GenerateAccessToken("507f1f77bcf86cd799439011", []string{"user"}, 0)
+	if err != nil {
+		t.Fatalf("GenerateAccessToken() error = %v", err)
+	}
+	refresh, err := tokens.GenerateRefreshToken("507f1f77bcf86cd799439011", 0, false)
+	if err != nil {
+		t.Fatalf("GenerateRefreshToken() error = %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		authorization string
+		wantStatus    int
+	}{
+		{name: "authenticated", authorization: "Bearer " + access, wantStatus: http.StatusOK},
+		{name: "missing token", wantStatus: http.StatusUnauthorized},
+		{name: "invalid token", authorization: "Bearer nope", wantStatus: http.StatusUnauthorized},
+		{name: "refresh token", authorization: "Bearer " + refresh, wantStatus: http.StatusUnauthorized},
+	}
+
+	handler := RequireAuth(tokens, nil, logtest.New())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/api/v1/users/me", nil)
+			if tt.authorization != "" {
+				r.Header.Set("Authorization", tt.authorization)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, r)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if got := rec.Header().Get("Cache-Control"); got != "no-store" {
+				t.Errorf("Cache-Control = %q, want no-store", got)
+			}
+		})
+	}
+}
+
+// stubVersions is a TokenVersionSource returning a fixed version or error
+type stubVersions struct {
+	version int64
+	err     error
+}
+
+func (s stubVersions) TokenVersion(ctx context.Context, userID string) (int64, error) {
+	return s.version, s.err
+}
+
+func TestRequireAuthTokenVersion(t *testing.T) {
+	tokens := utils.NewTokenService("test-secret-test-secret-test-secret", time.Hour, 24*time.Hour, 30*24*time.Hour)
+
+	tests := []struct {
+		name         string
+		tokenVersion int64
+		versions     TokenVersionSource
+		wantStatus   int
+		wantMessage  string
+	}{
+		{name: "no version source", tokenVersion: 0, wantStatus: http.StatusOK},
+		{name: "current version", tokenVersion: 3, versions: stubVersions{version: 3}, wantStatus: http.StatusOK},
+		{name: "revoked", tokenVersion: 2, versions: stubVersions{version: 3}, wantStatus: http.StatusUnauthorized, wantMessage: "Token has been revoked"},
+		{name: "issued before any bump", tokenVersion: 0, versions: stubVersions{version: 1}, wantStatus: http.StatusUnauthorized, wantMessage: "Token has been revoked"},
+		{name: "deleted user", versions: stubVersions{err: fmt.Errorf("user %w", interfaces.ErrNotFound)}, wantStatus: http.StatusUnauthorized, wantMessage: "Invalid token"},
+		{name: "lookup fails closed", versions: stubVersions{err: errors.New("connection refused")}, wantStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := tokens.GenerateAccessToken("507f1f77bcf86cd799439011", []string{"user"}, tt.tokenVersion)
+			if err != nil {
+				t.Fatalf("GenerateAccessToken() error = %v", err)
+			}
+
+			for _, wrap := range []struct {
+				name       string
+				middleware func(http.Handler) http.Handler
+			}{
+				{name: "RequireAuth", middleware: RequireAuth(tokens, tt.versions, logtest.New())},
+				{name: "OptionalAuth", middleware: OptionalAuth(tokens, tt.versions, logtest.New())},
+			} {
+				reached := false
+				handler := wrap.middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					reached = true
+					w.WriteHeader(http.StatusOK)
+				}))
+
+				r := httptest.NewRequest(http.MethodGet, "/api/v1/users/me", nil)
+				r.Header.Set("Authorization", "Bearer "+token)
+				rec := httptest.NewRecorder()
+				handler.ServeHTTP(rec, r)
+
+				if rec.Code != tt.wantStatus {
+					t.Fatalf("%s status = %d, want %d: %s", wrap.name, rec.Code, tt.wantStatus, rec.Body.String())
+				}
+				if reached != (tt.wantStatus == http.StatusOK) {
+					t.Errorf("%s reached handler = %v, want %v", wrap.name, reached, tt.wantStatus == http.StatusOK)
+				}
+				if tt.wantMessage != "" && !strings.Contains(rec.Body.String(), tt.wantMessage) {
+					t.Errorf("%s body = %s, want it to mention %q", wrap.name, rec.Body.String(), tt.wantMessage)
+				}
+			}
+		})
+	}
+}
+
+func TestRequireAuthLogsImpersonation(t *testing.T) {
+	tokens := utils.NewTokenService("test-secret-test-secret-test-secret", time.Hour, 24*time.Hour, 30*24*time.Hour)
+	access, err := tokens.GenerateAccessToken("user-1", []string{"user"}, 0)
+	if err != nil {
+		t.Fatalf("GenerateAccessToken() error = %v", err)
+	}
+	impersonation, err := tokens.GenerateImpersonationToken("user-1", []string{"user"}, 0, "admin-1", 15*time.Minute)
+	if err != nil {
+		t.Fatalf("GenerateImpersonationToken() error = %v", err)
+	}
+
+	tests := []struct {
+		name             string
+		token            string
+		wantImpersonator string
+	}{
+		{name: "regular token", token: access},
+		{name: "impersonation token", token: impersonation, wantImpersonator: "admin-1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, name := range []string{"RequireAuth", "OptionalAuth"} {
+				logger := logtest.New()
+				wrap := RequireAuth(tokens, nil, logger)
+				if name == "OptionalAuth" {
+					wrap = OptionalAuth(tokens, nil, logger)
+				}
+
+				var impersonator string
+				var impersonated bool
+				handler := wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					impersonator, impersonated = ImpersonatorFromContext(r.Context())
+					w.WriteHeader(http.StatusOK)
+				}))
+				r := httptest.NewRequest(http.MethodPatch, "/api/v1/users/user-1", nil)
+				r.Header.Set("Authorization", "Bearer "+tt.token)
+				rec := httptest.NewRecorder()
+				handler.ServeHTTP(rec, r)
+
+				if rec.Code != http.StatusOK {
+					t.Fatalf("%s status = %d, want 200: %s", name, rec.Code, rec.Body.String())
+				}
+				if impersonator != tt.wantImpersonator || impersonated != (tt.wantImpersonator != "") {
+					t.Errorf("%s ImpersonatorFromContext() = %q, %v, want %q", name, impersonator, impersonated, tt.wantImpersonator)
+				}
+
+				// Impersonated requests are logged with both IDs
+				var logged []logtest.Entry
+				for _, entry := range logger.Entries() {
+					if entry.Msg == "Impersonated request" {
+						logged = append(logged, entry)
+					}
+				}
+				if tt.wantImpersonator == "" {
+					if len(logged) != 0 {
+						t.Errorf("%s logged %+v, want no impersonation entry", name, logged)
+					}
+					continue
+				}
+				if len(logged) != 1 {
+					t.Fatalf("%s logged %d impersonation entries, want 1", name, len(logged))
+				}
+				args := map[interface{}]interface{}{}
+				for i := 0; i+1 < len(logged[0].Args); i += 2 {
+					args[logged[0].Args[i]] = logged[0].Args[i+1]
+				}
+				for key, want := range map[string]string{"admin_id": "admin-1", "user_id": "user-1", "method": http.MethodPatch, "path": "/api/v1/users/user-1"} {
+					if args[key] != want {
+						t.Errorf("%s logged %s = %v, want %q", name, key, args[key], want)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestImpersonatorFromContext(t *testing.T) {
+	tests := []struct {
+		name   string
+		claims *utils.TokenClaims
+		want   string
+		wantOK bool
+	}{
+		{name: "anonymous"},
+		{name: "regular token", claims: &utils.TokenClaims{Subject: "user-1"}},
+		{name: "impersonation token", claims: &utils.TokenClaims{Subject: "user-1", ImpersonatedBy: "admin-1"}, want: "admin-1", wantOK: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.claims != nil {
+				ctx = WithClaims(ctx, tt.claims)
+			}
+			got, ok := ImpersonatorFromContext(ctx)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("ImpersonatorFromContext() = %q, %v, want %q, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
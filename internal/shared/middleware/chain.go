@@ -0,0 +1,23 @@
+// internal/shared/middleware/chain.go
+package middleware
+
+import "net/http"
+
+// Chain composes middlewares into one, in the order given
+// The first middleware is the outermost: it sees the request first and the response last.
+func Chain(middlewares ...func(http.Handler) http.Handler) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		for i := len(middlewares) - 1; i >= 0; i-- {
+			next = middlewares[i](next)
+		}
+		return next
+	}
+}
+
+// ChainFunc composes middlewares like Chain, for wrapping individual handler functions
+func ChainFunc(middlewares ...func(http.Handler) http.Handler) func(http.HandlerFunc) http.Handler {
+	chain := Chain(middlewares...)
+	return func(h http.HandlerFunc) http.Handler {
+		return chain(h)
+	}
+}
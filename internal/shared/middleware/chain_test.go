@@ -0,0 +1,106 @@
+// internal/shared/middleware/chain_test.go
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+// recording returns a middleware that appends name to calls before and after the next handler
+func recording(calls *[]string, name string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			*calls = append(*calls, name+" in")
+			next.ServeHTTP(w, r)
+			*calls = append(*calls, name+" out")
+		})
+	}
+}
+
+// stopping returns a middleware that answers the request itself without calling the next handler
+func stopping(calls *[]string, name string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			*calls = append(*calls, name+" stop")
+			w.WriteHeader(http.StatusForbidden)
+		})
+	}
+}
+
+func TestChain(t *testing.T) {
+	tests := []struct {
+		name       string
+		chain      func(calls *[]string) []func(http.Handler) http.Handler
+		wantCalls  []string
+		wantStatus int
+	}{
+		{
+			name:       "no middlewares",
+			chain:      func(calls *[]string) []func(http.Handler) http.Handler { return nil },
+			wantCalls:  []string{"handler"},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name: "first given is outermost",
+			chain: func(calls *[]string) []func(http.Handler) http.Handler {
+				return []func(http.Handler) http.Handler{recording(calls, "a"), recording(calls, "b"), recording(calls, "c")}
+			},
+			wantCalls:  []string{"a in", "b in", "c in", "handler", "c out", "b out", "a out"},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name: "short circuit skips the rest",
+			chain: func(calls *[]string) []func(http.Handler) http.Handler {
+				return []func(http.Handler) http.Handler{recording(calls, "a"), stopping(calls, "auth"), recording(calls, "c")}
+			},
+			wantCalls:  []string{"a in", "auth stop", "a out"},
+			wantStatus: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		for _, wrap := range []string{"Chain", "ChainFunc"} {
+			t.Run(tt.name+" "+wrap, func(t *testing.T) {
+				var calls []string
+				handler := func(w http.ResponseWriter, r *http.Request) {
+					calls = append(calls, "handler")
+				}
+
+				var chained http.Handler
+				if wrap == "Chain" {
+					chained = Chain(tt.chain(&calls)...)(http.HandlerFunc(handler))
+				} else {
+					chained = ChainFunc(tt.chain(&calls)...)(handler)
+				}
+
+				rec := httptest.NewRecorder()
+				chained.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+				if !slices.Equal(calls, tt.wantCalls) {
+					t.Errorf("calls = %v, want %v", calls, tt.wantCalls)
+				}
+				if rec.Code != tt.wantStatus {
+					t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+				}
+			})
+		}
+	}
+}
+
+func TestChainsNest(t *testing.T) {
+	var calls []string
+	global := Chain(recording(&calls, "recovery"), recording(&calls, "requestid"))
+	route := ChainFunc(recording(&calls, "auth"), recording(&calls, "role"))
+
+	handler := global(route(func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, "handler")
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	want := []string{"recovery in", "requestid in", "auth in", "role in", "handler", "role out", "auth out", "requestid out", "recovery out"}
+	if !slices.Equal(calls, want) {
+		t.Errorf("calls = %v, want %v", calls, want)
+	}
+}
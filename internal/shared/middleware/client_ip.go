@@ -0,0 +1,94 @@
+// internal/shared/middleware/client_ip.go
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// TrustedProxies holds the parsed proxies whose forwarding headers are honoured
+// Parse them once with ParseTrustedProxies when building a handler rather than per request.
+type TrustedProxies []netip.Prefix
+
+// ParseTrustedProxies converts CIDRs and single addresses to TrustedProxies
+func ParseTrustedProxies(proxies []string) TrustedProxies {
+	return parseTrustedProxies(proxies)
+}
+
+// ClientIP returns the originating client IP of a request
+// X-Forwarded-For and X-Real-IP are only honoured when the connection comes from one of
+// trustedProxies, given as CIDRs or single addresses; anyone else could spoof them. See
+// TrustedProxies.ClientIP for how the headers are read.
+func ClientIP(r *http.Request, trustedProxies []string) string {
+	return ParseTrustedProxies(trustedProxies).ClientIP(r)
+}
+
+// ClientIP returns the originating client IP of a request
+// The X-Forwarded-For chain is read from the right, skipping trusted proxies, so entries a
+// client prepended itself are ignored. A malformed hop ends the walk at the last hop that
+// parsed, or the peer; X-Real-IP is only consulted when X-Forwarded-For is absent.
+func (t TrustedProxies) ClientIP(r *http.Request) string {
+	peer := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		peer = host
+	}
+
+	if !isTrustedProxy(peer, t) {
+		return peer
+	}
+
+	if forwarded := r.Header.Values("X-Forwarded-For"); len(forwarded) > 0 {
+		client := peer
+		hops := strings.Split(strings.Join(forwarded, ","), ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop := strings.TrimSpace(hops[i])
+			if _, err := netip.ParseAddr(hop); err != nil {
+				break
+			}
+			client = hop
+			if !isTrustedProxy(hop, t) {
+				break
+			}
+		}
+		return client
+	}
+
+	if realIP := strings.TrimSpace(r.Header.Get("X-Real-IP")); realIP != "" {
+		if _, err := netip.ParseAddr(realIP); err == nil {
+			return realIP
+		}
+	}
+
+	return peer
+}
+
+// parseTrustedProxies converts CIDRs and single addresses to prefixes, skipping invalid entries
+// Config validation rejects invalid entries, so none are expected at runtime.
+func parseTrustedProxies(proxies []string) []netip.Prefix {
+	prefixes := make([]netip.Prefix, 0, len(proxies))
+	for _, proxy := range proxies {
+		if prefix, err := netip.ParsePrefix(proxy); err == nil {
+			prefixes = append(prefixes, prefix.Masked())
+		} else if addr, err := netip.ParseAddr(proxy); err == nil {
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+		}
+	}
+	return prefixes
+}
+
+// isTrustedProxy reports whether ip falls within one of the trusted prefixes
+func isTrustedProxy(ip string, trusted []netip.Prefix) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, prefix := range trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,102 @@
+// internal/shared/middleware/client_ip_test.go
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	proxies := []string{"10.0.0.0/8", "192.0.2.10", "2001:db8::/32"}
+
+	tests := []struct {
+		name           string
+		remoteAddr     string
+		forwardedFor   []string // one X-Forwarded-For header per entry
+		realIP         string
+		trustedProxies []string
+		want           string
+	}{
+		{name: "direct connection", remoteAddr: "203.0.113.7:52100", trustedProxies: proxies, want: "203.0.113.7"},
+		{name: "direct ipv6 connection", remoteAddr: "[2001:db9::1]:443", trustedProxies: proxies, want: "2001:db9::1"},
+		{name: "address without a port", remoteAddr: "203.0.113.7", trustedProxies: proxies, want: "203.0.113.7"},
+		{name: "spoofed forwarded for from an untrusted peer", remoteAddr: "203.0.113.7:52100", forwardedFor: []string{"198.51.100.1"}, trustedProxies: proxies, want: "203.0.113.7"},
+		{name: "spoofed real ip from an untrusted peer", remoteAddr: "203.0.113.7:52100", realIP: "198.51.100.1", trustedProxies: proxies, want: "203.0.113.7"},
+		{name: "no trusted proxies ignores headers", remoteAddr: "10.0.0.2:52100", forwardedFor: []string{"198.51.100.1"}, realIP: "198.51.100.1", want: "10.0.0.2"},
+		{name: "forwarded by a trusted proxy", remoteAddr: "10.0.0.2:52100", forwardedFor: []string{"198.51.100.1"}, trustedProxies: proxies, want: "198.51.100.1"},
+		{name: "proxy trusted by address", remoteAddr: "192.0.2.10:52100", forwardedFor: []string{"198.51.100.1"}, trustedProxies: proxies, want: "198.51.100.1"},
+		{name: "neighbouring address is not trusted", remoteAddr: "192.0.2.11:52100", forwardedFor: []string{"198.51.100.1"}, trustedProxies: proxies, want: "192.0.2.11"},
+		{name: "forwarded by a trusted ipv6 proxy", remoteAddr: "[2001:db8::5]:443", forwardedFor: []string{"198.51.100.1"}, trustedProxies: proxies, want: "198.51.100.1"},
+		{name: "ipv4 mapped proxy address", remoteAddr: "[::ffff:10.0.0.2]:52100", forwardedFor: []string{"198.51.100.1"}, trustedProxies: proxies, want: "198.51.100.1"},
+		{
+			name:           "client prepended entries are ignored",
+			remoteAddr:     "10.0.0.2:52100",
+			forwardedFor:   []string{"1.2.3.4, 198.51.100.1"},
+			trustedProxies: proxies,
+			want:           "198.51.100.1",
+		},
+		{
+			name:           "chain of trusted proxies",
+			remoteAddr:     "10.0.0.2:52100",
+			forwardedFor:   []string{"198.51.100.1, 10.0.0.9, 192.0.2.10"},
+			trustedProxies: proxies,
+			want:           "198.51.100.1",
+		},
+		{
+			name:           "repeated headers form one chain",
+			remoteAddr:     "10.0.0.2:52100",
+			forwardedFor:   []string{"1.2.3.4", "198.51.100.1, 10.0.0.9"},
+			trustedProxies: proxies,
+			want:           "198.51.100.1",
+		},
+		{
+			name:           "every hop trusted",
+			remoteAddr:     "10.0.0.2:52100",
+			forwardedFor:   []string{"10.0.0.8, 10.0.0.9"},
+			trustedProxies: proxies,
+			want:           "10.0.0.8",
+		},
+		{name: "real ip from a trusted proxy", remoteAddr: "10.0.0.2:52100", realIP: " 198.51.100.1 ", trustedProxies: proxies, want: "198.51.100.1"},
+		{name: "forwarded for wins over real ip", remoteAddr: "10.0.0.2:52100", forwardedFor: []string{"198.51.100.1"}, realIP: "198.51.100.2", trustedProxies: proxies, want: "198.51.100.1"},
+		{name: "malformed forwarded for ignores real ip", remoteAddr: "10.0.0.2:52100", forwardedFor: []string{"unknown"}, realIP: "198.51.100.2", trustedProxies: proxies, want: "10.0.0.2"},
+		{name: "malformed headers fall back to the peer", remoteAddr: "10.0.0.2:52100", forwardedFor: []string{"unknown"}, realIP: "not-an-ip", trustedProxies: proxies, want: "10.0.0.2"},
+		{name: "empty forwarded for falls back to the peer", remoteAddr: "10.0.0.2:52100", forwardedFor: []string{""}, realIP: "198.51.100.2", trustedProxies: proxies, want: "10.0.0.2"},
+		{
+			name:           "malformed hop stops at the last parsed hop",
+			remoteAddr:     "10.0.0.2:52100",
+			forwardedFor:   []string{"198.51.100.1, unknown, 10.0.0.9"},
+			realIP:         "198.51.100.2",
+			trustedProxies: proxies,
+			want:           "10.0.0.9",
+		},
+		{
+			name:           "malformed hop before the client is ignored",
+			remoteAddr:     "10.0.0.2:52100",
+			forwardedFor:   []string{"unknown, 198.51.100.1, 10.0.0.9"},
+			trustedProxies: proxies,
+			want:           "198.51.100.1",
+		},
+		{name: "invalid trusted entries are skipped", remoteAddr: "10.0.0.2:52100", forwardedFor: []string{"198.51.100.1"}, trustedProxies: []string{"proxy.internal"}, want: "10.0.0.2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/api/v1/users", nil)
+			r.RemoteAddr = tt.remoteAddr
+			for _, value := range tt.forwardedFor {
+				r.Header.Add("X-Forwarded-For", value)
+			}
+			if tt.realIP != "" {
+				r.Header.Set("X-Real-IP", tt.realIP)
+			}
+
+			if got := ClientIP(r, tt.trustedProxies); got != tt.want {
+				t.Errorf("ClientIP() = %q, want %q", got, tt.want)
+			}
+			if got := ParseTrustedProxies(tt.trustedProxies).ClientIP(r); got != tt.want {
+				t.Errorf("TrustedProxies.ClientIP() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
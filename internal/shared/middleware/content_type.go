@@ -0,0 +1,56 @@
+// internal/shared/middleware/content_type.go
+package middleware
+
+import (
+	"mime"
+	"net/http"
+
+	"go-template/internal/shared/response"
+)
+
+// RequireJSON returns a middleware that answers POST, PUT and PATCH requests carrying a body
+// with 415 unless their Content-Type is application/json; parameters such as charset are allowed.
+// Requests without a body, such as action endpoints, pass through. Routes registered on mux
+// under one of exemptPatterns, such as multipart uploads, are not checked.
+func RequireJSON(mux *http.ServeMux, exemptPatterns ...string) func(http.Handler) http.Handler {
+	exempt := make(map[string]bool, len(exemptPatterns))
+	for _, pattern := range exemptPatterns {
+		exempt[pattern] = true
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !hasJSONBodyMethod(r.Method) || !hasBody(r) || isJSONContentType(r.Header.Get("Content-Type")) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if _, pattern := mux.Handler(r); exempt[pattern] {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			response.ErrorWithCode(w, response.ErrorCodeUnsupportedType,
+				"Content-Type must be application/json", http.StatusUnsupportedMediaType)
+		})
+	}
+}
+
+// hasJSONBodyMethod reports whether requests with method send a JSON body to this API
+func hasJSONBodyMethod(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch:
+		return true
+	}
+	return false
+}
+
+// hasBody reports whether a request carries a body, including chunked bodies of unknown length
+func hasBody(r *http.Request) bool {
+	return r.ContentLength != 0 && r.Body != nil && r.Body != http.NoBody
+}
+
+// isJSONContentType reports whether a Content-Type header value is application/json
+func isJSONContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && mediaType == "application/json"
+}
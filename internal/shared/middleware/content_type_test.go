@@ -0,0 +1,80 @@
+// internal/shared/middleware/content_type_test.go
+package middleware
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go-template/internal/shared/response"
+)
+
+func TestRequireJSON(t *testing.T) {
+	const avatarPattern = "POST /api/v1/users/{id}/avatar"
+
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		contentType string
+		body        io.Reader
+		wantServed  bool
+	}{
+		{name: "json", method: http.MethodPost, path: "/api/v1/users", contentType: "application/json", body: strings.NewReader(`{}`), wantServed: true},
+		{name: "json with charset", method: http.MethodPut, path: "/api/v1/users/1", contentType: "application/json; charset=utf-8", body: strings.NewReader(`{}`), wantServed: true},
+		{name: "media type case is ignored", method: http.MethodPatch, path: "/api/v1/users/1", contentType: "Application/JSON", body: strings.NewReader(`{}`), wantServed: true},
+		{name: "missing content type", method: http.MethodPost, path: "/api/v1/users", body: strings.NewReader(`{}`)},
+		{name: "form data", method: http.MethodPost, path: "/api/v1/users", contentType: "application/x-www-form-urlencoded", body: strings.NewReader("username=alice")},
+		{name: "plain text", method: http.MethodPatch, path: "/api/v1/users/1", contentType: "text/plain", body: strings.NewReader(`{}`)},
+		{name: "json suffix types are not json", method: http.MethodPost, path: "/api/v1/users", contentType: "application/merge-patch+json", body: strings.NewReader(`{}`)},
+		{name: "malformed content type", method: http.MethodPost, path: "/api/v1/users", contentType: "application/json; charset", body: strings.NewReader(`{}`)},
+		{name: "chunked body", method: http.MethodPost, path: "/api/v1/users", body: io.MultiReader(strings.NewReader(`{}`))},
+		{name: "action without a body", method: http.MethodPost, path: "/api/v1/users/1/restore", wantServed: true},
+		{name: "reads are not checked", method: http.MethodGet, path: "/api/v1/users", wantServed: true},
+		{name: "deletes are not checked", method: http.MethodDelete, path: "/api/v1/users", contentType: "text/plain", body: strings.NewReader(`{}`), wantServed: true},
+		{name: "exempt upload", method: http.MethodPost, path: "/api/v1/users/1/avatar", contentType: "multipart/form-data; boundary=x", body: strings.NewReader("--x--"), wantServed: true},
+		{name: "exemption is per route", method: http.MethodPut, path: "/api/v1/users/1/avatar", contentType: "multipart/form-data; boundary=x", body: strings.NewReader("--x--")},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(avatarPattern, func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			served := false
+			handler := RequireJSON(mux, avatarPattern)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				served = true
+				w.WriteHeader(http.StatusNoContent)
+			}))
+
+			req := httptest.NewRequest(tt.method, tt.path, tt.body)
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if served != tt.wantServed {
+				t.Fatalf("handler served = %v, want %v", served, tt.wantServed)
+			}
+			if tt.wantServed {
+				return
+			}
+
+			if rec.Code != http.StatusUnsupportedMediaType {
+				t.Fatalf("status = %d, want 415", rec.Code)
+			}
+			var resp response.Response
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("invalid response body %q: %v", rec.Body.String(), err)
+			}
+			if resp.Success || resp.Error == nil || resp.Error.Code != response.ErrorCodeUnsupportedType {
+				t.Errorf("response = %+v, want a %s error", resp, response.ErrorCodeUnsupportedType)
+			}
+		})
+	}
+}
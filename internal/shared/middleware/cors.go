@@ -0,0 +1,64 @@
+// internal/shared/middleware/cors.go
+package middleware
+
+import (
+	"net/http"
+	"strings"
+)
+
+// CORS settings sent to allowed browsers
+const (
+	corsAllowedMethods = "GET, POST, PUT, PATCH, DELETE"
+	corsAllowedHeaders = "Authorization, Content-Type, If-Match, If-None-Match, If-Modified-Since, " + IdempotencyKeyHeader + ", " + RequestIDHeader
+	corsExposedHeaders = "ETag, Last-Modified, " + RequestIDHeader
+	corsMaxAge         = "600" // Seconds browsers may reuse a preflight answer
+)
+
+// CORS returns a middleware that lets browser applications served from allowedOrigins call the API
+// "*" allows every origin. Preflight requests from allowed origins are answered directly.
+// Requests from other origins get no CORS headers, so browsers block them; with no allowed
+// origins the middleware does nothing.
+func CORS(allowedOrigins []string) func(http.Handler) http.Handler {
+	allowAll := false
+	allowed := make(map[string]bool, len(allowedOrigins))
+	for _, origin := range allowedOrigins {
+		if origin == "*" {
+			allowAll = true
+		}
+		allowed[strings.TrimSuffix(origin, "/")] = true
+	}
+
+	return func(next http.Handler) http.Handler {
+		if len(allowed) == 0 {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			if origin == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Add("Vary", "Origin")
+			if !allowAll && !allowed[origin] {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Set("Access-Control-Expose-Headers", corsExposedHeaders)
+
+			// Preflight: the browser asks before sending the actual request
+			if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+				w.Header().Set("Access-Control-Allow-Methods", corsAllowedMethods)
+				w.Header().Set("Access-Control-Allow-Headers", corsAllowedHeaders)
+				w.Header().Set("Access-Control-Max-Age", corsMaxAge)
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,101 @@
+// internal/shared/middleware/cors_test.go
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCORS(t *testing.T) {
+	tests := []struct {
+		name        string
+		allowed     []string
+		method      string
+		origin      string
+		preflight   bool
+		wantOrigin  string
+		wantStatus  int
+		wantHandled bool // whether the request reached the handler
+	}{
+		{name: "disabled", method: http.MethodGet, origin: "https://app.example.com", wantStatus: http.StatusOK, wantHandled: true},
+		{name: "same-origin request", allowed: []string{"https://app.example.com"}, method: http.MethodGet, wantStatus: http.StatusOK, wantHandled: true},
+		{
+			name:        "allowed origin",
+			allowed:     []string{"https://app.example.com"},
+			method:      http.MethodGet,
+			origin:      "https://app.example.com",
+			wantOrigin:  "https://app.example.com",
+			wantStatus:  http.StatusOK,
+			wantHandled: true,
+		},
+		{
+			name:        "configured with a trailing slash",
+			allowed:     []string{"https://app.example.com/"},
+			method:      http.MethodGet,
+			origin:      "https://app.example.com",
+			wantOrigin:  "https://app.example.com",
+			wantStatus:  http.StatusOK,
+			wantHandled: true,
+		},
+		{name: "other origin", allowed: []string{"https://app.example.com"}, method: http.MethodGet, origin: "https://evil.example.com", wantStatus: http.StatusOK, wantHandled: true},
+		{name: "any origin", allowed: []string{"*"}, method: http.MethodGet, origin: "https://evil.example.com", wantOrigin: "https://evil.example.com", wantStatus: http.StatusOK, wantHandled: true},
+		{
+			name:       "preflight from allowed origin",
+			allowed:    []string{"https://app.example.com"},
+			method:     http.MethodOptions,
+			origin:     "https://app.example.com",
+			preflight:  true,
+			wantOrigin: "https://app.example.com",
+			wantStatus: http.StatusNoContent,
+		},
+		{
+			name:        "preflight from other origin",
+			allowed:     []string{"https://app.example.com"},
+			method:      http.MethodOptions,
+			origin:      "https://evil.example.com",
+			preflight:   true,
+			wantStatus:  http.StatusOK,
+			wantHandled: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handled := false
+			handler := CORS(tt.allowed)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				handled = true
+			}))
+
+			r := httptest.NewRequest(tt.method, "/api/v1/users", nil)
+			if tt.origin != "" {
+				r.Header.Set("Origin", tt.origin)
+			}
+			if tt.preflight {
+				r.Header.Set("Access-Control-Request-Method", http.MethodPut)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, r)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if handled != tt.wantHandled {
+				t.Errorf("handled = %v, want %v", handled, tt.wantHandled)
+			}
+			if got := rec.Header().Get("Access-Control-Allow-Origin"); got != tt.wantOrigin {
+				t.Errorf("Access-Control-Allow-Origin = %q, want %q", got, tt.wantOrigin)
+			}
+			wantMethods := ""
+			if tt.preflight && tt.wantOrigin != "" {
+				wantMethods = corsAllowedMethods
+			}
+			if got := rec.Header().Get("Access-Control-Allow-Methods"); got != wantMethods {
+				t.Errorf("Access-Control-Allow-Methods = %q, want %q", got, wantMethods)
+			}
+			if tt.origin != "" && len(tt.allowed) > 0 && rec.Header().Get("Vary") != "Origin" {
+				t.Errorf("Vary = %q, want Origin", rec.Header().Get("Vary"))
+			}
+		})
+	}
+}
@@ -0,0 +1,221 @@
+// internal/shared/middleware/idempotency.go
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"go-template/internal/interfaces"
+	"go-template/internal/shared/response"
+)
+
+const (
+	// IdempotencyKeyHeader is the request header carrying the client-supplied idempotency key
+	IdempotencyKeyHeader = "Idempotency-Key"
+
+	// IdempotencyReplayedHeader is set on responses that were replayed from a stored result
+	IdempotencyReplayedHeader = "Idempotent-Replayed"
+
+	// DefaultIdempotencyTTL is how long stored responses are kept for replay
+	DefaultIdempotencyTTL = 24 * time.Hour
+
+	// maxIdempotencyKeyLength bounds the key size to keep cache keys reasonable
+	maxIdempotencyKeyLength = 255
+
+	// idempotencyLockTTL bounds how long a key stays reserved if its request never finishes;
+	// it outlives the server's request and write timeouts
+	idempotencyLockTTL = time.Minute
+
+	cacheKeyIdempotency     = "idempotency:%s:%s:%s" // scope:caller:key
+	cacheKeyIdempotencyLock = "lock:" + cacheKeyIdempotency
+)
+
+// unreplayedHeaders are response headers describing one particular response, not its result
+var unreplayedHeaders = map[string]bool{
+	"Content-Length":                         true,
+	"Date":                                   true,
+	http.CanonicalHeaderKey(RequestIDHeader): true,
+}
+
+// idempotencyRecord is the stored result of a request made with an idempotency key
+type idempotencyRecord struct {
+	BodyHash   string      `json:"body_hash"`
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header"`
+	Body       []byte      `json:"body"`
+}
+
+// Idempotency returns a middleware that makes a handler safe to retry with an Idempotency-Key header.
+// The first response for a key is stored in the cache for ttl and replayed, headers included, for
+// repeat requests carrying the same key and body; the same key with a different body is rejected
+// with 422. While the first request for a key is running, the key is reserved and concurrent
+// duplicates are rejected with 409 instead of running the handler again. Keys are scoped to the
+// caller: the authenticated user when claims are in the context, otherwise the client IP, resolved
+// through trustedProxies. Bodies are read up to response.DefaultMaxBodyBytes; larger ones get 413.
+// Requests without the header pass through untouched. Server errors are not stored so they can be retried.
+func Idempotency(cache interfaces.CacheInterface, scope string, ttl time.Duration, trustedProxies []string, logger interfaces.LoggerInterface) func(http.Handler) http.Handler {
+	trusted := ParseTrustedProxies(trustedProxies)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(IdempotencyKeyHeader)
+			if key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if len(key) > maxIdempotencyKeyLength {
+				response.BadRequest(w, fmt.Sprintf("%s header cannot exceed %d characters", IdempotencyKeyHeader, maxIdempotencyKeyLength))
+				return
+			}
+
+			// The body is buffered to hash it, so it is bounded before the handler's own limit applies
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, response.DefaultMaxBodyBytes))
+			if err != nil {
+				var maxBytesErr *http.MaxBytesError
+				if errors.As(err, &maxBytesErr) {
+					response.Error(w, fmt.Sprintf("Request body must not be larger than %d bytes", maxBytesErr.Limit), http.StatusRequestEntityTooLarge)
+					return
+				}
+				response.BadRequest(w, "Failed to read request body")
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			hash := sha256.Sum256(body)
+			bodyHash := hex.EncodeToString(hash[:])
+			caller := idempotencyCaller(r, trusted)
+			cacheKey := fmt.Sprintf(cacheKeyIdempotency, scope, caller, key)
+
+			// Replay a stored response if one exists for this key
+			if replayIdempotent(r.Context(), w, cache, cacheKey, bodyHash) {
+				return
+			}
+
+			// Reserve the key so a concurrent retry cannot run the handler a second time.
+			// If the cache cannot be reached the request runs unprotected, as it would without a key.
+			lockKey := fmt.Sprintf(cacheKeyIdempotencyLock, scope, caller, key)
+			acquired, err := cache.Lock(r.Context(), lockKey, idempotencyLockTTL)
+			switch {
+			case err != nil:
+				logger.WithContext(r.Context()).Warn("Failed to reserve idempotency key", "scope", scope, "error", err.Error())
+			case !acquired:
+				response.ErrorWithCode(w, response.ErrorCodeIdempotencyInProgress,
+					"A request with this Idempotency-Key is still being processed", http.StatusConflict)
+				return
+			default:
+				defer func() {
+					if err := cache.Unlock(context.WithoutCancel(r.Context()), lockKey); err != nil {
+						logger.WithContext(r.Context()).Warn("Failed to release idempotency key", "scope", scope, "error", err.Error())
+					}
+				}()
+				// The request holding the key before us may have finished in between
+				if replayIdempotent(r.Context(), w, cache, cacheKey, bodyHash) {
+					return
+				}
+			}
+
+			recorder := &recordingResponseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+			next.ServeHTTP(recorder, r)
+
+			if recorder.statusCode >= http.StatusInternalServerError {
+				return
+			}
+
+			record := idempotencyRecord{
+				BodyHash:   bodyHash,
+				StatusCode: recorder.statusCode,
+				Header:     recorder.replayableHeader(),
+				Body:       recorder.body.Bytes(),
+			}
+			// Without the stored response a retry would run the handler again
+			if err := cache.Set(context.WithoutCancel(r.Context()), cacheKey, record, ttl); err != nil {
+				logger.WithContext(r.Context()).Error("Failed to store idempotent response", err, "scope", scope, "status", recorder.statusCode)
+			}
+		})
+	}
+}
+
+// replayIdempotent writes the response stored under cacheKey, if any, and reports whether it did
+// A stored response for a different body is answered with 422 instead.
+func replayIdempotent(ctx context.Context, w http.ResponseWriter, cache interfaces.CacheInterface, cacheKey, bodyHash string) bool {
+	cached, err := cache.Get(ctx, cacheKey)
+	if err != nil {
+		return false
+	}
+	var record idempotencyRecord
+	if err := json.Unmarshal([]byte(cached), &record); err != nil {
+		return false
+	}
+
+	if record.BodyHash != bodyHash {
+		response.ErrorWithCode(w, response.ErrorCodeIdempotencyMismatch,
+			"Idempotency-Key was already used with a different request body", http.StatusUnprocessableEntity)
+		return true
+	}
+
+	for name, values := range record.Header {
+		w.Header()[name] = values
+	}
+	w.Header().Set(IdempotencyReplayedHeader, "true")
+	w.WriteHeader(record.StatusCode)
+	w.Write(record.Body)
+	return true
+}
+
+// idempotencyCaller identifies who sent r, so one caller cannot replay another's responses
+func idempotencyCaller(r *http.Request, trusted TrustedProxies) string {
+	if claims, ok := ClaimsFromContext(r.Context()); ok {
+		return "user:" + claims.Subject
+	}
+	return "ip:" + trusted.ClientIP(r)
+}
+
+// recordingResponseWriter passes writes through while keeping a copy of the status, headers and body
+type recordingResponseWriter struct {
+	http.ResponseWriter
+	statusCode int
+	header     http.Header
+	body       bytes.Buffer
+}
+
+// WriteHeader records the status code and headers before writing them
+func (rw *recordingResponseWriter) WriteHeader(statusCode int) {
+	if rw.header == nil {
+		rw.statusCode = statusCode
+		rw.header = rw.Header().Clone()
+	}
+	rw.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Write records the body before writing it
+func (rw *recordingResponseWriter) Write(b []byte) (int, error) {
+	if rw.header == nil {
+		rw.WriteHeader(http.StatusOK)
+	}
+	rw.body.Write(b)
+	return rw.ResponseWriter.Write(b)
+}
+
+// replayableHeader returns the headers sent with the response, minus per-response ones
+func (rw *recordingResponseWriter) replayableHeader() http.Header {
+	header := rw.header
+	if header == nil {
+		header = rw.Header()
+	}
+
+	replayable := http.Header{}
+	for name, values := range header {
+		if !unreplayedHeaders[name] {
+			replayable[name] = append([]string(nil), values...)
+		}
+	}
+	return replayable
+}
@@ -0,0 +1,364 @@
+// internal/shared/middleware/idempotency_test.go
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go-template/internal/database"
+	"go-template/internal/shared/logtest"
+	"go-template/internal/shared/response"
+	"go-template/internal/shared/utils"
+)
+
+// idempotentRequest describes one request sent through the Idempotency middleware
+type idempotentRequest struct {
+	key        string
+	body       string
+	subject    string // authenticated user, if any
+	remoteAddr string
+}
+
+func TestIdempotency(t *testing.T) {
+	first := idempotentRequest{key: "key-1", body: `{"username":"alice"}`, remoteAddr: "192.0.2.1:1000"}
+
+	tests := []struct {
+		name         string
+		first        idempotentRequest
+		second       idempotentRequest
+		firstStatus  int
+		wantStatus   int
+		wantCalls    int
+		wantReplayed bool
+	}{
+		{
+			name:         "replays the same key and body",
+			first:        first,
+			second:       first,
+			firstStatus:  http.StatusCreated,
+			wantStatus:   http.StatusCreated,
+			wantCalls:    1,
+			wantReplayed: true,
+		},
+		{
+			name:        "rejects the same key with a different body",
+			first:       first,
+			second:      idempotentRequest{key: "key-1", body: `{"username":"bob"}`, remoteAddr: "192.0.2.1:1000"},
+			firstStatus: http.StatusCreated,
+			wantStatus:  http.StatusUnprocessableEntity,
+			wantCalls:   1,
+		},
+		{
+			name:        "different keys run the handler",
+			first:       first,
+			second:      idempotentRequest{key: "key-2", body: first.body, remoteAddr: "192.0.2.1:1000"},
+			firstStatus: http.StatusCreated,
+			wantStatus:  http.StatusCreated,
+			wantCalls:   2,
+		},
+		{
+			name:        "requests without a key run the handler",
+			first:       idempotentRequest{body: first.body, remoteAddr: "192.0.2.1:1000"},
+			second:      idempotentRequest{body: first.body, remoteAddr: "192.0.2.1:1000"},
+			firstStatus: http.StatusCreated,
+			wantStatus:  http.StatusCreated,
+			wantCalls:   2,
+		},
+		{
+			name:        "server errors are not stored",
+			first:       first,
+			second:      first,
+			firstStatus: http.StatusInternalServerError,
+			wantStatus:  http.StatusInternalServerError,
+			wantCalls:   2,
+		},
+		{
+			name:         "client errors are stored",
+			first:        first,
+			second:       first,
+			firstStatus:  http.StatusConflict,
+			wantStatus:   http.StatusConflict,
+			wantCalls:    1,
+			wantReplayed: true,
+		},
+		{
+			name:        "keys are scoped to the client IP",
+			first:       first,
+			second:      idempotentRequest{key: "key-1", body: first.body, remoteAddr: "192.0.2.2:1000"},
+			firstStatus: http.StatusCreated,
+			wantStatus:  http.StatusCreated,
+			wantCalls:   2,
+		},
+		{
+			name:        "keys are scoped to the authenticated user",
+			first:       idempotentRequest{key: "key-1", body: first.body, subject: "user-1", remoteAddr: "192.0.2.1:1000"},
+			second:      idempotentRequest{key: "key-1", body: first.body, subject: "user-2", remoteAddr: "192.0.2.1:1000"},
+			firstStatus: http.StatusCreated,
+			wantStatus:  http.StatusCreated,
+			wantCalls:   2,
+		},
+		{
+			name:         "authenticated users keep their keys across addresses",
+			first:        idempotentRequest{key: "key-1", body: first.body, subject: "user-1", remoteAddr: "192.0.2.1:1000"},
+			second:       idempotentRequest{key: "key-1", body: first.body, subject: "user-1", remoteAddr: "192.0.2.2:1000"},
+			firstStatus:  http.StatusCreated,
+			wantStatus:   http.StatusCreated,
+			wantCalls:    1,
+			wantReplayed: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache := database.NewMemoryCache()
+			defer cache.Close()
+
+			calls := 0
+			handler := Idempotency(cache, "test", time.Hour, nil, logtest.New())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls++
+				w.Header().Set("Content-Type", "application/json")
+				w.Header().Set("Location", "/api/v1/users/1")
+				w.WriteHeader(tt.firstStatus)
+				w.Write([]byte(`{"id":"1"}`))
+			}))
+
+			original := sendIdempotent(handler, tt.first)
+			if original.Code != tt.firstStatus {
+				t.Fatalf("first status = %d, want %d", original.Code, tt.firstStatus)
+			}
+
+			rec := sendIdempotent(handler, tt.second)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("second status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("handler called %d times, want %d", calls, tt.wantCalls)
+			}
+
+			replayed := rec.Header().Get(IdempotencyReplayedHeader) == "true"
+			if replayed != tt.wantReplayed {
+				t.Errorf("replayed = %v, want %v", replayed, tt.wantReplayed)
+			}
+			if !replayed {
+				return
+			}
+			if rec.Body.String() != original.Body.String() {
+				t.Errorf("replayed body = %q, want %q", rec.Body.String(), original.Body.String())
+			}
+			for _, name := range []string{"Content-Type", "Location"} {
+				if got, want := rec.Header().Get(name), original.Header().Get(name); got != want {
+					t.Errorf("replayed %s = %q, want %q", name, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestIdempotencyDoesNotReplayRequestID(t *testing.T) {
+	cache := database.NewMemoryCache()
+	defer cache.Close()
+
+	handler := Idempotency(cache, "test", time.Hour, nil, logtest.New())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	}))
+	req := idempotentRequest{key: "key-1", body: "{}", remoteAddr: "192.0.2.1:1000"}
+
+	first := httptest.NewRecorder()
+	first.Header().Set(RequestIDHeader, "first")
+	handler.ServeHTTP(first, newIdempotentRequest(req))
+
+	second := httptest.NewRecorder()
+	second.Header().Set(RequestIDHeader, "second")
+	handler.ServeHTTP(second, newIdempotentRequest(req))
+
+	if second.Header().Get(IdempotencyReplayedHeader) != "true" {
+		t.Fatal("second request was not replayed")
+	}
+	if got := second.Header().Get(RequestIDHeader); got != "second" {
+		t.Errorf("%s = %q, want the replaying request's own ID", RequestIDHeader, got)
+	}
+}
+
+func TestIdempotencyRejectsLongKeys(t *testing.T) {
+	cache := database.NewMemoryCache()
+	defer cache.Close()
+
+	handler := Idempotency(cache, "test", time.Hour, nil, logtest.New())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("handler should not run")
+	}))
+	rec := sendIdempotent(handler, idempotentRequest{key: strings.Repeat("k", maxIdempotencyKeyLength+1), body: "{}"})
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+func TestIdempotencyRejectsConcurrentDuplicates(t *testing.T) {
+	cache := database.NewMemoryCache()
+	defer cache.Close()
+
+	var calls atomic.Int32
+	started, release := make(chan struct{}), make(chan struct{})
+	handler := Idempotency(cache, "test", time.Hour, nil, logtest.New())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			close(started)
+			<-release
+		}
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id":"1"}`))
+	}))
+	req := idempotentRequest{key: "key-1", body: `{"username":"alice"}`, remoteAddr: "192.0.2.1:1000"}
+
+	// The first request holds the key while its handler runs
+	first := make(chan *httptest.ResponseRecorder)
+	go func() { first <- sendIdempotent(handler, req) }()
+	select {
+	case <-started:
+	case <-time.After(time.Second):
+		t.Fatal("first request did not reach the handler")
+	}
+
+	tests := []struct {
+		name       string
+		req        idempotentRequest
+		wantStatus int
+		wantCode   string
+	}{
+		{name: "duplicate while in flight", req: req, wantStatus: http.StatusConflict, wantCode: response.ErrorCodeIdempotencyInProgress},
+		{name: "different body while in flight", req: idempotentRequest{key: "key-1", body: `{"username":"bob"}`, remoteAddr: req.remoteAddr}, wantStatus: http.StatusConflict, wantCode: response.ErrorCodeIdempotencyInProgress},
+		{name: "another caller's key is free", req: idempotentRequest{key: "key-1", body: req.body, remoteAddr: "192.0.2.2:1000"}, wantStatus: http.StatusCreated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := sendIdempotent(handler, tt.req)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantCode == "" {
+				return
+			}
+			var resp response.Response
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil || resp.Error == nil || resp.Error.Code != tt.wantCode {
+				t.Errorf("response = %s, want a %s error", rec.Body.String(), tt.wantCode)
+			}
+		})
+	}
+
+	close(release)
+	if rec := <-first; rec.Code != http.StatusCreated {
+		t.Fatalf("first status = %d, want 201", rec.Code)
+	}
+
+	// Once the first request is done its response is replayed and the key is free again
+	rec := sendIdempotent(handler, req)
+	if rec.Code != http.StatusCreated || rec.Header().Get(IdempotencyReplayedHeader) != "true" {
+		t.Errorf("retry status = %d, replayed %q, want the stored 201", rec.Code, rec.Header().Get(IdempotencyReplayedHeader))
+	}
+	if got := calls.Load(); got != 2 {
+		t.Errorf("handler called %d times, want 2 (once per caller)", got)
+	}
+}
+
+func TestIdempotencyBodyLimit(t *testing.T) {
+	tests := []struct {
+		name       string
+		size       int64
+		wantStatus int
+	}{
+		{name: "at the limit", size: response.DefaultMaxBodyBytes, wantStatus: http.StatusCreated},
+		{name: "over the limit", size: response.DefaultMaxBodyBytes + 1, wantStatus: http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache := database.NewMemoryCache()
+			defer cache.Close()
+
+			var received int
+			handler := Idempotency(cache, "test", time.Hour, nil, logtest.New())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				received = len(body)
+				w.WriteHeader(http.StatusCreated)
+			}))
+
+			rec := sendIdempotent(handler, idempotentRequest{key: "key-1", body: strings.Repeat("a", int(tt.size)), remoteAddr: "192.0.2.1:1000"})
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if tt.wantStatus != http.StatusCreated {
+				if received != 0 {
+					t.Error("handler ran for an oversized body")
+				}
+				return
+			}
+			if int64(received) != tt.size {
+				t.Errorf("handler read %d bytes, want the whole %d byte body", received, tt.size)
+			}
+		})
+	}
+}
+
+// failingSetCache is a memory cache whose writes fail
+type failingSetCache struct {
+	*database.MemoryCache
+}
+
+func (c failingSetCache) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error {
+	return errors.New("cache unavailable")
+}
+
+func TestIdempotencyLogsStoreFailures(t *testing.T) {
+	cache := failingSetCache{MemoryCache: database.NewMemoryCache()}
+	defer cache.Close()
+	logger := logtest.New()
+
+	calls := 0
+	handler := Idempotency(cache, "test", time.Hour, nil, logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusCreated)
+	}))
+	req := idempotentRequest{key: "key-1", body: "{}", remoteAddr: "192.0.2.1:1000"}
+
+	// The response could not be stored, so the retry runs again; the log explains why
+	for range 2 {
+		if rec := sendIdempotent(handler, req); rec.Code != http.StatusCreated {
+			t.Fatalf("status = %d, want 201", rec.Code)
+		}
+	}
+	if calls != 2 {
+		t.Errorf("handler called %d times, want 2", calls)
+	}
+	if !logger.Has(slog.LevelError, "Failed to store idempotent response") {
+		t.Error("store failure was not logged")
+	}
+}
+
+// newIdempotentRequest builds the POST request described by req
+func newIdempotentRequest(req idempotentRequest) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/api/v1/users", strings.NewReader(req.body))
+	if req.key != "" {
+		r.Header.Set(IdempotencyKeyHeader, req.key)
+	}
+	if req.remoteAddr != "" {
+		r.RemoteAddr = req.remoteAddr
+	}
+	if req.subject != "" {
+		r = r.WithContext(WithClaims(r.Context(), &utils.TokenClaims{Subject: req.subject}))
+	}
+	return r
+}
+
+// sendIdempotent serves req through handler and returns the recorded response
+func sendIdempotent(handler http.Handler, req idempotentRequest) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, newIdempotentRequest(req))
+	return rec
+}
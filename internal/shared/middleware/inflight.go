@@ -0,0 +1,32 @@
+// internal/shared/middleware/inflight.go
+package middleware
+
+import (
+	"net/http"
+	"sync/atomic"
+)
+
+// InFlightCounter tracks the number of requests currently being served
+type InFlightCounter struct {
+	count atomic.Int64
+}
+
+// NewInFlightCounter creates a new InFlightCounter
+func NewInFlightCounter() *InFlightCounter {
+	return &InFlightCounter{}
+}
+
+// Middleware returns a middleware that counts requests while they are being handled
+func (c *InFlightCounter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.count.Add(1)
+		defer c.count.Add(-1)
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Count returns the number of requests currently in flight
+func (c *InFlightCounter) Count() int64 {
+	return c.count.Load()
+}
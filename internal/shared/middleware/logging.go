@@ -0,0 +1,70 @@
+// internal/shared/middleware/logging.go
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"go-template/internal/interfaces"
+)
+
+// AccessLogLevelFunc chooses the log level for a completed request
+type AccessLogLevelFunc func(r *http.Request, statusCode int) slog.Level
+
+// DefaultAccessLogLevel logs server errors at error, client errors at warn and everything else at info
+func DefaultAccessLogLevel(r *http.Request, statusCode int) slog.Level {
+	switch {
+	case statusCode >= http.StatusInternalServerError:
+		return slog.LevelError
+	case statusCode >= http.StatusBadRequest:
+		return slog.LevelWarn
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// QuietRoutes returns an AccessLogLevelFunc that downgrades successful requests to the given
+// route patterns (e.g. "GET /health") to debug, deferring to DefaultAccessLogLevel otherwise
+func QuietRoutes(patterns ...string) AccessLogLevelFunc {
+	quiet := make(map[string]bool, len(patterns))
+	for _, pattern := range patterns {
+		quiet[pattern] = true
+	}
+
+	return func(r *http.Request, statusCode int) slog.Level {
+		if statusCode < http.StatusBadRequest && quiet[r.Pattern] {
+			return slog.LevelDebug
+		}
+		return DefaultAccessLogLevel(r, statusCode)
+	}
+}
+
+// AccessLog returns a middleware that logs one line per completed request
+// It must wrap the ServeMux without copying the request so the matched route pattern is available.
+// A nil levelFunc uses DefaultAccessLogLevel. See ClientIP for trustedProxies.
+func AccessLog(logger interfaces.LoggerInterface, levelFunc AccessLogLevelFunc, trustedProxies []string) func(http.Handler) http.Handler {
+	if levelFunc == nil {
+		levelFunc = DefaultAccessLogLevel
+	}
+	trusted := ParseTrustedProxies(trustedProxies)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			recorder := &statusRecorder{ResponseWriter: w, statusCode: http.StatusOK}
+			next.ServeHTTP(recorder, r)
+
+			logger.Log(r.Context(), levelFunc(r, recorder.statusCode), "HTTP request",
+				"method", r.Method,
+				"path", r.URL.Path,
+				"route", r.Pattern,
+				"status", recorder.statusCode,
+				"bytes", recorder.bytesWritten,
+				"duration_ms", float64(time.Since(start).Microseconds())/1000,
+				"client_ip", trusted.ClientIP(r),
+				"request_id", RequestIDFromContext(r.Context()),
+			)
+		})
+	}
+}
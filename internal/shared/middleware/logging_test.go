@@ -0,0 +1,168 @@
+// internal/shared/middleware/logging_test.go
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go-template/internal/interfaces"
+)
+
+// slogLogger adapts a *slog.Logger to interfaces.LoggerInterface
+type slogLogger struct {
+	logger *slog.Logger
+}
+
+func (l slogLogger) Debug(msg string, args ...interface{}) { l.logger.Debug(msg, args...) }
+func (l slogLogger) Info(msg string, args ...interface{})  { l.logger.Info(msg, args...) }
+func (l slogLogger) Warn(msg string, args ...interface{})  { l.logger.Warn(msg, args...) }
+func (l slogLogger) Error(msg string, err error, args ...interface{}) {
+	l.logger.Error(msg, append([]interface{}{"error", err}, args...)...)
+}
+func (l slogLogger) With(args ...interface{}) interfaces.LoggerInterface {
+	return slogLogger{logger: l.logger.With(args...)}
+}
+func (l slogLogger) WithContext(ctx context.Context) interfaces.LoggerInterface { return l }
+func (l slogLogger) Log(ctx context.Context, level slog.Level, msg string, args ...interface{}) {
+	l.logger.Log(ctx, level, msg, args...)
+}
+
+// newBufferLogger returns a logger writing JSON lines at every level to the returned buffer
+func newBufferLogger() (interfaces.LoggerInterface, *bytes.Buffer) {
+	buf := &bytes.Buffer{}
+	handler := slog.NewJSONHandler(buf, &slog.HandlerOptions{Level: slog.LevelDebug})
+	return slogLogger{logger: slog.New(handler)}, buf
+}
+
+func TestAccessLog(t *testing.T) {
+	tests := []struct {
+		name      string
+		target    string
+		status    int
+		body      string
+		levelFunc AccessLogLevelFunc
+		wantRoute string
+		wantLevel string
+
+		// The request comes from 203.0.113.7 unless trustedProxies lets forwardedFor through
+		forwardedFor   string
+		trustedProxies []string
+		wantClientIP   string
+	}{
+		{
+			name:      "successful request",
+			target:    "/api/v1/users/42",
+			status:    http.StatusOK,
+			body:      `{"id":"42"}`,
+			wantRoute: "GET /api/v1/users/{id}",
+			wantLevel: "INFO",
+		},
+		{
+			name:      "client error",
+			target:    "/api/v1/users/missing",
+			status:    http.StatusNotFound,
+			body:      "not found",
+			wantRoute: "GET /api/v1/users/{id}",
+			wantLevel: "WARN",
+		},
+		{
+			name:      "server error",
+			target:    "/api/v1/users/broken",
+			status:    http.StatusInternalServerError,
+			wantRoute: "GET /api/v1/users/{id}",
+			wantLevel: "ERROR",
+		},
+		{
+			name:      "quiet health check",
+			target:    "/health",
+			status:    http.StatusOK,
+			body:      "ok",
+			levelFunc: QuietRoutes("GET /health"),
+			wantRoute: "GET /health",
+			wantLevel: "DEBUG",
+		},
+		{
+			name:      "failing health check is not quieted",
+			target:    "/health",
+			status:    http.StatusServiceUnavailable,
+			levelFunc: QuietRoutes("GET /health"),
+			wantRoute: "GET /health",
+			wantLevel: "ERROR",
+		},
+		{
+			name:         "forwarded for from an untrusted peer",
+			target:       "/api/v1/users/42",
+			status:       http.StatusOK,
+			wantRoute:    "GET /api/v1/users/{id}",
+			wantLevel:    "INFO",
+			forwardedFor: "198.51.100.1",
+		},
+		{
+			name:           "forwarded for from a trusted proxy",
+			target:         "/api/v1/users/42",
+			status:         http.StatusOK,
+			wantRoute:      "GET /api/v1/users/{id}",
+			wantLevel:      "INFO",
+			forwardedFor:   "198.51.100.1",
+			trustedProxies: []string{"203.0.113.0/24"},
+			wantClientIP:   "198.51.100.1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reply := func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}
+			mux := http.NewServeMux()
+			mux.HandleFunc("GET /api/v1/users/{id}", reply)
+			mux.HandleFunc("GET /health", reply)
+
+			logger, buf := newBufferLogger()
+			handler := RequestID(AccessLog(logger, tt.levelFunc, tt.trustedProxies)(mux))
+
+			r := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			r.RemoteAddr = "203.0.113.7:52100"
+			r.Header.Set(RequestIDHeader, "req-123")
+			if tt.forwardedFor != "" {
+				r.Header.Set("X-Forwarded-For", tt.forwardedFor)
+			}
+			handler.ServeHTTP(httptest.NewRecorder(), r)
+
+			var line map[string]interface{}
+			if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+				t.Fatalf("expected one JSON log line, got %q: %v", buf.String(), err)
+			}
+
+			clientIP := "203.0.113.7"
+			if tt.wantClientIP != "" {
+				clientIP = tt.wantClientIP
+			}
+			want := map[string]interface{}{
+				"level":      tt.wantLevel,
+				"msg":        "HTTP request",
+				"method":     http.MethodGet,
+				"path":       tt.target,
+				"route":      tt.wantRoute,
+				"status":     float64(tt.status),
+				"bytes":      float64(len(tt.body)),
+				"client_ip":  clientIP,
+				"request_id": "req-123",
+			}
+			for key, value := range want {
+				if line[key] != value {
+					t.Errorf("%s = %v, want %v", key, line[key], value)
+				}
+			}
+			if duration, ok := line["duration_ms"].(float64); !ok || duration < 0 {
+				t.Errorf("duration_ms = %v, want a non-negative number", line["duration_ms"])
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+// internal/shared/middleware/maintenance.go
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"go-template/internal/shared/response"
+)
+
+// MaintenanceRetryAfter is the Retry-After sent with maintenance mode responses
+const MaintenanceRetryAfter = 2 * time.Minute
+
+// Maintenance returns a middleware that answers requests with 503 while enabled is set
+// With allowReads only mutating methods (POST, PUT, PATCH and DELETE) are refused, so the API
+// stays readable during deploys and migrations. Requests whose path starts with one of
+// exemptPrefixes, such as health probes and the endpoint that turns maintenance mode off,
+// are always served.
+func Maintenance(enabled *atomic.Bool, allowReads bool, exemptPrefixes ...string) func(http.Handler) http.Handler {
+	retryAfter := strconv.Itoa(int(MaintenanceRetryAfter.Seconds()))
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !enabled.Load() || (allowReads && !isMutatingMethod(r.Method)) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			for _, prefix := range exemptPrefixes {
+				if strings.HasPrefix(r.URL.Path, prefix) {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+
+			w.Header().Set("Retry-After", retryAfter)
+			response.ErrorWithCode(w, response.ErrorCodeServiceUnavailable,
+				"The service is undergoing maintenance, please try again later", http.StatusServiceUnavailable)
+		})
+	}
+}
+
+// isMutatingMethod reports whether an HTTP method changes server state
+func isMutatingMethod(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,105 @@
+// internal/shared/middleware/maintenance_test.go
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"go-template/internal/shared/response"
+)
+
+func TestMaintenance(t *testing.T) {
+	exempt := []string{"/api/v1/system/maintenance", "/health"}
+
+	tests := []struct {
+		name       string
+		enabled    bool
+		allowReads bool
+		method     string
+		path       string
+		wantServed bool
+	}{
+		{name: "disabled serves writes", method: http.MethodPost, path: "/api/v1/users", wantServed: true},
+		{name: "disabled serves reads", method: http.MethodGet, path: "/api/v1/users", wantServed: true},
+		{name: "GET passes with reads allowed", enabled: true, allowReads: true, method: http.MethodGet, path: "/api/v1/users", wantServed: true},
+		{name: "HEAD passes with reads allowed", enabled: true, allowReads: true, method: http.MethodHead, path: "/api/v1/users", wantServed: true},
+		{name: "OPTIONS passes with reads allowed", enabled: true, allowReads: true, method: http.MethodOptions, path: "/api/v1/users", wantServed: true},
+		{name: "POST is refused", enabled: true, allowReads: true, method: http.MethodPost, path: "/api/v1/users"},
+		{name: "PUT is refused", enabled: true, allowReads: true, method: http.MethodPut, path: "/api/v1/users/1"},
+		{name: "PATCH is refused", enabled: true, allowReads: true, method: http.MethodPatch, path: "/api/v1/users/1"},
+		{name: "DELETE is refused", enabled: true, allowReads: true, method: http.MethodDelete, path: "/api/v1/users/1"},
+		{name: "GET is refused without reads", enabled: true, method: http.MethodGet, path: "/api/v1/users"},
+		{name: "exempt endpoint accepts writes", enabled: true, method: http.MethodPost, path: "/api/v1/system/maintenance", wantServed: true},
+		{name: "exempt prefix covers subpaths", enabled: true, method: http.MethodGet, path: "/health/ready", wantServed: true},
+		{name: "similar path is not exempt", enabled: true, method: http.MethodGet, path: "/api/v1/system/status"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			enabled := new(atomic.Bool)
+			enabled.Store(tt.enabled)
+			served := false
+			handler := Maintenance(enabled, tt.allowReads, exempt...)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				served = true
+				w.WriteHeader(http.StatusNoContent)
+			}))
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, nil))
+
+			if served != tt.wantServed {
+				t.Fatalf("handler served = %v, want %v", served, tt.wantServed)
+			}
+			if tt.wantServed {
+				if rec.Code != http.StatusNoContent || rec.Header().Get("Retry-After") != "" {
+					t.Errorf("status = %d with Retry-After %q, want the handler's response", rec.Code, rec.Header().Get("Retry-After"))
+				}
+				return
+			}
+
+			if rec.Code != http.StatusServiceUnavailable {
+				t.Fatalf("status = %d, want 503", rec.Code)
+			}
+			if got := rec.Header().Get("Retry-After"); got != "120" {
+				t.Errorf("Retry-After = %q, want 120", got)
+			}
+			var resp response.Response
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("invalid response body %q: %v", rec.Body.String(), err)
+			}
+			if resp.Success || resp.Error == nil || resp.Error.Code != response.ErrorCodeServiceUnavailable {
+				t.Errorf("response = %+v, want a %s error", resp, response.ErrorCodeServiceUnavailable)
+			}
+		})
+	}
+}
+
+func TestMaintenanceToggle(t *testing.T) {
+	enabled := new(atomic.Bool)
+	handler := Maintenance(enabled, true)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	}))
+	post := func() int {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/users", nil))
+		return rec.Code
+	}
+
+	// The flag is read on every request, so switching it takes effect without rebuilding the chain
+	for _, step := range []struct {
+		enabled bool
+		want    int
+	}{
+		{enabled: false, want: http.StatusCreated},
+		{enabled: true, want: http.StatusServiceUnavailable},
+		{enabled: false, want: http.StatusCreated},
+	} {
+		enabled.Store(step.enabled)
+		if got := post(); got != step.want {
+			t.Errorf("enabled = %v: status = %d, want %d", step.enabled, got, step.want)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+// internal/shared/middleware/metrics.go
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// unmatchedRoute labels requests that did not match any registered pattern
+const unmatchedRoute = "unmatched"
+
+// Metrics records Prometheus metrics for HTTP requests
+type Metrics struct {
+	requests *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+	inFlight *prometheus.GaugeVec
+}
+
+// NewMetrics creates the HTTP metrics and registers them with the given registerer
+func NewMetrics(registerer prometheus.Registerer) *Metrics {
+	m := &Metrics{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_requests_total",
+			Help: "Total number of HTTP requests processed.",
+		}, []string{"method", "route", "status"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_request_duration_seconds",
+			Help:    "Duration of HTTP requests in seconds.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		// The route is only known once the mux has matched, so in-flight is labeled by method alone
+		inFlight: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "http_requests_in_flight",
+			Help: "Number of HTTP requests currently being served.",
+		}, []string{"method"}),
+	}
+
+	registerer.MustRegister(m.requests, m.duration, m.inFlight)
+	return m
+}
+
+// Middleware returns a middleware recording request count, duration and in-flight requests
+// It must wrap the ServeMux so that r.Pattern is populated once the request has been routed,
+// keeping the route label bounded to registered patterns rather than raw paths.
+func (m *Metrics) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		inFlight := m.inFlight.WithLabelValues(r.Method)
+		inFlight.Inc()
+		defer inFlight.Dec()
+
+		start := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w, statusCode: http.StatusOK}
+		next.ServeHTTP(recorder, r)
+
+		route := r.Pattern
+		if route == "" {
+			route = unmatchedRoute
+		}
+		status := strconv.Itoa(recorder.statusCode)
+
+		m.requests.WithLabelValues(r.Method, route, status).Inc()
+		m.duration.WithLabelValues(r.Method, route, status).Observe(time.Since(start).Seconds())
+	})
+}
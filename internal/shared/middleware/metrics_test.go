@@ -0,0 +1,76 @@
+// internal/shared/middleware/metrics_test.go
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+func TestMetrics(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	metrics := NewMetrics(registry)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("id") == "missing" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte("ok"))
+	})
+	mux.Handle("GET /metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+	handler := metrics.Middleware(mux)
+
+	for _, target := range []string{"/api/v1/users/1", "/api/v1/users/2", "/api/v1/users/missing", "/nowhere"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, target, nil))
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body, _ := io.ReadAll(rec.Body)
+	scrape := string(body)
+
+	tests := []struct {
+		name string
+		want string
+	}{
+		{
+			name: "requests are counted per route pattern, not raw path",
+			want: `http_requests_total{method="GET",route="GET /api/v1/users/{id}",status="200"} 2`,
+		},
+		{
+			name: "status codes are labeled",
+			want: `http_requests_total{method="GET",route="GET /api/v1/users/{id}",status="404"} 1`,
+		},
+		{
+			name: "unmatched requests share one label",
+			want: `http_requests_total{method="GET",route="unmatched",status="404"} 1`,
+		},
+		{
+			name: "durations are observed",
+			want: `http_request_duration_seconds_count{method="GET",route="GET /api/v1/users/{id}",status="200"} 2`,
+		},
+		{
+			name: "the scrape itself is in flight",
+			want: `http_requests_in_flight{method="GET"} 1`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !strings.Contains(scrape, tt.want) {
+				t.Errorf("scrape does not contain %s:\n%s", tt.want, scrape)
+			}
+		})
+	}
+
+	if strings.Contains(scrape, `/api/v1/users/1"`) {
+		t.Error("raw request path leaked into a label")
+	}
+}
@@ -0,0 +1,45 @@
+// internal/shared/middleware/recovery.go
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"runtime/debug"
+
+	"go-template/internal/interfaces"
+	"go-template/internal/shared/response"
+)
+
+// Recovery returns a middleware that answers 500 when a handler panics instead of dropping the connection
+// The panic is logged with its stack trace. http.ErrAbortHandler is re-raised, since handlers
+// use it to abort the response on purpose.
+func Recovery(logger interfaces.LoggerInterface) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			recorder := &statusRecorder{ResponseWriter: w, statusCode: http.StatusOK}
+
+			defer func() {
+				p := recover()
+				if p == nil {
+					return
+				}
+				if p == http.ErrAbortHandler {
+					panic(p)
+				}
+
+				logger.Error("Panic while serving request", fmt.Errorf("%v", p),
+					"method", r.Method,
+					"path", r.URL.Path,
+					"stack", string(debug.Stack()),
+				)
+
+				// Part of the response may already be on the wire; there is nothing left to fix then
+				if !recorder.wroteHeader {
+					response.InternalServerError(w)
+				}
+			}()
+
+			next.ServeHTTP(recorder, r)
+		})
+	}
+}
@@ -0,0 +1,73 @@
+// internal/shared/middleware/recovery_test.go
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go-template/internal/shared/logtest"
+)
+
+func TestRecovery(t *testing.T) {
+	tests := []struct {
+		name       string
+		handler    http.HandlerFunc
+		wantStatus int
+		wantLogged bool
+	}{
+		{
+			name:       "no panic",
+			handler:    func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusCreated) },
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name:       "panic before writing",
+			handler:    func(w http.ResponseWriter, r *http.Request) { panic("boom") },
+			wantStatus: http.StatusInternalServerError,
+			wantLogged: true,
+		},
+		{
+			name: "panic after writing keeps the status",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusAccepted)
+				panic("boom")
+			},
+			wantStatus: http.StatusAccepted,
+			wantLogged: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger := logtest.New()
+			rec := httptest.NewRecorder()
+			Recovery(logger)(tt.handler).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/users", nil))
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if logged := logger.Has(slog.LevelError, "Panic while serving request"); logged != tt.wantLogged {
+				t.Errorf("panic logged = %v, want %v", logged, tt.wantLogged)
+			}
+		})
+	}
+}
+
+func TestRecoveryReraisesAbortHandler(t *testing.T) {
+	logger := logtest.New()
+	handler := Recovery(logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	}))
+
+	defer func() {
+		if p := recover(); p != http.ErrAbortHandler {
+			t.Errorf("recovered %v, want http.ErrAbortHandler", p)
+		}
+		if logger.Has(slog.LevelError, "Panic while serving request") {
+			t.Error("deliberate abort was logged as a panic")
+		}
+	}()
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+}
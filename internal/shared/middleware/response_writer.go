@@ -0,0 +1,34 @@
+// internal/shared/middleware/response_writer.go
+package middleware
+
+import "net/http"
+
+// statusRecorder captures the status code and number of bytes written by a handler
+type statusRecorder struct {
+	http.ResponseWriter
+	statusCode   int
+	bytesWritten int
+	wroteHeader  bool
+}
+
+// WriteHeader records the first status code before writing it
+func (rw *statusRecorder) WriteHeader(statusCode int) {
+	if !rw.wroteHeader {
+		rw.statusCode = statusCode
+		rw.wroteHeader = true
+	}
+	rw.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Write counts the bytes written to the response body
+func (rw *statusRecorder) Write(b []byte) (int, error) {
+	rw.wroteHeader = true
+	n, err := rw.ResponseWriter.Write(b)
+	rw.bytesWritten += n
+	return n, err
+}
+
+// Unwrap exposes the underlying ResponseWriter to http.ResponseController
+func (rw *statusRecorder) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
@@ -0,0 +1,122 @@
+// internal/shared/middleware/timeout.go
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"go-template/internal/shared/response"
+)
+
+// Timeout returns a middleware that bounds how long a handler may run.
+// The request context is given a deadline of d, so database and cache calls made with it
+// are canceled once it passes. The handler's response is buffered; if the deadline passes
+// first a 503 is sent instead and anything the handler writes afterwards is discarded.
+// A non-positive d disables the middleware. Requests whose path starts with one of
+// exemptPrefixes, such as streaming endpoints, pass through unbuffered and without a deadline.
+//
+// It should wrap the ServeMux directly: the matched route pattern is copied back to the
+// outer request when the handler finishes, but timed-out requests are reported unmatched.
+func Timeout(d time.Duration, exemptPrefixes ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if d <= 0 {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for _, prefix := range exemptPrefixes {
+				if strings.HasPrefix(r.URL.Path, prefix) {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+
+			ctx, cancel := context.WithTimeout(r.Context(), d)
+			defer cancel()
+
+			inner := r.WithContext(ctx)
+			tw := &timeoutWriter{header: make(http.Header), statusCode: http.StatusOK}
+			done := make(chan struct{})
+			panicked := make(chan interface{}, 1)
+
+			go func() {
+				defer func() {
+					if p := recover(); p != nil {
+						panicked <- p
+					}
+				}()
+				next.ServeHTTP(tw, inner)
+				close(done)
+			}()
+
+			select {
+			case p := <-panicked:
+				// Re-panic on the serving goroutine so the server's recovery still applies
+				panic(p)
+			case <-done:
+				tw.mu.Lock()
+				defer tw.mu.Unlock()
+
+				r.Pattern = inner.Pattern
+				for key, values := range tw.header {
+					w.Header()[key] = values
+				}
+				w.WriteHeader(tw.statusCode)
+				w.Write(tw.body.Bytes())
+			case <-ctx.Done():
+				tw.mu.Lock()
+				tw.timedOut = true
+				tw.mu.Unlock()
+
+				// A canceled parent means the client went away and there is no one to answer
+				if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+					response.Error(w, "Request timed out", http.StatusServiceUnavailable)
+				}
+			}
+		})
+	}
+}
+
+// timeoutWriter buffers a handler's response so it can be dropped if the deadline passes
+type timeoutWriter struct {
+	mu          sync.Mutex
+	header      http.Header
+	body        bytes.Buffer
+	statusCode  int
+	wroteHeader bool
+	timedOut    bool
+}
+
+// Header returns the buffered response headers
+func (tw *timeoutWriter) Header() http.Header {
+	return tw.header
+}
+
+// WriteHeader records the first status code unless the request already timed out
+func (tw *timeoutWriter) WriteHeader(statusCode int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	if tw.timedOut || tw.wroteHeader {
+		return
+	}
+	tw.statusCode = statusCode
+	tw.wroteHeader = true
+}
+
+// Write buffers the response body, failing with http.ErrHandlerTimeout once timed out
+func (tw *timeoutWriter) Write(b []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	if tw.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	tw.wroteHeader = true
+	return tw.body.Write(b)
+}
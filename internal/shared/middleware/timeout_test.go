@@ -0,0 +1,119 @@
+// internal/shared/middleware/timeout_test.go
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go-template/internal/shared/response"
+)
+
+func TestTimeout(t *testing.T) {
+	const deadline = 20 * time.Millisecond
+
+	tests := []struct {
+		name       string
+		timeout    time.Duration
+		exempt     []string
+		path       string
+		sleep      time.Duration
+		wantStatus int
+		wantBody   string
+		// wantCanceled is whether the handler sees its context canceled
+		wantCanceled bool
+	}{
+		{name: "fast handler", timeout: deadline, path: "/api/v1/users", wantStatus: http.StatusCreated, wantBody: "created"},
+		{name: "slow handler times out", timeout: deadline, path: "/api/v1/users", sleep: 10 * deadline, wantStatus: http.StatusServiceUnavailable, wantCanceled: true},
+		{name: "disabled", timeout: 0, path: "/api/v1/users", sleep: 2 * deadline, wantStatus: http.StatusCreated, wantBody: "created"},
+		{name: "exempt prefix", timeout: deadline, exempt: []string{"/api/v1/users/export"}, path: "/api/v1/users/export", sleep: 2 * deadline, wantStatus: http.StatusCreated, wantBody: "created"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			canceled := make(chan bool, 1)
+			handler := Timeout(tt.timeout, tt.exempt...)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				select {
+				case <-time.After(tt.sleep):
+					canceled <- false
+				case <-r.Context().Done():
+					// A database call made with this context would be canceled here
+					canceled <- true
+				}
+				w.Header().Set("X-Handler", "done")
+				w.WriteHeader(http.StatusCreated)
+				w.Write([]byte("created"))
+			}))
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if got := <-canceled; got != tt.wantCanceled {
+				t.Errorf("handler context canceled = %v, want %v", got, tt.wantCanceled)
+			}
+
+			if tt.wantStatus == http.StatusServiceUnavailable {
+				var resp response.Response
+				if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+					t.Fatalf("invalid response body %q: %v", rec.Body.String(), err)
+				}
+				if resp.Success || resp.Error == nil {
+					t.Errorf("response = %+v, want an error", resp)
+				}
+				if rec.Header().Get("X-Handler") != "" {
+					t.Error("headers written after the deadline leaked into the response")
+				}
+				return
+			}
+			if rec.Body.String() != tt.wantBody || rec.Header().Get("X-Handler") != "done" {
+				t.Errorf("response = %q with X-Handler %q, want the handler's response", rec.Body.String(), rec.Header().Get("X-Handler"))
+			}
+		})
+	}
+}
+
+func TestTimeoutCopiesPattern(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/users/{id}", func(w http.ResponseWriter, r *http.Request) {})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/users/42", nil)
+	Timeout(time.Second)(mux).ServeHTTP(httptest.NewRecorder(), req)
+
+	if req.Pattern != "GET /api/v1/users/{id}" {
+		t.Errorf("Pattern = %q, want the matched route", req.Pattern)
+	}
+}
+
+func TestTimeoutPropagatesPanics(t *testing.T) {
+	handler := Timeout(time.Second)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}))
+
+	defer func() {
+		if p := recover(); p != "boom" {
+			t.Errorf("recovered %v, want the handler's panic", p)
+		}
+	}()
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+}
+
+func TestTimeoutClientGone(t *testing.T) {
+	handler := Timeout(time.Second)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx))
+
+	if rec.Body.Len() != 0 {
+		t.Errorf("wrote %q for a request whose client went away, want nothing", rec.Body.String())
+	}
+}
@@ -0,0 +1,17 @@
+// internal/shared/middleware/version.go
+package middleware
+
+import (
+	"net/http"
+
+	"go-template/internal/shared/response"
+)
+
+// EnvelopeVersion returns a middleware that applies the response envelope version requested
+// in the Accept header to the JSON responses of the wrapped handler.
+func EnvelopeVersion(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept")
+		next.ServeHTTP(response.WithVersion(w, response.Negotiate(r)), r)
+	})
+}
@@ -0,0 +1,44 @@
+// internal/shared/middleware/version_test.go
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go-template/internal/shared/response"
+)
+
+func TestEnvelopeVersion(t *testing.T) {
+	tests := []struct {
+		name            string
+		accept          string
+		wantContentType string
+	}{
+		{name: "default", wantContentType: "application/json; charset=utf-8"},
+		{name: "v1 requested", accept: "application/vnd.goapi.v1+json", wantContentType: "application/json; charset=utf-8"},
+		{name: "unknown version", accept: "application/vnd.goapi.v7+json", wantContentType: "application/json; charset=utf-8"},
+	}
+
+	handler := EnvelopeVersion(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		response.JSON(w, map[string]string{"id": "1"}, http.StatusOK)
+	}))
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/api/v1/users", nil)
+			if tt.accept != "" {
+				r.Header.Set("Accept", tt.accept)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, r)
+
+			if got := rec.Header().Get("Content-Type"); got != tt.wantContentType {
+				t.Errorf("Content-Type = %q, want %q", got, tt.wantContentType)
+			}
+			if got := rec.Header().Get("Vary"); got != "Accept" {
+				t.Errorf("Vary = %q, want Accept so caches keep versions apart", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,190 @@
+// internal/shared/request/query.go
+package request
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DateFormat is the date-only form accepted for time.Time parameters, read as midnight UTC
+const DateFormat = "2006-01-02"
+
+// FieldError describes why a single query parameter was rejected
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// FieldErrors collects every rejected query parameter of a request
+type FieldErrors []FieldError
+
+// Error lists every rejected parameter with its reason
+func (e FieldErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, fieldErr := range e {
+		messages[i] = fmt.Sprintf("invalid %s parameter (%s)", fieldErr.Field, fieldErr.Message)
+	}
+	return strings.Join(messages, "; ")
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// BindQuery fills the struct dst points to from query values
+// Fields are bound by their `query:"name"` tag; untagged fields and `query:"-"` are left alone.
+// Missing or blank parameters take the `default:"..."` tag value if there is one, otherwise
+// the field is not touched. Supported types are string, int, bool, time.Time (RFC 3339 or
+// YYYY-MM-DD), pointers to those, and []string from a comma-separated list. Integers may be
+// bounded with `min:"n"` and `max:"n"`.
+//
+// Invalid values are reported together as FieldErrors. Any other error means dst or one of
+// its tags is unsupported, which is a programming error.
+func BindQuery(values url.Values, dst interface{}) error {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Pointer || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("request: BindQuery needs a pointer to a struct, got %T", dst)
+	}
+	v = v.Elem()
+	t := v.Type()
+
+	var fieldErrs FieldErrors
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := field.Tag.Get("query")
+		if name == "" || name == "-" {
+			continue
+		}
+
+		raw := strings.TrimSpace(values.Get(name))
+		if raw == "" {
+			raw = field.Tag.Get("default")
+			if raw == "" {
+				continue
+			}
+		}
+
+		message, err := setValue(v.Field(i), raw)
+		if err != nil {
+			return fmt.Errorf("request: binding %s: %w", field.Name, err)
+		}
+		if message == "" {
+			message, err = checkBounds(field, v.Field(i))
+			if err != nil {
+				return fmt.Errorf("request: binding %s: %w", field.Name, err)
+			}
+		}
+		if message != "" {
+			fieldErrs = append(fieldErrs, FieldError{Field: name, Message: message})
+		}
+	}
+
+	if len(fieldErrs) > 0 {
+		return fieldErrs
+	}
+	return nil
+}
+
+// setValue parses raw into field
+// It returns a message for the client when raw is invalid, or an error when the type is unsupported.
+func setValue(field reflect.Value, raw string) (string, error) {
+	if field.Kind() == reflect.Pointer {
+		elem := reflect.New(field.Type().Elem())
+		message, err := setValue(elem.Elem(), raw)
+		if message == "" && err == nil {
+			field.Set(elem)
+		}
+		return message, err
+	}
+
+	if field.Type() == timeType {
+		parsed, err := parseTime(raw)
+		if err != nil {
+			return "expected RFC 3339 or YYYY-MM-DD", nil
+		}
+		field.Set(reflect.ValueOf(parsed))
+		return "", nil
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(raw)
+	case reflect.Int:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return "must be an integer", nil
+		}
+		field.SetInt(int64(n))
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return "must be true or false", nil
+		}
+		field.SetBool(b)
+	case reflect.Slice:
+		if field.Type().Elem().Kind() != reflect.String {
+			return "", fmt.Errorf("unsupported slice type %s", field.Type())
+		}
+		var items []string
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		field.Set(reflect.ValueOf(items).Convert(field.Type()))
+	default:
+		return "", fmt.Errorf("unsupported type %s", field.Type())
+	}
+	return "", nil
+}
+
+// checkBounds applies the min and max tags of an int field
+func checkBounds(field reflect.StructField, value reflect.Value) (string, error) {
+	if value.Kind() == reflect.Pointer {
+		if value.IsNil() {
+			return "", nil
+		}
+		value = value.Elem()
+	}
+
+	minTag, hasMin := field.Tag.Lookup("min")
+	maxTag, hasMax := field.Tag.Lookup("max")
+	if !hasMin && !hasMax {
+		return "", nil
+	}
+	if value.Kind() != reflect.Int {
+		return "", errors.New("min and max only apply to int fields")
+	}
+
+	n := value.Int()
+	if hasMin {
+		bound, err := strconv.ParseInt(minTag, 10, 64)
+		if err != nil {
+			return "", fmt.Errorf("invalid min tag %q", minTag)
+		}
+		if n < bound {
+			return fmt.Sprintf("must be at least %d", bound), nil
+		}
+	}
+	if hasMax {
+		bound, err := strconv.ParseInt(maxTag, 10, 64)
+		if err != nil {
+			return "", fmt.Errorf("invalid max tag %q", maxTag)
+		}
+		if n > bound {
+			return fmt.Sprintf("must be at most %d", bound), nil
+		}
+	}
+	return "", nil
+}
+
+// parseTime parses a timestamp given either as RFC 3339 or as a YYYY-MM-DD date (midnight UTC)
+func parseTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.UTC(), nil
+	}
+	return time.Parse(DateFormat, value)
+}
@@ -0,0 +1,213 @@
+// internal/shared/request/query_test.go
+package request
+
+import (
+	"errors"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type sampleQuery struct {
+	Page     int        `query:"page" default:"1" min:"1"`
+	Limit    int        `query:"limit" default:"20" min:"1" max:"100"`
+	Search   string     `query:"search"`
+	Sort     string     `query:"sort" default:"created_at"`
+	Active   *bool      `query:"active"`
+	Archived bool       `query:"archived"`
+	Offset   *int       `query:"offset" min:"0"`
+	Tags     []string   `query:"tags"`
+	Since    *time.Time `query:"since"`
+	Skipped  string     `query:"-"`
+	Internal string
+}
+
+func TestBindQuery(t *testing.T) {
+	boolPtr := func(b bool) *bool { return &b }
+	intPtr := func(n int) *int { return &n }
+	timePtr := func(tm time.Time) *time.Time { return &tm }
+
+	tests := []struct {
+		name       string
+		query      string
+		want       sampleQuery
+		wantFields []string
+		wantMsgs   []string
+	}{
+		{
+			name:  "defaults",
+			query: "",
+			want:  sampleQuery{Page: 1, Limit: 20, Sort: "created_at"},
+		},
+		{
+			name:  "every type",
+			query: "page=3&limit=50&search=ada&sort=username&active=false&archived=true&offset=0&tags=admin,%20staff,,&since=2026-01-02",
+			want: sampleQuery{
+				Page: 3, Limit: 50, Search: "ada", Sort: "username",
+				Active: boolPtr(false), Archived: true, Offset: intPtr(0),
+				Tags: []string{"admin", "staff"}, Since: timePtr(time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)),
+			},
+		},
+		{
+			name:  "RFC 3339 times are converted to UTC",
+			query: "since=2026-01-02T09:00:00%2B02:00",
+			want:  sampleQuery{Page: 1, Limit: 20, Sort: "created_at", Since: timePtr(time.Date(2026, 1, 2, 7, 0, 0, 0, time.UTC))},
+		},
+		{
+			name:  "blank values take the default",
+			query: "page=%20&sort=",
+			want:  sampleQuery{Page: 1, Limit: 20, Sort: "created_at"},
+		},
+		{
+			name:  "values are trimmed",
+			query: "search=%20ada%20&limit=%2010%20",
+			want:  sampleQuery{Page: 1, Limit: 10, Search: "ada", Sort: "created_at"},
+		},
+		{
+			name:  "bounds are inclusive",
+			query: "page=1&limit=100",
+			want:  sampleQuery{Page: 1, Limit: 100, Sort: "created_at"},
+		},
+		{
+			name:  "untagged and skipped fields are ignored",
+			query: "Internal=x&-=y&Skipped=z",
+			want:  sampleQuery{Page: 1, Limit: 20, Sort: "created_at"},
+		},
+		{
+			name:       "not an integer",
+			query:      "page=two",
+			wantFields: []string{"page"},
+			wantMsgs:   []string{"must be an integer"},
+		},
+		{
+			name:       "below the minimum",
+			query:      "page=0",
+			wantFields: []string{"page"},
+			wantMsgs:   []string{"must be at least 1"},
+		},
+		{
+			name:       "above the maximum",
+			query:      "limit=101",
+			wantFields: []string{"limit"},
+			wantMsgs:   []string{"must be at most 100"},
+		},
+		{
+			name:       "pointer bounds",
+			query:      "offset=-1",
+			wantFields: []string{"offset"},
+			wantMsgs:   []string{"must be at least 0"},
+		},
+		{
+			name:       "not a bool",
+			query:      "active=maybe",
+			wantFields: []string{"active"},
+			wantMsgs:   []string{"must be true or false"},
+		},
+		{
+			name:       "not a time",
+			query:      "since=yesterday",
+			wantFields: []string{"since"},
+			wantMsgs:   []string{"expected RFC 3339 or YYYY-MM-DD"},
+		},
+		{
+			name:       "every invalid parameter is reported",
+			query:      "page=0&limit=x&archived=nope",
+			wantFields: []string{"page", "limit", "archived"},
+			wantMsgs:   []string{"must be at least 1", "must be an integer", "must be true or false"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values, err := url.ParseQuery(tt.query)
+			if err != nil {
+				t.Fatalf("ParseQuery() error = %v", err)
+			}
+
+			var got sampleQuery
+			err = BindQuery(values, &got)
+			if tt.wantFields == nil {
+				if err != nil {
+					t.Fatalf("BindQuery() error = %v", err)
+				}
+				if !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("BindQuery() = %+v, want %+v", got, tt.want)
+				}
+				return
+			}
+
+			var fieldErrs FieldErrors
+			if !errors.As(err, &fieldErrs) {
+				t.Fatalf("BindQuery() error = %v, want FieldErrors", err)
+			}
+			if len(fieldErrs) != len(tt.wantFields) {
+				t.Fatalf("BindQuery() errors = %v, want fields %v", fieldErrs, tt.wantFields)
+			}
+			for i, fieldErr := range fieldErrs {
+				if fieldErr.Field != tt.wantFields[i] || fieldErr.Message != tt.wantMsgs[i] {
+					t.Errorf("error %d = %+v, want %s: %s", i, fieldErr, tt.wantFields[i], tt.wantMsgs[i])
+				}
+			}
+		})
+	}
+}
+
+func TestBindQueryLeavesInvalidPointersNil(t *testing.T) {
+	var got sampleQuery
+	if err := BindQuery(url.Values{"active": {"maybe"}, "offset": {"x"}}, &got); err == nil {
+		t.Fatal("BindQuery() error = nil, want FieldErrors")
+	}
+	if got.Active != nil || got.Offset != nil {
+		t.Errorf("Active = %v, Offset = %v, want both nil", got.Active, got.Offset)
+	}
+}
+
+func TestBindQueryUnsupported(t *testing.T) {
+	tests := []struct {
+		name    string
+		dst     interface{}
+		wantErr string
+	}{
+		{name: "not a pointer", dst: sampleQuery{}, wantErr: "needs a pointer to a struct"},
+		{name: "pointer to a non-struct", dst: new(int), wantErr: "needs a pointer to a struct"},
+		{name: "unsupported type", dst: &struct {
+			Ratio float64 `query:"ratio"`
+		}{}, wantErr: "unsupported type float64"},
+		{name: "unsupported slice", dst: &struct {
+			IDs []int `query:"ids"`
+		}{}, wantErr: "unsupported slice type []int"},
+		{name: "bounds on a string", dst: &struct {
+			Name string `query:"name" min:"1"`
+		}{}, wantErr: "min and max only apply to int fields"},
+		{name: "invalid min tag", dst: &struct {
+			N int `query:"n" min:"one"`
+		}{}, wantErr: `invalid min tag "one"`},
+		{name: "invalid max tag", dst: &struct {
+			N int `query:"n" max:"ten"`
+		}{}, wantErr: `invalid max tag "ten"`},
+	}
+
+	values := url.Values{"ratio": {"0.5"}, "ids": {"1,2"}, "name": {"x"}, "n": {"3"}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := BindQuery(values, tt.dst)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("BindQuery() error = %v, want it to mention %q", err, tt.wantErr)
+			}
+			var fieldErrs FieldErrors
+			if errors.As(err, &fieldErrs) {
+				t.Errorf("BindQuery() error = %v, want a programming error rather than FieldErrors", err)
+			}
+		})
+	}
+}
+
+func TestFieldErrorsError(t *testing.T) {
+	err := FieldErrors{{Field: "page", Message: "must be at least 1"}, {Field: "limit", Message: "must be an integer"}}
+	want := "invalid page parameter (must be at least 1); invalid limit parameter (must be an integer)"
+	if got := err.Error(); got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+}
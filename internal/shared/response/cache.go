@@ -0,0 +1,38 @@
+package response
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// SetPublicCache allows browsers and shared caches such as CDNs to reuse the response for maxAge
+func SetPublicCache(w http.ResponseWriter, maxAge time.Duration) {
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(maxAge.Seconds())))
+}
+
+// SetNoStore forbids any cache from keeping the response
+func SetNoStore(w http.ResponseWriter) {
+	w.Header().Set("Cache-Control", "no-store")
+}
+
+// SetLastModified sets the Last-Modified header
+// HTTP dates have second precision, so t is truncated to match what clients send back.
+func SetLastModified(w http.ResponseWriter, t time.Time) {
+	w.Header().Set("Last-Modified", t.UTC().Truncate(time.Second).Format(http.TimeFormat))
+}
+
+// NotModifiedSince reports whether the request's If-Modified-Since header is at or after lastModified
+// Unparseable dates are ignored, as RFC 9110 requires.
+func NotModifiedSince(r *http.Request, lastModified time.Time) bool {
+	header := r.Header.Get("If-Modified-Since")
+	if header == "" || lastModified.IsZero() {
+		return false
+	}
+
+	since, err := http.ParseTime(header)
+	if err != nil {
+		return false
+	}
+	return !lastModified.Truncate(time.Second).After(since)
+}
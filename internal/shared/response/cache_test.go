@@ -0,0 +1,71 @@
+// internal/shared/response/cache_test.go
+package response
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCacheControl(t *testing.T) {
+	tests := []struct {
+		name string
+		set  func(w http.ResponseWriter)
+		want string
+	}{
+		{name: "public", set: func(w http.ResponseWriter) { SetPublicCache(w, time.Minute) }, want: "public, max-age=60"},
+		{name: "public without reuse", set: func(w http.ResponseWriter) { SetPublicCache(w, 0) }, want: "public, max-age=0"},
+		{name: "fractional seconds are dropped", set: func(w http.ResponseWriter) { SetPublicCache(w, 1500*time.Millisecond) }, want: "public, max-age=1"},
+		{name: "no store", set: SetNoStore, want: "no-store"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			tt.set(rec)
+			if got := rec.Header().Get("Cache-Control"); got != tt.want {
+				t.Errorf("Cache-Control = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetLastModified(t *testing.T) {
+	rec := httptest.NewRecorder()
+	SetLastModified(rec, time.Date(2026, 3, 1, 14, 30, 15, 999_000_000, time.FixedZone("CET", 3600)))
+
+	if got, want := rec.Header().Get("Last-Modified"), "Sun, 01 Mar 2026 13:30:15 GMT"; got != want {
+		t.Errorf("Last-Modified = %q, want %q", got, want)
+	}
+}
+
+func TestNotModifiedSince(t *testing.T) {
+	lastModified := time.Date(2026, 3, 1, 13, 30, 15, 500_000_000, time.UTC)
+
+	tests := []struct {
+		name            string
+		ifModifiedSince string
+		lastModified    time.Time
+		want            bool
+	}{
+		{name: "no header", lastModified: lastModified},
+		{name: "same second", ifModifiedSince: "Sun, 01 Mar 2026 13:30:15 GMT", lastModified: lastModified, want: true},
+		{name: "later", ifModifiedSince: "Sun, 01 Mar 2026 14:00:00 GMT", lastModified: lastModified, want: true},
+		{name: "earlier", ifModifiedSince: "Sun, 01 Mar 2026 13:30:14 GMT", lastModified: lastModified},
+		{name: "unparseable date", ifModifiedSince: "yesterday", lastModified: lastModified},
+		{name: "unknown modification time", ifModifiedSince: "Sun, 01 Mar 2026 13:30:15 GMT"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.ifModifiedSince != "" {
+				r.Header.Set("If-Modified-Since", tt.ifModifiedSince)
+			}
+			if got := NotModifiedSince(r, tt.lastModified); got != tt.want {
+				t.Errorf("NotModifiedSince() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
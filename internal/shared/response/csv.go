@@ -0,0 +1,58 @@
+package response
+
+import (
+	"encoding/csv"
+	"log"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// Supported response formats
+const (
+	FormatJSON = "json"
+	FormatCSV  = "csv"
+)
+
+// NegotiateFormat determines the response format requested by the client
+// The ?format= query parameter takes precedence over the Accept header; unknown formats fall back to JSON
+func NegotiateFormat(r *http.Request) string {
+	if format := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("format"))); format != "" {
+		if format == FormatCSV {
+			return FormatCSV
+		}
+		return FormatJSON
+	}
+
+	for _, accept := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(accept))
+		if err != nil {
+			continue
+		}
+		switch mediaType {
+		case "text/csv":
+			return FormatCSV
+		case "application/json", "*/*":
+			return FormatJSON
+		}
+	}
+
+	return FormatJSON
+}
+
+// CSV sends a CSV response with a header line followed by one line per row
+// Fields containing commas, quotes or newlines are quoted and escaped
+func CSV(w http.ResponseWriter, headers []string, rows [][]string, statusCode int) {
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(statusCode)
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write(headers); err != nil {
+		log.Printf("Error encoding CSV response: %v", err)
+		return
+	}
+	if err := writer.WriteAll(rows); err != nil {
+		log.Printf("Error encoding CSV response: %v", err)
+	}
+}
@@ -0,0 +1,89 @@
+// internal/shared/response/csv_test.go
+package response
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNegotiateFormat(t *testing.T) {
+	tests := []struct {
+		name   string
+		target string
+		accept string
+		want   string
+	}{
+		{name: "default", target: "/users", want: FormatJSON},
+		{name: "format query", target: "/users?format=csv", want: FormatCSV},
+		{name: "format query is case-insensitive", target: "/users?format=CSV", want: FormatCSV},
+		{name: "unknown format falls back to JSON", target: "/users?format=xml", want: FormatJSON},
+		{name: "query wins over Accept", target: "/users?format=json", accept: "text/csv", want: FormatJSON},
+		{name: "Accept csv", target: "/users", accept: "text/csv", want: FormatCSV},
+		{name: "Accept csv with parameters", target: "/users", accept: "text/csv; charset=utf-8", want: FormatCSV},
+		{name: "first supported Accept entry wins", target: "/users", accept: "application/xml, application/json, text/csv", want: FormatJSON},
+		{name: "unknown Accept", target: "/users", accept: "application/xml", want: FormatJSON},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			if tt.accept != "" {
+				r.Header.Set("Accept", tt.accept)
+			}
+			if got := NegotiateFormat(r); got != tt.want {
+				t.Errorf("NegotiateFormat() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCSV(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers []string
+		rows    [][]string
+		want    string
+	}{
+		{
+			name:    "plain values",
+			headers: []string{"id", "username"},
+			rows:    [][]string{{"1", "alice"}, {"2", "bob"}},
+			want:    "id,username\n1,alice\n2,bob\n",
+		},
+		{
+			name:    "commas and quotes are escaped",
+			headers: []string{"id", "bio"},
+			rows:    [][]string{{"1", "Go, Mongo"}, {"2", `says "hi"`}},
+			want:    "id,bio\n1,\"Go, Mongo\"\n2,\"says \"\"hi\"\"\"\n",
+		},
+		{
+			name:    "newlines are quoted",
+			headers: []string{"id", "bio"},
+			rows:    [][]string{{"1", "line one\nline two"}},
+			want:    "id,bio\n1,\"line one\nline two\"\n",
+		},
+		{
+			name:    "header only",
+			headers: []string{"id"},
+			want:    "id\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			CSV(rec, tt.headers, tt.rows, http.StatusOK)
+
+			if rec.Code != http.StatusOK {
+				t.Errorf("status = %d, want %d", rec.Code, http.StatusOK)
+			}
+			if got := rec.Header().Get("Content-Type"); got != "text/csv; charset=utf-8" {
+				t.Errorf("Content-Type = %q, want text/csv", got)
+			}
+			if got := rec.Body.String(); got != tt.want {
+				t.Errorf("body = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
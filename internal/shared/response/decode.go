@@ -0,0 +1,90 @@
+package response
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// DefaultMaxBodyBytes is the request body limit used by handlers without special needs
+const DefaultMaxBodyBytes int64 = 1 << 20 // 1MB
+
+// DecodeJSON strictly decodes a JSON request body of at most maxBytes into dst.
+// Unknown fields, trailing data after the JSON value and oversized bodies are rejected.
+// On failure a 400 response describing the problem has already been written, so the
+// caller only needs to return.
+func DecodeJSON(w http.ResponseWriter, r *http.Request, dst interface{}, maxBytes int64) error {
+	r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
+	return writeDecodeError(w, decodeStrict(r.Body, dst))
+}
+
+// DecodeJSONBytes strictly decodes an already-read JSON body into dst, writing a 400
+// response on failure like DecodeJSON
+func DecodeJSONBytes(w http.ResponseWriter, data []byte, dst interface{}) error {
+	return writeDecodeError(w, decodeStrict(bytes.NewReader(data), dst))
+}
+
+// decodeStrict decodes exactly one JSON value from body into dst, disallowing unknown fields
+func decodeStrict(body io.Reader, dst interface{}) error {
+	dec := json.NewDecoder(body)
+	dec.DisallowUnknownFields()
+
+	if err := dec.Decode(dst); err != nil {
+		return err
+	}
+
+	// Anything but EOF after the first value is trailing garbage
+	if err := dec.Decode(&struct{}{}); !errors.Is(err, io.EOF) {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return err
+		}
+		return errTrailingData
+	}
+	return nil
+}
+
+var errTrailingData = errors.New("trailing data after JSON value")
+
+// writeDecodeError writes a 400 response describing err, if any, and returns it
+func writeDecodeError(w http.ResponseWriter, err error) error {
+	if err != nil {
+		ErrorWithCode(w, "INVALID_BODY", decodeErrorMessage(err), http.StatusBadRequest)
+	}
+	return err
+}
+
+// decodeErrorMessage turns a JSON decoding error into a message safe to show to clients
+func decodeErrorMessage(err error) string {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	var maxBytesErr *http.MaxBytesError
+
+	switch {
+	case errors.As(err, &maxBytesErr):
+		return fmt.Sprintf("Request body must not be larger than %d bytes", maxBytesErr.Limit)
+	case errors.Is(err, io.EOF):
+		return "Request body must not be empty"
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return "Request body contains malformed JSON"
+	case errors.As(err, &syntaxErr):
+		return fmt.Sprintf("Request body contains malformed JSON (at position %d)", syntaxErr.Offset)
+	case errors.As(err, &typeErr):
+		if typeErr.Field != "" {
+			return fmt.Sprintf("Request body field %q must be of type %s", typeErr.Field, typeErr.Type)
+		}
+		return fmt.Sprintf("Request body must be a JSON %s", typeErr.Type)
+	case errors.Is(err, errTrailingData):
+		return "Request body must contain a single JSON value"
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		// encoding/json has no typed error for unknown fields
+		field := strings.TrimPrefix(err.Error(), "json: unknown field ")
+		return fmt.Sprintf("Request body contains unknown field %s", field)
+	default:
+		return "Invalid request body format"
+	}
+}
@@ -0,0 +1,100 @@
+// internal/shared/response/decode_test.go
+package response
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// decodeTarget is a small request body used by the decode tests
+type decodeTarget struct {
+	Name string `json:"name"`
+	Age  int    `json:"age"`
+}
+
+func TestDecodeJSON(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		maxBytes    int64
+		wantErr     bool
+		wantMessage string
+		want        decodeTarget
+	}{
+		{name: "valid body", body: `{"name":"alice","age":30}`, maxBytes: DefaultMaxBodyBytes, want: decodeTarget{Name: "alice", Age: 30}},
+		{name: "trailing whitespace", body: "{\"name\":\"alice\"}\n  ", maxBytes: DefaultMaxBodyBytes, want: decodeTarget{Name: "alice"}},
+		{name: "body at the limit", body: `{"name":"abc"}`, maxBytes: 14, want: decodeTarget{Name: "abc"}},
+		{name: "oversized body", body: `{"name":"` + strings.Repeat("a", 64) + `"}`, maxBytes: 32, wantErr: true, wantMessage: "Request body must not be larger than 32 bytes"},
+		{name: "unknown field", body: `{"name":"alice","nmae":"typo"}`, maxBytes: DefaultMaxBodyBytes, wantErr: true, wantMessage: `Request body contains unknown field "nmae"`},
+		{name: "trailing garbage", body: `{"name":"alice"} garbage`, maxBytes: DefaultMaxBodyBytes, wantErr: true, wantMessage: "Request body must contain a single JSON value"},
+		{name: "second JSON value", body: `{"name":"alice"}{"name":"bob"}`, maxBytes: DefaultMaxBodyBytes, wantErr: true, wantMessage: "Request body must contain a single JSON value"},
+		{name: "empty body", body: "", maxBytes: DefaultMaxBodyBytes, wantErr: true, wantMessage: "Request body must not be empty"},
+		{name: "truncated JSON", body: `{"name":"alice"`, maxBytes: DefaultMaxBodyBytes, wantErr: true, wantMessage: "Request body contains malformed JSON"},
+		{name: "syntax error", body: `{"name":alice}`, maxBytes: DefaultMaxBodyBytes, wantErr: true, wantMessage: "Request body contains malformed JSON (at position 9)"},
+		{name: "wrong field type", body: `{"age":"thirty"}`, maxBytes: DefaultMaxBodyBytes, wantErr: true, wantMessage: `Request body field "age" must be of type int`},
+		{name: "wrong body type", body: `["alice"]`, maxBytes: DefaultMaxBodyBytes, wantErr: true, wantMessage: "Request body must be a JSON response.decodeTarget"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+
+			var got decodeTarget
+			err := DecodeJSON(rec, req, &got, tt.maxBytes)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DecodeJSON() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if !tt.wantErr {
+				if got != tt.want {
+					t.Errorf("decoded %+v, want %+v", got, tt.want)
+				}
+				if rec.Body.Len() != 0 {
+					t.Errorf("DecodeJSON() wrote a response on success: %s", rec.Body.String())
+				}
+				return
+			}
+
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+			}
+			var resp Response
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("invalid response body: %v", err)
+			}
+			if resp.Error == nil || resp.Error.Code != "INVALID_BODY" || resp.Error.Message != tt.wantMessage {
+				t.Errorf("error = %+v, want INVALID_BODY %q", resp.Error, tt.wantMessage)
+			}
+		})
+	}
+}
+
+func TestDecodeJSONBytes(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		wantErr bool
+	}{
+		{name: "valid body", body: `{"name":"alice"}`},
+		{name: "unknown field", body: `{"role":"admin"}`, wantErr: true},
+		{name: "trailing garbage", body: `{"name":"alice"}x`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			var got decodeTarget
+			err := DecodeJSONBytes(rec, []byte(tt.body), &got)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DecodeJSONBytes() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr && rec.Code != http.StatusBadRequest {
+				t.Errorf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+			}
+		})
+	}
+}
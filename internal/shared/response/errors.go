@@ -0,0 +1,110 @@
+package response
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"unicode"
+	"unicode/utf8"
+
+	"go-template/internal/interfaces"
+)
+
+// StatusClientClosedRequest is the non-standard status recorded when the client went away
+// before a response could be written, as popularised by nginx
+const StatusClientClosedRequest = 499
+
+// serviceErrorStatuses maps the shared sentinel errors to the status reported for them
+var serviceErrorStatuses = []struct {
+	err    error
+	status int
+}{
+	{interfaces.ErrNotFound, http.StatusNotFound},
+	{interfaces.ErrAlreadyExists, http.StatusConflict},
+	{interfaces.ErrValidation, http.StatusBadRequest},
+	{interfaces.ErrInvalidState, http.StatusBadRequest},
+}
+
+// IsContextError reports whether err comes from a canceled request or an expired deadline
+// rather than from a fault in the server
+func IsContextError(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// HandleError sends the response for an error a handler has no more specific mapping for
+// When the client has disconnected nothing is worth sending, so only the 499 status is
+// recorded for access logs and metrics. Expired deadlines get a 504, anything else a 500.
+func HandleError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(r.Context().Err(), context.Canceled) {
+		err = context.Canceled
+	}
+	if !writeContextError(w, err) {
+		InternalServerError(w)
+	}
+}
+
+// ServiceErrorStatus returns the status HandleServiceError reports for err
+func ServiceErrorStatus(err error) int {
+	status, _ := classifyServiceError(err)
+	return status
+}
+
+// HandleServiceError sends the response for an error returned by a service
+// Errors wrapping one of the interfaces sentinel errors are reported with its status and
+// the message of the error that wrapped it, e.g. 404 "User not found"; failures of the
+// caller's context are handled like HandleError, and anything else is a 500.
+func HandleServiceError(w http.ResponseWriter, err error) {
+	status, sentinel := classifyServiceError(err)
+	if sentinel != nil {
+		ErrorWithCode(w, ErrorCodeForStatus(status), serviceErrorMessage(err, sentinel), status)
+		return
+	}
+	if !writeContextError(w, err) {
+		InternalServerError(w)
+	}
+}
+
+// classifyServiceError returns the status for err and the sentinel error that decided it, if any
+func classifyServiceError(err error) (int, error) {
+	for _, mapping := range serviceErrorStatuses {
+		if errors.Is(err, mapping.err) {
+			return mapping.status, mapping.err
+		}
+	}
+	switch {
+	case errors.Is(err, context.Canceled):
+		return StatusClientClosedRequest, nil
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout, nil
+	}
+	return http.StatusInternalServerError, nil
+}
+
+// writeContextError responds to a canceled request or expired deadline, reporting whether err was one
+func writeContextError(w http.ResponseWriter, err error) bool {
+	switch {
+	case errors.Is(err, context.Canceled):
+		w.WriteHeader(StatusClientClosedRequest)
+	case errors.Is(err, context.DeadlineExceeded):
+		ErrorWithCode(w, ErrorCodeForStatus(http.StatusGatewayTimeout),
+			"The request took too long to complete", http.StatusGatewayTimeout)
+	default:
+		return false
+	}
+	return true
+}
+
+// serviceErrorMessage returns the message of the error in err's chain that wraps sentinel,
+// dropping the "failed to ..." context added further up, with its first letter capitalised
+func serviceErrorMessage(err, sentinel error) string {
+	message := sentinel.Error()
+	for e := err; e != nil; e = errors.Unwrap(e) {
+		if errors.Unwrap(e) == sentinel || e == sentinel {
+			message = e.Error()
+			break
+		}
+	}
+
+	first, size := utf8.DecodeRuneInString(message)
+	return string(unicode.ToUpper(first)) + message[size:]
+}
@@ -0,0 +1,157 @@
+// internal/shared/response/errors_test.go
+package response
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go-template/internal/interfaces"
+)
+
+func TestHandleServiceError(t *testing.T) {
+	tests := []struct {
+		name        string
+		err         error
+		wantStatus  int
+		wantCode    string
+		wantMessage string
+	}{
+		{name: "not found", err: fmt.Errorf("failed to get user: %w", fmt.Errorf("user %w", interfaces.ErrNotFound)), wantStatus: http.StatusNotFound, wantCode: "NOT_FOUND", wantMessage: "User " + interfaces.ErrNotFound.Error()},
+		{name: "already exists", err: fmt.Errorf("username 'alice' %w", interfaces.ErrAlreadyExists), wantStatus: http.StatusConflict, wantCode: "CONFLICT"},
+		{name: "validation", err: fmt.Errorf("%w: bad input", interfaces.ErrValidation), wantStatus: http.StatusBadRequest, wantCode: "BAD_REQUEST"},
+		{name: "invalid state", err: fmt.Errorf("%w: user is already active", interfaces.ErrInvalidState), wantStatus: http.StatusBadRequest, wantCode: "BAD_REQUEST"},
+		{name: "deadline", err: fmt.Errorf("query: %w", context.DeadlineExceeded), wantStatus: http.StatusGatewayTimeout, wantCode: "GATEWAY_TIMEOUT"},
+		{name: "unexpected", err: errors.New("mongo exploded"), wantStatus: http.StatusInternalServerError, wantCode: "INTERNAL_SERVER_ERROR", wantMessage: "An internal server error occurred"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			HandleServiceError(rec, tt.err)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if got := ServiceErrorStatus(tt.err); got != tt.wantStatus {
+				t.Errorf("ServiceErrorStatus() = %d, want %d", got, tt.wantStatus)
+			}
+			var resp Response
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			if resp.Error == nil || resp.Error.Code != tt.wantCode {
+				t.Fatalf("error = %+v, want code %s", resp.Error, tt.wantCode)
+			}
+			if tt.wantMessage != "" && resp.Error.Message != tt.wantMessage {
+				t.Errorf("message = %q, want %q", resp.Error.Message, tt.wantMessage)
+			}
+		})
+	}
+}
+
+func TestHandleServiceErrorClientGone(t *testing.T) {
+	rec := httptest.NewRecorder()
+	HandleServiceError(rec, fmt.Errorf("query: %w", context.Canceled))
+
+	if rec.Code != StatusClientClosedRequest {
+		t.Errorf("status = %d, want %d", rec.Code, StatusClientClosedRequest)
+	}
+	if rec.Body.Len() != 0 {
+		t.Errorf("body = %q, want nothing written for a client that went away", rec.Body.String())
+	}
+}
+
+func TestHandleError(t *testing.T) {
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name       string
+		ctx        context.Context
+		err        error
+		wantStatus int
+		wantCode   string // empty when nothing should be written
+	}{
+		{name: "canceled", ctx: context.Background(), err: fmt.Errorf("query: %w", context.Canceled), wantStatus: StatusClientClosedRequest},
+		{name: "deadline", ctx: context.Background(), err: fmt.Errorf("query: %w", context.DeadlineExceeded), wantStatus: http.StatusGatewayTimeout, wantCode: "GATEWAY_TIMEOUT"},
+		{name: "unexpected", ctx: context.Background(), err: errors.New("mongo exploded"), wantStatus: http.StatusInternalServerError, wantCode: "INTERNAL_SERVER_ERROR"},
+		// Drivers often report a dropped client as their own connection error
+		{name: "client gone with an unrelated error", ctx: canceled, err: errors.New("connection closed"), wantStatus: StatusClientClosedRequest},
+		{name: "sentinel errors are not mapped", ctx: context.Background(), err: fmt.Errorf("user %w", interfaces.ErrNotFound), wantStatus: http.StatusInternalServerError, wantCode: "INTERNAL_SERVER_ERROR"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/api/v1/users", nil).WithContext(tt.ctx)
+			rec := httptest.NewRecorder()
+			HandleError(rec, r, tt.err)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if tt.wantCode == "" {
+				if rec.Body.Len() != 0 {
+					t.Errorf("body = %q, want nothing written for a client that went away", rec.Body.String())
+				}
+				return
+			}
+			var resp Response
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			if resp.Error == nil || resp.Error.Code != tt.wantCode {
+				t.Errorf("error = %+v, want code %s", resp.Error, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestIsContextError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "canceled", err: context.Canceled, want: true},
+		{name: "wrapped deadline", err: fmt.Errorf("find users: %w", context.DeadlineExceeded), want: true},
+		{name: "other error", err: errors.New("mongo exploded")},
+		{name: "nil", err: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsContextError(tt.err); got != tt.want {
+				t.Errorf("IsContextError(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestServiceErrorMessage(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		sentinel error
+		want     string
+	}{
+		{name: "bare sentinel", err: interfaces.ErrNotFound, sentinel: interfaces.ErrNotFound, want: "Not found"},
+		{name: "entity prefix", err: fmt.Errorf("user %w", interfaces.ErrNotFound), sentinel: interfaces.ErrNotFound, want: "User not found"},
+		{name: "context added further up is dropped", err: fmt.Errorf("failed to get user: %w", fmt.Errorf("user %w", interfaces.ErrNotFound)), sentinel: interfaces.ErrNotFound, want: "User not found"},
+		{name: "detail after the sentinel", err: fmt.Errorf("%w: avatar file is empty", interfaces.ErrValidation), sentinel: interfaces.ErrValidation, want: "Validation failed: avatar file is empty"},
+		{name: "quoted value", err: fmt.Errorf("email 'a@example.com' %w", interfaces.ErrAlreadyExists), sentinel: interfaces.ErrAlreadyExists, want: "Email 'a@example.com' already exists"},
+		{name: "non-ASCII first letter", err: fmt.Errorf("élan %w", interfaces.ErrNotFound), sentinel: interfaces.ErrNotFound, want: "Élan not found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := serviceErrorMessage(tt.err, tt.sentinel); got != tt.want {
+				t.Errorf("serviceErrorMessage() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
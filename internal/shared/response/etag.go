@@ -0,0 +1,55 @@
+package response
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// ETagFor computes a strong ETag from the JSON representation of data
+// Returns an empty string if data cannot be marshaled
+func ETagFor(data interface{}) string {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return ""
+	}
+
+	hash := sha256.Sum256(payload)
+	return `"` + hex.EncodeToString(hash[:16]) + `"`
+}
+
+// SetETag sets the ETag header on the response
+// Unquoted tags are quoted as required by RFC 9110
+func SetETag(w http.ResponseWriter, tag string) {
+	if !strings.HasPrefix(tag, `"`) && !strings.HasPrefix(tag, `W/"`) {
+		tag = `"` + tag + `"`
+	}
+	w.Header().Set("ETag", tag)
+}
+
+// ETagMatches reports whether the request's If-None-Match header matches the given ETag
+func ETagMatches(r *http.Request, tag string) bool {
+	header := r.Header.Get("If-None-Match")
+	if header == "" || tag == "" {
+		return false
+	}
+
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+		// If-None-Match uses weak comparison, so ignore any W/ prefix
+		if strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(tag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+// NotModified sends a 304 Not Modified response with no body
+func NotModified(w http.ResponseWriter) {
+	w.WriteHeader(http.StatusNotModified)
+}
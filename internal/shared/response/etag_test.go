@@ -0,0 +1,93 @@
+// internal/shared/response/etag_test.go
+package response
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestETagFor(t *testing.T) {
+	a := ETagFor(map[string]string{"id": "1", "updated_at": "2026-01-01T00:00:00Z"})
+	b := ETagFor(map[string]string{"id": "1", "updated_at": "2026-01-01T00:00:00Z"})
+	c := ETagFor(map[string]string{"id": "1", "updated_at": "2026-01-02T00:00:00Z"})
+
+	if a == "" || a[0] != '"' || a[len(a)-1] != '"' {
+		t.Fatalf("ETagFor() = %q, want a quoted strong tag", a)
+	}
+	if a != b {
+		t.Errorf("ETagFor() not stable: %q != %q", a, b)
+	}
+	if a == c {
+		t.Errorf("ETagFor() = %q for different payloads", a)
+	}
+	if got := ETagFor(func() {}); got != "" {
+		t.Errorf("ETagFor(unmarshalable) = %q, want empty", got)
+	}
+}
+
+func TestSetETag(t *testing.T) {
+	tests := []struct {
+		tag  string
+		want string
+	}{
+		{tag: "abc", want: `"abc"`},
+		{tag: `"abc"`, want: `"abc"`},
+		{tag: `W/"abc"`, want: `W/"abc"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.tag, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			SetETag(rec, tt.tag)
+			if got := rec.Header().Get("ETag"); got != tt.want {
+				t.Errorf("ETag = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestETagMatches(t *testing.T) {
+	tests := []struct {
+		name        string
+		ifNoneMatch string
+		tag         string
+		want        bool
+	}{
+		{name: "no header", tag: `"abc"`},
+		{name: "exact match", ifNoneMatch: `"abc"`, tag: `"abc"`, want: true},
+		{name: "different tag", ifNoneMatch: `"xyz"`, tag: `"abc"`},
+		{name: "one of several", ifNoneMatch: `"xyz", "abc"`, tag: `"abc"`, want: true},
+		{name: "weak comparison", ifNoneMatch: `W/"abc"`, tag: `"abc"`, want: true},
+		{name: "wildcard", ifNoneMatch: "*", tag: `"abc"`, want: true},
+		{name: "no tag", ifNoneMatch: "*"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.ifNoneMatch != "" {
+				r.Header.Set("If-None-Match", tt.ifNoneMatch)
+			}
+			if got := ETagMatches(r, tt.tag); got != tt.want {
+				t.Errorf("ETagMatches(%q, %q) = %v, want %v", tt.ifNoneMatch, tt.tag, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNotModified(t *testing.T) {
+	rec := httptest.NewRecorder()
+	SetETag(rec, "abc")
+	NotModified(rec)
+
+	if rec.Code != http.StatusNotModified {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusNotModified)
+	}
+	if rec.Body.Len() != 0 {
+		t.Errorf("body = %q, want empty", rec.Body.String())
+	}
+	if rec.Header().Get("ETag") != `"abc"` {
+		t.Errorf("ETag = %q, want it kept", rec.Header().Get("ETag"))
+	}
+}
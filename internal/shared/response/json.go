@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"strings"
 	"time"
 )
 
@@ -31,6 +32,8 @@ type Meta struct {
 	Limit      int `json:"limit,omitempty"`
 	Total      int `json:"total,omitempty"`
 	TotalPages int `json:"total_pages,omitempty"`
+	HasNext    bool `json:"has_next"`
+	HasPrev    bool `json:"has_prev"`
 }
 
 // ValidationError represents field validation errors
@@ -75,36 +78,30 @@ func JSONWithMeta(w http.ResponseWriter, data interface{}, meta *Meta, statusCod
 	sendJSONResponse(w, response, statusCode)
 }
 
-// Error sends an error JSON response
-func Error(w http.ResponseWriter, message string, statusCode int) {
-	response := Response{
-		Success: false,
-		Error: &ErrorInfo{
-			Code:    http.StatusText(statusCode),
-			Message: message,
-		},
-		Timestamp: time.Now().UTC().Format(time.RFC3339),
-	}
+// Paginated sends a successful JSON response with the items as data and computed pagination metadata
+func Paginated(w http.ResponseWriter, items interface{}, page, limit, total int, statusCode int) {
+	JSONWithMeta(w, items, NewMeta(page, limit, total), statusCode)
+}
 
-	sendJSONResponse(w, response, statusCode)
+// Error sends an error JSON response with the stable error code for statusCode
+func Error(w http.ResponseWriter, message string, statusCode int) {
+	ErrorWithCodeAndStatus(w, ErrorCodeForStatus(statusCode), message, nil, statusCode)
 }
 
 // ErrorWithCode sends an error JSON response with a custom error code
 func ErrorWithCode(w http.ResponseWriter, code, message string, statusCode int) {
-	response := Response{
-		Success: false,
-		Error: &ErrorInfo{
-			Code:    code,
-			Message: message,
-		},
-		Timestamp: time.Now().UTC().Format(time.RFC3339),
-	}
-
-	sendJSONResponse(w, response, statusCode)
+	ErrorWithCodeAndStatus(w, code, message, nil, statusCode)
 }
 
 // ErrorWithDetails sends an error JSON response with additional details
 func ErrorWithDetails(w http.ResponseWriter, code, message string, details interface{}, statusCode int) {
+	ErrorWithCodeAndStatus(w, code, message, details, statusCode)
+}
+
+// ErrorWithCodeAndStatus sends an error JSON response; every error helper goes through it
+// code is a stable, machine-readable identifier such as ErrorCodeNotFound, while message
+// is meant for humans and may change. details is omitted when nil.
+func ErrorWithCodeAndStatus(w http.ResponseWriter, code, message string, details interface{}, statusCode int) {
 	response := Response{
 		Success: false,
 		Error: &ErrorInfo{
@@ -120,22 +117,12 @@ func ErrorWithDetails(w http.ResponseWriter, code, message string, details inter
 
 // ValidationError sends a validation error response
 func ValidationErrors(w http.ResponseWriter, errors []ValidationError) {
-	response := Response{
-		Success: false,
-		Error: &ErrorInfo{
-			Code:    "VALIDATION_ERROR",
-			Message: "Validation failed",
-			Details: errors,
-		},
-		Timestamp: time.Now().UTC().Format(time.RFC3339),
-	}
-
-	sendJSONResponse(w, response, http.StatusBadRequest)
+	ErrorWithCodeAndStatus(w, ErrorCodeValidation, "Validation failed", errors, http.StatusBadRequest)
 }
 
 // InternalServerError sends a generic internal server error
 func InternalServerError(w http.ResponseWriter) {
-	Error(w, "An internal server error occurred", http.StatusInternalServerError)
+	ErrorWithCode(w, ErrorCodeInternalServer, "An internal server error occurred", http.StatusInternalServerError)
 }
 
 // NotFound sends a not found error
@@ -144,7 +131,7 @@ func NotFound(w http.ResponseWriter, resource string) {
 	if resource != "" {
 		message = fmt.Sprintf("%s not found", resource)
 	}
-	Error(w, message, http.StatusNotFound)
+	ErrorWithCode(w, ErrorCodeNotFound, message, http.StatusNotFound)
 }
 
 // Unauthorized sends an unauthorized error
@@ -152,7 +139,7 @@ func Unauthorized(w http.ResponseWriter, message string) {
 	if message == "" {
 		message = "Authentication required"
 	}
-	Error(w, message, http.StatusUnauthorized)
+	ErrorWithCode(w, ErrorCodeUnauthorized, message, http.StatusUnauthorized)
 }
 
 // Forbidden sends a forbidden error
@@ -160,7 +147,7 @@ func Forbidden(w http.ResponseWriter, message string) {
 	if message == "" {
 		message = "Access forbidden"
 	}
-	Error(w, message, http.StatusForbidden)
+	ErrorWithCode(w, ErrorCodeForbidden, message, http.StatusForbidden)
 }
 
 // BadRequest sends a bad request error
@@ -168,18 +155,29 @@ func BadRequest(w http.ResponseWriter, message string) {
 	if message == "" {
 		message = "Bad request"
 	}
-	Error(w, message, http.StatusBadRequest)
+	ErrorWithCode(w, ErrorCodeBadRequest, message, http.StatusBadRequest)
 }
 
 // TooManyRequests sends a rate limit exceeded error
 func TooManyRequests(w http.ResponseWriter) {
-	Error(w, "Rate limit exceeded", http.StatusTooManyRequests)
+	ErrorWithCode(w, ErrorCodeRateLimit, "Rate limit exceeded", http.StatusTooManyRequests)
+}
+
+// prettyJSON controls whether responses are indented; set it through Configure
+var prettyJSON = true
+
+// Configure sets how responses are encoded: indented when pretty is true, compact otherwise
+// Call it once during startup, before requests are served.
+func Configure(pretty bool) {
+	prettyJSON = pretty
 }
 
 // sendJSONResponse is a helper function that actually sends the JSON response
 func sendJSONResponse(w http.ResponseWriter, response Response, statusCode int) {
+	body, contentType := envelopeFor(w, response)
+	
 	// Set response headers
-	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Content-Type", contentType)
 	w.Header().Set("X-Content-Type-Options", "nosniff")
 	
 	// Set status code
@@ -187,9 +185,11 @@ func sendJSONResponse(w http.ResponseWriter, response Response, statusCode int)
 
 	// Encode and send response
 	encoder := json.NewEncoder(w)
-	encoder.SetIndent("", "  ") // Pretty print in development
+	if prettyJSON {
+		encoder.SetIndent("", "  ")
+	}
 	
-	if err := encoder.Encode(response); err != nil {
+	if err := encoder.Encode(body); err != nil {
 		// If JSON encoding fails, send a basic error response
 		log.Printf("Failed to encode JSON response: %v", err)
 		
@@ -201,13 +201,18 @@ func sendJSONResponse(w http.ResponseWriter, response Response, statusCode int)
 
 // NewMeta creates a new Meta struct for pagination
 func NewMeta(page, limit, total int) *Meta {
-	totalPages := (total + limit - 1) / limit // Ceiling division
+	totalPages := 0
+	if limit > 0 && total > 0 {
+		totalPages = (total + limit - 1) / limit // Ceiling division
+	}
 	
 	return &Meta{
 		Page:       page,
 		Limit:      limit,
 		Total:      total,
 		TotalPages: totalPages,
+		HasNext:    page < totalPages,
+		HasPrev:    page > 1,
 	}
 }
 
@@ -231,8 +236,39 @@ const (
 	ErrorCodeBadRequest      = "BAD_REQUEST"
 	ErrorCodeConflict        = "CONFLICT"
 	ErrorCodeUnsupportedType = "UNSUPPORTED_TYPE"
+	ErrorCodeAccountLocked   = "ACCOUNT_LOCKED"
+	ErrorCodeIdempotencyMismatch = "IDEMPOTENCY_KEY_MISMATCH"
+	ErrorCodeIdempotencyInProgress = "IDEMPOTENCY_KEY_IN_PROGRESS"
+	ErrorCodeServiceUnavailable  = "SERVICE_UNAVAILABLE"
 )
 
+// statusErrorCodes maps HTTP statuses to the error codes Error uses for them
+var statusErrorCodes = map[int]string{
+	http.StatusBadRequest:           ErrorCodeBadRequest,
+	http.StatusUnauthorized:         ErrorCodeUnauthorized,
+	http.StatusForbidden:            ErrorCodeForbidden,
+	http.StatusNotFound:             ErrorCodeNotFound,
+	http.StatusConflict:             ErrorCodeConflict,
+	http.StatusUnsupportedMediaType: ErrorCodeUnsupportedType,
+	http.StatusTooManyRequests:      ErrorCodeRateLimit,
+	http.StatusInternalServerError:  ErrorCodeInternalServer,
+	http.StatusServiceUnavailable:   ErrorCodeServiceUnavailable,
+}
+
+// ErrorCodeForStatus returns the stable error code for an HTTP status
+// Statuses without a dedicated constant get their status text in upper snake case,
+// e.g. 413 becomes REQUEST_ENTITY_TOO_LARGE.
+func ErrorCodeForStatus(statusCode int) string {
+	if code, ok := statusErrorCodes[statusCode]; ok {
+		return code
+	}
+	text := http.StatusText(statusCode)
+	if text == "" {
+		return "ERROR"
+	}
+	return strings.ToUpper(strings.NewReplacer(" ", "_", "-", "_", "'", "").Replace(text))
+}
+
 // Success response helpers
 
 // Created sends a 201 Created response
@@ -243,6 +279,12 @@ func Created(w http.ResponseWriter, data interface{}, message string) {
 	JSONWithMessage(w, data, message, http.StatusCreated)
 }
 
+// CreatedWithLocation sends a 201 Created response with a Location header pointing at the new resource
+func CreatedWithLocation(w http.ResponseWriter, data interface{}, location, message string) {
+	w.Header().Set("Location", location)
+	Created(w, data, message)
+}
+
 // Updated sends a 200 OK response for updates
 func Updated(w http.ResponseWriter, data interface{}, message string) {
 	if message == "" {
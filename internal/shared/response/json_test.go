@@ -0,0 +1,242 @@
+// internal/shared/response/json_test.go
+package response
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNewMeta(t *testing.T) {
+	tests := []struct {
+		name               string
+		page, limit, total int
+		want               Meta
+	}{
+		{name: "first of several pages", page: 1, limit: 10, total: 25, want: Meta{Page: 1, Limit: 10, Total: 25, TotalPages: 3, HasNext: true}},
+		{name: "middle page", page: 2, limit: 10, total: 25, want: Meta{Page: 2, Limit: 10, Total: 25, TotalPages: 3, HasNext: true, HasPrev: true}},
+		{name: "last page", page: 3, limit: 10, total: 25, want: Meta{Page: 3, Limit: 10, Total: 25, TotalPages: 3, HasPrev: true}},
+		{name: "exact multiple", page: 1, limit: 10, total: 20, want: Meta{Page: 1, Limit: 10, Total: 20, TotalPages: 2, HasNext: true}},
+		{name: "single page", page: 1, limit: 10, total: 1, want: Meta{Page: 1, Limit: 10, Total: 1, TotalPages: 1}},
+		{name: "no items", page: 1, limit: 10, total: 0, want: Meta{Page: 1, Limit: 10}},
+		{name: "zero limit", page: 1, limit: 0, total: 25, want: Meta{Page: 1, Total: 25}},
+		{name: "page past the end", page: 5, limit: 10, total: 25, want: Meta{Page: 5, Limit: 10, Total: 25, TotalPages: 3, HasPrev: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewMeta(tt.page, tt.limit, tt.total); *got != tt.want {
+				t.Errorf("NewMeta(%d, %d, %d) = %+v, want %+v", tt.page, tt.limit, tt.total, *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPaginated(t *testing.T) {
+	tests := []struct {
+		name     string
+		items    interface{}
+		status   int
+		wantData interface{}
+		wantMeta Meta
+	}{
+		{
+			name:     "items",
+			items:    []string{"a", "b"},
+			status:   http.StatusOK,
+			wantData: []interface{}{"a", "b"},
+			wantMeta: Meta{Page: 2, Limit: 2, Total: 5, TotalPages: 3, HasNext: true, HasPrev: true},
+		},
+		{
+			name:     "empty page",
+			items:    []string{},
+			status:   http.StatusPartialContent,
+			wantData: []interface{}{},
+			wantMeta: Meta{Page: 2, Limit: 2, Total: 5, TotalPages: 3, HasNext: true, HasPrev: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			Paginated(rec, tt.items, 2, 2, 5, tt.status)
+
+			if rec.Code != tt.status {
+				t.Errorf("status = %d, want %d", rec.Code, tt.status)
+			}
+			if got := rec.Header().Get("Content-Type"); got != "application/json" && got != "application/json; charset=utf-8" {
+				t.Errorf("Content-Type = %q", got)
+			}
+
+			var resp struct {
+				Success bool        `json:"success"`
+				Data    interface{} `json:"data"`
+				Meta    *Meta       `json:"meta"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("invalid body %q: %v", rec.Body.String(), err)
+			}
+			if !resp.Success {
+				t.Error("success = false, want true")
+			}
+			if !reflect.DeepEqual(resp.Data, tt.wantData) {
+				t.Errorf("data = %v, want %v", resp.Data, tt.wantData)
+			}
+			if resp.Meta == nil || *resp.Meta != tt.wantMeta {
+				t.Errorf("meta = %+v, want %+v", resp.Meta, tt.wantMeta)
+			}
+		})
+	}
+}
+
+func TestConfigurePrettyJSON(t *testing.T) {
+	t.Cleanup(func() { Configure(true) })
+
+	tests := []struct {
+		name       string
+		pretty     bool
+		write      func(w http.ResponseWriter)
+		wantIndent bool
+	}{
+		{name: "pretty success", pretty: true, write: func(w http.ResponseWriter) { JSON(w, map[string]int{"a": 1}, http.StatusOK) }, wantIndent: true},
+		{name: "compact success", pretty: false, write: func(w http.ResponseWriter) { JSON(w, map[string]int{"a": 1}, http.StatusOK) }},
+		{name: "pretty error", pretty: true, write: func(w http.ResponseWriter) { BadRequest(w, "bad") }, wantIndent: true},
+		{name: "compact error", pretty: false, write: func(w http.ResponseWriter) { BadRequest(w, "bad") }},
+		{name: "compact paginated", pretty: false, write: func(w http.ResponseWriter) { Paginated(w, []string{"a"}, 1, 10, 1, http.StatusOK) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			Configure(tt.pretty)
+			rec := httptest.NewRecorder()
+			tt.write(rec)
+
+			body := rec.Body.Bytes()
+			if !json.Valid(body) {
+				t.Fatalf("body is not valid JSON: %s", body)
+			}
+			if indented := strings.Contains(string(body), "\n  \""); indented != tt.wantIndent {
+				t.Errorf("indented = %v, want %v: %s", indented, tt.wantIndent, body)
+			}
+
+			// Compact output has no whitespace left for json.Compact to strip
+			var compact bytes.Buffer
+			if err := json.Compact(&compact, body); err != nil {
+				t.Fatalf("json.Compact() error = %v", err)
+			}
+			if !tt.pretty && !bytes.Equal(bytes.TrimSpace(body), compact.Bytes()) {
+				t.Errorf("body = %s, want it compact", body)
+			}
+		})
+	}
+}
+
+func TestCreated(t *testing.T) {
+	tests := []struct {
+		name         string
+		write        func(w http.ResponseWriter)
+		wantLocation string
+	}{
+		{name: "without a location", write: func(w http.ResponseWriter) { Created(w, map[string]string{"id": "42"}, "Created") }},
+		{
+			name: "with a location",
+			write: func(w http.ResponseWriter) {
+				CreatedWithLocation(w, map[string]string{"id": "42"}, "/api/v1/users/42", "Created")
+			},
+			wantLocation: "/api/v1/users/42",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			tt.write(rec)
+
+			if rec.Code != http.StatusCreated {
+				t.Errorf("status = %d, want 201", rec.Code)
+			}
+			if got := rec.Header().Get("Location"); got != tt.wantLocation {
+				t.Errorf("Location = %q, want %q", got, tt.wantLocation)
+			}
+			var resp Response
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			if !resp.Success || resp.Message != "Created" || !reflect.DeepEqual(resp.Data, map[string]interface{}{"id": "42"}) {
+				t.Errorf("response = %+v, want the created entity", resp)
+			}
+		})
+	}
+}
+
+func TestErrorHelperCodes(t *testing.T) {
+	tests := []struct {
+		name        string
+		write       func(w http.ResponseWriter)
+		wantStatus  int
+		wantCode    string
+		wantMessage string
+	}{
+		{name: "NotFound", write: func(w http.ResponseWriter) { NotFound(w, "User") }, wantStatus: http.StatusNotFound, wantCode: "NOT_FOUND", wantMessage: "User not found"},
+		{name: "NotFound without a resource", write: func(w http.ResponseWriter) { NotFound(w, "") }, wantStatus: http.StatusNotFound, wantCode: "NOT_FOUND", wantMessage: "Resource not found"},
+		{name: "Unauthorized", write: func(w http.ResponseWriter) { Unauthorized(w, "") }, wantStatus: http.StatusUnauthorized, wantCode: "UNAUTHORIZED", wantMessage: "Authentication required"},
+		{name: "Forbidden", write: func(w http.ResponseWriter) { Forbidden(w, "Admins only") }, wantStatus: http.StatusForbidden, wantCode: "FORBIDDEN", wantMessage: "Admins only"},
+		{name: "BadRequest", write: func(w http.ResponseWriter) { BadRequest(w, "") }, wantStatus: http.StatusBadRequest, wantCode: "BAD_REQUEST", wantMessage: "Bad request"},
+		{name: "TooManyRequests", write: func(w http.ResponseWriter) { TooManyRequests(w) }, wantStatus: http.StatusTooManyRequests, wantCode: "RATE_LIMIT_EXCEEDED", wantMessage: "Rate limit exceeded"},
+		{name: "InternalServerError", write: func(w http.ResponseWriter) { InternalServerError(w) }, wantStatus: http.StatusInternalServerError, wantCode: "INTERNAL_SERVER_ERROR", wantMessage: "An internal server error occurred"},
+		{name: "ValidationErrors", write: func(w http.ResponseWriter) {
+			ValidationErrors(w, []ValidationError{NewValidationError("email", "invalid", "")})
+		}, wantStatus: http.StatusBadRequest, wantCode: "VALIDATION_ERROR", wantMessage: "Validation failed"},
+		{name: "Error with a mapped status", write: func(w http.ResponseWriter) { Error(w, "Taken", http.StatusConflict) }, wantStatus: http.StatusConflict, wantCode: "CONFLICT", wantMessage: "Taken"},
+		{name: "Error with an unmapped status", write: func(w http.ResponseWriter) { Error(w, "Too big", http.StatusRequestEntityTooLarge) }, wantStatus: http.StatusRequestEntityTooLarge, wantCode: "REQUEST_ENTITY_TOO_LARGE", wantMessage: "Too big"},
+		{name: "ErrorWithCode", write: func(w http.ResponseWriter) { ErrorWithCode(w, ErrorCodeAccountLocked, "Locked", http.StatusForbidden) }, wantStatus: http.StatusForbidden, wantCode: "ACCOUNT_LOCKED", wantMessage: "Locked"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			tt.write(rec)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			var resp Response
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			if resp.Success || resp.Error == nil {
+				t.Fatalf("response = %+v, want an error", resp)
+			}
+			if resp.Error.Code != tt.wantCode || resp.Error.Message != tt.wantMessage {
+				t.Errorf("error = %q %q, want %q %q", resp.Error.Code, resp.Error.Message, tt.wantCode, tt.wantMessage)
+			}
+		})
+	}
+}
+
+func TestErrorCodeForStatus(t *testing.T) {
+	tests := []struct {
+		status int
+		want   string
+	}{
+		{status: http.StatusBadRequest, want: ErrorCodeBadRequest},
+		{status: http.StatusServiceUnavailable, want: ErrorCodeServiceUnavailable},
+		{status: http.StatusUnsupportedMediaType, want: ErrorCodeUnsupportedType},
+		{status: http.StatusGatewayTimeout, want: "GATEWAY_TIMEOUT"},
+		{status: http.StatusTeapot, want: "IM_A_TEAPOT"},
+		{status: http.StatusNonAuthoritativeInfo, want: "NON_AUTHORITATIVE_INFORMATION"},
+		{status: 599, want: "ERROR"},
+	}
+
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.status), func(t *testing.T) {
+			if got := ErrorCodeForStatus(tt.status); got != tt.want {
+				t.Errorf("ErrorCodeForStatus(%d) = %q, want %q", tt.status, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package response
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// SetPaginationLinks sets an RFC 8288 (formerly RFC 5988) Link header pointing to the
+// first, last, previous and next pages of a paginated list. URLs are absolute and keep
+// every query parameter of the request except page and limit, which are replaced.
+// prev and next are omitted on the first and last page respectively.
+//
+// The scheme and host honour X-Forwarded-Proto and X-Forwarded-Host, so the server is
+// expected to run behind a proxy that sets or strips them.
+func SetPaginationLinks(w http.ResponseWriter, r *http.Request, page, limit, total int) {
+	if limit < 1 {
+		return
+	}
+
+	lastPage := (total + limit - 1) / limit
+	if lastPage < 1 {
+		lastPage = 1
+	}
+
+	base := requestURL(r)
+	link := func(target int, rel string) string {
+		query := base.Query()
+		query.Set("page", strconv.Itoa(target))
+		query.Set("limit", strconv.Itoa(limit))
+
+		pageURL := *base
+		pageURL.RawQuery = query.Encode()
+		return fmt.Sprintf(`<%s>; rel="%s"`, pageURL.String(), rel)
+	}
+
+	links := []string{link(1, "first")}
+	if page > 1 {
+		// Pages past the end point back to the last page that has items
+		prev := page - 1
+		if prev > lastPage {
+			prev = lastPage
+		}
+		links = append(links, link(prev, "prev"))
+	}
+	if page < lastPage {
+		links = append(links, link(page+1, "next"))
+	}
+	links = append(links, link(lastPage, "last"))
+
+	w.Header().Set("Link", strings.Join(links, ", "))
+}
+
+// requestURL reconstructs the absolute URL the client used for r
+func requestURL(r *http.Request) *url.URL {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if proto := r.Header.Get("X-Forwarded-Proto"); proto == "http" || proto == "https" {
+		scheme = proto
+	}
+
+	host := r.Host
+	if forwardedHost := r.Header.Get("X-Forwarded-Host"); forwardedHost != "" {
+		host, _, _ = strings.Cut(forwardedHost, ",")
+		host = strings.TrimSpace(host)
+	}
+
+	return &url.URL{
+		Scheme:   scheme,
+		Host:     host,
+		Path:     r.URL.Path,
+		RawQuery: r.URL.RawQuery,
+	}
+}
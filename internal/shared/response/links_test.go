@@ -0,0 +1,119 @@
+// internal/shared/response/links_test.go
+package response
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSetPaginationLinks(t *testing.T) {
+	tests := []struct {
+		name               string
+		target             string
+		header             map[string]string
+		tls                bool
+		page, limit, total int
+		want               string
+	}{
+		{
+			name:   "first page",
+			target: "/api/v1/users?page=1&limit=10",
+			page:   1, limit: 10, total: 35,
+			want: `<http://example.com/api/v1/users?limit=10&page=1>; rel="first", ` +
+				`<http://example.com/api/v1/users?limit=10&page=2>; rel="next", ` +
+				`<http://example.com/api/v1/users?limit=10&page=4>; rel="last"`,
+		},
+		{
+			name:   "middle page",
+			target: "/api/v1/users?page=2&limit=10",
+			page:   2, limit: 10, total: 35,
+			want: `<http://example.com/api/v1/users?limit=10&page=1>; rel="first", ` +
+				`<http://example.com/api/v1/users?limit=10&page=1>; rel="prev", ` +
+				`<http://example.com/api/v1/users?limit=10&page=3>; rel="next", ` +
+				`<http://example.com/api/v1/users?limit=10&page=4>; rel="last"`,
+		},
+		{
+			name:   "last page",
+			target: "/api/v1/users?page=4&limit=10",
+			page:   4, limit: 10, total: 35,
+			want: `<http://example.com/api/v1/users?limit=10&page=1>; rel="first", ` +
+				`<http://example.com/api/v1/users?limit=10&page=3>; rel="prev", ` +
+				`<http://example.com/api/v1/users?limit=10&page=4>; rel="last"`,
+		},
+		{
+			name:   "only page",
+			target: "/api/v1/users",
+			page:   1, limit: 10, total: 3,
+			want: `<http://example.com/api/v1/users?limit=10&page=1>; rel="first", ` +
+				`<http://example.com/api/v1/users?limit=10&page=1>; rel="last"`,
+		},
+		{
+			name:   "no results",
+			target: "/api/v1/users",
+			page:   1, limit: 10, total: 0,
+			want: `<http://example.com/api/v1/users?limit=10&page=1>; rel="first", ` +
+				`<http://example.com/api/v1/users?limit=10&page=1>; rel="last"`,
+		},
+		{
+			name:   "page past the end points back to the last page",
+			target: "/api/v1/users?page=9",
+			page:   9, limit: 10, total: 35,
+			want: `<http://example.com/api/v1/users?limit=10&page=1>; rel="first", ` +
+				`<http://example.com/api/v1/users?limit=10&page=4>; rel="prev", ` +
+				`<http://example.com/api/v1/users?limit=10&page=4>; rel="last"`,
+		},
+		{
+			name:   "other query parameters are kept",
+			target: "/api/v1/users?search=a%26b&role=admin&page=1&limit=5",
+			page:   1, limit: 5, total: 6,
+			want: `<http://example.com/api/v1/users?limit=5&page=1&role=admin&search=a%26b>; rel="first", ` +
+				`<http://example.com/api/v1/users?limit=5&page=2&role=admin&search=a%26b>; rel="next", ` +
+				`<http://example.com/api/v1/users?limit=5&page=2&role=admin&search=a%26b>; rel="last"`,
+		},
+		{
+			name:   "tls",
+			target: "/api/v1/users",
+			tls:    true,
+			page:   1, limit: 10, total: 1,
+			want: `<https://example.com/api/v1/users?limit=10&page=1>; rel="first", ` +
+				`<https://example.com/api/v1/users?limit=10&page=1>; rel="last"`,
+		},
+		{
+			name:   "forwarded by a proxy",
+			target: "/api/v1/users",
+			header: map[string]string{"X-Forwarded-Proto": "https", "X-Forwarded-Host": "api.example.org, internal:8080"},
+			page:   1, limit: 10, total: 1,
+			want: `<https://api.example.org/api/v1/users?limit=10&page=1>; rel="first", ` +
+				`<https://api.example.org/api/v1/users?limit=10&page=1>; rel="last"`,
+		},
+		{
+			name:   "unknown forwarded scheme is ignored",
+			target: "/api/v1/users",
+			header: map[string]string{"X-Forwarded-Proto": "javascript"},
+			page:   1, limit: 10, total: 1,
+			want: `<http://example.com/api/v1/users?limit=10&page=1>; rel="first", ` +
+				`<http://example.com/api/v1/users?limit=10&page=1>; rel="last"`,
+		},
+		{name: "zero limit sets no header", target: "/api/v1/users", page: 1, limit: 0, total: 10},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			if tt.tls {
+				r.TLS = &tls.ConnectionState{}
+			}
+			for name, value := range tt.header {
+				r.Header.Set(name, value)
+			}
+			w := httptest.NewRecorder()
+
+			SetPaginationLinks(w, r, tt.page, tt.limit, tt.total)
+			if got := w.Header().Get("Link"); got != tt.want {
+				t.Errorf("Link =\n  %s\nwant\n  %s", got, tt.want)
+			}
+		})
+	}
+}
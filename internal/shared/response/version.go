@@ -0,0 +1,103 @@
+package response
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Envelope versions clients can request with Accept: application/vnd.goapi.v<N>+json
+const (
+	EnvelopeV1 = 1 // The Response shape
+
+	DefaultEnvelopeVersion = EnvelopeV1
+
+	vendorMediaTypePrefix = "application/vnd.goapi.v"
+	vendorMediaTypeSuffix = "+json"
+)
+
+// EnvelopeFunc converts the standard Response into the body sent for an envelope version
+type EnvelopeFunc func(Response) interface{}
+
+// envelopes holds the envelope versions that can be negotiated; v1 sends Response as is
+var envelopes = map[int]EnvelopeFunc{
+	EnvelopeV1: func(response Response) interface{} { return response },
+}
+
+// RegisterEnvelope makes version negotiable, encoding its responses with envelope
+// This is the hook for introducing a new response shape without breaking existing clients,
+// which keep getting v1 unless they ask for the new version. Call it during startup,
+// before requests are served; v1 cannot be replaced.
+func RegisterEnvelope(version int, envelope EnvelopeFunc) {
+	if version == EnvelopeV1 || version < 1 || envelope == nil {
+		panic(fmt.Sprintf("response: cannot register envelope version %d", version))
+	}
+	envelopes[version] = envelope
+}
+
+// Negotiate returns the envelope version requested by the Accept header of r
+// The first vendor media type naming a registered version wins. Anything else, including
+// unknown versions and plain application/json, gets DefaultEnvelopeVersion.
+func Negotiate(r *http.Request) int {
+	for _, header := range r.Header.Values("Accept") {
+		for _, mediaRange := range strings.Split(header, ",") {
+			mediaType, _, _ := strings.Cut(mediaRange, ";")
+			mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+
+			if !strings.HasPrefix(mediaType, vendorMediaTypePrefix) || !strings.HasSuffix(mediaType, vendorMediaTypeSuffix) {
+				continue
+			}
+			digits := strings.TrimSuffix(strings.TrimPrefix(mediaType, vendorMediaTypePrefix), vendorMediaTypeSuffix)
+			version, err := strconv.Atoi(digits)
+			if err != nil {
+				continue
+			}
+			if _, ok := envelopes[version]; ok {
+				return version
+			}
+		}
+	}
+	return DefaultEnvelopeVersion
+}
+
+// versionedWriter carries the negotiated envelope version to the response helpers
+type versionedWriter struct {
+	http.ResponseWriter
+	version int
+}
+
+// Unwrap exposes the underlying ResponseWriter to http.ResponseController
+func (w *versionedWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// WithVersion returns a ResponseWriter whose JSON responses use the given envelope version
+func WithVersion(w http.ResponseWriter, version int) http.ResponseWriter {
+	return &versionedWriter{ResponseWriter: w, version: version}
+}
+
+// envelopeVersion finds the version set with WithVersion on w or a writer it wraps
+func envelopeVersion(w http.ResponseWriter) int {
+	for {
+		switch writer := w.(type) {
+		case *versionedWriter:
+			return writer.version
+		case interface{ Unwrap() http.ResponseWriter }:
+			w = writer.Unwrap()
+		default:
+			return DefaultEnvelopeVersion
+		}
+	}
+}
+
+// envelopeFor returns the body and content type to send response in the version chosen for w
+// v1 keeps the plain application/json content type it has always used.
+func envelopeFor(w http.ResponseWriter, response Response) (interface{}, string) {
+	version := envelopeVersion(w)
+	envelope, ok := envelopes[version]
+	if !ok || version == EnvelopeV1 {
+		return response, "application/json; charset=utf-8"
+	}
+	return envelope(response), fmt.Sprintf("%s%d%s; charset=utf-8", vendorMediaTypePrefix, version, vendorMediaTypeSuffix)
+}
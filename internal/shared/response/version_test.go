@@ -0,0 +1,159 @@
+// internal/shared/response/version_test.go
+package response
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+// registerTestEnvelope registers envelope as version for the duration of the test
+func registerTestEnvelope(t *testing.T, version int, envelope EnvelopeFunc) {
+	t.Helper()
+	RegisterEnvelope(version, envelope)
+	t.Cleanup(func() { delete(envelopes, version) })
+}
+
+// wrappingWriter stands in for middleware that wraps the ResponseWriter after the version is set
+type wrappingWriter struct {
+	http.ResponseWriter
+}
+
+func (w *wrappingWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// v2Envelope is a stand-in future envelope that renames the fields of Response
+func v2Envelope(response Response) interface{} {
+	return map[string]interface{}{"ok": response.Success, "result": response.Data}
+}
+
+func TestNegotiate(t *testing.T) {
+	registerTestEnvelope(t, 2, v2Envelope)
+
+	tests := []struct {
+		name   string
+		accept []string
+		want   int
+	}{
+		{name: "no Accept header", want: EnvelopeV1},
+		{name: "plain json", accept: []string{"application/json"}, want: EnvelopeV1},
+		{name: "any", accept: []string{"*/*"}, want: EnvelopeV1},
+		{name: "v1", accept: []string{"application/vnd.goapi.v1+json"}, want: EnvelopeV1},
+		{name: "registered v2", accept: []string{"application/vnd.goapi.v2+json"}, want: 2},
+		{name: "unknown version falls back", accept: []string{"application/vnd.goapi.v9+json"}, want: EnvelopeV1},
+		{name: "malformed version", accept: []string{"application/vnd.goapi.vtwo+json"}, want: EnvelopeV1},
+		{name: "case and parameters are ignored", accept: []string{"Application/VND.goapi.V2+JSON; q=0.9"}, want: 2},
+		{name: "first known version in a list wins", accept: []string{"application/vnd.goapi.v9+json, application/vnd.goapi.v2+json, application/vnd.goapi.v1+json"}, want: 2},
+		{name: "several Accept headers", accept: []string{"application/json", "application/vnd.goapi.v2+json"}, want: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			for _, accept := range tt.accept {
+				r.Header.Add("Accept", accept)
+			}
+			if got := Negotiate(r); got != tt.want {
+				t.Errorf("Negotiate() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEnvelopeVersions(t *testing.T) {
+	registerTestEnvelope(t, 2, v2Envelope)
+
+	tests := []struct {
+		name            string
+		wrap            func(w http.ResponseWriter) http.ResponseWriter
+		wantContentType string
+		wantKeys        []string
+	}{
+		{
+			name:            "default shape",
+			wrap:            func(w http.ResponseWriter) http.ResponseWriter { return w },
+			wantContentType: "application/json; charset=utf-8",
+			wantKeys:        []string{"data", "success", "timestamp"},
+		},
+		{
+			name:            "explicit v1",
+			wrap:            func(w http.ResponseWriter) http.ResponseWriter { return WithVersion(w, EnvelopeV1) },
+			wantContentType: "application/json; charset=utf-8",
+			wantKeys:        []string{"data", "success", "timestamp"},
+		},
+		{
+			name:            "unregistered version falls back to v1",
+			wrap:            func(w http.ResponseWriter) http.ResponseWriter { return WithVersion(w, 9) },
+			wantContentType: "application/json; charset=utf-8",
+			wantKeys:        []string{"data", "success", "timestamp"},
+		},
+		{
+			name:            "v2 hook",
+			wrap:            func(w http.ResponseWriter) http.ResponseWriter { return WithVersion(w, 2) },
+			wantContentType: "application/vnd.goapi.v2+json; charset=utf-8",
+			wantKeys:        []string{"ok", "result"},
+		},
+		{
+			name: "version found through wrapping writers",
+			wrap: func(w http.ResponseWriter) http.ResponseWriter {
+				return &wrappingWriter{ResponseWriter: WithVersion(w, 2)}
+			},
+			wantContentType: "application/vnd.goapi.v2+json; charset=utf-8",
+			wantKeys:        []string{"ok", "result"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			JSON(tt.wrap(rec), map[string]string{"id": "1"}, http.StatusOK)
+
+			if got := rec.Header().Get("Content-Type"); got != tt.wantContentType {
+				t.Errorf("Content-Type = %q, want %q", got, tt.wantContentType)
+			}
+			var body map[string]interface{}
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatalf("invalid body %q: %v", rec.Body.String(), err)
+			}
+			var keys []string
+			for key := range body {
+				keys = append(keys, key)
+			}
+			slices.Sort(keys)
+			if !reflect.DeepEqual(keys, tt.wantKeys) {
+				t.Errorf("body keys = %v, want %v", keys, tt.wantKeys)
+			}
+		})
+	}
+}
+
+func TestRegisterEnvelopeRejects(t *testing.T) {
+	tests := []struct {
+		name     string
+		version  int
+		envelope EnvelopeFunc
+	}{
+		{name: "replacing v1", version: EnvelopeV1, envelope: v2Envelope},
+		{name: "zero version", version: 0, envelope: v2Envelope},
+		{name: "negative version", version: -2, envelope: v2Envelope},
+		{name: "nil envelope", version: 3, envelope: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("RegisterEnvelope(%d) did not panic", tt.version)
+				}
+				if _, ok := envelopes[3]; ok {
+					t.Error("rejected envelope was registered")
+				}
+			}()
+			RegisterEnvelope(tt.version, tt.envelope)
+		})
+	}
+}
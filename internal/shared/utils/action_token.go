@@ -0,0 +1,130 @@
+// internal/shared/utils/action_token.go
+package utils
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go-template/internal/interfaces"
+)
+
+// ErrInvalidActionToken is returned when an action token is unknown, expired or already used
+var ErrInvalidActionToken = errors.New("invalid or expired token")
+
+// Action token purposes and lifetimes
+const (
+	PurposeEmailVerification  = "email_verification"
+	EmailVerificationTokenTTL = 24 * time.Hour
+
+	PurposePasswordReset  = "password_reset"
+	PasswordResetTokenTTL = 30 * time.Minute
+)
+
+// ActionTokenStore issues single-use tokens that prove control of an account, e.g. of its email
+// Tokens are stored hashed in the cache with a TTL and only the latest token per user is valid.
+type ActionTokenStore struct {
+	cache   interfaces.CacheInterface
+	purpose string
+	ttl     time.Duration
+}
+
+// NewActionTokenStore creates a token store for the given purpose
+func NewActionTokenStore(cache interfaces.CacheInterface, purpose string, ttl time.Duration) *ActionTokenStore {
+	return &ActionTokenStore{
+		cache:   cache,
+		purpose: purpose,
+		ttl:     ttl,
+	}
+}
+
+// NewEmailVerificationTokens creates the token store used for email verification
+func NewEmailVerificationTokens(cache interfaces.CacheInterface) *ActionTokenStore {
+	return NewActionTokenStore(cache, PurposeEmailVerification, EmailVerificationTokenTTL)
+}
+
+// NewPasswordResetTokens creates the token store used for password resets
+func NewPasswordResetTokens(cache interfaces.CacheInterface) *ActionTokenStore {
+	return NewActionTokenStore(cache, PurposePasswordReset, PasswordResetTokenTTL)
+}
+
+// TTL returns how long issued tokens remain valid
+func (s *ActionTokenStore) TTL() time.Duration {
+	return s.ttl
+}
+
+// Issue generates a new token for userID, revoking any token previously issued to that user
+func (s *ActionTokenStore) Issue(ctx context.Context, userID string) (string, error) {
+	tokenBytes := make([]byte, 32)
+	if _, err := rand.Read(tokenBytes); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	token := hex.EncodeToString(tokenBytes)
+
+	if err := s.Revoke(ctx, userID); err != nil {
+		return "", err
+	}
+
+	hash := hashActionToken(token)
+	if err := s.cache.Set(ctx, s.tokenKey(hash), userID, s.ttl); err != nil {
+		return "", fmt.Errorf("failed to store token: %w", err)
+	}
+	if err := s.cache.Set(ctx, s.userKey(userID), hash, s.ttl); err != nil {
+		return "", fmt.Errorf("failed to store token: %w", err)
+	}
+
+	return token, nil
+}
+
+// Consume validates token and deletes it, returning the user it was issued to
+// A token can be consumed at most once.
+func (s *ActionTokenStore) Consume(ctx context.Context, token string) (string, error) {
+	token = strings.TrimSpace(token)
+	if token == "" {
+		return "", ErrInvalidActionToken
+	}
+
+	userID, err := s.cache.GetDel(ctx, s.tokenKey(hashActionToken(token)))
+	if err != nil || userID == "" {
+		return "", ErrInvalidActionToken
+	}
+
+	// Best effort: the user key expires on its own
+	_ = s.cache.Delete(ctx, s.userKey(userID))
+
+	return userID, nil
+}
+
+// Revoke invalidates the outstanding token for userID, if any
+func (s *ActionTokenStore) Revoke(ctx context.Context, userID string) error {
+	hash, err := s.cache.GetDel(ctx, s.userKey(userID))
+	if err != nil || hash == "" {
+		return nil
+	}
+
+	if err := s.cache.Delete(ctx, s.tokenKey(hash)); err != nil {
+		return fmt.Errorf("failed to revoke token: %w", err)
+	}
+	return nil
+}
+
+// tokenKey returns the cache key mapping a token hash to its user
+func (s *ActionTokenStore) tokenKey(hash string) string {
+	return fmt.Sprintf("token:%s:%s", s.purpose, hash)
+}
+
+// userKey returns the cache key mapping a user to their outstanding token hash
+func (s *ActionTokenStore) userKey(userID string) string {
+	return fmt.Sprintf("token:%s:user:%s", s.purpose, userID)
+}
+
+// hashActionToken hashes a token so raw tokens are never stored
+func hashActionToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,70 @@
+// utils/clock.go
+package utils
+
+import (
+	"sync"
+	"time"
+
+	"go-template/internal/interfaces"
+)
+
+// realClock reads the system clock
+type realClock struct{}
+
+// Now returns the current system time in UTC
+func (realClock) Now() time.Time {
+	return time.Now().UTC()
+}
+
+// RealClock returns a Clock backed by the system clock
+func RealClock() interfaces.Clock {
+	return realClock{}
+}
+
+// FixedClock is a Clock that returns a set time until it is changed, for tests
+type FixedClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewFixedClock creates a FixedClock stopped at t
+func NewFixedClock(t time.Time) *FixedClock {
+	return &FixedClock{now: t.UTC()}
+}
+
+// Now returns the clock's current time
+func (c *FixedClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Set moves the clock to t
+func (c *FixedClock) Set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = t.UTC()
+}
+
+// Advance moves the clock forward by d
+func (c *FixedClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+var defaultClock interfaces.Clock = realClock{}
+
+// SetClock replaces the clock used to stamp models and repository writes
+// Call it during startup or test setup, before any requests are served.
+func SetClock(clock interfaces.Clock) {
+	if clock == nil {
+		clock = realClock{}
+	}
+	defaultClock = clock
+}
+
+// Now returns the current time in UTC from the configured clock
+func Now() time.Time {
+	return defaultClock.Now().UTC()
+}
@@ -0,0 +1,50 @@
+// internal/shared/utils/clock_test.go
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFixedClock(t *testing.T) {
+	start := time.Date(2026, 3, 1, 9, 30, 0, 0, time.FixedZone("UTC-5", -5*60*60))
+	clock := NewFixedClock(start)
+
+	tests := []struct {
+		name   string
+		change func()
+		want   time.Time
+	}{
+		{name: "stopped at the start in UTC", change: func() {}, want: time.Date(2026, 3, 1, 14, 30, 0, 0, time.UTC)},
+		{name: "does not move by itself", change: func() { time.Sleep(time.Millisecond) }, want: time.Date(2026, 3, 1, 14, 30, 0, 0, time.UTC)},
+		{name: "advance", change: func() { clock.Advance(90 * time.Minute) }, want: time.Date(2026, 3, 1, 16, 0, 0, 0, time.UTC)},
+		{name: "set", change: func() { clock.Set(time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)) }, want: time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.change()
+			got := clock.Now()
+			if !got.Equal(tt.want) || got.Location() != time.UTC {
+				t.Errorf("Now() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetClock(t *testing.T) {
+	fixed := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	SetClock(NewFixedClock(fixed))
+	t.Cleanup(func() { SetClock(nil) })
+
+	if got := Now(); !got.Equal(fixed) {
+		t.Errorf("Now() with a fixed clock = %v, want %v", got, fixed)
+	}
+
+	SetClock(nil)
+	before := time.Now()
+	got := Now()
+	if got.Before(before.Add(-time.Second)) || got.After(time.Now().Add(time.Second)) || got.Location() != time.UTC {
+		t.Errorf("Now() after SetClock(nil) = %v, want the current UTC time", got)
+	}
+}
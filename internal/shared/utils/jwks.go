@@ -0,0 +1,137 @@
+// internal/shared/utils/jwks.go
+package utils
+
+import (
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+)
+
+// minRSAKeyBits is the smallest RSA key accepted for signing tokens
+const minRSAKeyBits = 2048
+
+// JSONWebKey is the public half of a token signing key in JWK form (RFC 7517)
+type JSONWebKey struct {
+	KeyType   string `json:"kty"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid"`
+	Modulus   string `json:"n"`
+	Exponent  string `json:"e"`
+}
+
+// JSONWebKeySet is the document served at /.well-known/jwks.json
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+// JWKS returns the public keys tokens can be verified with
+// ok is false when tokens are signed with HS256, whose key must stay secret.
+func (ts *TokenService) JWKS() (set JSONWebKeySet, ok bool) {
+	signer, ok := ts.signer.(*rsaSigner)
+	if !ok {
+		return JSONWebKeySet{}, false
+	}
+
+	pub := &signer.key.PublicKey
+	return JSONWebKeySet{Keys: []JSONWebKey{{
+		KeyType:   "RSA",
+		Use:       "sig",
+		Algorithm: AlgorithmRS256,
+		KeyID:     signer.kid,
+		Modulus:   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+		Exponent:  base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+	}}}, true
+}
+
+// LoadRSAKeyPair reads a PEM encoded RSA private key and its public key
+// The private key may be PKCS #1 or PKCS #8 and the public key PKIX or PKCS #1. Keys shorter
+// than 2048 bits, or a public key that does not belong to the private key, are rejected.
+func LoadRSAKeyPair(privateKeyFile, publicKeyFile string) (*rsa.PrivateKey, error) {
+	privateBlock, err := readPEMFile(privateKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	key, err := parseRSAPrivateKey(privateBlock)
+	if err != nil {
+		return nil, fmt.Errorf("invalid private key in %s: %w", privateKeyFile, err)
+	}
+	if key.N.BitLen() < minRSAKeyBits {
+		return nil, fmt.Errorf("private key in %s is %d bits, at least %d are required", privateKeyFile, key.N.BitLen(), minRSAKeyBits)
+	}
+
+	publicBlock, err := readPEMFile(publicKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	pub, err := parseRSAPublicKey(publicBlock)
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key in %s: %w", publicKeyFile, err)
+	}
+	if !key.PublicKey.Equal(pub) {
+		return nil, fmt.Errorf("public key in %s does not match the private key in %s", publicKeyFile, privateKeyFile)
+	}
+
+	return key, nil
+}
+
+// readPEMFile returns the first PEM block of a file
+func readPEMFile(path string) (*pem.Block, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key file: %w", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data found in %s", path)
+	}
+	return block, nil
+}
+
+// parseRSAPrivateKey parses a PKCS #1 or PKCS #8 RSA private key
+func parseRSAPrivateKey(block *pem.Block) (*rsa.PrivateKey, error) {
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("not an RSA key")
+	}
+	return key, nil
+}
+
+// parseRSAPublicKey parses a PKIX or PKCS #1 RSA public key
+func parseRSAPublicKey(block *pem.Block) (*rsa.PublicKey, error) {
+	if pub, err := x509.ParsePKCS1PublicKey(block.Bytes); err == nil {
+		return pub, nil
+	}
+	parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	pub, ok := parsed.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("not an RSA key")
+	}
+	return pub, nil
+}
+
+// rsaKeyID returns the RFC 7638 thumbprint of a public key, used as its kid
+func rsaKeyID(pub *rsa.PublicKey) string {
+	// The members are in lexicographic order with no whitespace, as the RFC requires
+	canonical := fmt.Sprintf(`{"e":"%s","kty":"RSA","n":"%s"}`,
+		base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		base64.RawURLEncoding.EncodeToString(pub.N.Bytes()))
+	sum := sha256.Sum256([]byte(canonical))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
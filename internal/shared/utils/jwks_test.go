@@ -0,0 +1,200 @@
+// internal/shared/utils/jwks_test.go
+package utils
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+var (
+	testRSAKeyOnce sync.Once
+	testRSAKeys    [2]*rsa.PrivateKey
+)
+
+// testRSAKey returns one of two 2048-bit keys shared by the tests in this package
+// Generating RSA keys is slow, so they are created once.
+func testRSAKey(t *testing.T, i int) *rsa.PrivateKey {
+	t.Helper()
+	testRSAKeyOnce.Do(func() {
+		for n := range testRSAKeys {
+			key, err := rsa.GenerateKey(rand.Reader, minRSAKeyBits)
+			if err != nil {
+				panic(err)
+			}
+			testRSAKeys[n] = key
+		}
+	})
+	return testRSAKeys[i]
+}
+
+// writePEM writes a single PEM block to a file in dir and returns its path
+func writePEM(t *testing.T, dir, name, blockType string, der []byte) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	return path
+}
+
+func newTestRSATokenService(key *rsa.PrivateKey) *TokenService {
+	return NewRSATokenService(key, time.Hour, 24*time.Hour, 30*24*time.Hour)
+}
+
+func TestLoadRSAKeyPair(t *testing.T) {
+	key := testRSAKey(t, 0)
+	other := testRSAKey(t, 1)
+
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkix, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	small, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecPrivate, err := x509.MarshalPKCS8PrivateKey(ecKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"pkcs1":       writePEM(t, dir, "pkcs1.pem", "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(key)),
+		"pkcs8":       writePEM(t, dir, "pkcs8.pem", "PRIVATE KEY", pkcs8),
+		"pkix":        writePEM(t, dir, "pkix.pem", "PUBLIC KEY", pkix),
+		"pkcs1-pub":   writePEM(t, dir, "pkcs1-pub.pem", "RSA PUBLIC KEY", x509.MarshalPKCS1PublicKey(&key.PublicKey)),
+		"other-pub":   writePEM(t, dir, "other-pub.pem", "RSA PUBLIC KEY", x509.MarshalPKCS1PublicKey(&other.PublicKey)),
+		"small":       writePEM(t, dir, "small.pem", "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(small)),
+		"small-pub":   writePEM(t, dir, "small-pub.pem", "RSA PUBLIC KEY", x509.MarshalPKCS1PublicKey(&small.PublicKey)),
+		"ec":          writePEM(t, dir, "ec.pem", "PRIVATE KEY", ecPrivate),
+		"garbage-pem": writePEM(t, dir, "garbage.pem", "RSA PRIVATE KEY", []byte("not a key")),
+		"missing":     filepath.Join(dir, "missing.pem"),
+	}
+	notPEM := filepath.Join(dir, "not-pem.txt")
+	if err := os.WriteFile(notPEM, []byte("secret"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	files["not-pem"] = notPEM
+
+	tests := []struct {
+		name    string
+		private string
+		public  string
+		wantErr string
+	}{
+		{name: "PKCS #1 private and PKIX public", private: "pkcs1", public: "pkix"},
+		{name: "PKCS #8 private and PKCS #1 public", private: "pkcs8", public: "pkcs1-pub"},
+		{name: "missing private key", private: "missing", public: "pkix", wantErr: "failed to read key file"},
+		{name: "missing public key", private: "pkcs1", public: "missing", wantErr: "failed to read key file"},
+		{name: "not PEM", private: "not-pem", public: "pkix", wantErr: "no PEM data found"},
+		{name: "malformed private key", private: "garbage-pem", public: "pkix", wantErr: "invalid private key"},
+		{name: "malformed public key", private: "pkcs1", public: "garbage-pem", wantErr: "invalid public key"},
+		{name: "not an RSA key", private: "ec", public: "pkix", wantErr: "not an RSA key"},
+		{name: "key too short", private: "small", public: "small-pub", wantErr: "is 1024 bits, at least 2048 are required"},
+		{name: "public key of another pair", private: "pkcs1", public: "other-pub", wantErr: "does not match the private key"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := LoadRSAKeyPair(files[tt.private], files[tt.public])
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("LoadRSAKeyPair() error = %v, want it to mention %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadRSAKeyPair() error = %v", err)
+			}
+			if !got.Equal(key) {
+				t.Error("LoadRSAKeyPair() returned a different key")
+			}
+		})
+	}
+}
+
+func TestJWKS(t *testing.T) {
+	key := testRSAKey(t, 0)
+
+	tests := []struct {
+		name   string
+		tokens *TokenService
+		wantOK bool
+	}{
+		{name: "HS256 keys stay secret", tokens: NewTokenService("test-secret-test-secret-test-secret", time.Hour, 24*time.Hour, 30*24*time.Hour)},
+		{name: "RS256 publishes the public key", tokens: newTestRSATokenService(key), wantOK: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			set, ok := tt.tokens.JWKS()
+			if ok != tt.wantOK {
+				t.Fatalf("JWKS() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if !ok {
+				if len(set.Keys) != 0 {
+					t.Errorf("JWKS() = %+v, want no keys", set)
+				}
+				return
+			}
+
+			if len(set.Keys) != 1 {
+				t.Fatalf("JWKS() has %d keys, want 1", len(set.Keys))
+			}
+			jwk := set.Keys[0]
+			if jwk.KeyType != "RSA" || jwk.Use != "sig" || jwk.Algorithm != AlgorithmRS256 {
+				t.Errorf("JWKS() key = %+v, want an RSA signing key for RS256", jwk)
+			}
+			n, err := base64.RawURLEncoding.DecodeString(jwk.Modulus)
+			if err != nil || new(big.Int).SetBytes(n).Cmp(key.N) != 0 {
+				t.Errorf("n = %q (%v), want the key modulus", jwk.Modulus, err)
+			}
+			if jwk.Exponent != "AQAB" {
+				t.Errorf("e = %q, want AQAB", jwk.Exponent)
+			}
+
+			// Verifiers pick the key by the kid in the token header
+			token, err := tt.tokens.GenerateAccessToken("user-1", []string{"user"}, 0)
+			if err != nil {
+				t.Fatalf("GenerateAccessToken() error = %v", err)
+			}
+			if header := decodeHeader(t, token); header.KeyID != jwk.KeyID || header.KeyID == "" {
+				t.Errorf("token kid = %q, want the published kid %q", header.KeyID, jwk.KeyID)
+			}
+		})
+	}
+}
+
+func TestRSAKeyIDThumbprint(t *testing.T) {
+	// The example key of RFC 7638 section 3.1
+	n, err := base64.RawURLEncoding.DecodeString("0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECPebWKRXjBZCiFV4n3oknjhMstn64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY368QQMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5hajrn1n91CbOpbISD08qNLyrdkt-bFTWhAI4vMQFh6WeZu0fM4lFd2NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csFCur-kEgU8awapJzKnqDKgw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: 65537}
+
+	if got, want := rsaKeyID(pub), "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs"; got != want {
+		t.Errorf("rsaKeyID() = %q, want %q", got, want)
+	}
+}
@@ -2,43 +2,202 @@
 package utils
 
 import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
 	"errors"
-	"regexp"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
 
+	"golang.org/x/crypto/argon2"
 	"golang.org/x/crypto/bcrypt"
 )
 
 const (
 	// BcryptCost define el costo de bcrypt (10-12 es recomendado para producción)
 	BcryptCost = 12
+
+	// Parámetros por defecto de Argon2id (RFC 9106, perfil de memoria reducida)
+	Argon2Memory  uint32 = 64 * 1024 // en KiB
+	Argon2Time    uint32 = 3
+	Argon2Threads uint8  = 2
+	Argon2SaltLen        = 16
+	Argon2KeyLen  uint32 = 32
+)
+
+// Algoritmos de hash soportados
+const (
+	AlgorithmBcrypt   = "bcrypt"
+	AlgorithmArgon2id = "argon2id"
+)
+
+// Valores por defecto de la política de contraseñas
+const (
+	DefaultPasswordMinLength      = 8
+	DefaultPasswordMinCharClasses = 3
+
+	// MaxPasswordLength es la longitud máxima de una contraseña, independiente de la política
+	MaxPasswordLength = 128
 )
 
+// breachCheckTimeout limita cuánto puede tardar la comprobación de contraseñas filtradas
+const breachCheckTimeout = 3 * time.Second
+
+// argon2idPrefix identifica los hashes codificados con Argon2id
+const argon2idPrefix = "$argon2id$"
+
+// ErrUnsupportedAlgorithm se devuelve cuando se configura un algoritmo desconocido
+var ErrUnsupportedAlgorithm = errors.New("unsupported password hashing algorithm")
+
+// ErrPasswordBreached se devuelve cuando la contraseña aparece en una filtración conocida
+var ErrPasswordBreached = errors.New("password has appeared in a data breach, please choose a different one")
+
+// PasswordPolicy define las reglas de fortaleza de las contraseñas nuevas
+// Las clases de caracteres son mayúsculas, minúsculas, dígitos y caracteres especiales
+// (cualquier carácter que no sea letra ni dígito).
+type PasswordPolicy struct {
+	MinLength      int
+	RequireSpecial bool
+	MinCharClasses int
+}
+
+// DefaultPasswordPolicy devuelve la política por defecto: 8 caracteres y 3 clases distintas
+func DefaultPasswordPolicy() PasswordPolicy {
+	return PasswordPolicy{
+		MinLength:      DefaultPasswordMinLength,
+		MinCharClasses: DefaultPasswordMinCharClasses,
+	}
+}
+
+// BreachedPasswordChecker comprueba si una contraseña aparece en filtraciones conocidas
+// Está pensado para una consulta por k-anonimato (p. ej. Have I Been Pwned), en la que solo
+// se envía un prefijo del hash SHA-1 de la contraseña y nunca la contraseña en sí.
+type BreachedPasswordChecker interface {
+	IsBreached(ctx context.Context, password string) (bool, error)
+}
+
+// argon2Params contiene los parámetros codificados en un hash Argon2id
+type argon2Params struct {
+	memory  uint32
+	time    uint32
+	threads uint8
+	keyLen  uint32
+}
+
 // PasswordService maneja todas las operaciones relacionadas con contraseñas
 type PasswordService struct {
-	cost int
+	algorithm string
+	cost      int
+	argon2    argon2Params
+	pepper    []byte
+	policy    PasswordPolicy
+	breached  BreachedPasswordChecker
 }
 
 // NewPasswordService crea una nueva instancia del servicio de contraseñas
 func NewPasswordService() *PasswordService {
 	return &PasswordService{
-		cost: BcryptCost,
+		algorithm: AlgorithmBcrypt,
+		cost:      BcryptCost,
+		argon2:    defaultArgon2Params(),
+		policy:    DefaultPasswordPolicy(),
+	}
+}
+
+// SetPolicy reemplaza la política de fortaleza usada al validar contraseñas
+func (ps *PasswordService) SetPolicy(policy PasswordPolicy) {
+	ps.policy = policy
+}
+
+// Policy devuelve la política de fortaleza actual
+func (ps *PasswordService) Policy() PasswordPolicy {
+	return ps.policy
+}
+
+// SetBreachedPasswordChecker activa la comprobación de contraseñas filtradas en ValidatePassword
+// Con nil se desactiva.
+func (ps *PasswordService) SetBreachedPasswordChecker(checker BreachedPasswordChecker) {
+	ps.breached = checker
+}
+
+// SetBcryptCost cambia el costo de los nuevos hashes bcrypt
+// Debe estar entre bcrypt.MinCost (4) y bcrypt.MaxCost (31). Los hashes con otro costo
+// se siguen verificando y NeedsRehash los marca para regenerarlos.
+func (ps *PasswordService) SetBcryptCost(cost int) error {
+	if cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+		return fmt.Errorf("bcrypt cost must be between %d and %d, got %d", bcrypt.MinCost, bcrypt.MaxCost, cost)
 	}
+	ps.cost = cost
+	return nil
+}
+
+// BcryptCost devuelve el costo usado para los nuevos hashes bcrypt
+func (ps *PasswordService) BcryptCost() int {
+	return ps.cost
 }
 
 // NewPasswordServiceWithCost permite configurar un costo personalizado (útil para tests)
 func NewPasswordServiceWithCost(cost int) *PasswordService {
-	return &PasswordService{
-		cost: cost,
+	ps := NewPasswordService()
+	ps.cost = cost
+	return ps
+}
+
+// NewPasswordServiceWithAlgorithm crea un servicio que genera hashes con el algoritmo indicado
+// Los hashes existentes de cualquier algoritmo soportado se siguen pudiendo verificar.
+//
+// Si pepper no está vacío, la contraseña se pasa por HMAC-SHA256 con el pepper antes de
+// hashearla y al compararla. El pepper no se guarda en el hash, por lo que cambiarlo
+// invalida todos los hashes existentes: rotarlo obliga a restablecer las contraseñas.
+// Un pepper vacío desactiva este paso y mantiene la compatibilidad con hashes anteriores.
+func NewPasswordServiceWithAlgorithm(algorithm, pepper string) (*PasswordService, error) {
+	algorithm = strings.ToLower(strings.TrimSpace(algorithm))
+	if algorithm != AlgorithmBcrypt && algorithm != AlgorithmArgon2id {
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedAlgorithm, algorithm)
 	}
+
+	ps := NewPasswordService()
+	ps.algorithm = algorithm
+	if pepper != "" {
+		ps.pepper = []byte(pepper)
+	}
+	return ps, nil
+}
+
+// defaultArgon2Params devuelve los parámetros de Argon2id por defecto
+func defaultArgon2Params() argon2Params {
+	return argon2Params{
+		memory:  Argon2Memory,
+		time:    Argon2Time,
+		threads: Argon2Threads,
+		keyLen:  Argon2KeyLen,
+	}
+}
+
+// Algorithm devuelve el algoritmo usado para generar nuevos hashes
+func (ps *PasswordService) Algorithm() string {
+	return ps.algorithm
 }
 
-// HashPassword hashea una contraseña usando bcrypt
+// HashPassword hashea una contraseña con el algoritmo configurado
+// Aplica la política pero no consulta filtraciones, de eso se encarga ValidatePassword.
 func (ps *PasswordService) HashPassword(password string) (string, error) {
-	if err := ps.ValidatePassword(password); err != nil {
+	if err := ps.checkPolicy(password); err != nil {
 		return "", err
 	}
 
-	hashedBytes, err := bcrypt.GenerateFromPassword([]byte(password), ps.cost)
+	secret := ps.applyPepper(password)
+
+	if ps.algorithm == AlgorithmArgon2id {
+		return ps.hashArgon2id(secret)
+	}
+
+	hashedBytes, err := bcrypt.GenerateFromPassword(secret, ps.cost)
 	if err != nil {
 		return "", err
 	}
@@ -47,35 +206,98 @@ func (ps *PasswordService) HashPassword(password string) (string, error) {
 }
 
 // ComparePassword compara una contraseña plana con su hash
+// El algoritmo se detecta a partir del prefijo del hash
 func (ps *PasswordService) ComparePassword(hashedPassword, password string) bool {
-	err := bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
+	secret := ps.applyPepper(password)
+
+	if strings.HasPrefix(hashedPassword, argon2idPrefix) {
+		return compareArgon2id(hashedPassword, secret)
+	}
+
+	err := bcrypt.CompareHashAndPassword([]byte(hashedPassword), secret)
 	return err == nil
 }
 
-// ValidatePassword valida la fortaleza de la contraseña
+// ValidatePassword valida la fortaleza de la contraseña según la política configurada
+// y, si hay un BreachedPasswordChecker, que no aparezca en filtraciones conocidas.
+// Si la comprobación de filtraciones falla se acepta la contraseña, para que una caída
+// del servicio externo no impida registrarse ni cambiar la contraseña.
 func (ps *PasswordService) ValidatePassword(password string) error {
-	if len(password) < 8 {
-		return errors.New("password must be at least 8 characters long")
+	if err := ps.checkPolicy(password); err != nil {
+		return err
+	}
+
+	if ps.breached != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), breachCheckTimeout)
+		defer cancel()
+
+		if breached, err := ps.breached.IsBreached(ctx, password); err == nil && breached {
+			return ErrPasswordBreached
+		}
+	}
+
+	return nil
+}
+
+// checkPolicy comprueba la longitud y las clases de caracteres de la contraseña
+func (ps *PasswordService) checkPolicy(password string) error {
+	length := len(password)
+	if length < ps.policy.MinLength {
+		return fmt.Errorf("password must be at least %d characters long", ps.policy.MinLength)
 	}
 
-	if len(password) > 128 {
-		return errors.New("password cannot exceed 128 characters")
+	if length > MaxPasswordLength {
+		return fmt.Errorf("password cannot exceed %d characters", MaxPasswordLength)
 	}
 
-	// Verificar al menos una mayúscula, una minúscula y un dígito
-	hasUpper := regexp.MustCompile(`[A-Z]`).MatchString(password)
-	hasLower := regexp.MustCompile(`[a-z]`).MatchString(password)
-	hasDigit := regexp.MustCompile(`\d`).MatchString(password)
+	var hasUpper, hasLower, hasDigit, hasSpecial bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case !unicode.IsLetter(r):
+			hasSpecial = true
+		}
+	}
+
+	if ps.policy.RequireSpecial && !hasSpecial {
+		return errors.New("password must contain at least one special character")
+	}
 
-	if !hasUpper || !hasLower || !hasDigit {
-		return errors.New("password must contain at least one uppercase letter, one lowercase letter, and one digit")
+	classes := 0
+	for _, has := range []bool{hasUpper, hasLower, hasDigit, hasSpecial} {
+		if has {
+			classes++
+		}
+	}
+	if classes < ps.policy.MinCharClasses {
+		return fmt.Errorf("password must contain at least %d of: uppercase letters, lowercase letters, digits and special characters", ps.policy.MinCharClasses)
 	}
 
 	return nil
 }
 
-// NeedsRehash verifica si un hash necesita ser rehashed (por cambio de costo)
+// NeedsRehash verifica si un hash necesita ser rehashed
+// Ocurre cuando fue generado con otro algoritmo o con parámetros distintos a los actuales
 func (ps *PasswordService) NeedsRehash(hashedPassword string) bool {
+	if strings.HasPrefix(hashedPassword, argon2idPrefix) {
+		if ps.algorithm != AlgorithmArgon2id {
+			return true
+		}
+		params, _, _, err := decodeArgon2idHash(hashedPassword)
+		if err != nil {
+			return true
+		}
+		return params != ps.argon2
+	}
+
+	if ps.algorithm != AlgorithmBcrypt {
+		return true
+	}
 	cost, err := bcrypt.Cost([]byte(hashedPassword))
 	if err != nil {
 		return true // Si no podemos obtener el costo, asumir que necesita rehash
@@ -83,10 +305,90 @@ func (ps *PasswordService) NeedsRehash(hashedPassword string) bool {
 	return cost != ps.cost
 }
 
+// applyPepper mezcla el pepper con la contraseña mediante HMAC-SHA256
+// El resultado se codifica en base64 para no pasar bytes nulos a bcrypt y
+// mantenerse por debajo de su límite de 72 bytes. Sin pepper devuelve la contraseña tal cual.
+func (ps *PasswordService) applyPepper(password string) []byte {
+	if len(ps.pepper) == 0 {
+		return []byte(password)
+	}
+
+	mac := hmac.New(sha256.New, ps.pepper)
+	mac.Write([]byte(password))
+	return []byte(base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+}
+
+// hashArgon2id genera un hash Argon2id en formato PHC:
+// $argon2id$v=19$m=<memoria>,t=<iteraciones>,p=<hilos>$<salt>$<hash>
+func (ps *PasswordService) hashArgon2id(secret []byte) (string, error) {
+	salt := make([]byte, Argon2SaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("failed to generate salt: %w", err)
+	}
+
+	p := ps.argon2
+	key := argon2.IDKey(secret, salt, p.time, p.memory, p.threads, p.keyLen)
+
+	return fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2idPrefix, argon2.Version, p.memory, p.time, p.threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// compareArgon2id verifica una contraseña contra un hash Argon2id en tiempo constante
+func compareArgon2id(hashedPassword string, secret []byte) bool {
+	p, salt, key, err := decodeArgon2idHash(hashedPassword)
+	if err != nil {
+		return false
+	}
+
+	candidate := argon2.IDKey(secret, salt, p.time, p.memory, p.threads, p.keyLen)
+	return subtle.ConstantTimeCompare(key, candidate) == 1
+}
+
+// decodeArgon2idHash extrae los parámetros, el salt y la clave de un hash Argon2id
+func decodeArgon2idHash(hashedPassword string) (argon2Params, []byte, []byte, error) {
+	var p argon2Params
+
+	parts := strings.Split(hashedPassword, "$")
+	if len(parts) != 6 || parts[1] != AlgorithmArgon2id {
+		return p, nil, nil, errors.New("invalid argon2id hash format")
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return p, nil, nil, errors.New("unsupported argon2 version")
+	}
+
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.memory, &p.time, &p.threads); err != nil {
+		return p, nil, nil, fmt.Errorf("invalid argon2id parameters: %w", err)
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return p, nil, nil, fmt.Errorf("invalid argon2id salt: %w", err)
+	}
+
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return p, nil, nil, errors.New("invalid argon2id key")
+	}
+	p.keyLen = uint32(len(key))
+
+	return p, salt, key, nil
+}
+
 // Funciones de conveniencia globales para uso simple
 
 var defaultPasswordService = NewPasswordService()
 
+// SetDefaultPasswordService reemplaza el servicio usado por las funciones globales
+// Debe llamarse durante el arranque, antes de atender peticiones
+func SetDefaultPasswordService(ps *PasswordService) {
+	defaultPasswordService = ps
+}
+
 // HashPassword función global de conveniencia
 func HashPassword(password string) (string, error) {
 	return defaultPasswordService.HashPassword(password)
@@ -105,4 +407,4 @@ func ValidatePassword(password string) error {
 // NeedsRehash función global de conveniencia
 func NeedsRehash(hashedPassword string) bool {
 	return defaultPasswordService.NeedsRehash(hashedPassword)
-}
\ No newline at end of file
+}
@@ -0,0 +1,350 @@
+// internal/shared/utils/password_test.go
+package utils
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const testPassword = "Str0ng-Passw0rd"
+
+// newTestPasswordService builds a service for algorithm with the lowest bcrypt cost
+func newTestPasswordService(t *testing.T, algorithm, pepper string) *PasswordService {
+	t.Helper()
+	ps, err := NewPasswordServiceWithAlgorithm(algorithm, pepper)
+	if err != nil {
+		t.Fatalf("NewPasswordServiceWithAlgorithm(%q) error = %v", algorithm, err)
+	}
+	if err := ps.SetBcryptCost(bcrypt.MinCost); err != nil {
+		t.Fatalf("SetBcryptCost() error = %v", err)
+	}
+	return ps
+}
+
+func TestPasswordAlgorithms(t *testing.T) {
+	algorithms := []struct {
+		name       string
+		wantPrefix string
+	}{
+		{name: AlgorithmBcrypt, wantPrefix: "$2a$"},
+		{name: AlgorithmArgon2id, wantPrefix: "$argon2id$v=19$m=65536,t=3,p=2$"},
+	}
+
+	// Hash once per algorithm; every service must verify every hash
+	hashes := map[string]string{}
+	for _, algo := range algorithms {
+		hash, err := newTestPasswordService(t, algo.name, "").HashPassword(testPassword)
+		if err != nil {
+			t.Fatalf("HashPassword(%s) error = %v", algo.name, err)
+		}
+		if !strings.HasPrefix(hash, algo.wantPrefix) {
+			t.Errorf("%s hash = %q, want prefix %q", algo.name, hash, algo.wantPrefix)
+		}
+		hashes[algo.name] = hash
+	}
+
+	for _, preferred := range algorithms {
+		for _, hashedWith := range algorithms {
+			t.Run(preferred.name+" service/"+hashedWith.name+" hash", func(t *testing.T) {
+				ps := newTestPasswordService(t, preferred.name, "")
+				hash := hashes[hashedWith.name]
+
+				if !ps.ComparePassword(hash, testPassword) {
+					t.Error("ComparePassword(correct password) = false, want true")
+				}
+				if ps.ComparePassword(hash, "Wr0ng-Passw0rd") {
+					t.Error("ComparePassword(wrong password) = true, want false")
+				}
+				if got, want := ps.NeedsRehash(hash), preferred.name != hashedWith.name; got != want {
+					t.Errorf("NeedsRehash() = %v, want %v", got, want)
+				}
+			})
+		}
+	}
+}
+
+func TestNewPasswordServiceWithAlgorithm(t *testing.T) {
+	tests := []struct {
+		algorithm string
+		want      string
+		wantErr   bool
+	}{
+		{algorithm: "bcrypt", want: AlgorithmBcrypt},
+		{algorithm: " Argon2id ", want: AlgorithmArgon2id},
+		{algorithm: "scrypt", wantErr: true},
+		{algorithm: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.algorithm, func(t *testing.T) {
+			ps, err := NewPasswordServiceWithAlgorithm(tt.algorithm, "")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewPasswordServiceWithAlgorithm() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && ps.Algorithm() != tt.want {
+				t.Errorf("Algorithm() = %q, want %q", ps.Algorithm(), tt.want)
+			}
+		})
+	}
+}
+
+func TestSetBcryptCost(t *testing.T) {
+	tests := []struct {
+		name    string
+		cost    int
+		wantErr bool
+	}{
+		{name: "minimum", cost: bcrypt.MinCost},
+		{name: "above the minimum", cost: bcrypt.MinCost + 2},
+		{name: "below the minimum", cost: bcrypt.MinCost - 1, wantErr: true},
+		{name: "zero", cost: 0, wantErr: true},
+		{name: "above the maximum", cost: bcrypt.MaxCost + 1, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ps := NewPasswordService()
+			before := ps.BcryptCost()
+
+			err := ps.SetBcryptCost(tt.cost)
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "bcrypt cost must be between 4 and 31") {
+					t.Errorf("SetBcryptCost(%d) error = %v, want a range error", tt.cost, err)
+				}
+				if got := ps.BcryptCost(); got != before {
+					t.Errorf("BcryptCost() = %d after a rejected cost, want %d", got, before)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SetBcryptCost(%d) error = %v", tt.cost, err)
+			}
+			if got := ps.BcryptCost(); got != tt.cost {
+				t.Errorf("BcryptCost() = %d, want %d", got, tt.cost)
+			}
+
+			hash, err := ps.HashPassword(testPassword)
+			if err != nil {
+				t.Fatalf("HashPassword() error = %v", err)
+			}
+			if cost, err := bcrypt.Cost([]byte(hash)); err != nil || cost != tt.cost {
+				t.Errorf("bcrypt.Cost() = %d, %v, want %d", cost, err, tt.cost)
+			}
+			if !ps.ComparePassword(hash, testPassword) {
+				t.Error("ComparePassword() = false for the new hash")
+			}
+		})
+	}
+}
+
+func TestDefaultBcryptCost(t *testing.T) {
+	if got := NewPasswordService().BcryptCost(); got != 12 {
+		t.Errorf("BcryptCost() = %d, want the default 12", got)
+	}
+}
+
+func TestNeedsRehashOnParameterChange(t *testing.T) {
+	tests := []struct {
+		name      string
+		algorithm string
+		// change adjusts the service after the hash was made
+		change func(ps *PasswordService)
+		want   bool
+	}{
+		{name: "bcrypt same cost", algorithm: AlgorithmBcrypt, change: func(ps *PasswordService) {}},
+		{name: "bcrypt cost raised", algorithm: AlgorithmBcrypt, change: func(ps *PasswordService) { ps.SetBcryptCost(bcrypt.MinCost + 1) }, want: true},
+		{name: "argon2id same params", algorithm: AlgorithmArgon2id, change: func(ps *PasswordService) {}},
+		{name: "argon2id memory raised", algorithm: AlgorithmArgon2id, change: func(ps *PasswordService) { ps.argon2.memory *= 2 }, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ps := newTestPasswordService(t, tt.algorithm, "")
+			hash, err := ps.HashPassword(testPassword)
+			if err != nil {
+				t.Fatalf("HashPassword() error = %v", err)
+			}
+			tt.change(ps)
+			if got := ps.NeedsRehash(hash); got != tt.want {
+				t.Errorf("NeedsRehash() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPasswordPepper(t *testing.T) {
+	tests := []struct {
+		name        string
+		hashPepper  string
+		checkPepper string
+		want        bool
+	}{
+		{name: "same pepper", hashPepper: "pepper-one", checkPepper: "pepper-one", want: true},
+		{name: "rotated pepper", hashPepper: "pepper-one", checkPepper: "pepper-two"},
+		{name: "pepper removed", hashPepper: "pepper-one", checkPepper: ""},
+		{name: "pepper added", hashPepper: "", checkPepper: "pepper-one"},
+		{name: "no pepper", hashPepper: "", checkPepper: "", want: true},
+	}
+
+	for _, algorithm := range []string{AlgorithmBcrypt, AlgorithmArgon2id} {
+		for _, tt := range tests {
+			t.Run(algorithm+"/"+tt.name, func(t *testing.T) {
+				hash, err := newTestPasswordService(t, algorithm, tt.hashPepper).HashPassword(testPassword)
+				if err != nil {
+					t.Fatalf("HashPassword() error = %v", err)
+				}
+				if got := newTestPasswordService(t, algorithm, tt.checkPepper).ComparePassword(hash, testPassword); got != tt.want {
+					t.Errorf("ComparePassword() = %v, want %v", got, tt.want)
+				}
+			})
+		}
+	}
+}
+
+func TestPasswordPepperEmptyIsCompatible(t *testing.T) {
+	// Hashes made before peppering existed are plain bcrypt of the password
+	legacy, err := bcrypt.GenerateFromPassword([]byte(testPassword), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("GenerateFromPassword() error = %v", err)
+	}
+	if !newTestPasswordService(t, AlgorithmBcrypt, "").ComparePassword(string(legacy), testPassword) {
+		t.Error("ComparePassword(legacy hash) = false with an empty pepper, want true")
+	}
+}
+
+func TestPasswordPolicy(t *testing.T) {
+	defaults := DefaultPasswordPolicy()
+	special := PasswordPolicy{MinLength: 8, RequireSpecial: true, MinCharClasses: 3}
+	allClasses := PasswordPolicy{MinLength: 8, MinCharClasses: 4}
+	long := PasswordPolicy{MinLength: 16, MinCharClasses: 3}
+	strictest := PasswordPolicy{MinLength: 16, RequireSpecial: true, MinCharClasses: 4}
+
+	tests := []struct {
+		name     string
+		policy   PasswordPolicy
+		password string
+		wantErr  string
+	}{
+		{name: "default/upper lower digit", policy: defaults, password: "Passw0rd"},
+		{name: "default/lower digit special", policy: defaults, password: "passw0rd!"},
+		{name: "default/too short", policy: defaults, password: "Pa0rd", wantErr: "at least 8 characters"},
+		{name: "default/two classes", policy: defaults, password: "password1", wantErr: "at least 3 of"},
+		{name: "default/too long", policy: defaults, password: "Aa1" + strings.Repeat("a", MaxPasswordLength), wantErr: "cannot exceed 128"},
+		{name: "special/with special", policy: special, password: "Passw0rd!"},
+		{name: "special/without special", policy: special, password: "Passw0rd", wantErr: "special character"},
+		{name: "four classes/all present", policy: allClasses, password: "Passw0rd!"},
+		{name: "four classes/three present", policy: allClasses, password: "Passw0rd", wantErr: "at least 4 of"},
+		{name: "long/long enough", policy: long, password: "CorrectHorse1234"},
+		{name: "long/default length", policy: long, password: "Passw0rd", wantErr: "at least 16 characters"},
+		{name: "strictest/passes", policy: strictest, password: "Correct-Horse-12"},
+		{name: "strictest/default password", policy: strictest, password: "Passw0rd", wantErr: "at least 16 characters"},
+		{name: "strictest/long without special", policy: strictest, password: "CorrectHorse1234", wantErr: "special character"},
+		{name: "unicode letters count by case", policy: defaults, password: "Ñandú-2024"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ps := NewPasswordServiceWithCost(bcrypt.MinCost)
+			ps.SetPolicy(tt.policy)
+
+			err := ps.ValidatePassword(tt.password)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("ValidatePassword(%q) error = %v", tt.password, err)
+				}
+			} else if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("ValidatePassword(%q) error = %v, want it to mention %q", tt.password, err, tt.wantErr)
+			}
+
+			// HashPassword enforces the same policy
+			if _, hashErr := ps.HashPassword(tt.password); (hashErr != nil) != (err != nil) {
+				t.Errorf("HashPassword() error = %v, ValidatePassword() error = %v", hashErr, err)
+			}
+		})
+	}
+}
+
+func TestDefaultPasswordPolicyPassesStricterFails(t *testing.T) {
+	ps := NewPasswordServiceWithCost(bcrypt.MinCost)
+	if err := ps.ValidatePassword(testPassword); err != nil {
+		t.Fatalf("ValidatePassword() with the default policy error = %v", err)
+	}
+
+	const weak = "Passw0rd"
+	if err := ps.ValidatePassword(weak); err != nil {
+		t.Fatalf("ValidatePassword(%q) with the default policy error = %v", weak, err)
+	}
+	ps.SetPolicy(PasswordPolicy{MinLength: 12, RequireSpecial: true, MinCharClasses: 4})
+	if err := ps.ValidatePassword(weak); err == nil {
+		t.Errorf("ValidatePassword(%q) with a stricter policy error = nil", weak)
+	}
+}
+
+// stubBreachChecker reports every password in breached and fails with err
+type stubBreachChecker struct {
+	breached map[string]bool
+	err      error
+	calls    int
+}
+
+func (c *stubBreachChecker) IsBreached(ctx context.Context, password string) (bool, error) {
+	c.calls++
+	if _, ok := ctx.Deadline(); !ok {
+		return false, errors.New("no deadline on the breach check context")
+	}
+	return c.breached[password], c.err
+}
+
+func TestValidatePasswordBreachedChecker(t *testing.T) {
+	tests := []struct {
+		name     string
+		checker  *stubBreachChecker
+		password string
+		wantErr  error
+		// wantPolicyErr expects a policy error instead of a breach result
+		wantPolicyErr bool
+		wantCalls     int
+	}{
+		{name: "breached password", checker: &stubBreachChecker{breached: map[string]bool{testPassword: true}}, password: testPassword, wantErr: ErrPasswordBreached, wantCalls: 1},
+		{name: "clean password", checker: &stubBreachChecker{}, password: testPassword, wantCalls: 1},
+		{name: "checker failure accepts the password", checker: &stubBreachChecker{breached: map[string]bool{testPassword: true}, err: errors.New("hibp down")}, password: testPassword, wantCalls: 1},
+		{name: "policy failures skip the check", checker: &stubBreachChecker{}, password: "short", wantPolicyErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ps := NewPasswordServiceWithCost(bcrypt.MinCost)
+			ps.SetBreachedPasswordChecker(tt.checker)
+
+			err := ps.ValidatePassword(tt.password)
+			switch {
+			case tt.wantPolicyErr:
+				if err == nil || errors.Is(err, ErrPasswordBreached) {
+					t.Errorf("ValidatePassword() error = %v, want a policy error", err)
+				}
+			case !errors.Is(err, tt.wantErr):
+				t.Errorf("ValidatePassword() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.checker.calls != tt.wantCalls {
+				t.Errorf("IsBreached called %d times, want %d", tt.checker.calls, tt.wantCalls)
+			}
+		})
+	}
+
+	t.Run("nil disables the check", func(t *testing.T) {
+		checker := &stubBreachChecker{breached: map[string]bool{testPassword: true}}
+		ps := NewPasswordServiceWithCost(bcrypt.MinCost)
+		ps.SetBreachedPasswordChecker(checker)
+		ps.SetBreachedPasswordChecker(nil)
+		if err := ps.ValidatePassword(testPassword); err != nil {
+			t.Errorf("ValidatePassword() error = %v", err)
+		}
+		if checker.calls != 0 {
+			t.Errorf("IsBreached called %d times after removing the checker", checker.calls)
+		}
+	})
+}
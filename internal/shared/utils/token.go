@@ -0,0 +1,289 @@
+// internal/shared/utils/token.go
+package utils
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Token types
+const (
+	TokenTypeAccess  = "access"
+	TokenTypeRefresh = "refresh"
+)
+
+// Token errors
+var (
+	ErrInvalidToken = errors.New("invalid token")
+	ErrExpiredToken = errors.New("token has expired")
+)
+
+// Supported JWT signing algorithms
+const (
+	AlgorithmHS256 = "HS256"
+	AlgorithmRS256 = "RS256"
+)
+
+// TokenClaims represents the claims carried by a JWT issued by the TokenService
+type TokenClaims struct {
+	ID             string   `json:"jti"`
+	Subject        string   `json:"sub"`
+	Roles          []string `json:"roles,omitempty"`
+	TokenType      string   `json:"typ"`
+	Version        int64    `json:"ver,omitempty"` // The user's token version when issued
+	IssuedAt       int64    `json:"iat"`
+	ExpiresAt      int64    `json:"exp"`
+	ImpersonatedBy string   `json:"impersonated_by,omitempty"` // The admin acting as the subject, on impersonation tokens only
+}
+
+// HasRole checks if the claims include a specific role
+func (c *TokenClaims) HasRole(role string) bool {
+	for _, r := range c.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// tokenHeader represents the JOSE header of a JWT
+type tokenHeader struct {
+	Algorithm string `json:"alg"`
+	Type      string `json:"typ"`
+	KeyID     string `json:"kid,omitempty"`
+}
+
+// tokenSigner signs and verifies tokens with a single algorithm
+type tokenSigner interface {
+	algorithm() string
+	keyID() string
+	sign(input string) ([]byte, error)
+	verify(input string, signature []byte) bool
+}
+
+// hmacSigner signs tokens with HS256 using a shared secret
+type hmacSigner struct {
+	secret []byte
+}
+
+func (s *hmacSigner) algorithm() string { return AlgorithmHS256 }
+
+func (s *hmacSigner) keyID() string { return "" }
+
+func (s *hmacSigner) sign(input string) ([]byte, error) {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(input))
+	return mac.Sum(nil), nil
+}
+
+func (s *hmacSigner) verify(input string, signature []byte) bool {
+	expected, _ := s.sign(input)
+	return hmac.Equal(signature, expected)
+}
+
+// rsaSigner signs tokens with RS256 so they can be verified with the public key alone
+type rsaSigner struct {
+	key *rsa.PrivateKey
+	kid string
+}
+
+func (s *rsaSigner) algorithm() string { return AlgorithmRS256 }
+
+func (s *rsaSigner) keyID() string { return s.kid }
+
+func (s *rsaSigner) sign(input string) ([]byte, error) {
+	digest := sha256.Sum256([]byte(input))
+	return rsa.SignPKCS1v15(rand.Reader, s.key, crypto.SHA256, digest[:])
+}
+
+func (s *rsaSigner) verify(input string, signature []byte) bool {
+	digest := sha256.Sum256([]byte(input))
+	return rsa.VerifyPKCS1v15(&s.key.PublicKey, crypto.SHA256, digest[:], signature) == nil
+}
+
+// TokenService handles issuing and validating signed JWTs
+type TokenService struct {
+	signer             tokenSigner
+	accessExpiration   time.Duration
+	refreshExpiration  time.Duration
+	rememberExpiration time.Duration
+}
+
+// NewTokenService creates a new TokenService signing tokens with HS256
+// rememberExpiration is the refresh token lifetime used for "remember me" sessions
+func NewTokenService(secret string, accessExpiration, refreshExpiration, rememberExpiration time.Duration) *TokenService {
+	return &TokenService{
+		signer:             &hmacSigner{secret: []byte(secret)},
+		accessExpiration:   accessExpiration,
+		refreshExpiration:  refreshExpiration,
+		rememberExpiration: rememberExpiration,
+	}
+}
+
+// NewRSATokenService creates a new TokenService signing tokens with RS256
+// Other services can verify its tokens with the public key published by JWKS.
+func NewRSATokenService(key *rsa.PrivateKey, accessExpiration, refreshExpiration, rememberExpiration time.Duration) *TokenService {
+	return &TokenService{
+		signer:             &rsaSigner{key: key, kid: rsaKeyID(&key.PublicKey)},
+		accessExpiration:   accessExpiration,
+		refreshExpiration:  refreshExpiration,
+		rememberExpiration: rememberExpiration,
+	}
+}
+
+// Algorithm returns the JWT algorithm tokens are signed with
+func (ts *TokenService) Algorithm() string {
+	return ts.signer.algorithm()
+}
+
+// AccessExpiration returns the lifetime of access tokens
+func (ts *TokenService) AccessExpiration() time.Duration {
+	return ts.accessExpiration
+}
+
+// GenerateAccessToken issues an access token for a user
+// version is the user's current token version; bumping it later revokes the token.
+func (ts *TokenService) GenerateAccessToken(userID string, roles []string, version int64) (string, error) {
+	return ts.generate(userID, roles, version, TokenTypeAccess, ts.accessExpiration)
+}
+
+// GenerateImpersonationToken issues an access token letting an admin act as a user
+// The token carries the admin's ID in the impersonated_by claim and expires after expiration.
+func (ts *TokenService) GenerateImpersonationToken(userID string, roles []string, version int64, adminID string, expiration time.Duration) (string, error) {
+	claims, err := ts.newClaims(userID, roles, version, TokenTypeAccess, expiration)
+	if err != nil {
+		return "", err
+	}
+	claims.ImpersonatedBy = adminID
+	return ts.sign(claims)
+}
+
+// RefreshExpiration returns the lifetime of refresh tokens, extended for "remember me" sessions
+func (ts *TokenService) RefreshExpiration(rememberMe bool) time.Duration {
+	if rememberMe {
+		return ts.rememberExpiration
+	}
+	return ts.refreshExpiration
+}
+
+// GenerateRefreshToken issues a refresh token for a user
+// When rememberMe is set the token uses the extended "remember me" lifetime
+func (ts *TokenService) GenerateRefreshToken(userID string, version int64, rememberMe bool) (string, error) {
+	return ts.generate(userID, nil, version, TokenTypeRefresh, ts.RefreshExpiration(rememberMe))
+}
+
+// ValidateToken verifies the signature and expiry of a token and returns its claims
+func (ts *TokenService) ValidateToken(token string) (*TokenClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrInvalidToken
+	}
+
+	headerJSON, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	var header tokenHeader
+	if err := json.Unmarshal(headerJSON, &header); err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	// Only accept the algorithm we sign with (rejects "none" and algorithm confusion)
+	if header.Algorithm != ts.signer.algorithm() {
+		return nil, ErrInvalidToken
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	if !ts.signer.verify(parts[0]+"."+parts[1], signature) {
+		return nil, ErrInvalidToken
+	}
+
+	claimsJSON, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	var claims TokenClaims
+	if err := json.Unmarshal(claimsJSON, &claims); err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	if time.Now().UTC().Unix() >= claims.ExpiresAt {
+		return nil, ErrExpiredToken
+	}
+
+	return &claims, nil
+}
+
+// generate builds and signs a token with the given claims
+func (ts *TokenService) generate(userID string, roles []string, version int64, tokenType string, expiration time.Duration) (string, error) {
+	claims, err := ts.newClaims(userID, roles, version, tokenType, expiration)
+	if err != nil {
+		return "", err
+	}
+	return ts.sign(claims)
+}
+
+// newClaims builds the claims of a token issued now with a fresh token ID
+func (ts *TokenService) newClaims(userID string, roles []string, version int64, tokenType string, expiration time.Duration) (TokenClaims, error) {
+	id, err := generateTokenID()
+	if err != nil {
+		return TokenClaims{}, fmt.Errorf("failed to generate token ID: %w", err)
+	}
+
+	now := time.Now().UTC()
+	return TokenClaims{
+		ID:        id,
+		Subject:   userID,
+		Roles:     roles,
+		TokenType: tokenType,
+		Version:   version,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(expiration).Unix(),
+	}, nil
+}
+
+// sign encodes claims and signs them into a token
+func (ts *TokenService) sign(claims TokenClaims) (string, error) {
+	headerJSON, err := json.Marshal(tokenHeader{Algorithm: ts.signer.algorithm(), Type: "JWT", KeyID: ts.signer.keyID()})
+	if err != nil {
+		return "", err
+	}
+
+	claimsJSON, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	unsigned := base64.RawURLEncoding.EncodeToString(headerJSON) + "." + base64.RawURLEncoding.EncodeToString(claimsJSON)
+	signature, err := ts.signer.sign(unsigned)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign token: %w", err)
+	}
+
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// generateTokenID generates a random unique token identifier
+func generateTokenID() (string, error) {
+	bytes := make([]byte, 16)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(bytes), nil
+}
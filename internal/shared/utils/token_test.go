@@ -0,0 +1,246 @@
+// internal/shared/utils/token_test.go
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// decodeHeader returns the JOSE header of a token
+func decodeHeader(t *testing.T, token string) tokenHeader {
+	t.Helper()
+	headerJSON, err := base64.RawURLEncoding.DecodeString(strings.Split(token, ".")[0])
+	if err != nil {
+		t.Fatalf("invalid token header: %v", err)
+	}
+	var header tokenHeader
+	if err := json.Unmarshal(headerJSON, &header); err != nil {
+		t.Fatalf("invalid token header: %v", err)
+	}
+	return header
+}
+
+// forgeToken builds a token with an arbitrary header, signed by sign
+func forgeToken(t *testing.T, header tokenHeader, sign func(input string) []byte) string {
+	t.Helper()
+	headerJSON, _ := json.Marshal(header)
+	claimsJSON, _ := json.Marshal(TokenClaims{
+		Subject:   "user-1",
+		Roles:     []string{"admin"},
+		TokenType: TokenTypeAccess,
+		ExpiresAt: time.Now().Add(time.Hour).Unix(),
+	})
+	unsigned := base64.RawURLEncoding.EncodeToString(headerJSON) + "." + base64.RawURLEncoding.EncodeToString(claimsJSON)
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(sign(unsigned))
+}
+
+func TestTokenVersionClaim(t *testing.T) {
+	ts := NewTokenService("test-secret-test-secret-test-secret", time.Hour, 24*time.Hour, 30*24*time.Hour)
+
+	tests := []struct {
+		name     string
+		generate func(version int64) (string, error)
+		wantType string
+	}{
+		{
+			name: "access token",
+			generate: func(version int64) (string, error) {
+				return ts.GenerateAccessToken("user-1", []string{"user"}, version)
+			},
+			wantType: TokenTypeAccess,
+		},
+		{
+			name: "refresh token",
+			generate: func(version int64) (string, error) {
+				return ts.GenerateRefreshToken("user-1", version, false)
+			},
+			wantType: TokenTypeRefresh,
+		},
+	}
+
+	for _, tt := range tests {
+		for _, version := range []int64{0, 1, 42} {
+			t.Run(tt.name, func(t *testing.T) {
+				token, err := tt.generate(version)
+				if err != nil {
+					t.Fatalf("generate(%d) error = %v", version, err)
+				}
+				claims, err := ts.ValidateToken(token)
+				if err != nil {
+					t.Fatalf("ValidateToken() error = %v", err)
+				}
+				if claims.Version != version || claims.TokenType != tt.wantType || claims.Subject != "user-1" {
+					t.Errorf("claims = %+v, want a %s token for user-1 with version %d", claims, tt.wantType, version)
+				}
+			})
+		}
+	}
+}
+
+func TestTokenServiceAlgorithms(t *testing.T) {
+	tests := []struct {
+		name    string
+		tokens  *TokenService
+		want    string
+		wantKID bool
+	}{
+		{name: "HS256", tokens: NewTokenService("test-secret-test-secret-test-secret", time.Hour, 24*time.Hour, 30*24*time.Hour), want: AlgorithmHS256},
+		{name: "RS256", tokens: newTestRSATokenService(testRSAKey(t, 0)), want: AlgorithmRS256, wantKID: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.tokens.Algorithm(); got != tt.want {
+				t.Errorf("Algorithm() = %q, want %q", got, tt.want)
+			}
+
+			token, err := tt.tokens.GenerateAccessToken("user-1", []string{"user", "admin"}, 3)
+			if err != nil {
+				t.Fatalf("GenerateAccessToken() error = %v", err)
+			}
+			header := decodeHeader(t, token)
+			if header.Algorithm != tt.want || header.Type != "JWT" || (header.KeyID != "") != tt.wantKID {
+				t.Errorf("header = %+v, want alg %s with kid %v", header, tt.want, tt.wantKID)
+			}
+
+			claims, err := tt.tokens.ValidateToken(token)
+			if err != nil {
+				t.Fatalf("ValidateToken() error = %v", err)
+			}
+			if claims.Subject != "user-1" || !claims.HasRole("admin") || claims.Version != 3 {
+				t.Errorf("claims = %+v, want the issued claims", claims)
+			}
+
+			// Any change to the signed content invalidates the signature
+			parts := strings.Split(token, ".")
+			escalated := *claims
+			escalated.Subject = "user-2"
+			claimsJSON, _ := json.Marshal(escalated)
+			tampered := parts[0] + "." + base64.RawURLEncoding.EncodeToString(claimsJSON) + "." + parts[2]
+			if _, err := tt.tokens.ValidateToken(tampered); !errors.Is(err, ErrInvalidToken) {
+				t.Errorf("ValidateToken(tampered) error = %v, want ErrInvalidToken", err)
+			}
+		})
+	}
+}
+
+func TestValidateTokenRejectsOtherAlgorithms(t *testing.T) {
+	const secret = "test-secret-test-secret-test-secret"
+	key := testRSAKey(t, 0)
+	hs256 := NewTokenService(secret, time.Hour, 24*time.Hour, 30*24*time.Hour)
+	rs256 := newTestRSATokenService(key)
+	otherRS256 := newTestRSATokenService(testRSAKey(t, 1))
+
+	// An attacker who knows the public key may try to use it as an HMAC secret
+	pkix, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pkix})
+	hmacWith := func(secret []byte) func(string) []byte {
+		return func(input string) []byte {
+			mac := hmac.New(sha256.New, secret)
+			mac.Write([]byte(input))
+			return mac.Sum(nil)
+		}
+	}
+	unsigned := func(string) []byte { return nil }
+	issue := func(ts *TokenService) string {
+		token, err := ts.GenerateAccessToken("user-1", []string{"user"}, 0)
+		if err != nil {
+			t.Fatalf("GenerateAccessToken() error = %v", err)
+		}
+		return token
+	}
+
+	tests := []struct {
+		name   string
+		tokens *TokenService
+		token  string
+	}{
+		{name: "HS256 token at an RS256 service", tokens: rs256, token: issue(hs256)},
+		{name: "RS256 token at an HS256 service", tokens: hs256, token: issue(rs256)},
+		{name: "RS256 token signed by another key", tokens: rs256, token: issue(otherRS256)},
+		{name: "HS256 signed with the public key", tokens: rs256, token: forgeToken(t, tokenHeader{Algorithm: AlgorithmHS256, Type: "JWT"}, hmacWith(publicPEM))},
+		{name: "none at an HS256 service", tokens: hs256, token: forgeToken(t, tokenHeader{Algorithm: "none", Type: "JWT"}, unsigned)},
+		{name: "none at an RS256 service", tokens: rs256, token: forgeToken(t, tokenHeader{Algorithm: "none", Type: "JWT"}, unsigned)},
+		{name: "lowercase algorithm name", tokens: hs256, token: forgeToken(t, tokenHeader{Algorithm: "hs256", Type: "JWT"}, hmacWith([]byte(secret)))},
+		{name: "RS256 header with an HMAC signature", tokens: rs256, token: forgeToken(t, tokenHeader{Algorithm: AlgorithmRS256, Type: "JWT"}, hmacWith(publicPEM))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if claims, err := tt.tokens.ValidateToken(tt.token); !errors.Is(err, ErrInvalidToken) {
+				t.Errorf("ValidateToken() = %+v, %v, want ErrInvalidToken", claims, err)
+			}
+		})
+	}
+}
+
+func TestGenerateImpersonationToken(t *testing.T) {
+	ts := NewTokenService("test-secret-test-secret-test-secret", time.Hour, 24*time.Hour, 30*24*time.Hour)
+
+	tests := []struct {
+		name             string
+		generate         func() (string, error)
+		wantImpersonator string
+		wantLifetime     time.Duration
+	}{
+		{
+			name: "impersonation token",
+			generate: func() (string, error) {
+				return ts.GenerateImpersonationToken("user-1", []string{"user"}, 3, "admin-1", 15*time.Minute)
+			},
+			wantImpersonator: "admin-1",
+			wantLifetime:     15 * time.Minute,
+		},
+		{
+			name: "access token",
+			generate: func() (string, error) {
+				return ts.GenerateAccessToken("user-1", []string{"user"}, 3)
+			},
+			wantLifetime: time.Hour,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := tt.generate()
+			if err != nil {
+				t.Fatalf("generate() error = %v", err)
+			}
+			claims, err := ts.ValidateToken(token)
+			if err != nil {
+				t.Fatalf("ValidateToken() error = %v", err)
+			}
+
+			if claims.ImpersonatedBy != tt.wantImpersonator {
+				t.Errorf("impersonated_by = %q, want %q", claims.ImpersonatedBy, tt.wantImpersonator)
+			}
+			// Impersonation tokens are access tokens for the target user, with its roles and version
+			if claims.Subject != "user-1" || claims.TokenType != TokenTypeAccess || claims.Version != 3 || !claims.HasRole("user") {
+				t.Errorf("claims = %+v, want an access token for user-1 with version 3", claims)
+			}
+			if got := time.Duration(claims.ExpiresAt-claims.IssuedAt) * time.Second; got != tt.wantLifetime {
+				t.Errorf("lifetime = %v, want %v", got, tt.wantLifetime)
+			}
+
+			// Regular tokens leave the claim out entirely
+			payload, err := base64.RawURLEncoding.DecodeString(strings.Split(token, ".")[1])
+			if err != nil {
+				t.Fatalf("invalid token payload: %v", err)
+			}
+			if got := strings.Contains(string(payload), `"impersonated_by"`); got != (tt.wantImpersonator != "") {
+				t.Errorf("payload %s has impersonated_by = %v, want %v", payload, got, tt.wantImpersonator != "")
+			}
+		})
+	}
+}